@@ -3,10 +3,10 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
-	tui "bubbletea-ssh-manager/internal/tui"
-
-	tea "github.com/charmbracelet/bubbletea"
+	"bubbletea-ssh-manager/internal/cli"
+	"bubbletea-ssh-manager/internal/secret"
 )
 
 // TODO:
@@ -24,7 +24,6 @@ import (
 //       allow changing protocol in edit host form?
 //       check model_handle.go handleConnectFinishedMsg comment
 //       change color names back to actual colors in theme.go
-//       handle immediate ssh errors better (ie. bad config options)
 //       make ssh options a select/multi-select field in host form and add all of them?
 //
 //
@@ -46,18 +45,22 @@ import (
 //            - should be able to be focused and navigated (maybe tab to switch between)
 //       always format host names when displaying in status
 //       add icon for executable
-//       add config file for environment settings (eg. default user, default port, paths, etc.)
 //       fix silent errors in parser.go
 //       move relayout calls to a better place (not after every modal open/close) - maybe in update loop after handling msg?
-//       look into context.Context for managing preflight timeouts/cancellations
-//          - move everything else to internal (eg. internal/tui/model.go, internal/tui/keys(views, forms), etc.)
-//       make protocol a type with constants
 //       add --version flag
 
 func main() {
-	p := tea.NewProgram(tui.NewModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	// ssh runs the binary again as its askpass helper for hosts with a
+	// password secret
+	if secret.IsAskpass() {
+		if err := secret.Askpass(strings.Join(os.Args[1:], " "), os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
 	}
+
+	// arguments are a subcommand (list, connect, …) or a host to connect to;
+	// none starts the menu
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
 }
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/hooks"
+)
+
+// checkDialWorkers is how many hosts check --dial dials at once.
+const checkDialWorkers = 16
+
+// problem is something check found wrong with a host or config.
+type problem struct {
+	protocol  config.Protocol // protocol of the config ("" for app-wide files)
+	alias     string          // host alias ("" for the config as a whole)
+	msg       string          // what's wrong
+	inventory string          // inventory of the config, if it isn't a local one
+}
+
+// String returns the problem as a line: "[inventory] protocol alias: message".
+func (p problem) String() string {
+	where := strings.TrimSpace(strings.TrimSpace(p.inventory+" "+string(p.protocol)) + " " + p.alias)
+	if where == "" {
+		return p.msg
+	}
+	return where + ": " + p.msg
+}
+
+// runCheck checks every inventory's config and the hooks file: hosts missing
+// required fields or with invalid values, aliases defined twice and
+// ProxyJump chains that don't resolve. With --dial it also checks that
+// each host answers, like preflight.
+//
+// Problems go to stdout, one per line; the exit code is ExitFailure if
+// there are any.
+func (c *cli) runCheck(args []string) int {
+	fs := c.flags()
+	dial := fs.Bool("dial", false, "also check that every host accepts a connection")
+	timeout := fs.Duration("timeout", 5*time.Second, "timeout for each dial")
+	pos, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	if len(pos) > 0 {
+		return c.usageError("unexpected argument %q", pos[0])
+	}
+
+	var (
+		problems []problem
+		hosts    []config.Host
+	)
+	invs, err := config.Inventories()
+	if err != nil {
+		problems = append(problems, problem{msg: err.Error()})
+	}
+	for _, inv := range invs {
+		name := ""
+		if !inv.IsLocal() {
+			name = inv.Name
+		}
+		entries, err := inv.Read()
+		if err != nil {
+			problems = append(problems, problem{protocol: inv.Protocol, msg: err.Error(), inventory: name})
+		}
+		seen := map[string]string{}
+		for _, e := range entries {
+			h := config.Host{Protocol: inv.Protocol, Inventory: inv, HostEntry: e}
+			alias := strings.ToLower(e.Spec.Alias)
+			if src, dup := seen[alias]; dup {
+				problems = append(problems, problem{inv.Protocol, e.Spec.Alias,
+					fmt.Sprintf("defined in both %s and %s", src, e.SourcePath), name})
+				continue
+			}
+			seen[alias] = e.SourcePath
+			if err := connect.ValidateHost(h); err != nil {
+				for _, line := range strings.Split(err.Error(), "\n") {
+					problems = append(problems, problem{inv.Protocol, e.Spec.Alias, line, name})
+				}
+				continue
+			}
+			hosts = append(hosts, h)
+		}
+	}
+	for _, h := range hosts {
+		if _, err := targetFor(h, hosts); err != nil {
+			problems = append(problems, problem{h.Protocol, h.Spec.Alias, strings.TrimPrefix(err.Error(), h.Spec.Alias+": "),
+				inventoryLabel(h.Inventory)})
+		}
+	}
+	if _, err := hooks.Load(); err != nil {
+		problems = append(problems, problem{msg: "hooks: " + err.Error()})
+	}
+	if *dial {
+		problems = append(problems, dialHosts(hosts, *timeout)...)
+	}
+
+	for _, p := range problems {
+		fmt.Fprintln(c.stdout, p)
+	}
+	fmt.Fprintf(c.stderr, "Checked %d host(s): %d problem(s).\n", len(hosts), len(problems))
+	if len(problems) > 0 {
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// dialHosts dials every host that has a preflight check (the first jump hop
+// for hosts behind a ProxyJump) and returns the ones that don't answer.
+func dialHosts(hosts []config.Host, timeout time.Duration) []problem {
+	type job struct {
+		host     config.Host
+		hostPort string
+	}
+	jobs := make(chan job)
+	var results []problem
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for range checkDialWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				err := connect.PreflightDial(ctx, j.hostPort)
+				cancel()
+				if err != nil {
+					mu.Lock()
+					results = append(results, problem{j.host.Protocol, j.host.Spec.Alias,
+						fmt.Sprintf("%s doesn't answer: %v", j.hostPort, err), inventoryLabel(j.host.Inventory)})
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, h := range hosts {
+		t, err := targetFor(h, hosts)
+		if err != nil || !connect.ShouldPreflight(t) {
+			continue
+		}
+		jobs <- job{host: h, hostPort: connect.GenerateHostPort(withDefaultPort(t))}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// report in config order, not completion order
+	order := map[string]int{}
+	for i, h := range hosts {
+		order[inventoryLabel(h.Inventory)+":"+string(h.Protocol)+":"+h.Spec.Alias] = i
+	}
+	slices.SortStableFunc(results, func(a, b problem) int {
+		return cmp.Compare(order[a.inventory+":"+string(a.protocol)+":"+a.alias], order[b.inventory+":"+string(b.protocol)+":"+b.alias])
+	})
+	return results
+}
+
+// withDefaultPort fills in the protocol's default port, so a dial has one.
+func withDefaultPort(t connect.Target) connect.Target {
+	if t.Port == "" {
+		info, _ := config.LookupProtocol(t.Protocol)
+		t.Port = info.DefaultPort
+	}
+	return t
+}
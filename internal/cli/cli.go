@@ -0,0 +1,276 @@
+// Package cli implements the menu's non-interactive subcommands (list, show,
+// add, connect, …) on top of the config and connect packages.
+//
+// Output meant for scripts goes to stdout; errors and notes go to stderr.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/settings"
+)
+
+// Exit codes returned by Run. A connect returns the session's own exit code
+// instead when it has one (eg. ssh's or the remote command's), and
+// ExitConnectOffset plus one of these for its own errors.
+const (
+	ExitOK        = 0 // success
+	ExitFailure   = 1 // the command failed (eg. a config couldn't be written, check found problems)
+	ExitUsage     = 2 // bad arguments or flags
+	ExitNotFound  = 3 // no host matches
+	ExitAmbiguous = 4 // several hosts match and none was chosen
+)
+
+// ExitConnectOffset moves a connect's own exit codes (251-254) out of the way
+// of the session's, which are passed on unchanged: a remote "exit 3" isn't
+// taken for ExitNotFound. Shells and ssh don't use 251-254 (ssh's own errors
+// are 255).
+const ExitConnectOffset = 250
+
+// cli holds the streams a subcommand writes to.
+type cli struct {
+	stdout      io.Writer         // machine-readable output
+	stderr      io.Writer         // errors, notes and usage
+	session     bool              // a connect's session ran, so its exit code is the session's
+	cmd         command           // the running subcommand, for its usage
+	settings    settings.Settings // effective settings
+	settingsErr error             // problems loading the settings
+}
+
+// command is a subcommand.
+type command struct {
+	name    string                          // subcommand name
+	args    string                          // argument synopsis
+	summary string                          // one-line description
+	run     func(c *cli, args []string) int // runs the subcommand, returning the exit code
+}
+
+// commands are the subcommands, in the order the usage lists them.
+var commands = []command{
+	{"list", "[--json] [-p protocol] [-i inventory] [-g group]", "list hosts, one per line", (*cli).runList},
+	{"show", "[--json] [-p protocol] [-i inventory] ALIAS", "show a host's config block", (*cli).runShow},
+	{"add", "[-p protocol] [-i inventory] [field flags] ALIAS", "add a host", (*cli).runAdd},
+	{"edit", "[-p protocol] [-i inventory] [--alias NEW] [field flags] ALIAS", "change a host's fields", (*cli).runEdit},
+	{"rm", "[-p protocol] [-i inventory] ALIAS...", "remove hosts", (*cli).runRemove},
+	{"connect", "[-p protocol] [-l user] [--no-preflight] QUERY", "connect to the host matching QUERY", (*cli).runConnect},
+	{"export", "[--json] [-p protocol] [ALIAS|GROUP...]", "print hosts as config blocks or JSON", (*cli).runExport},
+	{"import", "[--json|--format name] [-p protocol] [-i inventory] [--replace] [--dry-run] FILE", "add hosts from a config, JSON or session export", (*cli).runImport},
+	{"check", "[--dial] [--timeout 5s]", "check the configs for problems", (*cli).runCheck},
+	{"config", "[--json]", "print the effective settings", (*cli).runConfig},
+}
+
+// Run runs the subcommand named by args[0] and returns the process exit code.
+// Without one, the interactive menu runs.
+//
+// Anything else is a direct connect ("menu web3", "menu -l root web3"): the
+// connect flags and a QUERY, with several matches opening the menu filtered
+// to QUERY.
+//
+// Leading --settings FILE, --set KEY=VALUE and --config SPEC flags apply to
+// everything.
+func Run(args []string, stdout, stderr io.Writer) int {
+	c := &cli{stdout: stdout, stderr: stderr}
+	args, path, sets, err := globalFlags(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "menu: %v\n", err)
+		return ExitUsage
+	}
+	c.settings, c.settingsErr = settings.Load(path, sets)
+	if err := settings.Apply(c.settings); err != nil {
+		c.settingsErr = errors.Join(c.settingsErr, err)
+	}
+	if len(args) == 0 {
+		return c.openMenu("")
+	}
+	// the menu shows settings problems itself; commands report them here
+	if c.settingsErr != nil && args[0] != "config" {
+		fmt.Fprintf(stderr, "menu: %v\n", c.settingsErr)
+	}
+
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			if cmd, ok := lookupCommand(args[1]); ok {
+				c.cmd = cmd
+				return cmd.run(c, []string{"-h"})
+			}
+		}
+		c.usage()
+		return ExitOK
+	}
+
+	cmd, ok := lookupCommand(name)
+	if !ok {
+		c.cmd, _ = lookupCommand("connect")
+		return c.runDirect(args)
+	}
+	c.cmd = cmd
+	return cmd.run(c, args[1:])
+}
+
+// globalFlags removes the leading --settings, --set and --config flags from
+// args, returning the settings file (if given) and the KEY=VALUE overrides.
+// --config SPEC is short for --set paths.inventories+=SPEC.
+func globalFlags(args []string) (rest []string, path string, sets []string, err error) {
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(args[0], "=")
+		name = "-" + strings.TrimLeft(name, "-")
+		if !strings.HasPrefix(args[0], "-") || !slices.Contains([]string{"-set", "-settings", "-config"}, name) {
+			break
+		}
+		args = args[1:]
+		if !hasValue {
+			if len(args) == 0 {
+				return nil, "", nil, fmt.Errorf("flag needs an argument: %s", name)
+			}
+			value, args = args[0], args[1:]
+		}
+		switch name {
+		case "-settings":
+			path = value
+		case "-config":
+			sets = append(sets, "paths.inventories+="+value)
+		default:
+			sets = append(sets, value)
+		}
+	}
+	return args, path, sets, nil
+}
+
+// lookupCommand returns the subcommand with the name.
+func lookupCommand(name string) (command, bool) {
+	i := slices.IndexFunc(commands, func(cmd command) bool { return cmd.name == name })
+	if i < 0 {
+		return command{}, false
+	}
+	return commands[i], true
+}
+
+// usage prints the list of subcommands.
+func (c *cli) usage() {
+	fmt.Fprintln(c.stderr, "Usage: menu [--settings FILE] [--set KEY=VALUE]... [--config SPEC]... [command] [flags] [args]")
+	fmt.Fprintln(c.stderr, "       menu [-p protocol] [-l user] [--no-preflight] QUERY")
+	fmt.Fprintln(c.stderr, "\nWithout arguments the interactive menu starts. A QUERY connects to the host it")
+	fmt.Fprintln(c.stderr, "matches, like connect, or opens the menu searching for it if several match. Flags")
+	fmt.Fprintln(c.stderr, "may also follow the QUERY or other arguments (eg. menu web3 -l root).\n\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(c.stderr, "\nRun 'menu help COMMAND' for a command's flags, and 'menu config' for the settings")
+	fmt.Fprintln(c.stderr, "--set (eg. --set preflight.timeout=20s) and --settings (another settings file) change.")
+	fmt.Fprintln(c.stderr, "--config [NAME=][ro:][PROTOCOL:]PATH reads hosts from another config too.")
+	fmt.Fprintf(c.stderr, "\nExit codes: %d ok, %d failed, %d usage, %d no match, %d ambiguous match. A connect\n",
+		ExitOK, ExitFailure, ExitUsage, ExitNotFound, ExitAmbiguous)
+	fmt.Fprintf(c.stderr, "exits with the session's code, and %d plus one of these for its own errors\n", ExitConnectOffset)
+	fmt.Fprintf(c.stderr, "(eg. %d no match).\n", ExitConnectOffset+ExitNotFound)
+}
+
+// flags returns a flag set for the running subcommand that reports errors
+// to stderr. -h prints the subcommand's usage and flags.
+func (c *cli) flags() *flag.FlagSet {
+	cmd := c.cmd
+	fs := flag.NewFlagSet("menu "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: menu %s %s\n\n%s.\n", cmd.name, cmd.args, capitalize(cmd.summary))
+		if hasFlags(fs) {
+			fmt.Fprintln(c.stderr, "\nFlags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// hasFlags reports whether fs defines any flags.
+func hasFlags(fs *flag.FlagSet) bool {
+	n := 0
+	fs.VisitAll(func(*flag.Flag) { n++ })
+	return n > 0
+}
+
+// protocolFlag adds -p/-protocol to fs.
+func protocolFlag(fs *flag.FlagSet, usage string) *string {
+	p := new(string)
+	fs.StringVar(p, "protocol", "", usage)
+	fs.StringVar(p, "p", "", "shorthand for -protocol")
+	return p
+}
+
+// inventoryFlag adds -i/-inventory to fs.
+func inventoryFlag(fs *flag.FlagSet, usage string) *string {
+	i := new(string)
+	fs.StringVar(i, "inventory", "", usage)
+	fs.StringVar(i, "i", "", "shorthand for -inventory")
+	return i
+}
+
+// parseArgs parses fs from args, allowing flags after positional arguments
+// (eg. "show web --json"), and returns the positional arguments. Everything
+// after "--" is positional.
+//
+// It returns the exit code to stop with if parsing fails or help was asked for.
+func parseArgs(fs *flag.FlagSet, args []string) (pos []string, code int, ok bool) {
+	var rest []string
+	if i := slices.Index(args, "--"); i >= 0 {
+		args, rest = args[:i], args[i+1:]
+	}
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, ExitOK, false
+			}
+			return nil, ExitUsage, false
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return append(pos, rest...), ExitOK, true
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+// parseProtocol returns the registered protocol named s ("" for any).
+func parseProtocol(s string) (config.Protocol, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return "", nil
+	}
+	if _, ok := config.LookupProtocol(config.Protocol(s)); ok {
+		return config.Protocol(s), nil
+	}
+	var names []string
+	for _, p := range config.Protocols() {
+		names = append(names, string(p.Name))
+	}
+	return "", fmt.Errorf("unknown protocol %q (one of %s)", s, strings.Join(names, ", "))
+}
+
+// errorf prints an error to stderr and returns code.
+func (c *cli) errorf(code int, format string, args ...any) int {
+	fmt.Fprintf(c.stderr, "menu: "+format+"\n", args...)
+	return code
+}
+
+// usageError prints an error and where to find the subcommand's usage, and
+// returns ExitUsage.
+func (c *cli) usageError(format string, args ...any) int {
+	fmt.Fprintf(c.stderr, "menu: "+format+"\n", args...)
+	fmt.Fprintf(c.stderr, "Run 'menu help %s' for usage.\n", c.cmd.name)
+	return ExitUsage
+}
+
+// capitalize uppercases the first letter of s.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
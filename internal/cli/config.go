@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"fmt"
+
+	"bubbletea-ssh-manager/internal/settings"
+)
+
+// settingJSON is a setting as printed by config --json.
+type settingJSON struct {
+	Key    string          `json:"key"`
+	Value  string          `json:"value"`
+	Source settings.Source `json:"source"`
+	Env    string          `json:"env"`
+}
+
+// runConfig prints the effective settings as a settings file, noting where
+// each value that isn't a default came from, or as JSON.
+//
+// Problems in the settings go to stderr, and make the exit code ExitFailure.
+func (c *cli) runConfig(args []string) int {
+	fs := c.flags()
+	asJSON := fs.Bool("json", false, "print a JSON array")
+	pos, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	if len(pos) > 0 {
+		return c.usageError("unexpected argument %q", pos[0])
+	}
+
+	if *asJSON {
+		var out []settingJSON
+		for _, k := range settings.Keys() {
+			out = append(out, settingJSON{Key: k.Name, Value: c.settings.Get(k), Source: c.settings.Source(k), Env: k.Env()})
+		}
+		if err := writeJSON(c.stdout, out); err != nil {
+			return c.errorf(ExitFailure, "%v", err)
+		}
+	} else {
+		fmt.Fprint(c.stdout, c.settings.Format())
+	}
+	if c.settingsErr != nil {
+		return c.errorf(ExitFailure, "%v", c.settingsErr)
+	}
+	return ExitOK
+}
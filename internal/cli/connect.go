@@ -0,0 +1,329 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/hooks"
+	"bubbletea-ssh-manager/internal/record"
+	"bubbletea-ssh-manager/internal/tui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/x/term"
+)
+
+// maxChoices is the most hosts the chooser offers for an ambiguous match.
+const maxChoices = 20
+
+// runConnect connects to a host. QUERY is matched the way matchHosts
+// matches it; several matches open a chooser when run from a terminal.
+//
+// The exit code is the session's (eg. ssh's or the remote shell's), or
+// connectExit's for the connect's own errors.
+func (c *cli) runConnect(args []string) int {
+	return c.connectExit(c.connectQuery(args, false))
+}
+
+// runDirect handles "menu QUERY": it connects like runConnect, but opens the
+// menu filtered to QUERY when several hosts match.
+func (c *cli) runDirect(args []string) int {
+	return c.connectExit(c.connectQuery(args, true))
+}
+
+// connectExit returns the exit code for a connect that ended with code: the
+// session's code as is, or the connect's own moved up by ExitConnectOffset,
+// so the two can't be confused.
+func (c *cli) connectExit(code int) int {
+	if c.session || code == ExitOK {
+		return code
+	}
+	return ExitConnectOffset + code
+}
+
+// connectQuery parses the connect flags and QUERY from args, picks the host
+// and connects to it. With openMenu, an ambiguous QUERY opens the menu
+// filtered to it instead of the chooser.
+func (c *cli) connectQuery(args []string, openMenu bool) int {
+	fs := c.flags()
+	protocolName := protocolFlag(fs, "only match hosts of this protocol")
+	user := fs.String("l", "", "log in as this user instead of the host's")
+	noPreflight := fs.Bool("no-preflight", false, "connect without first checking that the host answers")
+	pos, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	if len(pos) != 1 {
+		return c.usageError("expected one alias or search")
+	}
+	protocol, err := parseProtocol(*protocolName)
+	if err != nil {
+		return c.usageError("%v", err)
+	}
+	query := pos[0]
+
+	hosts := c.loadHosts()
+	matches, unique := matchHosts(filterProtocol(hosts, protocol), query)
+	var h config.Host
+	switch {
+	case len(matches) == 0:
+		code := c.errorf(ExitNotFound, "no host matches %q", query)
+		if openMenu {
+			fmt.Fprintln(c.stderr, "Run 'menu help' for the list of commands.")
+		}
+		return code
+	case unique:
+		h = matches[0]
+	case openMenu && term.IsTerminal(os.Stdin.Fd()) && term.IsTerminal(os.Stdout.Fd()):
+		return c.openMenu(query)
+	default:
+		if h, code = c.chooseHost(matches, query); code != ExitOK {
+			return code
+		}
+	}
+
+	if u := strings.TrimSpace(*user); u != "" {
+		h.Spec.User = u
+	}
+	return c.connectHost(h, hosts, !*noPreflight)
+}
+
+// openMenu runs the interactive menu, with its search set to query if not
+// empty.
+func (c *cli) openMenu(query string) int {
+	opts := []tui.Option{tui.WithSettings(c.settings, c.settingsErr)}
+	if query != "" {
+		opts = append(opts, tui.WithQuery(query))
+	}
+	p := tea.NewProgram(tui.NewModel(opts...), tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		return c.errorf(ExitFailure, "%v", err)
+	}
+	return ExitOK
+}
+
+// chooseHost asks which of the matches to connect to. Without a terminal to
+// ask on, it lists them and returns ExitAmbiguous.
+func (c *cli) chooseHost(matches []config.Host, query string) (config.Host, int) {
+	if !term.IsTerminal(os.Stdin.Fd()) || !term.IsTerminal(os.Stderr.Fd()) {
+		c.errorf(ExitAmbiguous, "%d hosts match %q:", len(matches), query)
+		for _, h := range matches {
+			fmt.Fprintln(c.stderr, "  "+hostLine(h))
+		}
+		return config.Host{}, ExitAmbiguous
+	}
+	matches = matches[:min(maxChoices, len(matches))]
+	opts := make([]huh.Option[int], 0, len(matches))
+	for i, h := range matches {
+		opts = append(opts, huh.NewOption(fmt.Sprintf("%s (%s) %s", h.Spec.Alias, hostWhere(h), hostAddress(h)), i))
+	}
+	var picked int
+	err := huh.NewSelect[int]().
+		Title(fmt.Sprintf("Several hosts match %q", query)).
+		Options(opts...).
+		Value(&picked).
+		WithTheme(huh.ThemeCharm()).
+		Run()
+	if err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return config.Host{}, ExitAmbiguous
+		}
+		return config.Host{}, c.errorf(ExitFailure, "%v", err)
+	}
+	return matches[picked], ExitOK
+}
+
+// hostAddress returns where a host connects to, for the chooser.
+func hostAddress(h config.Host) string {
+	addr := h.Spec.HostName
+	if addr == "" {
+		addr = h.SerialOptions.Device
+	}
+	if addr != "" && h.Spec.Port != "" {
+		addr += ":" + h.Spec.Port
+	}
+	if addr != "" && h.Spec.User != "" {
+		addr = h.Spec.User + "@" + addr
+	}
+	return addr
+}
+
+// connectHost runs a session to h in this terminal, with its hooks, login
+// script and recording, and returns the session's exit code. With
+// preflight, hosts whose protocol has a reachability check are checked
+// first, as the menu does.
+func (c *cli) connectHost(h config.Host, hosts []config.Host, preflight bool) int {
+	t, err := targetFor(h, hosts)
+	if err != nil {
+		return c.errorf(ExitFailure, "%v", err)
+	}
+	cfg, err := hooks.Load()
+	if err != nil {
+		return c.errorf(ExitFailure, "hooks: %v", err)
+	}
+	pre, post := cfg.For(h.Spec.Alias, h.AppOptions)
+
+	// ctrl+c cancels the pre-connect hooks and preflight; once the session
+	// starts it's the session's to handle, and this process waits for it to end
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err = hooks.Run(ctx, pre, hooks.NewEnv(hooks.Pre, t))
+	if err != nil {
+		stop()
+		return c.errorf(ExitFailure, "%v", err)
+	}
+	if preflight && connect.ShouldPreflight(t) {
+		if code := c.preflight(ctx, t, connect.DefaultPreflightPolicy().ForHost(h.AppOptions)); code != ExitOK {
+			stop()
+			return code
+		}
+	}
+	stop()
+
+	capture, err := record.NewCapture(t.Alias, t.WindowTitle(), h.AppOptions.Record, h.AppOptions.Log)
+	if err != nil {
+		return c.errorf(ExitFailure, "session capture: %v", err)
+	}
+	cmd, tgt, tail, err := connect.BuildCommand(t, capture.Writers()...)
+	if err != nil {
+		return c.errorf(ExitFailure, "%v", err)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	started := time.Now()
+	err = cmd.Run()
+	signal.Stop(sig)
+
+	recording, logPath := capture.Close()
+	if recording != "" {
+		fmt.Fprintln(c.stderr, "Recorded to "+recording)
+	}
+	if logPath != "" {
+		fmt.Fprintln(c.stderr, "Logged to "+logPath)
+	}
+
+	env := hooks.NewEnv(hooks.Post, tgt)
+	env.Duration = time.Since(started)
+	env.Err = err
+	if hookErr := hooks.Run(context.Background(), post, env); hookErr != nil {
+		fmt.Fprintf(c.stderr, "menu: %s: %v\n", tgt.Alias, hookErr)
+	}
+	return c.sessionExit(tgt, tail, err)
+}
+
+// preflight checks that t answers before connecting, retrying a flaky link
+// as policy allows. For ssh it also checks the host key, and refuses to
+// connect if the key changed or was revoked.
+//
+// It returns ExitOK if the session can start.
+func (c *cli) preflight(ctx context.Context, t connect.Target, policy connect.PreflightPolicy) int {
+	hostPort := connect.GenerateHostPort(t)
+	if hostPort == "" {
+		return c.errorf(ExitFailure, "%s: missing hostname", t.Protocol)
+	}
+	if term.IsTerminal(os.Stderr.Fd()) {
+		via := ""
+		if hop, ok := t.FirstHop(); ok {
+			via = " via " + hop.Alias
+		}
+		fmt.Fprintf(c.stderr, "Checking %s%s (%s)…\n", hostPort, via, policy)
+	}
+
+	info, _ := config.LookupProtocol(t.Protocol)
+	knownHost, knownPort := connect.KnownHostsName(t)
+	var (
+		res *connect.SSHPreflight
+		err error
+	)
+	attempt := 1
+	for {
+		actx, cancel := context.WithTimeout(ctx, policy.Timeout)
+		if info.Preflight == config.PreflightSSH {
+			files, _ := connect.KnownHostsFiles(t.Alias)
+			var r connect.SSHPreflight
+			r, err = connect.PreflightSSH(actx, hostPort, knownHost, knownPort, files)
+			res = &r
+		} else {
+			err = connect.PreflightDial(actx, hostPort)
+		}
+		cancel()
+		if !connect.IsRetryablePreflightError(err) || attempt >= policy.Attempts() {
+			break
+		}
+		delay := policy.Delay(attempt)
+		attempt++
+		fmt.Fprintf(c.stderr, "menu: %s: %v; retrying in %s (attempt %d of %d)\n", hostPort, err, delay,
+			attempt, policy.Attempts())
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-time.After(delay):
+			continue
+		}
+		break
+	}
+
+	if ctx.Err() != nil {
+		return c.errorf(ExitFailure, "%s to %s aborted", t.Protocol, t.Display())
+	}
+	if err != nil {
+		tries := ""
+		if attempt > 1 {
+			tries = fmt.Sprintf(" after %d attempts", attempt)
+		}
+		return c.errorf(ExitFailure, "%s %s failed%s: %v", t.Protocol, hostPort, tries, err)
+	}
+	if res != nil {
+		if f := res.Problem(knownHost); f != nil {
+			c.errorf(ExitFailure, "%s to %s failed: %s", t.Protocol, t.Display(), f.Summary)
+			fmt.Fprintln(c.stderr, f.Explanation)
+			fmt.Fprintln(c.stderr, "Fix: "+f.Suggestion)
+			return ExitFailure
+		}
+		fmt.Fprintln(c.stderr, "menu: "+res.Note())
+	}
+	return ExitOK
+}
+
+// sessionExit reports a failed session and returns its exit code, marking it
+// as the session's when it has one.
+func (c *cli) sessionExit(t connect.Target, tail *connect.TailBuffer, err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	code := connect.ExitCode(err)
+	if connect.IsConnectionAborted(err) {
+		c.session = code >= 0
+		return c.errorf(max(code, ExitFailure), "%s to %s aborted", t.Protocol, t.Display())
+	}
+	output := ""
+	if tail != nil {
+		output = strings.TrimSpace(tail.String())
+	}
+	if f := connect.ClassifyFailure(t.Protocol, output, err); f != nil {
+		c.errorf(ExitFailure, "%s to %s failed: %s", t.Protocol, t.Display(), f.Summary)
+		fmt.Fprintln(c.stderr, f.Explanation)
+		if f.Suggestion != "" {
+			fmt.Fprintln(c.stderr, "Fix: "+f.Suggestion)
+		}
+		if hop, ok := t.FailedHop(f); ok && hop >= 0 {
+			fmt.Fprintf(c.stderr, "This was jump host %s, not %s.\n", t.Jumps[hop].Alias, t.Alias)
+		}
+	} else if code < 0 {
+		c.errorf(ExitFailure, "%s to %s: %v", t.Protocol, t.Display(), err)
+	}
+	// an exit code is the session's own (eg. the remote shell's), so it's
+	// passed on without a message unless it was recognized above
+	if code < 0 {
+		return ExitFailure
+	}
+	c.session = true
+	return code
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+func TestConnectExitCodes(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("XDG_DATA_HOME", home)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"connect no match", []string{"connect", "nosuchhost"}, ExitConnectOffset + ExitNotFound},
+		{"direct no match", []string{"nosuchhost"}, ExitConnectOffset + ExitNotFound},
+		{"connect usage", []string{"connect"}, ExitConnectOffset + ExitUsage},
+		{"connect help", []string{"connect", "-h"}, ExitOK},
+		{"show no match", []string{"show", "nosuchhost"}, ExitNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := Run(tt.args, &stdout, &stderr); got != tt.want {
+				t.Errorf("Run(%q) = %d, want %d; stderr:\n%s", tt.args, got, tt.want, stderr.String())
+			}
+		})
+	}
+}
+
+func TestConnectExitSession(t *testing.T) {
+	c := &cli{}
+	if got := c.connectExit(ExitNotFound); got != ExitConnectOffset+ExitNotFound {
+		t.Errorf("connect's own ExitNotFound = %d, want %d", got, ExitConnectOffset+ExitNotFound)
+	}
+	c.session = true
+	if got := c.connectExit(3); got != 3 {
+		t.Errorf("session's exit 3 = %d, want 3", got)
+	}
+}
+
+func TestConnectFlagsAfterQuery(t *testing.T) {
+	c := &cli{}
+	fs := c.flags()
+	user := fs.String("l", "", "")
+	noPreflight := fs.Bool("no-preflight", false, "")
+	pos, _, ok := parseArgs(fs, []string{"web3", "-l", "root", "--no-preflight"})
+	if !ok || len(pos) != 1 || pos[0] != "web3" || *user != "root" || !*noPreflight {
+		t.Errorf("parseArgs = %q, %v; -l %q, --no-preflight %v", pos, ok, *user, *noPreflight)
+	}
+}
+
+func TestMatchHostsNickname(t *testing.T) {
+	host := func(alias string) config.Host {
+		var h config.Host
+		h.Spec.Alias = alias
+		return h
+	}
+	hosts := []config.Host{host("prod.Web3"), host("dev.web30"), host("web3-old")}
+
+	matches, unique := matchHosts(hosts, "WEB3")
+	if !unique || len(matches) != 1 || matches[0].Spec.Alias != "prod.Web3" {
+		t.Errorf("matchHosts(WEB3) = %v, unique %v; want prod.Web3 by its nickname", aliases(matches), unique)
+	}
+}
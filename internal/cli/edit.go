@@ -0,0 +1,433 @@
+package cli
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/importer"
+)
+
+// hostField is a host field that add and edit set from a flag.
+type hostField struct {
+	name   string                              // flag name
+	usage  string                              // flag description
+	str    func(e *config.HostEntry) *string   // the field, for string fields
+	list   func(e *config.HostEntry) *[]string // the field, for list fields (the flag is repeated)
+	toggle func(e *config.HostEntry) *bool     // the field, for yes/no fields
+	only   func(info config.ProtocolInfo) bool // protocols the field applies to (nil for all)
+}
+
+// hasField returns a hostField.only func for protocols whose form offers f.
+func hasField(f config.Field) func(config.ProtocolInfo) bool {
+	return func(info config.ProtocolInfo) bool { return info.Has(f) }
+}
+
+// hostFields are the fields add and edit set, in the order -h lists them.
+var hostFields = []hostField{
+	{name: "hostname", usage: "host name or IP address",
+		str: func(e *config.HostEntry) *string { return &e.Spec.HostName }, only: hasField(config.FieldHostName)},
+	{name: "port", usage: "port number",
+		str: func(e *config.HostEntry) *string { return &e.Spec.Port }, only: hasField(config.FieldPort)},
+	{name: "user", usage: "user name",
+		str: func(e *config.HostEntry) *string { return &e.Spec.User }, only: hasField(config.FieldUser)},
+	{name: "proxy-jump", usage: "ssh ProxyJump (eg. bastion or admin@jump:2222)",
+		str: func(e *config.HostEntry) *string { return &e.SSHOptions.ProxyJump }, only: hasField(config.FieldSSHOptions)},
+	{name: "identity-file", usage: "ssh IdentityFile; repeat for several, or give \"\" to clear",
+		list: func(e *config.HostEntry) *[]string { return &e.SSHOptions.IdentityFiles }, only: hasField(config.FieldSSHOptions)},
+	{name: "host-key-algorithms", usage: "ssh HostKeyAlgorithms",
+		str: func(e *config.HostEntry) *string { return &e.SSHOptions.HostKeyAlgorithms }, only: hasField(config.FieldSSHOptions)},
+	{name: "kex-algorithms", usage: "ssh KexAlgorithms",
+		str: func(e *config.HostEntry) *string { return &e.SSHOptions.KexAlgorithms }, only: hasField(config.FieldSSHOptions)},
+	{name: "ciphers", usage: "ssh Ciphers",
+		str: func(e *config.HostEntry) *string { return &e.SSHOptions.Ciphers }, only: hasField(config.FieldSSHOptions)},
+	{name: "macs", usage: "ssh MACs",
+		str: func(e *config.HostEntry) *string { return &e.SSHOptions.MACs }, only: hasField(config.FieldSSHOptions)},
+	{name: "term-type", usage: "telnet terminal type (eg. VT100)",
+		str: func(e *config.HostEntry) *string { return &e.TelnetOptions.TermType }, only: hasField(config.FieldTermType)},
+	{name: "command", usage: "command template (eg. \"nc {host} {port}\")",
+		str: func(e *config.HostEntry) *string { return &e.CommandOptions.Command }, only: hasField(config.FieldCommand)},
+	{name: "device", usage: "serial device (eg. /dev/ttyUSB0 or COM3)",
+		str: func(e *config.HostEntry) *string { return &e.SerialOptions.Device }, only: hasField(config.FieldSerial)},
+	{name: "baud", usage: "serial baud rate",
+		str: func(e *config.HostEntry) *string { return &e.SerialOptions.Baud }, only: hasField(config.FieldSerial)},
+	{name: "data-bits", usage: "serial data bits (5 to 8)",
+		str: func(e *config.HostEntry) *string { return &e.SerialOptions.DataBits }, only: hasField(config.FieldSerial)},
+	{name: "parity", usage: "serial parity: none, even or odd",
+		str: func(e *config.HostEntry) *string { return &e.SerialOptions.Parity }, only: hasField(config.FieldSerial)},
+	{name: "stop-bits", usage: "serial stop bits: 1 or 2",
+		str: func(e *config.HostEntry) *string { return &e.SerialOptions.StopBits }, only: hasField(config.FieldSerial)},
+	{name: "flow", usage: "serial flow control: none, rtscts or xonxoff",
+		str: func(e *config.HostEntry) *string { return &e.SerialOptions.Flow }, only: hasField(config.FieldSerial)},
+	{name: "tags", usage: "comma-separated tags",
+		str: func(e *config.HostEntry) *string { return &e.AppOptions.Tags }},
+	{name: "record", usage: "record sessions",
+		toggle: func(e *config.HostEntry) *bool { return &e.AppOptions.Record }},
+	{name: "log", usage: "write session logs",
+		toggle: func(e *config.HostEntry) *bool { return &e.AppOptions.Log }},
+	{name: "preflight-timeout", usage: "preflight timeout (eg. 20s)",
+		str: func(e *config.HostEntry) *string { return &e.AppOptions.PreflightTimeout }},
+	{name: "preflight-retries", usage: "preflight retries",
+		str: func(e *config.HostEntry) *string { return &e.AppOptions.PreflightRetries }},
+	{name: "script", usage: "login script name",
+		str: func(e *config.HostEntry) *string { return &e.AppOptions.Script }},
+	{name: "pre-connect", usage: "local command run before connecting",
+		str: func(e *config.HostEntry) *string { return &e.AppOptions.PreConnect }},
+	{name: "post-connect", usage: "local command run after the session",
+		str: func(e *config.HostEntry) *string { return &e.AppOptions.PostConnect }},
+	{name: "secret", usage: "name of the host's password secret",
+		str: func(e *config.HostEntry) *string { return &e.AppOptions.Secret }},
+}
+
+// bindHostFields adds a flag for every host field to fs, stored in e.
+func bindHostFields(fs *flag.FlagSet, e *config.HostEntry) {
+	for _, f := range hostFields {
+		switch {
+		case f.str != nil:
+			fs.StringVar(f.str(e), f.name, "", f.usage)
+		case f.list != nil:
+			fs.Var(listFlag{f.list(e)}, f.name, f.usage)
+		default:
+			fs.BoolVar(f.toggle(e), f.name, false, f.usage)
+		}
+	}
+}
+
+// listFlag is a flag that may be repeated, collecting every value.
+type listFlag struct {
+	values *[]string
+}
+
+func (f listFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(*f.values, ", ")
+}
+
+func (f listFlag) Set(v string) error {
+	*f.values = append(*f.values, v)
+	return nil
+}
+
+// copySetFields copies the host fields set on the command line from src to
+// dst. It returns an error naming a field the protocol doesn't use.
+func copySetFields(fs *flag.FlagSet, protocol config.Protocol, dst, src *config.HostEntry) (n int, err error) {
+	info, _ := config.LookupProtocol(protocol)
+	fs.Visit(func(fl *flag.Flag) {
+		for _, f := range hostFields {
+			if f.name != fl.Name {
+				continue
+			}
+			if f.only != nil && !f.only(info) {
+				err = errors.Join(err, fmt.Errorf("-%s doesn't apply to %s hosts", f.name, protocol))
+				continue
+			}
+			switch {
+			case f.str != nil:
+				*f.str(dst) = *f.str(src)
+			case f.list != nil:
+				*f.list(dst) = *f.list(src)
+			default:
+				*f.toggle(dst) = *f.toggle(src)
+			}
+			n++
+		}
+	})
+	return n, err
+}
+
+// runAdd adds a host to its protocol's root config, or to the root config of
+// another inventory.
+func (c *cli) runAdd(args []string) int {
+	fs := c.flags()
+	protocolName := protocolFlag(fs, "the host's protocol (default ssh)")
+	inventory := inventoryFlag(fs, "the inventory to add the host to (default local)")
+	var set config.HostEntry
+	bindHostFields(fs, &set)
+	pos, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	if len(pos) != 1 {
+		return c.usageError("expected one alias (eg. web or prod.web)")
+	}
+	protocol, err := parseProtocol(*protocolName)
+	if err != nil {
+		return c.usageError("%v", err)
+	}
+	if protocol == "" {
+		protocol = config.ProtocolSSH
+	}
+	inv, err := config.LookupInventory(strings.TrimSpace(*inventory), protocol)
+	if err != nil {
+		return c.usageError("%v", err)
+	}
+
+	alias, err := normalizeAlias(pos[0])
+	if err != nil {
+		return c.usageError("%v", err)
+	}
+	h := config.Host{Protocol: protocol, Inventory: inv}
+	if _, err := copySetFields(fs, protocol, &h.HostEntry, &set); err != nil {
+		return c.usageError("%v", err)
+	}
+	h.Spec.Alias = alias
+	h.HostEntry = h.HostEntry.Normalized()
+	if err := connect.ValidateHost(h); err != nil {
+		return c.errorf(ExitUsage, "%s: %v", alias, err)
+	}
+	if len(findAlias(filterInventory(filterProtocol(c.loadHosts(), protocol), inv.Name), alias)) > 0 {
+		return c.errorf(ExitFailure, "%s host %q already exists", protocol, alias)
+	}
+
+	if err := inv.AddHost(h.HostEntry); err != nil {
+		return c.errorf(ExitFailure, "%v", err)
+	}
+	fmt.Fprintf(c.stdout, "added\t%s\t%s\n", protocol, alias)
+	return ExitOK
+}
+
+// runEdit changes the fields of a host set on the command line, in the file
+// that defines it.
+func (c *cli) runEdit(args []string) int {
+	fs := c.flags()
+	protocolName := protocolFlag(fs, "the host's protocol, if the alias is used by several")
+	inventory := inventoryFlag(fs, "the host's inventory, if the alias is used by several")
+	newAlias := fs.String("alias", "", "rename the host")
+	var set config.HostEntry
+	bindHostFields(fs, &set)
+	pos, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	if len(pos) != 1 {
+		return c.usageError("expected one alias")
+	}
+	protocol, err := parseProtocol(*protocolName)
+	if err != nil {
+		return c.usageError("%v", err)
+	}
+
+	hosts := c.loadHosts()
+	h, code := c.findHost(filterInventory(hosts, *inventory), pos[0], protocol)
+	if code != ExitOK {
+		return code
+	}
+	if err := h.Inventory.Writable(); err != nil {
+		return c.errorf(ExitFailure, "%s: %v", h.Spec.Alias, err)
+	}
+	oldAlias := h.Spec.Alias
+	n, err := copySetFields(fs, h.Protocol, &h.HostEntry, &set)
+	if err != nil {
+		return c.usageError("%v", err)
+	}
+	if *newAlias != "" {
+		if h.Spec.Alias, err = normalizeAlias(*newAlias); err != nil {
+			return c.usageError("%v", err)
+		}
+		n++
+	}
+	if n == 0 {
+		return c.usageError("nothing to change")
+	}
+	h.HostEntry = h.HostEntry.Normalized()
+	if err := connect.ValidateHost(h); err != nil {
+		return c.errorf(ExitUsage, "%s: %v", h.Spec.Alias, err)
+	}
+	if !strings.EqualFold(h.Spec.Alias, oldAlias) &&
+		len(findAlias(filterInventory(filterProtocol(hosts, h.Protocol), h.Inventory.Name), h.Spec.Alias)) > 0 {
+		return c.errorf(ExitFailure, "%s host %q already exists", h.Protocol, h.Spec.Alias)
+	}
+
+	if err := h.Inventory.UpdateHost(oldAlias, h.HostEntry); err != nil {
+		return c.errorf(ExitFailure, "%s: %v", oldAlias, err)
+	}
+	fmt.Fprintf(c.stdout, "updated\t%s\t%s\n", h.Protocol, h.Spec.Alias)
+	return ExitOK
+}
+
+// runRemove removes hosts from the files that define them. It carries on past
+// a failure and returns the first failure's exit code.
+func (c *cli) runRemove(args []string) int {
+	fs := c.flags()
+	protocolName := protocolFlag(fs, "the hosts' protocol, if an alias is used by several")
+	inventory := inventoryFlag(fs, "the hosts' inventory, if an alias is used by several")
+	pos, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	if len(pos) == 0 {
+		return c.usageError("expected at least one alias")
+	}
+	protocol, err := parseProtocol(*protocolName)
+	if err != nil {
+		return c.usageError("%v", err)
+	}
+
+	hosts := filterInventory(c.loadHosts(), *inventory)
+	result := ExitOK
+	for _, alias := range pos {
+		h, code := c.findHost(hosts, alias, protocol)
+		if code == ExitOK {
+			if err := h.Inventory.RemoveHost(h.Spec.Alias); err != nil {
+				code = c.errorf(ExitFailure, "%s: %v", h.Spec.Alias, err)
+			} else {
+				fmt.Fprintf(c.stdout, "removed\t%s\t%s\n", h.Protocol, h.Spec.Alias)
+			}
+		}
+		if result == ExitOK {
+			result = code
+		}
+	}
+	return result
+}
+
+// runImport adds the hosts in a config file (of one protocol), a JSON file
+// from export --json or another client's session export (see importer) to
+// the local configs or another inventory. Hosts whose alias exists there are
+// skipped, or replaced with --replace. The hosts are written together: if
+// one can't be, none is.
+//
+// Each host gets a line on stdout: what happened, protocol, alias and (for
+// skipped or failed hosts) why.
+func (c *cli) runImport(args []string) int {
+	fs := c.flags()
+	asJSON := fs.Bool("json", false, "read a JSON array from export --json (FILE may be - for stdin)")
+	format := fs.String("format", "", "the file's format: config, json, "+strings.Join(importer.FormatNames(), ", ")+
+		" (default detected, or config)")
+	protocolName := protocolFlag(fs, "the protocol of a config file's hosts (default ssh)")
+	inventory := inventoryFlag(fs, "the inventory to import the hosts to (default local)")
+	replace := fs.Bool("replace", false, "replace hosts whose alias exists")
+	dryRun := fs.Bool("dry-run", false, "only report what would be imported")
+	pos, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	if len(pos) != 1 {
+		return c.usageError("expected one file")
+	}
+	protocol, err := parseProtocol(*protocolName)
+	if err != nil {
+		return c.usageError("%v", err)
+	}
+
+	name := strings.ToLower(strings.TrimSpace(*format))
+	if *asJSON {
+		name = "json"
+	}
+	if _, ok := importer.LookupFormat(name); !ok && name != "" && name != "json" && name != "config" {
+		return c.usageError("unknown format %q", *format)
+	}
+	if name == "" && pos[0] != "-" {
+		f, ok, err := importer.DetectFormat(pos[0])
+		if err != nil {
+			return c.errorf(ExitFailure, "%v", err)
+		}
+		if ok {
+			name = f.Name
+		}
+	}
+
+	var items []importer.Item
+	switch name {
+	case "json":
+		var hosts []config.Host
+		if hosts, err = readJSONHosts(pos[0]); err == nil {
+			items = importer.HostItems(hosts)
+		}
+	case "", "config":
+		var hosts []config.Host
+		if hosts, err = readConfigHosts(pos[0], cmp.Or(protocol, config.ProtocolSSH)); err == nil {
+			items = importer.HostItems(hosts)
+		}
+	default:
+		if protocol != "" {
+			return c.usageError("-p is only for config files; sessions keep their own protocol")
+		}
+		var sessions []importer.Session
+		if _, sessions, err = importer.ReadSessions(pos[0], name); err == nil {
+			items = importer.SessionItems(sessions)
+		}
+	}
+	if err != nil {
+		return c.errorf(ExitFailure, "%v", err)
+	}
+
+	plan := importer.NewPlan(items, importer.Options{Inventory: *inventory, Replace: *replace, Validate: connect.ValidateHost})
+	if !*dryRun {
+		if err := plan.Apply(); err != nil {
+			return c.errorf(ExitFailure, "import: %v (nothing was written)", err)
+		}
+	}
+
+	result := ExitOK
+	for _, it := range plan.Items {
+		line := importReports[it.Action] + "\t" + cmp.Or(string(it.Host.Protocol), "-") + "\t" +
+			cmp.Or(it.Host.Spec.Alias, it.Source)
+		if it.Reason != "" {
+			line += "\t" + it.Reason
+		}
+		fmt.Fprintln(c.stdout, line)
+		if it.Action == importer.ActionInvalid || it.Action == importer.ActionFail {
+			result = ExitFailure
+		}
+	}
+	return result
+}
+
+// importReports are the words import reports each action with.
+var importReports = map[importer.Action]string{
+	importer.ActionAdd:     "added",
+	importer.ActionReplace: "replaced",
+	importer.ActionSkip:    "skipped",
+	importer.ActionInvalid: "invalid",
+	importer.ActionFail:    "failed",
+}
+
+// readConfigHosts reads the hosts from a config file (and its includes).
+func readConfigHosts(path string, protocol config.Protocol) ([]config.Host, error) {
+	entries, err := config.ParseConfigRecursively(path)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make([]config.Host, 0, len(entries))
+	for _, e := range entries {
+		e.SourcePath = ""
+		hosts = append(hosts, config.Host{Protocol: protocol, HostEntry: e})
+	}
+	return hosts, nil
+}
+
+// readJSONHosts reads the hosts from a JSON array, from stdin if path is "-".
+func readJSONHosts(path string) ([]config.Host, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var records []hostJSON
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	hosts := make([]config.Host, 0, len(records))
+	for _, j := range records {
+		h := j.host()
+		if _, err := parseProtocol(string(h.Protocol)); err != nil {
+			return nil, fmt.Errorf("%s: %w", j.Alias, err)
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
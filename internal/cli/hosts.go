@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	str "bubbletea-ssh-manager/internal/stringutil"
+)
+
+// loadHosts reads every protocol's hosts. Config errors are reported but
+// don't stop the command, like the menu's status message.
+func (c *cli) loadHosts() []config.Host {
+	hosts, err := config.LoadHosts()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "menu: %v\n", err)
+	}
+	return hosts
+}
+
+// filterProtocol returns the hosts of protocol ("" for all).
+func filterProtocol(hosts []config.Host, protocol config.Protocol) []config.Host {
+	if protocol == "" {
+		return hosts
+	}
+	return slices.DeleteFunc(slices.Clone(hosts), func(h config.Host) bool { return h.Protocol != protocol })
+}
+
+// filterInventory returns the hosts of the inventory named name ("" for all).
+func filterInventory(hosts []config.Host, name string) []config.Host {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return hosts
+	}
+	return slices.DeleteFunc(slices.Clone(hosts), func(h config.Host) bool { return h.Inventory.Name != name })
+}
+
+// findAlias returns the hosts whose alias is alias, ignoring case.
+func findAlias(hosts []config.Host, alias string) []config.Host {
+	alias = str.NormalizeString(alias)
+	var out []config.Host
+	for _, h := range hosts {
+		if strings.ToLower(h.Spec.Alias) == alias {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// findHost returns the one host with the alias (of protocol, if set).
+//
+// It reports a missing alias, with close matches, or an alias defined for
+// several protocols or inventories, and returns the exit code to stop with.
+func (c *cli) findHost(hosts []config.Host, alias string, protocol config.Protocol) (config.Host, int) {
+	hosts = filterProtocol(hosts, protocol)
+	matches := findAlias(hosts, alias)
+	switch len(matches) {
+	case 1:
+		return matches[0], ExitOK
+	case 0:
+		msg := fmt.Sprintf("no host %q", alias)
+		if protocol != "" {
+			msg = fmt.Sprintf("no %s host %q", protocol, alias)
+		}
+		if near := rankHosts(hosts, alias); len(near) > 0 {
+			msg += " (did you mean " + strings.Join(aliases(near[:min(3, len(near))]), ", ") + "?)"
+		}
+		return config.Host{}, c.errorf(ExitNotFound, "%s", msg)
+	}
+	var where []string
+	for _, h := range matches {
+		where = append(where, hostWhere(h))
+	}
+	return config.Host{}, c.errorf(ExitAmbiguous, "%q is defined for %s; pick one with -p or -i", alias, strings.Join(where, " and "))
+}
+
+// matchHosts returns the hosts matching query, best match first, and
+// whether the first is a strong enough match to connect to without asking.
+//
+// It tries, in order: the alias, the nickname (the alias without its group),
+// the alias containing query, and the menu's fuzzy search. The first of these
+// that matches anything decides; it's a unique match if it matched one host.
+func matchHosts(hosts []config.Host, query string) ([]config.Host, bool) {
+	q := str.NormalizeString(query)
+	tiers := []func(h config.Host) bool{
+		func(h config.Host) bool { return strings.ToLower(h.Spec.Alias) == q },
+		func(h config.Host) bool {
+			_, nick, ok := str.SplitStringOnDelim(h.Spec.Alias)
+			return ok && strings.ToLower(nick) == q
+		},
+		func(h config.Host) bool { return strings.Contains(strings.ToLower(h.Spec.Alias), q) },
+	}
+	for _, match := range tiers {
+		var matches []config.Host
+		for _, h := range hosts {
+			if match(h) {
+				matches = append(matches, h)
+			}
+		}
+		if len(matches) > 0 {
+			return rankHosts(matches, q), len(matches) == 1
+		}
+	}
+	matches := rankHosts(hosts, q)
+	return matches, len(matches) == 1
+}
+
+// rankHosts returns the hosts matching query, best match first, ranked the
+// way the menu's search ranks them.
+func rankHosts(hosts []config.Host, query string) []config.Host {
+	type scored struct {
+		host  config.Host
+		score int
+	}
+	var matches []scored
+	for _, h := range hosts {
+		if s, ok := str.FuzzyScore(query, searchText(h)); ok {
+			matches = append(matches, scored{host: h, score: s})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b scored) int { return cmp.Compare(b.score, a.score) })
+
+	out := make([]config.Host, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, m.host)
+	}
+	return out
+}
+
+// searchText returns the text the menu's search matches a host against:
+// its display name, protocol, alias, user, hostname and tags.
+func searchText(h config.Host) string {
+	parts := []string{displayName(h.Spec.Alias), string(h.Protocol), h.Spec.Alias}
+	if v := h.Spec.User; v != "" {
+		parts = append(parts, v)
+	}
+	if v := h.Spec.HostName; v != "" {
+		parts = append(parts, v)
+	}
+	parts = append(parts, config.ParseTags(h.AppOptions.Tags)...)
+	return strings.ToLower(strings.Join(parts, " "))
+}
+
+// displayName returns the name the menu shows for an alias.
+func displayName(alias string) string {
+	if _, nick, ok := str.SplitStringOnDelim(alias); ok {
+		return str.FormatDisplayName(nick, false)
+	}
+	return str.FormatDisplayName(alias, false)
+}
+
+// groupOf returns the group part of an alias ("" if ungrouped).
+func groupOf(alias string) string {
+	g, _, _ := str.SplitStringOnDelim(alias)
+	return g
+}
+
+// aliases returns the hosts' aliases.
+func aliases(hosts []config.Host) []string {
+	out := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		out = append(out, h.Spec.Alias)
+	}
+	return out
+}
+
+// sortHosts sorts hosts by alias, then protocol.
+func sortHosts(hosts []config.Host) {
+	slices.SortStableFunc(hosts, func(a, b config.Host) int {
+		return cmp.Or(cmp.Compare(strings.ToLower(a.Spec.Alias), strings.ToLower(b.Spec.Alias)),
+			cmp.Compare(a.Protocol, b.Protocol))
+	})
+}
+
+// inventoryLabel returns the name of the inventory to show with a host: ""
+// for the local configs, which go without saying.
+func inventoryLabel(inv config.Inventory) string {
+	if inv.IsLocal() || inv.Name == "" {
+		return ""
+	}
+	return inv.Name
+}
+
+// hostWhere returns a host's protocol, and its inventory if it isn't local
+// (eg. "ssh in team").
+func hostWhere(h config.Host) string {
+	if inv := inventoryLabel(h.Inventory); inv != "" {
+		return string(h.Protocol) + " in " + inv
+	}
+	return string(h.Protocol)
+}
+
+// targetFor builds the connect.Target for a host, resolving an ssh
+// ProxyJump chain against the other ssh hosts of its inventory.
+func targetFor(h config.Host, hosts []config.Host) (connect.Target, error) {
+	t := connect.Target{Protocol: h.Protocol, Spec: h.Spec, Telnet: h.TelnetOptions, Command: h.CommandOptions,
+		Serial: h.SerialOptions, Script: h.AppOptions.Script, Secret: h.AppOptions.Secret}
+	if h.Protocol != config.ProtocolSSH {
+		return t, nil
+	}
+	if inventoryLabel(h.Inventory) != "" {
+		t.ConfigFile = h.Inventory.Path
+	}
+	if h.SSHOptions.ProxyJump == "" {
+		return t, nil
+	}
+	hosts = slices.DeleteFunc(slices.Clone(hosts), func(o config.Host) bool { return o.Inventory.Name != h.Inventory.Name })
+	jumps, err := connect.ResolveJumpChain(h.SSHOptions.ProxyJump, jumpLookup(hosts))
+	if err != nil {
+		return t, fmt.Errorf("%s: %w", h.Spec.Alias, err)
+	}
+	t.Jumps = jumps
+	return t, nil
+}
+
+// jumpLookup returns a connect.JumpLookup backed by the ssh hosts.
+func jumpLookup(hosts []config.Host) connect.JumpLookup {
+	return func(alias string) (config.Spec, config.SSHOptions, bool) {
+		for _, h := range hosts {
+			if h.Protocol == config.ProtocolSSH && h.Spec.Alias == alias {
+				return h.Spec, h.SSHOptions, true
+			}
+		}
+		return config.Spec{}, config.SSHOptions{}, false
+	}
+}
+
+// normalizeAlias returns alias ("group.nickname" or "nickname") formatted the
+// way the host form writes it.
+func normalizeAlias(alias string) (string, error) {
+	group, nick, ok := strings.Cut(strings.TrimSpace(alias), ".")
+	if !ok {
+		group, nick = "", group
+	}
+	return str.BuildAliasFromGroupNickname(group, nick)
+}
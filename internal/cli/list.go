@@ -0,0 +1,315 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+// hostJSON is a host as printed by list/show/export --json and read by
+// import --json. Empty fields are left out.
+type hostJSON struct {
+	Alias     string   `json:"alias"`
+	Group     string   `json:"group,omitempty"`
+	Name      string   `json:"name"`
+	Protocol  string   `json:"protocol"`
+	HostName  string   `json:"hostname,omitempty"`
+	Port      string   `json:"port,omitempty"`
+	User      string   `json:"user,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Source    string   `json:"source,omitempty"`
+	Inventory string   `json:"inventory,omitempty"` // for hosts outside the local configs
+
+	HostKeyAlgorithms string   `json:"host_key_algorithms,omitempty"`
+	KexAlgorithms     string   `json:"kex_algorithms,omitempty"`
+	Ciphers           string   `json:"ciphers,omitempty"`
+	MACs              string   `json:"macs,omitempty"`
+	ProxyJump         string   `json:"proxy_jump,omitempty"`
+	IdentityFiles     []string `json:"identity_files,omitempty"`
+	TermType          string   `json:"term_type,omitempty"`
+	Command           string   `json:"command,omitempty"`
+
+	Serial *serialJSON `json:"serial,omitempty"`
+
+	Record           bool   `json:"record,omitempty"`
+	Log              bool   `json:"log,omitempty"`
+	PreflightTimeout string `json:"preflight_timeout,omitempty"`
+	PreflightRetries string `json:"preflight_retries,omitempty"`
+	Script           string `json:"script,omitempty"`
+	PreConnect       string `json:"pre_connect,omitempty"`
+	PostConnect      string `json:"post_connect,omitempty"`
+	Secret           string `json:"secret,omitempty"`
+}
+
+// serialJSON is a serial host's line settings.
+type serialJSON struct {
+	Device   string `json:"device,omitempty"`
+	Baud     string `json:"baud,omitempty"`
+	DataBits string `json:"data_bits,omitempty"`
+	Parity   string `json:"parity,omitempty"`
+	StopBits string `json:"stop_bits,omitempty"`
+	Flow     string `json:"flow,omitempty"`
+}
+
+// toJSON returns the JSON form of a host.
+func toJSON(h config.Host) hostJSON {
+	j := hostJSON{
+		Alias:     h.Spec.Alias,
+		Group:     groupOf(h.Spec.Alias),
+		Name:      displayName(h.Spec.Alias),
+		Protocol:  string(h.Protocol),
+		HostName:  h.Spec.HostName,
+		Port:      h.Spec.Port,
+		User:      h.Spec.User,
+		Tags:      config.ParseTags(h.AppOptions.Tags),
+		Source:    h.SourcePath,
+		Inventory: inventoryLabel(h.Inventory),
+
+		HostKeyAlgorithms: h.SSHOptions.HostKeyAlgorithms,
+		KexAlgorithms:     h.SSHOptions.KexAlgorithms,
+		Ciphers:           h.SSHOptions.Ciphers,
+		MACs:              h.SSHOptions.MACs,
+		ProxyJump:         h.SSHOptions.ProxyJump,
+		IdentityFiles:     h.SSHOptions.IdentityFiles,
+		TermType:          h.TelnetOptions.TermType,
+		Command:           h.CommandOptions.Command,
+
+		Record:           h.AppOptions.Record,
+		Log:              h.AppOptions.Log,
+		PreflightTimeout: h.AppOptions.PreflightTimeout,
+		PreflightRetries: h.AppOptions.PreflightRetries,
+		Script:           h.AppOptions.Script,
+		PreConnect:       h.AppOptions.PreConnect,
+		PostConnect:      h.AppOptions.PostConnect,
+		Secret:           h.AppOptions.Secret,
+	}
+	if s := h.SerialOptions; s != (config.SerialOptions{}) {
+		j.Serial = &serialJSON{Device: s.Device, Baud: s.Baud, DataBits: s.DataBits, Parity: s.Parity,
+			StopBits: s.StopBits, Flow: s.Flow}
+	}
+	return j
+}
+
+// host returns the host a JSON record describes. An empty protocol is ssh.
+func (j hostJSON) host() config.Host {
+	h := config.Host{Protocol: config.Protocol(strings.ToLower(j.Protocol))}
+	if h.Protocol == "" {
+		h.Protocol = config.ProtocolSSH
+	}
+	h.Spec = config.Spec{Alias: j.Alias, HostName: j.HostName, Port: j.Port, User: j.User}
+	h.SSHOptions = config.SSHOptions{HostKeyAlgorithms: j.HostKeyAlgorithms, KexAlgorithms: j.KexAlgorithms,
+		Ciphers: j.Ciphers, MACs: j.MACs, ProxyJump: j.ProxyJump, IdentityFiles: j.IdentityFiles}
+	h.TelnetOptions = config.TelnetOptions{TermType: j.TermType}
+	h.CommandOptions = config.CommandOptions{Command: j.Command}
+	if s := j.Serial; s != nil {
+		h.SerialOptions = config.SerialOptions{Device: s.Device, Baud: s.Baud, DataBits: s.DataBits, Parity: s.Parity,
+			StopBits: s.StopBits, Flow: s.Flow}
+	}
+	h.AppOptions = config.AppOptions{Record: j.Record, Log: j.Log, PreflightTimeout: j.PreflightTimeout,
+		PreflightRetries: j.PreflightRetries, Tags: config.FormatTags(j.Tags), Script: j.Script,
+		PreConnect: j.PreConnect, PostConnect: j.PostConnect, Secret: j.Secret}
+	h.HostEntry = h.HostEntry.Normalized()
+	return h
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// hostsJSON returns the JSON form of hosts.
+func hostsJSON(hosts []config.Host) []hostJSON {
+	out := make([]hostJSON, 0, len(hosts))
+	for _, h := range hosts {
+		out = append(out, toJSON(h))
+	}
+	return out
+}
+
+// hostLine returns a host as a tab-separated line: alias, protocol,
+// hostname (or serial device), port, user and tags, with "-" for empty fields.
+func hostLine(h config.Host) string {
+	fields := []string{h.Spec.Alias, string(h.Protocol), h.Spec.HostName, h.Spec.Port, h.Spec.User,
+		config.FormatTags(config.ParseTags(h.AppOptions.Tags))}
+	if fields[2] == "" {
+		fields[2] = h.SerialOptions.Device
+	}
+	for i, f := range fields {
+		if f == "" {
+			fields[i] = "-"
+		}
+	}
+	return strings.Join(fields, "\t")
+}
+
+// runList prints the hosts, optionally limited to a protocol or group.
+func (c *cli) runList(args []string) int {
+	fs := c.flags()
+	asJSON := fs.Bool("json", false, "print a JSON array")
+	protocolName := protocolFlag(fs, "only list hosts of this protocol")
+	inventory := inventoryFlag(fs, "only list hosts of this inventory")
+	group := fs.String("group", "", "only list hosts in this group")
+	fs.StringVar(group, "g", "", "shorthand for -group")
+	pos, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	if len(pos) > 0 {
+		return c.usageError("unexpected argument %q", pos[0])
+	}
+	protocol, err := parseProtocol(*protocolName)
+	if err != nil {
+		return c.usageError("%v", err)
+	}
+
+	hosts := filterInventory(filterProtocol(c.loadHosts(), protocol), *inventory)
+	if g := strings.ToLower(strings.TrimSpace(*group)); g != "" {
+		hosts = slices.DeleteFunc(hosts, func(h config.Host) bool { return groupOf(h.Spec.Alias) != g })
+	}
+	sortHosts(hosts)
+
+	if *asJSON {
+		if err := writeJSON(c.stdout, hostsJSON(hosts)); err != nil {
+			return c.errorf(ExitFailure, "%v", err)
+		}
+		return ExitOK
+	}
+	for _, h := range hosts {
+		fmt.Fprintln(c.stdout, hostLine(h))
+	}
+	return ExitOK
+}
+
+// runShow prints a host's Host block, preceded by a comment naming its
+// protocol, config file and (if it isn't local) inventory.
+func (c *cli) runShow(args []string) int {
+	fs := c.flags()
+	asJSON := fs.Bool("json", false, "print a JSON object")
+	protocolName := protocolFlag(fs, "the host's protocol, if the alias is used by several")
+	inventory := inventoryFlag(fs, "the host's inventory, if the alias is used by several")
+	pos, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	if len(pos) != 1 {
+		return c.usageError("expected one alias")
+	}
+	protocol, err := parseProtocol(*protocolName)
+	if err != nil {
+		return c.usageError("%v", err)
+	}
+
+	h, code := c.findHost(filterInventory(c.loadHosts(), *inventory), pos[0], protocol)
+	if code != ExitOK {
+		return code
+	}
+	if *asJSON {
+		if err := writeJSON(c.stdout, toJSON(h)); err != nil {
+			return c.errorf(ExitFailure, "%v", err)
+		}
+		return ExitOK
+	}
+	lines, err := config.FormatHostEntries([]config.HostEntry{h.HostEntry})
+	if err != nil {
+		return c.errorf(ExitFailure, "%v", err)
+	}
+	header := fmt.Sprintf("# %s host from %s", h.Protocol, h.SourcePath)
+	if inv := inventoryLabel(h.Inventory); inv != "" {
+		header += " (inventory " + inv
+		if h.Inventory.Writable() != nil {
+			header += ", read-only"
+		}
+		header += ")"
+	}
+	fmt.Fprintln(c.stdout, header)
+	fmt.Fprintln(c.stdout, strings.TrimRight(strings.Join(lines, "\n"), "\n"))
+	return ExitOK
+}
+
+// runExport prints hosts as config blocks (one protocol at a time, so the
+// output can be used as that protocol's config) or as JSON.
+//
+// Arguments pick hosts by alias or whole groups by name; none exports every host.
+func (c *cli) runExport(args []string) int {
+	fs := c.flags()
+	asJSON := fs.Bool("json", false, "print a JSON array (any mix of protocols)")
+	protocolName := protocolFlag(fs, "only export hosts of this protocol")
+	pos, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	protocol, err := parseProtocol(*protocolName)
+	if err != nil {
+		return c.usageError("%v", err)
+	}
+
+	hosts := filterProtocol(c.loadHosts(), protocol)
+	if len(pos) > 0 {
+		var picked []config.Host
+		for _, name := range pos {
+			name = strings.ToLower(strings.TrimSpace(name))
+			n := len(picked)
+			for _, h := range hosts {
+				if (strings.ToLower(h.Spec.Alias) == name || groupOf(h.Spec.Alias) == name) &&
+					!slices.ContainsFunc(picked, func(p config.Host) bool { return sameHost(p, h) }) {
+					picked = append(picked, h)
+				}
+			}
+			if len(picked) == n {
+				return c.errorf(ExitNotFound, "no host or group %q", name)
+			}
+		}
+		hosts = picked
+	}
+	sortHosts(hosts)
+
+	if *asJSON {
+		if err := writeJSON(c.stdout, hostsJSON(hosts)); err != nil {
+			return c.errorf(ExitFailure, "%v", err)
+		}
+		return ExitOK
+	}
+
+	var protocols []config.Protocol
+	entries := make([]config.HostEntry, 0, len(hosts))
+	for _, h := range hosts {
+		if !slices.Contains(protocols, h.Protocol) {
+			protocols = append(protocols, h.Protocol)
+		}
+		e := h.HostEntry
+		e.SourcePath = ""
+		entries = append(entries, e)
+	}
+	if len(protocols) > 1 {
+		return c.usageError("the hosts use several protocols (%s); pick one with -p or use --json",
+			joinProtocols(protocols))
+	}
+	lines, err := config.FormatHostEntries(entries)
+	if err != nil {
+		return c.errorf(ExitFailure, "%v", err)
+	}
+	if len(lines) > 0 {
+		fmt.Fprintln(c.stdout, strings.TrimRight(strings.Join(lines, "\n"), "\n"))
+	}
+	return ExitOK
+}
+
+// sameHost reports whether a and b are the same host.
+func sameHost(a, b config.Host) bool {
+	return a.Protocol == b.Protocol && a.Spec.Alias == b.Spec.Alias && a.Inventory.Name == b.Inventory.Name
+}
+
+// joinProtocols returns the protocol names separated by commas.
+func joinProtocols(protocols []config.Protocol) string {
+	names := make([]string, 0, len(protocols))
+	for _, p := range protocols {
+		names = append(names, string(p))
+	}
+	return strings.Join(names, ", ")
+}
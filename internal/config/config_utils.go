@@ -24,11 +24,11 @@ func getHomeDirectory() (string, error) {
 	return home, nil
 }
 
-// expandPath expands a given path, replacing ~ with the effective home directory.
-// Used mainly to expand include paths in config files.
+// ExpandPath expands a given path, replacing ~ with the effective home directory.
+// Used mainly to expand include and identity file paths in config files.
 //
 // It returns an error if the path is empty or the home directory cannot be determined.
-func expandPath(path string) (string, error) {
+func ExpandPath(path string) (string, error) {
 	path = strings.TrimSpace(path)
 	if path == "" {
 		return "", errors.New("empty path")
@@ -54,9 +54,14 @@ func readLines(path string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	return splitLines(b), nil
+}
+
+// splitLines splits a file's contents into lines, normalizing line endings to LF.
+func splitLines(b []byte) []string {
 	s := strings.ReplaceAll(string(b), "\r\n", "\n")
 	s = strings.ReplaceAll(s, "\r", "\n")
-	return strings.Split(s, "\n"), nil
+	return strings.Split(s, "\n")
 }
 
 // writeLines writes lines to path with LF endings.
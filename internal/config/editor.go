@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -134,6 +135,104 @@ func RemoveHostEntry(configPath, alias string) error {
 	return writeLines(configPath, out)
 }
 
+// EditHostOption changes one option of alias's Host block in configPath,
+// leaving the rest of the file (including options the app doesn't know) as
+// it is. edit gets the option's current value ("" if the block doesn't set
+// it) and returns the new one; an option the block doesn't set is added at
+// the end of the block.
+//
+// The block must be alias's own, since changing a Host line shared with
+// other aliases would change them too. If alias isn't in configPath, it
+// returns os.ErrNotExist.
+func EditHostOption(configPath, alias, option string, edit func(value string) string) error {
+	alias = strings.TrimSpace(alias)
+	if !isSimpleAlias(alias) {
+		return fmt.Errorf("unsupported alias pattern: %q", alias)
+	}
+	lines, err := readLines(configPath)
+	if err != nil {
+		return err
+	}
+
+	start := -1
+	for i, raw := range lines {
+		if _, aliases, _, ok := parseHostHeader(raw); ok && slices.Contains(aliases, alias) {
+			if len(aliases) > 1 {
+				return fmt.Errorf("host %q shares its Host line with %s; edit %s by hand",
+					alias, strings.Join(slices.DeleteFunc(aliases, func(a string) bool { return a == alias }), ", "), option)
+			}
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return os.ErrNotExist
+	}
+	end := start + 1
+	for end < len(lines) {
+		if _, _, _, ok := parseHostHeader(lines[end]); ok {
+			break
+		}
+		end++
+	}
+
+	indent := DefaultHostIndent
+	last := start // last option of the block, so comments before the next block stay with it
+	for i := start + 1; i < end; i++ {
+		raw := lines[i]
+		fields := strings.Fields(stripComment(raw))
+		if len(fields) == 0 {
+			continue
+		}
+		last = i
+		indent = raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]
+		key, value, _ := strings.Cut(fields[0], "=")
+		if !strings.EqualFold(key, option) {
+			continue
+		}
+		if value == "" {
+			value = strings.Join(fields[1:], " ")
+		}
+		line := indent + key + " " + edit(value)
+		if _, comment, ok := strings.Cut(raw, "#"); ok {
+			line += " #" + comment
+		}
+		lines[i] = line
+		return writeLines(configPath, lines)
+	}
+
+	lines = slices.Insert(lines, last+1, indent+option+" "+edit(""))
+	return writeLines(configPath, lines)
+}
+
+// WriteHostEntries writes entries as Host blocks to a new file at path.
+//
+// It's used for exports, so an existing file is an error rather than
+// something to merge into.
+func WriteHostEntries(path string, entries []HostEntry) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	out, err := FormatHostEntries(entries)
+	if err != nil {
+		return err
+	}
+	return writeLines(path, out)
+}
+
+// FormatHostEntries returns entries as the lines of Host blocks, separated
+// by blank lines, as they'd be written to a config file.
+func FormatHostEntries(entries []HostEntry) ([]string, error) {
+	var out []string
+	for _, e := range entries {
+		if !isSimpleAlias(strings.TrimSpace(e.Spec.Alias)) {
+			return nil, fmt.Errorf("unsupported alias pattern: %q", e.Spec.Alias)
+		}
+		out = append(out, buildHostEntry(e, out)...)
+	}
+	return out, nil
+}
+
 // removeAliasFromLines removes alias from any Host headers in lines.
 //
 // Returns updated lines and a bool indicating whether any change was made.
@@ -224,3 +323,82 @@ func removeAliasFromLines(lines []string, alias string) ([]string, bool) {
 
 	return out, changed
 }
+
+// HostChanges are changes to one config file: aliases to remove, then Host
+// blocks to append (eg. an imported host replacing one with its alias).
+type HostChanges struct {
+	Path   string      // config file to change; created if it doesn't exist
+	Remove []string    // aliases to remove; each must be in the file
+	Add    []HostEntry // entries to append; their aliases mustn't be in the file
+}
+
+// ApplyHostChanges makes changes to several config files as one transaction.
+//
+// Every file's new contents are worked out before any file is written, so a
+// missing or duplicate alias changes nothing. If writing a file fails, the
+// files already written are put back the way they were.
+func ApplyHostChanges(changes []HostChanges) error {
+	type pending struct {
+		path    string
+		old     []byte // contents before the changes; nil if the file didn't exist
+		existed bool
+		lines   []string
+	}
+	var files []*pending
+	byPath := map[string]*pending{}
+
+	for _, c := range changes {
+		p, ok := byPath[c.Path]
+		if !ok {
+			p = &pending{path: c.Path}
+			b, err := os.ReadFile(c.Path)
+			switch {
+			case err == nil:
+				p.old, p.existed, p.lines = b, true, splitLines(b)
+			case !errors.Is(err, os.ErrNotExist):
+				return err
+			}
+			byPath[c.Path] = p
+			files = append(files, p)
+		}
+
+		for _, alias := range c.Remove {
+			alias = strings.TrimSpace(alias)
+			if !isSimpleAlias(alias) {
+				return fmt.Errorf("unsupported alias pattern: %q", alias)
+			}
+			if !fileContainsAlias(p.lines, alias) {
+				return fmt.Errorf("host %q not found in %s", alias, c.Path)
+			}
+			p.lines, _ = removeAliasFromLines(p.lines, alias)
+		}
+		for _, e := range c.Add {
+			alias := strings.TrimSpace(e.Spec.Alias)
+			if !isSimpleAlias(alias) {
+				return fmt.Errorf("unsupported alias pattern: %q", e.Spec.Alias)
+			}
+			if fileContainsAlias(p.lines, alias) {
+				return fmt.Errorf("host %q already exists in %s", alias, c.Path)
+			}
+			p.lines = append(p.lines, buildHostEntry(e, p.lines)...)
+		}
+	}
+
+	for i, p := range files {
+		err := writeLines(p.path, p.lines)
+		if err == nil {
+			continue
+		}
+		// put back the files written so far
+		errs := []error{fmt.Errorf("write %s: %w", p.path, err)}
+		for _, done := range files[:i] {
+			if done.existed {
+				errs = append(errs, writeFileAtomic(done.path, done.old))
+			} else {
+				errs = append(errs, os.Remove(done.path))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes lines to a new config file and returns its path.
+func writeConfig(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readConfig returns the lines of the config file at path.
+func readConfig(t *testing.T, path string) []string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+func TestEditHostOption(t *testing.T) {
+	addRSA := func(v string) string { return AppendAlgorithm(v, "ssh-rsa") }
+	tests := []struct {
+		name  string
+		alias string
+		in    []string
+		want  []string
+	}{
+		{
+			name:  "existing option edited in place",
+			alias: "old",
+			in: []string{
+				"Host old",
+				"    HostName 10.0.0.1",
+				"    HostKeyAlgorithms +ssh-dss # legacy box",
+				"    SetEnv FOO=bar",
+				"",
+				"Host new",
+				"    HostKeyAlgorithms ssh-ed25519",
+			},
+			want: []string{
+				"Host old",
+				"    HostName 10.0.0.1",
+				"    HostKeyAlgorithms +ssh-dss,ssh-rsa # legacy box",
+				"    SetEnv FOO=bar",
+				"",
+				"Host new",
+				"    HostKeyAlgorithms ssh-ed25519",
+			},
+		},
+		{
+			name:  "missing option added after the last one",
+			alias: "old",
+			in: []string{
+				"Host old",
+				"  HostName 10.0.0.1",
+				"  ForwardAgent yes",
+				"",
+				"# next up",
+				"Host new",
+			},
+			want: []string{
+				"Host old",
+				"  HostName 10.0.0.1",
+				"  ForwardAgent yes",
+				"  HostKeyAlgorithms +ssh-rsa",
+				"",
+				"# next up",
+				"Host new",
+			},
+		},
+		{
+			name:  "option name is case-insensitive and may use =",
+			alias: "old",
+			in:    []string{"Host old", "    hostkeyalgorithms=ssh-ed25519"},
+			want:  []string{"Host old", "    hostkeyalgorithms ssh-ed25519,ssh-rsa"},
+		},
+		{
+			name:  "only the first block for the alias",
+			alias: "old",
+			in:    []string{"Host old", "    User a", "Host old", "    HostKeyAlgorithms ssh-ed25519"},
+			want:  []string{"Host old", "    User a", "    HostKeyAlgorithms +ssh-rsa", "Host old", "    HostKeyAlgorithms ssh-ed25519"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.in...)
+			if err := EditHostOption(path, tt.alias, "HostKeyAlgorithms", addRSA); err != nil {
+				t.Fatal(err)
+			}
+			got := readConfig(t, path)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("config =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestEditHostOptionErrors(t *testing.T) {
+	path := writeConfig(t, "Host a b", "    HostName 10.0.0.1")
+	edit := func(v string) string { return "x" }
+
+	if err := EditHostOption(path, "c", "Ciphers", edit); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing alias: err = %v, want os.ErrNotExist", err)
+	}
+	if err := EditHostOption(path, "a", "Ciphers", edit); err == nil {
+		t.Error("shared Host line: err = nil, want an error")
+	}
+	if got := readConfig(t, path); len(got) != 2 {
+		t.Errorf("config changed after errors: %q", got)
+	}
+}
+
+func TestIdentityFilesRoundTrip(t *testing.T) {
+	path := writeConfig(t,
+		"Host web",
+		"    HostName 10.0.0.1",
+		"    IdentityFile ~/.ssh/id_ed25519",
+		"    IdentityFile \"~/keys/ops,old.pem\"",
+	)
+	entries, err := ParseConfigRecursively(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"~/.ssh/id_ed25519", "\"~/keys/ops,old.pem\""}
+	if len(entries) != 1 || !slices.Equal(entries[0].SSHOptions.IdentityFiles, want) {
+		t.Fatalf("parsed %+v, want IdentityFiles %q", entries, want)
+	}
+
+	out := filepath.Join(t.TempDir(), "out")
+	if err := WriteHostEntries(out, entries); err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParseConfigRecursively(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 1 || !slices.Equal(again[0].SSHOptions.IdentityFiles, want) {
+		t.Errorf("after writing, IdentityFiles = %+v, want %q", again, want)
+	}
+}
+
+func TestApplyHostChanges(t *testing.T) {
+	entry := func(alias string) HostEntry {
+		return HostEntry{Spec: Spec{Alias: alias, HostName: "10.0.0.9"}}
+	}
+	a := writeConfig(t, "Host web", "    HostName 10.0.0.1")
+	b := filepath.Join(t.TempDir(), "new", "config")
+
+	err := ApplyHostChanges([]HostChanges{
+		{Path: a, Remove: []string{"web"}, Add: []HostEntry{entry("web")}},
+		{Path: b, Add: []HostEntry{entry("db")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readConfig(t, a); !slices.Contains(got, "    HostName 10.0.0.9") || slices.Contains(got, "    HostName 10.0.0.1") {
+		t.Errorf("replaced host: config =\n%s", strings.Join(got, "\n"))
+	}
+	if got := readConfig(t, b); got[0] != "Host db" {
+		t.Errorf("new config =\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestApplyHostChangesErrors(t *testing.T) {
+	entry := func(alias string) HostEntry {
+		return HostEntry{Spec: Spec{Alias: alias, HostName: "10.0.0.9"}}
+	}
+	a := writeConfig(t, "Host web", "    HostName 10.0.0.1")
+	before := readConfig(t, a)
+	unchanged := func(what string) {
+		t.Helper()
+		if got := readConfig(t, a); !slices.Equal(got, before) {
+			t.Errorf("%s: config changed to\n%s", what, strings.Join(got, "\n"))
+		}
+	}
+
+	tests := []struct {
+		name    string
+		changes []HostChanges
+		want    string
+	}{
+		{"missing alias", []HostChanges{
+			{Path: a, Add: []HostEntry{entry("db")}},
+			{Path: a, Remove: []string{"nope"}},
+		}, "not found"},
+		{"duplicate alias", []HostChanges{
+			{Path: a, Add: []HostEntry{entry("db"), entry("web")}},
+		}, "already exists"},
+		{"pattern alias", []HostChanges{
+			{Path: a, Add: []HostEntry{entry("web-*")}},
+		}, "unsupported alias pattern"},
+	}
+	for _, tt := range tests {
+		err := ApplyHostChanges(tt.changes)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+		unchanged(tt.name)
+	}
+
+	// a write that fails puts back the files written before it: a is
+	// restored and the new file removed
+	dir := t.TempDir()
+	created := filepath.Join(dir, "created")
+	blocked := filepath.Join(dir, "file")
+	if err := os.WriteFile(blocked, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := ApplyHostChanges([]HostChanges{
+		{Path: a, Add: []HostEntry{entry("db")}},
+		{Path: created, Add: []HostEntry{entry("db")}},
+		{Path: filepath.Join(blocked, "config"), Add: []HostEntry{entry("db")}},
+	})
+	if err == nil {
+		t.Fatal("write under a file: err = nil")
+	}
+	unchanged("failed write")
+	if _, err := os.Stat(created); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file created before the failed write: stat err = %v, want it removed", err)
+	}
+}
@@ -2,22 +2,21 @@ package config
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"unicode"
 )
 
-const (
-	ProtocolSSH    Protocol = "ssh"
-	ProtocolTelnet Protocol = "telnet"
-)
-
-type Protocol string // "ssh" or "telnet"
-
 // HostEntry is a minimal representation of a Host block from an SSH-style config.
 // It intentionally contains only the fields this project currently supports.
 type HostEntry struct {
-	Spec       Spec       // host fields that are shared between SSH and Telnet (alias/hostname/port/user)
-	SSHOptions SSHOptions // SSH-specific options for this host
-	SourcePath string     // path to the config file this entry was read from
+	Spec           Spec           // host fields that are shared between SSH and Telnet (alias/hostname/port/user)
+	SSHOptions     SSHOptions     // SSH-specific options for this host
+	TelnetOptions  TelnetOptions  // Telnet-specific options for this host
+	CommandOptions CommandOptions // command template settings (mosh, raw, serial, custom)
+	SerialOptions  SerialOptions  // serial line settings (serial only)
+	AppOptions     AppOptions     // settings used only by this app (stored as #btms comments)
+	SourcePath     string         // path to the config file this entry was read from
 }
 
 // Spec is the shared representation of a host endpoint across the project.
@@ -31,16 +30,66 @@ type Spec struct {
 	User     string // user name
 }
 
-// SSHOptions represents a small subset of SSH algorithm selection settings.
+// SSHOptions represents a small subset of SSH algorithm selection and routing settings.
 //
 // These map to OpenSSH config keys and can be passed to ssh via `-o`.
-// Values should be comma-separated algorithm lists (OpenSSH format).
+// Algorithm values should be comma-separated lists (OpenSSH format).
 type SSHOptions struct {
-	HostKeyAlgorithms string // HostKeyAlgorithms option (e.g. "ssh-rsa,ssh-ed25519")
-	KexAlgorithms     string // KexAlgorithms option (e.g. "curve25519-sha256,ecdh-sha2-nistp256")
-	MACs              string // MACs option (e.g. "hmac-sha2-256,hmac-sha1")
+	HostKeyAlgorithms string   // HostKeyAlgorithms option (e.g. "ssh-rsa,ssh-ed25519")
+	KexAlgorithms     string   // KexAlgorithms option (e.g. "curve25519-sha256,ecdh-sha2-nistp256")
+	Ciphers           string   // Ciphers option (e.g. "aes256-ctr,aes128-cbc")
+	MACs              string   // MACs option (e.g. "hmac-sha2-256,hmac-sha1")
+	ProxyJump         string   // ProxyJump option (e.g. "bastion" or "jump1,admin@jump2:2222")
+	IdentityFiles     []string // IdentityFile options, unexpanded, in the order ssh tries them (e.g. "~/.ssh/id_ed25519")
+}
+
+// TelnetOptions represents settings used by the built-in telnet client.
+//
+// These only appear in the telnet config (ssh would reject them).
+type TelnetOptions struct {
+	TermType string // terminal type reported via TTYPE (e.g. "VT100"); empty uses $TERM
+}
+
+// CommandOptions represents settings for protocols that run a program from a
+// command template (see ExpandCommand).
+//
+// Like TelnetOptions, these only appear in those protocols' own configs.
+type CommandOptions struct {
+	Command string // command template (eg. "nc {host} {port}"); empty uses the protocol's default
+}
+
+// SerialOptions represents the serial line settings for a serial console.
+//
+// These only appear in the serial config. Empty values use the defaults
+// (9600 baud, 8 data bits, no parity, 1 stop bit, no flow control).
+type SerialOptions struct {
+	Device   string // serial device (eg. "/dev/ttyUSB0" or "COM3")
+	Baud     string // baud rate (eg. "115200")
+	DataBits string // data bits per character: 5 to 8
+	Parity   string // parity: none, even or odd
+	StopBits string // stop bits: 1 or 2
+	Flow     string // flow control: none, rtscts (hardware) or xonxoff (software)
+}
+
+// AppOptions represents per-host settings that only this app understands.
+//
+// They are stored inside the Host block as comment lines with the
+// AppDirectivePrefix (eg. "#btms Record yes") so ssh ignores them.
+type AppOptions struct {
+	Record           bool   // record sessions to asciicast files
+	Log              bool   // write plain-text session logs
+	PreflightTimeout string // per-attempt preflight timeout (eg. "20s"); empty uses the global default
+	PreflightRetries string // preflight retries on flaky links (eg. "3"); empty uses the global default
+	Tags             string // comma-separated tags for searching and bulk selection (eg. "prod,web")
+	Script           string // login script run at the start of each session (eg. "vms-login"); empty for none
+	PreConnect       string // local command run before connecting (see package hooks)
+	PostConnect      string // local command run after the session ends
+	Secret           string // name of the host's password secret (see package secret); empty for none
 }
 
+// AppDirectivePrefix marks an app-only directive stored as a config comment.
+const AppDirectivePrefix = "#btms "
+
 // Normalized returns a copy of the spec with leading/trailing whitespace removed.
 //
 // This is intended to be applied at boundaries (parsing user input / reading config)
@@ -57,14 +106,94 @@ func (s Spec) Normalized() Spec {
 func (o SSHOptions) Normalized() SSHOptions {
 	o.HostKeyAlgorithms = strings.TrimSpace(o.HostKeyAlgorithms)
 	o.KexAlgorithms = strings.TrimSpace(o.KexAlgorithms)
+	o.Ciphers = strings.TrimSpace(o.Ciphers)
 	o.MACs = strings.TrimSpace(o.MACs)
+	o.ProxyJump = strings.TrimSpace(o.ProxyJump)
+	var files []string
+	for _, f := range o.IdentityFiles {
+		if f = strings.TrimSpace(f); f != "" {
+			files = append(files, f)
+		}
+	}
+	o.IdentityFiles = files
 	return o
 }
 
-// Normalized returns a copy of the host entry with normalized Spec/SSHOptions.
+// AddAlgorithm returns a copy of the options with alg enabled for the named
+// algorithm option (eg. "HostKeyAlgorithms"), keeping the existing value.
+//
+// An empty option becomes "+alg" (append to ssh's defaults), and lists get alg
+// appended unless it's already there. A "-" removal list can't also append,
+// so it is replaced. It returns false if option isn't one we support.
+func (o SSHOptions) AddAlgorithm(option, alg string) (SSHOptions, bool) {
+	var f *string
+	switch strings.ToLower(option) {
+	case "hostkeyalgorithms":
+		f = &o.HostKeyAlgorithms
+	case "kexalgorithms":
+		f = &o.KexAlgorithms
+	case "ciphers":
+		f = &o.Ciphers
+	case "macs":
+		f = &o.MACs
+	default:
+		return o, false
+	}
+
+	*f = AppendAlgorithm(*f, alg)
+	return o, true
+}
+
+// AppendAlgorithm returns an algorithm option's value with alg enabled (see
+// SSHOptions.AddAlgorithm).
+func AppendAlgorithm(value, alg string) string {
+	alg = strings.TrimLeft(strings.TrimSpace(alg), "+-^")
+	cur := strings.TrimSpace(value)
+	for _, a := range strings.Split(strings.TrimLeft(cur, "+-^"), ",") {
+		if strings.TrimSpace(a) == alg && !strings.HasPrefix(cur, "-") {
+			return cur
+		}
+	}
+
+	switch {
+	case cur == "" || strings.HasPrefix(cur, "-"):
+		return "+" + alg
+	default:
+		return cur + "," + alg
+	}
+}
+
+// Normalized returns a copy of the telnet options with leading/trailing whitespace removed.
+func (o TelnetOptions) Normalized() TelnetOptions {
+	o.TermType = strings.TrimSpace(o.TermType)
+	return o
+}
+
+// Normalized returns a copy of the command options with leading/trailing whitespace removed.
+func (o CommandOptions) Normalized() CommandOptions {
+	o.Command = strings.TrimSpace(o.Command)
+	return o
+}
+
+// Normalized returns a copy of the serial options with leading/trailing
+// whitespace removed and the keyword values lowercased.
+func (o SerialOptions) Normalized() SerialOptions {
+	o.Device = strings.TrimSpace(o.Device)
+	o.Baud = strings.TrimSpace(o.Baud)
+	o.DataBits = strings.TrimSpace(o.DataBits)
+	o.Parity = strings.ToLower(strings.TrimSpace(o.Parity))
+	o.StopBits = strings.TrimSpace(o.StopBits)
+	o.Flow = strings.ToLower(strings.TrimSpace(o.Flow))
+	return o
+}
+
+// Normalized returns a copy of the host entry with normalized Spec/options.
 func (e HostEntry) Normalized() HostEntry {
 	e.Spec = e.Spec.Normalized()
 	e.SSHOptions = e.SSHOptions.Normalized()
+	e.TelnetOptions = e.TelnetOptions.Normalized()
+	e.CommandOptions = e.CommandOptions.Normalized()
+	e.SerialOptions = e.SerialOptions.Normalized()
 	return e
 }
 
@@ -106,6 +235,14 @@ func buildHostEntry(entry HostEntry, preceding []string) []string {
 	if len(sshOpts) > 0 {
 		out = append(out, sshOpts...)
 	}
+	if v := entry.TelnetOptions.TermType; v != "" {
+		out = append(out, indent+"TermType "+v)
+	}
+	if v := entry.CommandOptions.Command; v != "" {
+		out = append(out, indent+"Command "+v)
+	}
+	out = append(out, buildSerialOptions(entry.SerialOptions, indent)...)
+	out = append(out, BuildAppOptions(entry.AppOptions, indent)...)
 	// trailing blank line for readability
 	out = append(out, "")
 	return out
@@ -116,19 +253,168 @@ func buildHostEntry(entry HostEntry, preceding []string) []string {
 // It uses the given indent for each line.
 func BuildSSHOptions(o SSHOptions, indent string) []string {
 	o = o.Normalized()
-	parts := make([]string, 0, 3)
+	parts := make([]string, 0, 5)
 	if v := o.HostKeyAlgorithms; v != "" {
 		parts = append(parts, indent+"HostKeyAlgorithms "+v)
 	}
 	if v := o.KexAlgorithms; v != "" {
 		parts = append(parts, indent+"KexAlgorithms "+v)
 	}
+	if v := o.Ciphers; v != "" {
+		parts = append(parts, indent+"Ciphers "+v)
+	}
 	if v := o.MACs; v != "" {
 		parts = append(parts, indent+"MACs "+v)
 	}
+	if v := o.ProxyJump; v != "" {
+		parts = append(parts, indent+"ProxyJump "+v)
+	}
+	for _, f := range o.IdentityFiles {
+		parts = append(parts, indent+"IdentityFile "+f)
+	}
+	return parts
+}
+
+// buildSerialOptions creates config lines for non-empty serial options.
+func buildSerialOptions(o SerialOptions, indent string) []string {
+	parts := make([]string, 0, 8)
+	for _, kv := range [][2]string{
+		{"Device", o.Device},
+		{"Baud", o.Baud},
+		{"DataBits", o.DataBits},
+		{"Parity", o.Parity},
+		{"StopBits", o.StopBits},
+		{"FlowControl", o.Flow},
+	} {
+		if kv[1] != "" {
+			parts = append(parts, indent+kv[0]+" "+kv[1])
+		}
+	}
+	return parts
+}
+
+// BuildAppOptions creates the #btms comment lines for non-default app options.
+//
+// It uses the given indent for each line.
+func BuildAppOptions(o AppOptions, indent string) []string {
+	parts := make([]string, 0, 6)
+	if o.Record {
+		parts = append(parts, indent+AppDirectivePrefix+"Record yes")
+	}
+	if o.Log {
+		parts = append(parts, indent+AppDirectivePrefix+"Log yes")
+	}
+	if v := strings.TrimSpace(o.PreflightTimeout); v != "" {
+		parts = append(parts, indent+AppDirectivePrefix+"PreflightTimeout "+v)
+	}
+	if v := strings.TrimSpace(o.PreflightRetries); v != "" {
+		parts = append(parts, indent+AppDirectivePrefix+"PreflightRetries "+v)
+	}
+	if v := FormatTags(ParseTags(o.Tags)); v != "" {
+		parts = append(parts, indent+AppDirectivePrefix+"Tags "+v)
+	}
+	if v := strings.TrimSpace(o.Script); v != "" {
+		parts = append(parts, indent+AppDirectivePrefix+"Script "+v)
+	}
+	if v := strings.TrimSpace(o.PreConnect); v != "" {
+		parts = append(parts, indent+AppDirectivePrefix+"PreConnect "+v)
+	}
+	if v := strings.TrimSpace(o.PostConnect); v != "" {
+		parts = append(parts, indent+AppDirectivePrefix+"PostConnect "+v)
+	}
+	if v := strings.TrimSpace(o.Secret); v != "" {
+		parts = append(parts, indent+AppDirectivePrefix+"Secret "+v)
+	}
 	return parts
 }
 
+// parseAppDirective parses a raw config line as an app-only directive.
+//
+// It returns the lowercased key and the value, and ok=false if the line is not
+// an app directive.
+func parseAppDirective(line string) (key, value string, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(line), AppDirectivePrefix)
+	if !found {
+		return "", "", false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) < 2 {
+		return "", "", false
+	}
+	return strings.ToLower(fields[0]), strings.Join(fields[1:], " "), true
+}
+
+// setAppDirective applies a single app-only directive to the given HostEntry.
+//
+// It returns true if the directive was recognized and applied.
+func setAppDirective(key string, value string, entry *HostEntry) bool {
+	if entry == nil {
+		return false
+	}
+	value = strings.TrimSpace(value)
+
+	switch key {
+	case "record":
+		entry.AppOptions.Record = parseYesNo(value)
+		return true
+	case "log":
+		entry.AppOptions.Log = parseYesNo(value)
+		return true
+	case "preflighttimeout":
+		entry.AppOptions.PreflightTimeout = value
+		return true
+	case "preflightretries":
+		entry.AppOptions.PreflightRetries = value
+		return true
+	case "tags":
+		entry.AppOptions.Tags = FormatTags(ParseTags(value))
+		return true
+	case "script":
+		entry.AppOptions.Script = value
+		return true
+	case "preconnect":
+		entry.AppOptions.PreConnect = value
+		return true
+	case "postconnect":
+		entry.AppOptions.PostConnect = value
+		return true
+	case "secret":
+		entry.AppOptions.Secret = value
+		return true
+	}
+	return false
+}
+
+// ParseTags splits a tag list on commas and spaces.
+//
+// Tags are lowercased and duplicates are dropped; order is kept.
+func ParseTags(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if !slices.Contains(out, f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// FormatTags joins tags for storage (eg. "prod,web").
+func FormatTags(tags []string) string {
+	return strings.Join(tags, ",")
+}
+
+// parseYesNo returns true for the usual affirmative config values.
+func parseYesNo(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "true", "on", "1":
+		return true
+	}
+	return false
+}
+
 // setHostDirective applies a single Host block directive to the given HostEntry.
 //
 // It returns true if the directive was recognized and applied.
@@ -159,9 +445,49 @@ func setHostDirective(key string, value string, entry *HostEntry) bool {
 	case "kexalgorithms":
 		entry.SSHOptions.KexAlgorithms = value
 		return true
+	case "ciphers":
+		entry.SSHOptions.Ciphers = value
+		return true
 	case "macs":
 		entry.SSHOptions.MACs = value
 		return true
+	case "proxyjump":
+		entry.SSHOptions.ProxyJump = value
+		return true
+	case "identityfile":
+		// ssh tries every IdentityFile given, in order
+		entry.SSHOptions.IdentityFiles = append(entry.SSHOptions.IdentityFiles, value)
+		return true
+
+	// telnet options (built-in client)
+	case "termtype":
+		entry.TelnetOptions.TermType = value
+		return true
+
+	// command template options (mosh, raw, serial, custom)
+	case "command":
+		entry.CommandOptions.Command = value
+		return true
+
+	// serial line options (built-in serial client)
+	case "device":
+		entry.SerialOptions.Device = value
+		return true
+	case "baud":
+		entry.SerialOptions.Baud = value
+		return true
+	case "databits":
+		entry.SerialOptions.DataBits = value
+		return true
+	case "parity":
+		entry.SerialOptions.Parity = value
+		return true
+	case "stopbits":
+		entry.SerialOptions.StopBits = value
+		return true
+	case "flowcontrol":
+		entry.SerialOptions.Flow = value
+		return true
 	}
 
 	return false
@@ -0,0 +1,363 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// LocalInventory is the name of the inventory made of the protocols' own
+// config files (eg. ~/.ssh/config).
+const LocalInventory = "local"
+
+// ErrReadOnly is returned when changing the hosts of a read-only inventory.
+var ErrReadOnly = errors.New("read-only inventory")
+
+// Inventory is a root config file hosts are read from: one of the
+// protocols' own configs, or another one from the settings or --config
+// (eg. a team repo's ssh_config).
+type Inventory struct {
+	Name     string   // shown as the hosts' source; LocalInventory for the protocols' own configs
+	Protocol Protocol // protocol of the file's hosts
+	Path     string   // root config file; its Includes are read too
+	ReadOnly bool     // marked read-only ("ro:" in its spec)
+}
+
+// Host is a host entry from one of the inventories.
+type Host struct {
+	Protocol  Protocol  // protocol whose config defines the host
+	Inventory Inventory // inventory the host was read from
+	HostEntry
+}
+
+// Valid reports whether the host can be connected to: it has an alias and
+// sets every field its protocol requires.
+func (h Host) Valid() bool {
+	if h.Spec.Alias == "" {
+		return false
+	}
+	info, ok := LookupProtocol(h.Protocol)
+	return ok && info.CheckRequired(h.HostEntry) == nil
+}
+
+var (
+	inventoriesMu sync.RWMutex
+	inventories   []Inventory // inventories besides the local ones, in settings order
+)
+
+// SetInventories sets the inventories read besides the local ones.
+func SetInventories(invs []Inventory) {
+	inventoriesMu.Lock()
+	defer inventoriesMu.Unlock()
+	inventories = slices.Clone(invs)
+}
+
+// Inventories returns the local inventory of every registered protocol,
+// then the others. Another inventory with the same file as a local one is
+// left out.
+func Inventories() ([]Inventory, error) {
+	var (
+		out  []Inventory
+		errs []error
+	)
+	for _, p := range Protocols() {
+		inv, err := LocalInventoryFor(p.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		out = append(out, inv)
+	}
+
+	inventoriesMu.RLock()
+	defer inventoriesMu.RUnlock()
+	for _, inv := range inventories {
+		if !slices.ContainsFunc(out, func(o Inventory) bool { return o.Protocol == inv.Protocol && o.Path == inv.Path }) {
+			out = append(out, inv)
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+// HasOtherInventories reports whether any inventories besides the local
+// ones are set.
+func HasOtherInventories() bool {
+	inventoriesMu.RLock()
+	defer inventoriesMu.RUnlock()
+	return len(inventories) > 0
+}
+
+// LocalInventoryFor returns the protocol's local inventory.
+func LocalInventoryFor(protocol Protocol) (Inventory, error) {
+	path, err := GetConfigPathForProtocol(protocol)
+	if err != nil {
+		return Inventory{}, err
+	}
+	return Inventory{Name: LocalInventory, Protocol: protocol, Path: path}, nil
+}
+
+// LookupInventory returns the inventory named name with the protocol's hosts.
+func LookupInventory(name string, protocol Protocol) (Inventory, error) {
+	if name == "" || name == LocalInventory {
+		return LocalInventoryFor(protocol)
+	}
+	invs, _ := Inventories()
+	i := slices.IndexFunc(invs, func(inv Inventory) bool { return inv.Name == name && inv.Protocol == protocol })
+	if i < 0 {
+		return Inventory{}, fmt.Errorf("no %s inventory %q", protocol, name)
+	}
+	return invs[i], nil
+}
+
+// IsLocal reports whether inv is one of the protocols' own configs.
+func (inv Inventory) IsLocal() bool {
+	return inv.Name == LocalInventory
+}
+
+// Writable returns an error wrapping ErrReadOnly if hosts can't be added to,
+// changed in or removed from the inventory: it's marked read-only, or its
+// root config can't be written. A config that doesn't exist yet is created
+// by the first add.
+func (inv Inventory) Writable() error {
+	if inv.ReadOnly {
+		return fmt.Errorf("%w: %s is marked read-only", ErrReadOnly, inv.Name)
+	}
+	f, err := os.OpenFile(inv.Path, os.O_WRONLY, 0)
+	if err == nil {
+		return f.Close()
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s can't be written", ErrReadOnly, inv.Path)
+}
+
+// Read parses the inventory's root config and its includes. A missing local
+// config has no entries; a missing other one is an error.
+func (inv Inventory) Read() ([]HostEntry, error) {
+	entries, err := ParseConfigRecursively(inv.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && inv.IsLocal() {
+			return nil, nil
+		}
+		if inv.IsLocal() {
+			return nil, fmt.Errorf("read %s config: %w", inv.Protocol, err)
+		}
+		return nil, fmt.Errorf("read %s inventory: %w", inv.Name, err)
+	}
+	return entries, nil
+}
+
+// PathForAlias returns the config file that defines alias: the root config
+// or one of its includes. It returns ("", nil) if no file does.
+func (inv Inventory) PathForAlias(alias string) (string, error) {
+	entry, err := FindHostEntry(inv.Path, alias)
+	if err != nil || entry == nil {
+		return "", err
+	}
+	return entry.SourcePath, nil
+}
+
+// AddHost appends a new host block to the inventory's root config.
+//
+// This intentionally writes to the root config even if it contains Include
+// directives; may be extended in the future to support writing to included files.
+func (inv Inventory) AddHost(entry HostEntry) error {
+	if err := inv.Writable(); err != nil {
+		return err
+	}
+	entry.SourcePath = inv.Path
+	return AddHostEntry(inv.Path, entry.Normalized())
+}
+
+// UpdateHost updates an existing host entry.
+//
+// It uses Include-aware resolution so edits land in the file that originally
+// defined oldAlias.
+func (inv Inventory) UpdateHost(oldAlias string, updated HostEntry) error {
+	if err := inv.Writable(); err != nil {
+		return err
+	}
+	configPath, err := inv.PathForAlias(oldAlias)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(configPath) == "" {
+		return os.ErrNotExist
+	}
+	updated.SourcePath = configPath
+	return UpdateHostEntry(configPath, oldAlias, updated.Normalized())
+}
+
+// EditHostOption changes one option of an existing host in the file that
+// defines it, leaving the rest of the file as it is (see EditHostOption).
+func (inv Inventory) EditHostOption(alias, option string, edit func(value string) string) error {
+	if err := inv.Writable(); err != nil {
+		return err
+	}
+	configPath, err := inv.PathForAlias(alias)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(configPath) == "" {
+		return os.ErrNotExist
+	}
+	return EditHostOption(configPath, alias, option, edit)
+}
+
+// RemoveHost removes an alias from the config file that defined it.
+//
+// It uses Include-aware resolution so removals land in the correct include file.
+func (inv Inventory) RemoveHost(alias string) error {
+	if err := inv.Writable(); err != nil {
+		return err
+	}
+	configPath, err := inv.PathForAlias(alias)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(configPath) == "" {
+		return os.ErrNotExist
+	}
+	err = RemoveHostEntry(configPath, alias)
+	if errors.Is(err, os.ErrNotExist) {
+		return os.ErrNotExist
+	}
+	return err
+}
+
+// ParseInventory parses an inventory spec: "[NAME=][ro:][PROTOCOL:]PATH",
+// eg. "team=ro:~/team-hosts/ssh_config". The protocol defaults to ssh and
+// the name to the file's name, or its directory's for generic names like
+// ssh_config. A relative path is made absolute.
+func ParseInventory(spec string) (Inventory, error) {
+	inv := Inventory{Protocol: ProtocolSSH}
+	rest := strings.TrimSpace(spec)
+	if name, path, ok := strings.Cut(rest, "="); ok && validInventoryName(name) {
+		inv.Name, rest = name, path
+	}
+	for {
+		head, tail, ok := strings.Cut(rest, ":")
+		if !ok {
+			break
+		}
+		if head == "ro" {
+			inv.ReadOnly = true
+		} else if _, known := LookupProtocol(Protocol(head)); known {
+			inv.Protocol = Protocol(head)
+		} else {
+			break
+		}
+		rest = tail
+	}
+
+	path, err := ExpandPath(rest)
+	if err != nil {
+		return Inventory{}, fmt.Errorf("inventory %q: %w", spec, err)
+	}
+	if inv.Path, err = filepath.Abs(path); err != nil {
+		return Inventory{}, fmt.Errorf("inventory %q: %w", spec, err)
+	}
+	if inv.Name == "" {
+		inv.Name = inventoryName(inv.Path, inv.Protocol)
+	}
+	if inv.Name == LocalInventory {
+		return Inventory{}, fmt.Errorf("inventory %q: the name %q is taken by the local configs", spec, LocalInventory)
+	}
+	return inv, nil
+}
+
+// String returns the inventory's spec (see ParseInventory).
+func (inv Inventory) String() string {
+	s := inv.Name + "="
+	if inv.ReadOnly {
+		s += "ro:"
+	}
+	if inv.Protocol != ProtocolSSH {
+		s += string(inv.Protocol) + ":"
+	}
+	return s + inv.Path
+}
+
+// inventoryName returns the name an inventory gets from its path: the file's
+// name without its extension, or its directory's name if that's generic
+// (eg. team-hosts for team-hosts/ssh_config).
+func inventoryName(path string, protocol Protocol) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	generic := []string{"config", "hosts", string(protocol), string(protocol) + "_config"}
+	if slices.Contains(generic, strings.ToLower(base)) {
+		base = filepath.Base(filepath.Dir(path))
+	}
+	base = strings.TrimLeft(base, ".")
+	if !validInventoryName(base) {
+		return "inventory"
+	}
+	return base
+}
+
+// validInventoryName reports whether s can name an inventory: letters,
+// digits, - and _.
+func validInventoryName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// LoadHosts reads every inventory and returns the valid hosts, the local
+// configs first in protocol order, each in config order.
+//
+// Missing local configs are skipped. Errors reading the others are joined
+// and returned along with the hosts that could be read.
+func LoadHosts() ([]Host, error) {
+	invs, err := Inventories()
+	hosts, errs := []Host(nil), []error{err}
+	for _, inv := range invs {
+		entries, err := inv.Read()
+		errs = append(errs, err)
+		for _, e := range entries {
+			if h := (Host{Protocol: inv.Protocol, Inventory: inv, HostEntry: e}); h.Valid() {
+				hosts = append(hosts, h)
+			}
+		}
+	}
+	return hosts, errors.Join(errs...)
+}
+
+// SSHConfigFile returns the config ssh should read alias from with -F: the
+// root config of another inventory that defines it, or "" for the user's
+// own ssh config, which wins if both do.
+//
+// Tools that only have an alias (remote commands, sftp, ssh -G) use this;
+// a session started from a menu host knows its inventory already.
+func SSHConfigFile(alias string) string {
+	if !HasOtherInventories() {
+		return ""
+	}
+	if local, err := LocalInventoryFor(ProtocolSSH); err == nil {
+		if e, _ := FindHostEntry(local.Path, alias); e != nil {
+			return ""
+		}
+	}
+	invs, _ := Inventories()
+	for _, inv := range invs {
+		if inv.IsLocal() || inv.Protocol != ProtocolSSH {
+			continue
+		}
+		if e, _ := FindHostEntry(inv.Path, alias); e != nil {
+			return inv.Path
+		}
+	}
+	return ""
+}
@@ -18,7 +18,11 @@ const maxIncludeDepth = 5
 //   - HostName
 //   - User
 //   - Port
-//   - SSH options: HostKeyAlgorithms, KexAlgorithms, MACs
+//   - SSH options: HostKeyAlgorithms, KexAlgorithms, Ciphers, MACs, ProxyJump, IdentityFile
+//   - Telnet options: TermType
+//   - Command options: Command
+//   - Serial options: Device, Baud, DataBits, Parity, StopBits, FlowControl
+//   - App options (#btms comments): Record, Log, PreflightTimeout, PreflightRetries, Tags
 //
 // It returns a slice of HostEntry structs representing the parsed hosts.
 func ParseConfigRecursively(path string) ([]HostEntry, error) {
@@ -46,6 +50,16 @@ func parseConfigRecursively(path string, depth int) ([]HostEntry, error) {
 
 	// parse lines and split into directives, stripping comments and blank lines
 	for _, raw := range lines {
+		// app-only directives live in comments, so check before stripping them
+		if key, value, ok := parseAppDirective(raw); ok {
+			for _, a := range currentAliases {
+				if it := values[a]; it != nil {
+					setAppDirective(key, value, it)
+				}
+			}
+			continue
+		}
+
 		line := strings.TrimSpace(stripComment(raw))
 		if line == "" {
 			continue
@@ -60,7 +74,7 @@ func parseConfigRecursively(path string, depth int) ([]HostEntry, error) {
 		switch key {
 		case "include":
 			for _, incRaw := range fields[1:] {
-				inc, err := expandPath(incRaw) // expand ~ in include path
+				inc, err := ExpandPath(incRaw) // expand ~ in include path
 				if err != nil {
 					continue
 				}
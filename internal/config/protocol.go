@@ -0,0 +1,343 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	ProtocolSSH    Protocol = "ssh"
+	ProtocolTelnet Protocol = "telnet"
+	ProtocolMosh   Protocol = "mosh"
+	ProtocolRaw    Protocol = "raw"
+	ProtocolSerial Protocol = "serial"
+	ProtocolCustom Protocol = "custom"
+)
+
+type Protocol string // registered protocol name (eg. "ssh", "telnet")
+
+// PreflightStrategy says how a protocol's reachability is checked before connecting.
+type PreflightStrategy int
+
+const (
+	PreflightNone PreflightStrategy = iota // connect right away
+	PreflightTCP                           // dial HostName:Port when both are known
+	PreflightSSH                           // dial and check the ssh banner/host key (or the first jump hop)
+)
+
+// ProtocolStyle groups protocols by the color their hosts are shown in.
+type ProtocolStyle int
+
+const (
+	StyleOther  ProtocolStyle = iota // anything else (eg. serial, custom commands)
+	StyleSSH                         // sessions over ssh
+	StyleTelnet                      // plain TCP terminal sessions
+)
+
+// Field is a set of host fields, used to declare which ones a protocol
+// shows in the host form and which it requires.
+type Field uint
+
+const (
+	FieldHostName   Field = 1 << iota // HostName
+	FieldPort                         // Port
+	FieldUser                         // User
+	FieldSSHOptions                   // algorithm options and ProxyJump
+	FieldTermType                     // telnet terminal type
+	FieldCommand                      // command template
+	FieldSerial                       // serial device and line settings
+)
+
+// fieldNames are the names used in validation messages, in display order.
+var fieldNames = []struct {
+	field Field
+	name  string
+}{
+	{FieldHostName, "hostname"},
+	{FieldPort, "port"},
+	{FieldUser, "user"},
+	{FieldCommand, "command"},
+	{FieldSerial, "device"},
+}
+
+// ProtocolInfo declares how hosts of one protocol are stored, shown and connected to.
+type ProtocolInfo struct {
+	Name        Protocol          // protocol name, as shown in the menu
+	Description string            // short description for the protocol picker
+	ConfigPath  []string          // root config file, relative to the home directory
+	ConfigFile  string            // root config file from the settings (absolute or ~/…); overrides ConfigPath
+	DefaultPort string            // port used when none is set (empty if the protocol has no default)
+	DefaultUser string            // user for hosts that don't set one (empty leaves it to the client)
+	Required    Field             // fields a host must set
+	Fields      Field             // fields the host form offers
+	Preflight   PreflightStrategy // reachability check before connecting
+	Style       ProtocolStyle     // color group of its hosts in the menu
+	Command     string            // default command template (see ExpandCommand); empty if none
+
+	// Argv builds the external client's arguments for a host, with the
+	// program name first. Nil means the protocol only has a built-in client;
+	// a builder may also return no arguments to use the built-in client for
+	// that host (eg. serial without a command).
+	Argv func(p ProtocolInfo, e HostEntry) ([]string, error)
+}
+
+// BuildArgv returns the external client's arguments for a host (see Argv).
+func (p ProtocolInfo) BuildArgv(e HostEntry) ([]string, error) {
+	if p.Argv == nil {
+		return nil, fmt.Errorf("%s has no external client", p.Name)
+	}
+	return p.Argv(p, e)
+}
+
+// Has reports whether the protocol's host form offers all fields in f.
+func (p ProtocolInfo) Has(f Field) bool {
+	return p.Fields&f == f
+}
+
+// CheckRequired returns an error naming the first required field e doesn't set.
+func (p ProtocolInfo) CheckRequired(e HostEntry) error {
+	values := map[Field]string{
+		FieldHostName: e.Spec.HostName,
+		FieldPort:     e.Spec.Port,
+		FieldUser:     e.Spec.User,
+		FieldCommand:  cmp.Or(e.CommandOptions.Command, p.Command),
+		FieldSerial:   e.SerialOptions.Device,
+	}
+	for _, f := range fieldNames {
+		if p.Required&f.field != 0 && strings.TrimSpace(values[f.field]) == "" {
+			return fmt.Errorf("%s is required for %s", f.name, p.Name)
+		}
+	}
+	return nil
+}
+
+// protocols is the registry, in the order protocols are offered in the host form.
+var protocols = []ProtocolInfo{
+	{
+		Name:        ProtocolSSH,
+		Description: "OpenSSH client, by alias",
+		ConfigPath:  []string{".ssh", "config"},
+		DefaultPort: "22",
+		Fields:      FieldHostName | FieldPort | FieldUser | FieldSSHOptions,
+		Preflight:   PreflightSSH,
+		Style:       StyleSSH,
+		Argv:        sshArgv,
+	},
+	{
+		Name:        ProtocolTelnet,
+		Description: "built-in telnet client",
+		ConfigPath:  []string{".telnet", "config"},
+		DefaultPort: "23",
+		Required:    FieldHostName,
+		Fields:      FieldHostName | FieldPort | FieldUser | FieldTermType,
+		Preflight:   PreflightTCP,
+		Style:       StyleTelnet,
+	},
+	{
+		Name:        ProtocolMosh,
+		Description: "mobile shell over ssh",
+		ConfigPath:  []string{".mosh", "config"},
+		DefaultPort: "22",
+		Fields:      FieldHostName | FieldPort | FieldUser | FieldCommand,
+		Preflight:   PreflightSSH,
+		Style:       StyleSSH,
+		Command:     `mosh --ssh="ssh -p {port}" {target}`,
+		Argv:        commandArgv,
+	},
+	{
+		Name:        ProtocolRaw,
+		Description: "raw TCP via netcat",
+		ConfigPath:  []string{".btms", "raw"},
+		Required:    FieldHostName | FieldPort,
+		Fields:      FieldHostName | FieldPort | FieldCommand,
+		Preflight:   PreflightTCP,
+		Style:       StyleTelnet,
+		Command:     "nc {host} {port}",
+		Argv:        commandArgv,
+	},
+	{
+		Name:        ProtocolSerial,
+		Description: "built-in serial console",
+		ConfigPath:  []string{".btms", "serial"},
+		Required:    FieldSerial,
+		Fields:      FieldSerial | FieldCommand,
+		Preflight:   PreflightNone,
+		Argv:        serialArgv,
+	},
+	{
+		Name:        ProtocolCustom,
+		Description: "your own command template",
+		ConfigPath:  []string{".btms", "custom"},
+		Required:    FieldCommand,
+		Fields:      FieldHostName | FieldPort | FieldUser | FieldCommand,
+		Preflight:   PreflightTCP,
+		Argv:        commandArgv,
+	},
+}
+
+// protocolsMu guards protocols, whose defaults the settings can change
+// while the menu runs.
+var protocolsMu sync.RWMutex
+
+// Protocols returns the registered protocols, in the order they're offered.
+func Protocols() []ProtocolInfo {
+	protocolsMu.RLock()
+	defer protocolsMu.RUnlock()
+	return slices.Clone(protocols)
+}
+
+// LookupProtocol returns the registry entry for p.
+func LookupProtocol(p Protocol) (ProtocolInfo, bool) {
+	protocolsMu.RLock()
+	defer protocolsMu.RUnlock()
+	i := slices.IndexFunc(protocols, func(info ProtocolInfo) bool { return info.Name == p })
+	if i < 0 {
+		return ProtocolInfo{}, false
+	}
+	return protocols[i], true
+}
+
+// ProtocolDefaults are the registry values the settings file can change.
+type ProtocolDefaults struct {
+	ConfigFile string // root config file (absolute or ~/…); empty keeps ConfigPath
+	Port       string // default port
+	User       string // default user
+}
+
+// SetProtocolDefaults sets p's config file, default port and default user.
+func SetProtocolDefaults(p Protocol, d ProtocolDefaults) error {
+	protocolsMu.Lock()
+	defer protocolsMu.Unlock()
+	i := slices.IndexFunc(protocols, func(info ProtocolInfo) bool { return info.Name == p })
+	if i < 0 {
+		return fmt.Errorf("unknown protocol: %q", p)
+	}
+	protocols[i].ConfigFile = d.ConfigFile
+	protocols[i].DefaultPort = d.Port
+	protocols[i].DefaultUser = d.User
+	return nil
+}
+
+// DefaultBaud is the serial baud rate used when a host doesn't set one.
+const DefaultBaud = "9600"
+
+// sshArgv returns the ssh arguments to connect to the host by alias, as its
+// user and on e's port if set.
+//
+// hostname/port are only for display/preflight; ssh reads them (and any
+// ProxyJump chain) from its own config. -p beats that config, so callers
+// only set Port to a port ssh's config doesn't give the host (eg. the
+// settings' default port).
+func sshArgv(_ ProtocolInfo, e HostEntry) ([]string, error) {
+	argv := []string{string(ProtocolSSH)}
+	if e.Spec.User != "" {
+		argv = append(argv, "-l", e.Spec.User)
+	}
+	if e.Spec.Port != "" && e.Spec.Port != "22" {
+		argv = append(argv, "-p", e.Spec.Port)
+	}
+	return append(argv, e.Spec.Alias), nil
+}
+
+// serialArgv runs the host's command template (eg. "picocom -b {baud} {device}")
+// if it has one; otherwise the built-in serial client is used.
+func serialArgv(p ProtocolInfo, e HostEntry) ([]string, error) {
+	if strings.TrimSpace(e.CommandOptions.Command) == "" {
+		return nil, nil
+	}
+	return commandArgv(p, e)
+}
+
+// commandArgv expands the host's command template, or the protocol's default one.
+func commandArgv(p ProtocolInfo, e HostEntry) ([]string, error) {
+	template := cmp.Or(strings.TrimSpace(e.CommandOptions.Command), p.Command)
+	if template == "" {
+		return nil, fmt.Errorf("%s %q: empty command", p.Name, e.Spec.Alias)
+	}
+	return ExpandCommand(template, e)
+}
+
+// ExpandCommand splits a command template into arguments and fills in the
+// host's placeholders:
+//
+//   - {alias}: the host alias
+//   - {host}: the hostname (the alias if none is set)
+//   - {port}: the port
+//   - {user}: the user name
+//   - {target}: user@host, or just host without a user
+//   - {device}, {baud}: the serial device and baud rate (see SerialOptions)
+//
+// Placeholders are replaced after splitting, so values with spaces stay one argument.
+func ExpandCommand(template string, e HostEntry) ([]string, error) {
+	args, err := SplitArgs(template)
+	if err != nil {
+		return nil, fmt.Errorf("command %q: %w", template, err)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+
+	host := cmp.Or(e.Spec.HostName, e.Spec.Alias)
+	target := host
+	if e.Spec.User != "" {
+		target = e.Spec.User + "@" + host
+	}
+	r := strings.NewReplacer(
+		"{alias}", e.Spec.Alias,
+		"{host}", host,
+		"{port}", e.Spec.Port,
+		"{user}", e.Spec.User,
+		"{target}", target,
+		"{device}", e.SerialOptions.Device,
+		"{baud}", cmp.Or(e.SerialOptions.Baud, DefaultBaud),
+	)
+	for i, a := range args {
+		args[i] = r.Replace(a)
+	}
+	return args, nil
+}
+
+// SplitArgs splits s into arguments, honoring single and double quotes.
+//
+// Backslashes are kept as-is so Windows paths work unquoted.
+func SplitArgs(s string) ([]string, error) {
+	var (
+		args  []string
+		cur   strings.Builder
+		inArg bool
+		quote rune
+	)
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,258 @@
+package connect
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+// sshErrorExitCode is the exit code ssh uses for its own (non-remote) errors.
+const sshErrorExitCode = 255
+
+// FailureKind identifies a recognized connection failure.
+type FailureKind int
+
+const (
+	FailureUnknown FailureKind = iota
+	FailureHostKeyChanged
+	FailureHostKeyUnknown
+	FailurePermissionDenied
+	FailureNoMatchingAlgorithm
+	FailureConnectionRefused
+	FailureDNS
+	FailureBadConfigOption
+	FailureTimeout
+	FailureUnreachable
+)
+
+// OptionFix is a suggested change to a host's SSH options that can be applied
+// automatically (eg. adding +ssh-rsa to HostKeyAlgorithms).
+type OptionFix struct {
+	Option    string // ssh option name (eg. "HostKeyAlgorithms")
+	Algorithm string // algorithm to enable (eg. "ssh-rsa")
+}
+
+// String returns the fix in ssh config form (eg. "HostKeyAlgorithms +ssh-rsa").
+func (f OptionFix) String() string {
+	return f.Option + " +" + f.Algorithm
+}
+
+// ConnectError is a connection failure classified from the ssh/telnet output.
+type ConnectError struct {
+	Kind        FailureKind
+	Summary     string     // short description (eg. "permission denied")
+	Explanation string     // what happened, in plain words
+	Suggestion  string     // what to do about it
+	Fix         *OptionFix // one-key fix (nil if there isn't one)
+	Peer        string     // host ssh failed to negotiate with, as it names it ("UNKNOWN" through a jump host)
+	Line        string     // output line that was recognized
+	ExitCode    int        // process exit code (-1 if unknown)
+	Err         error      // underlying error from the command
+}
+
+// Error implements error.
+func (e *ConnectError) Error() string {
+	return e.Summary
+}
+
+// Unwrap returns the underlying command error.
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	reNoMatch = regexp.MustCompile(
+		`Unable to negotiate with (\S+) port \d+: no matching (key exchange method|cipher|MAC|host key type) found\. Their offer: (\S+)`)
+	rePermissionDenied = regexp.MustCompile(`Permission denied \(([^)]*)\)`)
+	reBadOption        = regexp.MustCompile(
+		`(?i)(?:(\S+) line (\d+): )?(?:bad configuration option|unsupported option)[: ]*(\S*)`)
+	reBadAlgSpec = regexp.MustCompile(`(?i)bad (?:ssh2 )?(?:cipher|mac|kexalgorithms|key types|hostkeyalgorithms)[^\n]*`)
+	reDNS        = regexp.MustCompile(
+		`(?i)could not resolve hostname (\S+?):|no such host|name or service not known|temporary failure in name resolution|nodename nor servname`)
+	reRefused     = regexp.MustCompile(`(?i)connection refused`)
+	reTimeout     = regexp.MustCompile(`(?i)connection timed out|operation timed out|i/o timeout|timed out`)
+	reUnreachable = regexp.MustCompile(`(?i)no route to host|network is unreachable|host is down`)
+)
+
+// noMatchOptions maps ssh's "no matching X found" wording to the config option.
+var noMatchOptions = map[string]string{
+	"key exchange method": "KexAlgorithms",
+	"cipher":              "Ciphers",
+	"MAC":                 "MACs",
+	"host key type":       "HostKeyAlgorithms",
+}
+
+// ExitCode returns the process exit code from err, or -1 if unknown.
+func ExitCode(err error) int {
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		return ee.ExitCode()
+	}
+	return -1
+}
+
+// ClassifyFailure inspects the output captured from a connection (usually a
+// TailBuffer over stderr) and the command error, and returns a ConnectError
+// explaining a recognized failure.
+//
+// It returns nil if err is nil, the connection was aborted by the user, or
+// nothing recognizable was found. For ssh, only exit code 255 is considered,
+// since any other status comes from the remote session.
+func ClassifyFailure(protocol config.Protocol, output string, err error) *ConnectError {
+	if err == nil || IsConnectionAborted(err) {
+		return nil
+	}
+	code := ExitCode(err)
+	if protocol == config.ProtocolSSH && code >= 0 && code != sshErrorExitCode {
+		return nil
+	}
+
+	text := output + "\n" + err.Error()
+	ce := classifyText(text)
+	if ce == nil {
+		return nil
+	}
+	ce.ExitCode = code
+	ce.Err = err
+	return ce
+}
+
+// classifyText matches known failure messages, most specific first.
+func classifyText(text string) *ConnectError {
+	if strings.Contains(text, "REMOTE HOST IDENTIFICATION HAS CHANGED") {
+		return &ConnectError{
+			Kind:    FailureHostKeyChanged,
+			Summary: "host key changed",
+			Explanation: "The server's host key doesn't match the one in known_hosts. " +
+				"The server may have been reinstalled, or someone may be intercepting the connection.",
+			Suggestion: "Verify the new key with the server's admin, then remove the old entry (ssh-keygen -R <host>).",
+			Line:       matchingLine(text, "REMOTE HOST IDENTIFICATION HAS CHANGED"),
+		}
+	}
+
+	if m := reBadOption.FindStringSubmatch(text); m != nil {
+		where := "the ssh config"
+		if m[1] != "" {
+			where = fmt.Sprintf("%s line %s", m[1], m[2])
+		}
+		return &ConnectError{
+			Kind:        FailureBadConfigOption,
+			Summary:     "bad config option " + strings.Trim(m[3], `"'`),
+			Explanation: fmt.Sprintf("ssh rejected an option in %s, so it didn't try to connect.", where),
+			Suggestion:  "Remove or correct the option (edit the host with E, or fix the file).",
+			Line:        strings.TrimSpace(m[0]),
+		}
+	}
+	if m := reBadAlgSpec.FindString(text); m != "" {
+		return &ConnectError{
+			Kind:        FailureBadConfigOption,
+			Summary:     "bad algorithm list",
+			Explanation: "ssh doesn't recognize one of the algorithms configured for this host.",
+			Suggestion:  "Check the algorithm names (ssh -Q cipher, ssh -Q mac, ssh -Q kex, ssh -Q key).",
+			Line:        strings.TrimSpace(m),
+		}
+	}
+
+	if m := reNoMatch.FindStringSubmatch(text); m != nil {
+		option := noMatchOptions[m[2]]
+		offer := m[3]
+		alg, _, _ := strings.Cut(offer, ",")
+		fix := &OptionFix{Option: option, Algorithm: alg}
+		return &ConnectError{
+			Kind:    FailureNoMatchingAlgorithm,
+			Summary: "server only offers " + offer,
+			Explanation: fmt.Sprintf("The server and ssh have no %s in common. "+
+				"The server only offers legacy algorithms that ssh disables by default.", m[2]),
+			Suggestion: fmt.Sprintf("Add +%s to %s.", alg, option),
+			Fix:        fix,
+			Peer:       m[1],
+			Line:       strings.TrimSpace(m[0]),
+		}
+	}
+
+	if m := reDNS.FindStringSubmatch(text); m != nil {
+		host := ""
+		if len(m) > 1 && m[1] != "" {
+			host = " " + m[1]
+		}
+		return &ConnectError{
+			Kind:        FailureDNS,
+			Summary:     "could not resolve" + host,
+			Explanation: "The hostname couldn't be found in DNS.",
+			Suggestion:  "Check the HostName for typos, or use an IP address.",
+			Line:        matchingLine(text, m[0]),
+		}
+	}
+
+	if m := reRefused.FindString(text); m != "" {
+		return &ConnectError{
+			Kind:        FailureConnectionRefused,
+			Summary:     "connection refused",
+			Explanation: "The host is reachable, but nothing is listening on that port.",
+			Suggestion:  "Check the Port, and that the service is running on the host.",
+			Line:        matchingLine(text, m),
+		}
+	}
+
+	if m := reUnreachable.FindString(text); m != "" {
+		return &ConnectError{
+			Kind:        FailureUnreachable,
+			Summary:     "host unreachable",
+			Explanation: "There's no network route to the host.",
+			Suggestion:  "Check the HostName, your network/VPN, or use a jump host.",
+			Line:        matchingLine(text, m),
+		}
+	}
+
+	if m := reTimeout.FindString(text); m != "" {
+		return &ConnectError{
+			Kind:        FailureTimeout,
+			Summary:     "connection timed out",
+			Explanation: "The host didn't answer; it may be down, or a firewall may be dropping the connection.",
+			Suggestion:  "Check the HostName and Port, or whether a jump host is needed.",
+			Line:        matchingLine(text, m),
+		}
+	}
+
+	if m := rePermissionDenied.FindStringSubmatch(text); m != nil {
+		methods := strings.Split(m[1], ",")
+		suggestion := "Check the username and credentials."
+		if len(methods) == 1 && methods[0] == "publickey" {
+			suggestion = "The server only accepts keys: install your public key on it (ssh-copy-id) or set the right key."
+		}
+		return &ConnectError{
+			Kind:    FailurePermissionDenied,
+			Summary: "permission denied",
+			Explanation: fmt.Sprintf("The server rejected authentication. It accepts: %s.",
+				strings.Join(methods, ", ")),
+			Suggestion: suggestion,
+			Line:       strings.TrimSpace(m[0]),
+		}
+	}
+
+	if strings.Contains(text, "Host key verification failed.") {
+		return &ConnectError{
+			Kind:        FailureHostKeyUnknown,
+			Summary:     "host key verification failed",
+			Explanation: "The server's host key isn't in known_hosts and ssh wasn't allowed to ask about it.",
+			Suggestion:  "Connect once interactively to accept the key, or add it to known_hosts.",
+			Line:        "Host key verification failed.",
+		}
+	}
+
+	return nil
+}
+
+// matchingLine returns the trimmed line of text that contains sub.
+func matchingLine(text, sub string) string {
+	for line := range strings.SplitSeq(text, "\n") {
+		if strings.Contains(line, sub) {
+			return strings.TrimSpace(line)
+		}
+	}
+	return strings.TrimSpace(sub)
+}
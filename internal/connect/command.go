@@ -1,113 +1,332 @@
 package connect
 
 import (
+	"bytes"
+	"cmp"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/secret"
 	str "bubbletea-ssh-manager/internal/stringutil"
 )
 
-// preferredProgramPath returns the preferred full path to the ssh/telnet program for the given protocol name.
+// DefaultMSYS2Root is where MSYS2 is installed by default; its OpenSSH
+// tools are preferred on Windows.
+const DefaultMSYS2Root = `C:\msys64`
+
+var msys2Root = DefaultMSYS2Root // guarded by defaultsMu
+
+// MSYS2Root returns where MSYS2's tools are looked for on Windows ("" if
+// they aren't).
+func MSYS2Root() string {
+	defaultsMu.RLock()
+	defer defaultsMu.RUnlock()
+	return msys2Root
+}
+
+// SetMSYS2Root sets where MSYS2's tools are looked for on Windows (from the
+// settings); "" only uses PATH.
+func SetMSYS2Root(root string) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	msys2Root = root
+}
+
+// preferredProgramPath returns the preferred full path to the named external program.
 //
 // On Windows, it prefers MSYS2 binaries if available.
 // On other platforms, it looks in the system PATH.
-func preferredProgramPath(protocol config.Protocol) (string, error) {
-	if protocol == "" {
+func preferredProgramPath(name string) (string, error) {
+	if name == "" {
 		return "", fmt.Errorf("empty program name")
 	}
 
 	// prefer MSYS2 binaries when running on Windows
-	if runtime.GOOS == "windows" {
-		roots := []string{}
-		// default install location
-		roots = append(roots, `C:\msys64`)
-		for _, root := range roots {
-			p := filepath.Join(root, "usr", "bin", string(protocol)+".exe")
-			if st, err := os.Stat(p); err == nil && !st.IsDir() {
-				return p, nil
-			}
+	if root := MSYS2Root(); runtime.GOOS == "windows" && root != "" {
+		p := filepath.Join(root, "usr", "bin", name+".exe")
+		if st, err := os.Stat(p); err == nil && !st.IsDir() {
+			return p, nil
 		}
 	}
 
 	// fallback to PATH lookup
-	p, err := exec.LookPath(string(protocol))
+	p, err := exec.LookPath(name)
 	if err != nil {
 		return "", err
 	}
 	return p, nil
 }
 
-// BuildCommand builds the exec.Cmd to connect to the given Target.
-//
-// It returns a Target for display/title, and a TailBuffer that captures the last
-// part of the command output for error reporting.
-func BuildCommand(trgt Target) (cmd *exec.Cmd, tgt Target, tail *TailBuffer, err error) {
-	// trgt should be normalized already, but normalize again to be sure
-	trgt.Spec = trgt.Spec.Normalized()
+// ProgramPath returns the full path to an OpenSSH tool (eg. ssh-add), found
+// the same way as the ssh client.
+func ProgramPath(name string) (string, error) {
+	p, err := preferredProgramPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%s not found: %w", name, err)
+	}
+	return p, nil
+}
 
-	if trgt.Protocol != config.ProtocolSSH && trgt.Protocol != config.ProtocolTelnet {
-		name := trgt.Alias
-		return nil, Target{}, nil, fmt.Errorf("unknown protocol for %s: %q", name, trgt.Protocol)
+// SSHConfigArgs returns ssh's -F option for an alias from another
+// inventory's config (see config.SSHConfigFile), or nil for one from the
+// user's own.
+func SSHConfigArgs(alias string) []string {
+	if path := config.SSHConfigFile(alias); path != "" {
+		return []string{"-F", path}
 	}
+	return nil
+}
 
-	programPath, err := preferredProgramPath(trgt.Protocol)
+// SFTPProgramPath returns the full path to the sftp client, found the same
+// way as the ssh client.
+func SFTPProgramPath() (string, error) {
+	p, err := preferredProgramPath("sftp")
 	if err != nil {
-		return nil, Target{}, nil, fmt.Errorf("%s not found: %w", trgt.Protocol, err)
+		return "", fmt.Errorf("sftp not found: %w", err)
 	}
+	return p, nil
+}
 
-	alias := trgt.Alias
-	user := trgt.User
-	hostName := trgt.HostName
-	portRaw := trgt.Port
+// ErrNoExternalClient is returned by SessionArgs for protocols that only have
+// the built-in client, which can't run outside this process.
+var ErrNoExternalClient = errors.New("no external client")
 
-	if alias == "" {
-		return nil, Target{}, nil, fmt.Errorf("empty %s alias", trgt.Protocol)
+// builtinClients build the Command for protocols without an external client.
+// The target is already validated, with its port normalized; script is its
+// login script (nil if none).
+var builtinClients = map[config.Protocol]func(t Target, script *loginScript) (Command, error){
+	config.ProtocolTelnet: func(t Target, script *loginScript) (Command, error) {
+		c := NewTelnetClient(net.JoinHostPort(t.HostName, t.Port), t.Telnet.TermType)
+		c.script = script
+		return c, nil
+	},
+	config.ProtocolSerial: func(t Target, script *loginScript) (Command, error) {
+		c, err := NewSerialClient(t.Serial)
+		if err != nil {
+			return nil, err
+		}
+		c.script = script
+		return c, nil
+	},
+}
+
+// resolveTarget normalizes t, checks the fields its protocol requires and
+// fills in the protocol's default port and user.
+func resolveTarget(t Target) (Target, config.ProtocolInfo, error) {
+	t.Spec = t.Spec.Normalized()
+	t.Command = t.Command.Normalized()
+	t.Serial = t.Serial.Normalized()
+	info, ok := config.LookupProtocol(t.Protocol)
+	if !ok {
+		return Target{}, info, fmt.Errorf("unknown protocol for %s: %q", t.Alias, t.Protocol)
+	}
+	if t.Alias == "" {
+		return Target{}, info, fmt.Errorf("empty %s alias", t.Protocol)
 	}
+	if err := info.CheckRequired(t.entry()); err != nil {
+		return Target{}, info, fmt.Errorf("%s %q: %w", t.Protocol, t.Alias, err)
+	}
+	p, err := str.NormalizePort(t.Port, t.Protocol)
+	if err != nil {
+		return Target{}, info, err
+	}
+	t.portDefault = t.Port == ""
+	t.Port = p
+	if t.User == "" && info.Has(config.FieldUser) {
+		t.User = info.DefaultUser
+	}
+	return t, info, nil
+}
 
-	tgt = Target{Protocol: trgt.Protocol, Spec: config.Spec{Alias: alias, User: user, HostName: hostName}}
+// externalArgv returns the external client's argv for t, with the program
+// resolved to its full path.
+//
+// It returns ErrNoExternalClient if the host uses the built-in client.
+func externalArgv(t Target, info config.ProtocolInfo) ([]string, error) {
+	if info.Argv == nil {
+		return nil, fmt.Errorf("%s: %w", t.Protocol, ErrNoExternalClient)
+	}
+	e := t.entry()
+	if t.Protocol == config.ProtocolSSH {
+		e.Spec.Port = sshPort(t)
+	}
+	argv, err := info.BuildArgv(e)
+	if err != nil {
+		return nil, err
+	}
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("%s: %w", t.Protocol, ErrNoExternalClient)
+	}
+	programPath, err := preferredProgramPath(argv[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s not found: %w", argv[0], err)
+	}
+	if t.Protocol == config.ProtocolSSH && t.ConfigFile != "" {
+		return append([]string{programPath, "-F", t.ConfigFile}, argv[1:]...), nil
+	}
+	return append([]string{programPath}, argv[1:]...), nil
+}
 
-	var args []string
-	switch trgt.Protocol {
-	case config.ProtocolSSH:
-		// ssh connects by alias; hostname/port are only for display/preflight
-		if hostName != "" {
-			p, err := str.NormalizePort(portRaw, config.ProtocolSSH)
-			if err != nil {
-				return nil, Target{}, nil, err
-			}
-			tgt.Port = p
-		}
-		if user != "" {
-			args = append(args, "-l", user, alias)
-		} else {
-			args = append(args, alias)
-		}
+// sshPort returns the port to pass to ssh with -p for t, or "" to leave it
+// to ssh. -p beats ssh's config, so only a default port from the settings
+// is passed, and only if ssh's config (the host's block, Host * or Match)
+// doesn't set one.
+func sshPort(t Target) string {
+	if !t.portDefault || t.Port == "22" {
+		return ""
+	}
+	if p := sshConfigPort(t.Alias, t.ConfigFile); p != "" && p != "22" {
+		return ""
+	}
+	return t.Port
+}
 
-	case config.ProtocolTelnet:
-		// telnet connects by hostname and port
-		if hostName == "" {
-			return nil, Target{}, nil, fmt.Errorf("telnet %q: empty hostname", alias)
-		}
-		p, err := str.NormalizePort(portRaw, config.ProtocolTelnet)
-		if err != nil {
-			return nil, Target{}, nil, err
+// sshConfigPort returns the port ssh's config gives alias (ssh -G), or ""
+// if ssh can't tell. ssh reports its own default, 22, when nothing sets one.
+func sshConfigPort(alias, configFile string) string {
+	ssh, err := preferredProgramPath(string(config.ProtocolSSH))
+	if err != nil {
+		return ""
+	}
+	args := []string{"-G", alias}
+	if configFile != "" {
+		args = append([]string{"-F", configFile}, args...)
+	}
+	out, err := exec.Command(ssh, args...).Output()
+	if err != nil {
+		return ""
+	}
+	for line := range bytes.Lines(out) {
+		if k, v, ok := strings.Cut(strings.TrimSpace(string(line)), " "); ok && k == "port" {
+			return v
 		}
-		tgt.Port = p
-		args = []string{hostName, p}
 	}
+	return ""
+}
+
+// AskpassTarget returns the login whose password prompts ssh's askpass
+// helper answers for t (see secret.StartAskpass).
+func AskpassTarget(t Target) secret.AskpassTarget {
+	return secret.AskpassTarget{Secret: t.Secret, User: t.User, Host: cmp.Or(t.HostName, t.Alias)}
+}
+
+// askpassCommand serves ssh's askpass helper while an external client runs,
+// for hosts with a password secret, so ssh (also when run by mosh) answers
+// password prompts from the secrets.
+type askpassCommand struct {
+	Command
+	cmd    *exec.Cmd // the client, whose environment points ssh at the helper
+	target secret.AskpassTarget
+}
+
+// withAskpass wraps c, which runs cmd, in an askpassCommand if t has a
+// password secret.
+func withAskpass(c Command, cmd *exec.Cmd, t Target) Command {
+	if t.Secret == "" {
+		return c
+	}
+	return &askpassCommand{Command: c, cmd: cmd, target: AskpassTarget(t)}
+}
+
+// Run serves the askpass helper until the client exits.
+func (c *askpassCommand) Run() error {
+	stop, err := secret.StartAskpass(c.cmd, c.target)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	return c.Command.Run()
+}
 
-	cmd = exec.Command(programPath, args...)
+// SessionArgs returns the program and arguments for an interactive session
+// to t, for running it outside the TUI (eg. in a new terminal tab).
+//
+// Hosts that use a built-in client (eg. telnet) return ErrNoExternalClient.
+func SessionArgs(t Target) ([]string, error) {
+	t, info, err := resolveTarget(t)
+	if err != nil {
+		return nil, err
+	}
+	return externalArgv(t, info)
+}
 
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
+// BuildCommand builds the Command to connect to the given Target.
+//
+// Protocols with an external client (ssh, mosh, ...) run the program from the
+// protocol's argv builder; the others use their built-in client (eg. telnet,
+// or serial without a command).
+//
+// A login script runs inside the built-in clients; external clients are
+// then run on a pseudo-terminal so the script can see their output. External
+// clients of hosts with a password secret get it through ssh's askpass.
+//
+// Any extra outputs (eg. a session recorder) receive a copy of everything the
+// session writes to the terminal (stdout and stderr). External clients are
+// then run on a pseudo-terminal too, and recorded from its master side, so
+// they still write to a terminal; where there's no pty support their output
+// is teed through a pipe instead.
+//
+// It returns a Target for display/title, and a TailBuffer that captures the last
+// part of the command output for error reporting.
+func BuildCommand(trgt Target, outputs ...io.Writer) (cmd Command, tgt Target, tail *TailBuffer, err error) {
+	tgt, info, err := resolveTarget(trgt)
+	if err != nil {
+		return nil, Target{}, nil, err
+	}
+	script, err := loadLoginScript(tgt)
+	if err != nil {
+		return nil, Target{}, nil, err
+	}
 
 	tail = NewTailBuffer(4096)
-	cmd.Stderr = io.MultiWriter(os.Stderr, tail)
+	// only wrap stdout when teeing, so clients keep writing straight to the terminal otherwise
+	stdout := io.Writer(os.Stdout)
+	if len(outputs) > 0 {
+		stdout = io.MultiWriter(append([]io.Writer{os.Stdout}, outputs...)...)
+	}
+	stderr := io.MultiWriter(append([]io.Writer{os.Stderr, tail}, outputs...)...)
+
+	argv, err := externalArgv(tgt, info)
+	switch {
+	case err == nil && (script != nil || (len(outputs) > 0 && ptySupported)):
+		if !ptySupported {
+			return nil, Target{}, nil, errNoPTY
+		}
+		// the pty merges stdout and stderr, so the tail sees both
+		out := io.MultiWriter(append([]io.Writer{os.Stdout, tail}, outputs...)...)
+		c := &ptyCommand{cmd: exec.Command(argv[0], argv[1:]...), script: script}
+		c.SetStdin(os.Stdin)
+		c.SetStdout(out)
+		c.SetStderr(stderr)
+		return withAskpass(c, c.cmd, tgt), tgt, tail, nil
+	case err == nil:
+		c := exec.Command(argv[0], argv[1:]...)
+		c.Stdin = os.Stdin
+		c.Stdout = stdout
+		c.Stderr = stderr
+		return withAskpass(execCommand{c}, c, tgt), tgt, tail, nil
+	case !errors.Is(err, ErrNoExternalClient):
+		return nil, Target{}, nil, err
+	}
 
-	return cmd, tgt, tail, nil
+	build, ok := builtinClients[tgt.Protocol]
+	if !ok {
+		return nil, Target{}, nil, err
+	}
+	c, err := build(tgt, script)
+	if err != nil {
+		return nil, Target{}, nil, err
+	}
+	c.SetStdin(os.Stdin)
+	c.SetStdout(stdout)
+	c.SetStderr(stderr)
+	return c, tgt, tail, nil
 }
@@ -0,0 +1,95 @@
+//go:build linux || darwin
+
+package connect
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+func TestBuildCommandRecordsFromPTY(t *testing.T) {
+	// keep the client's output out of the test's own
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	var rec bytes.Buffer
+	cmd, _, _, err := BuildCommand(Target{
+		Protocol: config.ProtocolCustom,
+		Spec:     config.Spec{Alias: "local"},
+		Command:  config.CommandOptions{Command: `sh -c "test -t 0 && test -t 1 && echo on-a-tty || echo not-a-tty"`},
+	}, &rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := cmd.(*ptyCommand)
+	if !ok {
+		t.Fatalf("BuildCommand with a recorder = %T, want a pty command", cmd)
+	}
+	c.stdin = strings.NewReader("")
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.String(); !strings.Contains(got, "on-a-tty") {
+		t.Errorf("recorded %q, want the client run on a terminal", got)
+	}
+}
+
+func TestSessionArgsSSHPort(t *testing.T) {
+	// a fake ssh that reports $FAKE_SSH_PORT for -G
+	bin := t.TempDir()
+	script := "#!/bin/sh\nprintf 'user alice\\nport %s\\n' \"$FAKE_SSH_PORT\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "ssh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	info, _ := config.LookupProtocol(config.ProtocolSSH)
+	t.Cleanup(func() {
+		_ = config.SetProtocolDefaults(config.ProtocolSSH,
+			config.ProtocolDefaults{ConfigFile: info.ConfigFile, Port: info.DefaultPort, User: info.DefaultUser})
+	})
+
+	tests := []struct {
+		name                    string
+		defaultPort, hostPort   string
+		configPort, wantPortArg string
+	}{
+		{"settings default", "2200", "", "22", "2200"},
+		{"default set by ssh's config", "2200", "", "2222", ""},
+		{"host's own port", "2200", "2022", "2022", ""},
+		{"host's port overridden by Host *", "22", "2022", "2200", ""},
+		{"ssh's default", "22", "", "22", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := config.SetProtocolDefaults(config.ProtocolSSH, config.ProtocolDefaults{Port: tt.defaultPort}); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("FAKE_SSH_PORT", tt.configPort)
+			argv, err := SessionArgs(Target{Protocol: config.ProtocolSSH,
+				Spec: config.Spec{Alias: "web", HostName: "10.0.0.1", Port: tt.hostPort}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{filepath.Join(bin, "ssh"), "web"}
+			if tt.wantPortArg != "" {
+				want = []string{filepath.Join(bin, "ssh"), "-p", tt.wantPortArg, "web"}
+			}
+			if !slices.Equal(argv, want) {
+				t.Errorf("SessionArgs = %q, want %q", argv, want)
+			}
+		})
+	}
+}
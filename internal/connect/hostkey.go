@@ -0,0 +1,336 @@
+package connect
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/knownhosts"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// errHostKeyFetched aborts the handshake once the host key has been verified.
+var errHostKeyFetched = errors.New("host key fetched")
+
+// hostKeyAlgorithms are the host key (signature) algorithms we ask for,
+// most preferred first.
+var hostKeyAlgorithms = []string{
+	"ssh-ed25519",
+	"ecdsa-sha2-nistp256",
+	"ecdsa-sha2-nistp384",
+	"ecdsa-sha2-nistp521",
+	"rsa-sha2-512",
+	"rsa-sha2-256",
+	"ssh-rsa",
+	"ssh-dss",
+}
+
+// HostKey is a server's public host key.
+type HostKey struct {
+	Type string // key type from the blob (eg. "ssh-ed25519", "ssh-rsa")
+	Blob []byte // wire-format public key
+}
+
+// Fingerprint returns the key's SHA256 fingerprint.
+func (k HostKey) Fingerprint() string {
+	return knownhosts.Fingerprint(k.Blob)
+}
+
+// SSHPreflight is the result of an SSH protocol-level preflight.
+type SSHPreflight struct {
+	Banner string            // server identification (eg. "SSH-2.0-OpenSSH_9.6")
+	Key    HostKey           // host key (empty if KeyErr is set)
+	Known  knownhosts.Result // known_hosts check (valid if KeyErr is nil and NoKnownHosts is false)
+	KeyErr error             // why the host key couldn't be fetched/checked
+
+	NoKnownHosts bool // ssh keeps no known_hosts for the host, so the key wasn't checked
+}
+
+// Software returns the server software from the banner (eg. "OpenSSH_9.6").
+func (p SSHPreflight) Software() string {
+	return BannerSoftware(p.Banner)
+}
+
+// BannerSoftware returns the software version part of an SSH identification.
+func BannerSoftware(banner string) string {
+	parts := strings.SplitN(banner, "-", 3)
+	if len(parts) < 3 {
+		return banner
+	}
+	return parts[2]
+}
+
+// Note returns a one-line summary of the preflight for display before ssh runs.
+func (p SSHPreflight) Note() string {
+	parts := []string{p.Software()}
+	switch {
+	case p.KeyErr != nil:
+		parts = append(parts, "host key not checked: "+p.KeyErr.Error())
+	case p.NoKnownHosts:
+		parts = append(parts, p.Key.Type+" "+p.Key.Fingerprint(), "not checked, known_hosts is off for this host")
+	case p.Known.Status == knownhosts.StatusNew:
+		parts = append(parts, p.Key.Type+" "+p.Key.Fingerprint(), "new host, ssh will ask to confirm this key")
+	default:
+		parts = append(parts, p.Key.Type+" "+p.Key.Fingerprint(), p.Known.Status.String())
+	}
+	return strings.Join(parts, " • ")
+}
+
+// Problem returns a ConnectError if the host key must not be trusted
+// (changed or revoked), or nil if it's fine to hand over to ssh.
+func (p SSHPreflight) Problem(host string) *ConnectError {
+	if p.KeyErr != nil || p.NoKnownHosts {
+		return nil
+	}
+
+	where := ""
+	if len(p.Known.Matching) > 0 {
+		e := p.Known.Matching[0]
+		where = fmt.Sprintf(" (%s line %d)", e.File, e.Line)
+	}
+	switch p.Known.Status {
+	case knownhosts.StatusChanged:
+		return &ConnectError{
+			Kind:    FailureHostKeyChanged,
+			Summary: "KEY CHANGED",
+			Explanation: fmt.Sprintf("%s now presents %s %s, which doesn't match known_hosts%s. "+
+				"The server may have been reinstalled, or someone may be intercepting the connection.",
+				host, p.Key.Type, p.Key.Fingerprint(), where),
+			Suggestion: fmt.Sprintf("Verify the new key with the server's admin, then remove the old entry (ssh-keygen -R %s).", host),
+			ExitCode:   -1,
+		}
+	case knownhosts.StatusRevoked:
+		return &ConnectError{
+			Kind:        FailureHostKeyChanged,
+			Summary:     "KEY REVOKED",
+			Explanation: fmt.Sprintf("%s presents %s %s, which is marked @revoked%s.", host, p.Key.Type, p.Key.Fingerprint(), where),
+			Suggestion:  "Don't connect; contact the server's admin.",
+			ExitCode:    -1,
+		}
+	}
+	return nil
+}
+
+// PreflightSSH dials hostPort and checks that it speaks SSH, then fetches the
+// host key and compares it with the known_hosts files for host/port (the
+// name ssh will look up, usually the HostName). nil files uses the defaults;
+// an empty list means ssh keeps no known_hosts for the host, so the key is
+// fetched but not checked (see KnownHostsFiles).
+//
+// It only fails if the address can't be reached or isn't an SSH server;
+// problems fetching or checking the key are reported in KeyErr. The key is
+// only returned once the server has signed the key exchange with it.
+//
+// Canceling ctx aborts the dial and closes the connection mid-handshake; its
+// deadline (if any) bounds the whole check.
+func PreflightSSH(ctx context.Context, hostPort, host, port string, files []string) (SSHPreflight, error) {
+	var res SSHPreflight
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "tcp", hostPort)
+	if err != nil {
+		return res, err
+	}
+	defer c.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = c.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = c.Close() })
+	defer stop()
+
+	if files == nil {
+		files, _ = knownhosts.DefaultFiles()
+	}
+	res.NoKnownHosts = len(files) == 0
+	known, _ := knownhosts.Lookup(files, host, port)
+
+	rc := &identRecorder{Conn: c}
+	key, err := fetchHostKey(rc, hostPort, preferredHostKeyAlgorithms(known))
+	banner, identErr := rc.Banner()
+	res.Banner = banner
+	if banner == "" {
+		// the AfterFunc close hides why the read failed; report the context's reason
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return res, ctxErr
+		}
+		return res, identErr
+	}
+	if identErr != nil {
+		res.KeyErr = identErr
+		return res, nil
+	}
+	if err != nil {
+		res.KeyErr = err
+		return res, nil
+	}
+	res.Key = key
+	if !res.NoKnownHosts {
+		res.Known, res.KeyErr = knownhosts.Check(files, host, port, key.Type, key.Blob)
+	}
+	return res, nil
+}
+
+// preferredHostKeyAlgorithms puts the algorithms for key types already in
+// known_hosts first, so the comparison uses a key ssh would also check.
+func preferredHostKeyAlgorithms(known []knownhosts.Entry) []string {
+	var out []string
+	for _, e := range known {
+		for _, alg := range hostKeyAlgorithms {
+			if hostKeyType(alg) == e.KeyType && !slices.Contains(out, alg) {
+				out = append(out, alg)
+			}
+		}
+	}
+	for _, alg := range hostKeyAlgorithms {
+		if !slices.Contains(out, alg) {
+			out = append(out, alg)
+		}
+	}
+	return out
+}
+
+// hostKeyType returns the key type used by a host key signature algorithm.
+func hostKeyType(alg string) string {
+	if strings.HasPrefix(alg, "rsa-sha2-") {
+		return "ssh-rsa"
+	}
+	return alg
+}
+
+// fetchHostKey runs the key exchange with the server on c and returns the
+// host key once the server has proven it owns it (signed the exchange hash).
+// The handshake is abandoned there, before authentication.
+//
+// Every algorithm x/crypto/ssh implements is offered, including the insecure
+// ones, so old servers can still be checked.
+func fetchHostKey(c net.Conn, addr string, hostKeyAlgs []string) (HostKey, error) {
+	supported, insecure := ssh.SupportedAlgorithms(), ssh.InsecureAlgorithms()
+	var key HostKey
+	cfg := &ssh.ClientConfig{
+		Config: ssh.Config{
+			KeyExchanges: append(supported.KeyExchanges, insecure.KeyExchanges...),
+			Ciphers:      append(supported.Ciphers, insecure.Ciphers...),
+			MACs:         append(supported.MACs, insecure.MACs...),
+		},
+		ClientVersion:     probeIdent,
+		HostKeyAlgorithms: hostKeyAlgs,
+		HostKeyCallback: func(_ string, _ net.Addr, k ssh.PublicKey) error {
+			key = HostKey{Type: k.Type(), Blob: k.Marshal()}
+			return errHostKeyFetched
+		},
+	}
+	_, _, _, err := ssh.NewClientConn(c, addr, cfg)
+	if key.Blob != nil {
+		return key, nil
+	}
+	var negErr *ssh.AlgorithmNegotiationError
+	if errors.As(err, &negErr) {
+		return HostKey{}, fmt.Errorf("no supported %s (server offers %s)", negErr.What, strings.Join(negErr.RequestedAlgorithms, ","))
+	}
+	return HostKey{}, err
+}
+
+// identRecorder keeps what is read from a connection until the server's
+// identification line has gone by, so the banner can be reported even if
+// the handshake fails after it.
+type identRecorder struct {
+	net.Conn
+	read []byte
+	done bool
+}
+
+func (r *identRecorder) Read(p []byte) (int, error) {
+	n, err := r.Conn.Read(p)
+	if !r.done {
+		r.read = append(r.read, p[:n]...)
+		r.done = hasIdentLine(r.read) || len(r.read) > maxBannerLines*(maxBannerLine+2)
+	}
+	return n, err
+}
+
+// Banner returns the server identification read so far.
+func (r *identRecorder) Banner() (string, error) {
+	return readServerIdent(bufio.NewReader(bytes.NewReader(r.read)))
+}
+
+// hasIdentLine reports whether b holds a complete "SSH-" line.
+func hasIdentLine(b []byte) bool {
+	for {
+		line, rest, ok := bytes.Cut(b, []byte("\n"))
+		if !ok {
+			return false
+		}
+		if bytes.HasPrefix(line, []byte("SSH-")) {
+			return true
+		}
+		b = rest
+	}
+}
+
+// KnownHostsFiles returns the known_hosts files ssh uses for alias
+// (UserKnownHostsFile) and whether it hashes the host names it adds
+// (HashKnownHosts), as reported by ssh -G. If ssh can't tell, it returns
+// the default files.
+//
+// If ssh keeps no known_hosts for alias (UserKnownHostsFile none or
+// /dev/null), files is empty but not nil.
+func KnownHostsFiles(alias string) (files []string, hash bool) {
+	files, _ = knownhosts.DefaultFiles()
+	ssh, err := preferredProgramPath(string(config.ProtocolSSH))
+	if err != nil {
+		return files, false
+	}
+	out, err := exec.Command(ssh, append(SSHConfigArgs(alias), "-G", alias)...).Output()
+	if err != nil {
+		return files, false
+	}
+	return parseKnownHostsConfig(out, files)
+}
+
+// parseKnownHostsConfig reads the known_hosts settings from ssh -G output,
+// keeping files if it doesn't name any.
+func parseKnownHostsConfig(out []byte, files []string) ([]string, bool) {
+	hash := false
+	for line := range bytes.Lines(out) {
+		k, v, ok := strings.Cut(strings.TrimSpace(string(line)), " ")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(k) {
+		case "userknownhostsfile":
+			fromSSH := []string{}
+			for _, f := range strings.Fields(v) {
+				if f == "none" || f == "/dev/null" {
+					continue
+				}
+				if p, err := config.ExpandPath(expandHomeToken(f)); err == nil {
+					fromSSH = append(fromSSH, p)
+				}
+			}
+			files = fromSSH
+		case "hashknownhosts":
+			hash = strings.EqualFold(v, "yes")
+		}
+	}
+	return files, hash
+}
+
+// expandHomeToken expands the %d (home directory) token ssh allows in
+// UserKnownHostsFile, using the same home as the rest of the app.
+func expandHomeToken(path string) string {
+	if !strings.Contains(path, "%d") {
+		return path
+	}
+	home, err := config.GetConfigPath()
+	if err != nil {
+		return path
+	}
+	return strings.ReplaceAll(path, "%d", home)
+}
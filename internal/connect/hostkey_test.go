@@ -0,0 +1,166 @@
+package connect
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"bubbletea-ssh-manager/internal/knownhosts"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseKnownHostsConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	defaults := []string{"default"}
+	tests := []struct {
+		name  string
+		out   string
+		files []string
+		hash  bool
+	}{
+		{"not set", "user alice\nport 22\n", defaults, false},
+		{"files", "userknownhostsfile ~/.ssh/known_hosts /etc/team_hosts\nhashknownhosts yes\n",
+			[]string{filepath.Join(home, ".ssh", "known_hosts"), "/etc/team_hosts"}, true},
+		{"home token", "userknownhostsfile %d/kh\n", []string{filepath.Join(home, "kh")}, false},
+		{"none", "userknownhostsfile none\n", []string{}, false},
+		{"dev null", "UserKnownHostsFile /dev/null\nhashknownhosts no\n", []string{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, hash := parseKnownHostsConfig([]byte(tt.out), defaults)
+			if !slices.Equal(files, tt.files) || (files == nil) != (tt.files == nil) || hash != tt.hash {
+				t.Errorf("parseKnownHostsConfig = %q (nil %v), %v; want %q, %v", files, files == nil, hash, tt.files, tt.hash)
+			}
+		})
+	}
+}
+
+func TestSSHPreflightNoKnownHosts(t *testing.T) {
+	p := SSHPreflight{Banner: "SSH-2.0-OpenSSH_9.6", Key: HostKey{Type: "ssh-ed25519", Blob: []byte("key")}, NoKnownHosts: true}
+	if f := p.Problem("web"); f != nil {
+		t.Errorf("Problem = %+v, want nil", f)
+	}
+	if note := p.Note(); !strings.Contains(note, "known_hosts is off") || strings.Contains(note, "new host") {
+		t.Errorf("Note = %q", note)
+	}
+}
+
+// newSigner returns a fresh ed25519 host key.
+func newSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// impostor presents one host key but signs with another, like a
+// man in the middle replaying a server's public key.
+type impostor struct {
+	ssh.Signer
+	pub ssh.PublicKey
+}
+
+func (i impostor) PublicKey() ssh.PublicKey { return i.pub }
+
+// serve accepts connections on a local listener and hands each to handle.
+func serve(t *testing.T, handle func(net.Conn)) (host, port string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				handle(c)
+			}()
+		}
+	}()
+	host, port, _ = net.SplitHostPort(ln.Addr().String())
+	return host, port
+}
+
+// serveSSH runs an SSH server with the given host key.
+func serveSSH(t *testing.T, signer ssh.Signer) (host, port string) {
+	cfg := &ssh.ServerConfig{NoClientAuth: true}
+	cfg.AddHostKey(signer)
+	return serve(t, func(c net.Conn) { _, _, _, _ = ssh.NewServerConn(c, cfg) })
+}
+
+func TestPreflightSSH(t *testing.T) {
+	signer := newSigner(t)
+	host, port := serveSSH(t, signer)
+	files := []string{filepath.Join(t.TempDir(), "known_hosts")}
+	preflight := func() SSHPreflight {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		res, err := PreflightSSH(ctx, net.JoinHostPort(host, port), host, port, files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.KeyErr != nil {
+			t.Fatalf("KeyErr = %v", res.KeyErr)
+		}
+		return res
+	}
+
+	res := preflight()
+	want := signer.PublicKey()
+	if !strings.HasPrefix(res.Banner, "SSH-2.0-Go") || res.Key.Type != want.Type() || string(res.Key.Blob) != string(want.Marshal()) {
+		t.Fatalf("preflight = %q, %s %s; want the server's %s %s", res.Banner, res.Key.Type, res.Key.Fingerprint(),
+			want.Type(), ssh.FingerprintSHA256(want))
+	}
+	if res.Known.Status != knownhosts.StatusNew {
+		t.Errorf("empty known_hosts: %s, want new host", res.Known.Status)
+	}
+
+	if err := knownhosts.Add(files[0], host, port, res.Key.Type, res.Key.Blob, false); err != nil {
+		t.Fatal(err)
+	}
+	if res := preflight(); res.Known.Status != knownhosts.StatusMatches {
+		t.Errorf("after adding the key: %s, want key matches", res.Known.Status)
+	}
+}
+
+func TestPreflightSSHUnverifiedKey(t *testing.T) {
+	host, port := serveSSH(t, impostor{Signer: newSigner(t), pub: newSigner(t).PublicKey()})
+	res, err := PreflightSSH(context.Background(), net.JoinHostPort(host, port), host, port, []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Banner == "" || res.KeyErr == nil || !strings.Contains(res.KeyErr.Error(), "signature") || res.Key.Blob != nil {
+		t.Errorf("preflight = %q, key %x, KeyErr %v; want the banner and a signature error", res.Banner, res.Key.Blob, res.KeyErr)
+	}
+}
+
+func TestPreflightSSHNotSSH(t *testing.T) {
+	host, port := serve(t, func(c net.Conn) {
+		_, _ = io.WriteString(c, "HTTP/1.1 400 Bad Request\r\n\r\n")
+	})
+	res, err := PreflightSSH(context.Background(), net.JoinHostPort(host, port), host, port, []string{})
+	if err == nil || !strings.Contains(err.Error(), "not an SSH server") || res.Banner != "" {
+		t.Errorf("preflight = %q, %v; want a not-an-SSH-server error", res.Banner, err)
+	}
+}
@@ -0,0 +1,139 @@
+package connect
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+// maxJumpDepth is the maximum number of hops followed when resolving a ProxyJump chain.
+const maxJumpDepth = 8
+
+// JumpLookup returns the spec and SSH options for a known SSH alias.
+//
+// It is used to resolve ProxyJump hops that reference other config aliases.
+type JumpLookup func(alias string) (config.Spec, config.SSHOptions, bool)
+
+// ParseJumpHop parses a single ProxyJump hop of the form [user@]host[:port].
+//
+// The host is stored as the Alias (ssh resolves it the same way), and HostName
+// is left empty so callers can fill it in from a matching config alias.
+func ParseJumpHop(raw string) (config.Spec, error) {
+	raw = strings.TrimSpace(raw)
+	raw = strings.TrimPrefix(raw, "ssh://")
+	if raw == "" {
+		return config.Spec{}, fmt.Errorf("empty jump host")
+	}
+
+	var hop config.Spec
+	if u, rest, ok := strings.Cut(raw, "@"); ok {
+		hop.User = u
+		raw = rest
+	}
+	hop.Alias = raw
+	if h, p, err := net.SplitHostPort(raw); err == nil {
+		hop.Alias = h
+		hop.Port = p
+	}
+	if hop.Alias == "" {
+		return config.Spec{}, fmt.Errorf("invalid jump host %q", raw)
+	}
+	return hop.Normalized(), nil
+}
+
+// ResolveJumpChain expands a ProxyJump value into the ordered list of hops
+// (first hop first).
+//
+// Hops that match a known alias use that alias's HostName/Port/User, and any
+// ProxyJump configured on the hop itself is expanded in front of it, mirroring
+// how ssh chains jump hosts. A value of "none" (or empty) yields no hops.
+func ResolveJumpChain(proxyJump string, lookup JumpLookup) ([]config.Spec, error) {
+	return resolveJumpChain(proxyJump, lookup, map[string]bool{}, 0)
+}
+
+func resolveJumpChain(proxyJump string, lookup JumpLookup, visiting map[string]bool, depth int) ([]config.Spec, error) {
+	proxyJump = strings.TrimSpace(proxyJump)
+	if proxyJump == "" || strings.EqualFold(proxyJump, "none") {
+		return nil, nil
+	}
+	if depth > maxJumpDepth {
+		return nil, fmt.Errorf("jump chain too deep")
+	}
+
+	var chain []config.Spec
+	for raw := range strings.SplitSeq(proxyJump, ",") {
+		hop, err := ParseJumpHop(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		if lookup != nil {
+			if spec, opts, ok := lookup(hop.Alias); ok {
+				if visiting[hop.Alias] {
+					return nil, fmt.Errorf("jump chain loop at %q", hop.Alias)
+				}
+				visiting[hop.Alias] = true
+				before, err := resolveJumpChain(opts.ProxyJump, lookup, visiting, depth+1)
+				delete(visiting, hop.Alias)
+				if err != nil {
+					return nil, err
+				}
+				chain = append(chain, before...)
+
+				// explicit user/port on the hop win over the alias config
+				hop.HostName = spec.HostName
+				if hop.User == "" {
+					hop.User = spec.User
+				}
+				if hop.Port == "" {
+					hop.Port = spec.Port
+				}
+			}
+		}
+		chain = append(chain, hop)
+	}
+	return chain, nil
+}
+
+// FirstHop returns the first hop of the target's jump chain.
+//
+// ok is false if the target connects directly.
+func (t Target) FirstHop() (hop config.Spec, ok bool) {
+	if len(t.Jumps) == 0 {
+		return config.Spec{}, false
+	}
+	return t.Jumps[0], true
+}
+
+// FailedHop returns which host of t's connection a failure was with: one
+// of its jump hops (an index into t.Jumps), or the target itself (-1).
+// ok is false if it can't be told.
+//
+// Only a failure that names its peer (see ConnectError.Peer) can be told
+// apart. ssh dials the first hop itself, so it names that hop's address;
+// hosts reached through a hop have no address of their own, and show as
+// "UNKNOWN".
+func (t Target) FailedHop(f *ConnectError) (hop int, ok bool) {
+	switch {
+	case len(t.Jumps) == 0:
+		return -1, true
+	case f == nil || f.Peer == "":
+		return 0, false
+	case f.Peer != "UNKNOWN":
+		return 0, true
+	case len(t.Jumps) == 1:
+		return -1, true
+	}
+	return 0, false // the target or a later hop
+}
+
+// hopDialHost returns the address ssh would dial for a hop
+// (HostName if set, otherwise the alias itself).
+func hopDialHost(hop config.Spec) string {
+	if hop.HostName != "" {
+		return hop.HostName
+	}
+	return hop.Alias
+}
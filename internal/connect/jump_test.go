@@ -0,0 +1,44 @@
+package connect
+
+import (
+	"errors"
+	"testing"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+func TestFailedHop(t *testing.T) {
+	hops := func(aliases ...string) []config.Spec {
+		var out []config.Spec
+		for _, a := range aliases {
+			out = append(out, config.Spec{Alias: a})
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		jumps  []config.Spec
+		output string
+		hop    int
+		ok     bool
+	}{
+		{"direct", nil, "Unable to negotiate with 10.0.0.5 port 22", -1, true},
+		{"first hop", hops("bastion"), "Unable to negotiate with 10.0.0.1 port 22", 0, true},
+		{"target through one hop", hops("bastion"), "Unable to negotiate with UNKNOWN port 65535", -1, true},
+		{"behind several hops", hops("a", "b"), "Unable to negotiate with UNKNOWN port 65535", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := tt.output + ": no matching key exchange method found. Their offer: diffie-hellman-group1-sha1"
+			f := ClassifyFailure(config.ProtocolSSH, output, errors.New("exit status 255"))
+			if f == nil || f.Fix == nil {
+				t.Fatalf("ClassifyFailure(%q) = %+v, want a fix", output, f)
+			}
+			tgt := Target{Protocol: config.ProtocolSSH, Spec: config.Spec{Alias: "web"}, Jumps: tt.jumps}
+			hop, ok := tgt.FailedHop(f)
+			if hop != tt.hop || ok != tt.ok {
+				t.Errorf("FailedHop = %d, %v; want %d, %v", hop, ok, tt.hop, tt.ok)
+			}
+		})
+	}
+}
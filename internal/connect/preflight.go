@@ -1,7 +1,13 @@
 package connect
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"bubbletea-ssh-manager/internal/config"
@@ -9,13 +15,19 @@ import (
 
 // ShouldPreflight returns true if the given Target requires a reachability check.
 //
-// Telnet always requires preflight (host/port).
-// SSH requires preflight if a hostname is set (host/port for display & checks).
+// It follows the protocol's preflight strategy:
+//   - tcp (eg. telnet, raw) dials host:port when both are known.
+//   - ssh (ssh, mosh) also requires a hostname (host/port for display & checks),
+//     or a jump chain (the first hop is checked instead).
 func ShouldPreflight(t Target) bool {
-	switch t.Protocol {
-	case config.ProtocolTelnet:
-		return true
-	case config.ProtocolSSH:
+	info, _ := config.LookupProtocol(t.Protocol)
+	switch info.Preflight {
+	case config.PreflightTCP:
+		return t.HostName != "" && t.Port != ""
+	case config.PreflightSSH:
+		if _, ok := t.FirstHop(); ok {
+			return true
+		}
 		return t.HostName != ""
 	default:
 		return false
@@ -23,7 +35,18 @@ func ShouldPreflight(t Target) bool {
 }
 
 // GenerateHostPort returns the host or host:port string used for preflight.
+//
+// For targets behind a ProxyJump chain, the workstation can only reach the
+// first hop, so that is what gets dialed.
 func GenerateHostPort(t Target) string {
+	if hop, ok := t.FirstHop(); ok {
+		port := hop.Port
+		if port == "" {
+			port = "22"
+		}
+		return net.JoinHostPort(hopDialHost(hop), port)
+	}
+
 	host := t.HostName
 	port := t.Port
 	if host == "" {
@@ -35,16 +58,175 @@ func GenerateHostPort(t Target) string {
 	return net.JoinHostPort(host, port)
 }
 
-// PreflightDial attempts to open a TCP connection to hostPort within timeout.
-func PreflightDial(hostPort string, timeout time.Duration) error {
+// KnownHostsName returns the host and port ssh looks up in known_hosts for the
+// host that preflight dials (the first jump hop, if any).
+func KnownHostsName(t Target) (host, port string) {
+	if hop, ok := t.FirstHop(); ok {
+		return hopDialHost(hop), cmp.Or(hop.Port, "22")
+	}
+	return cmp.Or(t.HostName, t.Alias), cmp.Or(t.Port, "22")
+}
+
+// PreflightDial attempts to open a TCP connection to hostPort.
+//
+// The dial is aborted when ctx is canceled or its deadline passes.
+func PreflightDial(ctx context.Context, hostPort string) error {
 	if hostPort == "" {
 		return nil
 	}
 
-	d := net.Dialer{Timeout: timeout}
-	c, err := d.Dial("tcp", hostPort)
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "tcp", hostPort)
 	if c != nil {
 		_ = c.Close()
 	}
 	return err
 }
+
+const (
+	DefaultPreflightTimeout = 10 * time.Second // per-attempt timeout
+	DefaultPreflightRetries = 2                // retries after a retryable failure
+	DefaultPreflightBackoff = 1 * time.Second  // delay before the first retry
+
+	maxPreflightBackoff = 30 * time.Second
+	maxPreflightRetries = 10
+)
+
+var (
+	defaultsMu sync.RWMutex // guards the defaults the settings can change while the menu runs
+
+	preflightDefaults = PreflightPolicy{
+		Timeout: DefaultPreflightTimeout,
+		Retries: DefaultPreflightRetries,
+		Backoff: DefaultPreflightBackoff,
+	}
+)
+
+// PreflightPolicy controls how long each preflight attempt may take and how
+// often a flaky link is retried.
+type PreflightPolicy struct {
+	Timeout time.Duration // per-attempt timeout
+	Retries int           // retries after the first attempt
+	Backoff time.Duration // delay before the first retry; doubles each retry
+}
+
+// DefaultPreflightPolicy returns the global preflight policy: the built-in
+// defaults, or the timeout and retries set with SetPreflightDefaults.
+func DefaultPreflightPolicy() PreflightPolicy {
+	defaultsMu.RLock()
+	defer defaultsMu.RUnlock()
+	return preflightDefaults
+}
+
+// SetPreflightDefaults sets the global preflight timeout and retries (from
+// the settings). Values out of range keep the built-in defaults.
+func SetPreflightDefaults(timeout time.Duration, retries int) {
+	p := PreflightPolicy{Timeout: DefaultPreflightTimeout, Retries: DefaultPreflightRetries, Backoff: DefaultPreflightBackoff}
+	if timeout > 0 {
+		p.Timeout = timeout
+	}
+	if retries >= 0 && retries <= maxPreflightRetries {
+		p.Retries = retries
+	}
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	preflightDefaults = p
+}
+
+// ForHost returns the policy with the host's PreflightTimeout/PreflightRetries
+// applied on top. Empty or invalid host values keep the policy's own.
+func (p PreflightPolicy) ForHost(o config.AppOptions) PreflightPolicy {
+	if d, err := ParsePreflightTimeout(o.PreflightTimeout); err == nil && d > 0 {
+		p.Timeout = d
+	}
+	if n, err := ParsePreflightRetries(o.PreflightRetries); err == nil && n >= 0 {
+		p.Retries = n
+	}
+	return p
+}
+
+// String describes the policy (eg. "10s, 2 retries").
+func (p PreflightPolicy) String() string {
+	switch p.Retries {
+	case 0:
+		return p.Timeout.String() + ", no retries"
+	case 1:
+		return p.Timeout.String() + ", 1 retry"
+	default:
+		return p.Timeout.String() + ", " + strconv.Itoa(p.Retries) + " retries"
+	}
+}
+
+// Attempts returns the total number of attempts (at least 1).
+func (p PreflightPolicy) Attempts() int {
+	return 1 + max(p.Retries, 0)
+}
+
+// Delay returns how long to wait before the given retry (1-based), doubling
+// from Backoff up to a cap.
+func (p PreflightPolicy) Delay(retry int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < retry && d < maxPreflightBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxPreflightBackoff)
+}
+
+// ParsePreflightTimeout parses a timeout like "15s" or "1m". A bare number is
+// taken as seconds. It returns 0 and no error for an empty string.
+func ParsePreflightTimeout(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		s = strconv.Itoa(n) + "s"
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("timeout must be positive")
+	}
+	return d, nil
+}
+
+// ParsePreflightRetries parses a retry count. It returns -1 and no error for
+// an empty string (meaning "use the default").
+func ParsePreflightRetries(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return -1, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return -1, err
+	}
+	if n < 0 || n > maxPreflightRetries {
+		return -1, errors.New("retries must be between 0 and " + strconv.Itoa(maxPreflightRetries))
+	}
+	return n, nil
+}
+
+// IsRetryablePreflightError reports whether a preflight failure may be
+// transient (timeouts, unreachable networks, resets), so another attempt could
+// succeed. Cancellation, DNS failures, refused connections and non-SSH
+// servers are final.
+func IsRetryablePreflightError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	ce := classifyText(err.Error())
+	if ce == nil {
+		return strings.Contains(err.Error(), "connection reset")
+	}
+	return ce.Kind == FailureTimeout || ce.Kind == FailureUnreachable
+}
@@ -0,0 +1,389 @@
+package connect
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+const (
+	probeIdent       = "SSH-2.0-btms_probe"
+	msgKexInit       = 20    // SSH_MSG_KEXINIT
+	maxBannerLines   = 32    // lines a server may send before its identification
+	maxBannerLine    = 255   // RFC 4253: identification line length limit
+	maxPacketLength  = 35000 // RFC 4253: minimum every implementation must handle
+	kexInitCookieLen = 16
+)
+
+// Algorithms lists SSH algorithms by negotiation category.
+type Algorithms struct {
+	Kex     []string // key exchange (KexAlgorithms)
+	HostKey []string // host key (HostKeyAlgorithms)
+	Ciphers []string // ciphers (Ciphers)
+	MACs    []string // MACs (MACs)
+}
+
+// ProbeResult is the outcome of an algorithm probe against an SSH server.
+type ProbeResult struct {
+	Banner    string      // server identification (eg. "SSH-2.0-OpenSSH_5.3")
+	Server    Algorithms  // what the server offers
+	Enabled   Algorithms  // what the local ssh enables for the host
+	Additions []OptionFix // minimal +alg additions needed to connect
+	Missing   []string    // options with nothing in common that the local ssh can't fix
+}
+
+// algorithmCategory ties an Algorithms field to its ssh config option and query name.
+type algorithmCategory struct {
+	option string                      // ssh config option
+	query  string                      // ssh -Q name
+	field  func(*Algorithms) *[]string // accessor
+}
+
+var algorithmCategories = []algorithmCategory{
+	{"KexAlgorithms", "kex", func(a *Algorithms) *[]string { return &a.Kex }},
+	{"HostKeyAlgorithms", "key-sig", func(a *Algorithms) *[]string { return &a.HostKey }},
+	{"Ciphers", "cipher", func(a *Algorithms) *[]string { return &a.Ciphers }},
+	{"MACs", "mac", func(a *Algorithms) *[]string { return &a.MACs }},
+}
+
+// ReadServerKexInit performs the start of an SSH handshake over rw: it sends
+// a client identification, reads the server's, then reads and parses the
+// server's (unencrypted) KEXINIT packet. Nothing else is sent, so no keys are
+// exchanged and no authentication happens.
+//
+// rw is usually a net.Conn, but any stream works (eg. net.Pipe in a fake server).
+func ReadServerKexInit(rw io.ReadWriter) (banner string, algs Algorithms, err error) {
+	banner, lists, err := startHandshake(rw)
+	if err != nil {
+		return banner, Algorithms{}, err
+	}
+	return banner, lists.algorithms(), nil
+}
+
+// startHandshake exchanges identifications and reads the server's KEXINIT.
+//
+// Both sides send their identification first, so ours is written
+// concurrently to avoid deadlocking on unbuffered streams.
+func startHandshake(rw io.ReadWriter) (banner string, lists kexInitLists, err error) {
+	wrote := make(chan error, 1)
+	go func() {
+		_, err := io.WriteString(rw, probeIdent+"\r\n")
+		wrote <- err
+	}()
+
+	br := bufio.NewReader(rw)
+	banner, err = readServerIdent(br)
+	if err != nil {
+		return "", lists, err
+	}
+	if err := <-wrote; err != nil {
+		return banner, lists, err
+	}
+
+	payload, err := readPacket(br)
+	if err != nil {
+		return banner, lists, fmt.Errorf("reading KEXINIT: %w", err)
+	}
+	lists, err = parseKexInit(payload)
+	return banner, lists, err
+}
+
+// readServerIdent reads lines until the "SSH-" identification line.
+//
+// Servers may send other lines first (RFC 4253 section 4.2).
+func readServerIdent(br *bufio.Reader) (string, error) {
+	for range maxBannerLines {
+		line, err := br.ReadString('\n')
+		if len(line) > maxBannerLine+2 {
+			return "", errors.New("server identification too long")
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "SSH-") {
+			if !strings.HasPrefix(line, "SSH-2.0-") && !strings.HasPrefix(line, "SSH-1.99-") {
+				return line, fmt.Errorf("unsupported SSH version: %s", line)
+			}
+			return line, nil
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", errors.New("connection closed before SSH identification (not an SSH server?)")
+			}
+			return "", err
+		}
+	}
+	return "", errors.New("no SSH identification from server (not an SSH server?)")
+}
+
+// readPacket reads one unencrypted binary packet and returns its payload.
+func readPacket(r io.Reader) ([]byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(hdr[:4])
+	padding := uint32(hdr[4])
+	if length < 1+padding || length > maxPacketLength {
+		return nil, fmt.Errorf("invalid packet length %d", length)
+	}
+	rest := make([]byte, length-1)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, err
+	}
+	return rest[:len(rest)-int(padding)], nil
+}
+
+// kexInitLists holds the ten name-lists of a KEXINIT, in wire order: kex,
+// host key, cipher c2s/s2c, mac c2s/s2c, compression c2s/s2c, language c2s/s2c.
+type kexInitLists [10][]string
+
+// parseKexInit parses a KEXINIT payload (RFC 4253 section 7.1).
+func parseKexInit(p []byte) (kexInitLists, error) {
+	var lists kexInitLists
+	if len(p) < 1+kexInitCookieLen || p[0] != msgKexInit {
+		return lists, errors.New("expected KEXINIT from server")
+	}
+	p = p[1+kexInitCookieLen:]
+
+	for i := range lists {
+		s, rest, ok := readString(p)
+		if !ok {
+			return lists, errors.New("truncated KEXINIT")
+		}
+		lists[i] = splitNameList(string(s))
+		p = rest
+	}
+	return lists, nil
+}
+
+// algorithms returns the negotiable algorithms (ciphers and MACs must work both ways).
+func (l kexInitLists) algorithms() Algorithms {
+	return Algorithms{
+		Kex:     l[0],
+		HostKey: l[1],
+		Ciphers: intersect(l[2], l[3]),
+		MACs:    intersect(l[4], l[5]),
+	}
+}
+
+// readString reads an SSH string (uint32 length + bytes) from p.
+func readString(p []byte) (s, rest []byte, ok bool) {
+	if len(p) < 4 {
+		return nil, p, false
+	}
+	n := binary.BigEndian.Uint32(p[:4])
+	if uint32(len(p)-4) < n {
+		return nil, p, false
+	}
+	return p[4 : 4+n], p[4+n:], true
+}
+
+// ProbeServer dials addr and reads the server's KEXINIT within timeout.
+func ProbeServer(addr string, timeout time.Duration) (string, Algorithms, error) {
+	c, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return "", Algorithms{}, err
+	}
+	defer c.Close()
+	_ = c.SetDeadline(time.Now().Add(timeout))
+	return ReadServerKexInit(c)
+}
+
+// ReadBanner dials hostPort and reads the server's SSH identification line.
+// Nothing is sent, so the server only sees a connection that goes away.
+//
+// The dial and read are bounded by ctx.
+func ReadBanner(ctx context.Context, hostPort string) (string, error) {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "tcp", hostPort)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = c.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = c.Close() })
+	defer stop()
+
+	banner, err := readServerIdent(bufio.NewReader(c))
+	if err != nil && ctx.Err() != nil {
+		return banner, ctx.Err()
+	}
+	return banner, err
+}
+
+// LocalAlgorithms asks the local ssh which algorithms it enables for alias
+// (ssh -G, which includes the host's config) and which it supports at all
+// (ssh -Q).
+func LocalAlgorithms(alias string) (enabled, supported Algorithms, err error) {
+	ssh, err := preferredProgramPath(string(config.ProtocolSSH))
+	if err != nil {
+		return Algorithms{}, Algorithms{}, fmt.Errorf("ssh not found: %w", err)
+	}
+
+	out, err := exec.Command(ssh, append(SSHConfigArgs(alias), "-G", alias)...).Output()
+	if err != nil {
+		return Algorithms{}, Algorithms{}, fmt.Errorf("ssh -G %s: %w", alias, err)
+	}
+	enabled = parseSSHConfigDump(out)
+
+	for _, c := range algorithmCategories {
+		out, err := exec.Command(ssh, "-Q", c.query).Output()
+		if err != nil && c.query == "key-sig" {
+			// older OpenSSH has no key-sig query; key types are close enough
+			out, err = exec.Command(ssh, "-Q", "key").Output()
+		}
+		if err != nil {
+			return Algorithms{}, Algorithms{}, fmt.Errorf("ssh -Q %s: %w", c.query, err)
+		}
+		*c.field(&supported) = strings.Fields(string(out))
+	}
+	return enabled, supported, nil
+}
+
+// parseSSHConfigDump extracts the algorithm lists from `ssh -G` output.
+func parseSSHConfigDump(out []byte) Algorithms {
+	var a Algorithms
+	for line := range bytes.Lines(out) {
+		k, v, ok := strings.Cut(strings.TrimSpace(string(line)), " ")
+		if !ok {
+			continue
+		}
+		for _, c := range algorithmCategories {
+			if strings.EqualFold(k, c.option) {
+				*c.field(&a) = splitNameList(v)
+			}
+		}
+	}
+	return a
+}
+
+// ProposeAdditions returns the fewest +alg additions that give each category
+// an algorithm in common, picking the server's most preferred one that the
+// local ssh supports. Categories where that's impossible are returned as missing.
+//
+// MACs are skipped when the cipher that would be negotiated has built-in
+// integrity (eg. aes256-gcm@openssh.com), since ssh doesn't negotiate a MAC then.
+func ProposeAdditions(server, enabled, supported Algorithms) (adds []OptionFix, missing []string) {
+	cipher := ""
+	for _, c := range algorithmCategories {
+		offered := usableAlgorithms(*c.field(&server))
+		mine := *c.field(&enabled)
+		if len(offered) == 0 {
+			continue
+		}
+		if c.option == "MACs" && isAEADCipher(cipher) {
+			continue
+		}
+
+		if shared := firstShared(mine, offered); shared != "" {
+			if c.option == "Ciphers" {
+				cipher = shared
+			}
+			continue
+		}
+
+		add := firstShared(offered, *c.field(&supported))
+		if add == "" {
+			missing = append(missing, c.option)
+			continue
+		}
+		adds = append(adds, OptionFix{Option: c.option, Algorithm: add})
+		if c.option == "Ciphers" {
+			cipher = add
+		}
+	}
+	return adds, missing
+}
+
+// Probe runs the full algorithm probe for an ssh host alias reachable at addr.
+func Probe(alias, addr string, timeout time.Duration) (ProbeResult, error) {
+	var r ProbeResult
+	enabled, supported, err := LocalAlgorithms(alias)
+	if err != nil {
+		return r, err
+	}
+	banner, server, err := ProbeServer(addr, timeout)
+	r.Banner = banner
+	if err != nil {
+		return r, err
+	}
+	r.Server = server
+	r.Enabled = enabled
+	r.Additions, r.Missing = ProposeAdditions(server, enabled, supported)
+	return r, nil
+}
+
+// AlgorithmOptions returns the ssh config options covered by Algorithms, in display order.
+func AlgorithmOptions() []string {
+	out := make([]string, 0, len(algorithmCategories))
+	for _, c := range algorithmCategories {
+		out = append(out, c.option)
+	}
+	return out
+}
+
+// List returns the algorithms for the given ssh config option (eg. "MACs").
+func (a Algorithms) List(option string) []string {
+	for _, c := range algorithmCategories {
+		if strings.EqualFold(c.option, option) {
+			return *c.field(&a)
+		}
+	}
+	return nil
+}
+
+// usableAlgorithms drops the pseudo-algorithms used for extension signalling.
+func usableAlgorithms(names []string) []string {
+	return slices.DeleteFunc(slices.Clone(names), func(n string) bool {
+		return strings.HasPrefix(n, "ext-info-") || strings.HasPrefix(n, "kex-strict-")
+	})
+}
+
+// isAEADCipher reports whether cipher provides its own integrity.
+func isAEADCipher(cipher string) bool {
+	return strings.Contains(cipher, "-gcm@") || strings.HasPrefix(cipher, "chacha20-poly1305")
+}
+
+// firstShared returns the first entry of prefer that also appears in other.
+func firstShared(prefer, other []string) string {
+	for _, p := range prefer {
+		if slices.Contains(other, p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// intersect returns the entries of a that are also in b, in a's order.
+func intersect(a, b []string) []string {
+	var out []string
+	for _, s := range a {
+		if slices.Contains(b, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// splitNameList splits an SSH name-list.
+func splitNameList(s string) []string {
+	var out []string
+	for n := range strings.SplitSeq(s, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,174 @@
+package connect
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// kexInitPacket returns a binary packet carrying a KEXINIT with the given
+// ten name-lists.
+func kexInitPacket(lists [10]string) []byte {
+	payload := []byte{msgKexInit}
+	payload = append(payload, make([]byte, kexInitCookieLen)...)
+	for _, l := range lists {
+		payload = binary.BigEndian.AppendUint32(payload, uint32(len(l)))
+		payload = append(payload, l...)
+	}
+	payload = append(payload, 0, 0, 0, 0, 0) // first_kex_packet_follows, reserved
+
+	padding := 8 - (5+len(payload))%8
+	if padding < 4 {
+		padding += 8
+	}
+	pkt := binary.BigEndian.AppendUint32(nil, uint32(1+len(payload)+padding))
+	pkt = append(pkt, byte(padding))
+	pkt = append(pkt, payload...)
+	return append(pkt, make([]byte, padding)...)
+}
+
+// serveKexInit plays a server on conn: it sends pre-banner lines, its
+// identification and a KEXINIT, and returns what the client sent first.
+func serveKexInit(conn net.Conn, banner string, lists [10]string) <-chan string {
+	got := make(chan string, 1)
+	go func() {
+		defer conn.Close()
+		// the client writes its identification while reading ours
+		go func() {
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			got <- line
+			_, _ = io.Copy(io.Discard, conn)
+		}()
+		_, _ = io.WriteString(conn, "Authorized use only\r\n"+banner+"\r\n")
+		_, _ = conn.Write(kexInitPacket(lists))
+	}()
+	return got
+}
+
+func TestReadServerKexInit(t *testing.T) {
+	lists := [10]string{
+		"diffie-hellman-group14-sha1,diffie-hellman-group1-sha1,ext-info-s",
+		"ssh-rsa,ssh-dss",
+		"aes128-ctr,aes128-cbc,3des-cbc",
+		"aes128-cbc,3des-cbc",
+		"hmac-sha1,hmac-md5",
+		"hmac-sha1",
+		"none,zlib@openssh.com",
+		"none",
+		"",
+		"",
+	}
+	client, server := net.Pipe()
+	defer client.Close()
+	sent := serveKexInit(server, "SSH-2.0-OpenSSH_5.3", lists)
+
+	banner, algs, err := ReadServerKexInit(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if banner != "SSH-2.0-OpenSSH_5.3" {
+		t.Errorf("banner = %q", banner)
+	}
+	if ident := <-sent; ident != probeIdent+"\r\n" {
+		t.Errorf("client identification = %q", ident)
+	}
+	want := Algorithms{
+		Kex:     []string{"diffie-hellman-group14-sha1", "diffie-hellman-group1-sha1", "ext-info-s"},
+		HostKey: []string{"ssh-rsa", "ssh-dss"},
+		Ciphers: []string{"aes128-cbc", "3des-cbc"}, // offered both ways
+		MACs:    []string{"hmac-sha1"},
+	}
+	if !reflect.DeepEqual(algs, want) {
+		t.Errorf("algorithms = %+v, want %+v", algs, want)
+	}
+
+	enabled := Algorithms{
+		Kex:     []string{"curve25519-sha256", "diffie-hellman-group14-sha256"},
+		HostKey: []string{"ssh-ed25519", "rsa-sha2-512"},
+		Ciphers: []string{"chacha20-poly1305@openssh.com", "aes128-ctr"},
+		MACs:    []string{"hmac-sha2-256", "hmac-sha1"},
+	}
+	supported := Algorithms{
+		Kex:     []string{"curve25519-sha256", "diffie-hellman-group14-sha256", "diffie-hellman-group14-sha1", "diffie-hellman-group1-sha1"},
+		HostKey: []string{"ssh-ed25519", "rsa-sha2-512", "ssh-rsa"},
+		Ciphers: []string{"chacha20-poly1305@openssh.com", "aes128-ctr", "aes128-cbc"},
+		MACs:    []string{"hmac-sha2-256", "hmac-sha1"},
+	}
+	adds, missing := ProposeAdditions(algs, enabled, supported)
+	wantAdds := []OptionFix{
+		{Option: "KexAlgorithms", Algorithm: "diffie-hellman-group14-sha1"},
+		{Option: "HostKeyAlgorithms", Algorithm: "ssh-rsa"},
+		{Option: "Ciphers", Algorithm: "aes128-cbc"},
+	}
+	if !reflect.DeepEqual(adds, wantAdds) {
+		t.Errorf("additions = %v, want %v", adds, wantAdds)
+	}
+	if len(missing) != 0 {
+		t.Errorf("missing = %v, want none", missing)
+	}
+}
+
+func TestReadServerKexInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		server string
+		want   string
+	}{
+		{"not ssh", "220 smtp.example.com ESMTP\r\n", "not an SSH server"},
+		{"ssh 1", "SSH-1.5-old\r\n", "unsupported SSH version"},
+		{"bad packet", "SSH-2.0-x\r\n\xff\xff\xff\xff\x04", "invalid packet length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			go func() {
+				wrote := make(chan struct{})
+				go func() {
+					_, _ = io.WriteString(server, tt.server)
+					close(wrote)
+				}()
+				// close only after taking the client's identification
+				_, _ = bufio.NewReader(server).ReadString('\n')
+				<-wrote
+				_ = server.Close()
+			}()
+			_, _, err := ReadServerKexInit(client)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProposeAdditionsMissing(t *testing.T) {
+	server := Algorithms{Kex: []string{"diffie-hellman-group1-sha1"}, Ciphers: []string{"aes256-gcm@openssh.com"},
+		MACs: []string{"hmac-md5"}}
+	enabled := Algorithms{Kex: []string{"curve25519-sha256"}, Ciphers: []string{"aes256-gcm@openssh.com"},
+		MACs: []string{"hmac-sha2-256"}}
+
+	// the MAC isn't negotiated with an AEAD cipher, so only kex is missing
+	adds, missing := ProposeAdditions(server, enabled, enabled)
+	if len(adds) != 0 || !reflect.DeepEqual(missing, []string{"KexAlgorithms"}) {
+		t.Errorf("ProposeAdditions = %v, %v; want no additions and KexAlgorithms missing", adds, missing)
+	}
+}
+
+func TestParseKexInitTruncated(t *testing.T) {
+	pkt := kexInitPacket([10]string{"a", "b"})
+	payload, err := readPacket(bytes.NewReader(pkt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseKexInit(payload[:len(payload)-10]); err == nil {
+		t.Error("parseKexInit of a truncated payload: err = nil")
+	}
+	if _, err := parseKexInit([]byte{21}); err == nil {
+		t.Error("parseKexInit of another message: err = nil")
+	}
+}
@@ -0,0 +1,36 @@
+package connect
+
+import (
+	"io"
+	"os/exec"
+)
+
+// ptyCommand runs an external client on a pseudo-terminal, so a login
+// script can watch its output (including prompts written straight to the
+// terminal, like ssh's password prompt) and type into it.
+type ptyCommand struct {
+	cmd    *exec.Cmd
+	script *loginScript
+
+	stdin  io.Reader
+	stdout io.Writer // receives the merged output of the client
+	stderr io.Writer // for errors starting the client
+}
+
+func (c *ptyCommand) SetStdin(r io.Reader) {
+	if c.stdin == nil {
+		c.stdin = r
+	}
+}
+
+func (c *ptyCommand) SetStdout(w io.Writer) {
+	if c.stdout == nil {
+		c.stdout = w
+	}
+}
+
+func (c *ptyCommand) SetStderr(w io.Writer) {
+	if c.stderr == nil {
+		c.stderr = w
+	}
+}
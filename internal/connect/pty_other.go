@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package connect
+
+const ptySupported = false
+
+// Run reports that external clients can't run on a pseudo-terminal here;
+// BuildCommand doesn't create a ptyCommand on these platforms.
+func (c *ptyCommand) Run() error {
+	return errNoPTY
+}
@@ -0,0 +1,97 @@
+//go:build linux || darwin
+
+package connect
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"bubbletea-ssh-manager/internal/termutil"
+
+	"github.com/muesli/cancelreader"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ptySupported = true
+
+	// ptyDrainTimeout is how long output is still relayed after the client
+	// exits, in case something it started keeps the terminal open.
+	ptyDrainTimeout = time.Second
+)
+
+// Run starts the client on a new pseudo-terminal and relays the real
+// terminal to it until the client exits, returning its exit error.
+func (c *ptyCommand) Run() error {
+	stdin, stdout, stderr := c.stdin, c.stdout, c.stderr
+	c.script.prepare(stderr)
+
+	master, slave, err := openPTY()
+	if err != nil {
+		fmt.Fprintf(stderr, "pty: %v\n", err)
+		return err
+	}
+	defer master.Close()
+
+	resize := func() {
+		if w, h, ok := termutil.Size(stdout); ok {
+			_ = unix.IoctlSetWinsize(int(master.Fd()), unix.TIOCSWINSZ, &unix.Winsize{Row: uint16(h), Col: uint16(w)})
+		}
+	}
+	resize()
+
+	c.cmd.Stdin, c.cmd.Stdout, c.cmd.Stderr = slave, slave, slave
+	c.cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	err = c.cmd.Start()
+	_ = slave.Close()
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return err
+	}
+
+	restore := termutil.MakeRaw(stdin)
+	defer restore()
+	stopResize := watchResize(resize)
+	defer stopResize()
+
+	in, err := cancelreader.NewReader(stdin)
+	if err != nil {
+		_ = c.cmd.Process.Kill()
+		_ = c.cmd.Wait()
+		return err
+	}
+	defer in.Close()
+
+	out, stopScript := c.script.start(master, stdout)
+	defer stopScript()
+
+	outDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(out, master) // ends with EIO once the client's side is closed
+		close(outDone)
+	}()
+	inDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(master, in)
+		close(inDone)
+	}()
+
+	err = c.cmd.Wait()
+	select {
+	case <-outDone:
+	case <-time.After(ptyDrainTimeout):
+	}
+	if in.Cancel() {
+		<-inDone
+	}
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		fmt.Fprintf(stderr, "%v\n", err)
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+// remoteWaitDelay bounds how long a killed ssh may keep its output pipes open.
+const remoteWaitDelay = 2 * time.Second
+
+// RemoteCommand builds a non-interactive ssh command that runs command on the
+// host alias. The ssh process is killed when ctx is done.
+//
+// It uses -T (no pty) and BatchMode, so a host that asks for a password or a
+// host key confirmation fails instead of waiting for input that never comes.
+// connectTimeout (if set) is passed on as ConnectTimeout, rounded up to whole seconds.
+func RemoteCommand(ctx context.Context, alias, command string, connectTimeout time.Duration) (*exec.Cmd, error) {
+	if alias == "" {
+		return nil, fmt.Errorf("empty ssh alias")
+	}
+	programPath, err := preferredProgramPath(string(config.ProtocolSSH))
+	if err != nil {
+		return nil, fmt.Errorf("ssh not found: %w", err)
+	}
+
+	args := append(SSHConfigArgs(alias), "-T", "-o", "BatchMode=yes")
+	if connectTimeout > 0 {
+		secs := int((connectTimeout + time.Second - 1) / time.Second)
+		args = append(args, "-o", "ConnectTimeout="+strconv.Itoa(secs))
+	}
+	args = append(args, alias, command)
+
+	c := exec.CommandContext(ctx, programPath, args...)
+	c.WaitDelay = remoteWaitDelay
+	return c, nil
+}
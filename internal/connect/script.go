@@ -0,0 +1,79 @@
+package connect
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+
+	"bubbletea-ssh-manager/internal/expect"
+	"bubbletea-ssh-manager/internal/secret"
+)
+
+// loginScript is a host's login script, ready to run against a session.
+type loginScript struct {
+	script *expect.Script
+	env    expect.Env
+}
+
+// loadLoginScript loads t's login script. It returns nil if t has none.
+func loadLoginScript(t Target) (*loginScript, error) {
+	if t.Script == "" {
+		return nil, nil
+	}
+	s, err := expect.Load(t.Script)
+	if err != nil {
+		return nil, err
+	}
+	return &loginScript{
+		script: s,
+		env: expect.Env{
+			Vars: map[string]string{
+				"alias": t.Alias,
+				"host":  cmp.Or(t.HostName, t.Alias),
+				"port":  t.Port,
+				"user":  t.User,
+			},
+			Secrets:  secret.Unattended(),
+			Password: t.Secret,
+		},
+	}, nil
+}
+
+// prepare unlocks the vault if the script needs secrets, asking for its
+// passphrase on the terminal. It must run before the terminal goes into raw
+// mode; on failure a note is printed and the script's lookups fail later.
+// A nil script does nothing.
+func (l *loginScript) prepare(stderr io.Writer) {
+	if l == nil || !l.script.UsesSecrets() {
+		return
+	}
+	if err := secret.DefaultVault().EnsureUnlocked(); err != nil {
+		fmt.Fprintf(stderr, "login script: %v\n", err)
+	}
+}
+
+// start runs the script against a session whose input is to.
+//
+// It returns the writer the session's output should go to (stdout, plus a
+// copy for the script) and a function that stops the script. If the script
+// fails, a note is printed and the session carries on interactively.
+// A nil script returns stdout as-is.
+func (l *loginScript) start(to, stdout io.Writer) (out io.Writer, stop func()) {
+	if l == nil {
+		return stdout, func() {}
+	}
+	run := expect.Start(l.script, to, l.env)
+	go func() {
+		if err := <-run.Done(); err != nil && !errors.Is(err, expect.ErrStopped) {
+			fmt.Fprintf(stdout, "\r\n[login script %v; continuing interactively]\r\n", err)
+		}
+	}()
+	return io.MultiWriter(stdout, run), run.Stop
+}
+
+// errNoPTY is returned when an external client needs a login script but the
+// platform has no pseudo-terminal support here.
+var errNoPTY = fmt.Errorf("login scripts for external clients aren't supported on %s; "+
+	"use the built-in telnet or serial client", runtime.GOOS)
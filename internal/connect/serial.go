@@ -0,0 +1,248 @@
+package connect
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/termutil"
+
+	"github.com/muesli/cancelreader"
+)
+
+const (
+	serialEscapeByte  = telnetEscapeByte // ctrl+], same as telnet
+	serialReadBufSize = 4096
+)
+
+// Serial parity and flow control settings.
+const (
+	ParityNone  = "none"
+	ParityEven  = "even"
+	ParityOdd   = "odd"
+	FlowNone    = "none"
+	FlowRTSCTS  = "rtscts"
+	FlowXonXoff = "xonxoff"
+)
+
+// errSerialEscape is returned internally when the user presses the escape key.
+var errSerialEscape = errors.New("serial: escape")
+
+// SerialSettings is a validated serial line configuration.
+type SerialSettings struct {
+	Baud     int    // line speed in bits per second
+	DataBits int    // 5 to 8
+	Parity   string // ParityNone, ParityEven or ParityOdd
+	StopBits int    // 1 or 2
+	Flow     string // FlowNone, FlowRTSCTS or FlowXonXoff
+}
+
+// ParseSerialSettings validates the serial options of a host, filling in
+// the defaults (9600 8N1, no flow control) for empty values.
+func ParseSerialSettings(o config.SerialOptions) (SerialSettings, error) {
+	o = o.Normalized()
+	s := SerialSettings{Parity: cmp.Or(o.Parity, ParityNone), Flow: cmp.Or(o.Flow, FlowNone)}
+
+	var err error
+	if s.Baud, err = strconv.Atoi(cmp.Or(o.Baud, config.DefaultBaud)); err != nil || s.Baud <= 0 {
+		return SerialSettings{}, fmt.Errorf("invalid baud rate %q", o.Baud)
+	}
+	if s.DataBits, err = strconv.Atoi(cmp.Or(o.DataBits, "8")); err != nil || s.DataBits < 5 || s.DataBits > 8 {
+		return SerialSettings{}, fmt.Errorf("invalid data bits %q (use 5 to 8)", o.DataBits)
+	}
+	if s.StopBits, err = strconv.Atoi(cmp.Or(o.StopBits, "1")); err != nil || s.StopBits < 1 || s.StopBits > 2 {
+		return SerialSettings{}, fmt.Errorf("invalid stop bits %q (use 1 or 2)", o.StopBits)
+	}
+	switch s.Parity {
+	case ParityNone, ParityEven, ParityOdd:
+	default:
+		return SerialSettings{}, fmt.Errorf("invalid parity %q (use none, even or odd)", o.Parity)
+	}
+	switch s.Flow {
+	case FlowNone, FlowRTSCTS, FlowXonXoff:
+	default:
+		return SerialSettings{}, fmt.Errorf("invalid flow control %q (use none, rtscts or xonxoff)", o.Flow)
+	}
+	return s, nil
+}
+
+// String returns the settings in the usual short form (eg. "115200 8N1",
+// "9600 7E1 rtscts").
+func (s SerialSettings) String() string {
+	out := fmt.Sprintf("%d %d%s%d", s.Baud, s.DataBits, strings.ToUpper(s.Parity[:1]), s.StopBits)
+	if s.Flow != FlowNone {
+		out += " " + s.Flow
+	}
+	return out
+}
+
+// SerialClient is the built-in serial console client.
+//
+// Like TelnetClient it implements Command: Run puts the terminal in raw mode
+// and relays it to the serial device until the escape key (ctrl+]) is pressed
+// or the device goes away.
+type SerialClient struct {
+	Device   string         // device path (eg. /dev/ttyUSB0 or COM3)
+	Settings SerialSettings // line settings
+
+	// Open opens and configures the device; defaults to the platform's
+	// serial port support. Tests can replace it with one end of a pty pair.
+	Open func(device string, s SerialSettings) (io.ReadWriteCloser, error)
+
+	script *loginScript // login script run once the port is open (nil for none)
+
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// NewSerialClient returns a client for the host's serial options.
+func NewSerialClient(o config.SerialOptions) (*SerialClient, error) {
+	o = o.Normalized()
+	if o.Device == "" {
+		return nil, errors.New("serial: empty device")
+	}
+	s, err := ParseSerialSettings(o)
+	if err != nil {
+		return nil, fmt.Errorf("serial %s: %w", o.Device, err)
+	}
+	return &SerialClient{Device: o.Device, Settings: s}, nil
+}
+
+func (c *SerialClient) SetStdin(r io.Reader) {
+	if c.stdin == nil {
+		c.stdin = r
+	}
+}
+
+func (c *SerialClient) SetStdout(w io.Writer) {
+	if c.stdout == nil {
+		c.stdout = w
+	}
+}
+
+func (c *SerialClient) SetStderr(w io.Writer) {
+	if c.stderr == nil {
+		c.stderr = w
+	}
+}
+
+// Run opens the device and relays the terminal until the session ends.
+//
+// Leaving via the escape key returns nil; losing the device (eg. a USB
+// adapter being unplugged) returns the read error.
+func (c *SerialClient) Run() error {
+	stdin, stdout, stderr := c.streams()
+	c.script.prepare(stderr)
+
+	open := c.Open
+	if open == nil {
+		open = openSerialPort
+	}
+	port, err := open(c.Device, c.Settings)
+	if err != nil {
+		fmt.Fprintf(stderr, "serial: %v\n", err)
+		return err
+	}
+	defer port.Close()
+
+	fmt.Fprintf(stdout, "Connected to %s (%s). Press Ctrl+] to return to the menu.\r\n", c.Device, c.Settings)
+
+	restore := termutil.MakeRaw(stdin)
+	defer restore()
+
+	in, err := cancelreader.NewReader(stdin)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, stopScript := c.script.start(port, stdout)
+	defer stopScript()
+
+	done := make(chan error, 2)
+	go func() { done <- relayFromDevice(port, out) }()
+	go func() { done <- relayToDevice(in, port) }()
+
+	// first side to finish ends the session; unblock the other one
+	// (input that can't be canceled, eg. a pipe, is left to finish on its own)
+	err = <-done
+	canceled := in.Cancel()
+	_ = port.Close()
+	if canceled {
+		<-done
+	}
+
+	if err == nil || errors.Is(err, errSerialEscape) || errors.Is(err, os.ErrClosed) {
+		fmt.Fprint(stdout, "\r\nConnection closed.\r\n")
+		return nil
+	}
+	fmt.Fprintf(stderr, "\r\nserial: %v\r\n", err)
+	return err
+}
+
+// streams returns the configured streams, falling back to the process's own.
+func (c *SerialClient) streams() (io.Reader, io.Writer, io.Writer) {
+	stdin, stdout, stderr := c.stdin, c.stdout, c.stderr
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	return stdin, stdout, stderr
+}
+
+// relayFromDevice copies device output to stdout until the device is closed or fails.
+func relayFromDevice(port io.Reader, stdout io.Writer) error {
+	buf := make([]byte, serialReadBufSize)
+	for {
+		n, err := port.Read(buf)
+		if n > 0 {
+			if _, werr := stdout.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// relayToDevice sends keyboard input to the device until the escape key is
+// pressed or input is canceled. The device echoes, so there's no local echo.
+func relayToDevice(in io.Reader, port io.Writer) error {
+	buf := make([]byte, serialReadBufSize)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			data := buf[:n]
+			esc := false
+			if i := strings.IndexByte(string(data), serialEscapeByte); i >= 0 {
+				data, esc = data[:i], true
+			}
+			if len(data) > 0 {
+				if _, werr := port.Write(data); werr != nil {
+					return werr
+				}
+			}
+			if esc {
+				return errSerialEscape
+			}
+		}
+		if err != nil {
+			if errors.Is(err, cancelreader.ErrCanceled) {
+				return nil
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package connect
+
+import "golang.org/x/sys/unix"
+
+const (
+	ioctlGetTermios = unix.TIOCGETA
+	ioctlSetTermios = unix.TIOCSETA
+)
+
+// setTermiosSpeed sets the line speed; the BSD termios takes the rate as is.
+func setTermiosSpeed(t *unix.Termios, baud int) error {
+	t.Ispeed = uint64(baud)
+	t.Ospeed = uint64(baud)
+	return nil
+}
@@ -0,0 +1,210 @@
+package connect
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openSerialPTY opens a pty pair and returns the master and the slave's path,
+// which stands in for a serial device. The slave is kept open until the test
+// ends, so the master doesn't see a hangup before the client opens it.
+func openSerialPTY(t *testing.T) (*os.File, string) {
+	t.Helper()
+	master, slave, err := openPTY()
+	if err != nil {
+		t.Skipf("no pty: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = master.Close()
+		_ = slave.Close()
+	})
+	return master, slave.Name()
+}
+
+// waitRecorded waits until r has collected want.
+func waitRecorded(t *testing.T, r *recorder, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		ok := bytes.Contains(r.buf.Bytes(), []byte(want))
+		r.mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("never got %q", want)
+}
+
+var serialTests = []struct {
+	settings SerialSettings
+	speed    uint32
+	cflag    uint32 // within CSIZE|PARENB|PARODD|CSTOPB|CRTSCTS
+	iflag    uint32 // within INPCK|IXON|IXOFF
+}{
+	{SerialSettings{Baud: 9600, DataBits: 8, Parity: ParityNone, StopBits: 1, Flow: FlowNone}, unix.B9600, unix.CS8, 0},
+	{SerialSettings{Baud: 115200, DataBits: 7, Parity: ParityEven, StopBits: 2, Flow: FlowRTSCTS}, unix.B115200,
+		unix.CS7 | unix.PARENB | unix.CSTOPB | unix.CRTSCTS, unix.INPCK},
+	{SerialSettings{Baud: 1200, DataBits: 5, Parity: ParityOdd, StopBits: 1, Flow: FlowXonXoff}, unix.B1200,
+		unix.CS5 | unix.PARENB | unix.PARODD, unix.INPCK | unix.IXON | unix.IXOFF},
+}
+
+const (
+	serialCflagBits = unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CRTSCTS
+	serialIflagBits = unix.INPCK | unix.IXON | unix.IXOFF
+)
+
+func TestSetSerialTermios(t *testing.T) {
+	for _, tt := range serialTests {
+		t.Run(tt.settings.String(), func(t *testing.T) {
+			// start from a cooked line with everything else set
+			tio := unix.Termios{
+				Iflag: unix.ICRNL | unix.IXON | unix.IXANY,
+				Oflag: unix.OPOST | unix.ONLCR,
+				Cflag: unix.CS8 | unix.PARENB | unix.CSTOPB | unix.CRTSCTS | unix.B38400,
+				Lflag: unix.ICANON | unix.ECHO | unix.ISIG | unix.IEXTEN,
+			}
+			if err := setSerialTermios(&tio, tt.settings); err != nil {
+				t.Fatal(err)
+			}
+			if got := tio.Cflag & serialCflagBits; got != tt.cflag {
+				t.Errorf("cflag = %#o, want %#o", got, tt.cflag)
+			}
+			if got := tio.Iflag & serialIflagBits; got != tt.iflag {
+				t.Errorf("iflag = %#o, want %#o", got, tt.iflag)
+			}
+			if got := tio.Cflag & unix.CBAUD; got != tt.speed || tio.Ispeed != tt.speed || tio.Ospeed != tt.speed {
+				t.Errorf("speed = %#o (in %#o, out %#o), want %#o", got, tio.Ispeed, tio.Ospeed, tt.speed)
+			}
+			if tio.Iflag&unix.ICRNL != 0 || tio.Lflag&(unix.ICANON|unix.ECHO|unix.ISIG) != 0 || tio.Oflag&unix.OPOST != 0 {
+				t.Errorf("line isn't raw: %+v", tio)
+			}
+			if tio.Cc[unix.VMIN] != 1 || tio.Cc[unix.VTIME] != 0 {
+				t.Errorf("VMIN, VTIME = %d, %d; want 1, 0", tio.Cc[unix.VMIN], tio.Cc[unix.VTIME])
+			}
+		})
+	}
+
+	tio := unix.Termios{}
+	if err := setSerialTermios(&tio, SerialSettings{Baud: 12345, DataBits: 8, Parity: ParityNone, StopBits: 1, Flow: FlowNone}); err == nil {
+		t.Error("nonstandard baud rate: err = nil")
+	}
+}
+
+func TestOpenSerialPortPTY(t *testing.T) {
+	// a pty always keeps CS8 and no parity, so only the rest is checked here
+	const kept = serialCflagBits &^ (unix.CSIZE | unix.PARENB)
+	for _, tt := range serialTests {
+		t.Run(tt.settings.String(), func(t *testing.T) {
+			_, device := openSerialPTY(t)
+			port, err := openSerialPort(device, tt.settings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer port.Close()
+
+			tio, err := unix.IoctlGetTermios(int(port.(*os.File).Fd()), ioctlGetTermios)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tio.Cflag & kept; got != tt.cflag&kept {
+				t.Errorf("cflag = %#o, want %#o", got, tt.cflag&kept)
+			}
+			if got := tio.Iflag & serialIflagBits; got != tt.iflag {
+				t.Errorf("iflag = %#o, want %#o", got, tt.iflag)
+			}
+			if got := tio.Cflag & unix.CBAUD; got != tt.speed {
+				t.Errorf("speed = %#o, want %#o", got, tt.speed)
+			}
+			if tio.Lflag&(unix.ICANON|unix.ECHO|unix.ISIG) != 0 || tio.Oflag&unix.OPOST != 0 {
+				t.Errorf("line isn't raw: lflag %#o, oflag %#o", tio.Lflag, tio.Oflag)
+			}
+		})
+	}
+}
+
+// runSerialPTY starts c on the slave of a new pty pair, through the Open
+// hook, and returns the master and Run's result.
+func runSerialPTY(t *testing.T, c *SerialClient, stdin io.Reader, stdout io.Writer) (*os.File, <-chan error) {
+	t.Helper()
+	master, device := openSerialPTY(t)
+	c.Open = func(_ string, s SerialSettings) (io.ReadWriteCloser, error) {
+		return openSerialPort(device, s)
+	}
+	c.SetStdin(stdin)
+	c.SetStdout(stdout)
+	c.SetStderr(io.Discard)
+
+	result := make(chan error, 1)
+	go func() { result <- c.Run() }()
+	return master, result
+}
+
+func TestSerialClientEscape(t *testing.T) {
+	stdinR, stdinW := io.Pipe()
+	defer stdinW.Close()
+	stdout := &recorder{}
+	c := &SerialClient{Device: "/dev/ttyFAKE", Settings: SerialSettings{Baud: 115200, DataBits: 8, Parity: ParityNone, StopBits: 1, Flow: FlowNone}}
+	master, result := runSerialPTY(t, c, stdinR, stdout)
+	received := &recorder{}
+	go func() { _, _ = io.Copy(received, master) }()
+
+	waitRecorded(t, stdout, "Connected to /dev/ttyFAKE (115200 8N1)")
+	if _, err := master.Write([]byte("login: ")); err != nil {
+		t.Fatal(err)
+	}
+	waitRecorded(t, stdout, "login: ")
+
+	// raw line: CR goes out as is, and nothing is echoed locally
+	if _, err := stdinW.Write([]byte("root\r")); err != nil {
+		t.Fatal(err)
+	}
+	waitRecorded(t, received, "root\r")
+
+	if _, err := stdinW.Write([]byte("ab\x1dcd")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("Run = %v, want nil after the escape key", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run didn't return after the escape key")
+	}
+	waitRecorded(t, received, "root\rab")
+	if got := received.take(); bytes.Contains(got, []byte("cd")) {
+		t.Errorf("device received %q, want nothing after the escape key", got)
+	}
+	waitRecorded(t, stdout, "Connection closed.")
+	if got := stdout.take(); bytes.Contains(got, []byte("root")) {
+		t.Errorf("stdout = %q: input was echoed locally", got)
+	}
+}
+
+func TestSerialClientDeviceGone(t *testing.T) {
+	stdinR, stdinW := io.Pipe()
+	defer stdinW.Close()
+	c := &SerialClient{Device: "/dev/ttyFAKE", Settings: SerialSettings{Baud: 9600, DataBits: 8, Parity: ParityNone, StopBits: 1, Flow: FlowNone}}
+	stdout := &recorder{}
+	master, result := runSerialPTY(t, c, stdinR, stdout)
+
+	// nothing reads the master, so closing it takes effect right away
+	waitRecorded(t, stdout, "Connected to")
+	_ = master.Close() // like unplugging the adapter
+	select {
+	case err := <-result:
+		if err == nil || errors.Is(err, os.ErrClosed) {
+			t.Fatalf("Run = %v, want the read error", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run didn't return after the device went away")
+	}
+}
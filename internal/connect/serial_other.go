@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+
+package connect
+
+import (
+	"errors"
+	"io"
+)
+
+// openSerialPort reports that the built-in serial client isn't available;
+// a serial host can still use an external program via its command template.
+func openSerialPort(string, SerialSettings) (io.ReadWriteCloser, error) {
+	return nil, errors.New("built-in serial client not supported on this platform; set a command (eg. picocom -b {baud} {device})")
+}
@@ -0,0 +1,90 @@
+//go:build linux || darwin
+
+package connect
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openSerialPort opens device and configures it for a raw serial line.
+//
+// The device is opened non-blocking so reads go through the runtime poller
+// and closing the port unblocks a pending read.
+func openSerialPort(device string, s SerialSettings) (io.ReadWriteCloser, error) {
+	f, err := os.OpenFile(device, os.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := f.SyscallConn()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	var cerr error
+	if err := rc.Control(func(fd uintptr) { cerr = configureTermios(int(fd), s) }); err != nil {
+		cerr = err
+	}
+	if cerr != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("configure %s: %w", device, cerr)
+	}
+	return f, nil
+}
+
+// configureTermios puts the line in raw mode with the given settings.
+func configureTermios(fd int, s SerialSettings) error {
+	t, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return err
+	}
+	if err := setSerialTermios(t, s); err != nil {
+		return err
+	}
+	return unix.IoctlSetTermios(fd, ioctlSetTermios, t)
+}
+
+// setSerialTermios changes t to a raw line with the given settings.
+func setSerialTermios(t *unix.Termios, s SerialSettings) error {
+	// raw mode (like cfmakeraw), keeping the receiver on and modem lines ignored
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR |
+		unix.ICRNL | unix.IXON | unix.IXOFF | unix.IXANY | unix.INPCK
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CRTSCTS
+	t.Cflag |= unix.CREAD | unix.CLOCAL
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+
+	switch s.DataBits {
+	case 5:
+		t.Cflag |= unix.CS5
+	case 6:
+		t.Cflag |= unix.CS6
+	case 7:
+		t.Cflag |= unix.CS7
+	default:
+		t.Cflag |= unix.CS8
+	}
+	switch s.Parity {
+	case ParityEven:
+		t.Cflag |= unix.PARENB
+		t.Iflag |= unix.INPCK
+	case ParityOdd:
+		t.Cflag |= unix.PARENB | unix.PARODD
+		t.Iflag |= unix.INPCK
+	}
+	if s.StopBits == 2 {
+		t.Cflag |= unix.CSTOPB
+	}
+	switch s.Flow {
+	case FlowRTSCTS:
+		t.Cflag |= unix.CRTSCTS
+	case FlowXonXoff:
+		t.Iflag |= unix.IXON | unix.IXOFF
+	}
+	return setTermiosSpeed(t, s.Baud)
+}
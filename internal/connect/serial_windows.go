@@ -0,0 +1,124 @@
+package connect
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// DCB flag bits (see the Win32 DCB structure).
+const (
+	dcbBinary      = 0x00000001
+	dcbParity      = 0x00000002
+	dcbOutxCtsFlow = 0x00000004
+	dcbOutX        = 0x00000100
+	dcbInX         = 0x00000200
+)
+
+const (
+	// serialReadPoll is how long a read waits for data before checking
+	// whether the port was closed.
+	serialReadPoll = 100 // milliseconds
+	maxDWORD       = 0xFFFFFFFF
+)
+
+// windowsPort is an open COM port.
+type windowsPort struct {
+	h      windows.Handle
+	closed atomic.Bool
+}
+
+// openSerialPort opens a COM port (eg. COM3) and applies the line settings.
+func openSerialPort(device string, s SerialSettings) (io.ReadWriteCloser, error) {
+	path := device
+	if !strings.HasPrefix(path, `\\.\`) {
+		path = `\\.\` + path // needed for COM10 and up
+	}
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.CreateFile(p, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: device, Err: err}
+	}
+
+	var dcb windows.DCB
+	dcb.DCBlength = uint32(unsafe.Sizeof(dcb))
+	if err := windows.GetCommState(h, &dcb); err != nil {
+		_ = windows.CloseHandle(h)
+		return nil, err
+	}
+	dcb.BaudRate = uint32(s.Baud)
+	dcb.ByteSize = uint8(s.DataBits)
+	dcb.Flags = dcbBinary | windows.DTR_CONTROL_ENABLE | windows.RTS_CONTROL_ENABLE
+	dcb.Parity = windows.NOPARITY
+	switch s.Parity {
+	case ParityEven:
+		dcb.Parity = windows.EVENPARITY
+		dcb.Flags |= dcbParity
+	case ParityOdd:
+		dcb.Parity = windows.ODDPARITY
+		dcb.Flags |= dcbParity
+	}
+	dcb.StopBits = windows.ONESTOPBIT
+	if s.StopBits == 2 {
+		dcb.StopBits = windows.TWOSTOPBITS
+	}
+	switch s.Flow {
+	case FlowRTSCTS:
+		dcb.Flags = dcb.Flags&^windows.RTS_CONTROL_ENABLE | windows.RTS_CONTROL_HANDSHAKE | dcbOutxCtsFlow
+	case FlowXonXoff:
+		dcb.Flags |= dcbOutX | dcbInX
+	}
+	if err := windows.SetCommState(h, &dcb); err != nil {
+		_ = windows.CloseHandle(h)
+		return nil, err
+	}
+
+	// reads return after serialReadPoll without data, so Read can notice Close
+	timeouts := windows.CommTimeouts{
+		ReadIntervalTimeout:        maxDWORD,
+		ReadTotalTimeoutMultiplier: maxDWORD,
+		ReadTotalTimeoutConstant:   serialReadPoll,
+	}
+	if err := windows.SetCommTimeouts(h, &timeouts); err != nil {
+		_ = windows.CloseHandle(h)
+		return nil, err
+	}
+	return &windowsPort{h: h}, nil
+}
+
+// Read waits for data, returning os.ErrClosed once the port is closed.
+func (p *windowsPort) Read(b []byte) (int, error) {
+	for {
+		if p.closed.Load() {
+			return 0, os.ErrClosed
+		}
+		var n uint32
+		if err := windows.ReadFile(p.h, b, &n, nil); err != nil {
+			return 0, err
+		}
+		if n > 0 {
+			return int(n), nil
+		}
+	}
+}
+
+func (p *windowsPort) Write(b []byte) (int, error) {
+	var n uint32
+	err := windows.WriteFile(p.h, b, &n, nil)
+	return int(n), err
+}
+
+// Close closes the port; safe to call more than once.
+func (p *windowsPort) Close() error {
+	if p.closed.Swap(true) {
+		return nil
+	}
+	return windows.CloseHandle(p.h)
+}
@@ -0,0 +1,43 @@
+package connect
+
+import (
+	"io"
+	"os/exec"
+)
+
+// Command is a connection session that runs in the foreground terminal.
+//
+// Its method set matches tea.ExecCommand, so both external programs and the
+// built-in telnet client can be handed to tea.Exec.
+type Command interface {
+	Run() error
+	SetStdin(io.Reader)
+	SetStdout(io.Writer)
+	SetStderr(io.Writer)
+}
+
+// execCommand adapts *exec.Cmd to Command.
+//
+// Streams already wired by BuildCommand are kept; tea.Exec would otherwise
+// point them at the program's own input/output.
+type execCommand struct {
+	*exec.Cmd
+}
+
+func (c execCommand) SetStdin(r io.Reader) {
+	if c.Stdin == nil {
+		c.Stdin = r
+	}
+}
+
+func (c execCommand) SetStdout(w io.Writer) {
+	if c.Stdout == nil {
+		c.Stdout = w
+	}
+}
+
+func (c execCommand) SetStderr(w io.Writer) {
+	if c.Stderr == nil {
+		c.Stderr = w
+	}
+}
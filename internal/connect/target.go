@@ -1,17 +1,33 @@
 package connect
 
 import (
+	"path/filepath"
 	"strings"
 
 	"bubbletea-ssh-manager/internal/config"
 	str "bubbletea-ssh-manager/internal/stringutil"
 )
 
-// A Target represents a connection target for SSH or Telnet.
-// It includes the protocol and host specification.
+// A Target represents a connection target for any registered protocol.
+// It includes the protocol, host specification and any ProxyJump chain.
 type Target struct {
-	Protocol    config.Protocol // "ssh" or "telnet"
-	config.Spec                 // shared host fields (alias/hostname/port/user)
+	Protocol    config.Protocol       // registered protocol (eg. "ssh", "telnet")
+	config.Spec                       // shared host fields (alias/hostname/port/user)
+	Jumps       []config.Spec         // resolved ProxyJump hops, first hop first (ssh only)
+	Telnet      config.TelnetOptions  // built-in telnet client settings (telnet only)
+	Command     config.CommandOptions // command template settings (mosh, raw, serial, custom)
+	Serial      config.SerialOptions  // serial line settings (serial only)
+	Script      string                // login script run at the start of the session (see expect.Load); empty for none
+	Secret      string                // name of the host's password secret (see package secret); empty for none
+	ConfigFile  string                // ssh config of the host's inventory, read with -F; empty for the user's own
+
+	portDefault bool // Port was filled in from the protocol's default (see resolveTarget)
+}
+
+// entry returns the host entry the protocol's argv builder and required
+// field checks work from.
+func (t Target) entry() config.HostEntry {
+	return config.HostEntry{Spec: t.Spec, TelnetOptions: t.Telnet, CommandOptions: t.Command, SerialOptions: t.Serial}
 }
 
 // Display returns the human-readable target for status messages.
@@ -19,6 +35,7 @@ type Target struct {
 // Examples:
 //   - ssh:    mike@krabby <10.0.0.147:22>
 //   - telnet: router <10.0.0.1:23>
+//   - serial: switch <ttyUSB0>
 func (t Target) Display() string {
 	alias := t.Alias
 	user := t.User
@@ -32,6 +49,9 @@ func (t Target) Display() string {
 	if hostName != "" && port != "" {
 		return displayAlias + " <" + hostName + ":" + port + ">"
 	}
+	if hostName == "" && t.Serial.Device != "" {
+		return displayAlias + " <" + filepath.Base(t.Serial.Device) + ">"
+	}
 	return displayAlias
 }
 
@@ -0,0 +1,215 @@
+package connect
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/termutil"
+
+	"github.com/muesli/cancelreader"
+)
+
+const (
+	telnetDialTimeout  = 10 * time.Second
+	telnetEscapeByte   = 0x1d // ctrl+]
+	telnetReadBufSize  = 4096
+	defaultTelnetTType = "VT100"
+)
+
+// errTelnetEscape is returned internally when the user presses the escape key.
+var errTelnetEscape = errors.New("telnet: escape")
+
+// TelnetClient is the built-in telnet client.
+//
+// It implements Command, so it runs through the same tea.Exec flow as the
+// external ssh program: the TUI releases the terminal, Run puts it in raw
+// mode, and control returns to the menu when the connection closes or the
+// escape key (ctrl+]) is pressed.
+type TelnetClient struct {
+	Addr     string // host:port to connect to
+	TermType string // terminal type reported via TTYPE
+
+	// Dial opens the connection; defaults to a TCP dial with a timeout.
+	// Tests can replace it to connect to an in-process server.
+	Dial func(network, addr string) (net.Conn, error)
+
+	script *loginScript // login script run once connected (nil for none)
+
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// NewTelnetClient returns a client for addr.
+//
+// If termType is empty, it falls back to $TERM, then VT100.
+func NewTelnetClient(addr, termType string) *TelnetClient {
+	termType = strings.TrimSpace(termType)
+	if termType == "" {
+		termType = strings.TrimSpace(os.Getenv("TERM"))
+	}
+	if termType == "" {
+		termType = defaultTelnetTType
+	}
+	return &TelnetClient{Addr: addr, TermType: strings.ToUpper(termType)}
+}
+
+func (c *TelnetClient) SetStdin(r io.Reader) {
+	if c.stdin == nil {
+		c.stdin = r
+	}
+}
+
+func (c *TelnetClient) SetStdout(w io.Writer) {
+	if c.stdout == nil {
+		c.stdout = w
+	}
+}
+
+func (c *TelnetClient) SetStderr(w io.Writer) {
+	if c.stderr == nil {
+		c.stderr = w
+	}
+}
+
+// Run connects and relays the terminal until the session ends.
+//
+// A connection closed by the server or via the escape key returns nil.
+func (c *TelnetClient) Run() error {
+	stdin, stdout, stderr := c.streams()
+	c.script.prepare(stderr)
+
+	dial := c.Dial
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: telnetDialTimeout}).Dial
+	}
+	conn, err := dial("tcp", c.Addr)
+	if err != nil {
+		fmt.Fprintf(stderr, "telnet: %v\n", err)
+		return err
+	}
+	defer conn.Close()
+
+	restore := termutil.MakeRaw(stdin)
+	defer restore()
+
+	tc := newTelnetConn(conn, c.TermType, func() (int, int, bool) { return termutil.Size(stdout) })
+	if err := tc.start(); err != nil {
+		fmt.Fprintf(stderr, "telnet: %v\n", err)
+		return err
+	}
+
+	stopResize := watchResize(func() { _ = tc.sendWindowSize() })
+	defer stopResize()
+
+	in, err := cancelreader.NewReader(stdin)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, stopScript := c.script.start(tc, stdout)
+	defer stopScript()
+
+	done := make(chan error, 2)
+	go func() { done <- relayFromServer(tc, conn, out) }()
+	go func() { done <- relayToServer(tc, in, stdout) }()
+
+	// first side to finish ends the session; unblock the other one
+	// (input that can't be canceled, eg. a pipe, is left to finish on its own)
+	err = <-done
+	canceled := in.Cancel()
+	_ = conn.Close()
+	if canceled {
+		<-done
+	}
+
+	switch {
+	case errors.Is(err, errTelnetEscape):
+		fmt.Fprint(stdout, "\r\nConnection closed.\r\n")
+		return nil
+	case err == nil || errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed):
+		fmt.Fprint(stdout, "\r\nConnection closed by foreign host.\r\n")
+		return nil
+	}
+	fmt.Fprintf(stderr, "telnet: %v\n", err)
+	return err
+}
+
+// streams returns the configured streams, falling back to the process's own.
+func (c *TelnetClient) streams() (io.Reader, io.Writer, io.Writer) {
+	stdin, stdout, stderr := c.stdin, c.stdout, c.stderr
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	return stdin, stdout, stderr
+}
+
+// relayFromServer copies decoded server output to stdout until the connection closes.
+func relayFromServer(tc *telnetConn, conn io.Reader, stdout io.Writer) error {
+	buf := make([]byte, telnetReadBufSize)
+	for {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			data, derr := tc.decode(buf[:n])
+			if len(data) > 0 {
+				if _, werr := stdout.Write(data); werr != nil {
+					return werr
+				}
+			}
+			if derr != nil {
+				return derr
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// relayToServer sends keyboard input to the server until the escape key is
+// pressed or input is canceled.
+//
+// When the server doesn't echo, input is echoed locally.
+func relayToServer(tc *telnetConn, in io.Reader, stdout io.Writer) error {
+	buf := make([]byte, telnetReadBufSize)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			data := buf[:n]
+			esc := false
+			if i := strings.IndexByte(string(data), telnetEscapeByte); i >= 0 {
+				data, esc = data[:i], true
+			}
+			if len(data) > 0 {
+				if tc.localEcho() {
+					echo := strings.ReplaceAll(string(data), "\r", "\r\n")
+					_, _ = io.WriteString(stdout, echo)
+				}
+				if _, werr := tc.Write(data); werr != nil {
+					return werr
+				}
+			}
+			if esc {
+				return errTelnetEscape
+			}
+		}
+		if err != nil {
+			if errors.Is(err, cancelreader.ErrCanceled) {
+				return nil
+			}
+			return err
+		}
+	}
+}
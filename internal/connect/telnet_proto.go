@@ -0,0 +1,351 @@
+package connect
+
+import (
+	"io"
+	"sync"
+)
+
+// telnet command bytes (RFC 854)
+const (
+	tnSE   byte = 240 // end of subnegotiation
+	tnSB   byte = 250 // start of subnegotiation
+	tnWILL byte = 251
+	tnWONT byte = 252
+	tnDO   byte = 253
+	tnDONT byte = 254
+	tnIAC  byte = 255 // interpret as command
+)
+
+// telnet options understood by the built-in client
+const (
+	optBinary byte = 0  // RFC 856
+	optEcho   byte = 1  // RFC 857
+	optSGA    byte = 3  // RFC 858 (suppress go ahead)
+	optTType  byte = 24 // RFC 1091 (terminal type)
+	optNAWS   byte = 31 // RFC 1073 (negotiate about window size)
+)
+
+// TTYPE subnegotiation codes (RFC 1091)
+const (
+	ttypeIS   byte = 0
+	ttypeSEND byte = 1
+)
+
+// maxSubnegotiation caps the bytes buffered for a single SB ... SE sequence.
+const maxSubnegotiation = 1024
+
+type tnState int // telnet stream parser state
+
+const (
+	tnStateData   tnState = iota // plain data
+	tnStateIAC                   // saw IAC
+	tnStateVerb                  // saw IAC WILL/WONT/DO/DONT, waiting for option
+	tnStateSB                    // saw IAC SB, waiting for option
+	tnStateSBData                // inside subnegotiation
+	tnStateSBIAC                 // saw IAC inside subnegotiation
+	tnStateCR                    // saw CR in data (NVT CR NUL handling)
+)
+
+// localOptions are options the client is willing to enable on its side (WILL).
+var localOptions = map[byte]bool{optBinary: true, optSGA: true, optTType: true, optNAWS: true}
+
+// remoteOptions are options the client accepts the server enabling (DO).
+var remoteOptions = map[byte]bool{optBinary: true, optEcho: true, optSGA: true}
+
+// telnetConn handles option negotiation and IAC framing for one connection.
+//
+// It decodes the server stream into plain data (answering negotiation as it
+// goes) and encodes client data for sending. It is safe for the reader and
+// writer sides to run on separate goroutines.
+type telnetConn struct {
+	rw       io.ReadWriter              // underlying connection
+	termType string                     // terminal type reported via TTYPE
+	size     func() (w, h int, ok bool) // current window size for NAWS
+
+	wmu sync.Mutex // serializes writes to rw
+
+	mu      sync.Mutex       // guards option state
+	local   map[byte]bool    // options enabled on our side
+	remote  map[byte]bool    // options enabled on the server side
+	pending map[[2]byte]bool // requests we sent and are awaiting an answer for ({verb, option})
+	nawsDue bool             // NAWS was just enabled; send the window size
+
+	// decoder state (reader goroutine only)
+	state tnState
+	verb  byte
+	sbOpt byte
+	sbBuf []byte
+}
+
+// newTelnetConn wraps rw with telnet negotiation.
+func newTelnetConn(rw io.ReadWriter, termType string, size func() (int, int, bool)) *telnetConn {
+	return &telnetConn{
+		rw:       rw,
+		termType: termType,
+		size:     size,
+		local:    map[byte]bool{},
+		remote:   map[byte]bool{},
+		pending:  map[[2]byte]bool{},
+	}
+}
+
+// start sends the client's opening negotiation.
+//
+// We offer terminal type and window size, and ask the server to suppress
+// go-ahead and to echo (character-at-a-time mode, which is what full-screen
+// apps on OpenVMS and network gear expect).
+func (t *telnetConn) start() error {
+	t.mu.Lock()
+	var out []byte
+	for _, req := range [][2]byte{{tnWILL, optTType}, {tnWILL, optNAWS}, {tnDO, optSGA}, {tnDO, optEcho}} {
+		t.pending[req] = true
+		out = append(out, tnIAC, req[0], req[1])
+	}
+	t.mu.Unlock()
+	return t.writeRaw(out)
+}
+
+// localEcho reports whether the client should echo typed input itself
+// (the server has not agreed to echo).
+func (t *telnetConn) localEcho() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.remote[optEcho]
+}
+
+// isBinary reports whether we send in binary mode.
+func (t *telnetConn) isBinary() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.local[optBinary]
+}
+
+// writeRaw writes already-framed bytes to the connection.
+func (t *telnetConn) writeRaw(p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+	_, err := t.rw.Write(p)
+	return err
+}
+
+// Write encodes client data (escaping IAC, NVT newline rules) and sends it.
+func (t *telnetConn) Write(p []byte) (int, error) {
+	binary := t.isBinary()
+	out := make([]byte, 0, len(p)+8)
+	for _, b := range p {
+		switch {
+		case b == tnIAC:
+			out = append(out, tnIAC, tnIAC)
+		case b == '\r' && !binary:
+			// NVT: a bare carriage return is sent as CR NUL
+			out = append(out, '\r', 0)
+		default:
+			out = append(out, b)
+		}
+	}
+	if err := t.writeRaw(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// sendWindowSize sends a NAWS subnegotiation if NAWS is enabled.
+func (t *telnetConn) sendWindowSize() error {
+	t.mu.Lock()
+	enabled := t.local[optNAWS]
+	t.mu.Unlock()
+	if !enabled || t.size == nil {
+		return nil
+	}
+	w, h, ok := t.size()
+	if !ok {
+		return nil
+	}
+	w = min(max(w, 0), 0xffff)
+	h = min(max(h, 0), 0xffff)
+	payload := []byte{byte(w >> 8), byte(w), byte(h >> 8), byte(h)}
+	return t.writeRaw(subnegotiation(optNAWS, payload))
+}
+
+// subnegotiation frames IAC SB opt <payload> IAC SE, escaping IAC in the payload.
+func subnegotiation(opt byte, payload []byte) []byte {
+	out := []byte{tnIAC, tnSB, opt}
+	for _, b := range payload {
+		if b == tnIAC {
+			out = append(out, tnIAC)
+		}
+		out = append(out, b)
+	}
+	return append(out, tnIAC, tnSE)
+}
+
+// decode strips telnet commands from p and returns the remaining data.
+//
+// Negotiation requests are answered on the connection as they are seen.
+func (t *telnetConn) decode(p []byte) ([]byte, error) {
+	out := make([]byte, 0, len(p))
+	var replies []byte
+	var sbDone [][]byte
+
+	t.mu.Lock()
+	binaryIn := t.remote[optBinary]
+	for _, b := range p {
+		switch t.state {
+		case tnStateData, tnStateCR:
+			wasCR := t.state == tnStateCR
+			t.state = tnStateData
+			if b == tnIAC {
+				t.state = tnStateIAC
+				continue
+			}
+			// NVT: CR NUL means a bare carriage return
+			if wasCR && b == 0 && !binaryIn {
+				continue
+			}
+			if b == '\r' {
+				t.state = tnStateCR
+			}
+			out = append(out, b)
+
+		case tnStateIAC:
+			switch b {
+			case tnIAC:
+				out = append(out, tnIAC) // escaped 0xff data byte
+				t.state = tnStateData
+			case tnWILL, tnWONT, tnDO, tnDONT:
+				t.verb = b
+				t.state = tnStateVerb
+			case tnSB:
+				t.state = tnStateSB
+			default:
+				// NOP, GA, AYT, etc. carry nothing we need
+				t.state = tnStateData
+			}
+
+		case tnStateVerb:
+			replies = append(replies, t.negotiate(t.verb, b)...)
+			t.state = tnStateData
+
+		case tnStateSB:
+			t.sbOpt = b
+			t.sbBuf = t.sbBuf[:0]
+			t.state = tnStateSBData
+
+		case tnStateSBData:
+			if b == tnIAC {
+				t.state = tnStateSBIAC
+				continue
+			}
+			if len(t.sbBuf) < maxSubnegotiation {
+				t.sbBuf = append(t.sbBuf, b)
+			}
+
+		case tnStateSBIAC:
+			switch b {
+			case tnSE:
+				sbDone = append(sbDone, append([]byte{t.sbOpt}, t.sbBuf...))
+				t.state = tnStateData
+			case tnIAC:
+				if len(t.sbBuf) < maxSubnegotiation {
+					t.sbBuf = append(t.sbBuf, tnIAC)
+				}
+				t.state = tnStateSBData
+			default:
+				// malformed; drop the subnegotiation
+				t.state = tnStateData
+			}
+		}
+	}
+	nawsNow := t.nawsDue
+	t.nawsDue = false
+	t.mu.Unlock()
+
+	for _, sb := range sbDone {
+		replies = append(replies, t.subnegotiate(sb[0], sb[1:])...)
+	}
+	if err := t.writeRaw(replies); err != nil {
+		return out, err
+	}
+	if nawsNow {
+		if err := t.sendWindowSize(); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// negotiate handles a single WILL/WONT/DO/DONT from the server and returns
+// the reply bytes (if any). Callers must hold t.mu.
+//
+// Replies are only sent when the option state actually changes, or when it
+// answers a request we didn't make, which keeps negotiation from looping.
+func (t *telnetConn) negotiate(verb, opt byte) []byte {
+	reply := func(v byte) []byte { return []byte{tnIAC, v, opt} }
+
+	switch verb {
+	case tnDO:
+		requested := t.pending[[2]byte{tnWILL, opt}]
+		delete(t.pending, [2]byte{tnWILL, opt})
+		if !localOptions[opt] {
+			return reply(tnWONT)
+		}
+		if t.local[opt] {
+			return nil
+		}
+		t.local[opt] = true
+		if opt == optNAWS {
+			// send our size once the lock is released
+			t.nawsDue = true
+		}
+		if requested {
+			return nil
+		}
+		return reply(tnWILL)
+
+	case tnDONT:
+		delete(t.pending, [2]byte{tnWILL, opt})
+		if !t.local[opt] {
+			return nil
+		}
+		t.local[opt] = false
+		return reply(tnWONT)
+
+	case tnWILL:
+		requested := t.pending[[2]byte{tnDO, opt}]
+		delete(t.pending, [2]byte{tnDO, opt})
+		if !remoteOptions[opt] {
+			return reply(tnDONT)
+		}
+		if t.remote[opt] {
+			return nil
+		}
+		t.remote[opt] = true
+		if requested {
+			return nil
+		}
+		return reply(tnDO)
+
+	case tnWONT:
+		delete(t.pending, [2]byte{tnDO, opt})
+		if !t.remote[opt] {
+			return nil
+		}
+		t.remote[opt] = false
+		return reply(tnDONT)
+	}
+	return nil
+}
+
+// subnegotiate answers a completed subnegotiation and returns the reply bytes.
+func (t *telnetConn) subnegotiate(opt byte, data []byte) []byte {
+	switch opt {
+	case optTType:
+		if len(data) > 0 && data[0] == ttypeSEND {
+			return subnegotiation(optTType, append([]byte{ttypeIS}, t.termType...))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+//go:build windows
+
+package connect
+
+import (
+	"os"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// resizePollInterval is how often the console size is checked on Windows,
+// which has no SIGWINCH.
+const resizePollInterval = 500 * time.Millisecond
+
+// watchResize calls onResize whenever the console window changes size.
+//
+// It returns a function that stops watching.
+func watchResize(onResize func()) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		w, h, _ := term.GetSize(os.Stdout.Fd())
+		t := time.NewTicker(resizePollInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				nw, nh, err := term.GetSize(os.Stdout.Fd())
+				if err == nil && (nw != w || nh != h) {
+					w, h = nw, nh
+					onResize()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
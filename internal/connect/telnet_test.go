@@ -0,0 +1,319 @@
+package connect
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is an io.ReadWriter that collects what the client writes.
+type recorder struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (r *recorder) Read([]byte) (int, error) { return 0, io.EOF }
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.Write(p)
+}
+
+// take returns what was written so far and resets it.
+func (r *recorder) take() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := bytes.Clone(r.buf.Bytes())
+	r.buf.Reset()
+	return out
+}
+
+func TestTelnetDecodeData(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks [][]byte
+		want   []byte
+	}{
+		{"plain", [][]byte{[]byte("hello")}, []byte("hello")},
+		{"escaped IAC", [][]byte{{'a', tnIAC, tnIAC, 'b'}}, []byte{'a', 0xff, 'b'}},
+		{"CR NUL", [][]byte{{'a', '\r', 0, 'b'}}, []byte("a\rb")},
+		{"CR LF kept", [][]byte{[]byte("a\r\nb")}, []byte("a\r\nb")},
+		{"NOP and GA dropped", [][]byte{{'a', tnIAC, 241, tnIAC, 249, 'b'}}, []byte("ab")},
+		{"IAC split across reads", [][]byte{{'a', tnIAC}, {tnIAC, 'b'}}, []byte{'a', 0xff, 'b'}},
+		{"CR NUL split across reads", [][]byte{{'a', '\r'}, {0, 'b'}}, []byte("a\rb")},
+		{"subnegotiation dropped", [][]byte{{'a', tnIAC, tnSB, 99, 1, 2, tnIAC, tnSE, 'b'}}, []byte("ab")},
+		{"subnegotiation split", [][]byte{{'a', tnIAC, tnSB}, {99, 1, tnIAC}, {tnSE, 'b'}}, []byte("ab")},
+		{"malformed subnegotiation", [][]byte{{tnIAC, tnSB, 99, 1, tnIAC, 'x', 'b'}}, []byte("b")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTelnetConn(&recorder{}, "VT100", nil)
+			var got []byte
+			for _, c := range tt.chunks {
+				out, err := tc.decode(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, out...)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("decode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTelnetNegotiate(t *testing.T) {
+	const optLinemode byte = 34
+	tests := []struct {
+		name  string
+		in    []byte // server negotiation, after the client's start
+		reply []byte // client's answer
+	}{
+		{"requested DO TTYPE is not answered", []byte{tnIAC, tnDO, optTType}, nil},
+		{"requested WILL ECHO is not answered", []byte{tnIAC, tnWILL, optEcho}, nil},
+		{"unrequested DO BINARY is agreed to", []byte{tnIAC, tnDO, optBinary}, []byte{tnIAC, tnWILL, optBinary}},
+		{"unrequested WILL BINARY is agreed to", []byte{tnIAC, tnWILL, optBinary}, []byte{tnIAC, tnDO, optBinary}},
+		{"unknown DO is refused", []byte{tnIAC, tnDO, optLinemode}, []byte{tnIAC, tnWONT, optLinemode}},
+		{"unknown WILL is refused", []byte{tnIAC, tnWILL, optLinemode}, []byte{tnIAC, tnDONT, optLinemode}},
+		{"DONT for a disabled option is ignored", []byte{tnIAC, tnDONT, optBinary}, nil},
+		{"WONT ECHO after WILL ECHO is acknowledged",
+			[]byte{tnIAC, tnWILL, optEcho, tnIAC, tnWONT, optEcho}, []byte{tnIAC, tnDONT, optEcho}},
+		{"repeated DO is answered once",
+			[]byte{tnIAC, tnDO, optBinary, tnIAC, tnDO, optBinary}, []byte{tnIAC, tnWILL, optBinary}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			tc := newTelnetConn(rec, "VT100", nil)
+			if err := tc.start(); err != nil {
+				t.Fatal(err)
+			}
+			rec.take()
+			if _, err := tc.decode(tt.in); err != nil {
+				t.Fatal(err)
+			}
+			if got := rec.take(); !bytes.Equal(got, tt.reply) {
+				t.Errorf("reply = %v, want %v", got, tt.reply)
+			}
+		})
+	}
+}
+
+func TestTelnetStart(t *testing.T) {
+	rec := &recorder{}
+	tc := newTelnetConn(rec, "VT100", nil)
+	if err := tc.start(); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{tnIAC, tnWILL, optTType, tnIAC, tnWILL, optNAWS, tnIAC, tnDO, optSGA, tnIAC, tnDO, optEcho}
+	if got := rec.take(); !bytes.Equal(got, want) {
+		t.Errorf("start = %v, want %v", got, want)
+	}
+	if !tc.localEcho() {
+		t.Error("localEcho before the server agrees to echo = false, want true")
+	}
+	if _, err := tc.decode([]byte{tnIAC, tnWILL, optEcho}); err != nil {
+		t.Fatal(err)
+	}
+	if tc.localEcho() {
+		t.Error("localEcho after WILL ECHO = true, want false")
+	}
+}
+
+func TestTelnetTerminalType(t *testing.T) {
+	rec := &recorder{}
+	tc := newTelnetConn(rec, "XTERM", nil)
+	if _, err := tc.decode([]byte{tnIAC, tnSB, optTType, ttypeSEND, tnIAC, tnSE}); err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte{tnIAC, tnSB, optTType, ttypeIS}, "XTERM"...)
+	want = append(want, tnIAC, tnSE)
+	if got := rec.take(); !bytes.Equal(got, want) {
+		t.Errorf("TTYPE IS = %v, want %v", got, want)
+	}
+}
+
+func TestTelnetWindowSize(t *testing.T) {
+	tests := []struct {
+		name string
+		w, h int
+		want []byte
+	}{
+		{"80x24", 80, 24, []byte{tnIAC, tnSB, optNAWS, 0, 80, 0, 24, tnIAC, tnSE}},
+		{"IAC in size is escaped", 255, 300, []byte{tnIAC, tnSB, optNAWS, 0, tnIAC, tnIAC, 1, 44, tnIAC, tnSE}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			tc := newTelnetConn(rec, "VT100", func() (int, int, bool) { return tt.w, tt.h, true })
+			if err := tc.sendWindowSize(); err != nil {
+				t.Fatal(err)
+			}
+			if got := rec.take(); len(got) != 0 {
+				t.Fatalf("size sent before NAWS was enabled: %v", got)
+			}
+			if err := tc.start(); err != nil {
+				t.Fatal(err)
+			}
+			rec.take()
+
+			// the size follows the server's DO NAWS
+			if _, err := tc.decode([]byte{tnIAC, tnDO, optNAWS}); err != nil {
+				t.Fatal(err)
+			}
+			if got := rec.take(); !bytes.Equal(got, tt.want) {
+				t.Errorf("NAWS = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTelnetWrite(t *testing.T) {
+	rec := &recorder{}
+	tc := newTelnetConn(rec, "VT100", nil)
+	if _, err := tc.Write([]byte{'a', '\r', 0xff, 'b'}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rec.take(), []byte{'a', '\r', 0, tnIAC, tnIAC, 'b'}; !bytes.Equal(got, want) {
+		t.Errorf("Write = %v, want %v", got, want)
+	}
+
+	// in binary mode CR is sent as is
+	if _, err := tc.decode([]byte{tnIAC, tnDO, optBinary}); err != nil {
+		t.Fatal(err)
+	}
+	rec.take()
+	if _, err := tc.Write([]byte{'\r'}); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.take(); !bytes.Equal(got, []byte{'\r'}) {
+		t.Errorf("binary Write = %v, want [13]", got)
+	}
+}
+
+// fakeTelnetServer is the server end of a net.Pipe: it collects what the
+// client sends, and the test writes to conn as the server.
+type fakeTelnetServer struct {
+	conn     net.Conn
+	received recorder
+	done     chan struct{}
+}
+
+// dialFake returns a Dial func connecting to a new fake server, which is
+// sent on srv.
+func dialFake(srv chan<- *fakeTelnetServer) func(network, addr string) (net.Conn, error) {
+	return func(network, addr string) (net.Conn, error) {
+		client, server := net.Pipe()
+		s := &fakeTelnetServer{conn: server, done: make(chan struct{})}
+		go func() {
+			defer close(s.done)
+			_, _ = io.Copy(&s.received, server)
+		}()
+		srv <- s
+		return client, nil
+	}
+}
+
+// waitFor waits until the server has received want.
+func (s *fakeTelnetServer) waitFor(t *testing.T, want []byte) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s.received.mu.Lock()
+		ok := bytes.Contains(s.received.buf.Bytes(), want)
+		s.received.mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("server never received %v", want)
+}
+
+func TestTelnetClientSession(t *testing.T) {
+	dialed := make(chan *fakeTelnetServer, 1)
+	stdinR, stdinW := io.Pipe()
+	defer stdinW.Close()
+	var stdout, stderr bytes.Buffer
+
+	c := NewTelnetClient("fake:23", "vt100")
+	c.Dial = dialFake(dialed)
+	c.SetStdin(stdinR)
+	c.SetStdout(&stdout)
+	c.SetStderr(&stderr)
+
+	result := make(chan error, 1)
+	go func() { result <- c.Run() }()
+
+	// the client opens with its own negotiation
+	srv := <-dialed
+	srv.waitFor(t, []byte{tnIAC, tnWILL, optTType, tnIAC, tnWILL, optNAWS})
+
+	script := []byte("login: ")
+	script = append(script, tnIAC, tnDO, optTType, tnIAC, tnWILL, optEcho)
+	script = append(script, tnIAC, tnSB, optTType, ttypeSEND, tnIAC, tnSE)
+	script = append(script, 'x', tnIAC, tnIAC, 'y')
+	if _, err := srv.conn.Write(script); err != nil {
+		t.Fatal(err)
+	}
+	wantTType := append([]byte{tnIAC, tnSB, optTType, ttypeIS}, "VT100"...)
+	srv.waitFor(t, append(wantTType, tnIAC, tnSE))
+
+	// typed input is framed for the wire, and the server echoes now
+	if _, err := stdinW.Write([]byte("ls\r")); err != nil {
+		t.Fatal(err)
+	}
+	srv.waitFor(t, []byte{'l', 's', '\r', 0})
+
+	_ = srv.conn.Close()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("Run = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run didn't return after the server closed")
+	}
+
+	out := stdout.String()
+	if !strings.HasPrefix(out, "login: x\xffy") {
+		t.Errorf("stdout = %q, want it to start with the decoded data", out)
+	}
+	if strings.Contains(out, "ls") {
+		t.Errorf("stdout = %q: input was echoed locally though the server echoes", out)
+	}
+	if !strings.Contains(out, "Connection closed by foreign host.") {
+		t.Errorf("stdout = %q, want the closed message", out)
+	}
+}
+
+func TestTelnetClientEscape(t *testing.T) {
+	dialed := make(chan *fakeTelnetServer, 1)
+	var stdout bytes.Buffer
+
+	c := NewTelnetClient("fake:23", "vt100")
+	c.Dial = dialFake(dialed)
+	c.SetStdin(strings.NewReader("ab\x1dcd"))
+	c.SetStdout(&stdout)
+	c.SetStderr(io.Discard)
+
+	if err := c.Run(); err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+	srv := <-dialed
+	<-srv.done
+	got := srv.received.take()
+	if !bytes.HasSuffix(got, []byte("ab")) {
+		t.Errorf("server received %q, want input up to the escape key", got)
+	}
+	// the server never agreed to echo, so input is echoed locally
+	if out := stdout.String(); !strings.HasPrefix(out, "ab") || !strings.Contains(out, "Connection closed.") {
+		t.Errorf("stdout = %q", out)
+	}
+}
@@ -0,0 +1,47 @@
+package connect
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/expect"
+	str "bubbletea-ssh-manager/internal/stringutil"
+)
+
+// ValidateHost checks a host's fields the way the host form does: its alias,
+// required fields and port, serial settings, preflight overrides and login
+// script. The CLI and imports use it for hosts that don't come from the form.
+func ValidateHost(h config.Host) error {
+	info, ok := config.LookupProtocol(h.Protocol)
+	if !ok {
+		return fmt.Errorf("unknown protocol %q", h.Protocol)
+	}
+	var errs []error
+	if group, nick, ok := strings.Cut(h.Spec.Alias, "."); ok {
+		errs = append(errs, str.ValidateHostGroup(group), str.ValidateHostNickname(nick))
+	} else {
+		errs = append(errs, str.ValidateHostNickname(h.Spec.Alias))
+	}
+	errs = append(errs, info.CheckRequired(h.HostEntry))
+	if _, err := str.NormalizePort(h.Spec.Port, h.Protocol); err != nil {
+		errs = append(errs, err)
+	}
+	if h.Protocol == config.ProtocolSerial {
+		_, err := ParseSerialSettings(h.SerialOptions)
+		errs = append(errs, err)
+	}
+	if _, err := ParsePreflightTimeout(h.AppOptions.PreflightTimeout); err != nil {
+		errs = append(errs, fmt.Errorf("preflight timeout: %w", err))
+	}
+	if _, err := ParsePreflightRetries(h.AppOptions.PreflightRetries); err != nil {
+		errs = append(errs, fmt.Errorf("preflight retries: %w", err))
+	}
+	if s := strings.TrimSpace(h.AppOptions.Script); s != "" {
+		if _, err := expect.Load(s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,45 @@
+package connect
+
+import (
+	"strings"
+	"testing"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+func TestValidateHost(t *testing.T) {
+	host := func(p config.Protocol, alias string, edit func(h *config.Host)) config.Host {
+		h := config.Host{Protocol: p}
+		h.Spec.Alias, h.Spec.HostName = alias, "10.0.0.1"
+		if edit != nil {
+			edit(&h)
+		}
+		return h
+	}
+	tests := []struct {
+		name string
+		host config.Host
+		want string // in the error; "" for valid
+	}{
+		{"valid", host(config.ProtocolSSH, "lab.web", nil), ""},
+		{"bad port", host(config.ProtocolSSH, "lab.web", func(h *config.Host) { h.Spec.Port = "99999" }), "port"},
+		{"bad serial", host(config.ProtocolSerial, "lab.console", func(h *config.Host) {
+			h.SerialOptions = config.SerialOptions{Device: "/dev/ttyUSB0", Baud: "fast"}
+		}), "baud"},
+		{"bad preflight timeout", host(config.ProtocolSSH, "lab.web", func(h *config.Host) {
+			h.AppOptions.PreflightTimeout = "soon"
+		}), "preflight timeout"},
+		{"bad preflight retries", host(config.ProtocolSSH, "lab.web", func(h *config.Host) {
+			h.AppOptions.PreflightRetries = "-3"
+		}), "preflight retries"},
+	}
+	for _, tt := range tests {
+		err := ValidateHost(tt.host)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: err = %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(strings.ToLower(err.Error()), tt.want)):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
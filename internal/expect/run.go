@@ -0,0 +1,301 @@
+package expect
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bubbletea-ssh-manager/internal/secret"
+)
+
+// maxBuffered is how much unmatched session output is kept for matching;
+// older output is dropped.
+const maxBuffered = 64 * 1024
+
+// ErrStopped is returned by a run that was stopped before it finished
+// (eg. the session ended).
+var ErrStopped = errors.New("login script stopped")
+
+// Env is what a script's send text can refer to.
+type Env struct {
+	Vars     map[string]string // placeholder values by name (eg. "user")
+	Secrets  secret.Provider   // provider for {secret:name}; nil if none
+	Password string            // secret name for {password}; "" if the host has none
+}
+
+// Run is a script running against a session.
+//
+// The session's output is fed to it through Write, and replies are written
+// to the session's input.
+type Run struct {
+	script *Script
+	env    Env
+	to     io.Writer
+
+	mu       sync.Mutex
+	buf      []byte // output not consumed by a match yet
+	finished bool   // the script is done; output is dropped
+
+	more chan struct{} // signaled when output arrives
+	stop chan struct{} // closed by Stop
+	done chan error    // receives the result once
+
+	stopOnce sync.Once
+}
+
+// Start runs s in the background, sending its replies to to.
+func Start(s *Script, to io.Writer, env Env) *Run {
+	r := &Run{
+		script: s,
+		env:    env,
+		to:     to,
+		more:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan error, 1),
+	}
+	go func() {
+		err := r.run()
+		r.mu.Lock()
+		r.finished, r.buf = true, nil
+		r.mu.Unlock()
+		r.done <- err
+	}()
+	return r
+}
+
+// Write feeds session output to the script. It never fails, so it can sit
+// behind an io.MultiWriter next to the terminal.
+func (r *Run) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	if !r.finished {
+		r.buf = append(r.buf, p...)
+		if over := len(r.buf) - maxBuffered; over > 0 {
+			r.buf = append(r.buf[:0], r.buf[over:]...)
+		}
+	}
+	r.mu.Unlock()
+
+	select {
+	case r.more <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Stop stops the script if it's still running.
+func (r *Run) Stop() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+// Done receives the script's result once it ends: nil if it ran to the end
+// (or a done statement), ErrStopped if stopped, otherwise an *Error.
+func (r *Run) Done() <-chan error {
+	return r.done
+}
+
+// run executes the script's statements.
+func (r *Run) run() error {
+	s := r.script
+	timeout := DefaultTimeout
+	for pc := 0; pc < len(s.ops); {
+		o := s.ops[pc]
+		pc++
+
+		switch o.kind {
+		case opTimeout:
+			timeout = o.wait
+		case opExpect:
+			_, err := r.wait([]branch{{re: o.re}}, cmp.Or(o.wait, timeout))
+			if err != nil {
+				return r.fail(o, err)
+			}
+		case opChoose:
+			i, err := r.wait(o.cases, cmp.Or(o.wait, timeout))
+			switch {
+			case errors.Is(err, errTimeout) && o.onTimer != "":
+				pc = s.labels[o.onTimer]
+			case err != nil:
+				return r.fail(o, err)
+			default:
+				pc = s.labels[o.cases[i].label]
+			}
+		case opSend:
+			text, err := r.expand(o.text)
+			if err != nil {
+				return r.fail(o, err)
+			}
+			if _, err := io.WriteString(r.to, text); err != nil {
+				return r.fail(o, fmt.Errorf("send: %w", err))
+			}
+		case opSleep:
+			select {
+			case <-time.After(o.wait):
+			case <-r.stop:
+				return ErrStopped
+			}
+		case opGoto:
+			pc = s.labels[o.label]
+		case opFail:
+			return r.fail(o, errors.New(cmp.Or(o.text, "failed")))
+		case opDone:
+			return nil
+		}
+	}
+	return nil
+}
+
+var errTimeout = errors.New("timed out")
+
+// wait blocks until one of the branches matches the buffered output, and
+// consumes the output up to the end of the match. It returns the index of
+// the branch that matched first in the output.
+func (r *Run) wait(branches []branch, timeout time.Duration) (int, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		if i, ok := r.match(branches); ok {
+			return i, nil
+		}
+		select {
+		case <-r.more:
+		case <-timer.C:
+			return -1, fmt.Errorf("%w after %s waiting for %s", errTimeout, timeout, describe(branches))
+		case <-r.stop:
+			return -1, ErrStopped
+		}
+	}
+}
+
+// match looks for the earliest match of any branch in the buffer.
+func (r *Run) match(branches []branch) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	best, bestLoc := -1, []int(nil)
+	for i, b := range branches {
+		if loc := b.re.FindIndex(r.buf); loc != nil && (bestLoc == nil || loc[0] < bestLoc[0]) {
+			best, bestLoc = i, loc
+		}
+	}
+	if best < 0 {
+		return -1, false
+	}
+	r.buf = append(r.buf[:0], r.buf[bestLoc[1]:]...)
+	return best, true
+}
+
+// fail wraps err with the statement's line, passing ErrStopped through.
+func (r *Run) fail(o op, err error) error {
+	if errors.Is(err, ErrStopped) {
+		return ErrStopped
+	}
+	return &Error{Script: r.script.Name, Line: o.line, Err: err}
+}
+
+// expand fills in the escapes and placeholders of send text. Placeholder
+// values are used as-is, and secret values are never included in errors.
+func (r *Run) expand(text string) (string, error) {
+	var b strings.Builder
+	for text != "" {
+		i := strings.IndexByte(text, '{')
+		j := -1
+		if i >= 0 {
+			j = strings.IndexByte(text[i:], '}')
+		}
+		if j < 0 {
+			i, j = len(text), 0
+		}
+		lit, err := unescape(text[:i])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(lit)
+		if i == len(text) {
+			break
+		}
+		name := text[i+1 : i+j]
+		text = text[i+j+1:]
+
+		sname, ok := strings.CutPrefix(name, "secret:")
+		if name == "password" {
+			if r.env.Password == "" {
+				return "", errors.New("{password}: the host has no password secret")
+			}
+			sname, ok = r.env.Password, true
+		}
+		if ok {
+			if r.env.Secrets == nil {
+				return "", fmt.Errorf("secret %q: no secret provider", sname)
+			}
+			v, err := r.env.Secrets.Lookup(sname)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", r.env.Secrets.Name(), err)
+			}
+			b.WriteString(v)
+			continue
+		}
+		v, ok := r.env.Vars[name]
+		if !ok {
+			v = "{" + name + "}"
+		}
+		b.WriteString(v)
+	}
+	return b.String(), nil
+}
+
+// unescape expands \r, \n, \t, \e, \\ and \xHH.
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		if i+1 >= len(s) {
+			return "", errors.New(`trailing \ in send text`)
+		}
+		i++
+		switch s[i] {
+		case 'r':
+			b.WriteByte('\r')
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'e':
+			b.WriteByte(0x1b)
+		case '\\':
+			b.WriteByte('\\')
+		case 'x':
+			if i+2 >= len(s) {
+				return "", errors.New(`\x needs two hex digits`)
+			}
+			v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf(`invalid escape \x%s`, s[i+1:i+3])
+			}
+			b.WriteByte(byte(v))
+			i += 2
+		default:
+			return "", fmt.Errorf(`unknown escape \%c`, s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// describe returns the patterns being waited for, for timeout messages.
+func describe(branches []branch) string {
+	q := make([]string, len(branches))
+	for i, b := range branches {
+		q[i] = strconv.Quote(b.re.String())
+	}
+	return strings.Join(q, " or ")
+}
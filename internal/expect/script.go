@@ -0,0 +1,349 @@
+// Package expect runs login scripts: small expect/send programs that wait
+// for patterns in a session's output and type replies, eg. to answer a
+// "Username:" prompt and pick a menu option on every login.
+//
+// Scripts are plain text, one statement per line:
+//
+//	# OpenVMS login
+//	timeout 15s               # default wait for expect/choose (10s if not set)
+//	expect "Username:"        # wait for a regular expression
+//	send "{user}\r"           # type text (placeholders and escapes are expanded)
+//	expect "Password:" 30s    # wait with its own timeout
+//	send "{secret:vms}\r"     # secrets come from a provider, never the script
+//	choose                    # wait for the first of several patterns
+//	  case "Choice:" goto menu
+//	  case "\$ $" goto ready
+//	  timeout goto ready      # without it, a timeout fails the script
+//	end
+//	label menu
+//	send "2\r"
+//	label ready
+//	sleep 500ms
+//	send "SET TERMINAL/INQUIRE\r"
+//
+// Other statements are "goto label", "fail message" and "done" (stop
+// successfully, like reaching the end). Arguments are split like a shell
+// command line; backslashes are kept, so patterns are written as-is.
+//
+// Send text expands {alias}, {host}, {port}, {user}, {secret:name} and
+// {password} (the host's password secret), and the escapes \r, \n, \t,
+// \e, \\ and \xHH.
+package expect
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+// DefaultTimeout is how long expect and choose wait when the script doesn't
+// set a timeout.
+const DefaultTimeout = 10 * time.Second
+
+// ScriptDir is the directory, relative to the home directory, that script
+// names without a path are looked up in.
+var ScriptDir = []string{".btms", "scripts"}
+
+type opKind int
+
+const (
+	opTimeout opKind = iota
+	opExpect
+	opChoose
+	opSend
+	opSleep
+	opGoto
+	opFail
+	opDone
+)
+
+// branch is one case of a choose statement.
+type branch struct {
+	re    *regexp.Regexp // pattern to wait for
+	label string         // where to continue when it matches
+}
+
+// op is one parsed statement.
+type op struct {
+	kind    opKind
+	line    int            // source line, for errors
+	text    string         // send text or fail message, unexpanded
+	re      *regexp.Regexp // expect pattern
+	wait    time.Duration  // timeout/sleep duration; 0 uses the current default for expect/choose
+	label   string         // goto target
+	cases   []branch       // choose cases
+	onTimer string         // choose: label to continue at on timeout ("" fails)
+}
+
+// Script is a parsed login script.
+type Script struct {
+	Name   string         // script name for messages (usually the file name)
+	ops    []op           // statements, in order
+	labels map[string]int // label -> index of the next statement
+}
+
+// UsesSecrets reports whether any send text refers to a secret.
+func (s *Script) UsesSecrets() bool {
+	for _, o := range s.ops {
+		if o.kind == opSend && (strings.Contains(o.text, "{secret:") || strings.Contains(o.text, "{password}")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads and parses the script at path.
+//
+// A leading ~ is the home directory, and a bare name (no directory) is
+// looked up in ScriptDir.
+func Load(path string) (*Script, error) {
+	p, err := ResolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("login script: %w", err)
+	}
+	return Parse(filepath.Base(p), string(b))
+}
+
+// ResolvePath returns the file a script path refers to (see Load).
+func ResolvePath(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	switch {
+	case path == "":
+		return "", errors.New("login script: empty path")
+	case path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`):
+		return config.GetConfigPath(path[min(len(path), 2):])
+	case !strings.ContainsAny(path, `/\`):
+		return config.GetConfigPath(append(ScriptDir, path)...)
+	}
+	return path, nil
+}
+
+// Parse parses script source. name is used in error messages.
+func Parse(name, src string) (*Script, error) {
+	s := &Script{Name: name, labels: map[string]int{}}
+	var choose *op // open choose block
+
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	for i, line := range strings.Split(src, "\n") {
+		lineNo := i + 1
+		args, err := config.SplitArgs(stripComment(line))
+		if err != nil {
+			return nil, s.errorf(lineNo, "%v", err)
+		}
+		if len(args) == 0 {
+			continue
+		}
+		kw, args := strings.ToLower(args[0]), args[1:]
+
+		if choose != nil {
+			if err := s.parseChooseLine(choose, lineNo, kw, args); err != nil {
+				return nil, err
+			}
+			if kw == "end" {
+				s.ops = append(s.ops, *choose)
+				choose = nil
+			}
+			continue
+		}
+
+		o := op{line: lineNo}
+		switch kw {
+		case "timeout":
+			o.kind = opTimeout
+			if len(args) != 1 {
+				return nil, s.errorf(lineNo, "usage: timeout DURATION")
+			}
+			if o.wait, err = parseDuration(args[0]); err != nil {
+				return nil, s.errorf(lineNo, "%v", err)
+			}
+		case "expect":
+			o.kind = opExpect
+			if len(args) < 1 || len(args) > 2 {
+				return nil, s.errorf(lineNo, "usage: expect PATTERN [TIMEOUT]")
+			}
+			if o.re, err = compile(args[0]); err != nil {
+				return nil, s.errorf(lineNo, "%v", err)
+			}
+			if len(args) == 2 {
+				if o.wait, err = parseDuration(args[1]); err != nil {
+					return nil, s.errorf(lineNo, "%v", err)
+				}
+			}
+		case "send":
+			o.kind = opSend
+			if len(args) != 1 {
+				return nil, s.errorf(lineNo, "usage: send TEXT (quote text with spaces)")
+			}
+			if _, err := unescape(args[0]); err != nil {
+				return nil, s.errorf(lineNo, "%v", err)
+			}
+			o.text = args[0]
+		case "sleep":
+			o.kind = opSleep
+			if len(args) != 1 {
+				return nil, s.errorf(lineNo, "usage: sleep DURATION")
+			}
+			if o.wait, err = parseDuration(args[0]); err != nil {
+				return nil, s.errorf(lineNo, "%v", err)
+			}
+		case "label":
+			if len(args) != 1 {
+				return nil, s.errorf(lineNo, "usage: label NAME")
+			}
+			if _, dup := s.labels[args[0]]; dup {
+				return nil, s.errorf(lineNo, "duplicate label %q", args[0])
+			}
+			s.labels[args[0]] = len(s.ops)
+			continue
+		case "goto":
+			o.kind = opGoto
+			if len(args) != 1 {
+				return nil, s.errorf(lineNo, "usage: goto LABEL")
+			}
+			o.label = args[0]
+		case "fail":
+			o.kind = opFail
+			o.text = strings.Join(args, " ")
+		case "done":
+			o.kind = opDone
+		case "choose":
+			if len(args) != 0 {
+				return nil, s.errorf(lineNo, "usage: choose, then case lines and end")
+			}
+			choose = &op{kind: opChoose, line: lineNo}
+			continue
+		default:
+			return nil, s.errorf(lineNo, "unknown statement %q", kw)
+		}
+		s.ops = append(s.ops, o)
+	}
+
+	if choose != nil {
+		return nil, s.errorf(choose.line, "choose without end")
+	}
+	for _, o := range s.ops {
+		for _, l := range append(o.labelRefs(), o.label) {
+			if _, ok := s.labels[l]; l != "" && !ok {
+				return nil, s.errorf(o.line, "unknown label %q", l)
+			}
+		}
+	}
+	return s, nil
+}
+
+// parseChooseLine parses one line inside a choose block.
+func (s *Script) parseChooseLine(c *op, lineNo int, kw string, args []string) error {
+	switch kw {
+	case "case":
+		if len(args) != 3 || strings.ToLower(args[1]) != "goto" {
+			return s.errorf(lineNo, "usage: case PATTERN goto LABEL")
+		}
+		re, err := compile(args[0])
+		if err != nil {
+			return s.errorf(lineNo, "%v", err)
+		}
+		c.cases = append(c.cases, branch{re: re, label: args[2]})
+	case "timeout":
+		switch {
+		case len(args) == 2 && strings.ToLower(args[0]) == "goto":
+			c.onTimer = args[1]
+		case len(args) == 3 && strings.ToLower(args[1]) == "goto":
+			d, err := parseDuration(args[0])
+			if err != nil {
+				return s.errorf(lineNo, "%v", err)
+			}
+			c.wait, c.onTimer = d, args[2]
+		default:
+			return s.errorf(lineNo, "usage: timeout [DURATION] goto LABEL")
+		}
+	case "end":
+		if len(args) != 0 {
+			return s.errorf(lineNo, "usage: end")
+		}
+		if len(c.cases) == 0 {
+			return s.errorf(c.line, "choose needs at least one case")
+		}
+	default:
+		return s.errorf(lineNo, "%q inside choose (expected case, timeout or end)", kw)
+	}
+	return nil
+}
+
+// labelRefs returns the labels a choose statement can jump to.
+func (o op) labelRefs() []string {
+	refs := []string{o.onTimer}
+	for _, c := range o.cases {
+		refs = append(refs, c.label)
+	}
+	return refs
+}
+
+// Error is a script error, with the line it happened on.
+type Error struct {
+	Script string // script name
+	Line   int    // source line (0 if not tied to a line)
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.Script, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.Script, e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func (s *Script) errorf(line int, format string, args ...any) error {
+	return &Error{Script: s.Name, Line: line, Err: fmt.Errorf(format, args...)}
+}
+
+// stripComment removes a # comment that starts a line or follows whitespace
+// outside quotes, so patterns like "#$" still work.
+func stripComment(line string) string {
+	var quote rune
+	prevSpace := true
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '#' && prevSpace:
+			return line[:i]
+		}
+		prevSpace = r == ' ' || r == '\t'
+	}
+	return line
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, errors.New("empty pattern")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q (eg. 10s, 500ms)", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,218 @@
+// Package fanout runs one command on many ssh hosts at once and collects
+// the output of each, so routine checks don't need a session per host.
+package fanout
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"bubbletea-ssh-manager/internal/connect"
+)
+
+const (
+	DefaultConcurrency = 8
+	DefaultTimeout     = 30 * time.Second
+
+	maxOutput       = 256 * 1024               // output kept per host; the rest is dropped
+	truncatedMarker = "\n[output truncated]\n" // appended to output cut at maxOutput
+)
+
+// Status is where a host's run is at.
+type Status int
+
+const (
+	StatusPending  Status = iota // waiting for a free slot
+	StatusRunning                // command is running
+	StatusDone                   // exited with status 0
+	StatusFailed                 // non-zero exit, or ssh couldn't connect
+	StatusTimedOut               // killed after the per-host timeout
+	StatusCanceled               // run was canceled before the host finished
+)
+
+// String returns a short description of the status.
+func (s Status) String() string {
+	switch s {
+	case StatusRunning:
+		return "running"
+	case StatusDone:
+		return "ok"
+	case StatusFailed:
+		return "failed"
+	case StatusTimedOut:
+		return "timed out"
+	case StatusCanceled:
+		return "canceled"
+	default:
+		return "pending"
+	}
+}
+
+// Finished reports whether the host won't change status again.
+func (s Status) Finished() bool {
+	return s >= StatusDone
+}
+
+// Options controls a run.
+type Options struct {
+	Concurrency int           // hosts running at once (DefaultConcurrency if <= 0)
+	Timeout     time.Duration // per-host timeout (DefaultTimeout if <= 0)
+}
+
+// Update reports progress for one host. Output carries complete lines (with
+// their newline) as they arrive; a final update has a finished Status.
+type Update struct {
+	Alias    string
+	Status   Status
+	Output   string        // new output since the last update
+	ExitCode int           // exit code once finished (-1 if unknown)
+	Err      error         // why the host failed (nil on success)
+	Duration time.Duration // run time once finished
+}
+
+// Result is the complete outcome for one host.
+type Result struct {
+	Alias    string
+	Status   Status
+	Output   string // stdout and stderr, interleaved as they arrived
+	ExitCode int    // -1 if unknown (eg. ssh couldn't start)
+	Err      error
+	Duration time.Duration
+}
+
+// Apply folds an update into the result.
+func (r *Result) Apply(u Update) {
+	r.Alias = u.Alias
+	r.Status = u.Status
+	if len(r.Output) < maxOutput {
+		r.Output += u.Output
+		if len(r.Output) > maxOutput {
+			r.Output = truncate(r.Output, maxOutput) + truncatedMarker
+		}
+	}
+	if u.Status.Finished() {
+		r.ExitCode = u.ExitCode
+		r.Err = u.Err
+		r.Duration = u.Duration
+	}
+}
+
+// truncate cuts s to at most n bytes, backing off to the start of a rune so
+// a multi-byte character isn't split.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Run executes command on every alias with `ssh -T`, at most opts.Concurrency
+// at a time, and sends progress to updates. It closes updates when every host
+// has finished. Canceling ctx kills the running commands and marks hosts that
+// haven't started as canceled.
+func Run(ctx context.Context, aliases []string, command string, opts Options, updates chan<- Update) {
+	defer close(updates)
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Concurrency)
+	for _, alias := range aliases {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			updates <- Update{Alias: alias, Status: StatusCanceled, ExitCode: -1, Err: ctx.Err()}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			runHost(ctx, alias, command, opts.Timeout, updates)
+		}()
+	}
+	wg.Wait()
+}
+
+// runHost runs the command on one host and reports its progress.
+func runHost(ctx context.Context, alias, command string, timeout time.Duration, updates chan<- Update) {
+	if ctx.Err() != nil {
+		updates <- Update{Alias: alias, Status: StatusCanceled, ExitCode: -1, Err: ctx.Err()}
+		return
+	}
+	hostCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	c, err := connect.RemoteCommand(hostCtx, alias, command, timeout)
+	if err != nil {
+		updates <- Update{Alias: alias, Status: StatusFailed, ExitCode: -1, Err: err}
+		return
+	}
+	out := &lineWriter{alias: alias, updates: updates}
+	c.Stdout = out
+	c.Stderr = out
+
+	updates <- Update{Alias: alias, Status: StatusRunning}
+	err = c.Run()
+	out.flush()
+
+	u := Update{Alias: alias, ExitCode: connect.ExitCode(err), Err: err, Duration: time.Since(start)}
+	switch {
+	case err == nil:
+		u.Status, u.ExitCode = StatusDone, 0
+	case errors.Is(ctx.Err(), context.Canceled):
+		u.Status, u.Err = StatusCanceled, ctx.Err()
+	case hostCtx.Err() != nil:
+		u.Status, u.Err = StatusTimedOut, hostCtx.Err()
+	default:
+		u.Status = StatusFailed
+	}
+	updates <- u
+}
+
+// lineWriter sends output as complete lines, holding back a partial last line.
+//
+// stdout and stderr share one writer, so it's safe for concurrent use.
+type lineWriter struct {
+	mu      sync.Mutex
+	alias   string
+	buf     []byte
+	updates chan<- Update
+}
+
+// Write implements io.Writer.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	if i := bytes.LastIndexByte(w.buf, '\n'); i >= 0 {
+		w.updates <- Update{Alias: w.alias, Status: StatusRunning, Output: normalizeNewlines(w.buf[:i+1])}
+		w.buf = append(w.buf[:0], w.buf[i+1:]...)
+	}
+	return len(p), nil
+}
+
+// flush sends any partial last line (with a newline added).
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.updates <- Update{Alias: w.alias, Status: StatusRunning, Output: normalizeNewlines(w.buf) + "\n"}
+		w.buf = nil
+	}
+}
+
+// normalizeNewlines turns CRLF line endings (ssh -T sends some) into LF.
+func normalizeNewlines(b []byte) string {
+	return string(bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n")))
+}
@@ -0,0 +1,52 @@
+package fanout
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestResultApply(t *testing.T) {
+	var r Result
+	r.Apply(Update{Alias: "web1", Status: StatusRunning, Output: "line 1\n"})
+	r.Apply(Update{Alias: "web1", Status: StatusRunning, Output: "line 2\n"})
+	if r.Output != "line 1\nline 2\n" || r.Status != StatusRunning || r.ExitCode != 0 {
+		t.Errorf("running: %+v", r)
+	}
+	r.Apply(Update{Alias: "web1", Status: StatusFailed, ExitCode: 3})
+	if r.Status != StatusFailed || r.ExitCode != 3 || r.Output != "line 1\nline 2\n" {
+		t.Errorf("finished: %+v", r)
+	}
+}
+
+func TestResultApplyTruncates(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		kept   int // bytes kept before the marker
+	}{
+		{"ascii", strings.Repeat("a", maxOutput+10), maxOutput},
+		{"rune across the limit", strings.Repeat("a", maxOutput-1) + "é" + "tail", maxOutput - 1},
+		{"rune at the limit", strings.Repeat("a", maxOutput-2) + "é" + "tail", maxOutput},
+		{"wide rune", strings.Repeat("a", maxOutput-2) + "😀", maxOutput - 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r Result
+			r.Apply(Update{Status: StatusRunning, Output: tt.output})
+			if !strings.HasSuffix(r.Output, truncatedMarker) {
+				t.Fatalf("output doesn't end with the truncation marker: %q", r.Output[max(len(r.Output)-40, 0):])
+			}
+			kept := strings.TrimSuffix(r.Output, truncatedMarker)
+			if len(kept) != tt.kept || !utf8.ValidString(kept) {
+				t.Errorf("kept %d bytes (valid UTF-8 %v), want %d", len(kept), utf8.ValidString(kept), tt.kept)
+			}
+
+			// later output is dropped
+			r.Apply(Update{Status: StatusDone, Output: "more\n"})
+			if strings.HasSuffix(r.Output, "more\n") {
+				t.Error("output appended after truncation")
+			}
+		})
+	}
+}
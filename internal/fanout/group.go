@@ -0,0 +1,117 @@
+package fanout
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// maxDiffLines bounds the outputs Diff compares line by line (the table is quadratic).
+const maxDiffLines = 2000
+
+// Group is a set of hosts that produced identical output and exit status.
+type Group struct {
+	Output   string
+	Status   Status
+	ExitCode int
+	Aliases  []string
+}
+
+// GroupOutputs groups finished results by output (ignoring trailing
+// whitespace), status and exit code. The largest group comes first; ties are
+// ordered by their first alias.
+func GroupOutputs(results []Result) []Group {
+	type groupKey struct {
+		output string
+		status Status
+		code   int
+	}
+	var order []groupKey
+	byKey := map[groupKey]*Group{}
+	for _, r := range results {
+		if !r.Status.Finished() {
+			continue
+		}
+		k := groupKey{strings.TrimRight(r.Output, " \t\r\n"), r.Status, r.ExitCode}
+		g, ok := byKey[k]
+		if !ok {
+			g = &Group{Output: k.output, Status: r.Status, ExitCode: r.ExitCode}
+			byKey[k] = g
+			order = append(order, k)
+		}
+		g.Aliases = append(g.Aliases, r.Alias)
+	}
+
+	out := make([]Group, 0, len(order))
+	for _, k := range order {
+		g := byKey[k]
+		slices.Sort(g.Aliases)
+		out = append(out, *g)
+	}
+	slices.SortStableFunc(out, func(a, b Group) int {
+		if c := cmp.Compare(len(b.Aliases), len(a.Aliases)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Aliases[0], b.Aliases[0])
+	})
+	return out
+}
+
+// Diff returns a line diff from a to b: unchanged lines are prefixed with
+// "  ", removed lines with "- " and added lines with "+ ".
+//
+// Outputs longer than maxDiffLines aren't compared; ok is false then.
+func Diff(a, b string) (lines []string, ok bool) {
+	al := splitLines(a)
+	bl := splitLines(b)
+	if len(al) > maxDiffLines || len(bl) > maxDiffLines {
+		return nil, false
+	}
+
+	// longest common subsequence table, filled from the end
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(al) && j < len(bl) {
+		switch {
+		case al[i] == bl[j]:
+			lines = append(lines, "  "+al[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "- "+al[i])
+			i++
+		default:
+			lines = append(lines, "+ "+bl[j])
+			j++
+		}
+	}
+	for ; i < len(al); i++ {
+		lines = append(lines, "- "+al[i])
+	}
+	for ; j < len(bl); j++ {
+		lines = append(lines, "+ "+bl[j])
+	}
+	return lines, true
+}
+
+// splitLines splits text into lines, without a trailing empty line.
+func splitLines(s string) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
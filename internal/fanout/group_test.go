@@ -0,0 +1,41 @@
+package fanout
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGroupOutputs(t *testing.T) {
+	results := []Result{
+		{Alias: "web3", Status: StatusDone, Output: "up 3 days\n"},
+		{Alias: "web1", Status: StatusDone, Output: "up 3 days"},
+		{Alias: "db1", Status: StatusDone, Output: "up 9 days\n"},
+		{Alias: "web2", Status: StatusFailed, ExitCode: 1, Output: "up 3 days\n"},
+		{Alias: "web4", Status: StatusFailed, ExitCode: 2, Output: "up 3 days\n"},
+		{Alias: "db2", Status: StatusTimedOut, ExitCode: -1, Err: errors.New("timed out")},
+		{Alias: "db3", Status: StatusRunning, Output: "up 3 days\n"},
+	}
+	want := []Group{
+		{Output: "up 3 days", Status: StatusDone, Aliases: []string{"web1", "web3"}},
+		{Output: "up 9 days", Status: StatusDone, Aliases: []string{"db1"}},
+		{Output: "", Status: StatusTimedOut, ExitCode: -1, Aliases: []string{"db2"}},
+		{Output: "up 3 days", Status: StatusFailed, ExitCode: 1, Aliases: []string{"web2"}},
+		{Output: "up 3 days", Status: StatusFailed, ExitCode: 2, Aliases: []string{"web4"}},
+	}
+	if got := GroupOutputs(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupOutputs =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if got := GroupOutputs(nil); len(got) != 0 {
+		t.Errorf("GroupOutputs(nil) = %+v", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	lines, ok := Diff("a\nb\nc\n", "a\nc\nd\n")
+	want := []string{"  a", "- b", "  c", "+ d"}
+	if !ok || !reflect.DeepEqual(lines, want) {
+		t.Errorf("Diff = %q, %v; want %q", lines, ok, want)
+	}
+}
@@ -0,0 +1,87 @@
+package fanout
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+// reportTimeLayout is used in report file names.
+const reportTimeLayout = "20060102-150405"
+
+// ReportDir returns the directory run reports are saved in.
+func ReportDir() (string, error) {
+	return config.GetDataPath("runs")
+}
+
+// SaveReport writes a report for a run started at started and returns its path.
+func SaveReport(command string, started time.Time, results []Result) (string, error) {
+	dir, err := ReportDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "run-"+started.Format(reportTimeLayout)+".txt")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	if err := WriteReport(f, command, started, results); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// WriteReport writes a plain-text report: the command, each host's status
+// and output, then a summary grouping hosts with identical output.
+func WriteReport(w io.Writer, command string, started time.Time, results []Result) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "Command: %s\n", command)
+	fmt.Fprintf(bw, "Started: %s\n", started.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(bw, "Hosts:   %d\n", len(results))
+
+	for _, r := range results {
+		fmt.Fprintf(bw, "\n=== %s: %s\n", r.Alias, ResultLabel(r))
+		if out := strings.TrimRight(r.Output, "\n"); out != "" {
+			fmt.Fprintln(bw, out)
+		}
+	}
+
+	fmt.Fprintf(bw, "\n=== Summary\n")
+	for _, g := range GroupOutputs(results) {
+		fmt.Fprintf(bw, "%d host(s), %s: %s\n", len(g.Aliases), GroupLabel(g), strings.Join(g.Aliases, ", "))
+	}
+	return bw.Flush()
+}
+
+// ResultLabel describes a host's outcome (eg. "exit 0 in 1.2s", "timed out").
+func ResultLabel(r Result) string {
+	switch r.Status {
+	case StatusDone, StatusFailed:
+		if r.ExitCode < 0 && r.Err != nil {
+			return "failed: " + r.Err.Error()
+		}
+		return fmt.Sprintf("exit %d in %s", r.ExitCode, r.Duration.Round(100*time.Millisecond))
+	default:
+		return r.Status.String()
+	}
+}
+
+// GroupLabel describes a group's shared outcome (eg. "exit 0", "timed out").
+func GroupLabel(g Group) string {
+	switch g.Status {
+	case StatusDone, StatusFailed:
+		return fmt.Sprintf("exit %d", g.ExitCode)
+	default:
+		return g.Status.String()
+	}
+}
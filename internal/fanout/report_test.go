@@ -0,0 +1,49 @@
+package fanout
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWriteReport(t *testing.T) {
+	started := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	results := []Result{
+		{Alias: "web1", Status: StatusDone, Output: "ok\n", Duration: 1240 * time.Millisecond},
+		{Alias: "web2", Status: StatusDone, Output: "ok\n", Duration: 800 * time.Millisecond},
+		{Alias: "db1", Status: StatusFailed, ExitCode: 1, Output: "disk full\n", Duration: 2 * time.Second},
+		{Alias: "db2", Status: StatusFailed, ExitCode: -1, Err: errors.New("ssh: connect refused")},
+		{Alias: "db3", Status: StatusTimedOut, ExitCode: -1},
+	}
+	var b bytes.Buffer
+	if err := WriteReport(&b, "uptime", started, results); err != nil {
+		t.Fatal(err)
+	}
+	want := `Command: uptime
+Started: 2024-05-01 09:30:00
+Hosts:   5
+
+=== web1: exit 0 in 1.2s
+ok
+
+=== web2: exit 0 in 800ms
+ok
+
+=== db1: exit 1 in 2s
+disk full
+
+=== db2: failed: ssh: connect refused
+
+=== db3: timed out
+
+=== Summary
+2 host(s), exit 0: web1, web2
+1 host(s), exit 1: db1
+1 host(s), exit -1: db2
+1 host(s), timed out: db3
+`
+	if got := b.String(); got != want {
+		t.Errorf("WriteReport =\n%s\nwant\n%s", got, want)
+	}
+}
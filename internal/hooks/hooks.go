@@ -0,0 +1,305 @@
+// Package hooks runs local commands around a session: pre-connect hooks
+// before connecting (eg. to add a VPN route) and post-connect hooks after
+// disconnecting (eg. to log the time spent).
+//
+// Global and per-group hooks live in ~/.btms/hooks, in the same style as an
+// ssh config:
+//
+//	Timeout 30s
+//	PreConnect ~/bin/vpn-route up
+//	PostConnect ~/bin/log-session
+//
+//	Group prod
+//	    Timeout 1m
+//	    PreConnect ~/bin/vpn-up prod
+//
+// Hosts add their own with "#btms PreConnect" and "#btms PostConnect". Pre
+// hooks run global first, then group, then host; post hooks run in the
+// reverse order. Each runs in a shell with the target in BTMS_* variables.
+//
+// A Timeout bounds its own level's hooks. A level without one uses the
+// enclosing level's (host hooks the group's, group hooks the global one),
+// then DefaultTimeout.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	str "bubbletea-ssh-manager/internal/stringutil"
+)
+
+const (
+	// DefaultTimeout bounds each hook when no Timeout is configured.
+	DefaultTimeout = 30 * time.Second
+
+	// maxOutput is how much of a hook's output is kept for error messages.
+	maxOutput = 4096
+
+	// waitDelay is how long to wait for a killed hook's output to close
+	// (eg. a background process it started keeps the pipe open).
+	waitDelay = 2 * time.Second
+)
+
+// ConfigPath is the hooks file, relative to the home directory.
+var ConfigPath = []string{".btms", "hooks"}
+
+// Stage is when a hook runs.
+type Stage string
+
+const (
+	Pre  Stage = "pre"  // before connecting
+	Post Stage = "post" // after the session ends
+)
+
+// Hook is one command to run.
+type Hook struct {
+	Command string        // shell command
+	Scope   string        // where it was configured (eg. "global", "group prod", "host web1")
+	Timeout time.Duration // kill the command after this long
+}
+
+// scope is the hooks configured at one level.
+type scope struct {
+	timeout time.Duration // 0 inherits
+	pre     []string
+	post    []string
+}
+
+// Config is the global and per-group hooks from the hooks file.
+type Config struct {
+	global scope
+	groups map[string]scope // by lowercased group name
+}
+
+// Load reads the hooks file. A missing file is an empty config.
+func Load() (Config, error) {
+	path, err := config.GetConfigPath(ConfigPath...)
+	if err != nil {
+		return Config{}, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+	c, err := Parse(string(b))
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse parses hooks file source.
+func Parse(src string) (Config, error) {
+	c := Config{groups: map[string]scope{}}
+	cur, group := &c.global, ""
+	save := func() {
+		if group != "" {
+			c.groups[group] = *cur
+		}
+	}
+
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	for i, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return Config{}, fmt.Errorf("line %d: %s needs a value", i+1, key)
+		}
+
+		switch strings.ToLower(key) {
+		case "group":
+			save()
+			group = strings.ToLower(value)
+			s := c.groups[group]
+			cur = &s
+		case "timeout":
+			d, err := ParseTimeout(value)
+			if err != nil {
+				return Config{}, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			cur.timeout = d
+		case "preconnect":
+			cur.pre = append(cur.pre, value)
+		case "postconnect":
+			cur.post = append(cur.post, value)
+		default:
+			return Config{}, fmt.Errorf("line %d: unknown directive %q", i+1, key)
+		}
+	}
+	save()
+	return c, nil
+}
+
+// ParseTimeout parses a hook timeout (eg. "30s", "2m").
+func ParseTimeout(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q (eg. 30s, 2m)", s)
+	}
+	return d, nil
+}
+
+// For returns the hooks for a host, in the order they run.
+func (c Config) For(alias string, app config.AppOptions) (pre, post []Hook) {
+	type level struct {
+		name  string
+		scope scope
+	}
+	levels := []level{{"global", c.global}}
+	if g, _, ok := str.SplitStringOnDelim(alias); ok {
+		if s, found := c.groups[strings.ToLower(g)]; found {
+			levels = append(levels, level{"group " + g, s})
+		}
+	}
+	host := scope{}
+	if v := strings.TrimSpace(app.PreConnect); v != "" {
+		host.pre = []string{v}
+	}
+	if v := strings.TrimSpace(app.PostConnect); v != "" {
+		host.post = []string{v}
+	}
+	levels = append(levels, level{"host " + alias, host})
+
+	// a level without a timeout inherits the enclosing level's
+	timeout := DefaultTimeout
+	for i := range levels {
+		if levels[i].scope.timeout != 0 {
+			timeout = levels[i].scope.timeout
+		}
+		levels[i].scope.timeout = timeout
+	}
+	for _, l := range levels {
+		for _, cmd := range l.scope.pre {
+			pre = append(pre, Hook{Command: cmd, Scope: l.name, Timeout: l.scope.timeout})
+		}
+	}
+	for _, l := range slices.Backward(levels) {
+		for _, cmd := range l.scope.post {
+			post = append(post, Hook{Command: cmd, Scope: l.name, Timeout: l.scope.timeout})
+		}
+	}
+	return pre, post
+}
+
+// Env describes the session a hook runs for. It is passed to the hook as
+// BTMS_* environment variables.
+type Env struct {
+	Stage    Stage
+	Protocol config.Protocol
+	Alias    string
+	Host     string
+	Port     string
+	User     string
+	Group    string        // group part of the alias ("" if ungrouped)
+	Duration time.Duration // session length (post only)
+	Err      error         // session error (post only)
+}
+
+// NewEnv returns the environment for hooks around a session to t.
+func NewEnv(stage Stage, t connect.Target) Env {
+	group, _, _ := str.SplitStringOnDelim(t.Alias)
+	return Env{Stage: stage, Protocol: t.Protocol, Alias: t.Alias, Host: t.HostName, Port: t.Port, User: t.User, Group: group}
+}
+
+// Vars returns the environment variables for e.
+func (e Env) Vars() []string {
+	vars := []string{
+		"BTMS_STAGE=" + string(e.Stage),
+		"BTMS_PROTOCOL=" + string(e.Protocol),
+		"BTMS_ALIAS=" + e.Alias,
+		"BTMS_HOST=" + e.Host,
+		"BTMS_PORT=" + e.Port,
+		"BTMS_USER=" + e.User,
+		"BTMS_GROUP=" + e.Group,
+	}
+	if e.Stage == Post {
+		result := "ok"
+		if e.Err != nil {
+			result = "error"
+		}
+		vars = append(vars,
+			"BTMS_DURATION="+strconv.Itoa(int(e.Duration.Round(time.Second).Seconds())),
+			"BTMS_RESULT="+result)
+	}
+	return vars
+}
+
+// Error is a hook that failed, with the end of its output.
+type Error struct {
+	Hook   Hook
+	Stage  Stage
+	Output string // trimmed combined output
+	Err    error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s-connect hook (%s) failed: %v", e.Stage, e.Hook.Scope, e.Err)
+	if line := str.LastNonEmptyLine(e.Output); line != "" {
+		msg += ": " + line
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Run runs hooks in order, stopping at the first failure (an *Error).
+func Run(ctx context.Context, hooks []Hook, env Env) error {
+	for _, h := range hooks {
+		if err := runOne(ctx, h, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runOne runs a single hook in a shell, bounded by its timeout.
+func runOne(ctx context.Context, h Hook, env Env) error {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+
+	name, args := shell(h.Command)
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env.Vars()...)
+	cmd.WaitDelay = waitDelay
+	killGroup(cmd)
+	out := connect.NewTailBuffer(maxOutput)
+	cmd.Stdout, cmd.Stderr = out, out
+
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", h.Timeout)
+	}
+	return &Error{Hook: h, Stage: env.Stage, Output: strings.TrimSpace(out.String()), Err: err}
+}
+
+// shell returns the program and arguments to run command with: sh, or
+// cmd.exe on Windows when no sh (eg. MSYS2) is on the PATH.
+func shell(command string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		if _, err := exec.LookPath("sh"); err != nil {
+			return "cmd.exe", []string{"/C", command}
+		}
+	}
+	return "sh", []string{"-c", command}
+}
@@ -0,0 +1,166 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+const hooksFile = `# global
+Timeout 10s
+PreConnect vpn up
+PostConnect log-session
+
+Group Prod
+    Timeout 1m
+    PreConnect prod-vpn up
+    PostConnect prod-vpn down
+
+Group dev
+    PreConnect dev-check
+`
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"PreConnect\n", "line 1: PreConnect needs a value"},
+		{"Timeout 30\n", "line 1: invalid timeout"},
+		{"\nTimeout -1s\n", "line 2: invalid timeout"},
+		{"PreCommand vpn up\n", `line 1: unknown directive "PreCommand"`},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.src); err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) = %v, want %s", tt.src, err, tt.want)
+		}
+	}
+	if _, err := Parse(strings.ReplaceAll(hooksFile, "\n", "\r\n")); err != nil {
+		t.Errorf("CRLF file: %v", err)
+	}
+}
+
+func TestConfigFor(t *testing.T) {
+	c, err := Parse(hooksFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := func(cmd, scope string, timeout time.Duration) Hook {
+		return Hook{Command: cmd, Scope: scope, Timeout: timeout}
+	}
+	tests := []struct {
+		name      string
+		alias     string
+		app       config.AppOptions
+		pre, post []Hook
+	}{
+		{"group with its own timeout", "prod.web1", config.AppOptions{PreConnect: "host-up", PostConnect: "host-down"},
+			[]Hook{
+				hook("vpn up", "global", 10*time.Second),
+				hook("prod-vpn up", "group prod", time.Minute),
+				hook("host-up", "host prod.web1", time.Minute),
+			},
+			[]Hook{
+				hook("host-down", "host prod.web1", time.Minute),
+				hook("prod-vpn down", "group prod", time.Minute),
+				hook("log-session", "global", 10*time.Second),
+			}},
+		{"group inherits the global timeout", "dev.db1", config.AppOptions{},
+			[]Hook{hook("vpn up", "global", 10*time.Second), hook("dev-check", "group dev", 10*time.Second)},
+			[]Hook{hook("log-session", "global", 10*time.Second)}},
+		{"no group", "web2", config.AppOptions{PreConnect: " host-up "},
+			[]Hook{hook("vpn up", "global", 10*time.Second), hook("host-up", "host web2", 10*time.Second)},
+			[]Hook{hook("log-session", "global", 10*time.Second)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pre, post := c.For(tt.alias, tt.app)
+			if !slices.Equal(pre, tt.pre) || !slices.Equal(post, tt.post) {
+				t.Errorf("For(%s) =\npre  %+v\npost %+v\nwant\npre  %+v\npost %+v", tt.alias, pre, post, tt.pre, tt.post)
+			}
+		})
+	}
+
+	pre, _ := Config{}.For("web", config.AppOptions{PreConnect: "up"})
+	if len(pre) != 1 || pre[0].Timeout != DefaultTimeout {
+		t.Errorf("without a hooks file: %+v, want one hook with the default timeout", pre)
+	}
+}
+
+func TestEnvVars(t *testing.T) {
+	e := Env{Stage: Post, Protocol: config.ProtocolSSH, Alias: "prod.web1", Host: "10.0.0.1", Port: "22",
+		User: "alice", Group: "prod", Duration: 90*time.Second + 400*time.Millisecond, Err: errors.New("exit 255")}
+	want := []string{"BTMS_STAGE=post", "BTMS_PROTOCOL=ssh", "BTMS_ALIAS=prod.web1", "BTMS_HOST=10.0.0.1",
+		"BTMS_PORT=22", "BTMS_USER=alice", "BTMS_GROUP=prod", "BTMS_DURATION=90", "BTMS_RESULT=error"}
+	if got := e.Vars(); !slices.Equal(got, want) {
+		t.Errorf("Vars =\n%q\nwant\n%q", got, want)
+	}
+	e.Stage = Pre
+	if got := e.Vars(); len(got) != 7 {
+		t.Errorf("pre Vars = %q, want no duration or result", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run in sh")
+	}
+	log := filepath.Join(t.TempDir(), "log")
+	t.Setenv("HOOK_LOG", log)
+	env := Env{Stage: Post, Alias: "prod.web1", Group: "prod", Duration: 3 * time.Second}
+
+	hooks := []Hook{
+		{Command: `echo "first $BTMS_STAGE $BTMS_ALIAS $BTMS_GROUP $BTMS_DURATION $BTMS_RESULT" >> "$HOOK_LOG"`, Timeout: time.Second},
+		{Command: `echo second >> "$HOOK_LOG"`, Timeout: time.Second},
+	}
+	if err := Run(context.Background(), hooks, env); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != "first post prod.web1 prod 3 ok\nsecond\n" {
+		t.Errorf("hooks wrote %q", got)
+	}
+
+	// a failure stops the run and keeps the output
+	_ = os.Remove(log)
+	hooks = []Hook{
+		{Command: "echo working; echo 'no route to vpn' >&2; exit 3", Scope: "group prod", Timeout: time.Second},
+		{Command: `echo ran >> "$HOOK_LOG"`, Timeout: time.Second},
+	}
+	err = Run(context.Background(), hooks, env)
+	var hookErr *Error
+	if !errors.As(err, &hookErr) || hookErr.Hook.Scope != "group prod" ||
+		err.Error() != "post-connect hook (group prod) failed: exit status 3: no route to vpn" {
+		t.Errorf("failing hook: %v", err)
+	}
+	if _, err := os.Stat(log); !errors.Is(err, os.ErrNotExist) {
+		t.Error("the hook after the failure ran")
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run in sh")
+	}
+	// the background sleep keeps the output pipe open unless the whole
+	// process group is killed
+	h := Hook{Command: "sleep 10 & sleep 10", Scope: "global", Timeout: 200 * time.Millisecond}
+	start := time.Now()
+	err := Run(context.Background(), []Hook{h}, Env{Stage: Pre})
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Errorf("Run = %v, want a timeout", err)
+	}
+	if d := time.Since(start); d >= waitDelay {
+		t.Errorf("Run took %s; the hook's children weren't killed", d)
+	}
+}
@@ -0,0 +1,7 @@
+package hooks
+
+import "os/exec"
+
+// killGroup is a no-op on Windows; only the shell itself is killed when
+// canceled, and WaitDelay bounds the wait for anything it started.
+func killGroup(*exec.Cmd) {}
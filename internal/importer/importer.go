@@ -0,0 +1,174 @@
+// Package importer reads the sessions saved by other terminal clients
+// (PuTTY, mRemoteNG, MobaXterm and SecureCRT) and turns them into hosts,
+// with a plan that previews what importing them would change before it's
+// written in one go.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"bubbletea-ssh-manager/internal/config"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	xunicode "golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// ErrUnknownFormat is returned when a file's format isn't given and can't be detected.
+var ErrUnknownFormat = errors.New("unknown session export format")
+
+// Session is a saved session from another client's export.
+type Session struct {
+	Name     string          // session name, without its folders
+	Folder   []string        // folders the session is in, outermost first
+	Type     string          // the client's name for the session's protocol (eg. "SSH2", "RDP")
+	Protocol config.Protocol // ssh or telnet; empty if the type isn't supported
+	HostName string          // hostname or IP address
+	Port     string          // port number; empty for the client's default
+	User     string          // user name
+}
+
+// Path returns the session's folders and name, joined with "/".
+func (s Session) Path() string {
+	return strings.Join(append(slices.Clone(s.Folder), s.Name), "/")
+}
+
+// Format is a client's session export format.
+type Format struct {
+	Name        string // name used to pick the format (eg. "putty")
+	Description string // the client and the file it exports
+
+	// Detect reports whether a file looks like this format, from its path
+	// and decoded text ("" for a directory).
+	Detect func(path, text string) bool
+
+	// Read returns the sessions in a file (or directory) of this format.
+	Read func(path string) ([]Session, error)
+}
+
+// formats are the supported formats, in the order they're detected.
+var formats = []Format{puttyFormat, mremotengFormat, mobaxtermFormat, securecrtFormat}
+
+// Formats returns the supported formats.
+func Formats() []Format {
+	return slices.Clone(formats)
+}
+
+// FormatNames returns the supported formats' names.
+func FormatNames() []string {
+	names := make([]string, 0, len(formats))
+	for _, f := range formats {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// LookupFormat returns the format named name.
+func LookupFormat(name string) (Format, bool) {
+	for _, f := range formats {
+		if strings.EqualFold(f.Name, strings.TrimSpace(name)) {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// DetectFormat returns the format of the file or directory at path. It
+// returns ok=false if it isn't one of the supported formats.
+func DetectFormat(path string) (f Format, ok bool, err error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return Format{}, false, err
+	}
+	text := ""
+	if !st.IsDir() {
+		if text, err = readText(path); err != nil {
+			return Format{}, false, err
+		}
+	}
+	for _, f := range formats {
+		if f.Detect(path, text) {
+			return f, true, nil
+		}
+	}
+	return Format{}, false, nil
+}
+
+// ReadSessions reads the sessions at path in the named format, or the
+// detected one if format is empty.
+func ReadSessions(path, format string) (Format, []Session, error) {
+	var f Format
+	if format == "" {
+		var ok bool
+		var err error
+		if f, ok, err = DetectFormat(path); err != nil {
+			return Format{}, nil, err
+		}
+		if !ok {
+			return Format{}, nil, fmt.Errorf("%s: %w", path, ErrUnknownFormat)
+		}
+	} else {
+		var ok bool
+		if f, ok = LookupFormat(format); !ok {
+			return Format{}, nil, fmt.Errorf("unknown format %q (one of %s)", format, strings.Join(FormatNames(), ", "))
+		}
+	}
+	sessions, err := f.Read(path)
+	if err != nil {
+		return f, nil, fmt.Errorf("read %s export: %w", f.Name, err)
+	}
+	return f, sessions, nil
+}
+
+// readText reads a text file, decoding it to UTF-8 (see decodeText).
+func readText(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return decodeText(b), nil
+}
+
+// decodeText decodes an exported file to UTF-8 with LF line endings.
+//
+// Windows clients write UTF-16 (regedit), UTF-8 with or without a BOM, or
+// the ANSI code page; text that isn't valid UTF-8 is taken to be Windows-1252.
+func decodeText(b []byte) string {
+	var fallback transform.Transformer = encoding.Nop.NewDecoder()
+	if !utf8.Valid(b) {
+		fallback = charmap.Windows1252.NewDecoder()
+	}
+	out, _, err := transform.Bytes(xunicode.BOMOverride(fallback), b)
+	if err != nil {
+		out = b
+	}
+	s := strings.ReplaceAll(string(out), "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
+
+// aliasPart turns a session or folder name into part of an alias: letters,
+// digits, '-' and '_' are kept, and runs of anything else become a hyphen.
+func aliasPart(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, "-", " ")), "-")
+}
+
+// splitUserHost splits "user@host" into its parts; user is empty without an '@'.
+func splitUserHost(s string) (user, host string) {
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return "", s
+}
@@ -0,0 +1,226 @@
+package importer
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+// writeFile writes b to name in dir, creating its directories, and returns
+// its path.
+func writeFile(t *testing.T, dir, name string, b []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// utf16LE encodes s as UTF-16LE with a BOM, the way regedit exports.
+func utf16LE(s string) []byte {
+	b := []byte{0xff, 0xfe}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+const puttyReg = "Windows Registry Editor Version 5.00\r\n\r\n" +
+	`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions]` + "\r\n\r\n" +
+	`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\Default%20Settings]` + "\r\n" +
+	`"HostName"=""` + "\r\n\r\n" +
+	`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\lab%2Fweb%20one]` + "\r\n" +
+	`"HostName"="admin@10.0.0.1"` + "\r\n" +
+	`"PortNumber"=dword:00000016` + "\r\n" +
+	`"Protocol"="ssh"` + "\r\n\r\n" +
+	`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\switch]` + "\r\n" +
+	`"HostName"="10.0.0.2"` + "\r\n" +
+	`"UserName"="ops \"night\" \\ shift"` + "\r\n" +
+	`"PortNumber"=dword:00000017` + "\r\n" +
+	`"Protocol"="telnet"` + "\r\n\r\n" +
+	`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\switch\Subkey]` + "\r\n" +
+	`"HostName"="ignored"` + "\r\n\r\n" +
+	`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\desk]` + "\r\n" +
+	`"HostName"="10.0.0.3"` + "\r\n" +
+	`"Protocol"="serial"` + "\r\n"
+
+var puttySessions = []Session{
+	{Name: "web one", Folder: []string{"lab"}, Type: "ssh", Protocol: config.ProtocolSSH,
+		HostName: "10.0.0.1", Port: "22", User: "admin"},
+	{Name: "switch", Folder: []string{}, Type: "telnet", Protocol: config.ProtocolTelnet,
+		HostName: "10.0.0.2", Port: "23", User: `ops "night" \ shift`},
+	{Name: "desk", Folder: []string{}, Type: "serial", HostName: "10.0.0.3"},
+}
+
+const mobaxtermIni = "[Bookmarks]\r\nSubRep=\r\nImgNum=42\r\n" +
+	"web1= #109#0%10.0.0.1%22%admin%%-1%-1%%%%%0%0%0%%%-1%0%0%0%%1080%%0%0%1#MobaFont%10%0%0%-1%15%236,236,236%30,30,30%180,180,192%0%-1%0%%xterm%-1%-1%_Std_Colors_0_%80%24%0%1%-1%<none>%%0%1%-1#0# #-1\r\n" +
+	"\r\n[Bookmarks_1]\r\nSubRep=Prod\\Switches\r\nImgNum=41\r\n" +
+	"core= #98#1%10.0.0.2%2323%%%2%%%%%0%0%%1080%#MobaFont%10%0%0%-1%15%236,236,236%30,30,30%180,180,192%0%-1%0%%xterm%-1%-1%_Std_Colors_0_%80%24%0%1%-1%<none>%%0%1%-1#0# #-1\r\n" +
+	"caf\xe9= #91#4%10.0.0.3%3389%%-1%-1%-1%0%0%0%-1%%%%%0%0%0%-1%%-1%-1%0%0%-1%-1%%0%%0#MobaFont%10#0# #-1\r\n"
+
+var mobaxtermSessions = []Session{
+	{Name: "web1", Folder: []string{}, Type: "SSH", Protocol: config.ProtocolSSH, HostName: "10.0.0.1", Port: "22", User: "admin"},
+	{Name: "core", Folder: []string{"Prod", "Switches"}, Type: "Telnet", Protocol: config.ProtocolTelnet,
+		HostName: "10.0.0.2", Port: "2323"},
+	{Name: "café", Folder: []string{"Prod", "Switches"}, Type: "RDP", HostName: "10.0.0.3", Port: "3389"},
+}
+
+const mremotengXML = `<?xml version="1.0" encoding="utf-8"?>
+<mrng:Connections xmlns:mrng="http://mremoteng.org" Name="Connections" Export="false" EncryptionEngine="AES" FullFileEncryption="false" ConfVersion="2.6">
+    <Node Name="Prod" Type="Container" Expanded="true">
+        <Node Name="Web" Type="Container" Expanded="true">
+            <Node Name="web1" Type="Connection" Hostname="10.0.0.1" Protocol="SSH2" Port="22" Username="admin" />
+        </Node>
+        <Node Name="core" Type="Connection" Hostname="10.0.0.2" Protocol="Telnet" Port="23" Username="" />
+    </Node>
+    <Node Name="desk" Type="Connection" Hostname="10.0.0.3" Protocol="RDP" Port="3389" Username="me" />
+</mrng:Connections>
+`
+
+var mremotengSessions = []Session{
+	{Name: "web1", Folder: []string{"Prod", "Web"}, Type: "SSH2", Protocol: config.ProtocolSSH,
+		HostName: "10.0.0.1", Port: "22", User: "admin"},
+	{Name: "core", Folder: []string{"Prod"}, Type: "Telnet", Protocol: config.ProtocolTelnet,
+		HostName: "10.0.0.2", Port: "23"},
+	{Name: "desk", Type: "RDP", HostName: "10.0.0.3", Port: "3389", User: "me"},
+}
+
+const securecrtXML = `<?xml version="1.0" encoding="UTF-8"?>
+<VanDyke version="3.0">
+    <key name="Sessions">
+        <key name="Default">
+            <string name="Protocol Name">SSH2</string>
+        </key>
+        <key name="Lab">
+            <key name="r1">
+                <string name="Protocol Name">SSH2</string>
+                <string name="Hostname">10.0.0.9</string>
+                <string name="Username">admin</string>
+                <dword name="[SSH2] Port">2222</dword>
+            </key>
+            <key name="sw1">
+                <string name="Protocol Name">Telnet</string>
+                <string name="Hostname">10.0.0.10</string>
+                <dword name="Port">23</dword>
+            </key>
+        </key>
+    </key>
+</VanDyke>
+`
+
+var securecrtXMLSessions = []Session{
+	{Name: "r1", Folder: []string{"Lab"}, Type: "SSH2", Protocol: config.ProtocolSSH,
+		HostName: "10.0.0.9", Port: "2222", User: "admin"},
+	{Name: "sw1", Folder: []string{"Lab"}, Type: "Telnet", Protocol: config.ProtocolTelnet,
+		HostName: "10.0.0.10", Port: "23"},
+}
+
+// securecrtIni is a session file in SecureCRT's Sessions directory.
+const securecrtIni = "S:\"Protocol Name\"=SSH2\r\n" +
+	"S:\"Hostname\"=10.0.0.9\r\n" +
+	"S:\"Username\"=admin\r\n" +
+	"D:\"[SSH2] Port\"=000008ae\r\n" +
+	"D:\"Port\"=00000017\r\n"
+
+func TestReadSessions(t *testing.T) {
+	dir := t.TempDir()
+	crt := filepath.Join(dir, "Sessions")
+	writeFile(t, crt, "__FolderData__.ini", []byte("S:\"Folder List\"=Lab\r\n"))
+	writeFile(t, crt, "Default.ini", []byte(securecrtIni))
+	writeFile(t, crt, "Lab/r1.ini", []byte(securecrtIni))
+
+	tests := []struct {
+		name   string
+		path   string
+		format string // detected
+		want   []Session
+	}{
+		{"putty utf-16", writeFile(t, dir, "putty.reg", utf16LE(puttyReg)), "putty", puttySessions},
+		{"putty utf-8", writeFile(t, dir, "putty8.reg", []byte(puttyReg)), "putty", puttySessions},
+		{"mobaxterm windows-1252", writeFile(t, dir, "MobaXterm.ini", []byte(mobaxtermIni)), "mobaxterm", mobaxtermSessions},
+		{"mremoteng", writeFile(t, dir, "confCons.xml", []byte(mremotengXML)), "mremoteng", mremotengSessions},
+		{"securecrt xml", writeFile(t, dir, "crt.xml", []byte(securecrtXML)), "securecrt", securecrtXMLSessions},
+		{"securecrt directory", crt, "securecrt", []Session{{Name: "r1", Folder: []string{"Lab"}, Type: "SSH2",
+			Protocol: config.ProtocolSSH, HostName: "10.0.0.9", Port: "2222", User: "admin"}}},
+		{"securecrt ini", writeFile(t, dir, "r1.ini", []byte(securecrtIni)), "securecrt", []Session{{Name: "r1",
+			Type: "SSH2", Protocol: config.ProtocolSSH, HostName: "10.0.0.9", Port: "2222", User: "admin"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, got, err := ReadSessions(tt.path, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Name != tt.format {
+				t.Errorf("detected %s, want %s", f.Name, tt.format)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sessions =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSessionsErrors(t *testing.T) {
+	dir := t.TempDir()
+	encrypted := strings.Replace(mremotengXML, `FullFileEncryption="false"`, `FullFileEncryption="true"`, 1)
+	if _, _, err := ReadSessions(writeFile(t, dir, "enc.xml", []byte(encrypted)), ""); err == nil ||
+		!strings.Contains(err.Error(), "encrypted") {
+		t.Errorf("encrypted mRemoteNG file: err = %v", err)
+	}
+	if _, _, err := ReadSessions(writeFile(t, dir, "notes.txt", []byte("hello\n")), ""); err == nil ||
+		!strings.Contains(err.Error(), ErrUnknownFormat.Error()) {
+		t.Errorf("unknown file: err = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestParseRegValue(t *testing.T) {
+	tests := []struct {
+		line, name, value string
+		ok                bool
+	}{
+		{`"HostName"="10.0.0.1"`, "HostName", "10.0.0.1", true},
+		{`"Path"="C:\\Users\\me \"x\""`, "Path", `C:\Users\me "x"`, true},
+		{`"PortNumber"=dword:00000016`, "PortNumber", "22", true},
+		{`"Big"=DWORD:ffffffff`, "Big", "4294967295", true},
+		{`"Bad"=dword:zz`, "", "", false},
+		{`"Bin"=hex:01,02`, "", "", false},
+		{`"Open"="no end`, "", "", false},
+		{`@="default"`, "", "", false},
+	}
+	for _, tt := range tests {
+		name, value, ok := parseRegValue(tt.line)
+		if ok != tt.ok || (ok && (name != tt.name || value != tt.value)) {
+			t.Errorf("parseRegValue(%s) = %q, %q, %v; want %q, %q, %v", tt.line, name, value, ok, tt.name, tt.value, tt.ok)
+		}
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"utf-8", []byte("café\r\nx"), "café\nx"},
+		{"utf-8 bom", append([]byte{0xef, 0xbb, 0xbf}, "café\n"...), "café\n"},
+		{"utf-16le bom", utf16LE("café\r\n"), "café\n"},
+		{"utf-16be bom", []byte{0xfe, 0xff, 0, 'c', 0, 0xe9, 0, '\r'}, "cé\n"},
+		{"windows-1252", []byte("caf\xe9 \x80\r"), "café €\n"},
+	}
+	for _, tt := range tests {
+		if got := decodeText(tt.in); got != tt.want {
+			t.Errorf("%s: decodeText = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"path/filepath"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+// mobaxtermFormat reads MobaXterm's exported sessions (.mxtsessions), or the
+// bookmarks in its MobaXterm.ini.
+var mobaxtermFormat = Format{
+	Name:        "mobaxterm",
+	Description: "MobaXterm sessions (.mxtsessions or MobaXterm.ini)",
+	Detect: func(path, text string) bool {
+		return strings.EqualFold(filepath.Ext(path), ".mxtsessions") ||
+			(strings.Contains(text, "[Bookmarks") && strings.Contains(text, "SubRep="))
+	},
+	Read: readMobaXterm,
+}
+
+// mobaxtermTypes names MobaXterm's session types, by the number it saves.
+var mobaxtermTypes = map[string]string{
+	"0": "SSH", "1": "Telnet", "2": "Rsh", "3": "XDMCP", "4": "RDP", "5": "VNC", "6": "FTP", "7": "SFTP",
+	"8": "Serial", "9": "File", "10": "Shell", "11": "Browser", "12": "Mosh", "13": "S3", "14": "WSL",
+}
+
+// readMobaXterm reads the sessions in MobaXterm's bookmark sections.
+//
+// Each [Bookmarks] or [Bookmarks_N] section is a folder, named by its
+// SubRep ('\' between subfolders), and every other line in it is a session:
+// "name= #icon#type%host%port%user%…". ssh (0) and telnet (1) sessions are
+// supported.
+func readMobaXterm(path string) ([]Session, error) {
+	text, err := readText(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []Session
+	var folder []string
+	inBookmarks := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section := line[1 : len(line)-1]
+			inBookmarks = section == "Bookmarks" || strings.HasPrefix(section, "Bookmarks_")
+			folder = nil
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !inBookmarks || !ok {
+			continue
+		}
+		// sessions are saved as "name= #icon#…", with a space before the '#'
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		switch name {
+		case "SubRep":
+			folder = strings.FieldsFunc(value, func(r rune) bool { return r == '\\' })
+			continue
+		case "ImgNum":
+			continue
+		}
+
+		// "#icon#settings#terminal settings#…": settings are '%'-separated
+		parts := strings.Split(value, "#")
+		if len(parts) < 3 || parts[0] != "" {
+			continue
+		}
+		fields := strings.Split(parts[2], "%")
+		s := Session{Name: name, Folder: folder}
+		s.Type = mobaxtermTypes[fields[0]]
+		if s.Type == "" {
+			s.Type = "type " + fields[0]
+		}
+		switch fields[0] {
+		case "0":
+			s.Protocol = config.ProtocolSSH
+		case "1":
+			s.Protocol = config.ProtocolTelnet
+		}
+		if len(fields) > 3 {
+			s.HostName, s.Port, s.User = fields[1], fields[2], fields[3]
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,80 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+// mremotengFormat reads mRemoteNG's connections file (confCons.xml), or an
+// XML export of some of its connections.
+var mremotengFormat = Format{
+	Name:        "mremoteng",
+	Description: "mRemoteNG connections (confCons.xml or an XML export)",
+	Detect: func(path, text string) bool {
+		return strings.Contains(text, "<mrng:Connections") ||
+			(strings.Contains(text, "<Connections") && strings.Contains(text, "ConfVersion="))
+	},
+	Read: readMRemoteNG,
+}
+
+// mrngNode is a folder ("Container") or connection in mRemoteNG's file.
+type mrngNode struct {
+	Name     string     `xml:"Name,attr"`
+	Type     string     `xml:"Type,attr"`
+	Hostname string     `xml:"Hostname,attr"`
+	Protocol string     `xml:"Protocol,attr"`
+	Port     string     `xml:"Port,attr"`
+	Username string     `xml:"Username,attr"`
+	Nodes    []mrngNode `xml:"Node"`
+}
+
+// readMRemoteNG reads the connections in an mRemoteNG XML file, with the
+// containers they're in as their folders.
+//
+// A file saved with "encrypt complete connection file" can't be read; the
+// connections have to be exported without it first.
+func readMRemoteNG(path string) ([]Session, error) {
+	text, err := readText(path)
+	if err != nil {
+		return nil, err
+	}
+	var root struct {
+		FullFileEncryption string     `xml:"FullFileEncryption,attr"`
+		Nodes              []mrngNode `xml:"Node"`
+	}
+	dec := xml.NewDecoder(strings.NewReader(text))
+	// readText has decoded the file already, whatever its declaration says
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+	if err := dec.Decode(&root); err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(root.FullFileEncryption, "true") {
+		return nil, errors.New("the file is encrypted; export the connections from mRemoteNG without full file encryption")
+	}
+
+	var sessions []Session
+	var walk func(nodes []mrngNode, folder []string)
+	walk = func(nodes []mrngNode, folder []string) {
+		for _, n := range nodes {
+			if strings.EqualFold(n.Type, "Container") {
+				walk(n.Nodes, append(folder[:len(folder):len(folder)], n.Name))
+				continue
+			}
+			s := Session{Name: n.Name, Folder: folder, Type: n.Protocol, HostName: n.Hostname,
+				Port: n.Port, User: n.Username}
+			switch strings.ToUpper(n.Protocol) {
+			case "SSH1", "SSH2":
+				s.Protocol = config.ProtocolSSH
+			case "TELNET":
+				s.Protocol = config.ProtocolTelnet
+			}
+			sessions = append(sessions, s)
+		}
+	}
+	walk(root.Nodes, nil)
+	return sessions, nil
+}
@@ -0,0 +1,244 @@
+package importer
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+	str "bubbletea-ssh-manager/internal/stringutil"
+)
+
+// Action is what importing an item does.
+type Action string
+
+const (
+	ActionAdd     Action = "add"     // add the host
+	ActionReplace Action = "replace" // replace the host with its alias
+	ActionSkip    Action = "skip"    // leave it out: its alias exists, or the session isn't supported
+	ActionInvalid Action = "invalid" // leave it out: the host isn't valid
+	ActionFail    Action = "fail"    // leave it out: its inventory can't be written
+)
+
+// Item is a host to import and what importing it does.
+type Item struct {
+	Source string      // where the host came from: a session's folders and name, or its alias
+	Host   config.Host // host to import; its inventory once planned
+	Action Action      // what importing it does; empty until planned
+	Reason string      // why it's left out
+	path   string      // config file it's written to
+}
+
+// SessionItems returns the items to import for sessions. Sessions get an
+// alias from their folders (the group, joined with '-') and name, made
+// unique with a numbered suffix; unsupported ones are skipped.
+func SessionItems(sessions []Session) []Item {
+	items := make([]Item, 0, len(sessions))
+	seen := map[string]bool{}
+	for _, s := range sessions {
+		it := Item{Source: s.Path()}
+		if s.Protocol == "" {
+			it.Action, it.Reason = ActionSkip, "unsupported session type "+cmp.Or(s.Type, "(none)")
+			items = append(items, it)
+			continue
+		}
+
+		var group []string
+		for _, f := range s.Folder {
+			if p := aliasPart(f); p != "" {
+				group = append(group, p)
+			}
+		}
+		nick := cmp.Or(aliasPart(s.Name), aliasPart(s.HostName), "host")
+		base := nick
+		if len(group) > 0 {
+			base = strings.Join(group, "-") + "." + nick
+		}
+		alias := base
+		for n := 2; seen[string(s.Protocol)+" "+alias]; n++ {
+			alias = base + "-" + strconv.Itoa(n)
+		}
+		seen[string(s.Protocol)+" "+alias] = true
+
+		port := s.Port
+		if info, _ := config.LookupProtocol(s.Protocol); port == info.DefaultPort || port == "0" {
+			port = ""
+		}
+		it.Host = config.Host{Protocol: s.Protocol, HostEntry: config.HostEntry{
+			Spec: config.Spec{Alias: alias, HostName: s.HostName, Port: port, User: s.User},
+		}}
+		items = append(items, it)
+	}
+	return items
+}
+
+// HostItems returns the items to import for hosts (eg. read from a config file).
+func HostItems(hosts []config.Host) []Item {
+	items := make([]Item, 0, len(hosts))
+	for _, h := range hosts {
+		items = append(items, Item{Source: h.Spec.Alias, Host: h})
+	}
+	return items
+}
+
+// Options says where a plan imports hosts to.
+type Options struct {
+	Inventory string // name of the inventory to import to; empty for the local configs
+	Replace   bool   // replace hosts whose alias exists rather than skip them
+
+	// Validate checks a host beyond its alias, required fields and port; may be nil.
+	Validate func(config.Host) error
+}
+
+// Plan is what importing a set of items would change, worked out against
+// the hosts already in the target inventory.
+type Plan struct {
+	Items []Item
+}
+
+// NewPlan works out what importing items does. Items already given an
+// action (eg. unsupported sessions) are kept as they are.
+//
+// A host is invalid if its alias or fields are; it's skipped if its alias
+// exists in the inventory (unless replacing) or earlier in the import, and
+// it fails if the inventory has no config for its protocol or can't be
+// written.
+func NewPlan(items []Item, opts Options) *Plan {
+	type target struct {
+		inv     config.Inventory
+		err     error
+		sources map[string]string // config file of each alias in the inventory
+	}
+	targets := map[config.Protocol]*target{}
+	targetFor := func(p config.Protocol) *target {
+		if t, ok := targets[p]; ok {
+			return t
+		}
+		t := &target{sources: map[string]string{}}
+		targets[p] = t
+		if t.inv, t.err = config.LookupInventory(strings.TrimSpace(opts.Inventory), p); t.err != nil {
+			return t
+		}
+		if t.err = t.inv.Writable(); t.err != nil {
+			return t
+		}
+		entries, err := t.inv.Read()
+		if err != nil {
+			t.err = err
+			return t
+		}
+		for _, e := range entries {
+			if _, ok := t.sources[e.Spec.Alias]; !ok {
+				t.sources[e.Spec.Alias] = e.SourcePath
+			}
+		}
+		return t
+	}
+
+	plan := &Plan{Items: make([]Item, 0, len(items))}
+	planned := map[string]bool{}
+	for _, it := range items {
+		if it.Action != "" {
+			plan.Items = append(plan.Items, it)
+			continue
+		}
+		h := &it.Host
+		var err error
+		if h.Spec.Alias, err = normalizeAlias(h.Spec.Alias); err == nil {
+			err = validate(*h, opts.Validate)
+		}
+		if err != nil {
+			it.Action, it.Reason = ActionInvalid, strings.ReplaceAll(err.Error(), "\n", "; ")
+			plan.Items = append(plan.Items, it)
+			continue
+		}
+
+		t := targetFor(h.Protocol)
+		h.Inventory = t.inv
+		source, exists := t.sources[h.Spec.Alias]
+		key := string(h.Protocol) + " " + h.Spec.Alias
+		switch {
+		case t.err != nil:
+			it.Action, it.Reason = ActionFail, t.err.Error()
+		case planned[key]:
+			it.Action, it.Reason = ActionSkip, "alias appears earlier in the import"
+		case exists && !opts.Replace:
+			it.Action, it.Reason = ActionSkip, "alias exists"
+		case exists:
+			it.Action, it.path = ActionReplace, source
+		default:
+			it.Action, it.path = ActionAdd, t.inv.Path
+		}
+		if it.path != "" {
+			planned[key] = true
+			h.SourcePath = it.path
+		}
+		plan.Items = append(plan.Items, it)
+	}
+	return plan
+}
+
+// Count returns how many items have one of actions.
+func (p *Plan) Count(actions ...Action) int {
+	n := 0
+	for _, it := range p.Items {
+		for _, a := range actions {
+			if it.Action == a {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// Apply writes the plan's added and replaced hosts as one transaction (see
+// config.ApplyHostChanges): either every one is written or none is.
+func (p *Plan) Apply() error {
+	var changes []config.HostChanges
+	index := map[string]int{}
+	for _, it := range p.Items {
+		if it.Action != ActionAdd && it.Action != ActionReplace {
+			continue
+		}
+		i, ok := index[it.path]
+		if !ok {
+			i = len(changes)
+			index[it.path] = i
+			changes = append(changes, config.HostChanges{Path: it.path})
+		}
+		if it.Action == ActionReplace {
+			changes[i].Remove = append(changes[i].Remove, it.Host.Spec.Alias)
+		}
+		changes[i].Add = append(changes[i].Add, it.Host.HostEntry.Normalized())
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return config.ApplyHostChanges(changes)
+}
+
+// normalizeAlias returns alias ("group.nickname" or "nickname") the way the
+// host form saves it.
+func normalizeAlias(alias string) (string, error) {
+	group, nick, ok := strings.Cut(strings.TrimSpace(alias), ".")
+	if !ok {
+		group, nick = "", group
+	}
+	return str.BuildAliasFromGroupNickname(group, nick)
+}
+
+// validate checks a host's required fields and port, then runs extra.
+func validate(h config.Host, extra func(config.Host) error) error {
+	info, ok := config.LookupProtocol(h.Protocol)
+	if !ok {
+		return fmt.Errorf("unknown protocol %q", h.Protocol)
+	}
+	_, portErr := str.NormalizePort(h.Spec.Port, h.Protocol)
+	if err := errors.Join(info.CheckRequired(h.HostEntry), portErr); err != nil || extra == nil {
+		return err
+	}
+	return extra(h)
+}
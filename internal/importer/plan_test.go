@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+func TestSessionItemsAliases(t *testing.T) {
+	items := SessionItems([]Session{
+		{Name: "Web 1", Folder: []string{"Prod", "EU West"}, Protocol: config.ProtocolSSH, HostName: "a", Port: "22"},
+		{Name: "web_1", Folder: []string{"prod", "eu-west"}, Protocol: config.ProtocolSSH, HostName: "b", Port: "2222"},
+		{Name: "web 1", Folder: []string{"Prod", "EU West"}, Protocol: config.ProtocolTelnet, HostName: "c"},
+		{Name: "!!", Protocol: config.ProtocolSSH, HostName: "10.0.0.1"},
+		{Name: "desk", Type: "RDP", HostName: "d"},
+	})
+	want := []struct {
+		alias, port string
+		action      Action
+	}{
+		{"prod-eu-west.web-1", "", ""},
+		{"prod-eu-west.web_1", "2222", ""},
+		{"prod-eu-west.web-1", "", ""}, // another protocol
+		{"10-0-0-1", "", ""},
+		{"", "", ActionSkip},
+	}
+	for i, w := range want {
+		it := items[i]
+		if it.Host.Spec.Alias != w.alias || it.Host.Spec.Port != w.port || it.Action != w.action {
+			t.Errorf("item %d (%s) = %q port %q %s; want %q port %q %s", i, it.Source, it.Host.Spec.Alias,
+				it.Host.Spec.Port, it.Action, w.alias, w.port, w.action)
+		}
+	}
+
+	items = SessionItems([]Session{
+		{Name: "web", Protocol: config.ProtocolSSH, HostName: "a"},
+		{Name: "Web", Protocol: config.ProtocolSSH, HostName: "b"},
+		{Name: "web", Protocol: config.ProtocolSSH, HostName: "c"},
+	})
+	for i, alias := range []string{"web", "web-2", "web-3"} {
+		if items[i].Host.Spec.Alias != alias {
+			t.Errorf("duplicate name %d: alias %q, want %q", i, items[i].Host.Spec.Alias, alias)
+		}
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	sshConfig := filepath.Join(home, ".ssh", "config")
+	if err := os.MkdirAll(filepath.Dir(sshConfig), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sshConfig, []byte("Host lab.web\n    HostName 10.0.0.1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	host := func(p config.Protocol, alias, hostName string) config.Host {
+		return config.Host{Protocol: p, HostEntry: config.HostEntry{
+			Spec: config.Spec{Alias: alias, HostName: hostName},
+		}}
+	}
+	ssh, telnet := config.ProtocolSSH, config.ProtocolTelnet
+	items := append(HostItems([]config.Host{
+		host(ssh, "lab.web", "10.0.0.9"),  // exists
+		host(ssh, " Lab.DB ", "10.0.0.2"), // added, alias normalized
+		host(ssh, "lab.db", "10.0.0.3"),   // earlier in the import
+		host(ssh, "lab.", "10.0.0.5"),     // invalid alias
+		host(telnet, "lab.switch", ""),    // missing hostname
+		host(ssh, "lab.rejected", "10.0.0.4"),
+	}), Item{Source: "desk", Action: ActionSkip, Reason: "unsupported session type RDP"})
+	rejected := errors.New("rejected by the validator")
+	validate := func(h config.Host) error {
+		if h.Spec.Alias == "lab.rejected" {
+			return rejected
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		actions []Action
+	}{
+		{"skip existing", Options{Validate: validate},
+			[]Action{ActionSkip, ActionAdd, ActionSkip, ActionInvalid, ActionInvalid, ActionInvalid, ActionSkip}},
+		{"replace existing", Options{Replace: true, Validate: validate},
+			[]Action{ActionReplace, ActionAdd, ActionSkip, ActionInvalid, ActionInvalid, ActionInvalid, ActionSkip}},
+		{"unknown inventory", Options{Inventory: "nope"},
+			[]Action{ActionFail, ActionFail, ActionFail, ActionInvalid, ActionInvalid, ActionFail, ActionSkip}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := NewPlan(items, tt.opts)
+			for i, it := range plan.Items {
+				if it.Action != tt.actions[i] {
+					t.Errorf("%s: %s (%s), want %s", it.Source, it.Action, it.Reason, tt.actions[i])
+				}
+			}
+		})
+	}
+
+	plan := NewPlan(items, Options{Replace: true, Validate: validate})
+	if got := plan.Items[1].Host.Spec.Alias; got != "lab.db" {
+		t.Errorf("normalized alias = %q, want lab.db", got)
+	}
+	if r := plan.Items[2].Reason; r != "alias appears earlier in the import" {
+		t.Errorf("duplicate reason = %q", r)
+	}
+	if r := plan.Items[5].Reason; r != rejected.Error() {
+		t.Errorf("validator reason = %q", r)
+	}
+	if err := plan.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(sshConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	if strings.Count(got, "Host lab.web") != 1 || !strings.Contains(got, "10.0.0.9") ||
+		strings.Contains(got, "10.0.0.1\n") || !strings.Contains(got, "Host lab.db") || strings.Contains(got, "10.0.0.3") {
+		t.Errorf("config after import =\n%s", got)
+	}
+}
@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+// puttySessionsKey is the registry key PuTTY keeps its saved sessions under.
+const puttySessionsKey = `\software\simontatham\putty\sessions\`
+
+// puttyFormat reads a .reg export of PuTTY's sessions, made with
+// `reg export HKCU\Software\SimonTatham\PuTTY\Sessions putty.reg`.
+var puttyFormat = Format{
+	Name:        "putty",
+	Description: `PuTTY sessions exported from the registry (.reg)`,
+	Detect: func(path, text string) bool {
+		return strings.Contains(strings.ToLower(text), puttySessionsKey)
+	},
+	Read: readPuTTY,
+}
+
+// readPuTTY reads the sessions in a PuTTY .reg export.
+//
+// PuTTY has no folders, but session names with '/' in them (as some session
+// managers save them) are split into folders, as is KiTTY's Folder value.
+// "Default Settings" isn't a session and is left out.
+func readPuTTY(path string) ([]Session, error) {
+	text, err := readText(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []Session
+	var values map[string]string
+	name := ""
+	flush := func() {
+		if values == nil || name == "" || name == "Default Settings" {
+			return
+		}
+		sessions = append(sessions, puttySession(name, values))
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			flush()
+			values, name = nil, ""
+			key := line[1 : len(line)-1]
+			i := strings.Index(strings.ToLower(key), puttySessionsKey)
+			if i < 0 {
+				continue
+			}
+			raw := key[i+len(puttySessionsKey):]
+			if raw == "" || strings.Contains(raw, `\`) {
+				continue // the Sessions key itself, or a subkey of a session
+			}
+			if name, err = url.PathUnescape(raw); err != nil {
+				name = raw
+			}
+			values = map[string]string{}
+			continue
+		}
+		if values == nil {
+			continue
+		}
+		if k, v, ok := parseRegValue(line); ok {
+			values[k] = v
+		}
+	}
+	flush()
+	return sessions, nil
+}
+
+// puttySession returns the session saved as name with the registry values.
+func puttySession(name string, values map[string]string) Session {
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '/' })
+	if len(parts) == 0 {
+		parts = []string{name}
+	}
+	s := Session{Name: parts[len(parts)-1], Folder: parts[:len(parts)-1]}
+	if f := values["Folder"]; f != "" && len(s.Folder) == 0 {
+		s.Folder = strings.FieldsFunc(f, func(r rune) bool { return r == '/' || r == '\\' })
+	}
+
+	// PuTTY saves sessions without a Protocol value as ssh, its default
+	s.Type = values["Protocol"]
+	if s.Type == "" {
+		s.Type = "ssh"
+	}
+	switch strings.ToLower(s.Type) {
+	case "ssh":
+		s.Protocol = config.ProtocolSSH
+	case "telnet":
+		s.Protocol = config.ProtocolTelnet
+	}
+	s.User, s.HostName = splitUserHost(strings.TrimSpace(values["HostName"]))
+	if u := strings.TrimSpace(values["UserName"]); u != "" {
+		s.User = u
+	}
+	s.Port = values["PortNumber"]
+	return s
+}
+
+// parseRegValue parses a .reg value line: `"Name"="string"` or
+// `"Name"=dword:0000001f`, which is returned in decimal. Other value types
+// aren't needed and return ok=false.
+func parseRegValue(line string) (name, value string, ok bool) {
+	if !strings.HasPrefix(line, `"`) {
+		return "", "", false
+	}
+	name, rest, ok := cutRegString(line)
+	if !ok || !strings.HasPrefix(rest, "=") {
+		return "", "", false
+	}
+	rest = rest[1:]
+	switch {
+	case strings.HasPrefix(rest, `"`):
+		value, _, ok = cutRegString(rest)
+		return name, value, ok
+	case strings.HasPrefix(strings.ToLower(rest), "dword:"):
+		n, err := strconv.ParseUint(rest[len("dword:"):], 16, 32)
+		if err != nil {
+			return "", "", false
+		}
+		return name, strconv.FormatUint(n, 10), true
+	}
+	return "", "", false
+}
+
+// cutRegString reads the quoted string s starts with, undoing its \\ and \"
+// escapes, and returns it with the rest of s.
+func cutRegString(s string) (value, rest string, ok bool) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case c == '"':
+			return b.String(), s[i+1:], true
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"encoding/xml"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+// securecrtFormat reads SecureCRT's sessions: its Sessions directory (or one
+// session's .ini file), or an XML export from Tools > Export Settings.
+var securecrtFormat = Format{
+	Name:        "securecrt",
+	Description: "SecureCRT sessions (the Sessions directory, a session .ini or an XML export)",
+	Detect: func(path, text string) bool {
+		if text == "" {
+			st, err := os.Stat(filepath.Join(path, "__FolderData__.ini"))
+			return err == nil && !st.IsDir()
+		}
+		return strings.Contains(text, `S:"Protocol Name"=`) || strings.Contains(text, "<VanDyke")
+	},
+	Read: readSecureCRT,
+}
+
+// readSecureCRT reads the sessions in a SecureCRT Sessions directory, where
+// subdirectories are folders, a single session file, or an XML export.
+func readSecureCRT(path string) ([]Session, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !st.IsDir() {
+		text, err := readText(path)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(text, "<VanDyke") {
+			return readSecureCRTXML(text)
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		return []Session{securecrtSession(name, nil, parseSecureCRTIni(text))}, nil
+	}
+
+	var sessions []Session
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		base := d.Name()
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(base), ".ini") ||
+			base == "__FolderData__.ini" || (base == "Default.ini" && filepath.Dir(p) == path) {
+			return nil
+		}
+		text, err := readText(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(path, filepath.Dir(p))
+		var folder []string
+		if rel != "." {
+			folder = strings.Split(filepath.ToSlash(rel), "/")
+		}
+		sessions = append(sessions, securecrtSession(strings.TrimSuffix(base, filepath.Ext(base)), folder, parseSecureCRTIni(text)))
+		return nil
+	})
+	return sessions, err
+}
+
+// parseSecureCRTIni returns the values in a session file, by name:
+// `S:"Hostname"=host` lines are strings and `D:"Port"=00000016` lines are
+// numbers in hex, returned in decimal.
+func parseSecureCRTIni(text string) map[string]string {
+	values := map[string]string{}
+	for _, line := range strings.Split(text, "\n") {
+		kind, rest, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || (kind != "S" && kind != "D") || !strings.HasPrefix(rest, `"`) {
+			continue
+		}
+		name, value, ok := strings.Cut(rest[1:], `"=`)
+		if !ok {
+			continue
+		}
+		if kind == "D" {
+			n, err := strconv.ParseUint(value, 16, 32)
+			if err != nil {
+				continue
+			}
+			value = strconv.FormatUint(n, 10)
+		}
+		values[name] = value
+	}
+	return values
+}
+
+// securecrtKey is a key in SecureCRT's XML export: a folder or a session.
+type securecrtKey struct {
+	Name    string         `xml:"name,attr"`
+	Keys    []securecrtKey `xml:"key"`
+	Strings []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"string"`
+	Dwords []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"dword"`
+}
+
+// readSecureCRTXML reads the sessions in SecureCRT's XML export: the keys
+// under "Sessions" with a protocol or hostname are sessions, the others
+// folders. Its dwords are in decimal.
+func readSecureCRTXML(text string) ([]Session, error) {
+	var root struct {
+		Keys []securecrtKey `xml:"key"`
+	}
+	dec := xml.NewDecoder(strings.NewReader(text))
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+	if err := dec.Decode(&root); err != nil {
+		return nil, err
+	}
+
+	var sessions []Session
+	var walk func(keys []securecrtKey, folder []string)
+	walk = func(keys []securecrtKey, folder []string) {
+		for _, k := range keys {
+			values := map[string]string{}
+			for _, s := range k.Strings {
+				values[s.Name] = s.Value
+			}
+			for _, d := range k.Dwords {
+				values[d.Name] = d.Value
+			}
+			if values["Protocol Name"] == "" && values["Hostname"] == "" {
+				walk(k.Keys, append(folder[:len(folder):len(folder)], k.Name))
+				continue
+			}
+			if len(folder) == 0 && k.Name == "Default" {
+				continue
+			}
+			sessions = append(sessions, securecrtSession(k.Name, folder, values))
+		}
+	}
+	for _, k := range root.Keys {
+		if k.Name == "Sessions" {
+			walk(k.Keys, nil)
+		}
+	}
+	return sessions, nil
+}
+
+// securecrtSession returns the session with SecureCRT's values. The port is
+// kept per protocol: "[SSH2] Port" for ssh2 and plain "Port" for telnet.
+func securecrtSession(name string, folder []string, values map[string]string) Session {
+	s := Session{Name: name, Folder: folder, Type: values["Protocol Name"],
+		HostName: strings.TrimSpace(values["Hostname"]), User: strings.TrimSpace(values["Username"])}
+	switch strings.ToUpper(s.Type) {
+	case "SSH2", "SSH1":
+		s.Protocol = config.ProtocolSSH
+		s.Port = values["["+strings.ToUpper(s.Type)+"] Port"]
+	case "TELNET":
+		s.Protocol = config.ProtocolTelnet
+		s.Port = values["Port"]
+	}
+	return s
+}
@@ -0,0 +1,180 @@
+package knownhosts
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// BackupSuffix is appended to a known_hosts file's name for the copy kept
+// before it's rewritten, like ssh-keygen -R.
+const BackupSuffix = ".old"
+
+// ReadFiles parses every file in order. Missing files are ignored.
+func ReadFiles(files []string) ([]Entry, error) {
+	var out []Entry
+	for _, f := range files {
+		entries, err := ReadFile(f)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return out, err
+		}
+		out = append(out, entries...)
+	}
+	return out, nil
+}
+
+// Remove deletes the keys for host and port from the files, like
+// ssh-keygen -R: lines with several host names go entirely, and
+// @cert-authority and @revoked lines are kept. Each changed file is backed
+// up first. It returns the number of lines removed.
+func Remove(files []string, host, port string) (int, error) {
+	removed := 0
+	for _, f := range files {
+		n, err := rewrite(f, func(n int, e Entry) bool {
+			return e.Marker == "" && e.MatchesHost(host, port)
+		})
+		removed += n
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// RemoveEntry deletes a single entry from its file. It fails if the line no
+// longer holds the entry's key (eg. the file was edited since it was read).
+func RemoveEntry(e Entry) error {
+	n, err := rewrite(e.File, func(n int, cur Entry) bool {
+		return n == e.Line && cur.KeyType == e.KeyType && bytes.Equal(cur.Key, e.Key)
+	})
+	if err == nil && n == 0 {
+		err = fmt.Errorf("%s line %d changed since it was read", e.File, e.Line)
+	}
+	return err
+}
+
+// rewrite writes the file back without the entry lines drop matches. Kept
+// lines are copied as they were, line endings included. The file is left
+// alone if nothing is dropped.
+func rewrite(name string, drop func(line int, e Entry) bool) (int, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var out bytes.Buffer
+	removed, n := 0, 0
+	for line := range bytes.Lines(data) {
+		n++
+		if e, ok := parseLine(string(line)); ok && drop(n, e) {
+			removed++
+			continue
+		}
+		out.Write(line)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(name+BackupSuffix, data, info.Mode().Perm()); err != nil {
+		return 0, fmt.Errorf("backing up %s: %w", name, err)
+	}
+	return removed, writeAtomic(name, out.Bytes(), info.Mode().Perm())
+}
+
+// Add appends a key for host and port to the file, creating it if needed.
+// With hash set, the host name is hashed like ssh's HashKnownHosts.
+func Add(name, host, port, keyType string, key []byte, hash bool) error {
+	hostName := HostName(host, port)
+	if hash {
+		var err error
+		if hostName, err = HashHostName(hostName); err != nil {
+			return err
+		}
+	}
+	line := hostName + " " + keyType + " " + base64.StdEncoding.EncodeToString(key) + "\n"
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	// keep the new line separate from a last line without a newline
+	if !endsWithNewline(name) {
+		line = "\n" + line
+	}
+	if _, err := f.WriteString(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// HashHostName hashes a known_hosts host name with a random salt.
+func HashHostName(name string) (string, error) {
+	salt := make([]byte, sha1.Size)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(name))
+	return hashPrefix + base64.StdEncoding.EncodeToString(salt) + "|" +
+		base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// endsWithNewline reports whether the file's last byte is a newline.
+func endsWithNewline(name string) bool {
+	f, err := os.Open(name)
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return true
+	}
+	b := make([]byte, 1)
+	if _, err := f.ReadAt(b, info.Size()-1); err != nil {
+		return true
+	}
+	return b[0] == '\n'
+}
+
+// writeAtomic replaces a file through a temporary file in the same directory.
+func writeAtomic(name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
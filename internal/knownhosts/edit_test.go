@@ -0,0 +1,162 @@
+package knownhosts
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// key returns a base64 key field for a known_hosts line.
+func key(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// writeKnownHosts writes lines to a known_hosts file in a temp dir, each
+// ending in eol.
+func writeKnownHosts(t *testing.T, eol string, lines ...string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(name, []byte(strings.Join(lines, eol)+eol), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestRemove(t *testing.T) {
+	hashedWeb, err := HashHostName("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		plain     = "web ssh-ed25519 " + key("web")
+		hashed    = hashedWeb + " ssh-rsa " + key("web-rsa")
+		port      = "[web]:2222 ssh-ed25519 " + key("web-2222")
+		list      = "db,10.0.0.5 ssh-ed25519 " + key("db")
+		wildcard  = "*.example.com ssh-ed25519 " + key("example")
+		ca        = "@cert-authority web ssh-ed25519 " + key("ca")
+		revoked   = "@revoked web ssh-ed25519 " + key("old")
+		comment   = "# web ssh-ed25519 " + key("commented")
+		other     = "mail ssh-ed25519 " + key("mail") + " mail key"
+		malformed = "web ssh-ed25519"
+	)
+	all := []string{plain, hashed, port, list, wildcard, ca, revoked, comment, other, malformed}
+
+	tests := []struct {
+		name, host, port string
+		removed          []string
+	}{
+		{"plain and hashed names", "web", "22", []string{plain, hashed}},
+		{"no port is port 22", "web", "", []string{plain, hashed}},
+		{"non-default port", "web", "2222", []string{port}},
+		{"one name of a list drops the line", "10.0.0.5", "22", []string{list}},
+		{"case-insensitive", "DB", "22", []string{list}},
+		{"wildcards match", "www.example.com", "22", []string{wildcard}},
+		{"no match", "nope", "22", nil},
+	}
+	for _, tt := range tests {
+		for _, eol := range []string{"\n", "\r\n"} {
+			t.Run(tt.name+strings.ReplaceAll(strings.ReplaceAll(eol, "\r", " CR"), "\n", " LF"), func(t *testing.T) {
+				name := writeKnownHosts(t, eol, all...)
+				before, _ := os.ReadFile(name)
+				n, err := Remove([]string{name, filepath.Join(t.TempDir(), "missing")}, tt.host, tt.port)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != len(tt.removed) {
+					t.Errorf("removed %d lines, want %d", n, len(tt.removed))
+				}
+
+				var kept []string
+				for _, l := range all {
+					if !slices.Contains(tt.removed, l) {
+						kept = append(kept, l)
+					}
+				}
+				got, _ := os.ReadFile(name)
+				if want := strings.Join(kept, eol) + eol; string(got) != want {
+					t.Errorf("file =\n%q\nwant\n%q", got, want)
+				}
+
+				backup, err := os.ReadFile(name + BackupSuffix)
+				switch {
+				case n == 0 && !errors.Is(err, fs.ErrNotExist):
+					t.Errorf("backup written with nothing removed: %v", err)
+				case n > 0 && string(backup) != string(before):
+					t.Errorf("backup =\n%q\nwant the original file", backup)
+				}
+			})
+		}
+	}
+}
+
+func TestRemoveKeepsLastLineWithoutNewline(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "known_hosts")
+	data := "web ssh-ed25519 " + key("web") + "\r\nmail ssh-ed25519 " + key("mail")
+	if err := os.WriteFile(name, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := Remove([]string{name}, "web", "22"); n != 1 || err != nil {
+		t.Fatalf("Remove = %d, %v", n, err)
+	}
+	if got, _ := os.ReadFile(name); string(got) != "mail ssh-ed25519 "+key("mail") {
+		t.Errorf("file = %q", got)
+	}
+}
+
+func TestRemoveEntry(t *testing.T) {
+	lines := []string{
+		"web ssh-ed25519 " + key("web"),
+		"web ssh-rsa " + key("web-rsa"),
+		"mail ssh-ed25519 " + key("mail"),
+	}
+	name := writeKnownHosts(t, "\r\n", lines...)
+	entries, err := ReadFile(name)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("ReadFile = %d entries, %v", len(entries), err)
+	}
+
+	if err := RemoveEntry(entries[1]); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(name); string(got) != lines[0]+"\r\n"+lines[2]+"\r\n" {
+		t.Errorf("file = %q", got)
+	}
+
+	// line 3 now holds nothing (the file changed since it was read)
+	err = RemoveEntry(entries[2])
+	if err == nil || !strings.Contains(err.Error(), "changed since it was read") {
+		t.Errorf("stale entry: %v", err)
+	}
+	// line 1 still holds a key for web, but not the same one
+	stale := entries[0]
+	stale.Key = []byte("other")
+	if err := RemoveEntry(stale); err == nil {
+		t.Error("removed a line whose key changed")
+	}
+	if got, _ := os.ReadFile(name); string(got) != lines[0]+"\r\n"+lines[2]+"\r\n" {
+		t.Errorf("failed removals changed the file: %q", got)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "ssh", "known_hosts")
+	if err := Add(name, "web", "22", "ssh-ed25519", []byte("web"), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(name, "web", "2222", "ssh-ed25519", []byte("web-2222"), true); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReadFile(name)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("ReadFile = %+v, %v", entries, err)
+	}
+	if entries[0].Hosts() != "web" || entries[0].Hashed() || !entries[1].Hashed() ||
+		!entries[1].MatchesHost("web", "2222") || entries[1].MatchesHost("web", "22") {
+		t.Errorf("added entries = %+v", entries)
+	}
+}
@@ -0,0 +1,267 @@
+// Package knownhosts reads OpenSSH known_hosts files and checks host keys
+// against them, including hashed host names.
+package knownhosts
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+const (
+	MarkerCertAuthority = "@cert-authority" // line holds a CA key for matching hosts
+	MarkerRevoked       = "@revoked"        // key must never be accepted
+
+	hashPrefix = "|1|" // hashed host name: |1|base64(salt)|base64(hmac-sha1(salt, host))
+)
+
+// Status is the result of checking a host key.
+type Status int
+
+const (
+	StatusNew     Status = iota // no key on file for the host
+	StatusMatches               // key on file matches
+	StatusChanged               // a different key of the same type is on file
+	StatusRevoked               // key is marked @revoked
+)
+
+// String returns a short description of the status.
+func (s Status) String() string {
+	switch s {
+	case StatusMatches:
+		return "key matches"
+	case StatusChanged:
+		return "KEY CHANGED"
+	case StatusRevoked:
+		return "KEY REVOKED"
+	default:
+		return "new host"
+	}
+}
+
+// Entry is a single key line from a known_hosts file.
+type Entry struct {
+	File     string   // file the entry was read from
+	Line     int      // 1-based line number
+	Marker   string   // "", MarkerCertAuthority or MarkerRevoked
+	Patterns []string // host patterns (may be hashed, wildcarded or negated)
+	KeyType  string   // key type (eg. "ssh-ed25519")
+	Key      []byte   // decoded public key blob
+	Comment  string   // trailing comment (if any)
+}
+
+// Hashed reports whether the entry's host names are hashed.
+func (e Entry) Hashed() bool {
+	return len(e.Patterns) == 1 && strings.HasPrefix(e.Patterns[0], hashPrefix)
+}
+
+// Hosts returns the entry's host patterns for display ("(hashed)" if hashed).
+func (e Entry) Hosts() string {
+	if e.Hashed() {
+		return "(hashed)"
+	}
+	return strings.Join(e.Patterns, ",")
+}
+
+// Fingerprint returns the entry key's SHA256 fingerprint.
+func (e Entry) Fingerprint() string {
+	return Fingerprint(e.Key)
+}
+
+// Result is the outcome of Check.
+type Result struct {
+	Status   Status  // overall result
+	Matching []Entry // entries for the host (any key type)
+}
+
+// DefaultFiles returns the user's default known_hosts files, under the same
+// home directory as the ssh config (see config.GetConfigPath).
+func DefaultFiles() ([]string, error) {
+	file, err := config.GetConfigPath(".ssh", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	return []string{file, file + "2"}, nil
+}
+
+// ReadFile parses a known_hosts file. Malformed lines are skipped.
+func ReadFile(name string) ([]Entry, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Entry
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		if e, ok := parseLine(sc.Text()); ok {
+			e.File = name
+			e.Line = n
+			out = append(out, e)
+		}
+	}
+	return out, sc.Err()
+}
+
+// parseLine parses one known_hosts line.
+func parseLine(line string) (Entry, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return Entry{}, false
+	}
+
+	var e Entry
+	if strings.HasPrefix(fields[0], "@") {
+		e.Marker = fields[0]
+		fields = fields[1:]
+	}
+	if len(fields) < 3 {
+		return Entry{}, false
+	}
+	key, err := base64.StdEncoding.DecodeString(fields[2])
+	if err != nil {
+		return Entry{}, false
+	}
+	e.Patterns = strings.Split(fields[0], ",")
+	e.KeyType = fields[1]
+	e.Key = key
+	e.Comment = strings.Join(fields[3:], " ")
+	return e, true
+}
+
+// HostName returns the name known_hosts uses for host and port:
+// "host" for port 22 (or no port), "[host]:port" otherwise.
+func HostName(host, port string) string {
+	if port == "" || port == "22" {
+		return host
+	}
+	return "[" + host + "]:" + port
+}
+
+// MatchesHost reports whether the entry applies to host and port.
+//
+// Negated patterns (!pattern) exclude the host even if another pattern matches.
+func (e Entry) MatchesHost(host, port string) bool {
+	name := HostName(host, port)
+	matched := false
+	for _, p := range e.Patterns {
+		negate := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		if !matchPattern(p, name) {
+			continue
+		}
+		if negate {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// matchPattern matches a single (possibly hashed or wildcarded) host pattern.
+func matchPattern(pattern, name string) bool {
+	if rest, ok := strings.CutPrefix(pattern, hashPrefix); ok {
+		salt64, hash64, ok := strings.Cut(rest, "|")
+		if !ok {
+			return false
+		}
+		salt, err1 := base64.StdEncoding.DecodeString(salt64)
+		want, err2 := base64.StdEncoding.DecodeString(hash64)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(name))
+		return hmac.Equal(mac.Sum(nil), want)
+	}
+
+	// known_hosts patterns use * and ?, which path.Match also treats specially;
+	// brackets are literal (eg. "[host]:2222"), so escape them first
+	escaped := strings.NewReplacer("[", `\[`, "]", `\]`).Replace(strings.ToLower(pattern))
+	ok, err := path.Match(escaped, strings.ToLower(name))
+	return err == nil && ok
+}
+
+// Lookup returns the key entries (not CA or revoked lines) that apply to host
+// and port in the given files. Missing files are ignored.
+func Lookup(files []string, host, port string) ([]Entry, error) {
+	var out []Entry
+	for _, f := range files {
+		entries, err := ReadFile(f)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return out, err
+		}
+		for _, e := range entries {
+			if e.Marker == "" && e.MatchesHost(host, port) {
+				out = append(out, e)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Check looks up host and port in the given files and compares key.
+//
+// keyType is the key blob's type (eg. "ssh-rsa", also for rsa-sha2-* signatures).
+// Missing files are ignored.
+func Check(files []string, host, port, keyType string, key []byte) (Result, error) {
+	var res Result
+	sameType := false
+	for _, f := range files {
+		entries, err := ReadFile(f)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return res, err
+		}
+		for _, e := range entries {
+			// revoked keys are rejected for every host, like ssh does
+			if e.Marker == MarkerRevoked {
+				if bytes.Equal(e.Key, key) {
+					res.Status = StatusRevoked
+					res.Matching = append(res.Matching, e)
+					return res, nil
+				}
+				continue
+			}
+			if e.Marker == MarkerCertAuthority || !e.MatchesHost(host, port) {
+				continue
+			}
+
+			res.Matching = append(res.Matching, e)
+			if e.KeyType != keyType {
+				continue
+			}
+			if bytes.Equal(e.Key, key) {
+				res.Status = StatusMatches
+			}
+			sameType = true
+		}
+	}
+
+	if res.Status != StatusMatches && sameType {
+		res.Status = StatusChanged
+	}
+	return res, nil
+}
+
+// Fingerprint returns the OpenSSH-style SHA256 fingerprint of a public key blob.
+func Fingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
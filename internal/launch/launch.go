@@ -0,0 +1,240 @@
+// Package launch opens sessions alongside the menu instead of in place: in a
+// new tmux window or pane, a GNU screen window, or a terminal tab started
+// from a command template (eg. Windows Terminal, kitty, wezterm).
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+// Environment variables that override the launch.launcher and
+// launch.terminal_cmd settings.
+const (
+	// EnvLauncher picks the backend: inplace (default), tmux, tmux-split, screen or terminal.
+	EnvLauncher = "BTMS_LAUNCHER"
+	// EnvTerminalCommand is the command template for the terminal backend.
+	EnvTerminalCommand = "BTMS_TERMINAL_CMD"
+)
+
+// Backend names accepted by Parse.
+const (
+	BackendInPlace   = "inplace"
+	BackendTmux      = "tmux"
+	BackendTmuxSplit = "tmux-split"
+	BackendScreen    = "screen"
+	BackendTerminal  = "terminal"
+)
+
+// Backends are the backend names Parse accepts, in-place first.
+var Backends = []string{BackendInPlace, BackendTmux, BackendTmuxSplit, BackendScreen, BackendTerminal}
+
+// ErrNoBackend is returned when no launcher is configured and none can be
+// detected from the environment.
+var ErrNoBackend = errors.New("no tab launcher: run inside tmux or screen, or set launch.launcher")
+
+// A Launcher starts a session command without taking over the menu's terminal.
+//
+// Launch returns once the session has been handed off; it doesn't wait for
+// the session to end.
+type Launcher interface {
+	Name() string                             // short description for status messages (eg. "tmux window")
+	Launch(title string, argv []string) error // start argv in a new tab/window/pane titled title
+}
+
+// Parse returns the launcher for a backend name, or nil for in-place.
+//
+// template is only used by the terminal backend; see Terminal.
+func Parse(name, template string) (Launcher, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", BackendInPlace:
+		return nil, nil
+	case BackendTmux:
+		return Tmux{}, nil
+	case BackendTmuxSplit:
+		return Tmux{Split: true}, nil
+	case BackendScreen:
+		return Screen{}, nil
+	case BackendTerminal:
+		t, err := NewTerminal(template)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unknown launcher %q (want %s or %s)", name,
+			strings.Join(Backends[:len(Backends)-1], ", "), Backends[len(Backends)-1])
+	}
+}
+
+// Detect returns a launcher for the multiplexer the app is running in (tmux,
+// then screen), or nil if there isn't one.
+func Detect() Launcher {
+	switch {
+	case os.Getenv("TMUX") != "":
+		return Tmux{}
+	case os.Getenv("STY") != "":
+		return Screen{}
+	}
+	return nil
+}
+
+// Tmux opens sessions in the current tmux session, as a new window or as a
+// split of the current window.
+type Tmux struct {
+	Split bool // split the current window instead of opening a new one
+}
+
+// Name implements Launcher.
+func (t Tmux) Name() string {
+	if t.Split {
+		return "tmux pane"
+	}
+	return "tmux window"
+}
+
+// Launch implements Launcher.
+func (t Tmux) Launch(title string, argv []string) error {
+	if os.Getenv("TMUX") == "" {
+		return errors.New("not running inside tmux")
+	}
+	if err := run("tmux", t.args(title, argv)...); err != nil {
+		return err
+	}
+	if t.Split {
+		// keep panes usable when several sessions are split off
+		_ = run("tmux", "select-layout", "tiled")
+	}
+	return nil
+}
+
+// args returns the tmux arguments that start argv.
+func (t Tmux) args(title string, argv []string) []string {
+	args := []string{"new-window", "-d", "-n", title, "--"}
+	if t.Split {
+		args = []string{"split-window", "-d", "--"}
+	}
+	return append(args, argv...)
+}
+
+// Screen opens sessions as new windows in the current GNU screen session.
+type Screen struct{}
+
+// Name implements Launcher.
+func (Screen) Name() string { return "screen window" }
+
+// Launch implements Launcher.
+func (Screen) Launch(title string, argv []string) error {
+	if os.Getenv("STY") == "" {
+		return errors.New("not running inside screen")
+	}
+	return run("screen", Screen{}.args(title, argv)...)
+}
+
+// args returns the screen arguments that start argv.
+func (Screen) args(title string, argv []string) []string {
+	return append([]string{"-X", "screen", "-t", title}, argv...)
+}
+
+// Terminal opens sessions by running a command template, for terminals that
+// can open a tab or window from the command line.
+//
+// The template is split into arguments like a shell would (quotes group
+// words) and these placeholders are replaced:
+//
+//   - {title}: the session title
+//   - {cmd}: the session command as separate arguments (must be a whole argument)
+//   - {cmdline}: the session command as one shell-quoted string
+//
+// If the template has neither {cmd} nor {cmdline}, the command is appended.
+// Examples:
+//
+//	wt.exe -w 0 new-tab --title {title} {cmd}
+//	kitty @ launch --type=tab --tab-title {title} {cmd}
+//	wezterm cli spawn -- {cmd}
+type Terminal struct {
+	template []string
+}
+
+// NewTerminal parses a terminal command template.
+func NewTerminal(template string) (Terminal, error) {
+	args, err := config.SplitArgs(template)
+	if err != nil {
+		return Terminal{}, fmt.Errorf("terminal command: %w", err)
+	}
+	if len(args) == 0 {
+		return Terminal{}, fmt.Errorf("launch.terminal_cmd is required for the %s launcher", BackendTerminal)
+	}
+	return Terminal{template: args}, nil
+}
+
+// Name implements Launcher.
+func (t Terminal) Name() string { return "terminal tab" }
+
+// Launch implements Launcher.
+func (t Terminal) Launch(title string, argv []string) error {
+	args := t.Expand(title, argv)
+	c := exec.Command(args[0], args[1:]...)
+	if err := c.Start(); err != nil {
+		return err
+	}
+	// some terminals stay in the foreground until the tab closes; reap them in the background
+	go func() { _ = c.Wait() }()
+	return nil
+}
+
+// Expand returns the template's arguments with the placeholders replaced.
+func (t Terminal) Expand(title string, argv []string) []string {
+	// one pass, so a title can't inject the command line (or the reverse)
+	r := strings.NewReplacer("{title}", title, "{cmdline}", quoteArgs(argv))
+	var out []string
+	hasCmd := false
+	for _, a := range t.template {
+		if a == "{cmd}" {
+			out = append(out, argv...)
+			hasCmd = true
+			continue
+		}
+		if strings.Contains(a, "{cmdline}") {
+			hasCmd = true
+		}
+		out = append(out, r.Replace(a))
+	}
+	if !hasCmd {
+		out = append(out, argv...)
+	}
+	return out
+}
+
+// run runs a short-lived helper command and includes its output in any error.
+func run(name string, args ...string) error {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("%s not found: %w", name, err)
+	}
+	if out, err := exec.Command(path, args...).CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// quoteArgs joins argv into one POSIX shell-quoted command line.
+func quoteArgs(argv []string) string {
+	out := make([]string, 0, len(argv))
+	for _, a := range argv {
+		if a != "" && !strings.ContainsAny(a, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+			out = append(out, a)
+			continue
+		}
+		out = append(out, "'"+strings.ReplaceAll(a, "'", `'\''`)+"'")
+	}
+	return strings.Join(out, " ")
+}
@@ -0,0 +1,135 @@
+package launch
+
+import (
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// session is a command line with the characters quoting has to get right.
+var session = []string{"ssh", "-o", "ProxyCommand=nc -X 5 %h %p", "it's", `say "hi"`, "$HOME", "`id`", ""}
+
+func TestTerminalExpand(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		title    string
+		want     []string
+	}{
+		{"cmd", "wt.exe -w 0 new-tab --title {title} {cmd}", "web 1",
+			append([]string{"wt.exe", "-w", "0", "new-tab", "--title", "web 1"}, session...)},
+		{"appended", "wezterm cli spawn --", "web",
+			append([]string{"wezterm", "cli", "spawn", "--"}, session...)},
+		{"cmdline", "xterm -T {title} -e 'sh -c {cmdline}'", "web",
+			[]string{"xterm", "-T", "web", "-e", `sh -c ssh -o 'ProxyCommand=nc -X 5 %h %p' 'it'\''s' 'say "hi"' '$HOME' '` + "`id`" + `' ''`}},
+		{"title in an argument", "kitty @ launch --tab-title=btms:{title} {cmd}", "db",
+			append([]string{"kitty", "@", "launch", "--tab-title=btms:db"}, session...)},
+		{"placeholder in the title", "term --title {title} -e {cmdline}", "{cmdline}",
+			[]string{"term", "--title", "{cmdline}", "-e", quoteArgs(session)}},
+		{"cmd inside an argument", "term --run={cmd}", "web",
+			append([]string{"term", "--run={cmd}"}, session...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term, err := NewTerminal(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := term.Expand(tt.title, session); !slices.Equal(got, tt.want) {
+				t.Errorf("Expand =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTerminalErrors(t *testing.T) {
+	for _, template := range []string{"", "  ", "wt.exe 'new-tab"} {
+		if _, err := NewTerminal(template); err == nil {
+			t.Errorf("NewTerminal(%q) succeeded", template)
+		}
+	}
+}
+
+func TestQuoteArgs(t *testing.T) {
+	tests := []struct {
+		argv []string
+		want string
+	}{
+		{[]string{"ssh", "web1"}, "ssh web1"},
+		{[]string{"ssh", "-p", "2222", "user@web1"}, "ssh -p 2222 user@web1"},
+		{[]string{"echo", "two words"}, "echo 'two words'"},
+		{[]string{"echo", "it's"}, `echo 'it'\''s'`},
+		{[]string{"echo", `"quoted"`}, `echo '"quoted"'`},
+		{[]string{"echo", "$HOME", "${PATH}"}, `echo '$HOME' '${PATH}'`},
+		{[]string{"echo", "a;b", "a|b", "a&b", "~"}, `echo 'a;b' 'a|b' 'a&b' '~'`},
+		{[]string{"echo", ""}, "echo ''"},
+	}
+	for _, tt := range tests {
+		if got := quoteArgs(tt.argv); got != tt.want {
+			t.Errorf("quoteArgs(%q) = %s, want %s", tt.argv, got, tt.want)
+		}
+	}
+}
+
+// TestQuoteArgsShell checks that a shell splits the quoted line back into
+// the original arguments.
+func TestQuoteArgsShell(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	argv := append([]string{"printf", `%s\0`}, session[1:]...)
+	argv = append(argv, "new\nline", "tab\there", `back\slash`, "!", "*", "a b  c")
+	out, err := exec.Command(sh, "-c", quoteArgs(argv)).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if want := argv[2:]; !slices.Equal(got, want) {
+		t.Errorf("sh split %s into\n%q\nwant\n%q", quoteArgs(argv), got, want)
+	}
+}
+
+func TestMultiplexerArgs(t *testing.T) {
+	argv := []string{"ssh", "-t", "web 1"}
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"tmux window", Tmux{}.args("web 1", argv), []string{"new-window", "-d", "-n", "web 1", "--", "ssh", "-t", "web 1"}},
+		{"tmux split", Tmux{Split: true}.args("web 1", argv), []string{"split-window", "-d", "--", "ssh", "-t", "web 1"}},
+		{"screen", Screen{}.args("web 1", argv), []string{"-X", "screen", "-t", "web 1", "ssh", "-t", "web 1"}},
+	}
+	for _, tt := range tests {
+		if !slices.Equal(tt.got, tt.want) {
+			t.Errorf("%s: %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		want Launcher
+	}{
+		{"", nil},
+		{"inplace", nil},
+		{" TMUX ", Tmux{}},
+		{"tmux-split", Tmux{Split: true}},
+		{"screen", Screen{}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.name, "")
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %#v, %v; want %#v", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := Parse("terminal", ""); err == nil {
+		t.Error("terminal without a template succeeded")
+	}
+	if _, err := Parse("xterm", ""); err == nil || !strings.Contains(err.Error(), "unknown launcher") {
+		t.Errorf("Parse(xterm) = %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+// Package monitor checks in the background whether hosts are reachable.
+//
+// A sweep probes every target with bounded concurrency, spreading the probes
+// out with a little random jitter, and keeps the latency and last time each
+// host was seen up.
+package monitor
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	"bubbletea-ssh-manager/internal/connect"
+)
+
+// State is the reachability of a host.
+type State int
+
+const (
+	StateUnknown  State = iota // not checked yet
+	StateUp                    // answered quickly
+	StateDegraded              // answered, but slowly or without an SSH banner
+	StateDown                  // didn't answer
+)
+
+// String returns a short description of the state.
+func (s State) String() string {
+	switch s {
+	case StateUp:
+		return "up"
+	case StateDegraded:
+		return "degraded"
+	case StateDown:
+		return "down"
+	default:
+		return "unknown"
+	}
+}
+
+// Target is a host to check.
+type Target struct {
+	Key      string // identifies the host in results (eg. "ssh:web01")
+	HostPort string // address to dial
+	Banner   bool   // also expect an SSH identification banner
+}
+
+// Result is the outcome of the latest check of a host.
+type Result struct {
+	State   State
+	RTT     time.Duration // time to connect (or read the banner); 0 if down
+	LastUp  time.Time     // last time the host answered (zero if never)
+	Checked time.Time     // when the host was last checked
+	Err     error         // why the last check failed (nil if up)
+}
+
+// Config controls how often and how hard hosts are probed.
+type Config struct {
+	Interval    time.Duration // time between sweeps
+	Timeout     time.Duration // per-host probe timeout
+	Concurrency int           // probes running at once
+	Jitter      time.Duration // max random delay before each probe
+	SlowRTT     time.Duration // RTT at or above this is degraded
+}
+
+// DefaultConfig returns the default monitor settings.
+func DefaultConfig() Config {
+	return Config{
+		Interval:    30 * time.Second,
+		Timeout:     3 * time.Second,
+		Concurrency: 8,
+		Jitter:      500 * time.Millisecond,
+		SlowRTT:     300 * time.Millisecond,
+	}
+}
+
+// Check probes a single target and returns its round-trip time.
+//
+// Without Banner it's a plain TCP connect; with Banner it also waits for the
+// server's SSH identification, which catches hosts where something else is
+// listening (errNoBanner is returned when the port answers but isn't SSH).
+func Check(ctx context.Context, t Target, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	if !t.Banner {
+		err := connect.PreflightDial(ctx, t.HostPort)
+		return time.Since(start), err
+	}
+	banner, err := connect.ReadBanner(ctx, t.HostPort)
+	rtt := time.Since(start)
+	if err != nil && banner == "" && ctx.Err() == nil && !isDialError(err) {
+		return rtt, errNoBanner
+	}
+	return rtt, err
+}
+
+// errNoBanner means the port accepted the connection but sent no SSH identification.
+var errNoBanner = errors.New("no SSH banner")
+
+// isDialError reports whether err came from connecting rather than reading.
+func isDialError(err error) bool {
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op == "dial"
+}
+
+// Sweep checks every target and returns their new results.
+//
+// prev holds the previous results (may be nil) so LastUp survives failed
+// checks. If ctx is canceled partway, targets that weren't checked keep their
+// previous result.
+func Sweep(ctx context.Context, targets []Target, cfg Config, prev map[string]Result) map[string]Result {
+	out := make(map[string]Result, len(targets))
+	for _, t := range targets {
+		if r, ok := prev[t.Key]; ok {
+			out[t.Key] = r
+		}
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, max(cfg.Concurrency, 1))
+	)
+	for _, t := range targets {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return out
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if !sleep(ctx, jitter(cfg.Jitter)) {
+				return
+			}
+			rtt, err := Check(ctx, t, cfg.Timeout)
+			if ctx.Err() != nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			r := out[t.Key]
+			r.Checked = time.Now()
+			r.Err = err
+			switch {
+			case err == nil:
+				r.State, r.RTT, r.LastUp = StateUp, rtt, r.Checked
+				if cfg.SlowRTT > 0 && rtt >= cfg.SlowRTT {
+					r.State = StateDegraded
+				}
+			case errors.Is(err, errNoBanner):
+				r.State, r.RTT, r.LastUp = StateDegraded, rtt, r.Checked
+			default:
+				r.State, r.RTT = StateDown, 0
+			}
+			out[t.Key] = r
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// jitter returns a random delay in [0, d).
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
+
+// sleep waits for d, returning false if ctx is canceled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
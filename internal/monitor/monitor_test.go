@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// listen accepts connections on a local port and writes greeting to each.
+func listen(t *testing.T, greeting string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = io.WriteString(c, greeting)
+			_ = c.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// closedPort returns a local address nothing listens on.
+func closedPort(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	return addr
+}
+
+func TestSweep(t *testing.T) {
+	ssh := listen(t, "SSH-2.0-OpenSSH_9.6\r\n")
+	http := listen(t, "HTTP/1.1 400 Bad Request\r\n\r\n")
+	down := closedPort(t)
+	targets := []Target{
+		{Key: "ssh", HostPort: ssh, Banner: true},
+		{Key: "tcp", HostPort: ssh},
+		{Key: "http", HostPort: http, Banner: true},
+		{Key: "down", HostPort: down},
+		{Key: "new-down", HostPort: down},
+	}
+	lastUp := time.Now().Add(-time.Hour)
+	prev := map[string]Result{
+		"down":    {State: StateUp, LastUp: lastUp},
+		"removed": {State: StateUp},
+	}
+	cfg := Config{Timeout: 2 * time.Second, Concurrency: 2}
+
+	got := Sweep(context.Background(), targets, cfg, prev)
+	want := map[string]State{
+		"ssh":      StateUp,
+		"tcp":      StateUp,
+		"http":     StateDegraded,
+		"down":     StateDown,
+		"new-down": StateDown,
+	}
+	if len(got) != len(want) {
+		t.Errorf("Sweep returned %d results, want %d (removed targets dropped)", len(got), len(want))
+	}
+	for key, state := range want {
+		r := got[key]
+		if r.State != state {
+			t.Errorf("%s: %s (%v), want %s", key, r.State, r.Err, state)
+		}
+		if r.Checked.IsZero() {
+			t.Errorf("%s: Checked not set", key)
+		}
+		if state == StateDown && (r.RTT != 0 || r.Err == nil) {
+			t.Errorf("%s: down with RTT %v, err %v", key, r.RTT, r.Err)
+		}
+		if state != StateDown && !r.LastUp.Equal(r.Checked) {
+			t.Errorf("%s: LastUp %v, want Checked %v", key, r.LastUp, r.Checked)
+		}
+	}
+	if !got["down"].LastUp.Equal(lastUp) {
+		t.Errorf("down: LastUp %v, want the previous %v", got["down"].LastUp, lastUp)
+	}
+	if !got["new-down"].LastUp.IsZero() {
+		t.Errorf("new-down: LastUp %v, want zero", got["new-down"].LastUp)
+	}
+
+	cfg.SlowRTT = time.Nanosecond
+	got = Sweep(context.Background(), targets[:1], cfg, got)
+	if r := got["ssh"]; r.State != StateDegraded || r.Err != nil {
+		t.Errorf("slow ssh: %s (%v), want degraded", r.State, r.Err)
+	}
+}
+
+func TestSweepCanceled(t *testing.T) {
+	prev := map[string]Result{"ssh": {State: StateUp}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	got := Sweep(ctx, []Target{{Key: "ssh", HostPort: closedPort(t)}}, Config{Timeout: time.Second}, prev)
+	if got["ssh"].State != StateUp {
+		t.Errorf("canceled sweep: %s, want the previous up", got["ssh"].State)
+	}
+}
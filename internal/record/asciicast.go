@@ -0,0 +1,237 @@
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// asciicast v2 event types
+const (
+	EventOutput = "o" // data written to the terminal
+	EventInput  = "i" // data typed by the user (not recorded by this app)
+	EventMarker = "m" // marker
+)
+
+// maxCastLine is the largest single line accepted when reading a cast file.
+const maxCastLine = 4 << 20
+
+// Header is the first line of an asciicast v2 file.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event is a single timed asciicast v2 event, encoded as [time, type, data].
+type Event struct {
+	Time float64 // seconds since the start of the recording
+	Type string  // event type (eg. EventOutput)
+	Data string  // event payload
+}
+
+// MarshalJSON encodes the event as a [time, type, data] array.
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.Time, e.Type, e.Data})
+}
+
+// UnmarshalJSON decodes a [time, type, data] array.
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 3 {
+		return fmt.Errorf("asciicast event: want 3 fields, got %d", len(raw))
+	}
+	if err := json.Unmarshal(raw[0], &e.Time); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw[1], &e.Type); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw[2], &e.Data)
+}
+
+// Recorder writes terminal output to an asciicast v2 file.
+//
+// It implements io.Writer so it can be teed next to the terminal with an
+// io.MultiWriter. The file is created on the first write, so a session that
+// never starts (eg. a failed preflight) leaves nothing behind.
+//
+// Write never fails: a recording problem must not break the live session.
+// The first error is kept and returned by Close.
+type Recorder struct {
+	path   string
+	header Header
+
+	mu      sync.Mutex
+	f       *os.File
+	w       *bufio.Writer
+	start   time.Time
+	pending []byte // incomplete UTF-8 sequence carried to the next write
+	err     error
+}
+
+// NewRecorder returns a Recorder that writes to path.
+func NewRecorder(path string, width, height int, title string) *Recorder {
+	return &Recorder{
+		path: path,
+		header: Header{
+			Version: 2,
+			Width:   width,
+			Height:  height,
+			Title:   title,
+			Env:     map[string]string{"TERM": os.Getenv("TERM")},
+		},
+	}
+}
+
+// Path returns the file the recording is written to.
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Started reports whether anything was recorded.
+func (r *Recorder) Started() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f != nil
+}
+
+// Write implements io.Writer.
+func (r *Recorder) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return len(p), nil
+	}
+	if r.f == nil {
+		if r.err = r.open(); r.err != nil {
+			return len(p), nil
+		}
+	}
+
+	// hold back a trailing partial UTF-8 rune so it isn't mangled into U+FFFD
+	data := append(r.pending, p...)
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	r.pending = append([]byte(nil), data[cut:]...)
+	if cut > 0 {
+		r.err = r.writeEvent(EventOutput, string(data[:cut]))
+	}
+	return len(p), nil
+}
+
+// Close flushes and closes the recording file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return r.err
+	}
+	if len(r.pending) > 0 && r.err == nil {
+		r.err = r.writeEvent(EventOutput, string(r.pending))
+		r.pending = nil
+	}
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.f.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	r.f = nil
+	return r.err
+}
+
+// open creates the file and writes the header. Callers must hold r.mu.
+func (r *Recorder) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	r.f = f
+	r.w = bufio.NewWriter(f)
+	r.start = time.Now()
+	r.header.Timestamp = r.start.Unix()
+
+	b, err := json.Marshal(r.header)
+	if err != nil {
+		return err
+	}
+	_, err = r.w.Write(append(b, '\n'))
+	return err
+}
+
+// writeEvent appends a single event line. Callers must hold r.mu.
+func (r *Recorder) writeEvent(typ, data string) error {
+	ev := Event{Time: time.Since(r.start).Seconds(), Type: typ, Data: data}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = r.w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadCast reads an asciicast v2 file.
+func ReadCast(path string) (Header, []Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), maxCastLine)
+
+	var h Header
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return Header{}, nil, err
+		}
+		return Header{}, nil, errors.New("empty recording")
+	}
+	if err := json.Unmarshal(sc.Bytes(), &h); err != nil {
+		return Header{}, nil, fmt.Errorf("asciicast header: %w", err)
+	}
+	if h.Version != 2 {
+		return Header{}, nil, fmt.Errorf("unsupported asciicast version %d", h.Version)
+	}
+
+	var events []Event
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			// a session killed mid-write can leave a torn last line; keep what we have
+			break
+		}
+		events = append(events, ev)
+	}
+	return h, events, sc.Err()
+}
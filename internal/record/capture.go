@@ -0,0 +1,63 @@
+package record
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"bubbletea-ssh-manager/internal/termutil"
+)
+
+// Capture holds the optional writers teed from a session's output.
+type Capture struct {
+	rec *Recorder   // asciicast recorder (nil if not recording)
+	log *SessionLog // plain-text log (nil if not logging)
+}
+
+// NewCapture sets up recording and/or logging for a session to the host
+// alias. title is the session's window title, kept in both files.
+func NewCapture(alias, title string, recording, logging bool) (Capture, error) {
+	var c Capture
+	if recording {
+		path, err := NewPath(alias, time.Now())
+		if err != nil {
+			return c, err
+		}
+		w, h := termutil.SizeOrDefault(os.Stdout)
+		c.rec = NewRecorder(path, w, h, title)
+	}
+	if logging {
+		path, err := LogPath(alias)
+		if err != nil {
+			return c, err
+		}
+		c.log = NewSessionLog(path, nil, DefaultLogRotation)
+		c.log.Begin(title)
+	}
+	return c, nil
+}
+
+// Writers returns the active capture writers, for connect.BuildCommand.
+func (c Capture) Writers() []io.Writer {
+	var out []io.Writer
+	if c.rec != nil {
+		out = append(out, c.rec)
+	}
+	if c.log != nil {
+		out = append(out, c.log)
+	}
+	return out
+}
+
+// Close closes the capture writers and returns the paths of any files written.
+func (c Capture) Close() (recording, logPath string) {
+	if c.rec != nil && c.rec.Started() {
+		_ = c.rec.Close()
+		recording = c.rec.Path()
+	}
+	if c.log != nil && c.log.Started() {
+		_ = c.log.Close()
+		logPath = c.log.Path()
+	}
+	return recording, logPath
+}
@@ -0,0 +1,310 @@
+package record
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/termutil"
+
+	"github.com/muesli/cancelreader"
+)
+
+const (
+	seekStep      = 5 * time.Second // left/right seek distance
+	minSpeed      = 0.25            // slowest playback speed
+	maxSpeed      = 16.0            // fastest playback speed
+	idleTimeLimit = 2.0             // seconds; longer pauses in the recording are shortened
+	statusEvery   = time.Second     // how often the title bar status refreshes while playing
+)
+
+// terminal control sequences used by the player
+const (
+	seqReset      = "\x1bc"            // full terminal reset (clears before replaying from the start)
+	seqClear      = "\x1b[H\x1b[2J"    // home + clear screen
+	seqTitleStart = "\x1b]2;"          // OSC 2: set window title
+	seqTitleEnd   = "\x07"             // BEL terminates OSC
+	seqShowCursor = "\x1b[?25h\x1b[0m" // make sure the cursor is visible and attributes reset on exit
+)
+
+// player key actions
+type playerKey int
+
+const (
+	keyNone playerKey = iota
+	keyPause
+	keyQuit
+	keyForward
+	keyBack
+	keyFaster
+	keySlower
+)
+
+// Player replays an asciicast v2 recording in the terminal.
+//
+// It implements the same Run/SetStdin/SetStdout/SetStderr method set as
+// tea.ExecCommand, so the TUI can hand it to tea.Exec like a connection.
+//
+// Keys: space pause/resume, ←/→ seek 5s, ↑/+ faster, ↓/- slower, q/esc quit.
+// Progress is shown in the window title so it doesn't disturb the replayed screen.
+type Player struct {
+	Path string // recording to play
+
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	events   []Event
+	duration float64 // seconds (after idle limiting)
+	idx      int     // next event to write
+	pos      float64 // current playback position in seconds
+	speed    float64
+	paused   bool
+}
+
+// NewPlayer returns a Player for the recording at path.
+func NewPlayer(path string) *Player {
+	return &Player{Path: path, speed: 1}
+}
+
+func (p *Player) SetStdin(r io.Reader) {
+	if p.stdin == nil {
+		p.stdin = r
+	}
+}
+
+func (p *Player) SetStdout(w io.Writer) {
+	if p.stdout == nil {
+		p.stdout = w
+	}
+}
+
+func (p *Player) SetStderr(w io.Writer) {
+	if p.stderr == nil {
+		p.stderr = w
+	}
+}
+
+// Run plays the recording until the user quits.
+//
+// When playback reaches the end it pauses, so the final screen stays visible
+// and the user can still seek back.
+func (p *Player) Run() error {
+	stdin, stdout := p.stdin, p.stdout
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+
+	_, events, err := ReadCast(p.Path)
+	if err != nil {
+		return err
+	}
+	p.events = compressIdle(events, idleTimeLimit)
+	if n := len(p.events); n > 0 {
+		p.duration = p.events[n-1].Time
+	}
+
+	restore := termutil.MakeRaw(stdin)
+	defer restore()
+
+	in, err := cancelreader.NewReader(stdin)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	keys := make(chan playerKey, 8)
+	done := make(chan struct{})
+	go readPlayerKeys(in, keys, done)
+	defer in.Cancel()
+	defer close(done)
+
+	fmt.Fprint(stdout, seqClear)
+	defer fmt.Fprint(stdout, seqShowCursor)
+
+	lastTick := time.Now()
+	lastStatus := time.Time{}
+	for {
+		if time.Since(lastStatus) >= statusEvery {
+			p.writeStatus(stdout)
+			lastStatus = time.Now()
+		}
+
+		// how long until the next event (or a status refresh while paused/at the end)
+		wait := statusEvery
+		if !p.paused && p.idx < len(p.events) {
+			wait = time.Duration((p.events[p.idx].Time - p.pos) / p.speed * float64(time.Second))
+			wait = min(max(wait, 0), statusEvery)
+		}
+
+		select {
+		case k, ok := <-keys:
+			if !ok || k == keyQuit {
+				return nil
+			}
+			p.handleKey(k, stdout)
+			lastStatus = time.Time{}
+
+		case <-time.After(wait):
+		}
+
+		now := time.Now()
+		if !p.paused {
+			p.pos = min(p.pos+now.Sub(lastTick).Seconds()*p.speed, p.duration)
+			p.writeUntil(p.pos, stdout)
+			if p.idx >= len(p.events) {
+				p.paused = true
+				lastStatus = time.Time{}
+			}
+		}
+		lastTick = now
+	}
+}
+
+// handleKey applies a playback key.
+func (p *Player) handleKey(k playerKey, w io.Writer) {
+	switch k {
+	case keyPause:
+		if p.idx >= len(p.events) {
+			// restart from the beginning once finished
+			p.seek(0, w)
+		}
+		p.paused = !p.paused
+	case keyForward:
+		p.seek(p.pos+seekStep.Seconds(), w)
+	case keyBack:
+		p.seek(p.pos-seekStep.Seconds(), w)
+	case keyFaster:
+		p.speed = min(p.speed*2, maxSpeed)
+	case keySlower:
+		p.speed = max(p.speed/2, minSpeed)
+	}
+}
+
+// seek moves playback to t seconds.
+//
+// Seeking back resets the terminal and replays from the start, since terminal
+// output can't be undone.
+func (p *Player) seek(t float64, w io.Writer) {
+	t = min(max(t, 0), p.duration)
+	if t < p.pos {
+		fmt.Fprint(w, seqReset)
+		p.idx = 0
+	}
+	p.pos = t
+	p.writeUntil(t, w)
+}
+
+// writeUntil writes all output events up to time t.
+func (p *Player) writeUntil(t float64, w io.Writer) {
+	var b strings.Builder
+	for p.idx < len(p.events) && p.events[p.idx].Time <= t {
+		if ev := p.events[p.idx]; ev.Type == EventOutput {
+			b.WriteString(ev.Data)
+		}
+		p.idx++
+	}
+	if b.Len() > 0 {
+		_, _ = io.WriteString(w, b.String())
+	}
+}
+
+// writeStatus shows playback state in the window title.
+func (p *Player) writeStatus(w io.Writer) {
+	state := "▶"
+	switch {
+	case p.idx >= len(p.events):
+		state = "■"
+	case p.paused:
+		state = "⏸"
+	}
+	pos := time.Duration(p.pos * float64(time.Second))
+	total := time.Duration(p.duration * float64(time.Second))
+	title := fmt.Sprintf("%s %s / %s  %gx  %s  [space ←/→ ↑/↓ q]",
+		state, FormatDuration(pos), FormatDuration(total), p.speed, filepath.Base(filepath.Dir(p.Path)))
+	fmt.Fprint(w, seqTitleStart+title+seqTitleEnd)
+}
+
+// compressIdle shortens gaps between events to at most limit seconds,
+// so long idle stretches in a session don't stall playback.
+func compressIdle(events []Event, limit float64) []Event {
+	out := make([]Event, 0, len(events))
+	var prev, shift float64
+	for _, ev := range events {
+		if gap := ev.Time - prev; gap > limit {
+			shift += gap - limit
+		}
+		prev = ev.Time
+		ev.Time -= shift
+		out = append(out, ev)
+	}
+	return out
+}
+
+// readPlayerKeys translates raw terminal input into player keys until input
+// is canceled or done is closed (the player has returned and stopped
+// receiving). It closes keys when done.
+func readPlayerKeys(in io.Reader, keys chan<- playerKey, done <-chan struct{}) {
+	defer close(keys)
+	send := func(k playerKey) bool {
+		select {
+		case keys <- k:
+			return true
+		case <-done:
+			return false
+		}
+	}
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		for _, k := range parsePlayerKeys(buf[:n]) {
+			if !send(k) {
+				return
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, cancelreader.ErrCanceled) {
+				send(keyQuit)
+			}
+			return
+		}
+	}
+}
+
+// parsePlayerKeys decodes a chunk of raw terminal input.
+func parsePlayerKeys(b []byte) []playerKey {
+	var out []playerKey
+	s := string(b)
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, "\x1b[C"), strings.HasPrefix(s, "\x1bOC"):
+			out, s = append(out, keyForward), s[3:]
+		case strings.HasPrefix(s, "\x1b[D"), strings.HasPrefix(s, "\x1bOD"):
+			out, s = append(out, keyBack), s[3:]
+		case strings.HasPrefix(s, "\x1b[A"), strings.HasPrefix(s, "\x1bOA"):
+			out, s = append(out, keyFaster), s[3:]
+		case strings.HasPrefix(s, "\x1b[B"), strings.HasPrefix(s, "\x1bOB"):
+			out, s = append(out, keySlower), s[3:]
+		default:
+			switch s[0] {
+			case ' ':
+				out = append(out, keyPause)
+			case 'q', 'Q', 0x1b, 0x03: // q, esc, ctrl+c
+				out = append(out, keyQuit)
+			case '+', '=':
+				out = append(out, keyFaster)
+			case '-', '_':
+				out = append(out, keySlower)
+			}
+			s = s[1:]
+		}
+	}
+	return out
+}
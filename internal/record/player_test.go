@@ -0,0 +1,35 @@
+package record
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadPlayerKeysStopsWhenDone(t *testing.T) {
+	// more keys than the channel holds, and nobody receiving them
+	in := strings.NewReader(strings.Repeat(" ", 64))
+	keys := make(chan playerKey, 1)
+	done := make(chan struct{})
+	returned := make(chan struct{})
+	go func() {
+		readPlayerKeys(in, keys, done)
+		close(returned)
+	}()
+
+	close(done)
+	select {
+	case <-returned:
+	case <-time.After(2 * time.Second):
+		t.Fatal("readPlayerKeys blocked after the player returned")
+	}
+}
+
+func TestParsePlayerKeys(t *testing.T) {
+	got := parsePlayerKeys([]byte(" \x1b[C\x1bOD+-q"))
+	want := []playerKey{keyPause, keyForward, keyBack, keyFaster, keySlower, keyQuit}
+	if !slices.Equal(got, want) {
+		t.Errorf("parsePlayerKeys = %v, want %v", got, want)
+	}
+}
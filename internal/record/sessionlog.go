@@ -0,0 +1,389 @@
+package record
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	logFileName       = "session.log"             // current log file in each host's log directory
+	logTimeLayout     = "2006-01-02 15:04:05.000" // per-line timestamp prefix
+	redactedText      = "[REDACTED]"              // replacement for redacted secrets
+	defaultLogMaxSize = 5 << 20                   // rotate after 5 MiB
+	defaultLogBackups = 5                         // keep session.log.1 .. session.log.5
+)
+
+// DefaultRedactions are applied to every logged line.
+//
+// Each pattern's first capture group (if any) is kept, and the rest of the
+// match is replaced, so "password: hunter2" becomes "password: [REDACTED]".
+var DefaultRedactions = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)((?:password|passwd|passphrase|pwd|secret|token|api[_-]?key)\s*[:=]\s*)\S+`),
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/-]+=*`),
+	regexp.MustCompile(`()AKIA[0-9A-Z]{16}`),
+	regexp.MustCompile(`()gh[pousr]_[A-Za-z0-9]{36,}`),
+	regexp.MustCompile(`()-----BEGIN [A-Z ]*PRIVATE KEY-----`),
+}
+
+// LogRotation controls size-based rotation of session logs.
+type LogRotation struct {
+	MaxSize    int64 // rotate once the log would grow past this many bytes
+	MaxBackups int   // number of rotated files to keep
+}
+
+// DefaultLogRotation rotates at 5 MiB and keeps 5 old files.
+var DefaultLogRotation = LogRotation{MaxSize: defaultLogMaxSize, MaxBackups: defaultLogBackups}
+
+// LogDir returns the root directory for session logs.
+func LogDir() (string, error) {
+	return dataDir("logs")
+}
+
+// LogPath returns the session log file for alias.
+func LogPath(alias string) (string, error) {
+	dir, err := LogDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, safeName(alias), logFileName), nil
+}
+
+// ansi stripper states
+type stripState int
+
+const (
+	stripGround    stripState = iota // plain text
+	stripEsc                         // saw ESC
+	stripEscInter                    // ESC followed by intermediate bytes (eg. ESC ( B)
+	stripCSI                         // inside ESC [ ... final
+	stripString                      // inside OSC/DCS/APC/PM/SOS, until BEL or ST
+	stripStringEsc                   // saw ESC inside a string (possible ST)
+)
+
+// SessionLog writes a plain-text, timestamped log of terminal output.
+//
+// It implements io.Writer so it can be teed next to the terminal. Escape
+// sequences are stripped, carriage returns and backspaces are applied to the
+// current line (so progress bars and line editing log as their final text),
+// and each completed line is redacted and written with a timestamp prefix.
+//
+// Like Recorder, Write never fails; the first error is returned by Close.
+type SessionLog struct {
+	path     string
+	title    string
+	redact   []*regexp.Regexp
+	rotation LogRotation
+
+	mu        sync.Mutex
+	f         *os.File
+	w         *bufio.Writer
+	size      int64
+	state     stripState
+	line      []rune    // current line being assembled
+	col       int       // cursor column within line
+	lineStart time.Time // when the first character of line was seen
+	pendingCR bool      // saw CR, waiting to see if LF follows
+	partial   []byte    // incomplete UTF-8 sequence
+	err       error
+}
+
+// NewSessionLog returns a SessionLog appending to path.
+//
+// If redact is nil, DefaultRedactions are used.
+func NewSessionLog(path string, redact []*regexp.Regexp, rotation LogRotation) *SessionLog {
+	if redact == nil {
+		redact = DefaultRedactions
+	}
+	return &SessionLog{path: path, redact: redact, rotation: rotation}
+}
+
+// Path returns the log file path.
+func (l *SessionLog) Path() string {
+	return l.path
+}
+
+// Started reports whether anything was logged.
+func (l *SessionLog) Started() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f != nil
+}
+
+// Begin sets the title written in the session start marker.
+//
+// The marker (and the file) is only written once the session produces output.
+func (l *SessionLog) Begin(title string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.title = title
+}
+
+// Write implements io.Writer.
+func (l *SessionLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return len(p), nil
+	}
+
+	data := append(l.partial, p...)
+	l.partial = nil
+	for i := 0; i < len(data); {
+		b := data[i]
+
+		// escape sequences and control bytes are handled a byte at a time
+		if l.state != stripGround || b < utf8.RuneSelf {
+			l.stripByte(b)
+			i++
+			continue
+		}
+
+		// printable UTF-8; hold back an incomplete rune for the next write
+		if !utf8.FullRune(data[i:]) {
+			l.partial = append([]byte(nil), data[i:]...)
+			break
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		l.putRune(r)
+		i += size
+	}
+	return len(p), nil
+}
+
+// Close writes any partial line and an end marker, then closes the file.
+func (l *SessionLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return l.err
+	}
+	if len(l.line) > 0 {
+		l.flushLine()
+	}
+	l.writeMarker("=== session end ===")
+	if err := l.w.Flush(); err != nil && l.err == nil {
+		l.err = err
+	}
+	if err := l.f.Close(); err != nil && l.err == nil {
+		l.err = err
+	}
+	l.f = nil
+	return l.err
+}
+
+// stripByte advances the escape-sequence state machine by one byte.
+// Callers must hold l.mu.
+func (l *SessionLog) stripByte(b byte) {
+	switch l.state {
+	case stripGround:
+		l.controlByte(b)
+
+	case stripEsc:
+		switch {
+		case b == '[':
+			l.state = stripCSI
+		case b == ']' || b == 'P' || b == 'X' || b == '^' || b == '_':
+			l.state = stripString
+		case b >= 0x20 && b <= 0x2f:
+			l.state = stripEscInter
+		default:
+			l.state = stripGround
+		}
+
+	case stripEscInter:
+		if b < 0x20 || b > 0x2f {
+			l.state = stripGround
+		}
+
+	case stripCSI:
+		if b >= 0x40 && b <= 0x7e {
+			l.state = stripGround
+		}
+
+	case stripString:
+		switch b {
+		case 0x07: // BEL ends OSC
+			l.state = stripGround
+		case 0x1b:
+			l.state = stripStringEsc
+		}
+
+	case stripStringEsc:
+		if b == '\\' {
+			l.state = stripGround
+		} else {
+			l.state = stripString
+		}
+	}
+}
+
+// controlByte handles an ASCII byte in plain text. Callers must hold l.mu.
+func (l *SessionLog) controlByte(b byte) {
+	if l.pendingCR {
+		l.pendingCR = false
+		if b == '\n' {
+			l.flushLine()
+			return
+		}
+		// bare CR: following text overwrites the line from the start
+		l.col = 0
+	}
+
+	switch b {
+	case 0x1b:
+		l.state = stripEsc
+	case '\r':
+		l.pendingCR = true
+	case '\n':
+		l.flushLine()
+	case '\b':
+		l.col = max(l.col-1, 0)
+	case '\t':
+		l.putRune('\t')
+	default:
+		if b >= 0x20 && b != 0x7f {
+			l.putRune(rune(b))
+		}
+	}
+}
+
+// putRune writes r at the cursor, overwriting or extending the line.
+// Callers must hold l.mu.
+func (l *SessionLog) putRune(r rune) {
+	if l.pendingCR {
+		l.pendingCR = false
+		l.col = 0
+	}
+	if len(l.line) == 0 {
+		l.lineStart = time.Now()
+	}
+	if l.col < len(l.line) {
+		l.line[l.col] = r
+	} else {
+		l.line = append(l.line, r)
+	}
+	l.col++
+}
+
+// flushLine redacts and writes the current line. Callers must hold l.mu.
+func (l *SessionLog) flushLine() {
+	at := l.lineStart
+	if at.IsZero() {
+		at = time.Now()
+	}
+	text := strings.TrimRight(string(l.line), " \t")
+	l.line = l.line[:0]
+	l.col = 0
+	l.lineStart = time.Time{}
+	l.writeLine(at, l.redactLine(text))
+}
+
+// redactLine applies the redaction patterns to s.
+func (l *SessionLog) redactLine(s string) string {
+	for _, re := range l.redact {
+		if re.NumSubexp() > 0 {
+			s = re.ReplaceAllString(s, "${1}"+redactedText)
+		} else {
+			s = re.ReplaceAllString(s, redactedText)
+		}
+	}
+	return s
+}
+
+// writeLine writes one timestamped line, opening/rotating the file as needed.
+// Callers must hold l.mu.
+func (l *SessionLog) writeLine(at time.Time, text string) {
+	if l.err != nil {
+		return
+	}
+	line := at.Format(logTimeLayout) + " " + text + "\n"
+
+	if l.f == nil {
+		if l.err = l.open(); l.err != nil {
+			return
+		}
+		l.writeMarker("=== session start: " + l.title + " ===")
+	}
+	if l.rotation.MaxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.rotation.MaxSize {
+		if l.err = l.rotate(); l.err != nil {
+			return
+		}
+	}
+
+	n, err := l.w.WriteString(line)
+	l.size += int64(n)
+	if err != nil {
+		l.err = err
+	}
+}
+
+// writeMarker writes a timestamped marker line. Callers must hold l.mu.
+func (l *SessionLog) writeMarker(text string) {
+	n, err := l.w.WriteString(time.Now().Format(logTimeLayout) + " " + text + "\n")
+	l.size += int64(n)
+	if err != nil && l.err == nil {
+		l.err = err
+	}
+}
+
+// open opens the log file for appending. Callers must hold l.mu.
+func (l *SessionLog) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	l.f = f
+	l.w = bufio.NewWriter(f)
+	l.size = st.Size()
+	return nil
+}
+
+// rotate shifts session.log -> session.log.1 -> ... and reopens a fresh file.
+// Callers must hold l.mu.
+func (l *SessionLog) rotate() error {
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	l.f = nil
+
+	backups := max(l.rotation.MaxBackups, 0)
+	if backups == 0 {
+		if err := os.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return l.open()
+	}
+
+	// drop the oldest, then shift the rest up by one
+	_ = os.Remove(fmt.Sprintf("%s.%d", l.path, backups))
+	for i := backups - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", l.path, i)
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", l.path, i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return l.open()
+}
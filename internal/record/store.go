@@ -0,0 +1,183 @@
+package record
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+)
+
+const (
+	castExt        = ".cast"           // asciicast file extension
+	castTimeLayout = "20060102-150405" // file name layout for recording start times
+)
+
+// Retention controls how long recordings are kept.
+//
+// A zero MaxAge or MaxPerHost disables that limit.
+type Retention struct {
+	MaxAge     time.Duration // remove recordings older than this
+	MaxPerHost int           // keep at most this many recordings per host (newest first)
+}
+
+// DefaultRetention keeps 30 days of recordings, up to 50 per host.
+var DefaultRetention = Retention{MaxAge: 30 * 24 * time.Hour, MaxPerHost: 50}
+
+// Info describes a recording on disk.
+type Info struct {
+	Path     string        // full path to the .cast file
+	Alias    string        // host alias (recording directory name)
+	Started  time.Time     // when the recording started
+	Duration time.Duration // time of the last event
+	Size     int64         // file size in bytes
+	Title    string        // title from the header
+}
+
+// Dir returns the root directory for recordings.
+func Dir() (string, error) {
+	return dataDir("recordings")
+}
+
+// dataDir returns a directory under the app's data directory.
+func dataDir(name string) (string, error) {
+	return config.GetDataPath(name)
+}
+
+// NewPath returns the file path for a new recording of alias started at t.
+func NewPath(alias string, t time.Time) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, safeName(alias), t.Format(castTimeLayout)+castExt), nil
+}
+
+// safeName makes an alias safe to use as a directory name.
+func safeName(alias string) string {
+	alias = strings.TrimSpace(alias)
+	if alias == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, alias)
+}
+
+// List returns all recordings, newest first.
+//
+// A missing recordings directory is not an error.
+func List() ([]Info, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Info
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != castExt {
+			return nil
+		}
+		out = append(out, statRecording(path))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(out, func(a, b Info) int { return b.Started.Compare(a.Started) })
+	return out, nil
+}
+
+// statRecording builds an Info for path, best-effort.
+//
+// Unreadable files are still listed (with what the file name and stat tell us)
+// so retention can clean them up.
+func statRecording(path string) Info {
+	info := Info{
+		Path:  path,
+		Alias: filepath.Base(filepath.Dir(path)),
+	}
+	if st, err := os.Stat(path); err == nil {
+		info.Size = st.Size()
+		info.Started = st.ModTime()
+	}
+	name := strings.TrimSuffix(filepath.Base(path), castExt)
+	if t, err := time.ParseInLocation(castTimeLayout, name, time.Local); err == nil {
+		info.Started = t
+	}
+
+	h, events, err := ReadCast(path)
+	if err != nil {
+		return info
+	}
+	info.Title = h.Title
+	if h.Timestamp > 0 {
+		info.Started = time.Unix(h.Timestamp, 0)
+	}
+	if n := len(events); n > 0 {
+		info.Duration = time.Duration(events[n-1].Time * float64(time.Second))
+	}
+	return info
+}
+
+// Prune removes recordings that fall outside the retention policy.
+//
+// It returns the number of files removed.
+func Prune(r Retention) (int, error) {
+	all, err := List()
+	if err != nil {
+		return 0, err
+	}
+
+	perHost := map[string]int{}
+	cutoff := time.Time{}
+	if r.MaxAge > 0 {
+		cutoff = time.Now().Add(-r.MaxAge)
+	}
+
+	var errs []error
+	removed := 0
+	// List is newest first, so the per-host count keeps the most recent ones
+	for _, info := range all {
+		perHost[info.Alias]++
+		expired := !cutoff.IsZero() && info.Started.Before(cutoff)
+		overLimit := r.MaxPerHost > 0 && perHost[info.Alias] > r.MaxPerHost
+		if !expired && !overLimit {
+			continue
+		}
+		if err := os.Remove(info.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+	return removed, errors.Join(errs...)
+}
+
+// FormatDuration formats a recording duration as mm:ss (or h:mm:ss).
+func FormatDuration(d time.Duration) string {
+	d = max(d.Round(time.Second), 0)
+	h := int(d / time.Hour)
+	m := int(d % time.Hour / time.Minute)
+	s := int(d % time.Minute / time.Second)
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
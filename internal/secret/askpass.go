@@ -0,0 +1,298 @@
+package secret
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AskpassEnv is set in ssh's environment to the socket the askpass
+	// helper asks for answers on. When the binary starts with it set, it
+	// runs as ssh's askpass helper instead of the menu.
+	AskpassEnv = "BTMS_ASKPASS"
+
+	// AskpassTokenEnv is set in ssh's environment to the token the helper
+	// proves itself with.
+	AskpassTokenEnv = "BTMS_ASKPASS_TOKEN"
+
+	// askpassRequestTimeout bounds reading a request from the helper.
+	askpassRequestTimeout = 5 * time.Second
+)
+
+// KeySecretPrefix prefixes the secret names of ssh key passphrases (eg.
+// "key:id_ed25519" for ~/.ssh/id_ed25519).
+const KeySecretPrefix = "key:"
+
+// AskpassTarget is the login whose password prompts the askpass helper
+// answers.
+type AskpassTarget struct {
+	Secret string // name of the password secret ("" for key passphrases only)
+	User   string // login user ("" for any)
+	Host   string // host name ssh connects to (eg. the HostName)
+}
+
+// askpassRequest is what the helper sends, as one line of JSON.
+type askpassRequest struct {
+	Token  string `json:"token"`
+	Prompt string `json:"prompt"`
+}
+
+// askpassReply is the answer to an askpassRequest. Found is false if the
+// prompt should be asked on the terminal.
+type askpassReply struct {
+	Answer string `json:"answer,omitempty"`
+	Found  bool   `json:"found"`
+	Err    string `json:"err,omitempty"`
+}
+
+// StartAskpass makes ssh (run by cmd) ask this process for passwords and
+// key passphrases, until stop is called.
+//
+// It listens on a socket in a private temporary directory and points ssh's
+// askpass at this binary, which asks over the socket. Each prompt gets only
+// the one secret that answers it: the target's password for its own
+// password prompt (not a jump host's), or the key's passphrase. The vault
+// passphrase is never passed on, and is removed from cmd's environment.
+//
+// Needs OpenSSH 8.4 or later (SSH_ASKPASS_REQUIRE); older versions ignore
+// it and prompt as usual.
+func StartAskpass(cmd *exec.Cmd, t AskpassTarget) (stop func(), err error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("askpass helper: %w", err)
+	}
+	tok := make([]byte, 16)
+	if _, err := rand.Read(tok); err != nil {
+		return nil, fmt.Errorf("askpass helper: %w", err)
+	}
+	dir, err := os.MkdirTemp("", "btms-askpass-")
+	if err != nil {
+		return nil, fmt.Errorf("askpass helper: %w", err)
+	}
+	addr := filepath.Join(dir, "s")
+	ln, err := net.Listen("unix", addr)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("askpass helper: %w", err)
+	}
+
+	s := &askpassServer{ln: ln, target: t, token: hex.EncodeToString(tok)}
+	s.wg.Add(1)
+	go s.serve()
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	env = slices.DeleteFunc(slices.Clone(env), func(kv string) bool {
+		return strings.HasPrefix(kv, VaultPassphraseEnv+"=")
+	})
+	cmd.Env = append(env,
+		"SSH_ASKPASS="+exe,
+		"SSH_ASKPASS_REQUIRE=force",
+		AskpassEnv+"="+addr,
+		AskpassTokenEnv+"="+s.token,
+	)
+
+	return func() {
+		_ = ln.Close()
+		s.wg.Wait()
+		_ = os.RemoveAll(dir)
+	}, nil
+}
+
+// askpassServer answers the helper's requests for one command.
+type askpassServer struct {
+	ln     net.Listener
+	target AskpassTarget
+	token  string
+	wg     sync.WaitGroup
+}
+
+// serve answers requests until the listener is closed. Requests are taken
+// one at a time, as ssh asks one prompt at a time.
+func (s *askpassServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.handle(conn)
+	}
+}
+
+// handle answers one request.
+func (s *askpassServer) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(askpassRequestTimeout))
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	var req askpassRequest
+	if json.Unmarshal(line, &req) != nil || subtle.ConstantTimeCompare([]byte(req.Token), []byte(s.token)) != 1 {
+		return
+	}
+
+	var reply askpassReply
+	if name := askpassSecret(req.Prompt, s.target); name != "" {
+		v, err := Default().Lookup(name)
+		switch {
+		case err == nil:
+			reply = askpassReply{Answer: v, Found: true}
+		case !errors.Is(err, ErrNotFound):
+			reply.Err = err.Error()
+		}
+	}
+	_ = json.NewEncoder(conn).Encode(reply)
+}
+
+// IsAskpass reports whether the binary was started as ssh's askpass helper.
+func IsAskpass() bool {
+	_, ok := os.LookupEnv(AskpassEnv)
+	return ok
+}
+
+// Askpass answers one ssh prompt, writing the answer to w.
+//
+// The prompt is sent to the process that started ssh (see StartAskpass),
+// which answers it if it has the secret for it. Anything else (eg. a new
+// host key confirmation), or a secret that isn't found, is asked on the
+// terminal.
+func Askpass(prompt string, w io.Writer) error {
+	if os.Getenv("SSH_ASKPASS_PROMPT") == "none" {
+		// a notice with no answer (eg. "Confirm user presence for key")
+		return notify(prompt)
+	}
+	reply, err := askServer(os.Getenv(AskpassEnv), os.Getenv(AskpassTokenEnv), prompt)
+	if err != nil {
+		return err
+	}
+	if reply.Found {
+		_, err = fmt.Fprintln(w, reply.Answer)
+		return err
+	}
+
+	var answer string
+	if confirmPrompt(prompt) {
+		answer, err = PromptLine(prompt)
+	} else {
+		answer, err = PromptPassword(prompt)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, answer)
+	return err
+}
+
+// askServer asks the askpass server at addr for the answer to prompt.
+func askServer(addr, token, prompt string) (askpassReply, error) {
+	conn, err := net.Dial("unix", addr)
+	if err != nil {
+		return askpassReply{}, fmt.Errorf("askpass: %w", err)
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(askpassRequest{Token: token, Prompt: prompt}); err != nil {
+		return askpassReply{}, fmt.Errorf("askpass: %w", err)
+	}
+	var reply askpassReply
+	if err := json.NewDecoder(conn).Decode(&reply); err != nil {
+		return askpassReply{}, fmt.Errorf("askpass: %w", err)
+	}
+	if reply.Err != "" {
+		return askpassReply{}, errors.New(reply.Err)
+	}
+	return reply, nil
+}
+
+// askpassSecret returns the secret that answers prompt, or "" if it should
+// be asked on the terminal.
+//
+// Password prompts only get t's password if they're for t's login; others
+// (eg. a ProxyJump hop's) are refused.
+func askpassSecret(prompt string, t AskpassTarget) string {
+	lower := strings.ToLower(prompt)
+	if strings.Contains(lower, "passphrase for key") {
+		// Enter passphrase for key '/home/me/.ssh/id_ed25519':
+		if i := strings.IndexByte(prompt, '\''); i >= 0 {
+			if j := strings.IndexByte(prompt[i+1:], '\''); j >= 0 {
+				return KeySecretPrefix + filepath.Base(prompt[i+1:i+1+j])
+			}
+		}
+		return ""
+	}
+	if i := strings.Index(lower, "passphrase for "); i >= 0 {
+		// ssh-add: Enter passphrase for /home/me/.ssh/id_ed25519:
+		path := strings.TrimSpace(prompt[i+len("passphrase for "):])
+		path, _, _ = strings.Cut(path, " (")
+		path = strings.TrimSuffix(strings.TrimSpace(path), ":")
+		if path == "" {
+			return ""
+		}
+		return KeySecretPrefix + filepath.Base(path)
+	}
+	if !strings.Contains(lower, "password") || t.Secret == "" {
+		return ""
+	}
+	user, host, ok := promptLogin(prompt)
+	if !ok || !strings.EqualFold(host, t.Host) || (t.User != "" && user != t.User) {
+		return ""
+	}
+	return strings.TrimSpace(t.Secret)
+}
+
+// promptLogin returns the user and host a password prompt is for, from
+// "user@host's password:" or "(user@host) Password:" (keyboard-interactive).
+func promptLogin(prompt string) (user, host string, ok bool) {
+	prompt = strings.TrimSpace(prompt)
+	var login string
+	if rest, found := strings.CutPrefix(prompt, "("); found {
+		login, _, found = strings.Cut(rest, ")")
+		if !found {
+			return "", "", false
+		}
+	} else if i := strings.Index(prompt, "'s password"); i > 0 {
+		login = prompt[:i]
+	} else {
+		return "", "", false
+	}
+	i := strings.LastIndexByte(login, '@')
+	if i <= 0 || i == len(login)-1 {
+		return "", "", false
+	}
+	return login[:i], login[i+1:], true
+}
+
+// confirmPrompt reports whether prompt asks for a visible answer (eg.
+// "yes/no") rather than a password.
+func confirmPrompt(prompt string) bool {
+	return os.Getenv("SSH_ASKPASS_PROMPT") == "confirm" ||
+		strings.Contains(strings.ToLower(prompt), "(yes/no")
+}
+
+// notify shows a message on the terminal.
+func notify(msg string) error {
+	in, out, err := openTTY()
+	if err != nil {
+		return err
+	}
+	defer closeTTY(in, out)
+	_, err = fmt.Fprint(out, strings.TrimRight(msg, "\r\n")+"\r\n")
+	return err
+}
@@ -0,0 +1,109 @@
+package secret
+
+import (
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAskpassSecret(t *testing.T) {
+	target := AskpassTarget{Secret: "web-password", User: "alice", Host: "10.0.0.5"}
+	tests := []struct {
+		name   string
+		prompt string
+		want   string
+	}{
+		{"password", "alice@10.0.0.5's password: ", "web-password"},
+		{"keyboard-interactive", "(alice@10.0.0.5) Password: ", "web-password"},
+		{"other user", "root@10.0.0.5's password: ", ""},
+		{"jump host", "alice@bastion.example.com's password: ", ""},
+		{"jump host, keyboard-interactive", "(alice@bastion) Password: ", ""},
+		{"no login in prompt", "Password: ", ""},
+		{"key passphrase", "Enter passphrase for key '/home/alice/.ssh/id_ed25519': ", "key:id_ed25519"},
+		{"ssh-add passphrase", "Enter passphrase for /home/alice/.ssh/id_rsa (will confirm): ", "key:id_rsa"},
+		{"host key", "Are you sure you want to continue connecting (yes/no/[fingerprint])? ", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := askpassSecret(tt.prompt, target); got != tt.want {
+				t.Errorf("askpassSecret(%q) = %q, want %q", tt.prompt, got, tt.want)
+			}
+		})
+	}
+
+	anyUser := AskpassTarget{Secret: "pw", Host: "web"}
+	if got := askpassSecret("bob@Web's password:", anyUser); got != "pw" {
+		t.Errorf("any user, host in another case: got %q, want pw", got)
+	}
+	if got := askpassSecret("alice@web's password:", AskpassTarget{Host: "web"}); got != "" {
+		t.Errorf("no password secret: got %q, want none", got)
+	}
+}
+
+func TestPromptLogin(t *testing.T) {
+	tests := []struct {
+		prompt     string
+		user, host string
+		ok         bool
+	}{
+		{"alice@web's password:", "alice", "web", true},
+		{"(me@corp.com@web) Password:", "me@corp.com", "web", true},
+		{"(alice@web Password:", "", "", false},
+		{"@web's password:", "", "", false},
+		{"alice@'s password:", "", "", false},
+		{"Password:", "", "", false},
+	}
+	for _, tt := range tests {
+		user, host, ok := promptLogin(tt.prompt)
+		if user != tt.user || host != tt.host || ok != tt.ok {
+			t.Errorf("promptLogin(%q) = %q, %q, %v; want %q, %q, %v", tt.prompt, user, host, ok, tt.user, tt.host, tt.ok)
+		}
+	}
+}
+
+func TestStartAskpass(t *testing.T) {
+	t.Setenv(EnvVar("web-password"), "s3cret")
+	t.Setenv(VaultPassphraseEnv, "master")
+
+	cmd := exec.Command("ssh")
+	stop, err := StartAskpass(cmd, AskpassTarget{Secret: "web-password", User: "alice", Host: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	env := func(name string) string {
+		for _, kv := range slices.Backward(cmd.Env) {
+			if v, ok := strings.CutPrefix(kv, name+"="); ok {
+				return v
+			}
+		}
+		return ""
+	}
+	if v := env(VaultPassphraseEnv); v != "" {
+		t.Errorf("the vault passphrase is passed to ssh: %q", v)
+	}
+	if env("SSH_ASKPASS_REQUIRE") != "force" {
+		t.Error("SSH_ASKPASS_REQUIRE isn't set")
+	}
+	addr, token := env(AskpassEnv), env(AskpassTokenEnv)
+
+	reply, err := askServer(addr, token, "alice@web's password: ")
+	if err != nil || !reply.Found || reply.Answer != "s3cret" {
+		t.Errorf("target's password prompt: %+v, %v; want the secret", reply, err)
+	}
+	reply, err = askServer(addr, token, "alice@bastion's password: ")
+	if err != nil || reply.Found {
+		t.Errorf("jump host's password prompt: %+v, %v; want no answer", reply, err)
+	}
+	if reply, err := askServer(addr, "wrong", "alice@web's password: "); err == nil || reply.Found {
+		t.Errorf("wrong token: %+v, %v; want an error", reply, err)
+	}
+
+	stop()
+	if _, err := os.Stat(addr); !os.IsNotExist(err) {
+		t.Errorf("socket still there after stop: %v", err)
+	}
+}
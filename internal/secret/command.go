@@ -0,0 +1,94 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+	str "bubbletea-ssh-manager/internal/stringutil"
+)
+
+// CommandEnv is the environment variable that sets the external command
+// provider's command line (eg. "pass show btms/{name}"), overriding the
+// secrets.command setting.
+const CommandEnv = "BTMS_SECRET_COMMAND"
+
+// DefaultCommandTimeout bounds an external command lookup. It is generous
+// since the command may ask for a GPG passphrase or a hardware key touch.
+const DefaultCommandTimeout = time.Minute
+
+// Command looks up secrets by running an external password manager.
+//
+// The command line is split like a shell command, and {name} in any
+// argument is replaced with the secret name. The first line of the
+// command's output is the secret.
+type Command struct {
+	Line    string        // command line template
+	Timeout time.Duration // 0 uses DefaultCommandTimeout
+}
+
+func (c Command) Name() string { return "command" }
+
+// Lookup runs the command for name. A command that fails is treated as not
+// having the secret, with the end of its error output in the message.
+func (c Command) Lookup(name string) (string, error) {
+	args, err := c.args(name)
+	if err != nil {
+		return "", err
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("%s: timed out after %s", args[0], timeout)
+		}
+		var exit *exec.ExitError
+		if !errors.As(err, &exit) {
+			return "", fmt.Errorf("%s: %w", args[0], err)
+		}
+		tail := ""
+		if line := str.LastNonEmptyLine(stderr.String()); line != "" {
+			tail = ": " + line
+		}
+		return "", fmt.Errorf("%q: %w by %s%s", name, ErrNotFound, args[0], tail)
+	}
+	line, _, _ := strings.Cut(stdout.String(), "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
+
+// args returns the command line for name.
+func (c Command) args(name string) ([]string, error) {
+	args, err := config.SplitArgs(c.Line)
+	if err != nil {
+		return nil, fmt.Errorf("secret command: %w", err)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("secret command is empty")
+	}
+	for i, a := range args {
+		args[i] = strings.ReplaceAll(a, "{name}", name)
+	}
+	return args, nil
+}
+
+// ValidateCommand reports whether line is a usable command line.
+func ValidateCommand(line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	_, err := Command{Line: line}.args("")
+	return err
+}
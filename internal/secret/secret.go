@@ -0,0 +1,146 @@
+// Package secret looks up passwords and other secrets by name, so login
+// scripts and ssh can use them without storing them in plain text.
+//
+// Secrets come from a chain of providers, tried in order: environment
+// variables (BTMS_SECRET_*), the encrypted vault (~/.btms/vault.age) and an
+// external command such as "pass show btms/{name}" (the secrets.command
+// setting).
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// EnvPrefix is the prefix of the environment variables the Env provider reads
+// (eg. BTMS_SECRET_VMS_PASSWORD for the secret "vms-password").
+const EnvPrefix = "BTMS_SECRET_"
+
+// ErrNotFound is returned when a provider has no secret with the given name.
+var ErrNotFound = errors.New("secret not found")
+
+// A Provider looks up secrets by name.
+type Provider interface {
+	Name() string                       // short description for messages (eg. "environment")
+	Lookup(name string) (string, error) // ErrNotFound if the provider doesn't have it
+}
+
+// A Store is a provider whose secrets can be changed.
+type Store interface {
+	Provider
+	Set(name, value string) error
+	Delete(name string) error
+	List() ([]string, error)
+}
+
+// Env reads secrets from environment variables named EnvPrefix plus the
+// secret name, uppercased with anything but letters and digits turned into
+// underscores.
+type Env struct{}
+
+func (Env) Name() string { return "environment" }
+
+// Lookup returns the value of the secret's environment variable.
+func (Env) Lookup(name string) (string, error) {
+	v, ok := os.LookupEnv(EnvVar(name))
+	if !ok {
+		return "", fmt.Errorf("%q: %w (set %s)", name, ErrNotFound, EnvVar(name))
+	}
+	return v, nil
+}
+
+// EnvVar returns the environment variable the Env provider reads for name.
+func EnvVar(name string) string {
+	return EnvPrefix + strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, strings.TrimSpace(name))
+}
+
+// Chain tries providers in order, returning the first secret found.
+type Chain []Provider
+
+func (c Chain) Name() string { return "secrets" }
+
+// Lookup returns the secret from the first provider that has it. Errors
+// other than ErrNotFound (eg. a wrong vault passphrase) stop the lookup.
+func (c Chain) Lookup(name string) (string, error) {
+	for _, p := range c {
+		v, err := p.Lookup(name)
+		if err == nil {
+			return v, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return "", fmt.Errorf("%s: %w", p.Name(), err)
+		}
+	}
+	names := make([]string, len(c))
+	for i, p := range c {
+		names[i] = p.Name()
+	}
+	return "", fmt.Errorf("%q: %w (looked in %s)", name, ErrNotFound, strings.Join(names, ", "))
+}
+
+var (
+	defaultMu    sync.Mutex
+	defaultChain Chain
+	commandLine  string // external command line ("" for none), from SetCommand
+)
+
+// SetCommand sets the external command (from the settings) that Default
+// tries last; "" for none.
+func SetCommand(line string) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	line = strings.TrimSpace(line)
+	if line != commandLine {
+		commandLine = line
+		defaultChain = nil
+	}
+}
+
+// Default returns the provider chain used for hosts: the environment, the
+// default vault, then the external command if one is set.
+func Default() Provider {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultChain == nil {
+		defaultChain = Chain{Env{}, DefaultVault()}
+		if commandLine != "" {
+			defaultChain = append(defaultChain, Command{Line: commandLine})
+		}
+	}
+	return defaultChain
+}
+
+// Unattended returns the same chain as Default, except that a locked vault
+// fails with ErrLocked instead of asking for its passphrase. It is for
+// lookups while the terminal is in use by a session.
+func Unattended() Provider {
+	c := slices.Clone(Default().(Chain))
+	for i, p := range c {
+		if v, ok := p.(*Vault); ok {
+			c[i] = unattendedVault{v}
+		}
+	}
+	return c
+}
+
+// unattendedVault is a vault that never asks for its passphrase.
+type unattendedVault struct{ v *Vault }
+
+func (u unattendedVault) Name() string { return u.v.Name() }
+
+func (u unattendedVault) Lookup(name string) (string, error) {
+	if !u.v.Unlocked() && u.v.Exists() {
+		return "", ErrLocked
+	}
+	return u.v.Lookup(name)
+}
@@ -0,0 +1,74 @@
+package secret
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// openTTY opens the controlling terminal for reading and writing, so
+// prompts work even when stdin and stdout are redirected (eg. under ssh).
+func openTTY() (in, out *os.File, err error) {
+	if runtime.GOOS == "windows" {
+		in, err = os.OpenFile("CONIN$", os.O_RDWR, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		out, err = os.OpenFile("CONOUT$", os.O_RDWR, 0)
+		if err != nil {
+			in.Close()
+			return nil, nil, err
+		}
+		return in, out, nil
+	}
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f, nil
+}
+
+// PromptPassword asks for a secret on the terminal without echoing it.
+func PromptPassword(prompt string) (string, error) {
+	in, out, err := openTTY()
+	if err != nil {
+		return "", fmt.Errorf("no terminal to ask on: %w", err)
+	}
+	defer closeTTY(in, out)
+
+	fmt.Fprint(out, prompt)
+	b, err := term.ReadPassword(in.Fd())
+	fmt.Fprint(out, "\r\n")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// PromptLine asks for a line of text on the terminal, echoing it.
+func PromptLine(prompt string) (string, error) {
+	in, out, err := openTTY()
+	if err != nil {
+		return "", fmt.Errorf("no terminal to ask on: %w", err)
+	}
+	defer closeTTY(in, out)
+
+	fmt.Fprint(out, prompt)
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// closeTTY closes the files from openTTY.
+func closeTTY(in, out *os.File) {
+	in.Close()
+	if out != in {
+		out.Close()
+	}
+}
@@ -0,0 +1,310 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"bubbletea-ssh-manager/internal/config"
+
+	"filippo.io/age"
+)
+
+// VaultPassphraseEnv is the environment variable the default vault reads its
+// passphrase from before asking on the terminal. It is never passed on to
+// child processes.
+const VaultPassphraseEnv = "BTMS_VAULT_PASSPHRASE"
+
+// VaultPath is the default vault file, relative to the home directory.
+var VaultPath = []string{".btms", "vault.age"}
+
+// ErrLocked is returned when the vault needs a passphrase and none is given.
+var ErrLocked = errors.New("vault is locked")
+
+// vaultVersion is the format of the decrypted vault contents.
+const vaultVersion = 1
+
+// vaultFile is the decrypted vault contents.
+type vaultFile struct {
+	Version int               `json:"version"`
+	Secrets map[string]string `json:"secrets"`
+}
+
+// Vault is a file of secrets encrypted with age using a passphrase (scrypt).
+//
+// It is locked until a passphrase is given, either with Unlock or, for
+// lookups, from Ask. Secrets are kept in memory once unlocked.
+type Vault struct {
+	Path string                 // encrypted file
+	Ask  func() (string, error) // asks for the passphrase on lookups; nil fails with ErrLocked
+
+	mu      sync.Mutex
+	pass    string            // passphrase once unlocked
+	secrets map[string]string // nil while locked
+}
+
+// NewVault returns a locked vault stored at path.
+func NewVault(path string) *Vault {
+	return &Vault{Path: path}
+}
+
+var (
+	defaultVault     *Vault
+	defaultVaultOnce sync.Once
+)
+
+// DefaultVault returns the vault at ~/.btms/vault.age, shared by the whole
+// process so unlocking it once is enough. Its passphrase comes from
+// BTMS_VAULT_PASSPHRASE or is asked for on the terminal.
+func DefaultVault() *Vault {
+	defaultVaultOnce.Do(func() {
+		path, err := config.GetConfigPath(VaultPath...)
+		if err != nil {
+			path = filepath.Join(VaultPath...)
+		}
+		defaultVault = NewVault(path)
+		defaultVault.Ask = func() (string, error) {
+			if v, ok := os.LookupEnv(VaultPassphraseEnv); ok {
+				return v, nil
+			}
+			return PromptPassword("Vault passphrase: ")
+		}
+	})
+	return defaultVault
+}
+
+func (v *Vault) Name() string { return "vault" }
+
+// Exists reports whether the vault file has been created.
+func (v *Vault) Exists() bool {
+	_, err := os.Stat(v.Path)
+	return err == nil
+}
+
+// Unlocked reports whether the vault's secrets are loaded.
+func (v *Vault) Unlocked() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.secrets != nil
+}
+
+// Unlock decrypts the vault with passphrase. A vault that doesn't exist yet
+// is unlocked empty, and will be created with passphrase on the first Set.
+func (v *Vault) Unlock(passphrase string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.unlock(passphrase)
+}
+
+// unlock decrypts the vault. The caller holds mu.
+func (v *Vault) unlock(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("vault passphrase is empty")
+	}
+	b, err := os.ReadFile(v.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		v.pass, v.secrets = passphrase, map[string]string{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	id, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return err
+	}
+	r, err := age.Decrypt(bytes.NewReader(b), id)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return fmt.Errorf("%s: wrong passphrase", v.Path)
+		}
+		return fmt.Errorf("%s: %w", v.Path, err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", v.Path, err)
+	}
+	var f vaultFile
+	if err := json.Unmarshal(plain, &f); err != nil {
+		return fmt.Errorf("%s: %w", v.Path, err)
+	}
+	if f.Version != vaultVersion {
+		return fmt.Errorf("%s: unsupported vault version %d", v.Path, f.Version)
+	}
+	if f.Secrets == nil {
+		f.Secrets = map[string]string{}
+	}
+	v.pass, v.secrets = passphrase, f.Secrets
+	return nil
+}
+
+// Lock forgets the passphrase and secrets.
+func (v *Vault) Lock() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.pass, v.secrets = "", nil
+}
+
+// EnsureUnlocked unlocks the vault with a passphrase from Ask if it exists
+// and is still locked. Call it before the terminal goes into raw mode, since
+// Ask may prompt on it.
+func (v *Vault) EnsureUnlocked() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.ensureUnlocked()
+}
+
+// ensureUnlocked is EnsureUnlocked with mu held.
+func (v *Vault) ensureUnlocked() error {
+	if v.secrets != nil {
+		return nil
+	}
+	if _, err := os.Stat(v.Path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if v.Ask == nil {
+		return ErrLocked
+	}
+	pass, err := v.Ask()
+	if err != nil {
+		return fmt.Errorf("vault passphrase: %w", err)
+	}
+	return v.unlock(pass)
+}
+
+// Lookup returns a secret from the vault, unlocking it first if needed.
+func (v *Vault) Lookup(name string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.ensureUnlocked(); err != nil {
+		return "", err
+	}
+	s, ok := v.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("%q: %w in the vault", name, ErrNotFound)
+	}
+	return s, nil
+}
+
+// Set stores a secret and rewrites the vault. The vault must be unlocked.
+func (v *Vault) Set(name, value string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("secret name is empty")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.secrets == nil {
+		return ErrLocked
+	}
+	next := maps.Clone(v.secrets)
+	next[name] = value
+	return v.save(next)
+}
+
+// Delete removes a secret and rewrites the vault. The vault must be unlocked.
+func (v *Vault) Delete(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.secrets == nil {
+		return ErrLocked
+	}
+	if _, ok := v.secrets[name]; !ok {
+		return fmt.Errorf("%q: %w in the vault", name, ErrNotFound)
+	}
+	next := maps.Clone(v.secrets)
+	delete(next, name)
+	return v.save(next)
+}
+
+// Has reports whether the unlocked vault has a secret. It is false while
+// the vault is locked.
+func (v *Vault) Has(name string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	_, ok := v.secrets[name]
+	return ok
+}
+
+// List returns the names of the secrets in the vault, sorted.
+func (v *Vault) List() ([]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.ensureUnlocked(); err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(v.secrets)), nil
+}
+
+// save encrypts secrets to the vault file and keeps them on success. The
+// caller holds mu.
+func (v *Vault) save(secrets map[string]string) error {
+	plain, err := json.Marshal(vaultFile{Version: vaultVersion, Secrets: secrets})
+	if err != nil {
+		return err
+	}
+	rcpt, err := age.NewScryptRecipient(v.pass)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, rcpt)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(plain); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := writePrivate(v.Path, buf.Bytes()); err != nil {
+		return err
+	}
+	v.secrets = secrets
+	return nil
+}
+
+// writePrivate writes data to path atomically, readable only by the owner.
+func writePrivate(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".tmp-vault-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+	}()
+	if err := f.Chmod(0o600); err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// on Windows, os.Rename won't overwrite an existing destination
+	if _, err := os.Stat(path); err == nil {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,225 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// FileName is the settings file's name in the user config dir.
+	FileName = "settings.toml"
+
+	// EnvPath names a different settings file.
+	EnvPath = "BTMS_SETTINGS"
+)
+
+// readFile reads the settings file and returns its values by key, as
+// strings. A missing file has no values.
+//
+// It checks the file against the schema: only known sections and keys, and
+// values of the right type. Anything else is reported and left out.
+func readFile(path string) (map[string]string, error) {
+	raw := map[string]any{}
+	if _, err := toml.DecodeFile(path, &raw); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("settings: %w", err)
+	}
+
+	name := filepath.Base(path)
+	values := map[string]string{}
+	var errs []error
+	for _, section := range slices.Sorted(maps.Keys(raw)) {
+		table, ok := raw[section].(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s: settings go in a [section]", name, section))
+			continue
+		}
+		for _, key := range slices.Sorted(maps.Keys(table)) {
+			full := section + "." + key
+			k, ok := LookupKey(full)
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown setting %q", name, full))
+				continue
+			}
+			v, err := fileValue(k, table[key])
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", name, full, err))
+				continue
+			}
+			values[full] = v
+		}
+	}
+	return values, errors.Join(errs...)
+}
+
+// fileValue returns a decoded TOML value as the string the key parses.
+// Integer keys also take strings ("22"), list keys a single string and
+// boolean keys "on" or "off", so either spelling works.
+func fileValue(k Key, v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int64:
+		if k.Kind == KindInt {
+			return strconv.FormatInt(v, 10), nil
+		}
+		return "", fmt.Errorf("expected a string, got %d", v)
+	case bool:
+		if k.Kind == KindBool {
+			return onOff(v), nil
+		}
+		return "", fmt.Errorf("expected a string, got %t", v)
+	case []any:
+		if k.Kind != KindList {
+			break
+		}
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return "", fmt.Errorf("expected a list of strings, got %v", item)
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ListSep), nil
+	}
+	switch k.Kind {
+	case KindInt:
+		return "", fmt.Errorf("expected an integer, got %v", v)
+	case KindList:
+		return "", fmt.Errorf("expected a list of strings, got %v", v)
+	case KindBool:
+		return "", fmt.Errorf("expected true or false, got %v", v)
+	}
+	return "", fmt.Errorf("expected a string, got %v", v)
+}
+
+// SetFile sets a key to v in the settings file's values (Save writes them),
+// and makes it the effective value. Setting a key back to its built-in value
+// leaves it out of the file.
+func (s *Settings) SetFile(k Key, v string) error {
+	if err := s.set(k, v, SourceFile, filepath.Base(s.path)); err != nil {
+		return err
+	}
+	if s.IsDefault(k) {
+		delete(s.file, k.Name)
+		delete(s.sources, k.Name)
+		return nil
+	}
+	s.file[k.Name] = k.get(s)
+	return nil
+}
+
+// Clone returns a copy of s that can be changed without changing s.
+func (s Settings) Clone() Settings {
+	s.Protocols = maps.Clone(s.Protocols)
+	s.file = maps.Clone(s.file)
+	s.sources = maps.Clone(s.sources)
+	return s
+}
+
+// Save writes the settings file's values to the settings file. Values from
+// the environment and --set flags aren't saved, and comments in the file
+// aren't kept.
+func (s Settings) Save() error {
+	if s.path == "" {
+		return errors.New("settings: no settings file")
+	}
+	var b strings.Builder
+	b.WriteString("# menu settings; 'menu config' prints every setting and its effective value.\n")
+	section := ""
+	for _, k := range Keys() {
+		v, ok := s.file[k.Name]
+		if !ok {
+			continue
+		}
+		if k.Section() != section {
+			section = k.Section()
+			fmt.Fprintf(&b, "\n[%s]\n", section)
+		}
+		fmt.Fprintf(&b, "%s = %s\n", strings.TrimPrefix(k.Name, section+"."), formatValue(k, v))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o600); err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("settings: %w", err)
+	}
+	return nil
+}
+
+// Format returns the effective settings as a settings file, with a comment
+// on each value that doesn't come from the built-in defaults saying where
+// it came from.
+func (s Settings) Format() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", s.path)
+	section := ""
+	for _, k := range Keys() {
+		if k.Section() != section {
+			section = k.Section()
+			fmt.Fprintf(&b, "\n[%s]\n", section)
+		}
+		line := fmt.Sprintf("%s = %s", strings.TrimPrefix(k.Name, section+"."), formatValue(k, s.Get(k)))
+		switch s.Source(k) {
+		case SourceFile:
+			line += "  # from the settings file"
+		case SourceEnv:
+			line += "  # from " + k.Env()
+		case SourceFlag:
+			line += "  # from --set"
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// formatValue returns v as a TOML value for the key: a bare integer for
+// integer keys, an array for list keys, true or false for boolean keys,
+// otherwise a literal string (so Windows paths need no escaping) unless it
+// contains a quote.
+func formatValue(k Key, v string) string {
+	switch k.Kind {
+	case KindInt:
+		if _, err := strconv.Atoi(v); err == nil {
+			return v
+		}
+	case KindList:
+		items := splitList(v)
+		for i, item := range items {
+			items[i] = formatString(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case KindBool:
+		if on, err := parseOnOff(v); err == nil {
+			return strconv.FormatBool(on)
+		}
+	}
+	return formatString(v)
+}
+
+// formatString returns v as a TOML literal string, or a basic one if it
+// contains a quote or newline.
+func formatString(v string) string {
+	if !strings.ContainsAny(v, "'\n") {
+		return "'" + v + "'"
+	}
+	return strconv.Quote(v)
+}
@@ -0,0 +1,552 @@
+// Package settings loads the app's settings: the protocols' config files,
+// default ports and users, the MSYS2 root, preflight defaults, how long
+// statuses stay up, the theme, the reachability monitor, how sessions are
+// launched and the external secret command.
+//
+// Settings are layered: built-in defaults, then the settings file
+// (settings.toml in the user config dir), then BTMS_* environment variables,
+// then --set flags. Apply pushes the effective settings to the packages that
+// use them.
+//
+//	[preflight]
+//	timeout = "20s"
+//	retries = 3
+//
+//	[ssh]
+//	user = "admin"
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/launch"
+	"bubbletea-ssh-manager/internal/secret"
+	str "bubbletea-ssh-manager/internal/stringutil"
+)
+
+// DefaultStatusTTL is how long info and success statuses stay up by default.
+const DefaultStatusTTL = 10 * time.Second
+
+// Themes are the theme names ui.theme accepts.
+const (
+	ThemeDefault = "default" // colors for dark terminals
+	ThemeLight   = "light"   // darker colors for light terminals
+)
+
+// Source is where a setting's effective value came from.
+type Source string
+
+const (
+	SourceDefault Source = "default" // built in
+	SourceFile    Source = "file"    // the settings file
+	SourceEnv     Source = "env"     // a BTMS_* environment variable
+	SourceFlag    Source = "flag"    // a --set flag
+)
+
+// Settings are the effective settings, and where each came from.
+type Settings struct {
+	MSYS2Root        string                                      // where MSYS2's tools are looked for on Windows ("" for PATH only)
+	PreflightTimeout time.Duration                               // per-attempt preflight timeout
+	PreflightRetries int                                         // preflight retries after a retryable failure
+	StatusTTL        time.Duration                               // how long info and success statuses stay up
+	Theme            string                                      // ThemeDefault or ThemeLight
+	Monitor          bool                                        // start the reachability monitor with the menu
+	Launcher         string                                      // launch backend (see launch.Parse; "" or inplace for in place)
+	TerminalCommand  string                                      // command template for the terminal launcher
+	SecretCommand    string                                      // external secret command line ("" for none)
+	Protocols        map[config.Protocol]config.ProtocolDefaults // config file, default port and user per protocol
+	Inventories      []config.Inventory                          // config files read besides the protocols' own
+
+	path    string            // settings file
+	file    map[string]string // values from the settings file, by key
+	sources map[string]Source // where each key's value came from (missing is SourceDefault)
+}
+
+// Kind is the type of a setting's value in the settings file.
+type Kind int
+
+const (
+	KindString Kind = iota // a string
+	KindInt                // an integer
+	KindList               // a list of strings, separated by ListSep outside the file
+	KindBool               // true or false; on or off outside the file
+)
+
+// ListSep separates the values of a list setting in environment variables,
+// --set flags and the settings screen.
+const ListSep = ";"
+
+// Key is one setting, named "section.name" (eg. "preflight.timeout").
+type Key struct {
+	Name    string   // section.name
+	Usage   string   // one-line description
+	Kind    Kind     // value type in the file
+	Choices []string // allowed values (nil for any)
+
+	env string // environment variable, if not the one named after the key
+	get func(s *Settings) string
+	set func(s *Settings, v string) error // parses and validates v
+}
+
+// Section returns the key's section (eg. "preflight").
+func (k Key) Section() string {
+	section, _, _ := strings.Cut(k.Name, ".")
+	return section
+}
+
+// Env returns the environment variable that overrides the key
+// (eg. BTMS_PREFLIGHT_TIMEOUT).
+func (k Key) Env() string {
+	if k.env != "" {
+		return k.env
+	}
+	return "BTMS_" + strings.ToUpper(strings.ReplaceAll(k.Name, ".", "_"))
+}
+
+// Validate reports whether v is a valid value for the key.
+func (k Key) Validate(v string) error {
+	s := Default()
+	return k.set(&s, v)
+}
+
+// builtinProtocols is the protocol registry as built in, before the
+// settings change its defaults.
+var builtinProtocols = config.Protocols()
+
+// Keys returns the settings, in the order the settings file lists them.
+func Keys() []Key {
+	keys := []Key{
+		{
+			Name:  "paths.msys2_root",
+			Usage: "where MSYS2 is installed; its OpenSSH tools are preferred on Windows (empty for PATH only)",
+			get:   func(s *Settings) string { return s.MSYS2Root },
+			set: func(s *Settings, v string) error {
+				s.MSYS2Root = strings.TrimSpace(v)
+				return nil
+			},
+		},
+		{
+			Name:  "paths.inventories",
+			Usage: "more host configs, as [NAME=][ro:][PROTOCOL:]PATH (eg. team=ro:~/team-hosts/ssh_config)",
+			Kind:  KindList,
+			get: func(s *Settings) string {
+				specs := make([]string, 0, len(s.Inventories))
+				for _, inv := range s.Inventories {
+					specs = append(specs, inv.String())
+				}
+				return strings.Join(specs, ListSep)
+			},
+			set: func(s *Settings, v string) error {
+				var invs []config.Inventory
+				for _, spec := range splitList(v) {
+					inv, err := config.ParseInventory(spec)
+					if err != nil {
+						return err
+					}
+					if slices.ContainsFunc(invs, func(o config.Inventory) bool { return o.Name == inv.Name }) {
+						return fmt.Errorf("two inventories are named %q; name one with NAME=PATH", inv.Name)
+					}
+					invs = append(invs, inv)
+				}
+				s.Inventories = invs
+				return nil
+			},
+		},
+		{
+			Name:  "preflight.timeout",
+			Usage: "how long each reachability check may take (eg. 10s)",
+			get:   func(s *Settings) string { return s.PreflightTimeout.String() },
+			set: func(s *Settings, v string) error {
+				d, err := connect.ParsePreflightTimeout(v)
+				if err == nil && d == 0 {
+					err = errors.New("timeout must be set")
+				}
+				if err != nil {
+					return err
+				}
+				s.PreflightTimeout = d
+				return nil
+			},
+		},
+		{
+			Name:  "preflight.retries",
+			Usage: "how often a check that timed out is retried",
+			Kind:  KindInt,
+			get:   func(s *Settings) string { return strconv.Itoa(s.PreflightRetries) },
+			set: func(s *Settings, v string) error {
+				n, err := connect.ParsePreflightRetries(v)
+				if err == nil && n < 0 {
+					err = errors.New("retries must be set")
+				}
+				if err != nil {
+					return err
+				}
+				s.PreflightRetries = n
+				return nil
+			},
+		},
+		{
+			Name:  "ui.status_ttl",
+			Usage: "how long info and success messages stay in the status line (eg. 10s)",
+			get:   func(s *Settings) string { return s.StatusTTL.String() },
+			set: func(s *Settings, v string) error {
+				d, err := parseDuration(v)
+				if err != nil {
+					return err
+				}
+				s.StatusTTL = d
+				return nil
+			},
+		},
+		{
+			Name:    "ui.theme",
+			Usage:   "color theme",
+			Choices: []string{ThemeDefault, ThemeLight},
+			get:     func(s *Settings) string { return s.Theme },
+			set: func(s *Settings, v string) error {
+				v = strings.ToLower(strings.TrimSpace(v))
+				if !slices.Contains([]string{ThemeDefault, ThemeLight}, v) {
+					return fmt.Errorf("unknown theme %q (one of %s, %s)", v, ThemeDefault, ThemeLight)
+				}
+				s.Theme = v
+				return nil
+			},
+		},
+		{
+			Name:    "ui.monitor",
+			Usage:   "start the reachability monitor with the menu",
+			Kind:    KindBool,
+			Choices: []string{"off", "on"},
+			env:     "BTMS_MONITOR",
+			get:     func(s *Settings) string { return onOff(s.Monitor) },
+			set: func(s *Settings, v string) error {
+				on, err := parseOnOff(v)
+				if err != nil {
+					return err
+				}
+				s.Monitor = on
+				return nil
+			},
+		},
+		{
+			Name:    "launch.launcher",
+			Usage:   "where sessions opened in a new tab go (inplace runs them in the menu's terminal)",
+			Choices: launch.Backends,
+			env:     launch.EnvLauncher,
+			get:     func(s *Settings) string { return s.Launcher },
+			set: func(s *Settings, v string) error {
+				v = strings.ToLower(strings.TrimSpace(v))
+				if v == "" {
+					v = launch.BackendInPlace
+				}
+				if !slices.Contains(launch.Backends, v) {
+					return fmt.Errorf("unknown launcher %q (one of %s)", v, strings.Join(launch.Backends, ", "))
+				}
+				s.Launcher = v
+				return nil
+			},
+		},
+		{
+			Name:  "launch.terminal_cmd",
+			Usage: "command that opens a terminal tab for the terminal launcher (eg. wezterm cli spawn -- {cmd})",
+			env:   launch.EnvTerminalCommand,
+			get:   func(s *Settings) string { return s.TerminalCommand },
+			set: func(s *Settings, v string) error {
+				v = strings.TrimSpace(v)
+				if v != "" {
+					if _, err := launch.NewTerminal(v); err != nil {
+						return err
+					}
+				}
+				s.TerminalCommand = v
+				return nil
+			},
+		},
+		{
+			Name:  "secrets.command",
+			Usage: "command that prints a secret, tried after the vault; {name} is the secret's name (eg. pass show btms/{name})",
+			env:   secret.CommandEnv,
+			get:   func(s *Settings) string { return s.SecretCommand },
+			set: func(s *Settings, v string) error {
+				v = strings.TrimSpace(v)
+				if err := secret.ValidateCommand(v); err != nil {
+					return err
+				}
+				s.SecretCommand = v
+				return nil
+			},
+		},
+	}
+	for _, info := range builtinProtocols {
+		keys = append(keys, protocolKeys(info)...)
+	}
+	return keys
+}
+
+// protocolKeys returns the settings for one protocol: its config file, and
+// its default port and user if it has them.
+func protocolKeys(info config.ProtocolInfo) []Key {
+	p := info.Name
+	keys := []Key{{
+		Name:  string(p) + ".config",
+		Usage: "the " + string(p) + " hosts' config file",
+		get:   func(s *Settings) string { return s.Protocols[p].ConfigFile },
+		set: func(s *Settings, v string) error {
+			v = strings.TrimSpace(v)
+			if v != "~" && !strings.HasPrefix(v, "~/") && !filepath.IsAbs(v) {
+				return fmt.Errorf("config file must be an absolute path or start with ~/")
+			}
+			d := s.Protocols[p]
+			d.ConfigFile = v
+			s.Protocols[p] = d
+			return nil
+		},
+	}}
+	if info.DefaultPort != "" {
+		keys = append(keys, Key{
+			Name:  string(p) + ".port",
+			Usage: "port for " + string(p) + " hosts that don't set one",
+			Kind:  KindInt,
+			get:   func(s *Settings) string { return s.Protocols[p].Port },
+			set: func(s *Settings, v string) error {
+				if strings.TrimSpace(v) == "" {
+					return errors.New("port must be set")
+				}
+				port, err := str.NormalizePort(v, p)
+				if err != nil {
+					return err
+				}
+				d := s.Protocols[p]
+				d.Port = port
+				s.Protocols[p] = d
+				return nil
+			},
+		})
+	}
+	if info.Has(config.FieldUser) {
+		keys = append(keys, Key{
+			Name:  string(p) + ".user",
+			Usage: "user for " + string(p) + " hosts that don't set one (empty leaves it to the client)",
+			get:   func(s *Settings) string { return s.Protocols[p].User },
+			set: func(s *Settings, v string) error {
+				v = strings.TrimSpace(v)
+				if strings.ContainsAny(v, " \t") {
+					return errors.New("user can't contain spaces")
+				}
+				d := s.Protocols[p]
+				d.User = v
+				s.Protocols[p] = d
+				return nil
+			},
+		})
+	}
+	return keys
+}
+
+// LookupKey returns the setting named name.
+func LookupKey(name string) (Key, bool) {
+	keys := Keys()
+	i := slices.IndexFunc(keys, func(k Key) bool { return k.Name == name })
+	if i < 0 {
+		return Key{}, false
+	}
+	return keys[i], true
+}
+
+// Default returns the built-in settings.
+func Default() Settings {
+	s := Settings{
+		MSYS2Root:        connect.DefaultMSYS2Root,
+		PreflightTimeout: connect.DefaultPreflightTimeout,
+		PreflightRetries: connect.DefaultPreflightRetries,
+		StatusTTL:        DefaultStatusTTL,
+		Theme:            ThemeDefault,
+		Launcher:         launch.BackendInPlace,
+		Protocols:        map[config.Protocol]config.ProtocolDefaults{},
+		file:             map[string]string{},
+		sources:          map[string]Source{},
+	}
+	for _, info := range builtinProtocols {
+		s.Protocols[info.Name] = config.ProtocolDefaults{
+			ConfigFile: "~/" + strings.Join(info.ConfigPath, "/"),
+			Port:       info.DefaultPort,
+			User:       info.DefaultUser,
+		}
+	}
+	return s
+}
+
+// Load returns the settings from the file at path ("" for Path), the
+// environment and overrides ("key=value", or "key+=value" to add to a list,
+// from --set flags), on top of the built-in defaults. A missing file is fine.
+//
+// Invalid values are reported and keep the value from the layer below, so
+// the settings are always usable.
+func Load(path string, overrides []string) (Settings, error) {
+	s := Default()
+	var errs []error
+	if path == "" {
+		p, err := Path()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		path = p
+	}
+	s.path = path
+
+	if path != "" {
+		file, err := readFile(path)
+		errs = append(errs, err)
+		for _, k := range Keys() {
+			v, ok := file[k.Name]
+			if !ok {
+				continue
+			}
+			s.file[k.Name] = v
+			errs = append(errs, s.set(k, v, SourceFile, filepath.Base(path)))
+		}
+	}
+
+	for _, k := range Keys() {
+		if v, ok := os.LookupEnv(k.Env()); ok && strings.TrimSpace(v) != "" {
+			errs = append(errs, s.set(k, v, SourceEnv, k.Env()))
+		}
+	}
+
+	for _, o := range overrides {
+		name, v, ok := strings.Cut(o, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		name, add := strings.CutSuffix(name, "+")
+		k, known := LookupKey(name)
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("--set %s: expected KEY=VALUE", o))
+		case !known:
+			errs = append(errs, fmt.Errorf("--set %s: unknown setting %q", o, name))
+		case add && k.Kind != KindList:
+			errs = append(errs, fmt.Errorf("--set %s: only list settings can be added to with +=", o))
+		default:
+			if cur := s.Get(k); add && cur != "" {
+				v = cur + ListSep + v
+			}
+			errs = append(errs, s.set(k, v, SourceFlag, "--set"))
+		}
+	}
+	return s, errors.Join(errs...)
+}
+
+// set applies v to k from source, recording where it came from; where
+// names the source in errors.
+func (s *Settings) set(k Key, v string, source Source, where string) error {
+	if err := k.set(s, v); err != nil {
+		return fmt.Errorf("%s: %s: %w", where, k.Name, err)
+	}
+	s.sources[k.Name] = source
+	return nil
+}
+
+// splitList returns the values of a list setting, without blanks.
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ListSep) {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// onOff returns "on" or "off".
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// parseOnOff parses a yes/no value: on, yes, true or 1, or off, no, false or 0.
+func parseOnOff(v string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "on", "yes", "true", "1":
+		return true, nil
+	case "off", "no", "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q isn't on or off", v)
+}
+
+// parseDuration parses a duration like "10s" or "1m"; a bare number is
+// seconds. It must be positive.
+func parseDuration(v string) (time.Duration, error) {
+	v = strings.TrimSpace(v)
+	if n, err := strconv.Atoi(v); err == nil {
+		v = strconv.Itoa(n) + "s"
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q isn't a duration like 10s or 1m", v)
+	}
+	return d, nil
+}
+
+// Path returns the settings file the menu reads: $BTMS_SETTINGS if set,
+// otherwise btms/settings.toml in the user config dir ($XDG_CONFIG_HOME or
+// ~/.config; %AppData% on Windows).
+func Path() (string, error) {
+	if p := strings.TrimSpace(os.Getenv(EnvPath)); p != "" {
+		return config.ExpandPath(p)
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("settings: %w", err)
+	}
+	return filepath.Join(dir, config.AppName, FileName), nil
+}
+
+// Path returns the settings file the settings were loaded from.
+func (s Settings) Path() string {
+	return s.path
+}
+
+// Get returns a key's effective value.
+func (s Settings) Get(k Key) string {
+	return k.get(&s)
+}
+
+// Source returns where a key's effective value came from.
+func (s Settings) Source(k Key) Source {
+	if src, ok := s.sources[k.Name]; ok {
+		return src
+	}
+	return SourceDefault
+}
+
+// IsDefault reports whether a key's effective value is the built-in one.
+func (s Settings) IsDefault(k Key) bool {
+	d := Default()
+	return k.get(&d) == k.get(&s)
+}
+
+// Apply makes the settings take effect in the packages that use them. The
+// TUI reads its own (status TTL, theme, monitor, launcher) from the settings
+// it's given.
+func Apply(s Settings) error {
+	var errs []error
+	for p, d := range s.Protocols {
+		errs = append(errs, config.SetProtocolDefaults(p, d))
+	}
+	config.SetInventories(s.Inventories)
+	connect.SetMSYS2Root(s.MSYS2Root)
+	connect.SetPreflightDefaults(s.PreflightTimeout, s.PreflightRetries)
+	secret.SetCommand(s.SecretCommand)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadLaunchMonitorSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	file := "[ui]\nmonitor = true\n\n[launch]\nlauncher = 'terminal'\nterminal_cmd = 'kitty @ launch {cmd}'\n"
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BTMS_LAUNCHER", "tmux")
+	t.Setenv("BTMS_SECRET_COMMAND", "")
+
+	s, err := Load(path, []string{"secrets.command=pass show btms/{name}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Monitor || s.TerminalCommand != "kitty @ launch {cmd}" {
+		t.Errorf("from the file: monitor %v, terminal_cmd %q", s.Monitor, s.TerminalCommand)
+	}
+	if s.Launcher != "tmux" {
+		t.Errorf("launcher = %q, want BTMS_LAUNCHER's tmux", s.Launcher)
+	}
+	if s.SecretCommand != "pass show btms/{name}" {
+		t.Errorf("secret command = %q, want the --set one", s.SecretCommand)
+	}
+
+	k, _ := LookupKey("launch.launcher")
+	if k.Env() != "BTMS_LAUNCHER" || s.Source(k) != SourceEnv {
+		t.Errorf("launch.launcher: env %s, source %s", k.Env(), s.Source(k))
+	}
+	k, _ = LookupKey("ui.monitor")
+	if got := formatValue(k, s.Get(k)); got != "true" {
+		t.Errorf("ui.monitor formats as %s, want true", got)
+	}
+}
+
+func TestLoadInvalidLaunchSecrets(t *testing.T) {
+	tests := []struct {
+		set, want string
+	}{
+		{"ui.monitor=maybe", "isn't on or off"},
+		{"launch.launcher=xterm", "unknown launcher"},
+		{"launch.terminal_cmd=wt.exe 'new-tab", "terminal command"},
+		{"secrets.command=pass show 'btms", "secret command"},
+	}
+	for _, tt := range tests {
+		_, err := Load(filepath.Join(t.TempDir(), FileName), []string{tt.set})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("--set %s: err = %v, want %q", tt.set, err, tt.want)
+		}
+	}
+}
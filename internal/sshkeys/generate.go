@@ -0,0 +1,147 @@
+package sshkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// KeyType is a type of key Generate can create.
+type KeyType string
+
+const (
+	ED25519 KeyType = "ed25519"
+	RSA     KeyType = "rsa"
+)
+
+// DefaultRSABits is the RSA key size used when none is given.
+const DefaultRSABits = 4096
+
+// GenerateOptions describes a key pair to create.
+type GenerateOptions struct {
+	Type       KeyType
+	Bits       int    // RSA only; 0 uses DefaultRSABits
+	Path       string // private key file; the public key gets ".pub" added
+	Comment    string // eg. "me@laptop"
+	Passphrase string // "" leaves the private key unencrypted
+}
+
+// DefaultFileName returns the usual file name for a key type (eg. "id_ed25519").
+func DefaultFileName(t KeyType) string {
+	return "id_" + string(t)
+}
+
+// DefaultComment returns the comment ssh-keygen uses (eg. "me@laptop").
+func DefaultComment() string {
+	name := "user"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		// Windows usernames are DOMAIN\user
+		name = u.Username[strings.LastIndexByte(u.Username, '\\')+1:]
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return name
+	}
+	return name + "@" + host
+}
+
+// Generate creates a key pair in OpenSSH format, like ssh-keygen. Existing
+// files are never overwritten.
+func Generate(o GenerateOptions) (Key, error) {
+	if strings.TrimSpace(o.Path) == "" {
+		return Key{}, errors.New("key file is empty")
+	}
+	for _, p := range []string{o.Path, o.Path + ".pub"} {
+		if _, err := os.Stat(p); err == nil {
+			return Key{}, fmt.Errorf("%s already exists", p)
+		}
+	}
+
+	var priv crypto.PrivateKey
+	var pub crypto.PublicKey
+	switch o.Type {
+	case ED25519:
+		pk, sk, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return Key{}, err
+		}
+		priv, pub = sk, pk
+	case RSA:
+		bits := o.Bits
+		if bits == 0 {
+			bits = DefaultRSABits
+		}
+		if bits < 2048 {
+			return Key{}, fmt.Errorf("RSA keys need at least 2048 bits, not %d", bits)
+		}
+		sk, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return Key{}, err
+		}
+		priv, pub = sk, &sk.PublicKey
+	default:
+		return Key{}, fmt.Errorf("unsupported key type %q (ed25519 or rsa)", o.Type)
+	}
+
+	var block *pem.Block
+	var err error
+	if o.Passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, o.Comment, []byte(o.Passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(priv, o.Comment)
+	}
+	if err != nil {
+		return Key{}, err
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return Key{}, err
+	}
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub)))
+	if o.Comment != "" {
+		line += " " + o.Comment
+	}
+
+	if err := os.MkdirAll(filepath.Dir(o.Path), 0o700); err != nil {
+		return Key{}, err
+	}
+	if err := writeNew(o.Path, pem.EncodeToMemory(block), 0o600); err != nil {
+		return Key{}, err
+	}
+	if err := writeNew(o.Path+".pub", []byte(line+"\n"), 0o644); err != nil {
+		_ = os.Remove(o.Path)
+		return Key{}, err
+	}
+
+	k, err := parsePublicKey([]byte(line))
+	if err != nil {
+		return Key{}, err
+	}
+	k.Path = o.Path
+	k.Encrypted = o.Passphrase != ""
+	return k, nil
+}
+
+// writeNew writes data to a file that must not exist yet.
+func writeNew(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,118 @@
+package sshkeys
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       GenerateOptions
+		typ        string
+		bits       int
+		encrypted  bool
+		sshKeyType string
+	}{
+		{"ed25519", GenerateOptions{Type: ED25519, Comment: "me@laptop"}, "ED25519", 256, false, ssh.KeyAlgoED25519},
+		{"ed25519 with passphrase", GenerateOptions{Type: ED25519, Passphrase: "secret"}, "ED25519", 256, true, ssh.KeyAlgoED25519},
+		{"rsa", GenerateOptions{Type: RSA, Bits: 2048, Comment: "ci"}, "RSA", 2048, false, ssh.KeyAlgoRSA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), ".ssh")
+			tt.opts.Path = filepath.Join(dir, "id_test")
+			k, err := Generate(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if k.Path != tt.opts.Path || k.Type != tt.typ || k.Bits != tt.bits || k.Encrypted != tt.encrypted ||
+				k.Comment != tt.opts.Comment {
+				t.Errorf("Generate = %+v", k)
+			}
+
+			// the files read back as the same key
+			keys, err := ListDir(dir)
+			if err != nil || len(keys) != 1 {
+				t.Fatalf("ListDir = %+v, %v", keys, err)
+			}
+			if keys[0] != k {
+				t.Errorf("ListDir = %+v\nwant %+v", keys[0], k)
+			}
+			priv, err := os.ReadFile(k.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var signer ssh.Signer
+			if tt.encrypted {
+				signer, err = ssh.ParsePrivateKeyWithPassphrase(priv, []byte(tt.opts.Passphrase))
+			} else {
+				signer, err = ssh.ParsePrivateKey(priv)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signer.PublicKey().Type() != tt.sshKeyType || ssh.FingerprintSHA256(signer.PublicKey()) != k.Fingerprint {
+				t.Errorf("private key is %s %s, want %s %s", signer.PublicKey().Type(),
+					ssh.FingerprintSHA256(signer.PublicKey()), tt.sshKeyType, k.Fingerprint)
+			}
+
+			if runtime.GOOS != "windows" {
+				for path, perm := range map[string]os.FileMode{k.Path: 0o600, k.Path + ".pub": 0o644, dir: 0o700} {
+					if st, err := os.Stat(path); err != nil || st.Mode().Perm() != perm {
+						t.Errorf("%s: mode %v, %v; want %v", path, st.Mode().Perm(), err, perm)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		opts GenerateOptions
+		want string
+	}{
+		{"no path", GenerateOptions{Type: ED25519, Path: " "}, "key file is empty"},
+		{"unknown type", GenerateOptions{Type: "dsa", Path: filepath.Join(dir, "id_dsa")}, "unsupported key type"},
+		{"small rsa", GenerateOptions{Type: RSA, Bits: 1024, Path: filepath.Join(dir, "id_rsa")}, "at least 2048 bits"},
+	}
+	for _, tt := range tests {
+		if _, err := Generate(tt.opts); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestGenerateNeverOverwrites(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(path+".pub", []byte("old public key\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Generate(GenerateOptions{Type: ED25519, Path: path}); err == nil {
+		t.Error("Generate overwrote an existing public key")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("private key written next to an existing public key: %v", err)
+	}
+
+	// the files are created with O_EXCL, even if one appears after the check
+	if err := os.WriteFile(path, []byte("old private key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeNew(path, []byte("new"), 0o600); !errors.Is(err, os.ErrExist) {
+		t.Errorf("writeNew over an existing file = %v, want ErrExist", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "old private key\n" {
+		t.Errorf("existing key changed to %q", b)
+	}
+}
@@ -0,0 +1,47 @@
+package sshkeys
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/connect"
+)
+
+// installScript appends the public key read from stdin to the remote
+// authorized_keys, unless it's already there, like ssh-copy-id. It runs
+// under sh whatever the login shell is, so it can't contain single quotes.
+const installScript = `umask 077; mkdir -p .ssh && touch .ssh/authorized_keys && k=$(cat) && ` +
+	`if grep -qxF "$k" .ssh/authorized_keys; then echo "key already installed"; else ` +
+	`if [ -s .ssh/authorized_keys ] && [ -n "$(tail -c1 .ssh/authorized_keys)" ]; then echo >> .ssh/authorized_keys; fi; ` +
+	`printf "%s\n" "$k" >> .ssh/authorized_keys && echo "key installed"; fi`
+
+// InstallCommand returns an ssh command that installs the public key on the
+// host alias. The key is sent on ssh's stdin, so ssh still asks for a
+// password on the terminal if it needs one. The caller sets the output.
+func InstallCommand(alias string, k Key) (*exec.Cmd, error) {
+	if k.PublicKey == "" {
+		return nil, errors.New("no public key")
+	}
+	prog, err := connect.ProgramPath("ssh")
+	if err != nil {
+		return nil, err
+	}
+	args := append(connect.SSHConfigArgs(alias), alias, "exec sh -c '"+installScript+"'")
+	cmd := exec.Command(prog, args...)
+	cmd.Stdin = strings.NewReader(k.PublicKey + "\n")
+	return cmd, nil
+}
+
+// AddToAgentCommand returns the ssh-add command that loads the key's
+// private file into the agent. It asks for the passphrase on the terminal.
+func AddToAgentCommand(k Key) (*exec.Cmd, error) {
+	if k.Path == "" {
+		return nil, errors.New("the key has no private key file")
+	}
+	prog, err := connect.ProgramPath("ssh-add")
+	if err != nil {
+		return nil, err
+	}
+	return exec.Command(prog, k.Path), nil
+}
@@ -0,0 +1,98 @@
+package sshkeys
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestInstallCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs an ssh on PATH without .exe")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ssh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	k := Key{PublicKey: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl me@laptop"}
+	cmd, err := InstallCommand("web1", k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(bin, "ssh"), "web1", `exec sh -c '` +
+		`umask 077; mkdir -p .ssh && touch .ssh/authorized_keys && k=$(cat) && ` +
+		`if grep -qxF "$k" .ssh/authorized_keys; then echo "key already installed"; else ` +
+		`if [ -s .ssh/authorized_keys ] && [ -n "$(tail -c1 .ssh/authorized_keys)" ]; then echo >> .ssh/authorized_keys; fi; ` +
+		`printf "%s\n" "$k" >> .ssh/authorized_keys && echo "key installed"; fi'`}
+	if strings.Join(cmd.Args, "\x00") != strings.Join(want, "\x00") {
+		t.Errorf("InstallCommand args =\n%q\nwant\n%q", cmd.Args, want)
+	}
+	stdin, _ := io.ReadAll(cmd.Stdin)
+	if string(stdin) != k.PublicKey+"\n" {
+		t.Errorf("stdin = %q, want the public key line", stdin)
+	}
+
+	if _, err := InstallCommand("web1", Key{}); err == nil {
+		t.Error("InstallCommand without a public key succeeded")
+	}
+}
+
+// TestInstallScript runs the remote script in a fake home directory.
+func TestInstallScript(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	if strings.Contains(installScript, "'") {
+		t.Fatal("installScript has a single quote, which ends the sh -c argument")
+	}
+	const key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl me@laptop"
+	home := t.TempDir()
+	install := func(k string) string {
+		t.Helper()
+		cmd := exec.Command(sh, "-c", installScript)
+		cmd.Dir = home
+		cmd.Stdin = strings.NewReader(k + "\n")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("install: %v: %s", err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	authorized := filepath.Join(home, ".ssh", "authorized_keys")
+	read := func() string {
+		t.Helper()
+		b, err := os.ReadFile(authorized)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	if out := install(key); out != "key installed" || read() != key+"\n" {
+		t.Errorf("first install: %q, authorized_keys %q", out, read())
+	}
+	if runtime.GOOS != "windows" {
+		if st, err := os.Stat(filepath.Dir(authorized)); err != nil || st.Mode().Perm() != 0o700 {
+			t.Errorf(".ssh mode = %v, %v; want 0700", st.Mode().Perm(), err)
+		}
+	}
+	if out := install(key); out != "key already installed" || read() != key+"\n" {
+		t.Errorf("second install: %q, authorized_keys %q", out, read())
+	}
+
+	// a file without a final newline gets one before the new key, and a
+	// key that's only a prefix of another line still counts as new
+	if err := os.WriteFile(authorized, []byte(key+" extra"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if out := install(key); out != "key installed" || read() != key+" extra\n"+key+"\n" {
+		t.Errorf("install after a partial line: %q, authorized_keys %q", out, read())
+	}
+}
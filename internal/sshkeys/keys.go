@@ -0,0 +1,212 @@
+// Package sshkeys lists, generates and installs ssh keys: the key pairs in
+// ~/.ssh and the keys loaded in the ssh agent.
+//
+// Agent and install operations run the OpenSSH tools (ssh-add, ssh), found
+// the same way as the ssh client, so they work wherever ssh does.
+package sshkeys
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	str "bubbletea-ssh-manager/internal/stringutil"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultIdentities are the key files ssh tries when a host has no
+// IdentityFile, in ssh's order.
+var DefaultIdentities = []string{"id_rsa", "id_ecdsa", "id_ecdsa_sk", "id_ed25519", "id_ed25519_sk"}
+
+// Key is an ssh key from ~/.ssh, the agent, or both.
+type Key struct {
+	Path        string // private key file ("" for a key only in the agent)
+	Type        string // key type (eg. "ED25519", "RSA")
+	Bits        int    // key size
+	Fingerprint string // SHA256 fingerprint (eg. "SHA256:...")
+	Comment     string // comment from the public key or agent
+	InAgent     bool   // the agent has the key loaded
+	Encrypted   bool   // the private key file has a passphrase
+	PublicKey   string // authorized_keys line (type, key and comment)
+}
+
+// Name returns the key's file name, or its comment for agent-only keys.
+func (k Key) Name() string {
+	if k.Path != "" {
+		return filepath.Base(k.Path)
+	}
+	return k.Comment
+}
+
+// IsDefault reports whether the key is one ssh tries for hosts without an
+// IdentityFile.
+func (k Key) IsDefault() bool {
+	return k.Path != "" && slices.Contains(DefaultIdentities, filepath.Base(k.Path))
+}
+
+// UsedBy reports whether a host with the given IdentityFile values names
+// the key. Hosts without an IdentityFile use the default identities (see
+// IsDefault) and aren't counted.
+func (k Key) UsedBy(identityFiles []string) bool {
+	if k.Path == "" {
+		return false
+	}
+	for _, f := range identityFiles {
+		p, err := config.ExpandPath(strings.Trim(f, `"`))
+		if err != nil {
+			continue
+		}
+		if filepath.Clean(strings.TrimSuffix(p, ".pub")) == filepath.Clean(k.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dir returns the ~/.ssh directory.
+func Dir() (string, error) {
+	return config.GetConfigPath(".ssh")
+}
+
+// ListDir returns the key pairs in dir: every *.pub file, with the private
+// key next to it.
+func ListDir(dir string) ([]Key, error) {
+	pubs, err := filepath.Glob(filepath.Join(dir, "*.pub"))
+	if err != nil {
+		return nil, err
+	}
+	var keys []Key
+	for _, pub := range pubs {
+		b, err := os.ReadFile(pub)
+		if err != nil {
+			continue
+		}
+		k, err := parsePublicKey(b)
+		if err != nil {
+			continue // eg. a host key or something that isn't a key
+		}
+		k.Path = strings.TrimSuffix(pub, ".pub")
+		k.Encrypted = isEncrypted(k.Path)
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// ErrNoAgent is returned when there is no ssh agent to talk to.
+var ErrNoAgent = errors.New("no ssh agent running (SSH_AUTH_SOCK isn't set or the agent isn't reachable)")
+
+// ListAgent returns the keys loaded in the ssh agent, using ssh-add -L.
+func ListAgent() ([]Key, error) {
+	prog, err := connect.ProgramPath("ssh-add")
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(prog, "-L")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exit *exec.ExitError
+		switch {
+		case errors.As(err, &exit) && exit.ExitCode() == 1:
+			return nil, nil // the agent has no identities
+		case errors.As(err, &exit) && exit.ExitCode() == 2:
+			return nil, ErrNoAgent
+		}
+		if line := str.LastNonEmptyLine(stderr.String()); line != "" {
+			return nil, fmt.Errorf("ssh-add -L: %w: %s", err, line)
+		}
+		return nil, fmt.Errorf("ssh-add -L: %w", err)
+	}
+
+	var keys []Key
+	for _, line := range strings.Split(string(out), "\n") {
+		k, err := parsePublicKey([]byte(line))
+		if err != nil {
+			continue
+		}
+		k.InAgent = true
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Merge combines the keys from ~/.ssh and the agent: files whose key is
+// loaded are marked InAgent, and agent keys without a file are added at
+// the end.
+func Merge(files, agent []Key) []Key {
+	out := slices.Clone(files)
+	for _, a := range agent {
+		i := slices.IndexFunc(out, func(k Key) bool { return k.Fingerprint == a.Fingerprint })
+		if i >= 0 {
+			out[i].InAgent = true
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// parsePublicKey parses an authorized_keys style line.
+func parsePublicKey(line []byte) (Key, error) {
+	pub, comment, _, _, err := ssh.ParseAuthorizedKey(line)
+	if err != nil {
+		return Key{}, err
+	}
+	typ, bits := keyTypeBits(pub)
+	text := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	if comment != "" {
+		text += " " + comment
+	}
+	return Key{
+		Type:        typ,
+		Bits:        bits,
+		Fingerprint: ssh.FingerprintSHA256(pub),
+		Comment:     comment,
+		PublicKey:   text,
+	}, nil
+}
+
+// keyTypeBits returns the display type and size of a public key.
+func keyTypeBits(pub ssh.PublicKey) (string, int) {
+	switch pub.Type() {
+	case ssh.KeyAlgoED25519:
+		return "ED25519", 256
+	case ssh.KeyAlgoSKED25519:
+		return "ED25519-SK", 256
+	case ssh.KeyAlgoSKECDSA256:
+		return "ECDSA-SK", 256
+	case ssh.KeyAlgoDSA:
+		return "DSA", 1024
+	}
+	if cpk, ok := pub.(ssh.CryptoPublicKey); ok {
+		switch k := cpk.CryptoPublicKey().(type) {
+		case *rsa.PublicKey:
+			return "RSA", k.N.BitLen()
+		case *ecdsa.PublicKey:
+			return "ECDSA", k.Curve.Params().BitSize
+		}
+	}
+	return strings.ToUpper(pub.Type()), 0
+}
+
+// isEncrypted reports whether the private key at path needs a passphrase.
+func isEncrypted(path string) bool {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	_, err = ssh.ParseRawPrivateKey(b)
+	var missing *ssh.PassphraseMissingError
+	return errors.As(err, &missing)
+}
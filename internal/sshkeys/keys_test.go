@@ -0,0 +1,70 @@
+package sshkeys
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestUsedBy(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	k := Key{Path: filepath.Join(home, ".ssh", "id_work")}
+
+	tests := []struct {
+		files []string
+		want  bool
+	}{
+		{[]string{"~/.ssh/id_work"}, true},
+		{[]string{`"~/.ssh/id_work"`}, true},
+		{[]string{"~/.ssh/id_work.pub"}, true},
+		{[]string{"~/.ssh/../.ssh/id_work"}, true},
+		{[]string{filepath.Join(home, ".ssh", "id_work")}, true},
+		{[]string{"~/.ssh/id_ed25519", "~/.ssh/id_work"}, true},
+		{[]string{"~/.ssh/id_work2"}, false},
+		{[]string{".ssh/id_work"}, false}, // relative to ssh's working directory, not home
+		{[]string{""}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := k.UsedBy(tt.files); got != tt.want {
+			t.Errorf("UsedBy(%q) = %v, want %v", tt.files, got, tt.want)
+		}
+	}
+	if (Key{Comment: "agent only"}).UsedBy([]string{"agent only"}) {
+		t.Error("an agent-only key is used by a host")
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	line := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl  me@laptop work\n"
+	k, err := parsePublicKey([]byte(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Key{
+		Type:        "ED25519",
+		Bits:        256,
+		Fingerprint: "SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU",
+		Comment:     "me@laptop work",
+		PublicKey:   "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl me@laptop work",
+	}
+	if k != want {
+		t.Errorf("parsePublicKey =\n%+v\nwant\n%+v", k, want)
+	}
+	if _, err := parsePublicKey([]byte("not a key")); err == nil {
+		t.Error("parsed a line that isn't a key")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	files := []Key{{Path: "/h/.ssh/id_a", Fingerprint: "A"}, {Path: "/h/.ssh/id_b", Fingerprint: "B"}}
+	agent := []Key{{Fingerprint: "B", InAgent: true}, {Fingerprint: "C", Comment: "yubikey", InAgent: true}}
+	got := Merge(files, agent)
+	if len(got) != 3 || got[0].InAgent || !got[1].InAgent || got[1].Path == "" || got[2].Comment != "yubikey" {
+		t.Errorf("Merge = %+v", got)
+	}
+	if files[1].InAgent {
+		t.Error("Merge changed its input")
+	}
+}
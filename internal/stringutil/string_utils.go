@@ -17,18 +17,15 @@ func NormalizeString(s string) string {
 // NormalizePort returns a validated numeric port for a protocol.
 //
 // Behavior:
-//   - If port is empty, returns the default port for the protocol (ssh=22, telnet=23).
+//   - If port is empty, returns the protocol's default port (eg. ssh=22, telnet=23),
+//     or "" if it has none.
 //   - If port is numeric, validates it's within 1..65535 and returns it.
 func NormalizePort(port string, protocol config.Protocol) (string, error) {
 	port = strings.TrimSpace(port)
 
 	if port == "" {
-		switch protocol {
-		case config.ProtocolSSH:
-			return "22", nil
-		case config.ProtocolTelnet:
-			return "23", nil
-		}
+		info, _ := config.LookupProtocol(protocol)
+		return info.DefaultPort, nil
 	}
 
 	n, err := strconv.Atoi(port)
@@ -138,3 +135,40 @@ func FormatDisplayName(raw string, isGroup bool) string {
 	}
 	return strings.ToLower(s)
 }
+
+// FuzzyScore returns a simple subsequence match score for the menu search;
+// higher is better. "ok" is false if q is not a subsequence of s.
+func FuzzyScore(q, s string) (score int, ok bool) {
+	q = NormalizeString(q)
+	s = NormalizeString(s)
+
+	// empty query matches everything with score 0
+	if q == "" {
+		return 0, true
+	}
+
+	// check for subsequence and calculate score
+	qi := 0
+	streak := 0
+	for i := 0; i < len(s) && qi < len(q); i++ {
+		if s[i] == q[qi] {
+			qi++
+			streak++
+			score += 10 + (streak * 2) // reward contiguous runs
+		} else {
+			streak = 0
+		}
+	}
+
+	// if we didn't consume all of q, it's not a match
+	if qi != len(q) {
+		return 0, false
+	}
+
+	// small preference for earlier matches
+	if idx := strings.Index(s, string(q[0])); idx >= 0 {
+		score -= idx
+	}
+
+	return score, true
+}
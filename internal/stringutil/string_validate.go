@@ -38,15 +38,17 @@ func ValidateHostGroup(s string) error {
 
 // ValidateHostName checks if the given hostname is valid for the specified protocol.
 func ValidateHostName(protocol config.Protocol, s string) error {
-	s = strings.TrimSpace(s)
-	if protocol == config.ProtocolTelnet && s == "" {
-		return errors.New("hostname is required for telnet")
-	}
-	return nil
+	info, _ := config.LookupProtocol(protocol)
+	info.Required &= config.FieldHostName
+	return info.CheckRequired(config.HostEntry{Spec: config.Spec{HostName: s}})
 }
 
 // ValidateHostPort checks if the given port is valid for the specified protocol.
 func ValidateHostPort(protocol config.Protocol, s string) error {
-	_, err := NormalizePort(s, protocol)
-	return err
+	if _, err := NormalizePort(s, protocol); err != nil {
+		return err
+	}
+	info, _ := config.LookupProtocol(protocol)
+	info.Required &= config.FieldPort
+	return info.CheckRequired(config.HostEntry{Spec: config.Spec{Port: s}})
 }
@@ -0,0 +1,53 @@
+package termutil
+
+import (
+	"io"
+	"os"
+
+	"github.com/charmbracelet/x/term"
+)
+
+const (
+	defaultWidth  = 80 // fallback terminal width
+	defaultHeight = 24 // fallback terminal height
+)
+
+// MakeRaw puts r in raw mode if it is a terminal.
+//
+// It returns a function that restores the previous mode (a no-op if r is
+// not a terminal, eg. a pipe in tests).
+func MakeRaw(r io.Reader) (restore func()) {
+	f, ok := r.(*os.File)
+	if !ok || !term.IsTerminal(f.Fd()) {
+		return func() {}
+	}
+	state, err := term.MakeRaw(f.Fd())
+	if err != nil {
+		return func() {}
+	}
+	return func() { _ = term.Restore(f.Fd(), state) }
+}
+
+// Size returns the size of w if it is a terminal.
+//
+// Writers that wrap the terminal (eg. an io.MultiWriter teeing output to a
+// recording) fall back to the size of os.Stdout.
+func Size(w io.Writer) (width, height int, ok bool) {
+	f, isFile := w.(*os.File)
+	if !isFile {
+		f = os.Stdout
+	}
+	width, height, err := term.GetSize(f.Fd())
+	if err != nil || width <= 0 || height <= 0 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// SizeOrDefault returns the size of w, or 80x24 if it can't be determined.
+func SizeOrDefault(w io.Writer) (width, height int) {
+	if width, height, ok := Size(w); ok {
+		return width, height
+	}
+	return defaultWidth, defaultHeight
+}
@@ -0,0 +1,118 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Entry is one file or directory in a local or remote listing.
+type Entry struct {
+	Name     string
+	Dir      bool
+	Link     bool   // symbolic link (remote links may point at directories)
+	Size     int64  // bytes (0 for directories)
+	Mode     string // permissions as ls shows them (eg. "-rw-r--r--")
+	Modified string // modification time as shown by ls (remote) or "Jan 2 15:04" (local)
+}
+
+// sortEntries sorts directories first, then by name (case-insensitive).
+func sortEntries(entries []Entry) {
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		if a.Dir != b.Dir {
+			if a.Dir {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+}
+
+// ListLocal returns the entries of a local directory.
+//
+// Entries that can't be stat'ed (eg. dangling links) are listed with no size.
+func ListLocal(dir string) ([]Entry, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(des))
+	for _, de := range des {
+		e := Entry{Name: de.Name(), Dir: de.IsDir(), Link: de.Type()&os.ModeSymlink != 0}
+		info, err := os.Stat(filepath.Join(dir, de.Name())) // follow links
+		if err == nil {
+			e.Dir = info.IsDir()
+			e.Mode = info.Mode().String()
+			e.Modified = info.ModTime().Format("Jan 2 15:04")
+			if !e.Dir {
+				e.Size = info.Size()
+			}
+		}
+		entries = append(entries, e)
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// Browser lists remote directories for a file browser, keeping one sftp
+// session open between listings. It's safe for concurrent use; listings
+// take turns.
+type Browser struct {
+	alias  string
+	ctx    context.Context // lives as long as the browser; the session is killed when it's done
+	cancel context.CancelFunc
+
+	mu sync.Mutex
+	s  *Session
+}
+
+// NewBrowser returns a browser for alias. The session is opened on the first listing.
+func NewBrowser(alias string) *Browser {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Browser{alias: alias, ctx: ctx, cancel: cancel}
+}
+
+// List changes to dir (the login directory if empty) and lists it. It
+// returns the directory actually listed, which is where the session stayed
+// if dir couldn't be entered (eg. a link to a file).
+//
+// If ctx is done first the session is killed; like a dropped session, it's
+// reopened on the next call.
+func (b *Browser) List(ctx context.Context, dir string) (string, []Entry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.s == nil {
+		s, err := Open(b.ctx, b.alias)
+		if err != nil {
+			return "", nil, err
+		}
+		b.s = s
+	}
+	stop := context.AfterFunc(ctx, b.s.kill)
+	defer stop()
+	if dir == "" {
+		dir = b.s.Home()
+	}
+	cwd, err := b.s.TryCd(dir)
+	if err != nil {
+		b.s = nil
+		return "", nil, err
+	}
+	entries, err := b.s.List()
+	if err != nil {
+		b.s = nil
+		return "", nil, err
+	}
+	return cwd, entries, nil
+}
+
+// Close ends the browser's session, if it has one, and fails any listing
+// in progress.
+func (b *Browser) Close() {
+	b.cancel()
+}
@@ -0,0 +1,334 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+const (
+	sizePollInterval   = 250 * time.Millisecond // how often a download's partial file is checked for progress
+	remotePollInterval = time.Second            // how often an upload's remote file is listed for progress
+)
+
+// resume is what an earlier run of a job got done, so a retry can carry on
+// from there. The zero value starts over.
+type resume struct {
+	copied  map[string]bool // files the earlier runs copied (relative to the destination directory)
+	partial string          // file that was being copied when the job stopped
+}
+
+// action returns how to copy the file rel, given the size of the file at
+// the destination (have, if exists): skip it if an earlier run copied it
+// all, append to it if it's the one that was cut short, and otherwise copy
+// it over. have is 0 unless appending.
+//
+// Files the job didn't write itself are always copied, even if they look
+// complete, since the same size doesn't mean the same contents.
+func (r resume) action(rel string, have, size int64, exists bool) (skip, appendTo bool, from int64) {
+	switch {
+	case exists && r.copied[rel] && have == size:
+		return true, false, 0
+	case exists && rel == r.partial && have > 0 && have < size:
+		return false, true, have
+	}
+	return false, false, 0
+}
+
+// dirPlan is a directory to create at the destination and the files to copy into it.
+type dirPlan struct {
+	rel   string     // path relative to the job's destination directory ("" for the directory itself)
+	files []filePlan // files to copy into it
+}
+
+// filePlan is one file to copy.
+type filePlan struct {
+	src  string // full source path
+	name string // base name at the destination
+	size int64  // source size in bytes
+}
+
+// count returns the number of files and bytes in plan.
+func count(plan []dirPlan) (files int, bytes int64) {
+	for _, d := range plan {
+		files += len(d.files)
+		for _, f := range d.files {
+			bytes += f.size
+		}
+	}
+	return files, bytes
+}
+
+// upload copies a local file or directory (recursively) into a remote directory.
+//
+// Files are copied over whatever is at the destination, except when r
+// resumes an earlier run (see resume.action). A second session lists the
+// file being copied for byte progress, since sftp shows none in batch mode;
+// without it, progress is per file.
+func upload(ctx context.Context, alias, src, destDir string, r resume, p *progress) error {
+	if src == "" || destDir == "" {
+		return errEmptyPath
+	}
+	plan, err := planUpload(src)
+	if err != nil {
+		return err
+	}
+	p.planned(count(plan))
+
+	s, err := Open(ctx, alias)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	watcher, err := Open(ctx, alias)
+	if err == nil {
+		defer watcher.Close()
+	}
+
+	for _, d := range plan {
+		dir := path.Join(destDir, d.rel)
+		if d.rel != "" {
+			// an existing directory is fine; anything else fails the cd below
+			if _, err := s.Run("-mkdir " + quote(dir)); err != nil {
+				return err
+			}
+		}
+		if err := s.Cd(dir); err != nil {
+			return err
+		}
+		existing, err := s.List()
+		if err != nil {
+			return err
+		}
+		sizes := make(map[string]int64, len(existing))
+		for _, e := range existing {
+			if !e.Dir {
+				sizes[e.Name] = e.Size
+			}
+		}
+
+		for _, f := range d.files {
+			rel := path.Join(d.rel, f.name)
+			have, ok := sizes[f.name]
+			skip, appendTo, from := r.action(rel, have, f.size, ok)
+			if skip {
+				p.done(rel, f.size, true)
+				continue
+			}
+			put := "put "
+			if appendTo {
+				put = "put -a "
+			}
+			p.start(rel, from)
+			stop := func() {}
+			if watcher != nil {
+				stop = watchSize(watcher.sizeFunc(path.Join(dir, f.name)), remotePollInterval, p)
+			}
+			_, err := s.Run(put + quoteGlob(f.src) + " " + quote(f.name))
+			stop()
+			if err != nil {
+				return fmt.Errorf("%s: %w", rel, err)
+			}
+			p.done(rel, f.size, false)
+		}
+	}
+	return nil
+}
+
+// planUpload lists what uploading src copies. Links to files are followed;
+// links to directories and special files are left out.
+func planUpload(src string) ([]dirPlan, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Base(src)
+	if !info.IsDir() {
+		return []dirPlan{{files: []filePlan{{src: src, name: base, size: info.Size()}}}}, nil
+	}
+
+	var plan []dirPlan
+	index := map[string]int{} // local dir -> plan index
+	err = filepath.WalkDir(src, func(p string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if de.IsDir() {
+			rel, _ := filepath.Rel(src, p)
+			index[p] = len(plan)
+			plan = append(plan, dirPlan{rel: path.Join(base, filepath.ToSlash(rel))})
+			return nil
+		}
+		info, err := os.Stat(p) // follow links
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		i := index[filepath.Dir(p)]
+		plan[i].files = append(plan[i].files, filePlan{src: p, name: de.Name(), size: info.Size()})
+		return nil
+	})
+	return plan, err
+}
+
+// download copies a remote file or directory (recursively) into a local directory.
+//
+// Like upload, files are copied over whatever is at the destination unless
+// r resumes an earlier run. The local file is watched for progress while
+// it's copied.
+func download(ctx context.Context, alias, src, destDir string, r resume, p *progress) error {
+	if src == "" || destDir == "" {
+		return errEmptyPath
+	}
+	s, err := Open(ctx, alias)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if !path.IsAbs(src) {
+		src = path.Join(s.Home(), src)
+	}
+	plan, err := planDownload(s, path.Clean(src))
+	if err != nil {
+		return err
+	}
+	p.planned(count(plan))
+
+	for _, d := range plan {
+		dir := filepath.Join(destDir, filepath.FromSlash(d.rel))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		for _, f := range d.files {
+			rel := path.Join(d.rel, f.name)
+			local := filepath.Join(dir, f.name)
+			var have int64
+			info, err := os.Stat(local)
+			exists := err == nil && info.Mode().IsRegular()
+			if exists {
+				have = info.Size()
+			}
+			skip, appendTo, from := r.action(rel, have, f.size, exists)
+			if skip {
+				p.done(rel, f.size, true)
+				continue
+			}
+			get := "get "
+			if appendTo {
+				get = "get -a "
+			}
+			p.start(rel, from)
+			stop := watchSize(localSizeFunc(local), sizePollInterval, p)
+			_, err = s.Run(get + quoteGlob(f.src) + " " + quote(local))
+			stop()
+			if err != nil {
+				return fmt.Errorf("%s: %w", rel, err)
+			}
+			p.done(rel, f.size, false)
+		}
+	}
+	return nil
+}
+
+// planDownload lists what downloading src copies, walking directories with
+// the session. Links inside directories are left out (as `get -r` does),
+// which also keeps link loops from being followed.
+func planDownload(s *Session, src string) ([]dirPlan, error) {
+	parent, name := path.Dir(src), path.Base(src)
+	if err := s.Cd(parent); err != nil {
+		return nil, err
+	}
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var e *Entry
+	for i := range entries {
+		if entries[i].Name == name {
+			e = &entries[i]
+			break
+		}
+	}
+	if e == nil {
+		return nil, fmt.Errorf("%s: no such file or directory", src)
+	}
+
+	isDir := e.Dir
+	if e.Link {
+		cwd, err := s.TryCd(name)
+		if err != nil {
+			return nil, err
+		}
+		isDir = cwd != parent
+	}
+	if !isDir {
+		return []dirPlan{{files: []filePlan{{src: src, name: name, size: e.Size}}}}, nil
+	}
+
+	var plan []dirPlan
+	todo := []dirPlan{{rel: name}}
+	for len(todo) > 0 {
+		d := todo[0]
+		todo = todo[1:]
+		dir := path.Join(parent, d.rel)
+		if err := s.Cd(dir); err != nil {
+			return nil, err
+		}
+		entries, err := s.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			switch {
+			case e.Link:
+			case e.Dir:
+				todo = append(todo, dirPlan{rel: path.Join(d.rel, e.Name)})
+			case e.Mode != "" && e.Mode[0] == '-':
+				d.files = append(d.files, filePlan{src: path.Join(dir, e.Name), name: e.Name, size: e.Size})
+			}
+		}
+		plan = append(plan, d)
+	}
+	return plan, nil
+}
+
+// watchSize reports the size of the file being copied, as size returns it,
+// every interval until stop is called.
+func watchSize(size func() (int64, bool), every time.Duration, p *progress) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				if n, ok := size(); ok {
+					p.partial(n)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// localSizeFunc returns a size func for watchSize for a local file.
+func localSizeFunc(local string) func() (int64, bool) {
+	return func() (int64, bool) {
+		info, err := os.Stat(local)
+		if err != nil {
+			return 0, false
+		}
+		return info.Size(), true
+	}
+}
@@ -0,0 +1,33 @@
+package transfer
+
+import "testing"
+
+func TestResumeAction(t *testing.T) {
+	r := resume{copied: map[string]bool{"a": true}, partial: "b"}
+	tests := []struct {
+		name       string
+		r          resume
+		rel        string
+		have, size int64
+		exists     bool
+		skip, app  bool
+		from       int64
+	}{
+		{"fresh job overwrites a same-size file", resume{}, "a", 10, 10, true, false, false, 0},
+		{"fresh job doesn't append", resume{}, "b", 4, 10, true, false, false, 0},
+		{"copied file is skipped", r, "a", 10, 10, true, true, false, 0},
+		{"copied file that changed size is copied again", r, "a", 7, 10, true, false, false, 0},
+		{"copied file that's gone is copied again", r, "a", 0, 10, false, false, false, 0},
+		{"interrupted file is appended to", r, "b", 4, 10, true, false, true, 4},
+		{"interrupted file that's too big is copied again", r, "b", 12, 10, true, false, false, 0},
+		{"other files are copied over", r, "c", 10, 10, true, false, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skip, app, from := tt.r.action(tt.rel, tt.have, tt.size, tt.exists)
+			if skip != tt.skip || app != tt.app || from != tt.from {
+				t.Errorf("action = %v, %v, %d; want %v, %v, %d", skip, app, from, tt.skip, tt.app, tt.from)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"path"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultConcurrency is how many transfers a queue runs at once.
+const DefaultConcurrency = 2
+
+// Direction is which way a transfer copies.
+type Direction int
+
+const (
+	Upload   Direction = iota // local to remote
+	Download                  // remote to local
+)
+
+// String returns "upload" or "download".
+func (d Direction) String() string {
+	if d == Download {
+		return "download"
+	}
+	return "upload"
+}
+
+// State is where a job is at.
+type State int
+
+const (
+	StateQueued   State = iota // waiting for a free slot
+	StateRunning               // copying
+	StateDone                  // every file was copied
+	StateFailed                // stopped on an error; retrying resumes it
+	StateCanceled              // canceled; retrying resumes it
+)
+
+// String returns a short description of the state.
+func (s State) String() string {
+	switch s {
+	case StateRunning:
+		return "running"
+	case StateDone:
+		return "done"
+	case StateFailed:
+		return "failed"
+	case StateCanceled:
+		return "canceled"
+	default:
+		return "queued"
+	}
+}
+
+// Finished reports whether the job won't change state again unless it's retried.
+func (s State) Finished() bool {
+	return s >= StateDone
+}
+
+// Job is one file or directory (copied recursively) to transfer.
+type Job struct {
+	ID        int
+	Alias     string // ssh host alias
+	Direction Direction
+	Source    string // local path (upload) or remote path (download)
+	DestDir   string // remote directory (upload) or local directory (download) to copy into
+
+	State      State
+	Err        error     // why the job failed
+	Files      int       // files to copy, once known
+	FilesDone  int       // files copied or skipped so far
+	Skipped    int       // files a retry didn't copy again, since an earlier run had
+	Bytes      int64     // bytes at the destination so far
+	TotalBytes int64     // bytes to copy, once known
+	Current    string    // file being copied (relative to Source)
+	Started    time.Time // when the job (last) started running
+	Ended      time.Time // when the job finished
+
+	resume resume // what earlier runs got done, for a retry
+}
+
+// Name returns the base name of the job's source.
+func (j Job) Name() string {
+	if j.Direction == Download {
+		return path.Base(j.Source)
+	}
+	return filepath.Base(j.Source)
+}
+
+// Dest returns the path the source is copied to.
+func (j Job) Dest() string {
+	if j.Direction == Download {
+		return filepath.Join(j.DestDir, j.Name())
+	}
+	return path.Join(j.DestDir, j.Name())
+}
+
+// Percent returns the job's progress (0-100), or -1 if the size isn't known yet.
+func (j Job) Percent() int {
+	switch {
+	case j.State == StateDone:
+		return 100
+	case j.Files == 0 && j.TotalBytes == 0:
+		return -1
+	case j.TotalBytes == 0:
+		return 100 * j.FilesDone / j.Files
+	}
+	return int(min(100*j.Bytes/j.TotalBytes, 100))
+}
+
+// Queue runs transfer jobs in the background, a few at a time, in the order
+// they were added. It's safe for concurrent use.
+type Queue struct {
+	mu       sync.Mutex
+	jobs     []*Job
+	cancels  map[int]context.CancelFunc // running jobs
+	finished []int                      // jobs finished since the last TakeFinished
+	nextID   int
+	slots    int // jobs that may run at once
+	running  int
+}
+
+// NewQueue returns an empty queue that runs up to concurrency jobs at once
+// (DefaultConcurrency if <= 0).
+func NewQueue(concurrency int) *Queue {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	return &Queue{cancels: map[int]context.CancelFunc{}, slots: concurrency, nextID: 1}
+}
+
+// Add queues a transfer and returns its job ID.
+func (q *Queue) Add(alias string, dir Direction, source, destDir string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j := &Job{ID: q.nextID, Alias: alias, Direction: dir, Source: source, DestDir: destDir}
+	q.nextID++
+	q.jobs = append(q.jobs, j)
+	q.schedule()
+	return j.ID
+}
+
+// Jobs returns a copy of every job, oldest first.
+func (q *Queue) Jobs() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]Job, len(q.jobs))
+	for i, j := range q.jobs {
+		out[i] = *j
+	}
+	return out
+}
+
+// Len returns the number of jobs in the queue, finished ones included.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+// Active returns the number of queued and running jobs.
+func (q *Queue) Active() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, j := range q.jobs {
+		if !j.State.Finished() {
+			n++
+		}
+	}
+	return n
+}
+
+// TakeFinished returns the jobs that finished since the last call, so the
+// caller can report each one once.
+func (q *Queue) TakeFinished() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []Job
+	for _, id := range q.finished {
+		if j := q.find(id); j != nil {
+			out = append(out, *j)
+		}
+	}
+	q.finished = nil
+	return out
+}
+
+// Cancel cancels a queued or running job. It reports whether there was one to cancel.
+func (q *Queue) Cancel(id int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j := q.find(id)
+	if j == nil || j.State.Finished() {
+		return false
+	}
+	if cancel, ok := q.cancels[id]; ok {
+		cancel() // the job finishes as canceled once sftp exits
+		return true
+	}
+	j.State = StateCanceled
+	j.Err = context.Canceled
+	j.Ended = time.Now()
+	q.finished = append(q.finished, id)
+	return true
+}
+
+// CancelAll cancels every queued and running job.
+func (q *Queue) CancelAll() {
+	for _, j := range q.Jobs() {
+		q.Cancel(j.ID)
+	}
+}
+
+// Retry queues a failed or canceled job again, resuming it: files it copied
+// are skipped (if they're still complete) and the one it was copying is
+// appended to. Everything else is copied again.
+func (q *Queue) Retry(id int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j := q.find(id)
+	if j == nil || (j.State != StateFailed && j.State != StateCanceled) {
+		return false
+	}
+	*j = Job{ID: j.ID, Alias: j.Alias, Direction: j.Direction, Source: j.Source, DestDir: j.DestDir, resume: j.resume}
+	q.finished = slices.DeleteFunc(q.finished, func(f int) bool { return f == id })
+	q.schedule()
+	return true
+}
+
+// Remove drops a finished job from the queue.
+func (q *Queue) Remove(id int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, j := range q.jobs {
+		if j.ID == id && j.State.Finished() {
+			q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ClearFinished drops every finished job and returns how many were dropped.
+func (q *Queue) ClearFinished() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	kept := q.jobs[:0]
+	for _, j := range q.jobs {
+		if !j.State.Finished() {
+			kept = append(kept, j)
+		}
+	}
+	n := len(q.jobs) - len(kept)
+	clear(q.jobs[len(kept):])
+	q.jobs = kept
+	return n
+}
+
+// find returns the job with id. q.mu must be held.
+func (q *Queue) find(id int) *Job {
+	for _, j := range q.jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// schedule starts queued jobs while there are free slots. q.mu must be held.
+func (q *Queue) schedule() {
+	for _, j := range q.jobs {
+		if q.running >= q.slots {
+			return
+		}
+		if j.State != StateQueued {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		q.cancels[j.ID] = cancel
+		q.running++
+		j.State = StateRunning
+		j.Started = time.Now()
+		go q.run(ctx, *j)
+	}
+}
+
+// run copies one job and records how it ended.
+func (q *Queue) run(ctx context.Context, j Job) {
+	p := &progress{q: q, id: j.ID}
+	var err error
+	if j.Direction == Download {
+		err = download(ctx, j.Alias, j.Source, j.DestDir, j.resume, p)
+	} else {
+		err = upload(ctx, j.Alias, j.Source, j.DestDir, j.resume, p)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	canceled := ctx.Err() != nil
+	q.cancels[j.ID]()
+	delete(q.cancels, j.ID)
+	q.running--
+	if job := q.find(j.ID); job != nil {
+		job.Ended = time.Now()
+		switch {
+		case canceled:
+			job.State = StateCanceled
+			job.Err = context.Canceled
+		case err != nil:
+			job.State = StateFailed
+			job.Err = err
+		default:
+			job.State = StateDone
+		}
+		if job.State != StateDone {
+			job.resume.partial = job.Current
+		}
+		job.Current = ""
+		q.finished = append(q.finished, j.ID)
+	}
+	q.schedule()
+}
+
+// progress records a running job's progress in the queue.
+type progress struct {
+	q  *Queue
+	id int
+
+	base int64 // bytes of the files finished so far
+}
+
+// update applies f to the job, if it's still in the queue.
+func (p *progress) update(f func(j *Job)) {
+	p.q.mu.Lock()
+	defer p.q.mu.Unlock()
+	if j := p.q.find(p.id); j != nil {
+		f(j)
+	}
+}
+
+// planned records how much there is to copy.
+func (p *progress) planned(files int, bytes int64) {
+	p.update(func(j *Job) { j.Files, j.TotalBytes = files, bytes })
+}
+
+// start records the file being copied and how much of it is already there.
+func (p *progress) start(name string, have int64) {
+	p.update(func(j *Job) { j.Current, j.Bytes = name, p.base+have })
+}
+
+// partial records how much of the current file is at the destination.
+func (p *progress) partial(have int64) {
+	p.update(func(j *Job) { j.Bytes = p.base + have })
+}
+
+// done records a finished file, rel (skipped if an earlier run copied it).
+func (p *progress) done(rel string, size int64, skipped bool) {
+	p.base += size
+	p.update(func(j *Job) {
+		j.FilesDone++
+		j.Bytes = p.base
+		if skipped {
+			j.Skipped++
+		}
+		if j.resume.copied == nil {
+			j.resume.copied = map[string]bool{}
+		}
+		j.resume.copied[rel] = true
+	})
+}
+
+// errEmptyPath is returned for jobs without a source or destination.
+var errEmptyPath = errors.New("empty source or destination path")
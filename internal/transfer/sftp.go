@@ -0,0 +1,344 @@
+// Package transfer copies files to and from ssh hosts by driving the sftp
+// client in batch mode, and runs those copies in a background queue.
+//
+// There's no native SFTP client here: sftp reuses the host's ssh config
+// (jump hosts, keys, agents) the same way the sessions do.
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/connect"
+)
+
+const (
+	connectTimeout = 10 * time.Second // ssh ConnectTimeout for sftp sessions
+	waitDelay      = 2 * time.Second  // how long a killed sftp may keep its pipes open
+
+	sentinel = "lpwd" // harmless command sent after each command to find the end of its output
+)
+
+// A Session is a running `sftp -b -` process that commands are fed to one at
+// a time. It is not safe for concurrent use.
+//
+// In batch mode sftp echoes each command as "sftp> cmd" and, unless the
+// command starts with "-", exits on the first failing one. Stdout and stderr
+// share one pipe so errors stay in order with the output around them.
+type Session struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	out   *bufio.Reader
+	home  string // remote working directory at login
+	err   error  // why the session ended (nil while it's running)
+}
+
+// Open starts an sftp session to alias and waits for it to log in. The
+// session is killed when ctx is done.
+//
+// BatchMode is on, so hosts that ask for a password fail instead of hanging.
+func Open(ctx context.Context, alias string) (*Session, error) {
+	if alias == "" {
+		return nil, errors.New("empty ssh alias")
+	}
+	program, err := connect.SFTPProgramPath()
+	if err != nil {
+		return nil, err
+	}
+
+	secs := strconv.Itoa(int(connectTimeout / time.Second))
+	args := append(connect.SSHConfigArgs(alias), "-q", "-b", "-",
+		"-o", "BatchMode=yes", "-o", "ConnectTimeout="+secs, alias)
+	c := exec.CommandContext(ctx, program, args...)
+	c.WaitDelay = waitDelay
+
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	c.Stdout = pw
+	c.Stderr = pw
+	if err := c.Start(); err != nil {
+		_ = pr.Close()
+		_ = pw.Close()
+		return nil, err
+	}
+	_ = pw.Close() // the child has its own copy
+
+	s := &Session{cmd: c, stdin: stdin, out: bufio.NewReader(pr)}
+	home, err := s.Pwd()
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.home = home
+	return s, nil
+}
+
+// Home returns the remote working directory the session started in.
+func (s *Session) Home() string {
+	return s.home
+}
+
+// Run sends one command and returns its output lines (errors included).
+//
+// If sftp exits before the command finishes (a failing command in batch
+// mode, or a dropped connection), the error carries the last output line.
+func (s *Session) Run(command string) ([]string, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if _, err := io.WriteString(s.stdin, command+"\n"+sentinel+"\n"); err != nil {
+		return nil, s.exitError(nil, err)
+	}
+
+	var out []string
+	echoed := false
+	for {
+		line, err := s.out.ReadString('\n')
+		if err != nil {
+			if line != "" {
+				out = append(out, strings.TrimRight(line, "\r\n"))
+			}
+			return out, s.exitError(out, err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case !echoed && strings.HasPrefix(line, "sftp> "):
+			// the command's own echo (may differ from command if sftp escaped it)
+			echoed = true
+		case echoed && line == "sftp> "+sentinel:
+			// the sentinel prints one line of its own
+			if _, err := s.out.ReadString('\n'); err != nil {
+				return out, s.exitError(out, err)
+			}
+			return out, nil
+		default:
+			out = append(out, line)
+		}
+	}
+}
+
+// exitError waits for sftp to exit and records why the session ended.
+func (s *Session) exitError(out []string, err error) error {
+	_ = s.stdin.Close()
+	waitErr := s.cmd.Wait()
+	switch {
+	case lastLine(out) != "":
+		s.err = errors.New(lastLine(out))
+	case waitErr != nil:
+		s.err = fmt.Errorf("sftp: %w", waitErr)
+	default:
+		s.err = fmt.Errorf("sftp: %w", err)
+	}
+	return s.err
+}
+
+// lastLine returns the last non-empty line of out.
+func lastLine(out []string) string {
+	for i := len(out) - 1; i >= 0; i-- {
+		if l := strings.TrimSpace(out[i]); l != "" {
+			return l
+		}
+	}
+	return ""
+}
+
+// Pwd returns the remote working directory.
+func (s *Session) Pwd() (string, error) {
+	out, err := s.Run("pwd")
+	if err != nil {
+		return "", err
+	}
+	for _, l := range out {
+		if _, dir, ok := strings.Cut(l, "Remote working directory: "); ok {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("unexpected pwd output: %q", strings.Join(out, "\n"))
+}
+
+// Cd changes the remote working directory.
+//
+// A failing cd ends the session, like any failing command in batch mode.
+func (s *Session) Cd(dir string) error {
+	_, err := s.Run("cd " + quote(dir))
+	return err
+}
+
+// TryCd changes the remote working directory if it can and returns where
+// the session ended up, so a failed cd (eg. not a directory) can be told
+// apart without ending the session.
+func (s *Session) TryCd(dir string) (string, error) {
+	if _, err := s.Run("-cd " + quote(dir)); err != nil {
+		return "", err
+	}
+	return s.Pwd()
+}
+
+// List returns the entries of the remote working directory, without "." and "..".
+//
+// A listing line that can't be parsed is an error, so a server with an
+// unexpected ls format doesn't just show an empty directory.
+func (s *Session) List() ([]Entry, error) {
+	out, err := s.Run("ls -la")
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, l := range out {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		e, ok := parseLongEntry(l)
+		if !ok {
+			return nil, fmt.Errorf("unrecognized ls output: %q", l)
+		}
+		if e.Name == "." || e.Name == ".." {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := s.trimLinkTargets(entries); err != nil {
+		return nil, err
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// trimLinkTargets removes the " -> target" some servers add to symlinks in
+// a long listing. A link's own name may contain " -> " too, so the names
+// are listed on their own to find where each one ends.
+func (s *Session) trimLinkTargets(entries []Entry) error {
+	var names map[string]bool
+	for i, e := range entries {
+		if !e.Link || !strings.Contains(e.Name, linkArrow) {
+			continue
+		}
+		if names == nil {
+			out, err := s.Run("ls -1a")
+			if err != nil {
+				return err
+			}
+			names = make(map[string]bool, len(out))
+			for _, n := range out {
+				names[n] = true
+			}
+		}
+		entries[i].Name = linkName(e.Name, names)
+	}
+	return nil
+}
+
+// sizeFunc returns a size func for watchSize for a remote file, which lists
+// it with the session. A missing file doesn't end the session.
+func (s *Session) sizeFunc(remote string) func() (int64, bool) {
+	return func() (int64, bool) {
+		out, err := s.Run("-ls -ln " + quoteGlob(remote))
+		if err != nil {
+			return 0, false
+		}
+		for _, l := range out {
+			if e, ok := parseLongEntry(l); ok && !e.Dir {
+				return e.Size, true
+			}
+		}
+		return 0, false
+	}
+}
+
+// Close ends the session. It doesn't wait for sftp to exit.
+func (s *Session) Close() {
+	if s.err != nil {
+		return // already exited
+	}
+	s.err = errors.New("session closed")
+	_ = s.stdin.Close() // sftp exits at the end of its input
+	go func() { _ = s.cmd.Wait() }()
+}
+
+// kill stops sftp right away, failing the command in progress.
+func (s *Session) kill() {
+	_ = s.cmd.Process.Kill()
+}
+
+// linkArrow separates a symlink from its target in a long listing.
+const linkArrow = " -> "
+
+// lsDate matches the date column of a long listing: month first (eg.
+// "Jan  2 15:04", "Jan  2  2006", "févr. 2 2006"), day first (eg.
+// "2 Jan 15:04") or ISO (eg. "2006-01-02 15:04").
+const lsDate = `\pL{2,5}\.?\s+\d{1,2}\.?\s+(?:\d{1,2}:\d{2}|\d{4})` +
+	`|\d{1,2}\.?\s+\pL{2,5}\.?\s+(?:\d{1,2}:\d{2}|\d{4})` +
+	`|\d{4}-\d{2}-\d{2}(?:[ T]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?)?(?:\s+[-+]\d{4})?`
+
+// longEntry matches a long listing line: mode, links, owner, group, size,
+// date and name.
+var longEntry = regexp.MustCompile(`^([-dlcbps][-rwxsStT]{9}\S*)\s+\d+\s+\S+\s+\S+\s+(\d+)\s+(` + lsDate + `)\s(.+)$`)
+
+// parseLongEntry parses one line of `ls -l` output from sftp.
+//
+// For a symlink, Name keeps any " -> target" the server added, since the
+// name itself may contain the arrow; see linkName.
+func parseLongEntry(line string) (Entry, bool) {
+	m := longEntry.FindStringSubmatch(line)
+	if m == nil {
+		return Entry{}, false
+	}
+	size, _ := strconv.ParseInt(m[2], 10, 64)
+	e := Entry{
+		Name:     m[4],
+		Mode:     m[1],
+		Size:     size,
+		Modified: strings.Join(strings.Fields(m[3]), " "),
+	}
+	switch m[1][0] {
+	case 'd':
+		e.Dir = true
+	case 'l':
+		e.Link = true
+	}
+	return e, true
+}
+
+// linkName returns a symlink's name from its long listing name, which may
+// end in " -> target": the longest part before an arrow that is one of the
+// directory's names, or else the part before the first arrow.
+func linkName(listed string, names map[string]bool) string {
+	if names[listed] {
+		return listed
+	}
+	for i := strings.LastIndex(listed, linkArrow); i > 0; i = strings.LastIndex(listed[:i], linkArrow) {
+		if names[listed[:i]] {
+			return listed[:i]
+		}
+	}
+	name, _, _ := strings.Cut(listed, linkArrow)
+	return name
+}
+
+// quote quotes a path for an sftp command line.
+func quote(p string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(p) + `"`
+}
+
+// quoteGlob quotes a path for an sftp argument that is glob-expanded (the
+// source of get and put), so wildcard characters in names match literally.
+func quoteGlob(p string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+	return `"` + r.Replace(p) + `"`
+}
@@ -0,0 +1,179 @@
+package transfer
+
+import (
+	"bufio"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseLongEntry(t *testing.T) {
+	tests := []struct {
+		line string
+		want Entry
+		ok   bool
+	}{
+		{"-rw-r--r--    1 alice    staff        1234 Jan  2 15:04 notes.txt",
+			Entry{Name: "notes.txt", Mode: "-rw-r--r--", Size: 1234, Modified: "Jan 2 15:04"}, true},
+		{"drwxr-xr-x    3 1000     1000         4096 Dec 31  2023 old logs",
+			Entry{Name: "old logs", Dir: true, Mode: "drwxr-xr-x", Size: 4096, Modified: "Dec 31 2023"}, true},
+		{"-rw-r--r--+   1 alice    staff           0 Mar  9 08:00  leading space",
+			Entry{Name: " leading space", Mode: "-rw-r--r--+", Modified: "Mar 9 08:00"}, true},
+		{"lrwxrwxrwx    1 root     root           7 Jan  2 15:04 current -> v1.2",
+			Entry{Name: "current -> v1.2", Link: true, Mode: "lrwxrwxrwx", Size: 7, Modified: "Jan 2 15:04"}, true},
+		{"-rw-r--r--    1 alice    staff          42 2024-01-02 15:04 iso.txt",
+			Entry{Name: "iso.txt", Mode: "-rw-r--r--", Size: 42, Modified: "2024-01-02 15:04"}, true},
+		{"-rw-r--r--    1 alice    staff          42 2024-01-02 iso-date.txt",
+			Entry{Name: "iso-date.txt", Mode: "-rw-r--r--", Size: 42, Modified: "2024-01-02"}, true},
+		{"-rw-r--r--    1 alice    staff          42 févr. 12 15:04 fr.txt",
+			Entry{Name: "fr.txt", Mode: "-rw-r--r--", Size: 42, Modified: "févr. 12 15:04"}, true},
+		{"-rw-r--r--    1 alice    staff          42 12 Feb  2024 day-first.txt",
+			Entry{Name: "day-first.txt", Mode: "-rw-r--r--", Size: 42, Modified: "12 Feb 2024"}, true},
+		{"Can't ls: \"/nope\" not found", Entry{}, false},
+		{"notes.txt", Entry{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseLongEntry(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseLongEntry(%q) =\n%+v, %v\nwant\n%+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLinkName(t *testing.T) {
+	names := map[string]bool{"a -> b": true, "plain": true, "x -> y -> z": true}
+	tests := []struct {
+		listed, want string
+	}{
+		{"plain -> /etc/plain", "plain"},
+		{"a -> b", "a -> b"},            // server doesn't add targets
+		{"a -> b -> /target", "a -> b"}, // arrow in the name and a target
+		{"x -> y -> z -> ../z", "x -> y -> z"},
+		{"gone -> nowhere", "gone"}, // not in the names: cut at the first arrow
+	}
+	for _, tt := range tests {
+		if got := linkName(tt.listed, names); got != tt.want {
+			t.Errorf("linkName(%q) = %q, want %q", tt.listed, got, tt.want)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		path, quote, glob string
+	}{
+		{"plain", `"plain"`, `"plain"`},
+		{"with space", `"with space"`, `"with space"`},
+		{`say "hi"`, `"say \"hi\""`, `"say \"hi\""`},
+		{`back\slash`, `"back\\slash"`, `"back\\slash"`},
+		{"*.log", `"*.log"`, `"\*.log"`},
+		{"a?[0-9]", `"a?[0-9]"`, `"a\?\[0-9\]"`},
+	}
+	for _, tt := range tests {
+		if got := quote(tt.path); got != tt.quote {
+			t.Errorf("quote(%q) = %s, want %s", tt.path, got, tt.quote)
+		}
+		if got := quoteGlob(tt.path); got != tt.glob {
+			t.Errorf("quoteGlob(%q) = %s, want %s", tt.path, got, tt.glob)
+		}
+	}
+}
+
+// nopWriteCloser collects what the session sends to sftp.
+type nopWriteCloser struct{ strings.Builder }
+
+func (*nopWriteCloser) Close() error { return nil }
+
+// fakeSession returns a session that reads transcript as sftp's output.
+func fakeSession(transcript string) (*Session, *nopWriteCloser) {
+	in := &nopWriteCloser{}
+	return &Session{cmd: exec.Command("sftp"), stdin: in, out: bufio.NewReader(strings.NewReader(transcript))}, in
+}
+
+func TestSessionRun(t *testing.T) {
+	s, in := fakeSession(`sftp> pwd
+Remote working directory: /home/alice
+sftp> lpwd
+Local working directory: /tmp
+sftp> -cd "nope"
+Can't change directory: "/home/alice/nope" not found
+sftp> lpwd
+Local working directory: /tmp
+sftp> ls -la
+-rw-r--r--    1 alice    staff           5 Jan  2 15:04 sftp> lpwd
+sftp> lpwd
+Local working directory: /tmp
+sftp> cd "nope"
+Can't change directory: "/home/alice/nope" not found
+`)
+	home, err := s.Pwd()
+	if err != nil || home != "/home/alice" {
+		t.Fatalf("Pwd = %q, %v", home, err)
+	}
+
+	out, err := s.Run(`-cd "nope"`)
+	if err != nil || !slices.Equal(out, []string{`Can't change directory: "/home/alice/nope" not found`}) {
+		t.Errorf("ignored failure: %q, %v", out, err)
+	}
+
+	entries, err := s.List()
+	if err != nil || len(entries) != 1 || entries[0].Name != "sftp> lpwd" {
+		t.Errorf("List = %+v, %v; want the file named like the sentinel", entries, err)
+	}
+
+	want := `Can't change directory: "/home/alice/nope" not found`
+	if err := s.Cd("nope"); err == nil || err.Error() != want {
+		t.Errorf("failing cd: %v, want %s", err, want)
+	}
+	if _, err := s.Run("pwd"); err == nil || err.Error() != want {
+		t.Errorf("after sftp exited: %v, want %s", err, want)
+	}
+
+	sent := "pwd\nlpwd\n-cd \"nope\"\nlpwd\nls -la\nlpwd\ncd \"nope\"\nlpwd\n"
+	if in.String() != sent {
+		t.Errorf("sent\n%s\nwant\n%s", in.String(), sent)
+	}
+}
+
+func TestSessionListUnrecognized(t *testing.T) {
+	s, _ := fakeSession(`sftp> ls -la
+drwxr-xr-x    3 alice    staff        4096 Jan  2 15:04 .
+-rw-r--r-- alice 5 notes.txt
+sftp> lpwd
+Local working directory: /tmp
+`)
+	if entries, err := s.List(); err == nil || !strings.Contains(err.Error(), "notes.txt") {
+		t.Errorf("List = %+v, %v; want an unrecognized output error", entries, err)
+	}
+}
+
+func TestSessionListLinks(t *testing.T) {
+	s, in := fakeSession(`sftp> ls -la
+lrwxrwxrwx    1 alice    staff           3 Jan  2 15:04 a -> b -> c
+lrwxrwxrwx    1 alice    staff           3 Jan  2 15:04 lib -> /usr/lib
+sftp> lpwd
+Local working directory: /tmp
+sftp> ls -1a
+.
+..
+a -> b
+lib
+sftp> lpwd
+Local working directory: /tmp
+`)
+	entries, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if !slices.Equal(names, []string{"a -> b", "lib"}) {
+		t.Errorf("link names = %q", names)
+	}
+	if !strings.Contains(in.String(), "ls -1a\n") {
+		t.Errorf("names weren't listed: sent %q", in.String())
+	}
+}
@@ -0,0 +1,689 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/hooks"
+	"bubbletea-ssh-manager/internal/launch"
+	str "bubbletea-ssh-manager/internal/stringutil"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// bulkListMax is the number of hosts listed in a bulk confirmation before
+// the rest are summarized.
+const bulkListMax = 12
+
+// bulkAction is an action applied to every marked host.
+type bulkAction string
+
+const (
+	bulkRemove bulkAction = "remove"
+	bulkMove   bulkAction = "move"
+	bulkTag    bulkAction = "tag"
+	bulkExport bulkAction = "export"
+	bulkRun    bulkAction = "run"
+	bulkTabs   bulkAction = "tabs"
+)
+
+type bulkState struct {
+	form   *huh.Form   // action form
+	values *bulkValues // values bound to the form
+	hosts  []*menuItem // marked hosts when the form was opened
+}
+
+// bulkValues holds the bulk form's input values.
+type bulkValues struct {
+	action bulkAction
+	group  string // target group (move)
+	tags   string // tag edits (tag): "web" adds, "-web" removes
+}
+
+// toggleMark marks or unmarks the selected host and moves to the next item.
+//
+// On a group it marks all of the group's hosts, or unmarks them if they're
+// all marked already.
+func (m model) toggleMark() (model, tea.Cmd) {
+	it, _ := m.lst.SelectedItem().(*menuItem)
+	if it == nil {
+		return m, nil
+	}
+	hosts := hostsUnder([]*menuItem{it})
+	m.setMarked(hosts, !m.allMarked(hosts))
+	m.lst.CursorDown()
+	return m, nil
+}
+
+// markAll marks every host in the current menu (including the hosts of its
+// groups), or unmarks them if they're all marked already.
+func (m model) markAll() (model, tea.Cmd) {
+	hosts := hostsUnder(m.allItems)
+	if len(hosts) == 0 {
+		return m, nil
+	}
+	on := !m.allMarked(hosts)
+	m.setMarked(hosts, on)
+	if !on {
+		return m, m.setStatusInfo(fmt.Sprintf("Unmarked %d host(s).", len(hosts)), statusTTL)
+	}
+	return m, m.setStatusInfo(fmt.Sprintf("Marked %d host(s). Press %s for bulk actions.", len(hosts), bulkSymbol), statusTTL)
+}
+
+// markFiltered marks every host matching the current search (including the
+// hosts of matching groups).
+func (m model) markFiltered() (model, tea.Cmd) {
+	q := strings.TrimSpace(m.query.Value())
+	if q == "" {
+		return m, m.setStatusError("Type a search first; "+markFilteredSymbol+" marks the hosts it matches.", statusTTL)
+	}
+	var items []*menuItem
+	for _, li := range m.lst.Items() {
+		if it, ok := li.(*menuItem); ok {
+			items = append(items, it)
+		}
+	}
+	hosts := hostsUnder(items)
+	m.setMarked(hosts, true)
+	return m, m.setStatusInfo(fmt.Sprintf("Marked %d host(s) matching %q.", len(hosts), q), statusTTL)
+}
+
+// clearMarks unmarks every host.
+func (m model) clearMarks() (model, tea.Cmd) {
+	n := len(m.marked)
+	clear(m.marked)
+	m.syncListTitle()
+	m.syncHelpKeys()
+	return m, m.setStatusInfo(fmt.Sprintf("Unmarked %d host(s).", n), statusTTL)
+}
+
+// setMarked marks or unmarks hosts.
+func (m *model) setMarked(hosts []*menuItem, on bool) {
+	for _, h := range hosts {
+		if on {
+			m.marked[hostKey(h)] = struct{}{}
+		} else {
+			delete(m.marked, hostKey(h))
+		}
+	}
+	m.syncListTitle()
+	m.syncHelpKeys()
+}
+
+// allMarked reports whether every host in hosts is marked.
+func (m model) allMarked(hosts []*menuItem) bool {
+	for _, h := range hosts {
+		if _, ok := m.marked[hostKey(h)]; !ok {
+			return false
+		}
+	}
+	return len(hosts) > 0
+}
+
+// markedHosts returns the marked hosts in menu order.
+func (m model) markedHosts() []*menuItem {
+	hosts, _ := getHostItemsWithHints(m.root)
+	out := make([]*menuItem, 0, len(m.marked))
+	for _, h := range hosts {
+		if _, ok := m.marked[hostKey(h)]; ok {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// pruneMarks drops marks for hosts that no longer exist (eg. after a reload
+// following a remove or rename).
+func (m *model) pruneMarks() {
+	if len(m.marked) == 0 {
+		return
+	}
+	hosts, _ := getHostItemsWithHints(m.root)
+	keep := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		keep[hostKey(h)] = true
+	}
+	for k := range m.marked {
+		if !keep[k] {
+			delete(m.marked, k)
+		}
+	}
+	m.syncListTitle()
+}
+
+// hostsUnder returns the hosts in items, expanding groups to their hosts.
+func hostsUnder(items []*menuItem) []*menuItem {
+	var out []*menuItem
+	for _, it := range items {
+		switch {
+		case it == nil:
+		case it.kind == itemHost:
+			out = append(out, it)
+		case it.kind == itemGroup:
+			out = append(out, hostsUnder(it.children)...)
+		}
+	}
+	return out
+}
+
+// countMarked returns how many of a group's hosts are marked.
+func countMarked(group *menuItem, marked map[string]struct{}) int {
+	n := 0
+	for _, h := range hostsUnder(group.children) {
+		if _, ok := marked[hostKey(h)]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// openBulkForm asks which action to apply to the marked hosts.
+func (m model) openBulkForm() (model, tea.Cmd) {
+	hosts := m.markedHosts()
+	if len(hosts) == 0 {
+		return m, m.setStatusError("Mark hosts with "+markSymbol+" first.", statusTTL)
+	}
+	b := &bulkState{hosts: hosts, values: &bulkValues{action: bulkRun}}
+	b.form = buildBulkForm(b, m.theme)
+
+	m.mode = modeBulk
+	m.ms.bulk = b
+	m.setStatusInfo("", 0)
+	m.relayout()
+	return m, b.form.Init()
+}
+
+// buildBulkForm builds the form picking a bulk action, plus the group or
+// tags for the actions that need one.
+func buildBulkForm(b *bulkState, appTheme Theme) *huh.Form {
+	v := b.values
+	aliases := make([]string, 0, len(b.hosts))
+	for _, h := range b.hosts {
+		aliases = append(aliases, h.spec.Alias)
+	}
+	desc := fmt.Sprintf("%d marked host(s): %s", len(b.hosts), summarizeList(aliases, 6))
+
+	f := huh.NewForm(
+		huh.NewGroup(
+			huh.NewNote().Title("Bulk Actions").Description(desc),
+			huh.NewSelect[bulkAction]().
+				Key("action").
+				Title("Action").
+				Options(
+					huh.NewOption("Run command", bulkRun),
+					huh.NewOption("Open in tabs", bulkTabs),
+					huh.NewOption("Move to group", bulkMove),
+					huh.NewOption("Tag", bulkTag),
+					huh.NewOption("Export", bulkExport),
+					huh.NewOption("Remove", bulkRemove),
+				).
+				Value(&v.action),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				Key("group").
+				Title("Group").
+				Description("Leave blank to move the hosts out of their groups.").
+				Value(&v.group).
+				Validate(str.ValidateHostGroup),
+		).WithHideFunc(func() bool { return v.action != bulkMove }),
+		huh.NewGroup(
+			huh.NewInput().
+				Key("tags").
+				Title("Tags").
+				Description("Tags to add; prefix a tag with - to remove it (eg. prod -staging).").
+				Value(&v.tags).
+				Validate(func(s string) error {
+					if add, remove := parseTagEdits(s); len(add)+len(remove) == 0 {
+						return errors.New("enter at least one tag")
+					}
+					return nil
+				}),
+		).WithHideFunc(func() bool { return v.action != bulkTag }),
+	).
+		WithShowHelp(false).
+		WithKeyMap(runFormKeyMap()).
+		WithTheme(hostFormTheme(appTheme))
+
+	f.SubmitCmd = func() tea.Msg { return bulkFormDoneMsg{bulk: b, ok: true} }
+	f.CancelCmd = func() tea.Msg { return bulkFormDoneMsg{bulk: b} }
+	return f
+}
+
+// handleBulkKeyMsg routes keys to the bulk form.
+func (m model) handleBulkKeyMsg(msg tea.KeyMsg) (model, tea.Cmd) {
+	b := m.ms.bulk
+	if b == nil || b.form == nil {
+		return m.closeBulk()
+	}
+	mdl, cmd := b.form.Update(msg)
+	if f, ok := mdl.(*huh.Form); ok {
+		b.form = f
+	}
+	return m, cmd
+}
+
+// closeBulk closes the bulk form.
+func (m model) closeBulk() (model, tea.Cmd) {
+	m.mode = modeMenu
+	m.ms.bulk = nil
+	m.relayout()
+	return m, nil
+}
+
+// handleBulkFormDoneMsg hands the picked action to the run form, or asks
+// for confirmation with the list of hosts it applies to.
+func (m model) handleBulkFormDoneMsg(msg bulkFormDoneMsg) (model, tea.Cmd) {
+	b := m.ms.bulk
+	if m.mode != modeBulk || b == nil || msg.bulk != b {
+		return m, nil
+	}
+	m, _ = m.closeBulk()
+	if !msg.ok {
+		return m, m.setStatusError(ErrorX+"Canceled bulk action.", statusTTL)
+	}
+
+	v := b.values
+	if v.action == bulkRun {
+		return m.openBulkRunForm(b.hosts)
+	}
+
+	hosts := b.hosts
+	var title, description string
+	var apply tea.Cmd
+	switch v.action {
+	case bulkRemove:
+		title = fmt.Sprintf("Remove %d host(s)?", len(hosts))
+		description = "This will remove the hosts from their config files."
+		apply = bulkRemoveCmd(hosts)
+	case bulkMove:
+		group := strings.TrimSpace(v.group)
+		target := str.FormatDisplayName(group, true)
+		if target == "" {
+			target = "(no group)"
+		}
+		title = fmt.Sprintf("Move %d host(s) to %s?", len(hosts), target)
+		description = "Hosts are renamed; ProxyJump references to them aren't updated."
+		apply = bulkMoveCmd(hosts, group)
+	case bulkTag:
+		add, remove := parseTagEdits(v.tags)
+		title = fmt.Sprintf("Tag %d host(s)?", len(hosts))
+		description = describeTagEdits(add, remove)
+		apply = bulkTagCmd(hosts, add, remove)
+	case bulkExport:
+		dir, _ := exportDir()
+		title = fmt.Sprintf("Export %d host(s)?", len(hosts))
+		description = "Host blocks are written to " + dir + "."
+		apply = bulkExportCmd(hosts)
+	case bulkTabs:
+		l := m.tabLauncher()
+		if l == nil {
+			return m, m.setStatusError(launch.ErrNoBackend.Error(), statusTTL)
+		}
+		title = fmt.Sprintf("Open %d host(s) in tabs?", len(hosts))
+		description = "Each ssh host opens in a new " + l.Name() + "."
+		apply = bulkTabsCmd(hosts, l)
+	default:
+		return m, nil
+	}
+
+	form := buildConfirmForm(title, description, m.theme)
+	m.mode = modeConfirm
+	m.ms.confirm = &confirmState{
+		form:        form,
+		title:       title,
+		description: description,
+		body:        m.buildBulkHostList(v.action, hosts),
+		onConfirm:   apply,
+		onCancel:    m.setStatusError(ErrorX+"Canceled bulk "+string(v.action)+".", statusTTL),
+	}
+	m.relayout()
+	return m, form.Init()
+}
+
+// openBulkRunForm opens the run form for the ssh hosts among hosts.
+func (m model) openBulkRunForm(hosts []*menuItem) (model, tea.Cmd) {
+	var aliases []string
+	skipped := 0
+	for _, h := range hosts {
+		if h.protocol != config.ProtocolSSH {
+			skipped++
+			continue
+		}
+		aliases = append(aliases, h.spec.Alias)
+	}
+	if len(aliases) == 0 {
+		return m, m.setStatusError("None of the marked hosts are ssh hosts.", statusTTL)
+	}
+	return m.openRunFormFor(fmt.Sprintf("%d marked host(s)", len(hosts)), aliases, skipped)
+}
+
+// buildBulkHostList renders the hosts a bulk action applies to, for the
+// confirmation's primary panel.
+func (m model) buildBulkHostList(action bulkAction, hosts []*menuItem) string {
+	s := m.newDetailsStyles()
+	var b strings.Builder
+	b.WriteString(s.header.Render("BULK " + strings.ToUpper(string(action))))
+	b.WriteString("\n\n")
+	for i, h := range hosts {
+		if i == bulkListMax {
+			fmt.Fprintf(&b, "%s\n", s.label.Render(fmt.Sprintf("… and %d more", len(hosts)-i)))
+			break
+		}
+		proto := s.proto.Foreground(m.theme.protocolColor(h.protocol))
+		line := proto.PaddingLeft(4).Render(fmt.Sprintf("%-6s", string(h.protocol))) + "  " + s.value.Render(h.spec.Alias)
+		if h.spec.HostName != "" {
+			line += s.value.Foreground(m.theme.PreflightText).Render(" <" + h.spec.HostName + ">")
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// handleBulkDoneMsg reports the outcome of a bulk action and reloads the
+// menu if the configs changed.
+func (m model) handleBulkDoneMsg(msg bulkDoneMsg) (model, tea.Cmd) {
+	var text string
+	switch msg.action {
+	case bulkRemove:
+		text = fmt.Sprintf("Removed %d host(s)", msg.done)
+	case bulkMove:
+		text = fmt.Sprintf("Moved %d host(s)", msg.done)
+	case bulkTag:
+		text = fmt.Sprintf("Tagged %d host(s)", msg.done)
+	case bulkExport:
+		text = fmt.Sprintf("Exported %d host(s) to %s", msg.done, strings.Join(msg.paths, ", "))
+	case bulkTabs:
+		text = fmt.Sprintf("Opened %d tab(s)", msg.done)
+	}
+	if msg.skipped > 0 {
+		text += fmt.Sprintf(" (%d non-ssh skipped)", msg.skipped)
+	}
+
+	for oldKey, newKey := range msg.renamed {
+		if _, ok := m.marked[oldKey]; ok {
+			delete(m.marked, oldKey)
+			m.marked[newKey] = struct{}{}
+		}
+	}
+
+	var reloadCmd tea.Cmd
+	switch msg.action {
+	case bulkRemove, bulkMove, bulkTag:
+		reloadCmd = func() tea.Msg {
+			root, err := seedMenu()
+			return menuReloadedMsg{root: root, err: err}
+		}
+	}
+
+	if len(msg.failed) > 0 {
+		text += fmt.Sprintf("; %d failed:\n%s", len(msg.failed), summarizeList(msg.failed, 5))
+		return m, tea.Batch(m.setStatusError(text, 0), reloadCmd)
+	}
+	return m, tea.Batch(m.setStatusSuccess(text+SuccessCheck, statusTTL), reloadCmd)
+}
+
+// bulkRemoveCmd removes every host from its config file.
+func bulkRemoveCmd(hosts []*menuItem) tea.Cmd {
+	return func() tea.Msg {
+		msg := bulkDoneMsg{action: bulkRemove}
+		for _, h := range hosts {
+			inv, err := h.inventory()
+			if err == nil {
+				err = inv.RemoveHost(h.spec.Alias)
+			}
+			msg.record(h, err)
+		}
+		return msg
+	}
+}
+
+// bulkMoveCmd renames every host into group (blank for no group), keeping
+// the nickname part of its alias.
+func bulkMoveCmd(hosts []*menuItem, group string) tea.Cmd {
+	return func() tea.Msg {
+		msg := bulkDoneMsg{action: bulkMove, renamed: map[string]string{}}
+		for _, h := range hosts {
+			nick := h.spec.Alias
+			if _, n, ok := str.SplitStringOnDelim(h.spec.Alias); ok {
+				nick = n
+			}
+			alias, err := str.BuildAliasFromGroupNickname(group, nick)
+			if err == nil && alias != h.spec.Alias {
+				entry := entryForItem(h)
+				entry.Spec.Alias = alias
+				err = updateItem(h, entry)
+				if err == nil {
+					moved := *h
+					moved.spec.Alias = alias
+					msg.renamed[hostKey(h)] = hostKey(&moved)
+				}
+			}
+			msg.record(h, err)
+		}
+		return msg
+	}
+}
+
+// updateItem replaces a host item's entry in the inventory it was read from.
+func updateItem(h *menuItem, entry config.HostEntry) error {
+	inv, err := h.inventory()
+	if err != nil {
+		return err
+	}
+	return inv.UpdateHost(h.spec.Alias, entry)
+}
+
+// bulkTagCmd adds and removes tags on every host.
+func bulkTagCmd(hosts []*menuItem, add, remove []string) tea.Cmd {
+	return func() tea.Msg {
+		msg := bulkDoneMsg{action: bulkTag}
+		for _, h := range hosts {
+			entry := entryForItem(h)
+			tags := slices.DeleteFunc(config.ParseTags(entry.AppOptions.Tags), func(t string) bool {
+				return slices.Contains(remove, t)
+			})
+			for _, t := range add {
+				if !slices.Contains(tags, t) {
+					tags = append(tags, t)
+				}
+			}
+			entry.AppOptions.Tags = config.FormatTags(tags)
+			msg.record(h, updateItem(h, entry))
+		}
+		return msg
+	}
+}
+
+// bulkExportCmd writes the hosts to new config files in the export
+// directory, one per protocol.
+func bulkExportCmd(hosts []*menuItem) tea.Cmd {
+	return func() tea.Msg {
+		msg := bulkDoneMsg{action: bulkExport}
+		dir, err := exportDir()
+		if err == nil {
+			err = os.MkdirAll(dir, 0o700)
+		}
+		if err != nil {
+			msg.failed = append(msg.failed, "export: "+err.Error())
+			return msg
+		}
+
+		stamp := time.Now().Format("20060102-150405")
+		byProtocol := map[config.Protocol][]config.HostEntry{}
+		var order []config.Protocol
+		for _, h := range hosts {
+			if _, ok := byProtocol[h.protocol]; !ok {
+				order = append(order, h.protocol)
+			}
+			byProtocol[h.protocol] = append(byProtocol[h.protocol], entryForItem(h))
+		}
+		for _, p := range order {
+			path := filepath.Join(dir, "hosts-"+stamp+"-"+string(p)+".conf")
+			if err := config.WriteHostEntries(path, byProtocol[p]); err != nil {
+				msg.failed = append(msg.failed, string(p)+": "+err.Error())
+				continue
+			}
+			msg.done += len(byProtocol[p])
+			msg.paths = append(msg.paths, path)
+		}
+		return msg
+	}
+}
+
+// bulkTabsCmd opens every host that has an external client with the
+// launcher, skipping built-in clients and hosts with a login script.
+// Pre-connect hooks run before each host is opened; post-connect hooks
+// can't, since the sessions outlive this command.
+func bulkTabsCmd(hosts []*menuItem, l launch.Launcher) tea.Cmd {
+	return func() tea.Msg {
+		msg := bulkDoneMsg{action: bulkTabs}
+		for _, h := range hosts {
+			t := connect.Target{Protocol: h.protocol, Spec: h.spec, Telnet: h.telnet, Command: h.command, Serial: h.serial,
+				ConfigFile: configFileFor(h)}
+			argv, err := connect.SessionArgs(t)
+			// login scripts only run in sessions started from the menu itself
+			if errors.Is(err, connect.ErrNoExternalClient) || h.app.Script != "" {
+				msg.skipped++
+				continue
+			}
+			if err == nil {
+				err = launchTab(h, t, l, argv)
+			}
+			msg.record(h, err)
+		}
+		return msg
+	}
+}
+
+// launchTab runs a host's pre-connect hooks and opens it in a tab. If the
+// tab can't be opened, the post-connect hooks run right away, so whatever
+// the pre-connect hooks set up is torn down again.
+func launchTab(h *menuItem, t connect.Target, l launch.Launcher, argv []string) error {
+	pre, post, err := hooksFor(h)
+	if err != nil {
+		return err
+	}
+	if err := hooks.Run(context.Background(), pre, hooks.NewEnv(hooks.Pre, t)); err != nil {
+		return err
+	}
+	err = l.Launch(t.WindowTitle(), argv)
+	if err != nil && len(post) > 0 {
+		env := hooks.NewEnv(hooks.Post, t)
+		env.Err = err
+		if herr := hooks.Run(context.Background(), post, env); herr != nil {
+			return errors.Join(err, herr)
+		}
+	}
+	return err
+}
+
+// tabLauncher returns the launcher for opening hosts in tabs: the configured
+// one, or else tmux/screen if the app runs inside one (nil if neither).
+func (m model) tabLauncher() launch.Launcher {
+	if m.launcher != nil {
+		return m.launcher
+	}
+	return launch.Detect()
+}
+
+// record counts a host as done, or adds it to the failures.
+func (msg *bulkDoneMsg) record(h *menuItem, err error) {
+	if err != nil {
+		msg.failed = append(msg.failed, h.spec.Alias+": "+err.Error())
+		return
+	}
+	msg.done++
+}
+
+// exportDir returns the directory bulk exports are written to.
+func exportDir() (string, error) {
+	return config.GetDataPath("exports")
+}
+
+// entryForItem returns the config entry for a host menu item.
+func entryForItem(h *menuItem) config.HostEntry {
+	entry := config.EntryFromSpec(h.spec, h.options, "")
+	entry.TelnetOptions = h.telnet
+	entry.CommandOptions = h.command
+	entry.SerialOptions = h.serial
+	entry.AppOptions = h.app
+	return entry
+}
+
+// parseTagEdits splits tag input into tags to add and tags to remove
+// (prefixed with -). A leading + is allowed on tags to add.
+func parseTagEdits(s string) (add, remove []string) {
+	for _, t := range config.ParseTags(s) {
+		switch {
+		case strings.HasPrefix(t, "-"):
+			if t = strings.TrimLeft(t, "-"); t != "" {
+				remove = append(remove, t)
+			}
+		default:
+			if t = strings.TrimLeft(t, "+"); t != "" {
+				add = append(add, t)
+			}
+		}
+	}
+	return add, remove
+}
+
+// describeTagEdits describes tag edits for the confirmation (eg. "Add prod; remove old.").
+func describeTagEdits(add, remove []string) string {
+	var parts []string
+	if len(add) > 0 {
+		parts = append(parts, "add "+strings.Join(add, ", "))
+	}
+	if len(remove) > 0 {
+		parts = append(parts, "remove "+strings.Join(remove, ", "))
+	}
+	s := strings.Join(parts, "; ") + "."
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// summarizeList joins up to n items, noting how many more were left out.
+func summarizeList(items []string, n int) string {
+	if len(items) <= n {
+		return strings.Join(items, ", ")
+	}
+	return strings.Join(items[:n], ", ") + fmt.Sprintf(" and %d more", len(items)-n)
+}
+
+// bulkHelpKeys returns the help keys shown under the bulk form.
+func (m model) bulkHelpKeys() []key.Binding {
+	return []key.Binding{m.keys.CloseForm, m.keys.FormPrev, m.keys.FormNext, m.keys.FormSelect}
+}
+
+// resizeBulk sizes the bulk form to the window.
+func (m *model) resizeBulk() {
+	if m.ms.bulk == nil || m.ms.bulk.form == nil {
+		return
+	}
+	m.ms.bulk.form = m.ms.bulk.form.WithWidth(max(0, min(m.width-hostFormPadding, 80)))
+}
+
+// viewBulk renders the bulk action form.
+func (m model) viewBulk() string {
+	b := m.ms.bulk
+	if b == nil || b.form == nil {
+		return ""
+	}
+	lg := lipgloss.NewStyle()
+	h := m.lst.Help
+	h.Width = m.width
+	help := lg.PaddingLeft(footerPadLeft).PaddingTop(1).Render(h.ShortHelpView(m.bulkHelpKeys()))
+	body := lg.Padding(1, 3).Render(b.form.View())
+	return strings.Join([]string{body, help}, "\n")
+}
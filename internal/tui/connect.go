@@ -1,20 +1,23 @@
 package tui
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
+	"io"
+	"os"
 	"strings"
 	"time"
 
 	"bubbletea-ssh-manager/internal/config"
 	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/hooks"
+	"bubbletea-ssh-manager/internal/launch"
+	"bubbletea-ssh-manager/internal/record"
 	str "bubbletea-ssh-manager/internal/stringutil"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-const preflightTimeout = 10 * time.Second
-
 // preflightTickCmd returns a command that waits 1 second and then sends a preflightTickMsg
 // with the given token.
 //
@@ -28,14 +31,74 @@ func preflightTickCmd(token int) tea.Cmd {
 // preflightDialCmd returns a command that attempts to dial the given host:port
 // and sends a preflightResultMsg with the result.
 //
-// It uses the given token to identify which preflight this result belongs to.
-func preflightDialCmd(token int, hostPort string) tea.Cmd {
+// For protocols with the ssh preflight strategy, it also reads the server
+// banner and checks the host key against alias's known_hosts files under the
+// name knownHost/knownPort.
+//
+// The attempt is bounded by timeout and aborted as soon as ctx is canceled.
+// It uses the given token and attempt to identify which preflight this result belongs to.
+func preflightDialCmd(ctx context.Context, token, attempt int, timeout time.Duration,
+	protocol config.Protocol, alias, hostPort, knownHost, knownPort string) tea.Cmd {
 	return func() tea.Msg {
-		err := connect.PreflightDial(hostPort, preflightTimeout)
-		return preflightResultMsg{token: token, err: err}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if info, _ := config.LookupProtocol(protocol); info.Preflight == config.PreflightSSH {
+			files, _ := connect.KnownHostsFiles(alias)
+			res, err := connect.PreflightSSH(ctx, hostPort, knownHost, knownPort, files)
+			return preflightResultMsg{token: token, attempt: attempt, err: err, ssh: &res}
+		}
+		err := connect.PreflightDial(ctx, hostPort)
+		return preflightResultMsg{token: token, attempt: attempt, err: err}
 	}
 }
 
+// preflightRetryCmd returns a command that sends a preflightRetryMsg after delay.
+func preflightRetryCmd(token, attempt int, delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return preflightRetryMsg{token: token, attempt: attempt}
+	})
+}
+
+// startPreflightAttempt resets the countdown and returns the command that
+// dials for the current attempt.
+func (m *model) startPreflightAttempt() tea.Cmd {
+	pf := &m.ms.preflight
+	pf.retrying = false
+	pf.remaining = int(pf.policy.Timeout.Round(time.Second).Seconds())
+	pf.endsAt = time.Now().Add(pf.policy.Timeout)
+	return preflightDialCmd(pf.ctx, pf.token, pf.attempt, pf.policy.Timeout,
+		pf.protocol, pf.alias, pf.hostPort, pf.knownHost, pf.knownPort)
+}
+
+// preflightPolicyFor returns the preflight timeout/retry policy for a host:
+// the global defaults with the host's own settings applied.
+func (m model) preflightPolicyFor(it *menuItem) connect.PreflightPolicy {
+	return connect.DefaultPreflightPolicy().ForHost(it.app)
+}
+
+// noteCommand prints a line to the terminal before running the wrapped command.
+type noteCommand struct {
+	connect.Command
+	note   string
+	stdout io.Writer
+}
+
+// SetStdout sets the output for the note and the wrapped command.
+func (c *noteCommand) SetStdout(w io.Writer) {
+	c.stdout = w
+	c.Command.SetStdout(w)
+}
+
+// Run prints the note, then runs the wrapped command.
+func (c *noteCommand) Run() error {
+	out := c.stdout
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintf(out, "%s\r\n", c.note)
+	return c.Command.Run()
+}
+
 // cancelPreflightCmd cancels the current preflight operation.
 //
 // It returns the updated model, a command that sends a connectFinishedMsg
@@ -46,17 +109,35 @@ func (m model) cancelPreflightCmd() (model, tea.Cmd) {
 	if target == "" {
 		target = m.ms.preflight.hostPort
 	}
+	var post postHooks
+	if m.preHooksRan {
+		post = m.ms.preflight.post
+		post.started = time.Now()
+		m.preHooksRan = false
+	}
 	m.clearPreflightState()
 	return m, func() tea.Msg {
-		return connectFinishedMsg{protocol: protocol, target: target, err: connect.ErrAborted}
+		return connectFinishedMsg{protocol: protocol, target: target, err: connect.ErrAborted, post: post}
 	}
 }
 
 // initPreflightState sets all preflight state fields.
 //
-// Pass zero values to clear the state. The token is always incremented.
+// Pass zero values to clear the state. The token is always incremented, and
+// any running preflight dial is canceled.
 func (m *model) initPreflightState(protocol config.Protocol, hostPort, windowTitle, display string,
-	cmd *exec.Cmd, tail *connect.TailBuffer) int {
+	cmd connect.Command, tail *connect.TailBuffer) int {
+
+	if m.ms.preflight.cancel != nil {
+		m.ms.preflight.cancel()
+	}
+	m.ms.preflight.ctx = nil
+	m.ms.preflight.cancel = nil
+	m.ms.preflight.policy = connect.PreflightPolicy{}
+	m.ms.preflight.attempt = 0
+	m.ms.preflight.retrying = false
+	m.ms.preflight.remaining = 0
+	m.ms.preflight.endsAt = time.Time{}
 
 	m.ms.preflight.token++
 	m.ms.preflight.protocol = protocol
@@ -65,13 +146,17 @@ func (m *model) initPreflightState(protocol config.Protocol, hostPort, windowTit
 	m.ms.preflight.display = display
 	m.ms.preflight.cmd = cmd
 	m.ms.preflight.tail = tail
+	m.ms.preflight.knownHost = ""
+	m.ms.preflight.knownPort = ""
+	m.ms.preflight.alias = ""
+	m.ms.preflight.via = ""
+	m.ms.preflight.capture = record.Capture{}
+	m.ms.preflight.argv = nil
+	m.ms.preflight.hooks = false
+	m.ms.preflight.post = postHooks{}
 
 	if hostPort != "" {
-		m.ms.preflight.remaining = int(preflightTimeout.Seconds())
-		m.ms.preflight.endsAt = time.Now().Add(preflightTimeout)
-	} else {
-		m.ms.preflight.remaining = 0
-		m.ms.preflight.endsAt = time.Time{}
+		m.ms.preflight.ctx, m.ms.preflight.cancel = context.WithCancel(context.Background())
 	}
 
 	return m.ms.preflight.token
@@ -83,6 +168,67 @@ func (m *model) clearPreflightState() {
 	m.initPreflightState("", "", "", "", nil, nil)
 }
 
+// configFileFor returns the ssh config to connect to an ssh host item with:
+// its inventory's, if that isn't the user's own ssh config.
+func configFileFor(it *menuItem) string {
+	if it.protocol != config.ProtocolSSH || it.inv.Name == "" || it.inv.IsLocal() {
+		return ""
+	}
+	return it.inv.Path
+}
+
+// targetFor builds the connect.Target for a host menu item.
+//
+// For SSH hosts with a ProxyJump, the jump chain is resolved against the
+// other SSH hosts of the item's inventory so preflight and details can show
+// every hop.
+func (m model) targetFor(it *menuItem) (connect.Target, error) {
+	hosts, _ := getHostItemsWithHints(m.root)
+	return targetWith(it, jumpLookups(hosts))
+}
+
+// targetWith builds the connect.Target for a host menu item, resolving its
+// jump chain with lookups (see jumpLookups).
+func targetWith(it *menuItem, lookups func(inventory string) connect.JumpLookup) (connect.Target, error) {
+	t := connect.Target{Protocol: it.protocol, Spec: it.spec, Telnet: it.telnet, Command: it.command,
+		Serial: it.serial, Script: it.app.Script, Secret: it.app.Secret, ConfigFile: configFileFor(it)}
+	if it.protocol != config.ProtocolSSH || it.options.ProxyJump == "" {
+		return t, nil
+	}
+	jumps, err := connect.ResolveJumpChain(it.options.ProxyJump, lookups(it.inv.Name))
+	if err != nil {
+		return t, fmt.Errorf("%s: %w", it.spec.Alias, err)
+	}
+	t.Jumps = jumps
+	return t, nil
+}
+
+// jumpLookups indexes the SSH hosts by inventory and returns the
+// connect.JumpLookup backed by one inventory's hosts. Build it once to
+// resolve many hosts' jump chains.
+func jumpLookups(hosts []*menuItem) func(inventory string) connect.JumpLookup {
+	byInventory := make(map[string]map[string]*menuItem)
+	for _, h := range hosts {
+		if h.protocol != config.ProtocolSSH || h.spec.Alias == "" {
+			continue
+		}
+		if byInventory[h.inv.Name] == nil {
+			byInventory[h.inv.Name] = make(map[string]*menuItem)
+		}
+		byInventory[h.inv.Name][h.spec.Alias] = h
+	}
+	return func(inventory string) connect.JumpLookup {
+		byAlias := byInventory[inventory]
+		return func(alias string) (config.Spec, config.SSHOptions, bool) {
+			h, ok := byAlias[alias]
+			if !ok {
+				return config.Spec{}, config.SSHOptions{}, false
+			}
+			return h.spec, h.options, true
+		}
+	}
+}
+
 // startConnect builds and starts the connection command for the given menu item.
 //
 // It sets the status message and returns a command to execute the connection process.
@@ -95,38 +241,98 @@ func (m model) startConnect(it *menuItem) (model, tea.Cmd) {
 		return m, m.setStatusError("No host selected.", 0)
 	}
 
-	cmd, tgt, tail, err := connect.BuildCommand(connect.Target{
-		Protocol: it.protocol,
-		Spec:     it.spec,
-	})
+	trgt, err := m.targetFor(it)
 	if err != nil {
 		return m, m.setStatusError(err.Error(), 0)
 	}
+	pre, post, err := hooksFor(it)
+	if err != nil {
+		return m, m.setStatusError(err.Error(), 0)
+	}
+	if len(pre) > 0 {
+		return m.startPreHooks(it, trgt, pre, post)
+	}
+	return m.connectHost(it, post)
+}
+
+// connectHost builds the connection command for the given menu item and
+// starts it, after a preflight check if the protocol has one. post are the
+// post-connect hooks to run once the session ends (or now, if it can't
+// start and the pre-connect hooks already ran).
+func (m model) connectHost(it *menuItem, post []hooks.Hook) (model, tea.Cmd) {
+	trgt, err := m.targetFor(it)
+	fail := func(m model, status string, err error, ttl time.Duration) (model, tea.Cmd) {
+		statusCmd := m.setStatusError(status, ttl)
+		return m, tea.Batch(statusCmd, m.owedPostHooksCmd(postHooks{hooks: post, env: hooks.NewEnv(hooks.Post, trgt)}, err))
+	}
+	if err != nil {
+		return fail(m, err.Error(), err, 0)
+	}
+
+	// record if the host asks for it, or if the user armed recording for this session
+	recording := it.app.Record || m.recordNext
+	m.recordNext = false
+	capture, err := record.NewCapture(trgt.Alias, trgt.WindowTitle(), recording, it.app.Log)
+	if err != nil {
+		return fail(m, "Session capture: "+err.Error(), err, 0)
+	}
+
+	cmd, tgt, tail, err := connect.BuildCommand(trgt, capture.Writers()...)
+	if err != nil {
+		capture.Close()
+		return fail(m, err.Error(), err, 0)
+	}
 
 	protocol := tgt.Protocol
 	display := tgt.Display()
 
+	// with a launcher the session opens alongside the menu; captured sessions,
+	// login scripts, password secrets, post-connect hooks and built-in clients
+	// (no argv) still run in place since they need this process
+	var argv []string
+	if m.launcher != nil && len(capture.Writers()) == 0 && tgt.Script == "" && tgt.Secret == "" && len(post) == 0 {
+		argv, _ = connect.SessionArgs(tgt)
+	}
+
 	// no preflight needed; start connection immediately
 	if !connect.ShouldPreflight(tgt) {
+		m.preHooksRan = false // owed hooks now run when the session ends
+		if argv != nil {
+			return m, launchSessionCmd(m.launcher, tgt.WindowTitle(), argv, protocol, display)
+		}
 		m.mode = modeExecuting
-		return m, launchExecCmd(tgt.WindowTitle(), cmd, protocol, display, tail)
+		m.pauseMonitor() // no probes while the session has the terminal
+		return m, launchExecCmd(tgt.WindowTitle(), cmd, protocol, tgt.Alias, display, tail, capture,
+			postHooks{hooks: post, env: hooks.NewEnv(hooks.Post, tgt)})
 	}
 
 	// preflight required
 	hostPort := connect.GenerateHostPort(tgt)
 	if hostPort == "" {
-		return m, m.setStatusError(fmt.Sprintf("%s: missing hostname", string(protocol)), statusTTL)
+		capture.Close()
+		err := fmt.Errorf("%s: missing hostname", string(protocol))
+		return fail(m, err.Error(), err, statusTTL)
 	}
 
 	m.mode = modePreflight
 	tok := m.initPreflightState(protocol, hostPort, tgt.WindowTitle(), display, cmd, tail)
+	m.ms.preflight.alias = tgt.Alias
+	m.ms.preflight.knownHost, m.ms.preflight.knownPort = connect.KnownHostsName(tgt)
+	if hop, ok := tgt.FirstHop(); ok {
+		m.ms.preflight.via = hop.Alias
+	}
+	m.ms.preflight.capture = capture
+	m.ms.preflight.argv = argv
+	m.ms.preflight.post = postHooks{hooks: post, env: hooks.NewEnv(hooks.Post, tgt)}
+	m.ms.preflight.policy = m.preflightPolicyFor(it)
+	m.ms.preflight.attempt = 1
 	m.setStatusInfo("", 0)
 
-	return m, tea.Batch(preflightDialCmd(tok, hostPort), preflightTickCmd(tok), m.spinner.Tick)
+	return m, tea.Batch(m.startPreflightAttempt(), preflightTickCmd(tok), m.spinner.Tick)
 }
 
 // launchExecCmd returns a command that exits the TUI and starts
-// the given exec.Cmd in the main terminal.
+// the given connection command in the main terminal.
 //
 // tea.ExitAltScreen is used to to make every connection login
 // session starts fresh in the main terminal, avoiding issues
@@ -134,18 +340,34 @@ func (m model) startConnect(it *menuItem) (model, tea.Cmd) {
 //
 // It sets the window title before starting the command, and sends a
 // connectFinishedMsg when the command exits, capturing any output from
-// the provided TailBuffer for error reporting.
-func launchExecCmd(windowTitle string, cmd *exec.Cmd, protocol config.Protocol, target string, tail *connect.TailBuffer) tea.Cmd {
+// the provided TailBuffer for error reporting. Failures are classified from
+// the full tail so the status can explain them. Any session recording or
+// log is closed once the command exits, and the post-connect hooks are
+// passed along to run next.
+func launchExecCmd(windowTitle string, cmd connect.Command, protocol config.Protocol, alias, target string,
+	tail *connect.TailBuffer, capture record.Capture, post postHooks) tea.Cmd {
+	post.started = time.Now()
 	return tea.Sequence(
 		tea.ExitAltScreen,
 		tea.SetWindowTitle(windowTitle),
-		tea.ExecProcess(cmd, func(err error) tea.Msg {
-			out := ""
+		tea.Exec(cmd, func(err error) tea.Msg {
+			out, full := "", ""
 			if tail != nil {
-				out = strings.TrimSpace(tail.String())
-				out = str.LastNonEmptyLine(out)
+				full = strings.TrimSpace(tail.String())
+				out = str.LastNonEmptyLine(full)
 			}
-			return connectFinishedMsg{protocol: protocol, target: target, err: err, output: out}
+			recording, logPath := capture.Close()
+			return connectFinishedMsg{protocol: protocol, alias: alias, target: target, err: err, output: out,
+				failure: connect.ClassifyFailure(protocol, full, err), recording: recording, logPath: logPath, post: post}
 		}),
 	)
 }
+
+// launchSessionCmd returns a command that opens the session argv with l,
+// leaving the menu running, and sends a sessionLaunchedMsg.
+func launchSessionCmd(l launch.Launcher, windowTitle string, argv []string, protocol config.Protocol, target string) tea.Cmd {
+	return func() tea.Msg {
+		err := l.Launch(windowTitle, argv)
+		return sessionLaunchedMsg{protocol: protocol, target: target, launcher: l.Name(), err: err}
+	}
+}
@@ -1,10 +1,12 @@
 package tui
 
 import (
+	"cmp"
 	"fmt"
 	"strings"
 
 	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -14,8 +16,7 @@ type detailsStyles struct {
 	header       lipgloss.Style
 	label        lipgloss.Style
 	value        lipgloss.Style
-	protoSSH     lipgloss.Style
-	protoTelnet  lipgloss.Style
+	proto        lipgloss.Style
 	optionsLabel lipgloss.Style
 	optionsValue lipgloss.Style
 }
@@ -29,8 +30,7 @@ func (m model) newDetailsStyles() detailsStyles {
 			Bold(true),
 		label:        lipgloss.NewStyle().Foreground(m.theme.DetailsLabel).PaddingLeft(4),
 		value:        lipgloss.NewStyle().Foreground(m.theme.StatusDefault),
-		protoSSH:     lipgloss.NewStyle().Foreground(m.theme.ProtocolSSH).Bold(true),
-		protoTelnet:  lipgloss.NewStyle().Foreground(m.theme.ProtocolTelnet).Bold(true),
+		proto:        lipgloss.NewStyle().Bold(true),
 		optionsLabel: lipgloss.NewStyle().Foreground(m.theme.OptionsLabel).PaddingLeft(4),
 		optionsValue: lipgloss.NewStyle().Foreground(m.theme.StatusDefault).PaddingLeft(4),
 	}
@@ -52,25 +52,100 @@ func (m model) buildHostDetails() string {
 	b.WriteString("\n\n")
 	b.WriteString(m.buildHostInfo(it, s))
 
-	if it.protocol == config.ProtocolSSH {
+	info, _ := config.LookupProtocol(it.protocol)
+	if info.Has(config.FieldSSHOptions) && it.options.ProxyJump != "" {
+		b.WriteString("\n")
+		b.WriteString(s.header.PaddingBottom(1).Render("JUMP CHAIN"))
+		b.WriteString("\n")
+		b.WriteString(m.buildJumpChain(it, s))
+	}
+
+	if info.Has(config.FieldSSHOptions) {
 		b.WriteString("\n")
 		b.WriteString(s.header.PaddingBottom(1).Render("SSH OPTIONS"))
 		b.WriteString("\n")
 		b.WriteString(m.buildSSHOptions(it, s))
 		b.WriteString("\n")
+		b.WriteString(m.buildProbeSection(it, s))
+	}
+
+	if info.Has(config.FieldTermType) {
+		b.WriteString("\n")
+		b.WriteString(s.header.PaddingBottom(1).Render("TELNET OPTIONS"))
+		b.WriteString("\n")
+		b.WriteString(m.buildTelnetOptions(it, s))
+		b.WriteString("\n")
+	}
+
+	if info.Has(config.FieldSerial) {
+		b.WriteString("\n")
+		b.WriteString(s.header.PaddingBottom(1).Render("SERIAL OPTIONS"))
+		b.WriteString("\n")
+		b.WriteString(m.buildSerialOptions(it, s))
+		b.WriteString("\n")
+	}
+
+	if info.Has(config.FieldCommand) {
+		b.WriteString("\n")
+		b.WriteString(s.header.PaddingBottom(1).Render("COMMAND"))
+		b.WriteString("\n")
+		b.WriteString(m.buildCommandOptions(it, info, s))
+		b.WriteString("\n")
 	}
 
 	return b.String()
 }
 
+// sourceStatus describes the inventory a host was read from, if it isn't
+// the local config: its name and whether it's read-only.
+func sourceStatus(inv config.Inventory) string {
+	if inv.Name == "" || inv.IsLocal() {
+		return ""
+	}
+	if inv.Writable() != nil {
+		return inv.Name + " (read-only)"
+	}
+	return inv.Name
+}
+
 // buildHostInfo renders the core host fields (protocol, alias, hostname, etc.).
+//
+// Endpoint fields the protocol doesn't use (eg. hostname for serial) are left out.
 func (m model) buildHostInfo(it *menuItem, s detailsStyles) string {
+	info, _ := config.LookupProtocol(it.protocol)
 	rows := [][2]string{
 		{"Protocol", string(it.protocol)},
 		{"Alias", it.spec.Alias},
-		{"HostName", it.spec.HostName},
-		{"Port", it.spec.Port},
-		{"User", it.spec.User},
+	}
+	for _, f := range []struct {
+		field config.Field
+		label string
+		value string
+	}{
+		{config.FieldHostName, "HostName", it.spec.HostName},
+		{config.FieldPort, "Port", it.spec.Port},
+		{config.FieldUser, "User", it.spec.User},
+	} {
+		if info.Has(f.field) {
+			rows = append(rows, [2]string{f.label, f.value})
+		}
+	}
+	rows = append(rows,
+		[2]string{"Record", yesNo(it.app.Record)},
+		[2]string{"Log", yesNo(it.app.Log)},
+		[2]string{"Preflight", m.preflightPolicyFor(it).String()},
+		[2]string{"Tags", strings.Join(config.ParseTags(it.app.Tags), ", ")},
+	)
+	for _, r := range [][2]string{
+		{"Script", it.app.Script},
+		{"PreConnect", it.app.PreConnect},
+		{"PostConnect", it.app.PostConnect},
+		{"Secret", secretStatus(it.app.Secret)},
+		{"Source", sourceStatus(it.inv)},
+	} {
+		if r[1] != "" {
+			rows = append(rows, r)
+		}
 	}
 
 	maxLabelW := 0
@@ -87,13 +162,18 @@ func (m model) buildHostInfo(it *menuItem, s detailsStyles) string {
 	return b.String()
 }
 
+// yesNo formats a boolean setting for display.
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
 // renderInfoValue renders a value with appropriate styling based on field name.
 func (m model) renderInfoValue(field, value string, proto config.Protocol, s detailsStyles) string {
 	if field == "Protocol" {
-		if proto == config.ProtocolTelnet {
-			return s.protoTelnet.Render(value)
-		}
-		return s.protoSSH.Render(value)
+		return s.proto.Foreground(m.theme.protocolColor(proto)).Render(value)
 	}
 	return s.value.Render(value)
 }
@@ -102,7 +182,9 @@ func (m model) renderInfoValue(field, value string, proto config.Protocol, s det
 func (m model) buildSSHOptions(it *menuItem, s detailsStyles) string {
 	if it.options.HostKeyAlgorithms == "" &&
 		it.options.KexAlgorithms == "" &&
-		it.options.MACs == "" {
+		it.options.Ciphers == "" &&
+		it.options.MACs == "" &&
+		len(it.options.IdentityFiles) == 0 {
 		return s.optionsValue.Render("(none)")
 	}
 
@@ -119,3 +201,70 @@ func (m model) buildSSHOptions(it *menuItem, s detailsStyles) string {
 	}
 	return b.String()
 }
+
+// buildJumpChain renders the resolved ProxyJump chain, one hop per line,
+// ending with the host itself.
+func (m model) buildJumpChain(it *menuItem, s detailsStyles) string {
+	tgt, err := m.targetFor(it)
+	if err != nil {
+		return s.optionsValue.Render(ErrorX+err.Error()) + "\n"
+	}
+
+	var b strings.Builder
+	for i, hop := range tgt.Jumps {
+		hopTgt := connect.Target{Protocol: config.ProtocolSSH, Spec: hop}
+		hopTgt.Port = cmp.Or(hop.Port, "22")
+		hopTgt.HostName = cmp.Or(hop.HostName, hop.Alias)
+		fmt.Fprintf(&b, "%s  %s\n", s.label.Render(fmt.Sprintf("%d.", i+1)), s.value.Render(hopTgt.Display()))
+	}
+	fmt.Fprintf(&b, "%s  %s\n", s.label.Render("→"), s.value.Render(tgt.Display()))
+	return b.String()
+}
+
+// buildTelnetOptions renders the telnet options section.
+func (m model) buildTelnetOptions(it *menuItem, s detailsStyles) string {
+	if it.telnet.TermType == "" {
+		return s.optionsValue.Render("(none)")
+	}
+	return fmt.Sprintf("%s: %s\n", s.optionsLabel.Render("TermType"), s.value.Render(it.telnet.TermType))
+}
+
+// buildCommandOptions renders the command section: the template (or the
+// protocol's default) and the command it expands to.
+func (m model) buildCommandOptions(it *menuItem, info config.ProtocolInfo, s detailsStyles) string {
+	template := it.command.Command
+	switch {
+	case template == "" && info.Command == "":
+		return s.optionsValue.Render("(none, uses the built-in client)") + "\n"
+	case template == "":
+		template = info.Command + " (default)"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s\n", s.optionsLabel.Render("Template"), s.value.Render(template))
+	tgt, err := m.targetFor(it)
+	if err == nil {
+		var argv []string
+		if argv, err = connect.SessionArgs(tgt); err == nil {
+			fmt.Fprintf(&b, "%s: %s\n", s.optionsLabel.Render("Runs"), s.value.Render(strings.Join(argv, " ")))
+		}
+	}
+	if err != nil {
+		b.WriteString(s.optionsValue.Render(ErrorX+err.Error()) + "\n")
+	}
+	return b.String()
+}
+
+// buildSerialOptions renders the serial options section: the device and the
+// line settings with defaults filled in.
+func (m model) buildSerialOptions(it *menuItem, s detailsStyles) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s\n", s.optionsLabel.Render("Device"), s.value.Render(it.serial.Device))
+	settings, err := connect.ParseSerialSettings(it.serial)
+	if err != nil {
+		b.WriteString(s.optionsValue.Render(ErrorX+err.Error()) + "\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%s: %s\n", s.optionsLabel.Render("Line"), s.value.Render(settings.String()))
+	return b.String()
+}
@@ -0,0 +1,165 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// pendingFix is a one-key fix offered by the status line after a failed connect.
+type pendingFix struct {
+	alias     string            // ssh host alias to update (the target or the jump host that failed)
+	inventory string            // name of the inventory the alias is from
+	fix       connect.OptionFix // option change to apply
+}
+
+// YellowF renders the apply-fix key for status/help text.
+func YellowF() string {
+	return lipgloss.NewStyle().Foreground(DefaultTheme().KeyClear).Render("F")
+}
+
+// failureStatus formats a classified connection failure for the status line.
+func failureStatus(protocol config.Protocol, target string, f *connect.ConnectError) string {
+	lines := []string{
+		fmt.Sprintf("%s to %s failed: %s", string(protocol), target, f.Summary),
+		f.Explanation,
+	}
+	if f.Suggestion != "" {
+		lines = append(lines, "Fix: "+f.Suggestion)
+	}
+	if f.Kind == connect.FailureHostKeyChanged && protocol == config.ProtocolSSH {
+		lines = append(lines, "Press "+knownHostsSymbol+" on the host to review its known_hosts entries.")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// offerFix remembers a fix for the host so 'F' can apply it.
+//
+// Fixes are only offered for ssh hosts; the status line must be set first,
+// since changing the status drops any pending fix. If the failure was with
+// a jump host rather than the target, the fix is for the jump host, and
+// only if it's a host in the menu too.
+func (m *model) offerFix(protocol config.Protocol, alias string, f *connect.ConnectError) {
+	if f == nil || f.Fix == nil || protocol != config.ProtocolSSH || alias == "" {
+		return
+	}
+	host := m.sshHostItem(alias, "")
+	if host == nil {
+		return
+	}
+	tgt, err := m.targetFor(host)
+	if err != nil {
+		return
+	}
+	hop, ok := tgt.FailedHop(f)
+	switch {
+	case !ok:
+		m.status += "\nssh didn't say whether this was " + alias + " or one of its jump hosts, so no fix is offered."
+		m.relayout()
+		return
+	case hop >= 0:
+		jump := tgt.Jumps[hop].Alias
+		m.status += "\nThis was jump host " + jump + ", not " + alias + "."
+		if m.sshHostItem(jump, host.inv.Name) == nil {
+			m.status += " Add " + f.Fix.String() + " to its config."
+			m.relayout()
+			return
+		}
+		alias = jump
+	}
+	m.ms.pendingFix = &pendingFix{alias: alias, inventory: host.inv.Name, fix: *f.Fix}
+	m.status += "\nPress " + YellowF() + " to add " + f.Fix.String() + " to " + alias + "."
+	m.relayout()
+}
+
+// applyPendingFix saves the pending fix to the host's config.
+func (m model) applyPendingFix() (model, tea.Cmd) {
+	pf := m.ms.pendingFix
+	if pf == nil {
+		return m, nil
+	}
+	m.ms.pendingFix = nil
+
+	fixes := []connect.OptionFix{pf.fix}
+	inv, err := m.fixInventory(pf.alias, pf.inventory, fixes)
+	if err != nil {
+		return m, m.setStatusError(err.Error(), statusTTL)
+	}
+	m.setStatusInfo("Applying "+pf.fix.String()+"…", 0)
+	return m, applyFixCmd(inv, pf.alias, fixes)
+}
+
+// fixInventory returns the inventory to write fixes for the ssh host alias
+// to (from the named inventory, or any if it's ""), checking the host still
+// exists and the fixes are for options it can change.
+func (m model) fixInventory(alias, inventory string, fixes []connect.OptionFix) (config.Inventory, error) {
+	host := m.sshHostItem(alias, inventory)
+	if host == nil {
+		return config.Inventory{}, fmt.Errorf("host %s no longer exists", alias)
+	}
+	for _, f := range fixes {
+		if _, ok := host.options.AddAlgorithm(f.Option, f.Algorithm); !ok {
+			return config.Inventory{}, fmt.Errorf("unsupported option: %s", f.Option)
+		}
+	}
+	return host.inventory()
+}
+
+// sshHostItem returns the ssh host item for alias from the named inventory,
+// or from any inventory if it's "" (the local config's host wins if several
+// have the alias, like it does for ssh). It returns nil if there's none.
+func (m model) sshHostItem(alias, inventory string) *menuItem {
+	hosts, _ := getHostItemsWithHints(m.root)
+	for _, h := range hosts {
+		if h.protocol == config.ProtocolSSH && h.spec.Alias == alias && (inventory == "" || h.inv.Name == inventory) {
+			return h
+		}
+	}
+	return nil
+}
+
+// applyFixCmd returns a command that adds the fixes' algorithms to the
+// host's options in inv, editing just those lines of its Host block.
+func applyFixCmd(inv config.Inventory, alias string, fixes []connect.OptionFix) tea.Cmd {
+	return func() tea.Msg {
+		msg := fixAppliedMsg{alias: alias, fixes: fixes}
+		for _, f := range fixes {
+			err := inv.EditHostOption(alias, f.Option, func(value string) string {
+				return config.AppendAlgorithm(value, f.Algorithm)
+			})
+			if errors.Is(err, os.ErrNotExist) {
+				err = errors.New("config file not found")
+			}
+			if err != nil {
+				msg.err = err
+				break
+			}
+		}
+		return msg
+	}
+}
+
+// handleFixAppliedMsg reports the result of applying a fix and reloads the menu.
+func (m model) handleFixAppliedMsg(msg fixAppliedMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		return m, m.setStatusError(fmt.Sprintf("Failed to update %s: %v", msg.alias, msg.err), 0)
+	}
+
+	added := make([]string, 0, len(msg.fixes))
+	for _, f := range msg.fixes {
+		added = append(added, f.String())
+	}
+	statusCmd := m.setStatusSuccess(fmt.Sprintf("Added %s to %s%s", strings.Join(added, ", "), msg.alias, SuccessCheck), statusTTL)
+	reloadCmd := func() tea.Msg {
+		root, err := seedMenu()
+		return menuReloadedMsg{root: root, err: err}
+	}
+	return m, tea.Batch(statusCmd, reloadCmd)
+}
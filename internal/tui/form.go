@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"slices"
 	"strings"
 
 	"bubbletea-ssh-manager/internal/config"
@@ -10,13 +11,23 @@ import (
 )
 
 type form struct {
-	protocol  config.Protocol   // protocol
-	groupName string            // group name portion of alias (display form; spaces allowed)
-	nickname  string            // host nickname portion of alias (display form; spaces allowed)
-	hostname  string            // hostname or IP address
-	port      string            // port number as string
-	user      string            // user name
-	sshOpts   config.SSHOptions // SSH options
+	protocol  config.Protocol       // protocol
+	groupName string                // group name portion of alias (display form; spaces allowed)
+	nickname  string                // host nickname portion of alias (display form; spaces allowed)
+	hostname  string                // hostname or IP address
+	port      string                // port number as string
+	user      string                // user name
+	sshOpts   config.SSHOptions     // SSH options (IdentityFiles comes from identity on submit)
+	identity  string                // IdentityFile paths, comma separated
+	telnet    config.TelnetOptions  // telnet options
+	command   config.CommandOptions // command template options
+	serial    config.SerialOptions  // serial line options
+	app       config.AppOptions     // app-only settings
+	inventory string                // inventory the host is in, or is added to
+
+	password     string // new password to store in the vault ("" keeps the stored one)
+	vaultPass    string // vault passphrase, when the vault is locked or new
+	vaultConfirm string // vault passphrase again, when creating the vault
 }
 
 // openAddHostForm opens the host add form.
@@ -29,8 +40,14 @@ func (m model) openAddHostForm() (model, tea.Cmd) {
 	m.ms.hostFormMode = modeAdd
 	m.ms.hostFormOldAlias = ""
 
-	v := &form{protocol: config.ProtocolSSH}
-	form := buildHostForm(modeAdd, "", v, m.theme)
+	// hosts are added to the source being browsed, if it takes them
+	v := &form{protocol: config.ProtocolSSH, inventory: config.LocalInventory}
+	if cur := m.current(); cur.source != "" {
+		if inv, err := config.LookupInventory(cur.name, v.protocol); err == nil && inv.Writable() == nil {
+			v.inventory = inv.Name
+		}
+	}
+	form := buildHostForm(modeAdd, "", v, m.sshAliases(), m.theme)
 
 	m.ms.hostForm = form
 	m.ms.hostFormValues = v
@@ -47,6 +64,15 @@ func (m model) openEditHostForm() (model, tea.Cmd) {
 		m.setStatusError("Select a host to edit.", statusTTL)
 		return m, nil
 	}
+	inv, err := it.inventory()
+	if err == nil {
+		err = inv.Writable()
+	}
+	if err != nil {
+		m.mode = modeMenu // the status isn't shown over host details
+		m.relayout()
+		return m, m.setStatusError("Can't edit "+it.spec.Alias+": "+err.Error(), statusTTL)
+	}
 
 	// close other modals
 	m.mode = modeHostForm
@@ -67,10 +93,18 @@ func (m model) openEditHostForm() (model, tea.Cmd) {
 		sshOpts: config.SSHOptions{
 			HostKeyAlgorithms: it.options.HostKeyAlgorithms,
 			KexAlgorithms:     it.options.KexAlgorithms,
+			Ciphers:           it.options.Ciphers,
 			MACs:              it.options.MACs,
+			ProxyJump:         it.options.ProxyJump,
 		},
+		identity:  strings.Join(it.options.IdentityFiles, ", "),
+		telnet:    it.telnet,
+		command:   it.command,
+		serial:    it.serial,
+		app:       it.app,
+		inventory: inv.Name,
 	}
-	form := buildHostForm(modeEdit, m.ms.hostFormOldAlias, v, m.theme)
+	form := buildHostForm(modeEdit, m.ms.hostFormOldAlias, v, m.sshAliases(), m.theme)
 
 	m.ms.hostForm = form
 	m.ms.hostFormValues = v
@@ -78,6 +112,21 @@ func (m model) openEditHostForm() (model, tea.Cmd) {
 	return m, form.Init()
 }
 
+// sshAliases returns the aliases of all SSH hosts in the menu, sorted.
+//
+// Used to offer jump host choices in the host form.
+func (m model) sshAliases() []string {
+	hosts, _ := getHostItemsWithHints(m.root)
+	out := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		if h.protocol == config.ProtocolSSH && h.spec.Alias != "" {
+			out = append(out, h.spec.Alias)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
 // closeHostForm closes the host form and resets related state.
 //
 // If a non-empty status message is provided, it sets that status.
@@ -103,6 +152,15 @@ func (m model) openRemoveConfirm() (model, tea.Cmd) {
 		m.setStatusError("Select a host to remove.", statusTTL)
 		return m, nil
 	}
+	inv, err := it.inventory()
+	if err == nil {
+		err = inv.Writable()
+	}
+	if err != nil {
+		m.mode = modeMenu // the status isn't shown over host details
+		m.relayout()
+		return m, m.setStatusError("Can't remove "+it.spec.Alias+": "+err.Error(), statusTTL)
+	}
 
 	m.mode = modeConfirm
 
@@ -111,7 +169,7 @@ func (m model) openRemoveConfirm() (model, tea.Cmd) {
 	title := "Remove " + alias + "?"
 	description := "This will remove the host from the config file."
 	removeCmd := func() tea.Msg {
-		err := config.RemoveHostFromConfig(protocol, alias)
+		err := inv.RemoveHost(alias)
 		return removeHostResultMsg{
 			protocol: protocol,
 			alias:    alias,
@@ -144,6 +202,9 @@ func (m model) openHostFormConfirm() (model, tea.Cmd) {
 // closeConfirm closes the confirmation dialog and returns to the appropriate mode.
 func (m model) closeConfirm() (model, tea.Cmd) {
 	m.mode = modeMenu
+	if m.ms.confirm != nil {
+		m.mode = m.ms.confirm.returnMode
+	}
 	m.ms.confirm = nil
 	m.relayout()
 	return m, nil
@@ -4,6 +4,8 @@ import (
 	"strings"
 
 	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/expect"
 
 	"github.com/charmbracelet/bubbles/paginator"
 	tea "github.com/charmbracelet/bubbletea"
@@ -11,6 +13,41 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// hostFormPages is the number of visible pages in the host form
+// (main fields, protocol options, session options, password).
+const hostFormPages = 4
+
+// hostFormFieldPages maps option field keys to their paginator page.
+// Fields not listed are on the main page (0).
+var hostFormFieldPages = map[string]int{
+	"proxyjump":         1,
+	"hostkeyalgorithms": 1,
+	"kexalgorithms":     1,
+	"ciphers":           1,
+	"macs":              1,
+	"identityfile":      1,
+	"termtype":          1,
+	"command":           1,
+	"device":            1,
+	"baud":              1,
+	"databits":          1,
+	"parity":            1,
+	"stopbits":          1,
+	"flow":              1,
+	"record":            2,
+	"log":               2,
+	"preflighttimeout":  2,
+	"preflightretries":  2,
+	"tags":              2,
+	"script":            2,
+	"preconnect":        2,
+	"postconnect":       2,
+	"secret":            3,
+	"password":          3,
+	"vaultpass":         3,
+	"vaultconfirm":      3,
+}
+
 const (
 	hostFormStatusInnerWidth = 30                           // calculated based on content
 	hostFormStatusOuterWidth = hostFormStatusInnerWidth + 2 // border left+right
@@ -53,7 +90,9 @@ func buildConfirmForm(title string, description string, appTheme Theme) *huh.For
 // buildHostForm returns a Form which represents the data model for the host add/edit form.
 //
 // It holds the input values for the various fields.
-func buildHostForm(mode formMode, oldAlias string, v *form, appTheme Theme) *huh.Form {
+//
+// jumpAliases lists the SSH aliases that can be picked as a ProxyJump host.
+func buildHostForm(mode formMode, oldAlias string, v *form, jumpAliases []string, appTheme Theme) *huh.Form {
 	if v == nil {
 		v = &form{}
 	}
@@ -62,9 +101,16 @@ func buildHostForm(mode formMode, oldAlias string, v *form, appTheme Theme) *huh
 	}
 
 	mainGroup := buildMainFieldGroup(mode, v)
-	sshOptsGroup := buildSSHOptionsGroup(v)
-
-	form := huh.NewForm(mainGroup, sshOptsGroup).
+	sshOptsGroup := buildSSHOptionsGroup(v, oldAlias, jumpAliases)
+	telnetOptsGroup := buildTelnetOptionsGroup(v)
+	serialOptsGroup := buildSerialOptionsGroup(v)
+	commandOptsGroup := buildCommandOptionsGroup(v)
+	sessionOptsGroup := buildSessionOptionsGroup(v)
+	secretOptsGroup := buildSecretOptionsGroup(v)
+	vaultGroup := buildVaultGroup(v)
+
+	form := huh.NewForm(mainGroup, sshOptsGroup, telnetOptsGroup, serialOptsGroup, commandOptsGroup,
+		sessionOptsGroup, secretOptsGroup, vaultGroup).
 		WithShowHelp(false).
 		WithShowErrors(false).
 		WithKeyMap(NewFormKeyMap()).
@@ -84,6 +130,9 @@ func buildMainFieldGroup(mode formMode, v *form) *huh.Group {
 	fields := []huh.Field{note}
 	if mode == modeAdd {
 		fields = append(fields, buildProtocolField(v))
+		if config.HasOtherInventories() {
+			fields = append(fields, buildSourceField(v))
+		}
 	}
 	fields = append(fields,
 		buildInputField("group", "Group", &v.groupName),
@@ -95,18 +144,64 @@ func buildMainFieldGroup(mode formMode, v *form) *huh.Group {
 	return huh.NewGroup(fields...)
 }
 
-// buildProtocolField creates the protocol selector field.
+// buildProtocolField creates the protocol selector field, with an option
+// for every registered protocol.
 func buildProtocolField(v *form) *huh.Select[config.Protocol] {
+	var opts []huh.Option[config.Protocol]
+	for _, p := range config.Protocols() {
+		opts = append(opts, huh.NewOption(string(p.Name)+" ("+p.Description+")", p.Name))
+	}
 	return huh.NewSelect[config.Protocol]().
 		Key("protocol").
 		Title("Protocol").
-		Options(
-			huh.NewOption("ssh", config.ProtocolSSH),
-			huh.NewOption("telnet", config.ProtocolTelnet),
-		).
+		Options(opts...).
 		Value(&v.protocol)
 }
 
+// buildSourceField creates the selector for the inventory a new host is added
+// to, with an option for every writable inventory of the form's protocol.
+//
+// The form's inventory is listed first: huh scrolls a select to its value,
+// which would hide the options above it.
+func buildSourceField(v *form) *huh.Select[string] {
+	return huh.NewSelect[string]().
+		Key("source").
+		Title("Source").
+		OptionsFunc(func() []huh.Option[string] {
+			invs, _ := config.Inventories()
+			var opts []huh.Option[string]
+			for _, inv := range invs {
+				if inv.Protocol != v.protocol || inv.Writable() != nil {
+					continue
+				}
+				opt := huh.NewOption(inv.Name+" ("+inv.Path+")", inv.Name)
+				if inv.Name == v.inventory {
+					opts = append([]huh.Option[string]{opt}, opts...)
+				} else {
+					opts = append(opts, opt)
+				}
+			}
+			return opts
+		}, &v.protocol).
+		Value(&v.inventory)
+}
+
+// formProtocolHas reports whether the form's current protocol offers field f.
+func formProtocolHas(v *form, f config.Field) bool {
+	info, _ := config.LookupProtocol(v.protocol)
+	return info.Has(f)
+}
+
+// isFormSelectField returns true if f is one of the host form's select fields,
+// where enter picks an option instead of submitting.
+func isFormSelectField(f huh.Field) bool {
+	switch f.(type) {
+	case *huh.Select[config.Protocol], *huh.Select[string]:
+		return true
+	}
+	return false
+}
+
 // buildInputField creates a simple Huh text input field.
 func buildInputField(key, title string, value *string) *huh.Input {
 	return huh.NewInput().
@@ -115,20 +210,207 @@ func buildInputField(key, title string, value *string) *huh.Input {
 		Value(value)
 }
 
+// buildJumpHostField creates the ProxyJump selector field.
+//
+// Options are the other SSH aliases in the menu. A value that doesn't match a
+// single alias (eg. a hand-written "a,b" chain) is kept as its own option so
+// editing a host never silently drops it.
+func buildJumpHostField(v *form, oldAlias string, jumpAliases []string) *huh.Select[string] {
+	opts := []huh.Option[string]{huh.NewOption("(none)", "")}
+	current := strings.TrimSpace(v.sshOpts.ProxyJump)
+	found := current == ""
+	for _, a := range jumpAliases {
+		if a == oldAlias {
+			continue
+		}
+		if a == current {
+			found = true
+		}
+		opts = append(opts, huh.NewOption(a, a))
+	}
+	if !found {
+		opts = append(opts, huh.NewOption(current, current))
+	}
+
+	return huh.NewSelect[string]().
+		Key("proxyjump").
+		Title("Jump Host").
+		Options(opts...).
+		Height(5).
+		Value(&v.sshOpts.ProxyJump)
+}
+
 // buildSSHOptionsGroup creates the SSH-specific options Huh group.
-func buildSSHOptionsGroup(v *form) *huh.Group {
+func buildSSHOptionsGroup(v *form, oldAlias string, jumpAliases []string) *huh.Group {
 	note := huh.NewNote().Description(sshOptionsHelpText())
 
 	return huh.NewGroup(
 		note,
+		buildJumpHostField(v, oldAlias, jumpAliases),
 		buildInputField("hostkeyalgorithms", "HostKeyAlgorithms", &v.sshOpts.HostKeyAlgorithms),
 		buildInputField("kexalgorithms", "KexAlgorithms", &v.sshOpts.KexAlgorithms),
+		buildInputField("ciphers", "Ciphers", &v.sshOpts.Ciphers),
 		buildInputField("macs", "MACs", &v.sshOpts.MACs),
+		buildInputField("identityfile", "IdentityFile", &v.identity),
+	).WithHideFunc(func() bool {
+		return !formProtocolHas(v, config.FieldSSHOptions)
+	})
+}
+
+// buildTelnetOptionsGroup creates the telnet-specific options Huh group.
+func buildTelnetOptionsGroup(v *form) *huh.Group {
+	note := huh.NewNote().Description(
+		"Optional telnet settings. Leave blank to use defaults. Press " + GreenEnter() + " to save.\n\n" +
+			"_Terminal type is reported to the host (eg. VT100, VT220, XTERM).")
+
+	return huh.NewGroup(
+		note,
+		buildInputField("termtype", "Terminal Type", &v.telnet.TermType),
+	).WithHideFunc(func() bool {
+		return !formProtocolHas(v, config.FieldTermType)
+	})
+}
+
+// buildCommandOptionsGroup creates the command template options Huh group
+// (mosh, raw, serial, custom).
+func buildCommandOptionsGroup(v *form) *huh.Group {
+	note := huh.NewNote().DescriptionFunc(func() string {
+		info, _ := config.LookupProtocol(v.protocol)
+		def := "none, a command is required"
+		switch {
+		case info.Command != "":
+			def = info.Command
+		case info.Has(config.FieldSerial):
+			def = "none, the built-in serial client is used"
+		}
+		lines := []string{
+			"Command run to connect. Leave blank for the default. Press " + GreenEnter() + " to save.",
+			"",
+			"_Default: " + def,
+			"Placeholders: {alias} {host} {port} {user} {target} (user@host)",
+		}
+		if info.Has(config.FieldSerial) {
+			lines = append(lines, "Serial: {device} {baud}")
+		}
+		return strings.Join(lines, "\n")
+	}, &v.protocol)
+
+	return huh.NewGroup(
+		note,
+		buildInputField("command", "Command", &v.command.Command),
+	).WithHideFunc(func() bool {
+		return !formProtocolHas(v, config.FieldCommand)
+	})
+}
+
+// buildSerialOptionsGroup creates the serial port options Huh group.
+func buildSerialOptionsGroup(v *form) *huh.Group {
+	note := huh.NewNote().Description(
+		"Serial line settings. The device is required. Press " + GreenEnter() + " to save.\n\n" +
+			"_Device is eg. /dev/ttyUSB0 or COM3. The defaults are " + config.DefaultBaud + " 8N1 without flow control.\n" +
+			"Press Ctrl+] during a session to return to the menu.")
+
+	return huh.NewGroup(
+		note,
+		buildInputField("device", "Device", &v.serial.Device),
+		buildInputField("baud", "Baud Rate", &v.serial.Baud).
+			Validate(func(s string) error {
+				_, err := connect.ParseSerialSettings(config.SerialOptions{Baud: s})
+				return err
+			}),
+		buildSerialSelectField("databits", "Data Bits", &v.serial.DataBits, "8", "7", "6", "5"),
+		buildSerialSelectField("parity", "Parity", &v.serial.Parity,
+			connect.ParityNone, connect.ParityEven, connect.ParityOdd),
+		buildSerialSelectField("stopbits", "Stop Bits", &v.serial.StopBits, "1", "2"),
+		buildSerialSelectField("flow", "Flow Control", &v.serial.Flow,
+			connect.FlowNone, connect.FlowRTSCTS, connect.FlowXonXoff),
 	).WithHideFunc(func() bool {
-		return v.protocol != config.ProtocolSSH
+		return !formProtocolHas(v, config.FieldSerial)
 	})
 }
 
+// buildSerialSelectField creates an inline select for a serial line setting.
+//
+// The first value is the default and is stored as empty, so saved hosts only
+// list the settings that were changed.
+func buildSerialSelectField(key, title string, value *string, values ...string) *huh.Select[string] {
+	opts := []huh.Option[string]{huh.NewOption(values[0]+" (default)", "")}
+	for _, v := range values[1:] {
+		opts = append(opts, huh.NewOption(v, v))
+	}
+	if *value == values[0] {
+		*value = ""
+	}
+	return huh.NewSelect[string]().
+		Key(key).
+		Title(title).
+		Options(opts...).
+		Inline(true).
+		Value(value)
+}
+
+// buildSessionOptionsGroup creates the session options Huh group (all protocols).
+func buildSessionOptionsGroup(v *form) *huh.Group {
+	note := huh.NewNote().Description(
+		"Session settings for this host. Press " + GreenEnter() + " to save.\n\n" +
+			"_Recordings can be played back from the menu with " + BlueP() + ".\n" +
+			"Logs are plain text with timestamps; common secrets are redacted.\n" +
+			"Preflight timeout (eg. 20s) and retries apply to the reachability check; blank uses the defaults.\n" +
+			"Tags are comma separated and can be searched for.\n" +
+			"Login script is a name in ~/.btms/scripts or a path; it answers prompts at the start of each session.\n" +
+			"Pre/post-connect commands run locally around the session, inside any hooks from ~/.btms/hooks;\n" +
+			"they get BTMS_ALIAS, BTMS_HOST, BTMS_PORT and BTMS_USER, and a failing pre-connect command stops the connect.")
+
+	return huh.NewGroup(
+		note,
+		huh.NewConfirm().
+			Key("record").
+			Title("Record Sessions").
+			Affirmative("Yes").
+			Negative("No").
+			Value(&v.app.Record),
+		huh.NewConfirm().
+			Key("log").
+			Title("Log Sessions").
+			Affirmative("Yes").
+			Negative("No").
+			Value(&v.app.Log),
+		buildInputField("preflighttimeout", "Preflight Timeout", &v.app.PreflightTimeout).
+			Validate(func(s string) error {
+				_, err := connect.ParsePreflightTimeout(s)
+				return err
+			}),
+		buildInputField("preflightretries", "Preflight Retries", &v.app.PreflightRetries).
+			Validate(func(s string) error {
+				_, err := connect.ParsePreflightRetries(s)
+				return err
+			}),
+		buildInputField("tags", "Tags", &v.app.Tags),
+		buildInputField("script", "Login Script", &v.app.Script).
+			Validate(func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return nil
+				}
+				_, err := expect.Load(s)
+				return err
+			}),
+		buildInputField("preconnect", "Pre-Connect Command", &v.app.PreConnect),
+		buildInputField("postconnect", "Post-Connect Command", &v.app.PostConnect),
+	)
+}
+
+// normalizedAppOptions trims the free-text app options and tidies the tag list.
+func normalizedAppOptions(o config.AppOptions) config.AppOptions {
+	o.PreflightTimeout = strings.TrimSpace(o.PreflightTimeout)
+	o.PreflightRetries = strings.TrimSpace(o.PreflightRetries)
+	o.Tags = config.FormatTags(config.ParseTags(o.Tags))
+	o.Script = strings.TrimSpace(o.Script)
+	o.PreConnect = strings.TrimSpace(o.PreConnect)
+	o.PostConnect = strings.TrimSpace(o.PostConnect)
+	o.Secret = strings.TrimSpace(o.Secret)
+	return o
+}
+
 // sshOptionsHelpText returns the help text for the SSH options group.
 func sshOptionsHelpText() string {
 	lines := []string{
@@ -137,6 +419,7 @@ func sshOptionsHelpText() string {
 		"",
 		"_It's generally recommended to append to defaults rather than override them.",
 		"Multiple algorithms can be comma separated. See ssh config man page for details.",
+		"IdentityFile is a key such as ~/.ssh/id_ed25519; several are comma separated (see the keys view, K).",
 	}
 	return strings.Join(lines, "\n")
 }
@@ -155,19 +438,40 @@ func buildSubmitCmd(mode formMode, oldAlias string, v *form) func() tea.Msg {
 			User:     v.user,
 		}
 
+		// only keep the option groups the protocol offers
+		info, _ := config.LookupProtocol(p)
 		opts := config.SSHOptions{}
-		if p == config.ProtocolSSH {
+		if info.Has(config.FieldSSHOptions) {
 			opts = v.sshOpts
+			opts.IdentityFiles = strings.Split(v.identity, ",")
+		}
+		telnet := config.TelnetOptions{}
+		if info.Has(config.FieldTermType) {
+			telnet = v.telnet
+		}
+		command := config.CommandOptions{}
+		if info.Has(config.FieldCommand) {
+			command = v.command
+		}
+		serial := config.SerialOptions{}
+		if info.Has(config.FieldSerial) {
+			serial = v.serial
 		}
 
 		return formSubmittedMsg{
-			mode:     mode,
-			protocol: p,
-			oldAlias: oldAlias,
-			group:    v.groupName,
-			nickname: v.nickname,
-			spec:     spec,
-			opts:     opts,
+			mode:      mode,
+			protocol:  p,
+			oldAlias:  oldAlias,
+			inventory: v.inventory,
+			group:     v.groupName,
+			nickname:  v.nickname,
+			spec:      spec,
+			opts:      opts,
+			telnet:    telnet,
+			command:   command,
+			serial:    serial,
+			app:       normalizedAppOptions(v.app),
+			password:  hostPassword{password: v.password, vaultPass: v.vaultPass},
 		}
 	}
 }
@@ -181,15 +485,20 @@ func (m model) buildHostFormHeader() (header string) {
 	}
 
 	protocol := m.hostFormProtocol()
+	inventory := ""
+	if m.ms.hostFormValues != nil {
+		inventory = m.ms.hostFormValues.inventory
+	}
 
 	configPath := "(unknown)"
-	if action == "Adding" {
-		if p, err := config.GetConfigPathForProtocol(protocol); err == nil && strings.TrimSpace(p) != "" {
-			configPath = p
-		}
-	} else {
+	inv, err := config.LookupInventory(inventory, protocol)
+	switch {
+	case err != nil:
+	case action == "Adding":
+		configPath = inv.Path
+	default:
 		oldAlias := strings.TrimSpace(m.ms.hostFormOldAlias)
-		if p, err := config.GetConfigPathForAlias(protocol, oldAlias); err == nil && strings.TrimSpace(p) != "" {
+		if p, err := inv.PathForAlias(oldAlias); err == nil && strings.TrimSpace(p) != "" {
 			configPath = p
 		}
 	}
@@ -217,7 +526,7 @@ func (m model) buildHostFormFooter(panelW int) string {
 	enterBinding := m.keys.FormSubmit
 	if m.ms.hostForm != nil {
 		if f := m.ms.hostForm.GetFocusedField(); f != nil {
-			if _, ok := f.(*huh.Select[config.Protocol]); ok {
+			if isFormSelectField(f) {
 				enterBinding = m.keys.FormSelect
 			}
 		}
@@ -238,25 +547,19 @@ func (m model) buildHostFormFooter(panelW int) string {
 
 // buildHostFormPaginator builds the paginator view for the host form.
 //
-// It only shows when the protocol is "ssh" and there are multiple pages
-// (SSH options).
+// The second page holds the protocol's options (SSH, telnet, serial or
+// command), the third holds session options and the fourth the password.
 func (m model) buildHostFormPaginator() string {
 	if m.ms.hostForm == nil {
 		return ""
 	}
-	if m.hostFormProtocol() != config.ProtocolSSH {
-		return ""
-	}
 
 	page := 0
 	if f := m.ms.hostForm.GetFocusedField(); f != nil {
-		switch f.GetKey() {
-		case "hostkeyalgorithms", "kexalgorithms", "macs":
-			page = 1
-		}
+		page = hostFormFieldPages[f.GetKey()]
 	}
 
-	p := paginator.New(paginator.WithPerPage(1), paginator.WithTotalPages(2))
+	p := paginator.New(paginator.WithPerPage(1), paginator.WithTotalPages(hostFormPages))
 	p.Type = paginator.Dots
 	p.Page = page
 
@@ -26,6 +26,9 @@ func (m model) handleHostFormSubmit(msg formSubmittedMsg) (model, tea.Cmd) {
 	msg.spec.Alias = alias
 	msg.spec = msg.spec.Normalized()
 	msg.opts = msg.opts.Normalized()
+	msg.telnet = msg.telnet.Normalized()
+	msg.command = msg.command.Normalized()
+	msg.serial = msg.serial.Normalized()
 
 	oldAlias := m.ms.hostFormOldAlias
 	if oldAlias == "" {
@@ -35,24 +38,39 @@ func (m model) handleHostFormSubmit(msg formSubmittedMsg) (model, tea.Cmd) {
 	// close the form before doing IO
 	m, _ = m.closeHostForm("", statusInfo)
 
-	return m, m.saveHostCmd(msg.mode, protocol, oldAlias, msg.spec, msg.opts)
+	entry := config.EntryFromSpec(msg.spec, msg.opts, "")
+	entry.TelnetOptions = msg.telnet
+	entry.CommandOptions = msg.command
+	entry.SerialOptions = msg.serial
+	entry.AppOptions = msg.app
+
+	// a new password without a secret name is stored under the alias
+	pw := msg.password
+	if pw.password != "" && entry.AppOptions.Secret == "" {
+		entry.AppOptions.Secret = alias
+	}
+	pw.secret = entry.AppOptions.Secret
+	return m, m.saveHostCmd(msg.mode, protocol, msg.inventory, oldAlias, entry, pw)
 }
 
-// saveHostCmd returns a command that performs the host save operation.
-func (m model) saveHostCmd(mode formMode, protocol config.Protocol, oldAlias string, spec config.Spec, opts config.SSHOptions) tea.Cmd {
+// saveHostCmd returns a command that performs the host save operation in
+// the named inventory ("" for the protocol's local config).
+//
+// Once the host is saved, a new password (if any) is stored in the vault.
+func (m model) saveHostCmd(mode formMode, protocol config.Protocol, inventory, oldAlias string, entry config.HostEntry, pw hostPassword) tea.Cmd {
 	return func() tea.Msg {
-		result := formSaveResultMsg{protocol: protocol, spec: spec}
+		result := formSaveResultMsg{protocol: protocol, spec: entry.Spec}
+		inv, err := config.LookupInventory(inventory, protocol)
+		if err != nil {
+			result.err = err
+			return result
+		}
 
 		switch mode {
 		case modeAdd:
-			root, err := config.GetConfigPathForProtocol(protocol)
-			if err != nil {
-				result.err = err
-				return result
-			}
-			result.err = config.AddHostToRootConfig(protocol, spec, opts)
+			result.err = inv.AddHost(entry)
 			if result.err == nil {
-				result.configPath = root
+				result.configPath = inv.Path
 			}
 
 		case modeEdit:
@@ -60,7 +78,7 @@ func (m model) saveHostCmd(mode formMode, protocol config.Protocol, oldAlias str
 				result.err = errors.New("missing old alias")
 				return result
 			}
-			configPath, err := config.GetConfigPathForAlias(protocol, oldAlias)
+			configPath, err := inv.PathForAlias(oldAlias)
 			if err != nil {
 				result.err = err
 				return result
@@ -70,12 +88,16 @@ func (m model) saveHostCmd(mode formMode, protocol config.Protocol, oldAlias str
 				return result
 			}
 			result.configPath = configPath
-			result.err = config.UpdateHostInConfig(protocol, oldAlias, spec, opts)
+			result.err = inv.UpdateHost(oldAlias, entry)
 
 		default:
 			result.err = errors.New("unknown form mode")
 		}
 
+		if result.err == nil && pw.password != "" {
+			result.secret = pw.secret
+			result.secretErr = storePassword(pw)
+		}
 		return result
 	}
 }
@@ -97,6 +119,13 @@ func (m model) handleHostFormSaveResult(msg formSaveResultMsg) (model, tea.Cmd)
 			targetText = fmt.Sprintf("%s <%s>", alias, hostName)
 		}
 		status := fmt.Sprintf("✔️ Saved Host %s to %s", targetText, msg.configPath)
+		if msg.secretErr != nil {
+			status += fmt.Sprintf(", but storing password %q failed: %v", msg.secret, msg.secretErr)
+			return m, tea.Batch(m.setStatusError(status, 0), cmd)
+		}
+		if msg.secret != "" {
+			status += fmt.Sprintf(" and password %q to the vault", msg.secret)
+		}
 		return m, tea.Batch(m.setStatusSuccess(status, statusTTL), cmd)
 	}
 	if errors.Is(msg.err, os.ErrNotExist) {
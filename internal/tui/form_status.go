@@ -5,13 +5,14 @@ import (
 	"strings"
 
 	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
 	str "bubbletea-ssh-manager/internal/stringutil"
 
 	"github.com/charmbracelet/lipgloss"
 )
 
 type formStatusData struct {
-	protocol  config.Protocol // current protocol (ssh/telnet)
+	protocol  config.Protocol // current protocol (eg. ssh/telnet)
 	groupName string          // will add to existing group if present, else create new group
 	nickname  string          // if no group, nickname is the alias and will appear at root level
 	hostname  string          // hostname or IP - for telnet it's required
@@ -24,6 +25,7 @@ type formStatusData struct {
 	nicknameErr error // validation error
 	hostErr     error // validation error
 	portErr     error // validation error
+	optionsErr  error // missing required protocol option (eg. serial device, custom command) or vault passphrase
 }
 
 type formStatusRenderers struct {
@@ -132,7 +134,7 @@ func (m model) existingRootGroupNames() []string {
 	}
 	seen := map[string]struct{}{}
 	out := make([]string, 0)
-	for _, it := range m.root.children {
+	for _, it := range hostLevel(m.root) {
 		if it == nil || it.kind != itemGroup {
 			continue
 		}
@@ -173,13 +175,25 @@ func (m model) formStatusData() formStatusData {
 	// for display, show the protocol's default port when empty (ssh=22, telnet=23)
 	// this keeps the status panel synced with the *effective* config
 	portDisplay := port
-	portErr := error(nil)
-	if p, err := str.NormalizePort(port, protocol); err != nil {
-		portErr = err
-	} else {
+	portErr := str.ValidateHostPort(protocol, port)
+	if p, err := str.NormalizePort(port, protocol); err == nil {
 		portDisplay = p
 	}
 
+	// protocol options that are required (hostname/port are checked above)
+	var optionsErr error
+	if info, ok := config.LookupProtocol(protocol); ok && m.ms.hostFormValues != nil {
+		v := m.ms.hostFormValues
+		info.Required &= config.FieldCommand | config.FieldSerial
+		optionsErr = info.CheckRequired(config.HostEntry{CommandOptions: v.command, SerialOptions: v.serial})
+		if optionsErr == nil && info.Has(config.FieldSerial) {
+			_, optionsErr = connect.ParseSerialSettings(v.serial)
+		}
+		if optionsErr == nil {
+			optionsErr = passwordErr(v)
+		}
+	}
+
 	return formStatusData{
 		protocol:       protocol,
 		groupName:      groupName,
@@ -193,6 +207,7 @@ func (m model) formStatusData() formStatusData {
 		nicknameErr: str.ValidateHostNickname(nickname),
 		hostErr:     str.ValidateHostName(protocol, hostname),
 		portErr:     portErr,
+		optionsErr:  optionsErr,
 	}
 }
 
@@ -210,6 +225,10 @@ func buildFormStatusLines(r formStatusRenderers, d formStatusData) []string {
 
 	lines = append(lines, r.label("\nUser: ")+r.formatUnvalidated(d.user))
 
+	if d.optionsErr != nil {
+		lines = append(lines, "\n"+ErrorX+r.errText(d.optionsErr.Error()))
+	}
+
 	// show existing groups if any
 	if len(d.existingGroups) > 0 {
 		lines = append(lines, r.label("\n\nCurrent Groups:"))
@@ -232,7 +251,7 @@ func buildFormStatusLines(r formStatusRenderers, d formStatusData) []string {
 // This is used to prevent form submission when there are validation errors.
 func (m model) hasFormValidationErrors() bool {
 	d := m.formStatusData()
-	return d.groupErr != nil || d.nicknameErr != nil || d.hostErr != nil || d.portErr != nil
+	return d.groupErr != nil || d.nicknameErr != nil || d.hostErr != nil || d.portErr != nil || d.optionsErr != nil
 }
 
 // buildFormStatusPanel builds the form status panel view.
@@ -0,0 +1,96 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/hooks"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// postHooks are the post-connect hooks to run once a session ends.
+type postHooks struct {
+	hooks   []hooks.Hook // hooks in the order they run
+	env     hooks.Env    // session the hooks are for
+	started time.Time    // when the session started, for BTMS_DURATION
+}
+
+// hooksFor returns the pre- and post-connect hooks for a host, reading the
+// hooks file each time so edits apply to the next connect.
+func hooksFor(it *menuItem) (pre, post []hooks.Hook, err error) {
+	cfg, err := hooks.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("hooks: %w", err)
+	}
+	pre, post = cfg.For(it.spec.Alias, it.app)
+	return pre, post, nil
+}
+
+// startPreHooks shows the preflight screen while the pre-connect hooks run.
+//
+// ctrl+c cancels them like a preflight check; once they pass, the connect
+// continues from connectHost.
+func (m model) startPreHooks(it *menuItem, t connect.Target, pre, post []hooks.Hook) (model, tea.Cmd) {
+	m.mode = modePreflight
+	tok := m.initPreflightState(t.Protocol, "", t.WindowTitle(), t.Display(), nil, nil)
+	m.ms.preflight.hooks = true
+	m.ms.preflight.ctx, m.ms.preflight.cancel = context.WithCancel(context.Background())
+	m.setStatusInfo("", 0)
+
+	ctx := m.ms.preflight.ctx
+	env := hooks.NewEnv(hooks.Pre, t)
+	return m, tea.Batch(m.spinner.Tick, func() tea.Msg {
+		return preHooksDoneMsg{token: tok, item: it, post: post, err: hooks.Run(ctx, pre, env)}
+	})
+}
+
+// handlePreHooksDoneMsg connects once the pre-connect hooks pass, or shows
+// the failing hook's output.
+func (m model) handlePreHooksDoneMsg(msg preHooksDoneMsg) (model, tea.Cmd) {
+	if m.mode != modePreflight || !m.ms.preflight.hooks || msg.token != m.ms.preflight.token {
+		return m, nil
+	}
+	m.clearPreflightState()
+	if msg.err != nil {
+		return m, m.setStatusError(msg.err.Error(), 0)
+	}
+	m.preHooksRan = true
+	return m.connectHost(msg.item, msg.post)
+}
+
+// owedPostHooksCmd runs the post-connect hooks of a connect that ends before
+// its session starts, if its pre-connect hooks ran, so whatever they set up
+// (eg. a VPN) is torn down again. err is why the connect ended.
+func (m *model) owedPostHooksCmd(p postHooks, err error) tea.Cmd {
+	if !m.preHooksRan {
+		return nil
+	}
+	m.preHooksRan = false
+	p.started = time.Now()
+	return runPostHooksCmd(p, err)
+}
+
+// runPostHooksCmd runs the post-connect hooks in the background.
+func runPostHooksCmd(p postHooks, sessionErr error) tea.Cmd {
+	if len(p.hooks) == 0 {
+		return nil
+	}
+	env := p.env
+	env.Stage = hooks.Post
+	env.Duration = time.Since(p.started)
+	env.Err = sessionErr
+	return func() tea.Msg {
+		return postHooksDoneMsg{alias: env.Alias, err: hooks.Run(context.Background(), p.hooks, env)}
+	}
+}
+
+// handlePostHooksDoneMsg reports a failed post-connect hook.
+func (m model) handlePostHooksDoneMsg(msg postHooksDoneMsg) (model, tea.Cmd) {
+	if msg.err == nil {
+		return m, nil
+	}
+	return m, m.setStatusError(fmt.Sprintf("%s: %v", msg.alias, msg.err), 0)
+}
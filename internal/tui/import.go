@@ -0,0 +1,349 @@
+package tui
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/importer"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type importState struct {
+	form   *huh.Form     // file and options form
+	values *importValues // values bound to the form
+}
+
+// importValues holds the import form's input values.
+type importValues struct {
+	path      string // export file or directory
+	format    string // format name; empty detects it
+	inventory string // inventory the hosts are imported to
+	replace   bool   // replace hosts whose alias exists
+}
+
+// openImport opens the form picking another client's session export to
+// import hosts from.
+func (m model) openImport() (model, tea.Cmd) {
+	v := &importValues{inventory: config.LocalInventory}
+	if cur := m.current(); cur.source != "" && writableSource(cur.name) {
+		v.inventory = cur.name
+	}
+	st := &importState{values: v}
+	st.form = buildImportForm(st, m.theme)
+
+	m.mode = modeImport
+	m.ms.imports = st
+	m.setStatusInfo("", 0)
+	m.relayout()
+	return m, st.form.Init()
+}
+
+// writableSource reports whether the inventory named name takes hosts of
+// any protocol.
+func writableSource(name string) bool {
+	invs, _ := config.Inventories()
+	return slices.ContainsFunc(invs, func(inv config.Inventory) bool { return inv.Name == name && inv.Writable() == nil })
+}
+
+// buildImportForm builds the import form: the export to read, its format,
+// the source to import to (with other inventories) and whether to replace
+// hosts whose alias exists.
+func buildImportForm(st *importState, appTheme Theme) *huh.Form {
+	v := st.values
+	desc := "Reads the sessions saved by another client. Folders become groups, and you " +
+		"review the hosts before anything is written."
+
+	formats := []huh.Option[string]{huh.NewOption("Detect from the file", "")}
+	for _, f := range importer.Formats() {
+		formats = append(formats, huh.NewOption(f.Description, f.Name))
+	}
+
+	// the preselected source goes first: huh scrolls a select to its value
+	var sources []huh.Option[string]
+	invs, _ := config.Inventories()
+	for _, inv := range invs {
+		if slices.ContainsFunc(sources, func(o huh.Option[string]) bool { return o.Value == inv.Name }) ||
+			!writableSource(inv.Name) {
+			continue
+		}
+		opt := huh.NewOption(inv.Name, inv.Name)
+		if inv.Name == v.inventory {
+			sources = append([]huh.Option[string]{opt}, sources...)
+		} else {
+			sources = append(sources, opt)
+		}
+	}
+
+	f := huh.NewForm(
+		huh.NewGroup(
+			huh.NewNote().Title("Import Hosts").Description(desc),
+			huh.NewInput().
+				Key("path").
+				Title("File").
+				Description("The export file, or SecureCRT's Sessions directory.").
+				Value(&v.path).
+				Validate(func(s string) error {
+					_, err := importPath(s)
+					return err
+				}),
+			huh.NewSelect[string]().
+				Key("format").
+				Title("Format").
+				Options(formats...).
+				Value(&v.format),
+		),
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Key("source").
+				Title("Source").
+				Description("Where the hosts are added.").
+				Options(sources...).
+				Value(&v.inventory),
+		).WithHideFunc(func() bool { return !config.HasOtherInventories() }),
+		huh.NewGroup(
+			huh.NewConfirm().
+				Key("replace").
+				Title("Replace hosts whose alias exists?").
+				Affirmative("Replace").
+				Negative("Skip").
+				Value(&v.replace),
+		),
+	).
+		WithShowHelp(false).
+		WithKeyMap(runFormKeyMap()).
+		WithTheme(hostFormTheme(appTheme))
+
+	f.SubmitCmd = func() tea.Msg { return importFormDoneMsg{imports: st, ok: true} }
+	f.CancelCmd = func() tea.Msg { return importFormDoneMsg{imports: st} }
+	return f
+}
+
+// importPath returns the export path s names, with ~ expanded, or why it
+// can't be read.
+func importPath(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", errors.New("enter the export's path")
+	}
+	path, err := config.ExpandPath(s)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%s doesn't exist", s)
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+// handleImportKeyMsg routes keys to the import form.
+func (m model) handleImportKeyMsg(msg tea.KeyMsg) (model, tea.Cmd) {
+	st := m.ms.imports
+	if st == nil || st.form == nil {
+		return m.closeImport()
+	}
+	mdl, cmd := st.form.Update(msg)
+	if f, ok := mdl.(*huh.Form); ok {
+		st.form = f
+	}
+	return m, cmd
+}
+
+// closeImport closes the import form.
+func (m model) closeImport() (model, tea.Cmd) {
+	m.mode = modeMenu
+	m.ms.imports = nil
+	m.relayout()
+	return m, nil
+}
+
+// handleImportFormDoneMsg reads the export and plans the import in the background.
+func (m model) handleImportFormDoneMsg(msg importFormDoneMsg) (model, tea.Cmd) {
+	st := m.ms.imports
+	if m.mode != modeImport || st == nil || msg.imports != st {
+		return m, nil
+	}
+	m, _ = m.closeImport()
+	if !msg.ok {
+		return m, m.setStatusError(ErrorX+"Canceled import.", statusTTL)
+	}
+	v := *st.values
+	return m, tea.Batch(m.setStatusInfo("Reading "+v.path+"…", 0), importPlanCmd(v))
+}
+
+// importPlanCmd reads the sessions in the export and works out what
+// importing them does.
+func importPlanCmd(v importValues) tea.Cmd {
+	return func() tea.Msg {
+		path, err := importPath(v.path)
+		if err != nil {
+			return importPlannedMsg{path: v.path, err: err}
+		}
+		f, sessions, err := importer.ReadSessions(path, v.format)
+		if err != nil {
+			return importPlannedMsg{path: v.path, err: err}
+		}
+		plan := importer.NewPlan(importer.SessionItems(sessions), importer.Options{Inventory: v.inventory, Replace: v.replace,
+			Validate: connect.ValidateHost})
+		return importPlannedMsg{path: v.path, format: f, plan: plan}
+	}
+}
+
+// handleImportPlannedMsg asks to confirm the import, showing what it would
+// add, replace and leave out.
+func (m model) handleImportPlannedMsg(msg importPlannedMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		return m, m.setStatusError("Import: "+msg.err.Error(), 0)
+	}
+	plan := msg.plan
+	summary := importSummary(plan)
+	n := plan.Count(importer.ActionAdd, importer.ActionReplace)
+	if n == 0 {
+		if len(plan.Items) == 0 {
+			return m, m.setStatusError("No sessions in "+msg.path+".", statusTTL)
+		}
+		return m, m.setStatusError("Nothing to import from "+filepath.Base(msg.path)+": "+summary+".", 0)
+	}
+
+	title := fmt.Sprintf("Import %d host(s)?", n)
+	description := fmt.Sprintf("From %s (%s): %s.", filepath.Base(msg.path), msg.format.Name, summary)
+	form := buildConfirmForm(title, description, m.theme)
+	m.setStatusInfo("", 0)
+	m.mode = modeConfirm
+	m.ms.confirm = &confirmState{
+		form:        form,
+		title:       title,
+		description: description,
+		body:        m.buildImportPreview(plan),
+		onConfirm:   importApplyCmd(plan),
+		onCancel:    m.setStatusError(ErrorX+"Canceled import.", statusTTL),
+	}
+	m.relayout()
+	return m, form.Init()
+}
+
+// importActionOrder ranks actions for the preview: conflicts with existing
+// hosts first, then the hosts left out, then the new ones.
+var importActionOrder = []importer.Action{importer.ActionReplace, importer.ActionSkip, importer.ActionInvalid,
+	importer.ActionFail, importer.ActionAdd}
+
+// importSummary counts a plan's items by action, eg. "2 to add, 1 skipped".
+func importSummary(plan *importer.Plan) string {
+	words := map[importer.Action]string{
+		importer.ActionAdd:     "%d to add",
+		importer.ActionReplace: "%d to replace",
+		importer.ActionSkip:    "%d skipped",
+		importer.ActionInvalid: "%d invalid",
+		importer.ActionFail:    "%d failed",
+	}
+	var parts []string
+	for _, a := range []importer.Action{importer.ActionAdd, importer.ActionReplace, importer.ActionSkip,
+		importer.ActionInvalid, importer.ActionFail} {
+		if n := plan.Count(a); n > 0 {
+			parts = append(parts, fmt.Sprintf(words[a], n))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// buildImportPreview renders the plan's items for the confirmation's
+// primary panel: action, protocol, alias, and the hostname or why the host
+// is left out.
+func (m model) buildImportPreview(plan *importer.Plan) string {
+	s := m.newDetailsStyles()
+	items := slices.Clone(plan.Items)
+	slices.SortStableFunc(items, func(a, b importer.Item) int {
+		return cmp.Compare(slices.Index(importActionOrder, a.Action), slices.Index(importActionOrder, b.Action))
+	})
+
+	var b strings.Builder
+	b.WriteString(s.header.Render("IMPORT PREVIEW"))
+	b.WriteString("\n\n")
+	for i, it := range items {
+		if i == bulkListMax {
+			fmt.Fprintf(&b, "%s\n", s.label.Render(fmt.Sprintf("… and %d more", len(items)-i)))
+			break
+		}
+		action := s.label.Render(fmt.Sprintf("%-8s", it.Action))
+		switch it.Action {
+		case importer.ActionAdd:
+			action = s.label.Foreground(m.theme.StatusSuccess).Render(fmt.Sprintf("%-8s", it.Action))
+		case importer.ActionInvalid, importer.ActionFail:
+			action = s.label.Foreground(m.theme.StatusError).Render(fmt.Sprintf("%-8s", it.Action))
+		}
+		proto := s.proto.Foreground(m.theme.protocolColor(it.Host.Protocol))
+		line := action + " " + proto.Render(fmt.Sprintf("%-6s", cmp.Or(string(it.Host.Protocol), "-"))) + "  " +
+			s.value.Render(cmp.Or(it.Host.Spec.Alias, it.Source))
+		if it.Reason != "" {
+			line += s.value.Foreground(m.theme.PreflightText).Render(" (" + it.Reason + ")")
+		} else if it.Host.Spec.HostName != "" {
+			line += s.value.Foreground(m.theme.PreflightText).Render(" <" + it.Host.Spec.HostName + ">")
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// importApplyCmd writes the plan's hosts in one go.
+func importApplyCmd(plan *importer.Plan) tea.Cmd {
+	return func() tea.Msg {
+		return importDoneMsg{plan: plan, err: plan.Apply()}
+	}
+}
+
+// handleImportDoneMsg reports the import and reloads the menu.
+func (m model) handleImportDoneMsg(msg importDoneMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		return m, m.setStatusError("Import failed; nothing was written: "+msg.err.Error(), 0)
+	}
+	text := fmt.Sprintf("Imported %d host(s)", msg.plan.Count(importer.ActionAdd, importer.ActionReplace))
+	if n := msg.plan.Count(importer.ActionSkip, importer.ActionInvalid, importer.ActionFail); n > 0 {
+		text += fmt.Sprintf(" (%d left out)", n)
+	}
+	reloadCmd := func() tea.Msg {
+		root, err := seedMenu()
+		return menuReloadedMsg{root: root, err: err}
+	}
+	return m, tea.Batch(m.setStatusSuccess(text+SuccessCheck, statusTTL), reloadCmd)
+}
+
+// importHelpKeys returns the help keys shown under the import form.
+func (m model) importHelpKeys() []key.Binding {
+	return []key.Binding{m.keys.CloseForm, m.keys.FormPrev, m.keys.FormNext, m.keys.FormSelect}
+}
+
+// resizeImport sizes the import form to the window.
+func (m *model) resizeImport() {
+	if m.ms.imports == nil || m.ms.imports.form == nil {
+		return
+	}
+	w := max(0, min(m.width-hostFormPadding, 80))
+	m.ms.imports.form = m.ms.imports.form.WithWidth(w).WithHeight(max(0, m.height-hostFormHeaderFooter))
+}
+
+// viewImport renders the import form.
+func (m model) viewImport() string {
+	st := m.ms.imports
+	if st == nil || st.form == nil {
+		return ""
+	}
+	lg := lipgloss.NewStyle()
+	h := m.lst.Help
+	h.Width = m.width
+	help := lg.PaddingLeft(footerPadLeft).PaddingTop(1).Render(h.ShortHelpView(m.importHelpKeys()))
+	body := lg.Padding(1, 3).Render(st.form.View())
+	return strings.Join([]string{body, help}, "\n")
+}
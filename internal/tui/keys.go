@@ -29,6 +29,85 @@ const (
 	removeSymbol  = "R"
 	removeHelp    = "remove"
 
+	probeSymbol    = "L"
+	probeHelp      = "probe algorithms"
+	applyFixSymbol = "F"
+	applyFixHelp   = "apply fix"
+
+	monitorSymbol = "M"
+	monitorHelp   = "monitor"
+
+	runSymbol      = "X"
+	runHelp        = "run command"
+	runGroupSymbol = "G"
+	runGroupHelp   = "group outputs"
+	runSaveSymbol  = "S"
+	runSaveHelp    = "save"
+
+	markSymbol         = "space"
+	markHelp           = "mark"
+	markAllSymbol      = "^T"
+	markAllHelp        = "mark all"
+	markFilteredSymbol = "^G"
+	markFilteredHelp   = "mark matches"
+	bulkSymbol         = "B"
+	bulkHelp           = "bulk actions"
+
+	transferSymbol        = "T"
+	transferHelp          = "transfer files"
+	transfersSymbol       = "J"
+	transfersHelp         = "transfers"
+	transferPaneSymbol    = "tab"
+	transferPaneHelp      = "switch pane"
+	transferOpenSymbol    = "enter"
+	transferOpenHelp      = "open"
+	transferParentSymbol  = "🡨 "
+	transferParentHelp    = "parent"
+	transferCopySymbol    = "C"
+	transferCopyHelp      = "copy"
+	transferRefreshSymbol = "^R"
+	transferRefreshHelp   = "refresh"
+	transferCancelSymbol  = "R"
+	transferCancelHelp    = "cancel/remove"
+	transferRetrySymbol   = "U"
+	transferRetryHelp     = "resume"
+	transferClearSymbol   = "C"
+	transferClearHelp     = "clear finished"
+
+	sshKeysSymbol     = "K"
+	sshKeysHelp       = "ssh keys"
+	keyGenerateSymbol = "G"
+	keyGenerateHelp   = "generate"
+	keyAgentSymbol    = "A"
+	keyAgentHelp      = "add to agent"
+	keyInstallSymbol  = "I"
+	keyInstallHelp    = "install on host"
+	keyRefreshSymbol  = "^R"
+	keyRefreshHelp    = "refresh"
+
+	knownHostsSymbol  = "H"
+	knownHostsHelp    = "known hosts"
+	knownScopeSymbol  = "tab"
+	knownScopeHelp    = "host/all"
+	knownSeedSymbol   = "S"
+	knownSeedHelp     = "fetch & add key"
+	knownRemoveSymbol = "R"
+	knownRemoveHelp   = "remove host keys"
+	knownDeleteSymbol = "D"
+	knownDeleteHelp   = "delete entry"
+
+	settingsSymbol = "S"
+	settingsHelp   = "settings"
+
+	importSymbol = "I"
+	importHelp   = "import"
+
+	recordingsSymbol = "P"
+	recordingsHelp   = "recordings"
+	recordNextSymbol = "^R"
+	recordNextHelp   = "record next"
+	playHelp         = "play"
+
 	leftRightSymbol = "🡨 |🡪 "
 	leftRightHelp   = "choose selection"
 
@@ -58,6 +137,45 @@ type KeyMap struct {
 	Remove        key.Binding
 	ConfirmSelect key.Binding
 	LeftRight     key.Binding
+	Recordings    key.Binding
+	RecordNext    key.Binding
+	Play          key.Binding
+	ApplyFix      key.Binding
+	Probe         key.Binding
+	Monitor       key.Binding
+	Run           key.Binding
+	RunGroup      key.Binding
+	RunSave       key.Binding
+	Mark          key.Binding
+	MarkAll       key.Binding
+	MarkFiltered  key.Binding
+	Bulk          key.Binding
+
+	Transfer        key.Binding
+	Transfers       key.Binding
+	TransferPane    key.Binding
+	TransferOpen    key.Binding
+	TransferParent  key.Binding
+	TransferCopy    key.Binding
+	TransferRefresh key.Binding
+	TransferCancel  key.Binding
+	TransferRetry   key.Binding
+	TransferClear   key.Binding
+
+	SSHKeys     key.Binding
+	KeyGenerate key.Binding
+	KeyAgent    key.Binding
+	KeyInstall  key.Binding
+	KeyRefresh  key.Binding
+
+	KnownHosts  key.Binding
+	KnownScope  key.Binding
+	KnownSeed   key.Binding
+	KnownRemove key.Binding
+	KnownDelete key.Binding
+
+	Settings key.Binding
+	Import   key.Binding
 }
 
 // newBinding is a helper to create a key.Binding with styled help text.
@@ -183,6 +301,251 @@ func NewKeyMap(theme Theme) KeyMap {
 			theme.KeyCursor,
 			theme.HelpText,
 		),
+		Recordings: newBinding(
+			[]string{"P"},
+			recordingsSymbol,
+			recordingsHelp,
+			theme.KeyRecord,
+			theme.HelpText,
+		),
+		RecordNext: newBinding(
+			[]string{"ctrl+r"},
+			recordNextSymbol,
+			recordNextHelp,
+			theme.KeyRecord,
+			theme.HelpText,
+		),
+		Probe: newBinding(
+			[]string{"L"},
+			probeSymbol,
+			probeHelp,
+			theme.KeyInfo,
+			theme.HelpText,
+		),
+		ApplyFix: newBinding(
+			[]string{"F"},
+			applyFixSymbol,
+			applyFixHelp,
+			theme.KeyClear,
+			theme.HelpText,
+		),
+		Monitor: newBinding(
+			[]string{"M"},
+			monitorSymbol,
+			monitorHelp,
+			theme.KeyInfo,
+			theme.HelpText,
+		),
+		Run: newBinding(
+			[]string{"X"},
+			runSymbol,
+			runHelp,
+			theme.KeyEnter,
+			theme.HelpText,
+		),
+		RunGroup: newBinding(
+			[]string{"G"},
+			runGroupSymbol,
+			runGroupHelp,
+			theme.KeyInfo,
+			theme.HelpText,
+		),
+		RunSave: newBinding(
+			[]string{"S"},
+			runSaveSymbol,
+			runSaveHelp,
+			theme.KeyEdit,
+			theme.HelpText,
+		),
+		Mark: newBinding(
+			[]string{" "},
+			markSymbol,
+			markHelp,
+			theme.KeyClear,
+			theme.HelpText,
+		),
+		MarkAll: newBinding(
+			[]string{"ctrl+t"},
+			markAllSymbol,
+			markAllHelp,
+			theme.KeyClear,
+			theme.HelpText,
+		),
+		MarkFiltered: newBinding(
+			[]string{"ctrl+g"},
+			markFilteredSymbol,
+			markFilteredHelp,
+			theme.KeyClear,
+			theme.HelpText,
+		),
+		Bulk: newBinding(
+			[]string{"B"},
+			bulkSymbol,
+			bulkHelp,
+			theme.KeyEnter,
+			theme.HelpText,
+		),
+		Transfer: newBinding(
+			[]string{"T"},
+			transferSymbol,
+			transferHelp,
+			theme.KeyEnter,
+			theme.HelpText,
+		),
+		Transfers: newBinding(
+			[]string{"J"},
+			transfersSymbol,
+			transfersHelp,
+			theme.KeyInfo,
+			theme.HelpText,
+		),
+		TransferPane: newBinding(
+			[]string{"tab"},
+			transferPaneSymbol,
+			transferPaneHelp,
+			theme.KeyCursor,
+			theme.HelpText,
+		),
+		TransferOpen: newBinding(
+			[]string{"enter", "right"},
+			transferOpenSymbol,
+			transferOpenHelp,
+			theme.KeyEnter,
+			theme.HelpText,
+		),
+		TransferParent: newBinding(
+			[]string{"left", "backspace"},
+			transferParentSymbol,
+			transferParentHelp,
+			theme.KeyBack,
+			theme.HelpText,
+		),
+		TransferCopy: newBinding(
+			[]string{"C"},
+			transferCopySymbol,
+			transferCopyHelp,
+			theme.KeyEnter,
+			theme.HelpText,
+		),
+		TransferRefresh: newBinding(
+			[]string{"ctrl+r"},
+			transferRefreshSymbol,
+			transferRefreshHelp,
+			theme.KeyInfo,
+			theme.HelpText,
+		),
+		TransferCancel: newBinding(
+			[]string{"R"},
+			transferCancelSymbol,
+			transferCancelHelp,
+			theme.KeyRemove,
+			theme.HelpText,
+		),
+		TransferRetry: newBinding(
+			[]string{"U"},
+			transferRetrySymbol,
+			transferRetryHelp,
+			theme.KeyEdit,
+			theme.HelpText,
+		),
+		TransferClear: newBinding(
+			[]string{"C"},
+			transferClearSymbol,
+			transferClearHelp,
+			theme.KeyClear,
+			theme.HelpText,
+		),
+		SSHKeys: newBinding(
+			[]string{"K"},
+			sshKeysSymbol,
+			sshKeysHelp,
+			theme.KeyInfo,
+			theme.HelpText,
+		),
+		KeyGenerate: newBinding(
+			[]string{"G"},
+			keyGenerateSymbol,
+			keyGenerateHelp,
+			theme.KeyAdd,
+			theme.HelpText,
+		),
+		KeyAgent: newBinding(
+			[]string{"A"},
+			keyAgentSymbol,
+			keyAgentHelp,
+			theme.KeyEnter,
+			theme.HelpText,
+		),
+		KeyInstall: newBinding(
+			[]string{"I"},
+			keyInstallSymbol,
+			keyInstallHelp,
+			theme.KeyEdit,
+			theme.HelpText,
+		),
+		KeyRefresh: newBinding(
+			[]string{"ctrl+r"},
+			keyRefreshSymbol,
+			keyRefreshHelp,
+			theme.KeyInfo,
+			theme.HelpText,
+		),
+		KnownHosts: newBinding(
+			[]string{"H"},
+			knownHostsSymbol,
+			knownHostsHelp,
+			theme.KeyInfo,
+			theme.HelpText,
+		),
+		KnownScope: newBinding(
+			[]string{"tab"},
+			knownScopeSymbol,
+			knownScopeHelp,
+			theme.KeyCursor,
+			theme.HelpText,
+		),
+		KnownSeed: newBinding(
+			[]string{"S"},
+			knownSeedSymbol,
+			knownSeedHelp,
+			theme.KeyAdd,
+			theme.HelpText,
+		),
+		KnownRemove: newBinding(
+			[]string{"R"},
+			knownRemoveSymbol,
+			knownRemoveHelp,
+			theme.KeyRemove,
+			theme.HelpText,
+		),
+		KnownDelete: newBinding(
+			[]string{"D"},
+			knownDeleteSymbol,
+			knownDeleteHelp,
+			theme.KeyRemove,
+			theme.HelpText,
+		),
+		Settings: newBinding(
+			[]string{"S"},
+			settingsSymbol,
+			settingsHelp,
+			theme.KeyInfo,
+			theme.HelpText,
+		),
+		Import: newBinding(
+			[]string{"I"},
+			importSymbol,
+			importHelp,
+			theme.KeyAdd,
+			theme.HelpText,
+		),
+		Play: newBinding(
+			[]string{"enter"},
+			enterSymbol,
+			playHelp,
+			theme.KeyEnter,
+			theme.HelpText,
+		),
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
@@ -36,7 +37,7 @@ func (m model) handleKeyMsg(msg tea.KeyMsg) (model, tea.Cmd, bool) {
 			return nm, cmd, true
 		case key.Matches(msg, m.keys.Quit):
 			m.quitting = true
-			return m, tea.Quit, true
+			return m, tea.Sequence(m.owedPostHooksCmd(m.ms.preflight.post, connect.ErrAborted), tea.Quit), true
 		default:
 			return m, nil, true
 		}
@@ -44,6 +45,42 @@ func (m model) handleKeyMsg(msg tea.KeyMsg) (model, tea.Cmd, bool) {
 	case modePromptUsername:
 		nm, cmd := m.handlePromptKeyMsg(msg)
 		return nm, cmd, true
+
+	case modeRecordings:
+		nm, cmd := m.handleRecordingsKeyMsg(msg)
+		return nm, cmd, true
+
+	case modeRun:
+		nm, cmd := m.handleRunKeyMsg(msg)
+		return nm, cmd, true
+
+	case modeBulk:
+		nm, cmd := m.handleBulkKeyMsg(msg)
+		return nm, cmd, true
+
+	case modeTransfer:
+		nm, cmd := m.handleTransferKeyMsg(msg)
+		return nm, cmd, true
+
+	case modeTransferQueue:
+		nm, cmd := m.handleTransferQueueKeyMsg(msg)
+		return nm, cmd, true
+
+	case modeSSHKeys:
+		nm, cmd := m.handleSSHKeysKeyMsg(msg)
+		return nm, cmd, true
+
+	case modeKnownHosts:
+		nm, cmd := m.handleKnownHostsKeyMsg(msg)
+		return nm, cmd, true
+
+	case modeSettings:
+		nm, cmd := m.handleSettingsKeyMsg(msg)
+		return nm, cmd, true
+
+	case modeImport:
+		nm, cmd := m.handleImportKeyMsg(msg)
+		return nm, cmd, true
 	}
 
 	return m.handleBaseKeyMsg(msg)
@@ -53,7 +90,7 @@ func (m model) handleKeyMsg(msg tea.KeyMsg) (model, tea.Cmd, bool) {
 //
 // Host add/edit behaves like a modal:
 //   - while open, it routes all keys to the form
-//   - 'enter' on input fields attempts to submit the form
+//   - 'enter' on input and confirm fields attempts to submit the form
 //   - on select fields, it selects the option (default behavior)
 //
 // It returns an error checked (newModel, cmd).
@@ -64,8 +101,8 @@ func (m model) handleHostFormKeyMsg(msg tea.KeyMsg) (model, tea.Cmd) {
 	}
 
 	if key.Matches(msg, m.keys.FormSubmit) {
-		// if focused field is a select (e.g. protocol selector), let default behavior handle it
-		if _, ok := m.ms.hostForm.GetFocusedField().(*huh.Select[config.Protocol]); ok {
+		// if focused field is a select (e.g. protocol or jump host), let default behavior handle it
+		if isFormSelectField(m.ms.hostForm.GetFocusedField()) {
 			mdl, cmd := m.ms.hostForm.Update(msg)
 			if f, ok := mdl.(*huh.Form); ok {
 				m.ms.hostForm = f
@@ -74,8 +111,9 @@ func (m model) handleHostFormKeyMsg(msg tea.KeyMsg) (model, tea.Cmd) {
 			return m, cmd
 		}
 
-		// for input fields, attempt to submit the form
-		if _, ok := m.ms.hostForm.GetFocusedField().(*huh.Input); ok {
+		// for input and confirm fields, attempt to submit the form
+		switch m.ms.hostForm.GetFocusedField().(type) {
+		case *huh.Input, *huh.Confirm:
 			mdl, cmd := m.ms.hostForm.Update(msg)
 			if f, ok := mdl.(*huh.Form); ok {
 				m.ms.hostForm = f
@@ -131,6 +169,14 @@ func (m model) handleHostDetailsKeyMsg(msg tea.KeyMsg) (model, tea.Cmd) {
 		nm, cmd := m.openRemoveConfirm()
 		return nm, cmd
 
+	case key.Matches(msg, m.keys.Probe):
+		nm, cmd := m.startAlgorithmProbe()
+		return nm, cmd
+
+	case key.Matches(msg, m.keys.Transfer):
+		nm, cmd := m.openTransfer()
+		return nm, cmd
+
 	default:
 		return m, nil
 	}
@@ -210,8 +256,88 @@ func (m model) handleBaseKeyMsg(msg tea.KeyMsg) (model, tea.Cmd, bool) {
 		nm, cmd := m.openAddHostForm()
 		return nm, cmd, true
 
-	// esc to clear search if non-empty; otherwise do nothing
+	// open recordings browser on 'P'
+	case key.Matches(msg, m.keys.Recordings):
+		nm, cmd := m.openRecordings()
+		return nm, cmd, true
+
+	// arm/disarm recording for the next session on ctrl+r
+	case key.Matches(msg, m.keys.RecordNext):
+		nm, cmd := m.toggleRecordNext()
+		return nm, cmd, true
+
+	// run a command on the marked hosts, or the selected group's hosts, on 'X'
+	case key.Matches(msg, m.keys.Run):
+		nm, cmd := m.openRunForm()
+		return nm, cmd, true
+
+	// mark/unmark the selected host (or group's hosts) on space, unless
+	// searching, where space is typed into the query (names have spaces)
+	case key.Matches(msg, m.keys.Mark) && m.query.Value() == "":
+		nm, cmd := m.toggleMark()
+		return nm, cmd, true
+
+	// mark every host in the current menu on ctrl+t
+	case key.Matches(msg, m.keys.MarkAll):
+		nm, cmd := m.markAll()
+		return nm, cmd, true
+
+	// mark every host matching the search on ctrl+g
+	case key.Matches(msg, m.keys.MarkFiltered):
+		nm, cmd := m.markFiltered()
+		return nm, cmd, true
+
+	// pick an action for the marked hosts on 'B'
+	case key.Matches(msg, m.keys.Bulk):
+		nm, cmd := m.openBulkForm()
+		return nm, cmd, true
+
+	// browse and copy files to/from the selected ssh host on 'T'
+	case key.Matches(msg, m.keys.Transfer):
+		nm, cmd := m.openTransfer()
+		return nm, cmd, true
+
+	// show background transfers on 'J'
+	case key.Matches(msg, m.keys.Transfers):
+		nm, cmd := m.openTransferQueue()
+		return nm, cmd, true
+
+	// manage ssh keys on 'K'
+	case key.Matches(msg, m.keys.SSHKeys):
+		nm, cmd := m.openSSHKeys()
+		return nm, cmd, true
+
+	// review the selected host's known_hosts entries on 'H'
+	case key.Matches(msg, m.keys.KnownHosts):
+		nm, cmd := m.openKnownHosts()
+		return nm, cmd, true
+
+	// edit the settings file on 'S'
+	case key.Matches(msg, m.keys.Settings):
+		nm, cmd := m.openSettings()
+		return nm, cmd, true
+
+	// import another client's sessions on 'I'
+	case key.Matches(msg, m.keys.Import):
+		nm, cmd := m.openImport()
+		return nm, cmd, true
+
+	// start/stop the background reachability monitor on 'M'
+	case key.Matches(msg, m.keys.Monitor):
+		nm, cmd := m.toggleMonitor()
+		return nm, cmd, true
+
+	// apply the fix offered by the last connection failure on 'F'
+	case key.Matches(msg, m.keys.ApplyFix) && m.ms.pendingFix != nil:
+		nm, cmd := m.applyPendingFix()
+		return nm, cmd, true
+
+	// esc to clear search if non-empty; otherwise clear any marks
 	case key.Matches(msg, m.keys.Clear):
+		if m.query.Value() == "" && len(m.marked) > 0 {
+			nm, cmd := m.clearMarks()
+			return nm, cmd, true
+		}
 		nm, cmd := m.clearSearch()
 		return nm, cmd, true
 
@@ -231,6 +357,7 @@ func (m model) handleBaseKeyMsg(msg tea.KeyMsg) (model, tea.Cmd, bool) {
 	// show host details on '?'
 	case key.Matches(msg, m.keys.Details):
 		m.mode = modeHostDetails
+		m.ms.probe = nil         // drop any probe shown for a previous host
 		m.lst.SetShowHelp(false) // hide base help
 		m.setStatusInfo("", 0)   // hide status
 		m.relayout()
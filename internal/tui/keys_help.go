@@ -1,17 +1,50 @@
 package tui
 
 import (
+	"bubbletea-ssh-manager/internal/config"
+
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/lipgloss"
 )
 
-func (m *model) mainHelpKeys() []key.Binding  { return []key.Binding{m.keys.Add} }
-func (m *model) groupHelpKeys() []key.Binding { return []key.Binding{m.keys.Back} }
+func (m *model) mainHelpKeys() []key.Binding {
+	keys := []key.Binding{m.keys.Add, m.keys.Run}
+	if m.query.Value() == "" {
+		keys = append(keys, m.keys.Mark)
+	}
+	if len(m.marked) > 0 {
+		keys = append(keys, m.keys.Bulk)
+	}
+	keys = append(keys, m.keys.Recordings, m.keys.RecordNext, m.keys.Monitor, m.keys.SSHKeys, m.keys.Settings, m.keys.Import)
+	if m.transfers.Len() > 0 {
+		keys = append(keys, m.keys.Transfers)
+	}
+	return keys
+}
+func (m *model) groupHelpKeys() []key.Binding {
+	keys := []key.Binding{m.keys.Back, m.keys.Run}
+	if m.query.Value() != "" {
+		keys = append(keys, m.keys.MarkFiltered)
+	} else {
+		keys = append(keys, m.keys.Mark, m.keys.MarkAll)
+	}
+	if len(m.marked) > 0 {
+		keys = append(keys, m.keys.Bulk)
+	}
+	if m.transfers.Len() > 0 {
+		keys = append(keys, m.keys.Transfers)
+	}
+	return keys
+}
 func (m *model) promptHelpKeys() []key.Binding {
 	return []key.Binding{m.keys.Back, m.keys.Clear}
 }
 func (m model) detailsHelpKeys() []key.Binding {
-	return []key.Binding{m.keys.CloseDetails, m.keys.Edit, m.keys.Remove}
+	keys := []key.Binding{m.keys.CloseDetails, m.keys.Edit, m.keys.Remove}
+	if it, _ := m.lst.SelectedItem().(*menuItem); it != nil && it.protocol == config.ProtocolSSH {
+		keys = append(keys, m.keys.Probe, m.keys.Transfer, m.keys.KnownHosts)
+	}
+	return keys
 }
 func (m model) formHelpKeys() []key.Binding {
 	return []key.Binding{m.keys.CloseForm, m.keys.FormPrev, m.keys.FormNext}
@@ -0,0 +1,479 @@
+package tui
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/knownhosts"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	knownHostsDetailLines = 10               // entry details under the list
+	knownHostsSeedTimeout = 15 * time.Second // fetching a host key to add
+)
+
+type knownHostsState struct {
+	host    *menuItem         // ssh host the view was opened on (nil if none)
+	all     bool              // show every entry, not just the host's
+	name    string            // host name the host's keys are filed under
+	port    string            // port the host's keys are filed under
+	files   []string          // known_hosts files (UserKnownHostsFile)
+	hash    bool              // ssh hashes the host names it adds (HashKnownHosts)
+	entries []knownHostsEntry // every entry in files
+	cursor  int               // selected entry (index into shown())
+	loading bool              // reading the files
+	seeding bool              // fetching a host key to add
+	seq     int               // increments on loads; for result matching
+}
+
+// knownHostsEntry is a known_hosts entry with the menu hosts it applies to.
+type knownHostsEntry struct {
+	knownhosts.Entry
+	users []string // aliases of the ssh hosts the entry matches
+}
+
+// knownHostRef is an ssh host's known_hosts name, for matching entries in
+// the background.
+type knownHostRef struct {
+	alias, name, port string
+}
+
+// shown returns the entries in view: the host's, or every entry.
+func (ks *knownHostsState) shown() []knownHostsEntry {
+	if ks.host == nil || ks.all {
+		return ks.entries
+	}
+	var out []knownHostsEntry
+	for _, e := range ks.entries {
+		if e.Marker == "" && e.MatchesHost(ks.name, ks.port) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// selected returns the entry under the cursor, if any.
+func (ks *knownHostsState) selected() (knownHostsEntry, bool) {
+	shown := ks.shown()
+	if ks.cursor < 0 || ks.cursor >= len(shown) {
+		return knownHostsEntry{}, false
+	}
+	return shown[ks.cursor], true
+}
+
+// knownHostName returns the name and port ssh files an ssh host's key under:
+// its HostName (or alias) and port.
+func knownHostName(it *menuItem) (host, port string) {
+	return cmp.Or(it.spec.HostName, it.spec.Alias), cmp.Or(it.spec.Port, "22")
+}
+
+// openKnownHosts opens the known_hosts view, showing the selected ssh host's
+// entries (or every entry if no ssh host is selected).
+func (m model) openKnownHosts() (model, tea.Cmd) {
+	ks := &knownHostsState{all: true}
+	if it, _ := m.lst.SelectedItem().(*menuItem); it != nil && it.kind == itemHost && it.protocol == config.ProtocolSSH {
+		ks.host, ks.all = it, false
+		ks.name, ks.port = knownHostName(it)
+	}
+	m.mode = modeKnownHosts
+	m.ms.knownHosts = ks
+	m.setStatusInfo("", 0)
+	m.relayout()
+	return m, m.loadKnownHosts()
+}
+
+// closeKnownHosts closes the known_hosts view and returns to the menu.
+func (m model) closeKnownHosts() (model, tea.Cmd) {
+	m.mode = modeMenu
+	m.ms.knownHosts = nil
+	m.relayout()
+	return m, nil
+}
+
+// loadKnownHosts starts reading the known_hosts files and matching their
+// entries to the menu's ssh hosts.
+func (m *model) loadKnownHosts() tea.Cmd {
+	ks := m.ms.knownHosts
+	ks.seq++
+	ks.loading = true
+	seq := ks.seq
+	alias := "*" // the files from the global config
+	if ks.host != nil {
+		alias = ks.host.spec.Alias
+	}
+	hosts, _ := getHostItemsWithHints(m.root)
+	var refs []knownHostRef
+	for _, h := range hosts {
+		if h.protocol == config.ProtocolSSH {
+			name, port := knownHostName(h)
+			refs = append(refs, knownHostRef{alias: h.spec.Alias, name: name, port: port})
+		}
+	}
+
+	return func() tea.Msg {
+		files, hash := connect.KnownHostsFiles(alias)
+		entries, err := knownhosts.ReadFiles(files)
+		out := make([]knownHostsEntry, 0, len(entries))
+		for _, e := range entries {
+			ke := knownHostsEntry{Entry: e}
+			for _, r := range refs {
+				if e.Marker == "" && e.MatchesHost(r.name, r.port) {
+					ke.users = append(ke.users, r.alias)
+				}
+			}
+			out = append(out, ke)
+		}
+		return knownHostsLoadedMsg{known: ks, seq: seq, files: files, hash: hash, entries: out, err: err}
+	}
+}
+
+// handleKnownHostsLoadedMsg fills the known_hosts view, keeping the cursor
+// in range.
+func (m model) handleKnownHostsLoadedMsg(msg knownHostsLoadedMsg) (model, tea.Cmd) {
+	ks := m.ms.knownHosts
+	if ks == nil || msg.known != ks || msg.seq != ks.seq {
+		return m, nil
+	}
+	ks.loading = false
+	ks.files, ks.hash, ks.entries = msg.files, msg.hash, msg.entries
+	ks.cursor = max(0, min(ks.cursor, len(ks.shown())-1))
+	if msg.err != nil {
+		return m, m.setStatusError("known_hosts: "+msg.err.Error(), statusTTL)
+	}
+	return m, nil
+}
+
+// handleKnownHostsKeyMsg handles keys in the known_hosts view.
+//
+// Up/down pick an entry, tab switches between the host's entries and every
+// entry, R removes all of the host's keys (like ssh-keygen -R), D deletes
+// the selected entry, S fetches the host's key to add, ctrl+r reloads
+// and left/esc closes the view.
+func (m model) handleKnownHostsKeyMsg(msg tea.KeyMsg) (model, tea.Cmd) {
+	ks := m.ms.knownHosts
+	if ks == nil {
+		return m.closeKnownHosts()
+	}
+
+	switch {
+	case msg.String() == "ctrl+c", key.Matches(msg, m.keys.Quit):
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.CloseDetails), key.Matches(msg, m.keys.CloseForm):
+		return m.closeKnownHosts()
+
+	case msg.String() == "up":
+		ks.cursor = max(0, ks.cursor-1)
+
+	case msg.String() == "down":
+		ks.cursor = max(0, min(len(ks.shown())-1, ks.cursor+1))
+
+	case key.Matches(msg, m.keys.KnownScope) && ks.host != nil:
+		ks.all = !ks.all
+		ks.cursor = 0
+
+	case key.Matches(msg, m.keys.KeyRefresh):
+		return m, m.loadKnownHosts()
+
+	case key.Matches(msg, m.keys.KnownRemove):
+		return m.removeKnownHost()
+
+	case key.Matches(msg, m.keys.KnownDelete):
+		e, ok := ks.selected()
+		if !ok {
+			return m, nil
+		}
+		if err := knownhosts.RemoveEntry(e.Entry); err != nil {
+			return m, tea.Batch(m.setStatusError("Delete entry: "+err.Error(), statusTTL), m.loadKnownHosts())
+		}
+		text := fmt.Sprintf("Deleted %s line %d (backup in %s).%s",
+			filepath.Base(e.File), e.Line, filepath.Base(e.File)+knownhosts.BackupSuffix, SuccessCheck)
+		return m, tea.Batch(m.setStatusSuccess(text, statusTTL), m.loadKnownHosts())
+
+	case key.Matches(msg, m.keys.KnownSeed):
+		return m.seedKnownHost()
+	}
+	return m, nil
+}
+
+// removeKnownHost removes every key for the view's host, or the selected
+// entry's host, from the known_hosts files.
+func (m model) removeKnownHost() (model, tea.Cmd) {
+	ks := m.ms.knownHosts
+	name, port := ks.name, ks.port
+	if ks.host == nil || ks.all {
+		e, ok := ks.selected()
+		if !ok || len(e.users) == 0 {
+			return m, m.setStatusInfo("The entry isn't for a host in the menu; press D to delete it instead.", statusTTL)
+		}
+		name, port = m.knownHostNameFor(e.users[0])
+	}
+	n, err := knownhosts.Remove(ks.files, name, port)
+	if err != nil {
+		return m, tea.Batch(m.setStatusError("Remove "+name+": "+err.Error(), statusTTL), m.loadKnownHosts())
+	}
+	if n == 0 {
+		return m, m.setStatusInfo("No keys on file for "+knownhosts.HostName(name, port)+".", statusTTL)
+	}
+	text := fmt.Sprintf("Removed %d key(s) for %s (backups end in %s).%s",
+		n, knownhosts.HostName(name, port), knownhosts.BackupSuffix, SuccessCheck)
+	return m, tea.Batch(m.setStatusSuccess(text, statusTTL), m.loadKnownHosts())
+}
+
+// knownHostNameFor returns the known_hosts name and port of the ssh host alias.
+func (m model) knownHostNameFor(alias string) (string, string) {
+	hosts, _ := getHostItemsWithHints(m.root)
+	for _, h := range hosts {
+		if h.protocol == config.ProtocolSSH && h.spec.Alias == alias {
+			return knownHostName(h)
+		}
+	}
+	return alias, "22"
+}
+
+// seedKnownHost fetches the view's host key, the way preflight does. A key
+// the host isn't known by yet is only added once its fingerprint has been
+// confirmed (see handleKnownHostSeededMsg).
+//
+// The key is fetched from the host itself, so hosts reached through a
+// ProxyJump are refused: dialing them directly would reach the wrong host,
+// if any.
+func (m model) seedKnownHost() (model, tea.Cmd) {
+	ks := m.ms.knownHosts
+	switch {
+	case ks.host == nil:
+		return m, m.setStatusInfo("Open this view on an ssh host to add its key.", statusTTL)
+	case ks.seeding:
+		return m, nil
+	case len(ks.files) == 0:
+		return m, m.setStatusError("ssh doesn't use a known_hosts file for "+ks.host.spec.Alias+".", statusTTL)
+	}
+	t, err := m.targetFor(ks.host)
+	if err != nil {
+		return m, m.setStatusError(err.Error(), statusTTL)
+	}
+	if hop, ok := t.FirstHop(); ok {
+		return m, m.setStatusError(ks.host.spec.Alias+" is reached through "+hop.Alias+
+			"; its key can't be fetched directly. Connect once to add it.", statusTTL)
+	}
+	host, port := connect.KnownHostsName(t)
+	addr := connect.GenerateHostPort(t)
+	files := ks.files
+
+	ks.seeding = true
+	cmd := func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), knownHostsSeedTimeout)
+		defer cancel()
+		res, err := connect.PreflightSSH(ctx, addr, host, port, files)
+		if err == nil {
+			err = res.KeyErr
+		}
+		return knownHostSeededMsg{known: ks, host: host, port: port, addr: addr, res: res, err: err}
+	}
+	return m, tea.Batch(m.setStatusInfo("Fetching the host key of "+knownhosts.HostName(host, port)+"…", 0), cmd)
+}
+
+// handleKnownHostSeededMsg reports what fetching the host key found, and
+// asks before adding a new one.
+func (m model) handleKnownHostSeededMsg(msg knownHostSeededMsg) (model, tea.Cmd) {
+	ks := m.ms.knownHosts
+	if ks == nil || msg.known != ks {
+		return m, nil
+	}
+	ks.seeding = false
+	name := knownhosts.HostName(msg.host, msg.port)
+	desc := msg.res.Key.Type + " " + msg.res.Key.Fingerprint()
+	switch {
+	case msg.err != nil:
+		return m, m.setStatusError("Fetch host key of "+name+": "+msg.err.Error(), statusTTL)
+	case msg.added:
+		return m, tea.Batch(m.setStatusSuccess("Added "+name+" "+desc+"."+SuccessCheck, statusTTL), m.loadKnownHosts())
+	}
+	switch msg.res.Known.Status {
+	case knownhosts.StatusNew:
+		if len(ks.files) == 0 {
+			return m, m.setStatusError("ssh doesn't use a known_hosts file for "+ks.host.spec.Alias+".", statusTTL)
+		}
+		return m.openKnownHostConfirm(msg)
+	case knownhosts.StatusChanged:
+		return m, m.setStatusError(name+" now presents "+desc+", which doesn't match known_hosts.\n"+
+			"If the change is expected, press R to remove the old keys, then S again.", 0)
+	case knownhosts.StatusRevoked:
+		return m, m.setStatusError(name+" presents "+desc+", which is marked @revoked.", 0)
+	}
+	return m, m.setStatusInfo(name+" "+desc+" is already known.", statusTTL)
+}
+
+// openKnownHostConfirm shows a fetched host key and asks before adding it to
+// the first known_hosts file, returning to the known_hosts view either way.
+func (m model) openKnownHostConfirm(msg knownHostSeededMsg) (model, tea.Cmd) {
+	ks := m.ms.knownHosts
+	name := knownhosts.HostName(msg.host, msg.port)
+	file, hash := ks.files[0], ks.hash
+	key := msg.res.Key
+
+	title := "Add the host key of " + name + "?"
+	description := "Only add it if the fingerprint matches the one the host's admin published."
+	add := func() tea.Msg {
+		err := knownhosts.Add(file, msg.host, msg.port, key.Type, key.Blob, hash)
+		return knownHostSeededMsg{known: ks, host: msg.host, port: msg.port, addr: msg.addr, res: msg.res,
+			added: err == nil, err: err}
+	}
+
+	s := m.newDetailsStyles()
+	var b strings.Builder
+	b.WriteString(s.header.PaddingBottom(1).Render("NEW HOST KEY"))
+	b.WriteString("\n")
+	for _, r := range [][2]string{
+		{"Host", ks.host.spec.Alias},
+		{"Name", name},
+		{"Address", msg.addr},
+		{"Key", key.Type},
+		{"Fingerprint", key.Fingerprint()},
+		{"File", file},
+	} {
+		label := s.label.Render(fmt.Sprintf("%11s", r[0]))
+		fmt.Fprintf(&b, "%s:  %s\n", label, s.value.Render(r[1]))
+	}
+
+	form := buildConfirmForm(title, description, m.theme)
+	m.setStatusInfo("", 0)
+	m.mode = modeConfirm
+	m.ms.confirm = &confirmState{
+		form:        form,
+		title:       title,
+		description: description,
+		body:        strings.TrimRight(b.String(), "\n"),
+		returnMode:  modeKnownHosts,
+		onConfirm:   add,
+		onCancel:    m.setStatusError(ErrorX+"Didn't add the host key of "+name+".", statusTTL),
+	}
+	m.relayout()
+	return m, form.Init()
+}
+
+// knownHostsHelpKeys returns the help keys shown in the known_hosts view.
+func (m model) knownHostsHelpKeys() []key.Binding {
+	keys := []key.Binding{m.keys.CloseDetails, m.lst.KeyMap.CursorUp, m.lst.KeyMap.CursorDown}
+	if ks := m.ms.knownHosts; ks != nil && ks.host != nil {
+		keys = append(keys, m.keys.KnownScope, m.keys.KnownSeed)
+	}
+	return append(keys, m.keys.KnownRemove, m.keys.KnownDelete, m.keys.KeyRefresh)
+}
+
+// viewKnownHosts renders the entries with the selected entry's details.
+func (m model) viewKnownHosts() string {
+	ks := m.ms.knownHosts
+	if ks == nil {
+		return ""
+	}
+	lg := lipgloss.NewStyle()
+	dim := lg.Foreground(m.theme.PreflightText)
+	h := m.lst.Help
+	h.Width = m.width
+	width := max(10, m.width-footerPadLeft-2)
+
+	shown := ks.shown()
+	title := fmt.Sprintf("KNOWN HOSTS • all entries (%d)", len(shown))
+	if ks.host != nil && !ks.all {
+		title = fmt.Sprintf("KNOWN HOSTS • %s (%s)", ks.host.spec.Alias, knownhosts.HostName(ks.name, ks.port))
+	}
+	header := lg.Padding(1, 0, 0, 1).Render(m.lst.Styles.Title.Render(title))
+	fileList := strings.Join(ks.files, ", ")
+	if len(ks.files) == 0 && !ks.loading {
+		fileList = "none (ssh keeps no known_hosts here)"
+	}
+	files := dim.PaddingLeft(footerPadLeft).PaddingBottom(1).
+		Render(ansi.Truncate("Files: "+fileList, width, "…"))
+
+	footer := transferFooterLines + knownHostsDetailLines + 2
+	if m.status != "" {
+		footer += 1 + lipgloss.Height(m.status)
+	}
+	fit := max(1, m.height-footer-3)
+	first := max(0, ks.cursor-fit+1)
+
+	var rows []string
+	for i := first; i < len(shown) && i < first+fit; i++ {
+		prefix := "  "
+		if i == ks.cursor {
+			prefix = lg.Foreground(m.theme.SelectedItemTitle).Render("> ")
+		}
+		rows = append(rows, prefix+m.knownHostLine(shown[i], width))
+	}
+	switch {
+	case ks.loading && len(ks.entries) == 0:
+		rows = append(rows, dim.Render("  Reading known_hosts…"))
+	case len(shown) == 0 && ks.host != nil && !ks.all:
+		rows = append(rows, dim.Render("  No keys on file for this host. Press S to fetch and add its key."))
+	case len(shown) == 0:
+		rows = append(rows, dim.Render("  No known_hosts entries."))
+	}
+
+	body := header + "\n" + files + "\n" + lg.PaddingLeft(footerPadLeft).Render(strings.Join(rows, "\n"))
+	if e, ok := ks.selected(); ok {
+		body += "\n" + m.knownHostDetails(e)
+	}
+	lines := []string{body}
+	if m.status != "" {
+		lines = append(lines, lg.PaddingLeft(footerPadLeft).PaddingTop(1).Foreground(m.statusColor()).Render(m.status))
+	}
+	lines = append(lines, lg.PaddingLeft(footerPadLeft).PaddingTop(1).Render(h.ShortHelpView(m.knownHostsHelpKeys())))
+	return strings.Join(lines, "\n")
+}
+
+// knownHostLine renders an entry on one line: hosts, key type and the menu
+// hosts it's for.
+func (m model) knownHostLine(e knownHostsEntry, width int) string {
+	lg := lipgloss.NewStyle()
+	right := lg.Foreground(m.theme.PreflightText).Render(strings.Join(e.users, ","))
+	if e.Marker != "" {
+		right = lg.Foreground(m.theme.StatusError).Render(e.Marker)
+	}
+	left := fmt.Sprintf("%-32s %s", ansi.Truncate(e.Hosts(), 32, "…"), e.KeyType)
+	left = ansi.Truncate(left, max(1, width-lipgloss.Width(right)-1), "…")
+	return left + strings.Repeat(" ", max(1, width-lipgloss.Width(left)-lipgloss.Width(right))) + right
+}
+
+// knownHostDetails renders the selected entry's details.
+func (m model) knownHostDetails(e knownHostsEntry) string {
+	s := m.newDetailsStyles()
+	users := "(none)"
+	if len(e.users) > 0 {
+		users = summarizeList(e.users, sshKeysUsedByMax)
+	}
+	rows := [][2]string{
+		{"File", fmt.Sprintf("%s line %d", e.File, e.Line)},
+		{"Hosts", e.Hosts()},
+		{"Key", e.KeyType},
+		{"Fingerprint", e.Fingerprint()},
+		{"Menu Hosts", users},
+	}
+	if e.Marker != "" {
+		rows = append(rows, [2]string{"Marker", e.Marker})
+	}
+	if e.Comment != "" {
+		rows = append(rows, [2]string{"Comment", e.Comment})
+	}
+
+	var b strings.Builder
+	b.WriteString(s.header.PaddingBottom(1).Render("ENTRY DETAILS"))
+	b.WriteString("\n")
+	for _, r := range rows {
+		label := s.label.Render(fmt.Sprintf("%11s", r[0]))
+		fmt.Fprintf(&b, "%s:  %s\n", label, s.value.Render(r[1]))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -20,7 +20,8 @@ const (
 // should suppress list navigation and help.
 func (m *model) isModalMode() bool {
 	switch m.mode {
-	case modePreflight, modePromptUsername, modeHostDetails, modeHostForm, modeConfirm:
+	case modePreflight, modePromptUsername, modeHostDetails, modeHostForm, modeConfirm, modeRecordings, modeRun, modeBulk,
+		modeTransfer, modeTransferQueue, modeSSHKeys, modeKnownHosts, modeSettings, modeImport:
 		return true
 	}
 	return false
@@ -29,7 +30,8 @@ func (m *model) isModalMode() bool {
 // hidesListHelp returns true if the current mode should hide the list's help bar.
 func (m *model) hidesListHelp() bool {
 	switch m.mode {
-	case modePreflight, modeHostDetails, modeHostForm, modeConfirm:
+	case modePreflight, modeHostDetails, modeHostForm, modeConfirm, modeRecordings, modeRun, modeBulk,
+		modeTransfer, modeTransferQueue, modeSSHKeys, modeKnownHosts, modeSettings, modeImport:
 		return true
 	}
 	return false
@@ -87,6 +89,12 @@ func (m *model) relayout() {
 	m.syncHelpKeys()
 	m.resizeHostForm()
 	m.resizeConfirmDialog()
+	m.resizeRecordings()
+	m.resizeRun()
+	m.resizeBulk()
+	m.resizeSSHKeys()
+	m.resizeSettings()
+	m.resizeImport()
 }
 
 // footerHeight calculates how many lines the footer area consumes.
@@ -2,10 +2,8 @@ package tui
 
 import (
 	"cmp"
-	"errors"
-	"fmt"
-	"os"
 	"slices"
+	"strings"
 
 	"bubbletea-ssh-manager/internal/config"
 	str "bubbletea-ssh-manager/internal/stringutil"
@@ -43,62 +41,68 @@ func addMenuItem(ungrouped *[]*menuItem, groups map[string]*menuItem, host *menu
 	*ungrouped = append(*ungrouped, host)
 }
 
-// buildMenuFromConfigs builds menu items from SSH and Telnet config files.
+// buildMenuFromConfigs builds menu items from the config file of every registered protocol.
 //
-// SSH items connect by alias (ssh reads ~/.ssh/config).
-// Telnet items connect by HostName/Port because telnet typically does not use aliases.
+// SSH items connect by alias (ssh reads ~/.ssh/config, or the host's
+// inventory with -F). Telnet items connect by HostName/Port because telnet
+// typically does not use aliases. Other protocols connect however their argv
+// builder says (eg. a command template).
+//
+// When inventories besides the local configs are set up, each one is a
+// top-level source holding its own hosts and groups.
 func buildMenuFromConfigs() ([]*menuItem, error) {
-	sshPath, err := config.GetConfigPath(".ssh", "config")
-	if err != nil {
-		return nil, err
-	}
-	telnetPath, err := config.GetConfigPath(".telnet", "config")
-	if err != nil {
-		return nil, err
-	}
-
-	var (
-		ungrouped []*menuItem
-		groups    = map[string]*menuItem{}
-		parseErrs []error
-	)
-
-	parseErrs = append(parseErrs, parseConfigToMenu(sshPath, config.ProtocolSSH, &ungrouped, groups))
-	parseErrs = append(parseErrs, parseConfigToMenu(telnetPath, config.ProtocolTelnet, &ungrouped, groups))
-
-	items := buildSortedMenuItems(ungrouped, groups)
-	return items, errors.Join(parseErrs...)
-}
-
-// parseConfigToMenu parses a config file and adds entries to the menu structure.
-func parseConfigToMenu(path string, protocol config.Protocol, ungrouped *[]*menuItem, groups map[string]*menuItem) error {
-	entries, err := config.ParseConfigRecursively(path)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil
+	hosts, err := config.LoadHosts()
+	items := func(hosts []config.Host) []*menuItem {
+		var (
+			ungrouped []*menuItem
+			groups    = map[string]*menuItem{}
+		)
+		for _, e := range hosts {
+			h := &menuItem{kind: itemHost, protocol: e.Protocol, spec: e.Spec, options: e.SSHOptions, telnet: e.TelnetOptions,
+				command: e.CommandOptions, serial: e.SerialOptions, app: e.AppOptions, inv: e.Inventory}
+			addMenuItem(&ungrouped, groups, h)
 		}
-		return fmt.Errorf("read %s config: %w", protocol, err)
+		return buildSortedMenuItems(ungrouped, groups)
+	}
+	if !config.HasOtherInventories() {
+		return items(hosts), err
 	}
 
-	for _, e := range entries {
-		if !isValidEntry(e, protocol) {
+	// Inventories' errors are in err already, from LoadHosts
+	invs, _ := config.Inventories()
+	var sources []*menuItem
+	for _, inv := range invs {
+		if slices.ContainsFunc(sources, func(s *menuItem) bool { return s.name == inv.Name }) {
 			continue
 		}
-		h := &menuItem{kind: itemHost, protocol: protocol, spec: e.Spec, options: e.SSHOptions}
-		addMenuItem(ungrouped, groups, h)
+		mine := slices.DeleteFunc(slices.Clone(invs), func(o config.Inventory) bool { return o.Name != inv.Name })
+		hosts := slices.DeleteFunc(slices.Clone(hosts), func(h config.Host) bool { return h.Inventory.Name != inv.Name })
+		sources = append(sources, &menuItem{kind: itemGroup, name: inv.Name, source: sourceDescription(mine),
+			children: items(hosts)})
 	}
-	return nil
+	return sources, err
 }
 
-// isValidEntry checks if a config entry has required fields for the given protocol.
-func isValidEntry(e config.HostEntry, protocol config.Protocol) bool {
-	if e.Spec.Alias == "" {
-		return false
+// sourceDescription describes a source made of invs (one per protocol):
+// its protocols and whether they're writable, eg. "ssh, telnet • writable"
+// or "ssh • writable, telnet • read-only".
+func sourceDescription(invs []config.Inventory) string {
+	var writable, readOnly []string
+	for _, inv := range invs {
+		if inv.Writable() != nil {
+			readOnly = append(readOnly, string(inv.Protocol))
+		} else {
+			writable = append(writable, string(inv.Protocol))
+		}
+	}
+	var parts []string
+	if len(writable) > 0 {
+		parts = append(parts, strings.Join(writable, ", ")+" • writable")
 	}
-	if protocol == config.ProtocolTelnet && e.Spec.HostName == "" {
-		return false
+	if len(readOnly) > 0 {
+		parts = append(parts, strings.Join(readOnly, ", ")+" • read-only")
 	}
-	return true
+	return strings.Join(parts, ", ")
 }
 
 // buildSortedMenuItems converts groups map to slice and sorts all items alphabetically.
@@ -5,9 +5,10 @@ import (
 	"io"
 	"strings"
 
-	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/monitor"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
 )
 
@@ -15,6 +16,11 @@ type menuDelegate struct {
 	list.DefaultDelegate                      // embed default delegate to reuse its functionality
 	groupHints           map[*menuItem]string // optional group hints per host item
 	theme                Theme                // app theme for coloring
+
+	monitoring bool                      // draw reachability indicators
+	reach      map[string]monitor.Result // reachability results by hostKey
+
+	marked map[string]struct{} // hosts marked for bulk actions, by hostKey
 }
 
 // newMenuDelegate creates a new menuDelegate with default settings.
@@ -56,32 +62,49 @@ func (d *menuDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 	}
 
 	// apply per-kind coloring to titles
+	dot := ""
 	mi, _ := item.(*menuItem)
 	if mi != nil {
 		switch mi.kind {
 		case itemGroup:
 			normalTitle = normalTitle.Foreground(d.theme.GroupName)
 			selectedTitle = selectedTitle.Foreground(d.theme.GroupName)
+			if n := countMarked(mi, d.marked); n > 0 {
+				desc += fmt.Sprintf(" • %d marked", n)
+			}
+			if d.monitoring {
+				if up, down := groupReachability(mi, d.reach); up+down > 0 {
+					desc += fmt.Sprintf(" • %d up, %d down", up, down)
+				}
+			}
 		case itemHost:
 			if d.groupHints != nil {
 				if grp := d.groupHints[mi]; grp != "" {
 					desc = string(mi.protocol) + " • " + grp
 				}
 			}
-			if mi.protocol == config.ProtocolTelnet {
-				normalTitle = normalTitle.Foreground(d.theme.ProtocolTelnet)
-				selectedTitle = selectedTitle.Foreground(d.theme.ProtocolTelnet)
-			} else {
-				// default to SSH color (green)
-				normalTitle = normalTitle.Foreground(d.theme.ProtocolSSH)
-				selectedTitle = selectedTitle.Foreground(d.theme.ProtocolSSH)
+			if d.monitoring {
+				r, ok := d.reach[hostKey(mi)]
+				dot = lipgloss.NewStyle().Foreground(d.theme.monitorColor(r.State)).Render(MonitorDot)
+				desc += " • " + monitorSummary(r, ok)
 			}
+			if _, ok := d.marked[hostKey(mi)]; ok {
+				title = MarkSymbol + title
+				normalTitle = normalTitle.Bold(true)
+				selectedTitle = selectedTitle.Bold(true)
+			}
+			normalTitle = normalTitle.Foreground(d.theme.protocolColor(mi.protocol))
+			selectedTitle = selectedTitle.Foreground(d.theme.protocolColor(mi.protocol))
 		}
 	}
 
 	// prevent text from exceeding list width
 	textWidth := max(width-normalTitle.GetPaddingLeft()-normalTitle.GetPaddingRight(), 0)
-	title = ansi.Truncate(title, textWidth, "…")
+	if dot != "" {
+		title = ansi.Truncate(title, max(textWidth-2, 0), "…")
+	} else {
+		title = ansi.Truncate(title, textWidth, "…")
+	}
 	if d.ShowDescription {
 		var lines []string
 		for i, line := range strings.Split(desc, "\n") {
@@ -103,6 +126,12 @@ func (d *menuDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 		desc = normalDesc.Render(desc)
 	}
 
+	// the dot is styled on its own and appended last so its color reset
+	// doesn't cut the title style short
+	if dot != "" {
+		title += " " + dot
+	}
+
 	// render final output
 	if d.ShowDescription {
 		fmt.Fprintf(w, "%s\n%s", title, desc)
@@ -10,11 +10,29 @@ import (
 	"github.com/charmbracelet/bubbles/list"
 )
 
+// hostLevel returns the items at the level hosts and host groups live at:
+// root's children, with top-level inventory sources replaced by theirs.
+func hostLevel(root *menuItem) []*menuItem {
+	if root == nil {
+		return nil
+	}
+	var out []*menuItem
+	for _, it := range root.children {
+		if it != nil && it.source != "" {
+			out = append(out, it.children...)
+			continue
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
 // getHostItemsWithHints returns all host items in the tree and an optional
 // group hint per host indicating where the host came from.
 //
-// Assumes groups are one level deep (non-nested): root contains (a) ungrouped
-// hosts and (b) group items whose direct children are hosts.
+// Assumes groups are one level deep (non-nested): the host level (see
+// hostLevel) contains (a) ungrouped hosts and (b) group items whose direct
+// children are hosts. Hints of hosts under an inventory source name it too.
 func getHostItemsWithHints(root *menuItem) (hosts []*menuItem, hints map[*menuItem]string) {
 	if root == nil {
 		return nil, nil
@@ -22,29 +40,50 @@ func getHostItemsWithHints(root *menuItem) (hosts []*menuItem, hints map[*menuIt
 
 	hosts = make([]*menuItem, 0, 64)
 	hints = map[*menuItem]string{}
-	for _, it := range root.children {
-		if it == nil {
+	for _, src := range root.children {
+		if src == nil {
 			continue
 		}
-		if it.kind == itemHost {
-			hosts = append(hosts, it)
-			continue
+		items, prefix := []*menuItem{src}, ""
+		if src.source != "" {
+			items, prefix = src.children, src.name
 		}
-		if it.kind != itemGroup {
+		for _, it := range items {
+			addHostsWithHints(it, prefix, &hosts, hints)
+		}
+	}
+	return hosts, hints
+}
+
+// addHostsWithHints adds it (a host, or a group's hosts) to hosts, hinting
+// them with prefix and the group's name.
+func addHostsWithHints(it *menuItem, prefix string, hosts *[]*menuItem, hints map[*menuItem]string) {
+	if it == nil {
+		return
+	}
+	if it.kind == itemHost {
+		*hosts = append(*hosts, it)
+		if prefix != "" {
+			hints[it] = prefix
+		}
+		return
+	}
+	if it.kind != itemGroup {
+		return
+	}
+	grp := it.name
+	if prefix != "" {
+		grp = prefix + " / " + grp
+	}
+	for _, ch := range it.children {
+		if ch == nil || ch.kind != itemHost {
 			continue
 		}
-		grp := it.name
-		for _, ch := range it.children {
-			if ch == nil || ch.kind != itemHost {
-				continue
-			}
-			hosts = append(hosts, ch)
-			if grp != "" {
-				hints[ch] = grp
-			}
+		*hosts = append(*hosts, ch)
+		if grp != "" {
+			hints[ch] = grp
 		}
 	}
-	return hosts, hints
 }
 
 // applyFilter filters the list items based on the query string q.
@@ -69,8 +108,9 @@ func (m *model) applyFilter(q string) {
 	// behavior:
 	// - inside a group: search only hosts in that group (current page)
 	// - at the root: search all hosts globally, plus allow matching group names
+	// - inside an inventory source: like the root, for the source's hosts
 	candidates := make([]*menuItem, 0, len(m.allItems))
-	if m.inGroup() {
+	if m.inGroup() && m.current().source == "" {
 		// in group: current page is a group's host list
 		candidates = append(candidates, m.allItems...)
 		if m.delegate != nil {
@@ -85,7 +125,7 @@ func (m *model) applyFilter(q string) {
 		}
 
 		// also include all hosts with group hints
-		hosts, hints := getHostItemsWithHints(m.root)
+		hosts, hints := getHostItemsWithHints(m.current())
 		candidates = append(candidates, hosts...)
 		if m.delegate != nil {
 			m.delegate.groupHints = hints
@@ -110,7 +150,7 @@ func (m *model) applyFilter(q string) {
 
 		// fuzzy match
 		hay := strings.ToLower(it.FilterValue())
-		s, ok := fuzzyScore(q, hay)
+		s, ok := str.FuzzyScore(q, hay)
 		if !ok {
 			continue
 		}
@@ -128,40 +168,3 @@ func (m *model) applyFilter(q string) {
 	}
 	m.updateItems(filtered)
 }
-
-// fuzzyScore returns a simple subsequence match score
-// higher is better. "ok" is false if q is not a subsequence of s.
-func fuzzyScore(q, s string) (score int, ok bool) {
-	q = str.NormalizeString(q)
-	s = str.NormalizeString(s)
-
-	// empty query matches everything with score 0
-	if q == "" {
-		return 0, true
-	}
-
-	// check for subsequence and calculate score
-	qi := 0
-	streak := 0
-	for i := 0; i < len(s) && qi < len(q); i++ {
-		if s[i] == q[qi] {
-			qi++
-			streak++
-			score += 10 + (streak * 2) // reward contiguous runs
-		} else {
-			streak = 0
-		}
-	}
-
-	// if we didn't consume all of q, it's not a match
-	if qi != len(q) {
-		return 0, false
-	}
-
-	// small preference for earlier matches
-	if idx := strings.Index(s, string(q[0])); idx >= 0 {
-		score -= idx
-	}
-
-	return score, true
-}
@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"fmt"
 	"strings"
 
 	"bubbletea-ssh-manager/internal/config"
@@ -21,12 +22,18 @@ type menuItem struct {
 	name string   // display name (host alias or group name)
 
 	// host-only fields
-	protocol config.Protocol   // protocol
-	spec     config.Spec       // shared host fields (alias/hostname/port/user)
-	options  config.SSHOptions // SSH options (only for SSH hosts)
+	protocol config.Protocol       // protocol
+	spec     config.Spec           // shared host fields (alias/hostname/port/user)
+	options  config.SSHOptions     // SSH options (only for SSH hosts)
+	telnet   config.TelnetOptions  // telnet options (only for telnet hosts)
+	command  config.CommandOptions // command template options (mosh, raw, serial, custom)
+	serial   config.SerialOptions  // serial line options (only for serial hosts)
+	app      config.AppOptions     // app-only settings (recording, etc.)
+	inv      config.Inventory      // inventory the host was read from
 
 	// group-only fields
 	children []*menuItem // child menu items
+	source   string      // description of a top-level inventory source ("" for host groups)
 }
 
 // Title returns the main display name of the menu item.
@@ -37,17 +44,21 @@ func (it *menuItem) Title() string {
 // Description returns a short description of the menu item.
 //
 // For host items, it's the protocol.
-// For group items, it's just "group".
+// For inventory sources, it's their protocols and whether they're writable.
+// For other group items, it's just "group".
 func (it *menuItem) Description() string {
 	if it.kind == itemHost {
 		return string(it.protocol)
 	}
+	if it.source != "" {
+		return it.source
+	}
 	return "group"
 }
 
 // FilterValue returns the string used for filtering this item.
 //
-// For host items, it's a combination of name, protocol, alias, username, and hostname.
+// For host items, it's a combination of name, protocol, alias, username, hostname, and tags.
 // For group items, it's just the name.
 func (it *menuItem) FilterValue() string {
 	if it.kind == itemHost {
@@ -61,11 +72,33 @@ func (it *menuItem) FilterValue() string {
 		if v := it.spec.HostName; v != "" {
 			parts = append(parts, v)
 		}
+		parts = append(parts, config.ParseTags(it.app.Tags)...)
 		return strings.Join(parts, " ")
 	}
 	return it.name
 }
 
+// inventory returns the inventory a host item was read from. Items that
+// weren't read from one (eg. the stub menu's) belong to the local config.
+func (it *menuItem) inventory() (config.Inventory, error) {
+	if it.inv.Name == "" {
+		return config.LocalInventoryFor(it.protocol)
+	}
+	return it.inv, nil
+}
+
+// hostKey identifies a host across menu reloads (monitor results, marks).
+//
+// Protocol is included since an alias may exist in both the ssh and telnet
+// configs, and the inventory for hosts outside the local configs.
+func hostKey(it *menuItem) string {
+	key := string(it.protocol) + ":" + it.spec.Alias
+	if it.inv.Name != "" && !it.inv.IsLocal() {
+		key = it.inv.Name + ":" + key
+	}
+	return key
+}
+
 // current returns the current menu item (the last in the path).
 func (m *model) current() *menuItem {
 	return m.path[len(m.path)-1]
@@ -110,7 +143,12 @@ func (m *model) setCurrentMenu(items []*menuItem) {
 		m.delegate.groupHints = nil
 	}
 	m.updateItems(toListItems(items))
+	m.syncListTitle()
+}
 
+// syncListTitle sets the list title to the navigation path, plus the number
+// of marked hosts and running transfers if any.
+func (m *model) syncListTitle() {
 	parts := make([]string, 0, len(m.path))
 	for _, p := range m.path {
 		name := p.name
@@ -120,6 +158,14 @@ func (m *model) setCurrentMenu(items []*menuItem) {
 		parts = append(parts, name)
 	}
 	m.lst.Title = strings.Join(parts, " / ")
+	if n := len(m.marked); n > 0 {
+		m.lst.Title += fmt.Sprintf(" • %d marked", n)
+	}
+	if m.transfers != nil {
+		if n := m.transfers.Active(); n > 0 {
+			m.lst.Title += fmt.Sprintf(" • %d transferring", n)
+		}
+	}
 }
 
 // updateItems sets the list items and resets selection to the first item.
@@ -4,7 +4,10 @@ import (
 	"strings"
 	"time"
 
+	"bubbletea-ssh-manager/internal/settings"
+
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 type statusKind int // type of status message (info/success/error)
@@ -14,7 +17,8 @@ const (
 	statusSuccess
 	statusError
 )
-const statusTTL = 10 * time.Second // duration for non-error info statuses
+
+var statusTTL = settings.DefaultStatusTTL // duration for non-error info statuses (ui.status_ttl)
 
 // setStatus sets the status message and kind.
 //
@@ -30,6 +34,7 @@ func (m *model) setStatus(text string, kind statusKind, d time.Duration) tea.Cmd
 
 	m.statusToken++
 	m.status = text
+	m.ms.pendingFix = nil // a fix is only offered while its status is shown
 	m.statusKind = kind
 	m.relayout()
 
@@ -42,6 +47,17 @@ func (m *model) setStatus(text string, kind statusKind, d time.Duration) tea.Cmd
 	return nil
 }
 
+// statusColor returns the theme color for the current status kind.
+func (m model) statusColor() lipgloss.Color {
+	switch m.statusKind {
+	case statusError:
+		return m.theme.StatusError
+	case statusSuccess:
+		return m.theme.StatusSuccess
+	}
+	return m.theme.StatusDefault
+}
+
 func (m *model) setStatusInfo(text string, d time.Duration) tea.Cmd {
 	return m.setStatus(text, statusInfo, d)
 }
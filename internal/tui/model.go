@@ -1,7 +1,10 @@
 package tui
 
 import (
+	"bubbletea-ssh-manager/internal/launch"
+	"bubbletea-ssh-manager/internal/settings"
 	str "bubbletea-ssh-manager/internal/stringutil"
+	"bubbletea-ssh-manager/internal/transfer"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -19,6 +22,15 @@ const (
 	modePreflight
 	modeExecuting
 	modeConfirm
+	modeRecordings
+	modeRun
+	modeBulk
+	modeTransfer
+	modeTransferQueue
+	modeSSHKeys
+	modeKnownHosts
+	modeSettings
+	modeImport
 )
 
 type model struct {
@@ -44,26 +56,81 @@ type model struct {
 	statusKind  statusKind // status style (info/success/error)
 	statusToken int        // increments on status updates; tracked to clear status
 	quitting    bool       // is the app quitting?
+
+	recordNext  bool                // record the next session even if the host doesn't have recording on
+	preHooksRan bool                // the connect in progress ran its pre-connect hooks, so its post-connect hooks are owed
+	marked      map[string]struct{} // hosts marked for bulk actions, by hostKey
+
+	monitor monitorState // background reachability monitor
+
+	launcher launch.Launcher // opens sessions alongside the menu (nil runs them in place)
+
+	transfers       *transfer.Queue // background file transfers
+	transferTicking bool            // progress tick is scheduled
+
+	settings settings.Settings // effective settings
+}
+
+// options are what NewModel's Options set.
+type options struct {
+	query       string            // initial search
+	settings    settings.Settings // effective settings
+	settingsErr error             // problems loading the settings, shown in the status line
+}
+
+// Option changes how NewModel sets up the TUI.
+type Option func(*options)
+
+// WithQuery starts the menu with q in the search box, filtered as if it had
+// been typed.
+func WithQuery(q string) Option {
+	return func(o *options) { o.query = q }
+}
+
+// WithSettings runs the menu with the given settings (the built-in ones by
+// default); err is any problem loading them, shown in the status line.
+func WithSettings(s settings.Settings, err error) Option {
+	return func(o *options) { o.settings, o.settingsErr = s, err }
 }
 
 // NewModel constructs the Bubble Tea model for the TUI.
 //
 // It returns the model as tea.Model so callers don't need access to the
 // package-private concrete type.
-func NewModel() tea.Model {
-	return newModel()
+func NewModel(opts ...Option) tea.Model {
+	o := options{settings: settings.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	m := newModel(o.settings)
+	if o.query != "" {
+		m.query.SetValue(o.query)
+		m.applyFilter(o.query)
+		m.syncHelpKeys()
+	}
+	if o.settingsErr != nil {
+		m.setStatusError("Settings: "+str.LastNonEmptyLine(o.settingsErr.Error()), 0)
+	}
+	return m
 }
 
-// Init returns the initial command for the TUI (blinking cursor and window title).
+// Init returns the initial command for the TUI (blinking cursor and window title),
+// plus the first monitor sweep if the monitor starts enabled.
 func (m model) Init() tea.Cmd {
-	return tea.Batch(tea.SetWindowTitle("SSH Manager"), textinput.Blink)
+	var monitorCmd tea.Cmd
+	if m.monitor.enabled {
+		monitorCmd = monitorTickCmd(m.monitor.token, 0)
+	}
+	return tea.Batch(tea.SetWindowTitle("SSH Manager"), textinput.Blink, monitorCmd)
 }
 
-// newModel creates a new TUI model with initial state and seeded menu items.
+// newModel creates a new TUI model with initial state and seeded menu items,
+// styled and timed by the settings.
 //
 // It returns the initialized model.
-func newModel() model {
-	theme := DefaultTheme()
+func newModel(st settings.Settings) model {
+	statusTTL = st.StatusTTL
+	theme := themeNamed(st.Theme)
 	keys := NewKeyMap(theme)
 
 	// text input for search query
@@ -91,6 +158,8 @@ func newModel() model {
 
 	// setup list to display menu items
 	d := newMenuDelegate(theme)
+	marked := map[string]struct{}{}
+	d.marked = marked
 	lst := list.New(litems, d, 0, 0)
 	lst.InfiniteScrolling = true
 	lst.Styles.TitleBar = lst.Styles.TitleBar.Padding(1, 0, 1, 1)
@@ -111,12 +180,23 @@ func newModel() model {
 		path:     path,
 		lst:      lst,
 		mode:     modeMenu,
+		marked:   marked,
+
+		transfers: transfer.NewQueue(transfer.DefaultConcurrency),
+		settings:  st,
 	}
 
 	m.initHelpKeys()
 	m.setCurrentMenu(items)
+	if st.Monitor {
+		m.startMonitor()
+	}
+	launcher, launchErr := launch.Parse(st.Launcher, st.TerminalCommand)
+	m.launcher = launcher
 	if seedErr != nil {
 		m.setStatusError("Config: "+str.LastNonEmptyLine(seedErr.Error()), 0)
+	} else if launchErr != nil {
+		m.setStatusError(launchErr.Error()+"; sessions open in place.", 0)
 	}
 	return m
 }
@@ -158,12 +238,99 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case preflightTickMsg:
 		nm, cmd := m.handlePreflightTickMsg(v)
 		return nm, cmd
+	case monitorTickMsg:
+		nm, cmd := m.handleMonitorTickMsg(v)
+		return nm, cmd
+	case monitorSweptMsg:
+		nm, cmd := m.handleMonitorSweptMsg(v)
+		return nm, cmd
+	case preflightRetryMsg:
+		nm, cmd := m.handlePreflightRetryMsg(v)
+		return nm, cmd
 	case preflightResultMsg:
 		nm, cmd := m.handlePreflightResultMsg(v)
 		return nm, cmd
+	case sessionLaunchedMsg:
+		nm, cmd := m.handleSessionLaunchedMsg(v)
+		return nm, cmd
 	case connectFinishedMsg:
 		nm, cmd := m.handleConnectFinishedMsg(v)
 		return nm, cmd
+	case recordingsLoadedMsg:
+		nm, cmd := m.handleRecordingsLoadedMsg(v)
+		return nm, cmd
+	case algorithmsProbedMsg:
+		nm, cmd := m.handleAlgorithmsProbedMsg(v)
+		return nm, cmd
+	case fixAppliedMsg:
+		nm, cmd := m.handleFixAppliedMsg(v)
+		return nm, cmd
+	case runFormDoneMsg:
+		nm, cmd := m.handleRunFormDoneMsg(v)
+		return nm, cmd
+	case runUpdatesMsg:
+		nm, cmd := m.handleRunUpdatesMsg(v)
+		return nm, cmd
+	case runSavedMsg:
+		nm, cmd := m.handleRunSavedMsg(v)
+		return nm, cmd
+	case bulkFormDoneMsg:
+		nm, cmd := m.handleBulkFormDoneMsg(v)
+		return nm, cmd
+	case bulkDoneMsg:
+		nm, cmd := m.handleBulkDoneMsg(v)
+		return nm, cmd
+	case settingsFormDoneMsg:
+		nm, cmd := m.handleSettingsFormDoneMsg(v)
+		return nm, cmd
+	case settingsSavedMsg:
+		nm, cmd := m.handleSettingsSavedMsg(v)
+		return nm, cmd
+	case importFormDoneMsg:
+		nm, cmd := m.handleImportFormDoneMsg(v)
+		return nm, cmd
+	case importPlannedMsg:
+		nm, cmd := m.handleImportPlannedMsg(v)
+		return nm, cmd
+	case importDoneMsg:
+		nm, cmd := m.handleImportDoneMsg(v)
+		return nm, cmd
+	case remoteListedMsg:
+		nm, cmd := m.handleRemoteListedMsg(v)
+		return nm, cmd
+	case preHooksDoneMsg:
+		nm, cmd := m.handlePreHooksDoneMsg(v)
+		return nm, cmd
+	case postHooksDoneMsg:
+		nm, cmd := m.handlePostHooksDoneMsg(v)
+		return nm, cmd
+	case transferTickMsg:
+		nm, cmd := m.handleTransferTickMsg(v)
+		return nm, cmd
+	case sshKeysLoadedMsg:
+		nm, cmd := m.handleSSHKeysLoadedMsg(v)
+		return nm, cmd
+	case sshKeysFormDoneMsg:
+		nm, cmd := m.handleSSHKeysFormDoneMsg(v)
+		return nm, cmd
+	case sshKeyGeneratedMsg:
+		nm, cmd := m.handleSSHKeyGeneratedMsg(v)
+		return nm, cmd
+	case sshKeyAddedMsg:
+		nm, cmd := m.handleSSHKeyAddedMsg(v)
+		return nm, cmd
+	case sshKeyInstalledMsg:
+		nm, cmd := m.handleSSHKeyInstalledMsg(v)
+		return nm, cmd
+	case knownHostsLoadedMsg:
+		nm, cmd := m.handleKnownHostsLoadedMsg(v)
+		return nm, cmd
+	case knownHostSeededMsg:
+		nm, cmd := m.handleKnownHostSeededMsg(v)
+		return nm, cmd
+	case playbackFinishedMsg:
+		nm, cmd := m.handlePlaybackFinishedMsg(v)
+		return nm, cmd
 	case tea.KeyMsg:
 		if nm, cmd, handled := m.handleKeyMsg(v); handled {
 			return nm, cmd
@@ -200,6 +367,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 //   - host form (if open)
 //   - host details (if open)
 //   - preflight status (if active)
+//   - recordings browser (if open)
+//   - run command form/results (if open)
+//   - bulk action form (if open)
+//   - file transfer browser and transfer queue (if open)
+//   - ssh keys and known_hosts views (if open)
 //   - main menu list with status and search/prompt input
 //
 // It returns the complete string to be displayed.
@@ -220,6 +392,24 @@ func (m model) View() string {
 		return m.viewConfirm()
 	case modePreflight:
 		return m.viewPreflight()
+	case modeRecordings:
+		return m.viewRecordings()
+	case modeRun:
+		return m.viewRun()
+	case modeBulk:
+		return m.viewBulk()
+	case modeTransfer:
+		return m.viewTransfer()
+	case modeTransferQueue:
+		return m.viewTransferQueue()
+	case modeSSHKeys:
+		return m.viewSSHKeys()
+	case modeKnownHosts:
+		return m.viewKnownHosts()
+	case modeSettings:
+		return m.viewSettings()
+	case modeImport:
+		return m.viewImport()
 	default:
 		return m.viewMenu()
 	}
@@ -67,6 +67,7 @@ func (m model) handleMenuReloadedMsg(msg menuReloadedMsg) (model, tea.Cmd) {
 	m.path = []*menuItem{msg.root}
 	m.query.SetValue("")
 	m.setCurrentMenu(msg.root.children)
+	m.pruneMarks()
 	m.relayout()
 	if msg.err != nil {
 		return m, m.setStatusError("Config: "+msg.err.Error(), statusTTL)
@@ -88,18 +89,22 @@ func (m model) handleStatusClearMsg(msg statusClearMsg) (model, tea.Cmd) {
 
 // handlePreflightTickMsg handles preflight tick messages.
 //
-// It updates the remaining time for the preflight operation and
-// returns a command to schedule the next tick if needed.
+// It updates the remaining time for the current attempt (or retry wait) and
+// schedules the next tick for as long as the preflight is running.
 func (m model) handlePreflightTickMsg(msg preflightTickMsg) (model, tea.Cmd) {
 	if m.mode != modePreflight || msg.token != m.ms.preflight.token {
 		return m, nil
 	}
-	remaining := max(int(time.Until(m.ms.preflight.endsAt).Round(time.Second).Seconds()), 0)
-	m.ms.preflight.remaining = remaining
-	if remaining > 0 {
-		return m, preflightTickCmd(msg.token)
+	m.ms.preflight.remaining = max(int(time.Until(m.ms.preflight.endsAt).Round(time.Second).Seconds()), 0)
+	return m, preflightTickCmd(msg.token)
+}
+
+// handlePreflightRetryMsg starts the next preflight attempt once the backoff has elapsed.
+func (m model) handlePreflightRetryMsg(msg preflightRetryMsg) (model, tea.Cmd) {
+	if m.mode != modePreflight || msg.token != m.ms.preflight.token || msg.attempt != m.ms.preflight.attempt {
+		return m, nil
 	}
-	return m, nil
+	return m, m.startPreflightAttempt()
 }
 
 // handleSpinnerTickMsg handles preflight spinner tick messages.
@@ -119,50 +124,115 @@ func (m model) handleSpinnerTickMsg(msg spinner.TickMsg) (model, tea.Cmd) {
 // It processes the result of the preflight check and either starts
 // the connection or shows an error status.
 func (m model) handlePreflightResultMsg(msg preflightResultMsg) (model, tea.Cmd) {
-	if m.mode != modePreflight || msg.token != m.ms.preflight.token {
+	if m.mode != modePreflight || msg.token != m.ms.preflight.token || msg.attempt != m.ms.preflight.attempt {
 		return m, nil
 	}
 
+	// flaky link: wait, then try again (the tick keeps counting down the wait)
+	pf := &m.ms.preflight
+	if connect.IsRetryablePreflightError(msg.err) && pf.attempt < pf.policy.Attempts() {
+		delay := pf.policy.Delay(pf.attempt)
+		pf.attempt++
+		pf.retrying = true
+		pf.remaining = int(delay.Round(time.Second).Seconds())
+		pf.endsAt = time.Now().Add(delay)
+		return m, preflightRetryCmd(pf.token, pf.attempt, delay)
+	}
+	attempts := pf.attempt
+
 	protocol := m.ms.preflight.protocol
 	hostPort := m.ms.preflight.hostPort
+	alias := m.ms.preflight.alias
 	display := m.ms.preflight.display
 	windowTitle := m.ms.preflight.windowTitle
 	cmd := m.ms.preflight.cmd
 	tail := m.ms.preflight.tail
+	capture := m.ms.preflight.capture
+	knownHost := m.ms.preflight.knownHost
+	argv := m.ms.preflight.argv
+	post := m.ms.preflight.post
 	m.clearPreflightState()
 
 	if msg.err != nil {
-		statusCmd := m.setStatusError(fmt.Sprintf("%s %s failed: \n%v", string(protocol), hostPort, msg.err), statusTTL)
-		return m, statusCmd
+		tries := ""
+		if attempts > 1 {
+			tries = fmt.Sprintf(" after %d attempts", attempts)
+		}
+		capture.Close()
+		statusCmd := m.setStatusError(fmt.Sprintf("%s %s failed%s: \n%v", string(protocol), hostPort, tries, msg.err), statusTTL)
+		return m, tea.Batch(statusCmd, m.owedPostHooksCmd(post, msg.err))
+	}
+
+	// refuse to hand over to ssh if the host key changed; otherwise show what was found
+	if msg.ssh != nil {
+		if f := msg.ssh.Problem(knownHost); f != nil {
+			capture.Close()
+			statusCmd := m.setStatusError(failureStatus(protocol, display, f), 0)
+			return m, tea.Batch(statusCmd, m.owedPostHooksCmd(post, f))
+		}
+		cmd = &noteCommand{Command: cmd, note: "btms: " + msg.ssh.Note()}
 	}
 
+	// hand the session to the launcher if there is one; the menu stays up
+	m.preHooksRan = false // owed hooks now run when the session ends
+	if argv != nil {
+		return m, launchSessionCmd(m.launcher, windowTitle, argv, protocol, display)
+	}
 	m.mode = modeExecuting
-	return m, launchExecCmd(windowTitle, cmd, protocol, display, tail)
+	m.pauseMonitor() // no probes while the session has the terminal
+	return m, launchExecCmd(windowTitle, cmd, protocol, alias, display, tail, capture, post)
+}
+
+// handleSessionLaunchedMsg reports where a launched session was opened.
+func (m model) handleSessionLaunchedMsg(msg sessionLaunchedMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		return m, m.setStatusError(fmt.Sprintf("%s to %s: opening %s failed: %v", string(msg.protocol), msg.target, msg.launcher, msg.err), 0)
+	}
+	return m, m.setStatusSuccess(fmt.Sprintf("%s to %s opened in a new %s.", string(msg.protocol), msg.target, msg.launcher), statusTTL)
 }
 
 // handleConnectFinishedMsg handles connection finished messages.
 //
-// It resets the UI to menu mode and sets an appropriate status message
-// based on whether the connection succeeded or failed.
+// It resets the UI to menu mode, sets an appropriate status message
+// based on whether the connection succeeded or failed, and starts the
+// post-connect hooks.
 func (m model) handleConnectFinishedMsg(msg connectFinishedMsg) (model, tea.Cmd) {
 	m.mode = modeMenu
 	titleCmd := tea.SetWindowTitle("MENU")
 	output := strings.TrimSpace(msg.output)
+	postCmd := runPostHooksCmd(msg.post, msg.err)
+
+	// note where the session was recorded/logged, and apply retention now that there's a new file
+	recNote := ""
+	var pruneCmd tea.Cmd
+	if msg.recording != "" {
+		recNote = "\n" + RecordingDot + "Recorded to " + msg.recording
+		pruneCmd = pruneRecordingsCmd()
+	}
+	if msg.logPath != "" {
+		recNote += "\nLogged to " + msg.logPath
+	}
+
 	if msg.err != nil {
 		if connect.IsConnectionAborted(msg.err) { // test if switching this is correct (may have to change launchExecCmd instead)
-			statusCmd := m.setStatusError(fmt.Sprintf("%s to %s aborted.", string(msg.protocol), msg.target), statusTTL) // eg. if tail != nil && connect.IsConnectionAborted
-			return m, tea.Batch(titleCmd, statusCmd)
+			statusCmd := m.setStatusError(fmt.Sprintf("%s to %s aborted.%s", string(msg.protocol), msg.target, recNote), statusTTL) // eg. if tail != nil && connect.IsConnectionAborted
+			return m, tea.Batch(titleCmd, statusCmd, pruneCmd, postCmd)
+		}
+		if f := msg.failure; f != nil {
+			statusCmd := m.setStatusError(failureStatus(msg.protocol, msg.target, f)+recNote, 0)
+			m.offerFix(msg.protocol, msg.alias, f)
+			return m, tea.Batch(titleCmd, statusCmd, pruneCmd, postCmd)
 		}
 		if output != "" {
-			statusCmd := m.setStatusError(fmt.Sprintf("%s to %s exited:\n%s (%v)", string(msg.protocol), msg.target, output, msg.err), 0)
-			return m, tea.Batch(titleCmd, statusCmd)
+			statusCmd := m.setStatusError(fmt.Sprintf("%s to %s exited:\n%s (%v)%s", string(msg.protocol), msg.target, output, msg.err, recNote), 0)
+			return m, tea.Batch(titleCmd, statusCmd, pruneCmd, postCmd)
 		}
-		statusCmd := m.setStatusError(fmt.Sprintf("%s to %s exited:\n%v", string(msg.protocol), msg.target, msg.err), 0)
-		return m, tea.Batch(titleCmd, statusCmd)
+		statusCmd := m.setStatusError(fmt.Sprintf("%s to %s exited:\n%v%s", string(msg.protocol), msg.target, msg.err, recNote), 0)
+		return m, tea.Batch(titleCmd, statusCmd, pruneCmd, postCmd)
 	}
 
-	statusCmd := m.setStatusSuccess(fmt.Sprintf("%s to %s ended.", string(msg.protocol), msg.target), statusTTL)
-	return m, tea.Batch(titleCmd, statusCmd)
+	statusCmd := m.setStatusSuccess(fmt.Sprintf("%s to %s ended.%s", string(msg.protocol), msg.target, recNote), statusTTL)
+	return m, tea.Batch(titleCmd, statusCmd, pruneCmd, postCmd)
 }
 
 // handleModalMsg routes messages to the active modal component (if any).
@@ -196,6 +266,56 @@ func (m model) handleModalMsg(msg tea.Msg) (model, tea.Cmd, bool) {
 		}
 		m.relayout()
 		return m, cmd, true
+
+	case modeRun:
+		if m.ms.run == nil || m.ms.run.form == nil {
+			return m, nil, true
+		}
+		mdl, cmd := m.ms.run.form.Update(msg)
+		if f, ok := mdl.(*huh.Form); ok {
+			m.ms.run.form = f
+		}
+		return m, cmd, true
+
+	case modeBulk:
+		if m.ms.bulk == nil || m.ms.bulk.form == nil {
+			return m, nil, true
+		}
+		mdl, cmd := m.ms.bulk.form.Update(msg)
+		if f, ok := mdl.(*huh.Form); ok {
+			m.ms.bulk.form = f
+		}
+		return m, cmd, true
+
+	case modeSSHKeys:
+		if m.ms.sshKeys == nil || m.ms.sshKeys.form == nil {
+			return m, nil, true
+		}
+		mdl, cmd := m.ms.sshKeys.form.Update(msg)
+		if f, ok := mdl.(*huh.Form); ok {
+			m.ms.sshKeys.form = f
+		}
+		return m, cmd, true
+
+	case modeSettings:
+		if m.ms.settings == nil || m.ms.settings.form == nil {
+			return m, nil, true
+		}
+		mdl, cmd := m.ms.settings.form.Update(msg)
+		if f, ok := mdl.(*huh.Form); ok {
+			m.ms.settings.form = f
+		}
+		return m, cmd, true
+
+	case modeImport:
+		if m.ms.imports == nil || m.ms.imports.form == nil {
+			return m, nil, true
+		}
+		mdl, cmd := m.ms.imports.form.Update(msg)
+		if f, ok := mdl.(*huh.Form); ok {
+			m.ms.imports.form = f
+		}
+		return m, cmd, true
 	}
 
 	return m, nil, false
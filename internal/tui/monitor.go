@@ -0,0 +1,210 @@
+package tui
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/monitor"
+	str "bubbletea-ssh-manager/internal/stringutil"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// monitorTickCmd returns a command that sends a monitorTickMsg after d.
+func monitorTickCmd(token int, d time.Duration) tea.Cmd {
+	return tea.Tick(d, func(time.Time) tea.Msg {
+		return monitorTickMsg{token: token}
+	})
+}
+
+// monitorSweepCmd returns a command that checks every target and sends a
+// monitorSweptMsg with the results.
+func monitorSweepCmd(ctx context.Context, token int, targets []monitor.Target, cfg monitor.Config,
+	prev map[string]monitor.Result) tea.Cmd {
+	return func() tea.Msg {
+		return monitorSweptMsg{token: token, results: monitor.Sweep(ctx, targets, cfg, prev)}
+	}
+}
+
+// toggleMonitor starts or stops the background reachability monitor.
+func (m model) toggleMonitor() (model, tea.Cmd) {
+	if m.monitor.enabled {
+		m.stopMonitor()
+		return m, m.setStatusInfo("Reachability monitor off.", statusTTL)
+	}
+	cmd := m.startMonitor()
+	return m, tea.Batch(cmd, m.setStatusInfo(
+		fmt.Sprintf("Reachability monitor on (every %s).", m.monitor.cfg.Interval), statusTTL))
+}
+
+// startMonitor enables the monitor and returns the command for the first sweep.
+func (m *model) startMonitor() tea.Cmd {
+	m.stopMonitor()
+	m.monitor.enabled = true
+	if m.monitor.cfg == (monitor.Config{}) {
+		m.monitor.cfg = monitor.DefaultConfig()
+	}
+	m.delegate.reach = m.monitor.results
+	m.delegate.monitoring = true
+	return monitorTickCmd(m.monitor.token, 0)
+}
+
+// stopMonitor disables the monitor and cancels any sweep in flight.
+//
+// Cached results are kept so turning the monitor back on shows them straight away.
+func (m *model) stopMonitor() {
+	m.pauseMonitor()
+	m.monitor.token++
+	m.monitor.enabled = false
+	m.delegate.monitoring = false
+}
+
+// pauseMonitor cancels the sweep in flight (if any).
+//
+// Ticks that arrive while a session is executing are skipped, so the monitor
+// stays quiet until the menu is back.
+func (m *model) pauseMonitor() {
+	if m.monitor.cancel != nil {
+		m.monitor.cancel()
+		m.monitor.cancel = nil
+	}
+}
+
+// handleMonitorTickMsg starts a sweep, unless one is running or a session is executing.
+func (m model) handleMonitorTickMsg(msg monitorTickMsg) (model, tea.Cmd) {
+	if !m.monitor.enabled || msg.token != m.monitor.token {
+		return m, nil
+	}
+	if m.mode == modeExecuting || m.monitor.cancel != nil {
+		return m, monitorTickCmd(msg.token, m.monitor.cfg.Interval)
+	}
+
+	var ctx context.Context
+	ctx, m.monitor.cancel = context.WithCancel(context.Background())
+	return m, monitorSweepCmd(ctx, msg.token, m.monitorTargets(), m.monitor.cfg, m.monitor.results)
+}
+
+// handleMonitorSweptMsg stores the sweep results and schedules the next sweep.
+func (m model) handleMonitorSweptMsg(msg monitorSweptMsg) (model, tea.Cmd) {
+	if msg.token != m.monitor.token {
+		return m, nil
+	}
+	m.pauseMonitor()
+	m.monitor.results = msg.results
+	m.delegate.reach = msg.results
+	if !m.monitor.enabled {
+		return m, nil
+	}
+	return m, monitorTickCmd(msg.token, m.monitor.cfg.Interval)
+}
+
+// monitorTargets returns what to probe for every host in the menu.
+//
+// Hosts behind a ProxyJump are checked through their first hop, like preflight.
+// Hosts with the ssh preflight strategy get a banner check so a port that
+// answers but isn't SSH shows as degraded. Hosts without a network endpoint
+// (eg. serial, or a custom command without a port) aren't probed.
+func (m model) monitorTargets() []monitor.Target {
+	hosts, _ := getHostItemsWithHints(m.root)
+	lookups := jumpLookups(hosts)
+	out := make([]monitor.Target, 0, len(hosts))
+	for _, h := range hosts {
+		if h.spec.Alias == "" {
+			continue
+		}
+		hostPort := ""
+		if t, err := targetWith(h, lookups); err == nil {
+			if _, ok := t.FirstHop(); ok {
+				hostPort = connect.GenerateHostPort(t)
+			}
+		}
+		info, _ := config.LookupProtocol(h.protocol)
+		if info.Preflight == config.PreflightNone {
+			continue
+		}
+		if hostPort == "" {
+			port, err := str.NormalizePort(h.spec.Port, h.protocol)
+			if err != nil || port == "" {
+				continue
+			}
+			hostPort = net.JoinHostPort(cmp.Or(h.spec.HostName, h.spec.Alias), port)
+		}
+		out = append(out, monitor.Target{
+			Key:      hostKey(h),
+			HostPort: hostPort,
+			Banner:   info.Preflight == config.PreflightSSH,
+		})
+	}
+	return out
+}
+
+// monitorColor returns the theme color for a monitor state.
+func (t Theme) monitorColor(s monitor.State) lipgloss.Color {
+	switch s {
+	case monitor.StateUp:
+		return t.MonitorUp
+	case monitor.StateDegraded:
+		return t.MonitorDegraded
+	case monitor.StateDown:
+		return t.MonitorDown
+	default:
+		return t.MonitorUnknown
+	}
+}
+
+// monitorSummary returns the short reachability text shown in a host's description.
+func monitorSummary(r monitor.Result, ok bool) string {
+	switch {
+	case !ok || r.State == monitor.StateUnknown:
+		return "checking…"
+	case r.State == monitor.StateDown && !r.LastUp.IsZero():
+		return "down, up " + formatAgo(time.Since(r.LastUp)) + " ago"
+	case r.State == monitor.StateDown:
+		return "down"
+	case r.State == monitor.StateDegraded && r.Err != nil:
+		return r.Err.Error()
+	default:
+		return formatRTT(r.RTT)
+	}
+}
+
+// formatRTT formats a round-trip time compactly (eg. "23ms", "1.2s").
+func formatRTT(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", max(d.Milliseconds(), 1))
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
+
+// formatAgo formats an elapsed time in its largest whole unit (eg. "5m").
+func formatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+// groupReachability counts the hosts in a group that are up (or degraded) and down.
+func groupReachability(g *menuItem, reach map[string]monitor.Result) (up, down int) {
+	for _, ch := range hostsUnder(g.children) {
+		switch reach[hostKey(ch)].State {
+		case monitor.StateUp, monitor.StateDegraded:
+			up++
+		case monitor.StateDown:
+			down++
+		}
+	}
+	return up, down
+}
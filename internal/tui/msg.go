@@ -1,6 +1,17 @@
 package tui
 
-import "bubbletea-ssh-manager/internal/config"
+import (
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/fanout"
+	"bubbletea-ssh-manager/internal/hooks"
+	"bubbletea-ssh-manager/internal/importer"
+	"bubbletea-ssh-manager/internal/monitor"
+	"bubbletea-ssh-manager/internal/record"
+	"bubbletea-ssh-manager/internal/settings"
+	"bubbletea-ssh-manager/internal/sshkeys"
+	"bubbletea-ssh-manager/internal/transfer"
+)
 
 const (
 	modeAdd formMode = iota
@@ -12,13 +23,19 @@ type formMode int // add vs edit mode for host entry form
 type formCanceledMsg struct{}
 
 type formSubmittedMsg struct {
-	mode     formMode          // add vs edit mode for host entry form
-	protocol config.Protocol   // protocol being edited/added
-	oldAlias string            // for edit/rename
-	group    string            // group name (display form)
-	nickname string            // host nickname (display form)
-	spec     config.Spec       // shared host fields (alias/hostname/port/user)
-	opts     config.SSHOptions // SSH options (only for SSH hosts)
+	mode      formMode              // add vs edit mode for host entry form
+	protocol  config.Protocol       // protocol being edited/added
+	oldAlias  string                // for edit/rename
+	inventory string                // inventory the host is in, or is added to
+	group     string                // group name (display form)
+	nickname  string                // host nickname (display form)
+	spec      config.Spec           // shared host fields (alias/hostname/port/user)
+	opts      config.SSHOptions     // SSH options (only for SSH hosts)
+	telnet    config.TelnetOptions  // telnet options (only for telnet hosts)
+	command   config.CommandOptions // command template options (mosh, raw, serial, custom)
+	serial    config.SerialOptions  // serial line options (only for serial hosts)
+	app       config.AppOptions     // app-only settings (recording, etc.)
+	password  hostPassword          // new password to store in the vault (secret name set on submit)
 }
 
 type formSaveResultMsg struct {
@@ -26,6 +43,8 @@ type formSaveResultMsg struct {
 	protocol   config.Protocol // protocol that was saved
 	spec       config.Spec     // saved host spec
 	configPath string          // config file written to (best-effort; set on success)
+	secret     string          // password secret stored in the vault ("" if none)
+	secretErr  error           // error storing the password (the host itself was saved)
 }
 
 type menuReloadedMsg struct {
@@ -38,10 +57,23 @@ type statusClearMsg struct {
 }
 
 type connectFinishedMsg struct {
+	protocol  config.Protocol       // protocol used
+	alias     string                // host alias that was connected to
+	target    string                // display target (eg. host:port)
+	err       error                 // error from connection attempt
+	output    string                // output from ssh/telnet command
+	failure   *connect.ConnectError // classified failure (nil if unrecognized)
+	recording string                // path to the session recording (empty if not recorded)
+	logPath   string                // path to the session log (empty if not logged)
+	post      postHooks             // post-connect hooks to run now that the session ended
+}
+
+// sessionLaunchedMsg is sent when a session has been handed to a launcher.
+type sessionLaunchedMsg struct {
 	protocol config.Protocol // protocol used
 	target   string          // display target (eg. host:port)
-	err      error           // error from connection attempt
-	output   string          // output from ssh/telnet command
+	launcher string          // where the session was opened (eg. "tmux window")
+	err      error           // error starting the session
 }
 
 type preflightTickMsg struct {
@@ -51,8 +83,27 @@ type preflightTickMsg struct {
 
 type preflightResultMsg struct {
 	// should match model's preflightToken
-	token int   // token to identify which preflight to complete
-	err   error // error from preflight check
+	token   int                   // token to identify which preflight to complete
+	attempt int                   // attempt this result belongs to
+	err     error                 // error from preflight check
+	ssh     *connect.SSHPreflight // banner/host key check (ssh only)
+}
+
+// monitorTickMsg is sent when it's time for the next reachability sweep.
+type monitorTickMsg struct {
+	token int // should match the monitor token
+}
+
+// monitorSweptMsg is sent when a reachability sweep finishes.
+type monitorSweptMsg struct {
+	token   int                       // should match the monitor token
+	results map[string]monitor.Result // results by hostKey
+}
+
+// preflightRetryMsg is sent when the backoff before a preflight retry has elapsed.
+type preflightRetryMsg struct {
+	token   int // token to identify which preflight to retry
+	attempt int // attempt to start
 }
 
 // confirmResultMsg is sent when a confirmation dialog completes (confirmed or canceled).
@@ -60,8 +111,178 @@ type confirmResultMsg struct {
 	confirmed bool // true if user confirmed, false if canceled
 }
 
+type recordingsLoadedMsg struct {
+	items []record.Info // recordings, newest first
+	err   error         // error listing recordings
+}
+
+type playbackFinishedMsg struct {
+	err error // error from the player
+}
+
 type removeHostResultMsg struct {
 	protocol config.Protocol // protocol that was removed
 	alias    string          // alias of host that was removed
 	err      error           // error during removal
 }
+
+type fixAppliedMsg struct {
+	alias string              // host alias that was updated
+	fixes []connect.OptionFix // option changes that were applied
+	err   error               // error during save IO operation
+}
+
+type algorithmsProbedMsg struct {
+	alias  string              // host alias that was probed
+	result connect.ProbeResult // probe result
+	err    error               // error during probe
+}
+
+// runFormDoneMsg is sent when the run command form is submitted or canceled.
+type runFormDoneMsg struct {
+	run *runState // run the form belongs to
+	ok  bool      // true if submitted
+}
+
+// runUpdatesMsg carries progress from a running command.
+type runUpdatesMsg struct {
+	run     *runState       // run the updates belong to
+	updates []fanout.Update // progress since the last message
+	done    bool            // every host has finished
+}
+
+// runSavedMsg is sent when a run report has been written.
+type runSavedMsg struct {
+	path string // report path
+	err  error  // error writing the report
+}
+
+// bulkFormDoneMsg is sent when the bulk action form is submitted or canceled.
+type bulkFormDoneMsg struct {
+	bulk *bulkState // form the message belongs to
+	ok   bool       // true if submitted
+}
+
+// settingsFormDoneMsg is sent when the settings form is submitted or canceled.
+type settingsFormDoneMsg struct {
+	settings *settingsState // form the message belongs to
+	ok       bool           // true if submitted
+}
+
+// importFormDoneMsg is sent when the import form is submitted or canceled.
+type importFormDoneMsg struct {
+	imports *importState // form the message belongs to
+	ok      bool         // true if submitted
+}
+
+// importPlannedMsg is sent when an export has been read and its import planned.
+type importPlannedMsg struct {
+	path   string          // export as entered in the form
+	format importer.Format // format it was read as
+	plan   *importer.Plan  // what importing it does
+	err    error           // error reading the export
+}
+
+// importDoneMsg is sent when an import plan has been written.
+type importDoneMsg struct {
+	plan *importer.Plan // plan that was applied
+	err  error          // error writing it; nothing was written
+}
+
+// settingsSavedMsg is sent when the settings file has been written.
+type settingsSavedMsg struct {
+	settings     settings.Settings // settings that were saved
+	themeChanged bool              // ui.theme changed (applies on the next start)
+	err          error             // error writing the file
+}
+
+// bulkDoneMsg is sent when a bulk action has been applied to the marked hosts.
+type bulkDoneMsg struct {
+	action  bulkAction // action that was applied
+	done    int        // hosts the action succeeded on
+	skipped int        // hosts the action doesn't apply to (eg. telnet hosts for tabs)
+	failed  []string   // "alias: error" for each host that failed
+	paths   []string   // files written (export only)
+
+	renamed map[string]string // old hostKey -> new hostKey (move only), so marks follow the hosts
+}
+
+// remoteListedMsg is sent when a remote directory has been listed for the file browser.
+type remoteListedMsg struct {
+	transfer  *transferState   // browser the listing belongs to
+	seq       int              // should match the remote pane's seq
+	requested string           // directory that was asked for ("" for the login directory)
+	dir       string           // directory that was listed
+	entries   []transfer.Entry // its entries
+	sel       string           // entry to select once shown
+	err       error            // error listing the directory
+}
+
+// preHooksDoneMsg is sent when the pre-connect hooks for a host have run.
+type preHooksDoneMsg struct {
+	token int          // should match the preflight token
+	item  *menuItem    // host being connected to
+	post  []hooks.Hook // post-connect hooks for the session
+	err   error        // first hook failure (a *hooks.Error)
+}
+
+// postHooksDoneMsg is sent when the post-connect hooks for a session have run.
+type postHooksDoneMsg struct {
+	alias string // host alias the session was for
+	err   error  // first hook failure (a *hooks.Error)
+}
+
+// transferTickMsg is sent periodically while transfers are queued or running.
+type transferTickMsg struct{}
+
+// sshKeysLoadedMsg is sent when the keys in ~/.ssh and the agent have been listed.
+type sshKeysLoadedMsg struct {
+	keys     []sshkeys.Key // keys from ~/.ssh, then agent-only keys
+	err      error         // error listing ~/.ssh
+	agentErr error         // error listing the agent (eg. no agent running)
+}
+
+// sshKeysFormDoneMsg is sent when a keys view form is submitted or canceled.
+type sshKeysFormDoneMsg struct {
+	keys *sshKeysState // view the form belongs to
+	ok   bool          // true if submitted
+}
+
+// sshKeyGeneratedMsg is sent when a new key pair has been written.
+type sshKeyGeneratedMsg struct {
+	key sshkeys.Key // new key
+	err error       // error generating or writing it
+}
+
+// sshKeyAddedMsg is sent when ssh-add exits.
+type sshKeyAddedMsg struct {
+	name string // key file name
+	err  error  // error from ssh-add
+}
+
+// sshKeyInstalledMsg is sent when a key has been installed on the chosen hosts.
+type sshKeyInstalledMsg struct {
+	name    string             // key file name
+	results []keyInstallResult // outcome per host
+}
+
+// knownHostsLoadedMsg is sent when the known_hosts files have been read.
+type knownHostsLoadedMsg struct {
+	known   *knownHostsState  // view the load belongs to
+	seq     int               // should match the view's seq
+	files   []string          // known_hosts files ssh uses
+	hash    bool              // ssh hashes the host names it adds
+	entries []knownHostsEntry // entries with the menu hosts they match
+	err     error             // error reading a file
+}
+
+// knownHostSeededMsg is sent when a host key has been fetched to add to known_hosts.
+type knownHostSeededMsg struct {
+	known *knownHostsState     // view the fetch belongs to
+	host  string               // host name the key is filed under
+	port  string               // port the key is filed under
+	addr  string               // host:port the key was fetched from
+	res   connect.SSHPreflight // fetched key and its known_hosts check
+	added bool                 // the key was added
+	err   error                // error fetching or adding the key
+}
@@ -0,0 +1,153 @@
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	probeTimeout     = 10 * time.Second // dial + handshake timeout for algorithm probes
+	probeListWrapW   = 64               // wrap width for algorithm lists in host details
+	probeSectionName = "ALGORITHM PROBE"
+)
+
+type probeState struct {
+	alias   string              // host alias being probed
+	loading bool                // true until algorithmsProbedMsg arrives
+	result  connect.ProbeResult // probe result (valid when !loading and err == nil)
+	err     error               // probe error
+}
+
+// startAlgorithmProbe probes the selected ssh host for the algorithms it offers.
+//
+// The result is shown in the host details; if the host needs legacy
+// algorithms enabled, a confirm dialog offers to add them.
+func (m model) startAlgorithmProbe() (model, tea.Cmd) {
+	it, _ := m.lst.SelectedItem().(*menuItem)
+	if it == nil || it.kind != itemHost {
+		return m, nil
+	}
+
+	alias := it.spec.Alias
+	switch {
+	case it.protocol != config.ProtocolSSH:
+		m.ms.probe = &probeState{alias: alias, err: fmt.Errorf("only ssh hosts can be probed")}
+		return m, nil
+	case it.options.ProxyJump != "":
+		m.ms.probe = &probeState{alias: alias,
+			err: fmt.Errorf("hosts behind ProxyJump can't be probed directly; probe the jump host instead")}
+		return m, nil
+	}
+
+	m.ms.probe = &probeState{alias: alias, loading: true}
+	host := cmp.Or(it.spec.HostName, alias)
+	port := cmp.Or(it.spec.Port, "22")
+	return m, probeAlgorithmsCmd(alias, net.JoinHostPort(host, port))
+}
+
+// probeAlgorithmsCmd runs the algorithm probe in the background.
+func probeAlgorithmsCmd(alias, addr string) tea.Cmd {
+	return func() tea.Msg {
+		res, err := connect.Probe(alias, addr, probeTimeout)
+		return algorithmsProbedMsg{alias: alias, result: res, err: err}
+	}
+}
+
+// handleAlgorithmsProbedMsg stores the probe result and, if algorithms need
+// adding, asks whether to write them to the host's config.
+func (m model) handleAlgorithmsProbedMsg(msg algorithmsProbedMsg) (model, tea.Cmd) {
+	if m.ms.probe == nil || m.ms.probe.alias != msg.alias {
+		return m, nil
+	}
+	m.ms.probe.loading = false
+	m.ms.probe.result = msg.result
+	m.ms.probe.err = msg.err
+	m.relayout()
+
+	adds := msg.result.Additions
+	if msg.err != nil || len(adds) == 0 || m.mode != modeHostDetails {
+		return m, nil
+	}
+
+	inv, err := m.fixInventory(msg.alias, "", adds)
+	if err != nil {
+		m.ms.probe.err = err
+		return m, nil
+	}
+
+	lines := make([]string, 0, len(adds)+1)
+	for _, f := range adds {
+		lines = append(lines, f.String())
+	}
+	if len(msg.result.Missing) > 0 {
+		lines = append(lines, "(still no match for "+strings.Join(msg.result.Missing, ", ")+")")
+	}
+
+	m.mode = modeConfirm
+	title := fmt.Sprintf("Add %d algorithm(s) to %s?", len(adds), msg.alias)
+	description := strings.Join(lines, "\n")
+	form := buildConfirmForm(title, description, m.theme)
+	m.ms.confirm = &confirmState{
+		form:        form,
+		title:       title,
+		description: description,
+		onConfirm:   applyFixCmd(inv, msg.alias, adds),
+	}
+	m.relayout()
+	return m, form.Init()
+}
+
+// buildProbeSection renders the algorithm probe result for the host details.
+//
+// It returns an empty string if there is no probe for it.
+func (m model) buildProbeSection(it *menuItem, s detailsStyles) string {
+	p := m.ms.probe
+	if p == nil || p.alias != it.spec.Alias {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString(s.header.PaddingBottom(1).Render(probeSectionName))
+	b.WriteString("\n")
+
+	switch {
+	case p.loading:
+		b.WriteString(s.optionsValue.Render("Probing "+it.spec.Alias+"…") + "\n")
+		return b.String()
+	case p.err != nil:
+		b.WriteString(s.optionsValue.Render(ErrorX+p.err.Error()) + "\n")
+		return b.String()
+	}
+
+	res := p.result
+	wrap := s.value.Width(probeListWrapW)
+	fmt.Fprintf(&b, "%s: %s\n", s.optionsLabel.Render("Server"), s.value.Render(res.Banner))
+	for _, opt := range connect.AlgorithmOptions() {
+		fmt.Fprintf(&b, "%s\n", s.optionsLabel.Render(opt))
+		fmt.Fprintf(&b, "%s %s\n", s.label.Render("server:"), wrap.Render(strings.Join(res.Server.List(opt), ", ")))
+		fmt.Fprintf(&b, "%s %s\n", s.label.Render("client:"), wrap.Render(strings.Join(res.Enabled.List(opt), ", ")))
+		for _, f := range res.Additions {
+			if f.Option == opt {
+				fmt.Fprintf(&b, "%s %s\n", s.label.Render("→"), lipgloss.NewStyle().Foreground(m.theme.KeyCursor).Render("+"+f.Algorithm))
+			}
+		}
+	}
+
+	switch {
+	case len(res.Missing) > 0:
+		b.WriteString(s.optionsValue.Render(ErrorX+"No usable algorithm for "+strings.Join(res.Missing, ", ")) + "\n")
+	case len(res.Additions) == 0:
+		b.WriteString(s.optionsValue.Render("Client and server already share algorithms"+SuccessCheck) + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,222 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/record"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type recordingsState struct {
+	lst     list.Model // recordings list
+	loading bool       // true until the first recordingsLoadedMsg arrives
+}
+
+// recordingItem is a list item for a single recording.
+type recordingItem struct {
+	info record.Info
+}
+
+// Title returns the host alias the recording belongs to.
+func (r recordingItem) Title() string {
+	return r.info.Alias
+}
+
+// Description returns the start time, duration and size of the recording.
+func (r recordingItem) Description() string {
+	return fmt.Sprintf("%s • %s • %s",
+		r.info.Started.Format("2006-01-02 15:04:05"),
+		record.FormatDuration(r.info.Duration),
+		formatBytes(r.info.Size),
+	)
+}
+
+// FilterValue returns the string used for filtering (unused; filtering is disabled).
+func (r recordingItem) FilterValue() string {
+	return r.info.Alias
+}
+
+// formatBytes formats a file size for display.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+// pruneRecordingsCmd applies the recording retention policy in the background.
+func pruneRecordingsCmd() tea.Cmd {
+	return func() tea.Msg {
+		_, _ = record.Prune(record.DefaultRetention)
+		return nil
+	}
+}
+
+// loadRecordingsCmd prunes old recordings and lists the rest.
+func loadRecordingsCmd() tea.Cmd {
+	return func() tea.Msg {
+		_, pruneErr := record.Prune(record.DefaultRetention)
+		items, err := record.List()
+		if err == nil {
+			err = pruneErr
+		}
+		return recordingsLoadedMsg{items: items, err: err}
+	}
+}
+
+// toggleRecordNext arms (or disarms) recording for the next session.
+func (m model) toggleRecordNext() (model, tea.Cmd) {
+	m.recordNext = !m.recordNext
+	if m.recordNext {
+		return m, m.setStatusInfo(RecordingDot+"Next session will be recorded (ctrl+r to cancel).", 0)
+	}
+	return m, m.setStatusInfo("Recording for next session canceled.", statusTTL)
+}
+
+// openRecordings opens the recordings browser and starts loading recordings.
+func (m model) openRecordings() (model, tea.Cmd) {
+	d := list.NewDefaultDelegate()
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.
+		Foreground(m.theme.SelectedItemTitle).
+		BorderForeground(m.theme.SelectedItemBorder)
+	d.Styles.SelectedDesc = d.Styles.SelectedDesc.BorderForeground(m.theme.SelectedItemBorder)
+
+	lst := list.New(nil, d, 0, 0)
+	lst.Title = "RECORDINGS"
+	lst.Styles.TitleBar = lst.Styles.TitleBar.Padding(1, 0, 1, 1)
+	lst.Styles.Title = lst.Styles.Title.Padding(0, 2)
+	lst.SetShowStatusBar(false)
+	lst.SetFilteringEnabled(false)
+	lst.SetShowHelp(false)
+	lst.InfiniteScrolling = true
+
+	m.mode = modeRecordings
+	m.ms.recordings = &recordingsState{lst: lst, loading: true}
+	m.setStatusInfo("", 0)
+	m.relayout()
+	return m, loadRecordingsCmd()
+}
+
+// closeRecordings closes the recordings browser and returns to the menu.
+func (m model) closeRecordings() (model, tea.Cmd) {
+	m.mode = modeMenu
+	m.ms.recordings = nil
+	m.relayout()
+	return m, nil
+}
+
+// handleRecordingsLoadedMsg fills the recordings browser.
+func (m model) handleRecordingsLoadedMsg(msg recordingsLoadedMsg) (model, tea.Cmd) {
+	if m.mode != modeRecordings || m.ms.recordings == nil {
+		return m, nil
+	}
+	items := make([]list.Item, 0, len(msg.items))
+	for _, info := range msg.items {
+		items = append(items, recordingItem{info: info})
+	}
+	m.ms.recordings.loading = false
+	m.ms.recordings.lst.SetItems(items)
+	m.ms.recordings.lst.Select(0)
+	m.relayout()
+	if msg.err != nil {
+		return m, m.setStatusError("Recordings: "+msg.err.Error(), statusTTL)
+	}
+	return m, nil
+}
+
+// handleRecordingsKeyMsg handles keys in the recordings browser.
+//
+// Enter plays the selected recording, left closes the browser, and
+// up/down move through the list.
+func (m model) handleRecordingsKeyMsg(msg tea.KeyMsg) (model, tea.Cmd) {
+	if m.ms.recordings == nil {
+		return m.closeRecordings()
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.CloseDetails), key.Matches(msg, m.keys.CloseForm):
+		return m.closeRecordings()
+
+	case key.Matches(msg, m.keys.Play):
+		it, ok := m.ms.recordings.lst.SelectedItem().(recordingItem)
+		if !ok {
+			return m, nil
+		}
+		player := record.NewPlayer(it.info.Path)
+		return m, tea.Exec(player, func(err error) tea.Msg {
+			return playbackFinishedMsg{err: err}
+		})
+
+	case msg.String() == "ctrl+c", key.Matches(msg, m.keys.Quit):
+		m.quitting = true
+		return m, tea.Quit
+	}
+
+	var cmd tea.Cmd
+	m.ms.recordings.lst, cmd = m.ms.recordings.lst.Update(msg)
+	return m, cmd
+}
+
+// handlePlaybackFinishedMsg restores the title after playback and reports errors.
+func (m model) handlePlaybackFinishedMsg(msg playbackFinishedMsg) (model, tea.Cmd) {
+	titleCmd := tea.SetWindowTitle("MENU")
+	if msg.err != nil {
+		return m, tea.Batch(titleCmd, m.setStatusError("Playback failed: "+msg.err.Error(), statusTTL))
+	}
+	return m, titleCmd
+}
+
+// recordingsHelpKeys returns the help keys shown in the recordings browser.
+func (m model) recordingsHelpKeys() []key.Binding {
+	return []key.Binding{m.keys.CloseDetails, m.lst.KeyMap.CursorUp, m.lst.KeyMap.CursorDown, m.keys.Play}
+}
+
+// viewRecordings renders the recordings browser with its help line and status.
+func (m model) viewRecordings() string {
+	if m.ms.recordings == nil {
+		return ""
+	}
+	lg := lipgloss.NewStyle()
+
+	body := m.ms.recordings.lst.View()
+	switch {
+	case m.ms.recordings.loading:
+		body += "\n" + lg.PaddingLeft(footerPadLeft+2).Foreground(m.theme.PreflightText).Render("Loading recordings…")
+	case len(m.ms.recordings.lst.Items()) == 0:
+		body += "\n" + lg.PaddingLeft(footerPadLeft+2).Foreground(m.theme.PreflightText).
+			Render("No recordings yet. Turn on \"Record Sessions\" for a host, or press ctrl+r before connecting.")
+	}
+
+	h := m.lst.Help
+	h.Width = m.width
+	lines := []string{body}
+	if m.status != "" {
+		statusStyle := lg.PaddingLeft(footerPadLeft).PaddingTop(1).Foreground(m.statusColor())
+		lines = append(lines, statusStyle.Render(m.status))
+	}
+	lines = append(lines, lg.PaddingLeft(footerPadLeft).PaddingTop(1).Render(h.ShortHelpView(m.recordingsHelpKeys())))
+	return strings.Join(lines, "\n")
+}
+
+// resizeRecordings sizes the recordings list to the window, leaving room for help and status.
+func (m *model) resizeRecordings() {
+	if m.ms.recordings == nil {
+		return
+	}
+	footer := 2
+	if m.status != "" {
+		footer += 1 + lipgloss.Height(m.status)
+	}
+	m.ms.recordings.lst.SetSize(m.width, max(0, m.height-footer-1))
+}
@@ -0,0 +1,604 @@
+package tui
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/fanout"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	runMaxUpdates   = 256 // updates folded into one runUpdatesMsg
+	runHostsMinW    = 20  // minimum width of the host column
+	runHeaderLines  = 3   // title + padding
+	runFooterLines  = 4   // progress + help + padding
+	runPaneGap      = 2   // space between the host column and the output pane
+	runHostColExtra = 14  // icon, padding and duration next to the alias
+)
+
+type runState struct {
+	form   *huh.Form  // command form (nil once the run has started)
+	values *runValues // values bound to the form
+
+	title   string   // what the hosts were picked from (group name or alias)
+	aliases []string // ssh hosts to run on, in display order
+	skipped int      // non-ssh hosts that were left out
+
+	command string
+	started time.Time
+	results []fanout.Result // one per alias, same order
+	index   map[string]int  // alias -> results index
+
+	running bool                 // run in progress
+	cancel  context.CancelFunc   // cancels the run
+	updates <-chan fanout.Update // progress from fanout.Run
+
+	selected int            // selected host
+	grouped  bool           // output pane shows grouped outputs instead of one host
+	output   viewport.Model // output pane
+}
+
+// runValues holds the run form's input values.
+type runValues struct {
+	command     string
+	concurrency string
+	timeout     string
+}
+
+// runTargets returns the ssh hosts a run would use: the selected group's
+// hosts, or the selected host. Hosts of other protocols are counted as skipped.
+func (m model) runTargets() (title string, aliases []string, skipped int) {
+	it, _ := m.lst.SelectedItem().(*menuItem)
+	if it == nil {
+		return "", nil, 0
+	}
+	hosts := []*menuItem{it}
+	if it.kind == itemGroup {
+		hosts = hostsUnder(it.children)
+	}
+	for _, h := range hosts {
+		if h == nil || h.kind != itemHost {
+			continue
+		}
+		if h.protocol != config.ProtocolSSH {
+			skipped++
+			continue
+		}
+		aliases = append(aliases, h.spec.Alias)
+	}
+	return it.name, aliases, skipped
+}
+
+// openRunForm asks for the command to run on the marked hosts, or the
+// selected group (or host) if nothing is marked.
+func (m model) openRunForm() (model, tea.Cmd) {
+	if len(m.marked) > 0 {
+		return m.openBulkRunForm(m.markedHosts())
+	}
+	title, aliases, skipped := m.runTargets()
+	if len(aliases) == 0 {
+		return m, m.setStatusError("Select a group or host with ssh hosts to run a command on.", statusTTL)
+	}
+	return m.openRunFormFor(title, aliases, skipped)
+}
+
+// openRunFormFor opens the run form for the given ssh host aliases.
+func (m model) openRunFormFor(title string, aliases []string, skipped int) (model, tea.Cmd) {
+	v := &runValues{
+		concurrency: strconv.Itoa(fanout.DefaultConcurrency),
+		timeout:     fanout.DefaultTimeout.String(),
+	}
+	run := &runState{title: title, aliases: aliases, skipped: skipped, values: v}
+	run.form = buildRunForm(run, m.theme)
+
+	m.mode = modeRun
+	m.ms.run = run
+	m.setStatusInfo("", 0)
+	m.relayout()
+	return m, run.form.Init()
+}
+
+// buildRunForm builds the form asking for the command, concurrency and timeout.
+func buildRunForm(run *runState, appTheme Theme) *huh.Form {
+	v := run.values
+	desc := fmt.Sprintf("Runs with ssh -T on %d host(s) in %s; hosts that ask for a password fail.",
+		len(run.aliases), run.title)
+	if run.skipped > 0 {
+		desc += fmt.Sprintf("\n%d non-ssh host(s) skipped.", run.skipped)
+	}
+
+	f := huh.NewForm(huh.NewGroup(
+		huh.NewNote().Title("Run Command").Description(desc),
+		huh.NewInput().
+			Key("command").
+			Title("Command").
+			Placeholder("uptime").
+			Value(&v.command).
+			Validate(func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return fmt.Errorf("command is required")
+				}
+				return nil
+			}),
+		huh.NewInput().
+			Key("concurrency").
+			Title("Concurrency").
+			Value(&v.concurrency).
+			Validate(func(s string) error {
+				_, err := parseRunConcurrency(s)
+				return err
+			}),
+		huh.NewInput().
+			Key("timeout").
+			Title("Timeout per host").
+			Value(&v.timeout).
+			Validate(func(s string) error {
+				_, err := parseRunTimeout(s)
+				return err
+			}),
+	)).
+		WithShowHelp(false).
+		WithKeyMap(runFormKeyMap()).
+		WithTheme(hostFormTheme(appTheme))
+
+	f.SubmitCmd = func() tea.Msg { return runFormDoneMsg{run: run, ok: true} }
+	f.CancelCmd = func() tea.Msg { return runFormDoneMsg{run: run} }
+	return f
+}
+
+// runFormKeyMap is the form key map with enter also moving to the next
+// field, so enter on the last field starts the run.
+func runFormKeyMap() *huh.KeyMap {
+	km := NewFormKeyMap()
+	km.Input.Next = key.NewBinding(key.WithKeys("enter", "tab", "down"))
+	return km
+}
+
+// parseRunConcurrency parses the concurrency input (1-64).
+func parseRunConcurrency(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 || n > 64 {
+		return 0, fmt.Errorf("concurrency must be between 1 and 64")
+	}
+	return n, nil
+}
+
+// parseRunTimeout parses the timeout input (eg. "30s"; a bare number is
+// seconds), which is required.
+func parseRunTimeout(s string) (time.Duration, error) {
+	d, err := connect.ParsePreflightTimeout(s)
+	if err != nil || d == 0 {
+		return 0, fmt.Errorf("timeout must be a duration like 30s or 2m")
+	}
+	return d, nil
+}
+
+// plainOutput returns remote output without escape sequences or other
+// control characters (but with newlines and tabs), so a host can't restyle
+// or move things around in the output pane.
+func plainOutput(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, ansi.Strip(s))
+}
+
+// waitRunUpdatesCmd waits for progress from a run and sends it as one
+// runUpdatesMsg, folding in whatever else is already queued.
+func waitRunUpdatesCmd(run *runState, ch <-chan fanout.Update) tea.Cmd {
+	return func() tea.Msg {
+		u, ok := <-ch
+		if !ok {
+			return runUpdatesMsg{run: run, done: true}
+		}
+		batch := []fanout.Update{u}
+		for len(batch) < runMaxUpdates {
+			select {
+			case u, ok := <-ch:
+				if !ok {
+					return runUpdatesMsg{run: run, updates: batch, done: true}
+				}
+				batch = append(batch, u)
+			default:
+				return runUpdatesMsg{run: run, updates: batch}
+			}
+		}
+		return runUpdatesMsg{run: run, updates: batch}
+	}
+}
+
+// handleRunFormDoneMsg starts the run once the form is submitted, or closes it.
+func (m model) handleRunFormDoneMsg(msg runFormDoneMsg) (model, tea.Cmd) {
+	run := m.ms.run
+	if m.mode != modeRun || run == nil || msg.run != run || run.form == nil {
+		return m, nil
+	}
+	if !msg.ok {
+		return m.closeRun()
+	}
+
+	concurrency, _ := parseRunConcurrency(run.values.concurrency)
+	timeout, _ := parseRunTimeout(run.values.timeout)
+	run.form = nil
+	run.command = strings.TrimSpace(run.values.command)
+	run.started = time.Now()
+	run.results = make([]fanout.Result, len(run.aliases))
+	run.index = make(map[string]int, len(run.aliases))
+	for i, a := range run.aliases {
+		run.results[i] = fanout.Result{Alias: a, ExitCode: -1}
+		run.index[a] = i
+	}
+	run.output = viewport.New(0, 0)
+
+	ch := make(chan fanout.Update, runMaxUpdates)
+	ctx, cancel := context.WithCancel(context.Background())
+	run.running = true
+	run.cancel = cancel
+	run.updates = ch
+	go fanout.Run(ctx, run.aliases, run.command, fanout.Options{Concurrency: concurrency, Timeout: timeout}, ch)
+
+	m.relayout()
+	m.refreshRunOutput(true)
+	return m, waitRunUpdatesCmd(run, ch)
+}
+
+// handleRunUpdatesMsg folds run progress into the results.
+func (m model) handleRunUpdatesMsg(msg runUpdatesMsg) (model, tea.Cmd) {
+	run := m.ms.run
+	if run == nil || msg.run != run {
+		return m, nil
+	}
+	for _, u := range msg.updates {
+		if i, ok := run.index[u.Alias]; ok {
+			u.Output = plainOutput(u.Output)
+			run.results[i].Apply(u)
+		}
+	}
+	if !msg.done {
+		m.refreshRunOutput(false)
+		return m, waitRunUpdatesCmd(run, run.updates)
+	}
+
+	run.running = false
+	run.cancel()
+	m.refreshRunOutput(false)
+	ok, failed := run.counts()
+	if failed > 0 {
+		return m, m.setStatusError(fmt.Sprintf("Finished: %d ok, %d failed. Press G to group outputs, S to save.", ok, failed), 0)
+	}
+	return m, m.setStatusSuccess(fmt.Sprintf("Finished: %d ok. Press G to group outputs, S to save.", ok), 0)
+}
+
+// counts returns how many hosts finished ok and how many didn't.
+func (r *runState) counts() (ok, failed int) {
+	for _, res := range r.results {
+		switch {
+		case res.Status == fanout.StatusDone:
+			ok++
+		case res.Status.Finished():
+			failed++
+		}
+	}
+	return ok, failed
+}
+
+// closeRun closes the run view, canceling the run if it's still going.
+//
+// The update channel is drained in the background so the workers can finish.
+func (m model) closeRun() (model, tea.Cmd) {
+	if run := m.ms.run; run != nil && run.running {
+		run.cancel()
+		go func(ch <-chan fanout.Update) {
+			for range ch {
+			}
+		}(run.updates)
+	}
+	m.mode = modeMenu
+	m.ms.run = nil
+	m.relayout()
+	return m, nil
+}
+
+// saveRunCmd writes the run report in the background.
+func saveRunCmd(command string, started time.Time, results []fanout.Result) tea.Cmd {
+	results = append([]fanout.Result(nil), results...)
+	return func() tea.Msg {
+		path, err := fanout.SaveReport(command, started, results)
+		return runSavedMsg{path: path, err: err}
+	}
+}
+
+// handleRunSavedMsg reports where the run report was saved.
+func (m model) handleRunSavedMsg(msg runSavedMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		return m, m.setStatusError("Save failed: "+msg.err.Error(), statusTTL)
+	}
+	return m, m.setStatusSuccess("Saved results to "+msg.path+SuccessCheck, 0)
+}
+
+// handleRunKeyMsg handles keys in the run form and run view.
+//
+// While the form is open, keys go to the form. Afterwards, up/down pick a host,
+// pgup/pgdown scroll its output, G toggles grouped outputs, S saves a report
+// and left/esc cancels a running command (or closes the view once it's done).
+func (m model) handleRunKeyMsg(msg tea.KeyMsg) (model, tea.Cmd) {
+	run := m.ms.run
+	if run == nil {
+		return m.closeRun()
+	}
+
+	if run.form != nil {
+		mdl, cmd := run.form.Update(msg)
+		if f, ok := mdl.(*huh.Form); ok {
+			run.form = f
+		}
+		return m, cmd
+	}
+
+	switch {
+	case msg.String() == "ctrl+c", key.Matches(msg, m.keys.CloseDetails), key.Matches(msg, m.keys.CloseForm):
+		if run.running {
+			run.cancel()
+			return m, m.setStatusInfo("Canceling…", 0)
+		}
+		if msg.String() == "ctrl+c" {
+			m.quitting = true
+			return m, tea.Quit
+		}
+		return m.closeRun()
+
+	case key.Matches(msg, m.keys.Quit):
+		m.quitting = true
+		return m, tea.Quit
+
+	case msg.String() == "up":
+		if run.selected > 0 {
+			run.selected--
+			m.refreshRunOutput(true)
+		}
+		return m, nil
+
+	case msg.String() == "down":
+		if run.selected < len(run.aliases)-1 {
+			run.selected++
+			m.refreshRunOutput(true)
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.RunGroup):
+		run.grouped = !run.grouped
+		m.refreshRunOutput(true)
+		return m, nil
+
+	case key.Matches(msg, m.keys.RunSave):
+		if run.running {
+			return m, m.setStatusInfo("Wait for the run to finish before saving.", statusTTL)
+		}
+		return m, saveRunCmd(run.command, run.started, run.results)
+	}
+
+	var cmd tea.Cmd
+	run.output, cmd = run.output.Update(msg)
+	return m, cmd
+}
+
+// refreshRunOutput rebuilds the output pane. It stays scrolled to the bottom
+// while following output, and jumps to the top if reset is set.
+func (m *model) refreshRunOutput(reset bool) {
+	run := m.ms.run
+	if run == nil || run.form != nil {
+		return
+	}
+	follow := run.output.AtBottom()
+	run.output.SetContent(m.buildRunOutput())
+	switch {
+	case reset:
+		run.output.GotoTop()
+	case follow && !run.grouped:
+		run.output.GotoBottom()
+	}
+}
+
+// buildRunOutput renders either the selected host's output or the grouped outputs.
+func (m model) buildRunOutput() string {
+	run := m.ms.run
+	lg := lipgloss.NewStyle()
+	head := lg.Foreground(m.theme.DetailsLabel).Bold(true)
+	dim := lg.Foreground(m.theme.PreflightText)
+	width := max(run.output.Width, 1)
+
+	if !run.grouped {
+		r := run.results[run.selected]
+		var b strings.Builder
+		b.WriteString(head.Render(r.Alias) + dim.Render(" • "+runResultLabel(r)) + "\n\n")
+		switch {
+		case r.Output != "":
+			b.WriteString(ansi.Wrap(r.Output, width, ""))
+		case r.Err != nil && r.Status.Finished():
+			b.WriteString(dim.Render(r.Err.Error()))
+		case !r.Status.Finished():
+			b.WriteString(dim.Render("waiting for output…"))
+		}
+		return b.String()
+	}
+
+	groups := fanout.GroupOutputs(run.results)
+	if len(groups) == 0 {
+		return dim.Render("No host has finished yet.")
+	}
+	added := lg.Foreground(m.theme.StatusSuccess)
+	removed := lg.Foreground(m.theme.StatusError)
+
+	var b strings.Builder
+	for i, g := range groups {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "%s %s\n%s\n\n",
+			head.Render(fmt.Sprintf("%d host(s)", len(g.Aliases))),
+			dim.Render("• "+fanout.GroupLabel(g)),
+			ansi.Wrap(strings.Join(g.Aliases, ", "), width, ""))
+
+		// the largest group is shown in full; the others as a diff against it
+		if i == 0 {
+			b.WriteString(ansi.Wrap(cmp.Or(g.Output, dim.Render("(no output)")), width, ""))
+			continue
+		}
+		lines, ok := fanout.Diff(groups[0].Output, g.Output)
+		if !ok {
+			b.WriteString(ansi.Wrap(g.Output, width, ""))
+			continue
+		}
+		b.WriteString(dim.Render("diff against the first group:") + "\n")
+		for _, l := range lines {
+			l = ansi.Truncate(l, width, "…")
+			switch {
+			case strings.HasPrefix(l, "+ "):
+				b.WriteString(added.Render(l))
+			case strings.HasPrefix(l, "- "):
+				b.WriteString(removed.Render(l))
+			default:
+				b.WriteString(dim.Render(l))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// runResultLabel describes a host's progress for the run view.
+func runResultLabel(r fanout.Result) string {
+	if !r.Status.Finished() {
+		return r.Status.String()
+	}
+	return fanout.ResultLabel(r)
+}
+
+// runStatusIcon returns the icon and color for a host's status.
+func (m model) runStatusIcon(s fanout.Status) (string, lipgloss.Color) {
+	switch s {
+	case fanout.StatusRunning:
+		return "●", m.theme.PreflightSpinner
+	case fanout.StatusDone:
+		return "✔", m.theme.StatusSuccess
+	case fanout.StatusFailed:
+		return "✘", m.theme.StatusError
+	case fanout.StatusTimedOut:
+		return "⏱", m.theme.StatusError
+	case fanout.StatusCanceled:
+		return "–", m.theme.PreflightText
+	default:
+		return "·", m.theme.PreflightText
+	}
+}
+
+// runHostsWidth returns the width of the host column.
+func (m model) runHostsWidth() int {
+	w := runHostsMinW
+	if run := m.ms.run; run != nil {
+		for _, a := range run.aliases {
+			w = max(w, lipgloss.Width(a)+runHostColExtra)
+		}
+	}
+	return min(w, max(m.width/3, runHostsMinW))
+}
+
+// resizeRun sizes the run output pane to the window.
+func (m *model) resizeRun() {
+	run := m.ms.run
+	if run == nil {
+		return
+	}
+	if run.form != nil {
+		run.form = run.form.WithWidth(max(0, min(m.width-hostFormPadding, 80)))
+		return
+	}
+	footer := runFooterLines
+	if m.status != "" {
+		footer += 1 + lipgloss.Height(m.status)
+	}
+	run.output.Width = max(0, m.width-m.runHostsWidth()-runPaneGap-footerPadLeft)
+	run.output.Height = max(1, m.height-runHeaderLines-footer)
+	m.refreshRunOutput(false)
+}
+
+// runHelpKeys returns the help keys shown in the run view.
+func (m model) runHelpKeys() []key.Binding {
+	if m.ms.run != nil && m.ms.run.form != nil {
+		return []key.Binding{m.keys.CloseForm, m.keys.FormPrev, m.keys.FormNext}
+	}
+	return []key.Binding{m.keys.CloseDetails, m.lst.KeyMap.CursorUp, m.lst.KeyMap.CursorDown,
+		m.keys.RunGroup, m.keys.RunSave}
+}
+
+// viewRun renders the run form, or the host list and output pane of a run.
+func (m model) viewRun() string {
+	run := m.ms.run
+	if run == nil {
+		return ""
+	}
+	lg := lipgloss.NewStyle()
+	h := m.lst.Help
+	h.Width = m.width
+	help := lg.PaddingLeft(footerPadLeft).PaddingTop(1).Render(h.ShortHelpView(m.runHelpKeys()))
+
+	if run.form != nil {
+		body := lg.Padding(1, 3).Render(run.form.View())
+		return strings.Join([]string{body, help}, "\n")
+	}
+
+	title := m.lst.Styles.Title.Render(ansi.Truncate("RUN "+run.command, max(0, m.width-12), "…"))
+	header := lg.Padding(1, 0, 1, 1).Render(title)
+
+	// host column
+	hostsW := m.runHostsWidth()
+	var hosts []string
+	first := max(0, run.selected-run.output.Height+1)
+	for i := first; i < len(run.results) && i < first+run.output.Height; i++ {
+		r := run.results[i]
+		icon, color := m.runStatusIcon(r.Status)
+		label := r.Alias
+		if d := r.Duration.Round(100 * time.Millisecond); r.Status.Finished() && d > 0 {
+			label += " " + lg.Foreground(m.theme.PreflightText).Render(d.String())
+		}
+		line := lg.Foreground(color).Render(icon) + " " + label
+		if i == run.selected && !run.grouped {
+			line = lg.Foreground(m.theme.SelectedItemTitle).Render("> ") + line
+		} else {
+			line = "  " + line
+		}
+		hosts = append(hosts, ansi.Truncate(line, hostsW, "…"))
+	}
+	left := lg.Width(hostsW).PaddingLeft(footerPadLeft).Render(strings.Join(hosts, "\n"))
+	body := lipgloss.JoinHorizontal(lipgloss.Top, left, strings.Repeat(" ", runPaneGap), run.output.View())
+
+	// progress line
+	ok, failed := run.counts()
+	progress := fmt.Sprintf("%d/%d finished • %d ok • %d failed", ok+failed, len(run.results), ok, failed)
+	if run.running {
+		progress += " • running (esc to cancel)"
+	}
+	lines := []string{header, body,
+		lg.PaddingLeft(footerPadLeft).PaddingTop(1).Foreground(m.theme.PreflightText).Render(progress)}
+	if m.status != "" {
+		lines = append(lines, lg.PaddingLeft(footerPadLeft).PaddingTop(1).Foreground(m.statusColor()).Render(m.status))
+	}
+	lines = append(lines, help)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,109 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/secret"
+
+	"github.com/charmbracelet/huh"
+)
+
+// hostPassword is a password to store in the vault when the host form saves.
+type hostPassword struct {
+	secret    string // secret name
+	password  string // "" stores nothing
+	vaultPass string // vault passphrase, if the vault is locked
+}
+
+// buildSecretOptionsGroup creates the password secret Huh group (all protocols).
+func buildSecretOptionsGroup(v *form) *huh.Group {
+	note := huh.NewNote().Description(
+		"Password for this host. Press " + GreenEnter() + " to save.\n\n" +
+			"_The secret name is looked up in BTMS_SECRET_<NAME>, the vault (~/.btms/vault.age),\n" +
+			"then the secrets.command setting (eg. pass show btms/{name}). Hosts can share a secret.\n" +
+			"ssh gets it through its askpass helper, and login scripts send it with {password}.\n" +
+			"A new password is stored in the vault; leave it blank to keep the stored one.\n" +
+			"Key passphrases are secrets named key:<file> (eg. key:id_ed25519).")
+
+	return huh.NewGroup(
+		note,
+		buildInputField("secret", "Password Secret", &v.app.Secret),
+		buildInputField("password", "New Password", &v.password).
+			EchoMode(huh.EchoModePassword),
+	)
+}
+
+// buildVaultGroup creates the vault passphrase Huh group, shown when a new
+// password needs the vault unlocked (or created).
+func buildVaultGroup(v *form) *huh.Group {
+	vault := secret.DefaultVault()
+	fields := []huh.Field{
+		huh.NewNote().Description("The vault is locked. Enter its passphrase to store the password."),
+		buildInputField("vaultpass", "Vault Passphrase", &v.vaultPass).
+			EchoMode(huh.EchoModePassword),
+	}
+	if !vault.Exists() {
+		// a new vault's passphrase is typed twice, since it can't be recovered
+		fields = []huh.Field{
+			huh.NewNote().Description("There's no vault yet. Choose a passphrase to create it with;\n" +
+				"it can't be recovered if forgotten."),
+			fields[1],
+			buildInputField("vaultconfirm", "Confirm Passphrase", &v.vaultConfirm).
+				EchoMode(huh.EchoModePassword),
+		}
+	}
+
+	return huh.NewGroup(fields...).WithHideFunc(func() bool {
+		return v.password == "" || vault.Unlocked()
+	})
+}
+
+// passwordErr reports what's missing to store the form's new password.
+func passwordErr(v *form) error {
+	if v.password == "" {
+		return nil
+	}
+	vault := secret.DefaultVault()
+	switch {
+	case vault.Unlocked():
+		return nil
+	case vault.Exists() && v.vaultPass == "":
+		return errors.New("enter the vault passphrase to store the password")
+	case !vault.Exists() && v.vaultPass == "":
+		return errors.New("choose a vault passphrase to store the password")
+	case !vault.Exists() && v.vaultPass != v.vaultConfirm:
+		return errors.New("vault passphrases don't match")
+	}
+	return nil
+}
+
+// storePassword stores a host's new password in the vault, unlocking it
+// with the given passphrase if needed. It runs in a Cmd since the vault's
+// key derivation is slow on purpose.
+func storePassword(p hostPassword) error {
+	vault := secret.DefaultVault()
+	if !vault.Unlocked() {
+		if err := vault.Unlock(p.vaultPass); err != nil {
+			return err
+		}
+	}
+	return vault.Set(p.secret, p.password)
+}
+
+// secretStatus describes where a host's password secret is, for the details
+// view.
+func secretStatus(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ""
+	}
+	vault := secret.DefaultVault()
+	switch {
+	case vault.Has(name):
+		return name + " (in vault)"
+	case vault.Exists() && !vault.Unlocked():
+		return name + " (vault locked)"
+	}
+	return name + " (not in vault)"
+}
@@ -0,0 +1,242 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/launch"
+	"bubbletea-ssh-manager/internal/settings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type settingsState struct {
+	form   *huh.Form          // settings form, one page per section
+	keys   []settings.Key     // keys with a field in the form
+	values map[string]*string // values bound to the form, by key name
+}
+
+// openSettings opens the settings form, filled in with the effective
+// settings. Settings set by an environment variable or --set flag aren't
+// editable here, since they'd win over the file anyway.
+func (m model) openSettings() (model, tea.Cmd) {
+	st := &settingsState{values: map[string]*string{}}
+	for _, k := range settings.Keys() {
+		if src := m.settings.Source(k); src == settings.SourceEnv || src == settings.SourceFlag {
+			continue
+		}
+		v := m.settings.Get(k)
+		st.keys = append(st.keys, k)
+		st.values[k.Name] = &v
+	}
+	st.form = buildSettingsForm(st, m.settings, m.theme)
+
+	m.mode = modeSettings
+	m.ms.settings = st
+	m.setStatusInfo("", 0)
+	m.relayout()
+	return m, st.form.Init()
+}
+
+// buildSettingsForm builds the settings form: a page per section, with a
+// note listing the section's settings that are overridden elsewhere.
+func buildSettingsForm(st *settingsState, s settings.Settings, appTheme Theme) *huh.Form {
+	var groups []*huh.Group
+	var fields []huh.Field
+	var overridden []string
+	section := ""
+	flush := func() {
+		if section == "" {
+			return
+		}
+		desc := "Saved to " + escapeNote(s.Path()) + "."
+		if len(overridden) > 0 {
+			desc += "\nSet elsewhere: " + escapeNote(strings.Join(overridden, ", ")) + "."
+		}
+		note := huh.NewNote().Title("Settings: " + section).Description(desc)
+		groups = append(groups, huh.NewGroup(append([]huh.Field{note}, fields...)...))
+		fields, overridden = nil, nil
+	}
+
+	for _, k := range settings.Keys() {
+		if k.Section() != section {
+			flush()
+			section = k.Section()
+		}
+		v, ok := st.values[k.Name]
+		if !ok {
+			where := k.Env()
+			if s.Source(k) == settings.SourceFlag {
+				where = "--set"
+			}
+			overridden = append(overridden, fmt.Sprintf("%s (%s)", k.Name, where))
+			continue
+		}
+		fields = append(fields, settingsField(k, v))
+	}
+	flush()
+	if len(groups) == 0 {
+		groups = append(groups, huh.NewGroup(huh.NewNote().Title("Settings").
+			Description("Every setting is set by an environment variable or --set flag.")))
+	}
+
+	f := huh.NewForm(groups...).
+		WithShowHelp(false).
+		WithKeyMap(runFormKeyMap()).
+		WithTheme(hostFormTheme(appTheme))
+
+	f.SubmitCmd = func() tea.Msg { return settingsFormDoneMsg{settings: st, ok: true} }
+	f.CancelCmd = func() tea.Msg { return settingsFormDoneMsg{settings: st} }
+	return f
+}
+
+// noteEscaper escapes the characters huh notes treat as markup.
+var noteEscaper = strings.NewReplacer(`\`, `\\`, "_", `\_`, "*", `\*`, "`", "\\`")
+
+// escapeNote returns s escaped to show as-is in a huh note (eg. paths and
+// environment variable names, which have underscores).
+func escapeNote(s string) string {
+	return noteEscaper.Replace(s)
+}
+
+// settingsField returns the form field for a setting: a select for keys
+// with a fixed set of values, otherwise an input checked like the file is.
+func settingsField(k settings.Key, v *string) huh.Field {
+	title := strings.TrimPrefix(k.Name, k.Section()+".")
+	if len(k.Choices) > 0 {
+		opts := make([]huh.Option[string], 0, len(k.Choices))
+		for _, c := range k.Choices {
+			opts = append(opts, huh.NewOption(c, c))
+		}
+		return huh.NewSelect[string]().
+			Key(k.Name).
+			Title(title).
+			Description(k.Usage).
+			Options(opts...).
+			Value(v)
+	}
+	return huh.NewInput().
+		Key(k.Name).
+		Title(title).
+		Description(k.Usage).
+		Value(v).
+		Validate(k.Validate)
+}
+
+// handleSettingsKeyMsg routes keys to the settings form.
+func (m model) handleSettingsKeyMsg(msg tea.KeyMsg) (model, tea.Cmd) {
+	st := m.ms.settings
+	if st == nil || st.form == nil {
+		return m.closeSettings()
+	}
+	mdl, cmd := st.form.Update(msg)
+	if f, ok := mdl.(*huh.Form); ok {
+		st.form = f
+	}
+	return m, cmd
+}
+
+// closeSettings closes the settings form.
+func (m model) closeSettings() (model, tea.Cmd) {
+	m.mode = modeMenu
+	m.ms.settings = nil
+	m.relayout()
+	return m, nil
+}
+
+// handleSettingsFormDoneMsg saves the changed settings to the settings file.
+func (m model) handleSettingsFormDoneMsg(msg settingsFormDoneMsg) (model, tea.Cmd) {
+	st := m.ms.settings
+	if m.mode != modeSettings || st == nil || msg.settings != st {
+		return m, nil
+	}
+	m, _ = m.closeSettings()
+	if !msg.ok {
+		return m, m.setStatusError(ErrorX+"Canceled settings. Any changes made were not saved.", statusTTL)
+	}
+
+	s := m.settings.Clone()
+	changed := 0
+	for _, k := range st.keys {
+		v := strings.TrimSpace(*st.values[k.Name])
+		if v == m.settings.Get(k) {
+			continue
+		}
+		if err := s.SetFile(k, v); err != nil {
+			return m, m.setStatusError(err.Error(), 0)
+		}
+		changed++
+	}
+	if changed == 0 {
+		return m, m.setStatusInfo("No settings changed.", statusTTL)
+	}
+	return m, saveSettingsCmd(s, s.Theme != m.settings.Theme)
+}
+
+// saveSettingsCmd writes the settings file in the background.
+func saveSettingsCmd(s settings.Settings, themeChanged bool) tea.Cmd {
+	return func() tea.Msg {
+		return settingsSavedMsg{settings: s, themeChanged: themeChanged, err: s.Save()}
+	}
+}
+
+// handleSettingsSavedMsg applies the saved settings (the launcher included)
+// and reloads the menu, since the protocols' config files may have moved.
+func (m model) handleSettingsSavedMsg(msg settingsSavedMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		return m, m.setStatusError("Save failed: "+msg.err.Error(), 0)
+	}
+	if err := settings.Apply(msg.settings); err != nil {
+		return m, m.setStatusError("Settings: "+err.Error(), 0)
+	}
+	launcher, err := launch.Parse(msg.settings.Launcher, msg.settings.TerminalCommand)
+	if err != nil {
+		return m, m.setStatusError("Settings: "+err.Error(), 0)
+	}
+	m.settings = msg.settings
+	m.launcher = launcher
+	statusTTL = msg.settings.StatusTTL
+
+	text := "Saved settings to " + msg.settings.Path()
+	if msg.themeChanged {
+		text += "; the theme changes the next time the menu starts"
+	}
+	reloadCmd := func() tea.Msg {
+		root, err := seedMenu()
+		return menuReloadedMsg{root: root, err: err}
+	}
+	return m, tea.Batch(m.setStatusSuccess(text+SuccessCheck, statusTTL), reloadCmd)
+}
+
+// settingsHelpKeys returns the help keys shown under the settings form.
+func (m model) settingsHelpKeys() []key.Binding {
+	return []key.Binding{m.keys.CloseForm, m.keys.FormPrev, m.keys.FormNext, m.keys.FormSelect}
+}
+
+// resizeSettings sizes the settings form to the window.
+func (m *model) resizeSettings() {
+	if m.ms.settings == nil || m.ms.settings.form == nil {
+		return
+	}
+	// a fixed height keeps long descriptions, wrapped at this width, from
+	// pushing a page's title out of view
+	w := max(0, min(m.width-hostFormPadding, 80))
+	m.ms.settings.form = m.ms.settings.form.WithWidth(w).WithHeight(max(0, m.height-hostFormHeaderFooter))
+}
+
+// viewSettings renders the settings form.
+func (m model) viewSettings() string {
+	st := m.ms.settings
+	if st == nil || st.form == nil {
+		return ""
+	}
+	lg := lipgloss.NewStyle()
+	h := m.lst.Help
+	h.Width = m.width
+	help := lg.PaddingLeft(footerPadLeft).PaddingTop(1).Render(h.ShortHelpView(m.settingsHelpKeys()))
+	body := lg.Padding(1, 3).Render(st.form.View())
+	return strings.Join([]string{body, help}, "\n")
+}
@@ -0,0 +1,707 @@
+package tui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/secret"
+	"bubbletea-ssh-manager/internal/sshkeys"
+	str "bubbletea-ssh-manager/internal/stringutil"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	sshKeysDetailLines = 10 // key details under the list
+	sshKeysUsedByMax   = 6  // hosts listed in a key's details before the rest are summarized
+)
+
+// sshKeysForm is the form open in the keys view.
+type sshKeysForm int
+
+const (
+	sshKeysNoForm sshKeysForm = iota
+	sshKeysGenerateForm
+	sshKeysInstallForm
+)
+
+type sshKeysState struct {
+	keys     []sshkeys.Key // keys in ~/.ssh and the agent
+	cursor   int           // selected key
+	loading  bool          // true until the first sshKeysLoadedMsg arrives
+	agentErr string        // why the agent couldn't be listed
+
+	formKind sshKeysForm   // form shown instead of the list
+	form     *huh.Form     // generate or install form (nil if none)
+	gen      *keyGenValues // generate form values
+	target   int           // install form target (index into targets)
+	targets  []keyTarget   // install form targets
+}
+
+// keyGenValues holds the generate form's input values.
+type keyGenValues struct {
+	keyType    sshkeys.KeyType
+	bits       string
+	file       string
+	comment    string
+	passphrase string
+	confirm    string
+}
+
+// keyTarget is a host or group a key can be installed on.
+type keyTarget struct {
+	label string
+	hosts []*menuItem
+}
+
+// selected returns the key under the cursor, if any.
+func (ks *sshKeysState) selected() (sshkeys.Key, bool) {
+	if ks.cursor < 0 || ks.cursor >= len(ks.keys) {
+		return sshkeys.Key{}, false
+	}
+	return ks.keys[ks.cursor], true
+}
+
+// loadSSHKeysCmd lists the keys in ~/.ssh and the agent.
+func loadSSHKeysCmd() tea.Cmd {
+	return func() tea.Msg {
+		var files []sshkeys.Key
+		dir, err := sshkeys.Dir()
+		if err == nil {
+			files, err = sshkeys.ListDir(dir)
+		}
+		agent, agentErr := sshkeys.ListAgent()
+		return sshKeysLoadedMsg{keys: sshkeys.Merge(files, agent), err: err, agentErr: agentErr}
+	}
+}
+
+// openSSHKeys opens the ssh keys view and starts loading the keys.
+func (m model) openSSHKeys() (model, tea.Cmd) {
+	m.mode = modeSSHKeys
+	m.ms.sshKeys = &sshKeysState{loading: true}
+	m.setStatusInfo("", 0)
+	m.relayout()
+	return m, loadSSHKeysCmd()
+}
+
+// closeSSHKeys closes the ssh keys view and returns to the menu.
+func (m model) closeSSHKeys() (model, tea.Cmd) {
+	m.mode = modeMenu
+	m.ms.sshKeys = nil
+	m.relayout()
+	return m, nil
+}
+
+// handleSSHKeysLoadedMsg fills the keys view, keeping the selected key if
+// it's still there.
+func (m model) handleSSHKeysLoadedMsg(msg sshKeysLoadedMsg) (model, tea.Cmd) {
+	ks := m.ms.sshKeys
+	if m.mode != modeSSHKeys || ks == nil {
+		return m, nil
+	}
+	prev, _ := ks.selected()
+	ks.loading = false
+	ks.keys = msg.keys
+	ks.cursor = 0
+	for i, k := range ks.keys {
+		if k.Fingerprint == prev.Fingerprint {
+			ks.cursor = i
+			break
+		}
+	}
+	ks.agentErr = ""
+	if msg.agentErr != nil {
+		ks.agentErr = msg.agentErr.Error()
+	}
+	if msg.err != nil {
+		return m, m.setStatusError("SSH keys: "+msg.err.Error(), statusTTL)
+	}
+	return m, nil
+}
+
+// handleSSHKeysKeyMsg handles keys in the ssh keys view.
+//
+// Up/down pick a key, G generates a new one, A adds the selected key to the
+// agent, I installs it on a host or group, ctrl+r reloads and left/esc
+// closes the view. While a form is open, keys go to the form.
+func (m model) handleSSHKeysKeyMsg(msg tea.KeyMsg) (model, tea.Cmd) {
+	ks := m.ms.sshKeys
+	if ks == nil {
+		return m.closeSSHKeys()
+	}
+	if ks.form != nil {
+		mdl, cmd := ks.form.Update(msg)
+		if f, ok := mdl.(*huh.Form); ok {
+			ks.form = f
+		}
+		return m, cmd
+	}
+
+	switch {
+	case msg.String() == "ctrl+c", key.Matches(msg, m.keys.Quit):
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.CloseDetails), key.Matches(msg, m.keys.CloseForm):
+		return m.closeSSHKeys()
+
+	case msg.String() == "up":
+		ks.cursor = max(0, ks.cursor-1)
+
+	case msg.String() == "down":
+		ks.cursor = max(0, min(len(ks.keys)-1, ks.cursor+1))
+
+	case key.Matches(msg, m.keys.KeyRefresh):
+		ks.loading = true
+		return m, loadSSHKeysCmd()
+
+	case key.Matches(msg, m.keys.KeyGenerate):
+		return m.openKeyGenerateForm()
+
+	case key.Matches(msg, m.keys.KeyAgent):
+		return m.addKeyToAgent()
+
+	case key.Matches(msg, m.keys.KeyInstall):
+		return m.openKeyInstallForm()
+	}
+	return m, nil
+}
+
+// openKeyGenerateForm asks for the type, file, comment and passphrase of a new key.
+func (m model) openKeyGenerateForm() (model, tea.Cmd) {
+	ks := m.ms.sshKeys
+	ks.gen = &keyGenValues{keyType: sshkeys.ED25519, bits: strconv.Itoa(sshkeys.DefaultRSABits),
+		comment: sshkeys.DefaultComment()}
+	ks.formKind = sshKeysGenerateForm
+	ks.form = buildKeyGenerateForm(ks, m.theme)
+	m.relayout()
+	return m, ks.form.Init()
+}
+
+// buildKeyGenerateForm builds the form for a new key pair.
+func buildKeyGenerateForm(ks *sshKeysState, appTheme Theme) *huh.Form {
+	v := ks.gen
+	f := huh.NewForm(
+		huh.NewGroup(
+			huh.NewNote().Title("Generate Key").
+				Description("Creates a key pair like ssh-keygen. Existing files are kept."),
+			huh.NewSelect[sshkeys.KeyType]().
+				Key("type").
+				Title("Type").
+				Options(
+					huh.NewOption("ed25519 (recommended)", sshkeys.ED25519),
+					huh.NewOption("RSA", sshkeys.RSA),
+				).
+				Value(&v.keyType),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				Key("bits").
+				Title("Bits").
+				Value(&v.bits).
+				Validate(func(s string) error {
+					n, err := strconv.Atoi(strings.TrimSpace(s))
+					if err != nil || n < 2048 {
+						return errors.New("enter a key size of at least 2048")
+					}
+					return nil
+				}),
+		).WithHideFunc(func() bool { return v.keyType != sshkeys.RSA }),
+		huh.NewGroup(
+			huh.NewInput().
+				Key("file").
+				Title("File").
+				Description("Relative to ~/.ssh. Leave blank for id_<type>.").
+				Value(&v.file).
+				Validate(func(s string) error {
+					p, err := keyGenPath(v.keyType, s)
+					if err != nil {
+						return err
+					}
+					if _, err := os.Stat(p); err == nil {
+						return fmt.Errorf("%s already exists", filepath.Base(p))
+					}
+					return nil
+				}),
+			huh.NewInput().
+				Key("comment").
+				Title("Comment").
+				Value(&v.comment),
+			huh.NewInput().
+				Key("passphrase").
+				Title("Passphrase").
+				Description("Leave blank for no passphrase.").
+				EchoMode(huh.EchoModePassword).
+				Value(&v.passphrase),
+			huh.NewInput().
+				Key("confirm").
+				Title("Confirm Passphrase").
+				EchoMode(huh.EchoModePassword).
+				Value(&v.confirm).
+				Validate(func(s string) error {
+					if s != v.passphrase {
+						return errors.New("passphrases don't match")
+					}
+					return nil
+				}),
+		),
+	).
+		WithShowHelp(false).
+		WithKeyMap(runFormKeyMap()).
+		WithTheme(hostFormTheme(appTheme))
+
+	f.SubmitCmd = func() tea.Msg { return sshKeysFormDoneMsg{keys: ks, ok: true} }
+	f.CancelCmd = func() tea.Msg { return sshKeysFormDoneMsg{keys: ks} }
+	return f
+}
+
+// keyGenPath returns the private key path for the generate form's file
+// input: relative names are under ~/.ssh, and blank is the type's default.
+func keyGenPath(t sshkeys.KeyType, file string) (string, error) {
+	file = strings.TrimSpace(file)
+	if file == "" {
+		file = sshkeys.DefaultFileName(t)
+	}
+	file = strings.TrimSuffix(file, ".pub")
+	if strings.HasPrefix(file, "~") || filepath.IsAbs(file) {
+		return config.ExpandPath(file)
+	}
+	dir, err := sshkeys.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, file), nil
+}
+
+// openKeyInstallForm asks which host or group to install the selected key on.
+func (m model) openKeyInstallForm() (model, tea.Cmd) {
+	ks := m.ms.sshKeys
+	k, ok := ks.selected()
+	if !ok {
+		return m, m.setStatusInfo("Select a key to install.", statusTTL)
+	}
+	targets := m.keyTargets()
+	if len(targets) == 0 {
+		return m, m.setStatusError("There are no ssh hosts to install the key on.", statusTTL)
+	}
+	ks.targets = targets
+	ks.target = 0
+	ks.formKind = sshKeysInstallForm
+
+	opts := make([]huh.Option[int], 0, len(targets))
+	for i, t := range targets {
+		opts = append(opts, huh.NewOption(t.label, i))
+	}
+	f := huh.NewForm(
+		huh.NewGroup(
+			huh.NewNote().Title("Install Key").
+				Description("Adds "+k.Name()+" to the hosts' authorized keys, like ssh-copy-id.\n"+
+					"Hosts may ask for their password."),
+			huh.NewSelect[int]().
+				Key("target").
+				Title("Install On").
+				Options(opts...).
+				Value(&ks.target),
+		),
+	).
+		WithShowHelp(false).
+		WithKeyMap(runFormKeyMap()).
+		WithTheme(hostFormTheme(m.theme))
+	f.SubmitCmd = func() tea.Msg { return sshKeysFormDoneMsg{keys: ks, ok: true} }
+	f.CancelCmd = func() tea.Msg { return sshKeysFormDoneMsg{keys: ks} }
+	ks.form = f
+	m.relayout()
+	return m, f.Init()
+}
+
+// keyTargets returns the ssh groups, then the ssh hosts, a key can be installed on.
+func (m model) keyTargets() []keyTarget {
+	var groups, hosts []keyTarget
+	if m.root == nil {
+		return nil
+	}
+	for _, it := range hostLevel(m.root) {
+		switch {
+		case it == nil:
+		case it.kind == itemHost && it.protocol == config.ProtocolSSH:
+			hosts = append(hosts, keyTarget{label: it.spec.Alias, hosts: []*menuItem{it}})
+		case it.kind == itemGroup:
+			var members []*menuItem
+			for _, h := range hostsUnder(it.children) {
+				if h.protocol == config.ProtocolSSH {
+					members = append(members, h)
+					hosts = append(hosts, keyTarget{label: h.spec.Alias, hosts: []*menuItem{h}})
+				}
+			}
+			if len(members) > 0 {
+				groups = append(groups, keyTarget{
+					label: fmt.Sprintf("Group %s (%d hosts)", it.name, len(members)),
+					hosts: members,
+				})
+			}
+		}
+	}
+	return append(groups, hosts...)
+}
+
+// handleSSHKeysFormDoneMsg generates the key or installs it once its form is submitted.
+func (m model) handleSSHKeysFormDoneMsg(msg sshKeysFormDoneMsg) (model, tea.Cmd) {
+	ks := m.ms.sshKeys
+	if m.mode != modeSSHKeys || ks == nil || msg.keys != ks || ks.form == nil {
+		return m, nil
+	}
+	kind := ks.formKind
+	ks.form, ks.formKind = nil, sshKeysNoForm
+	m.relayout()
+	if !msg.ok {
+		return m, nil
+	}
+
+	switch kind {
+	case sshKeysGenerateForm:
+		v := ks.gen
+		path, err := keyGenPath(v.keyType, v.file)
+		if err != nil {
+			return m, m.setStatusError("Generate key: "+err.Error(), statusTTL)
+		}
+		bits, _ := strconv.Atoi(strings.TrimSpace(v.bits))
+		o := sshkeys.GenerateOptions{Type: v.keyType, Bits: bits, Path: path,
+			Comment: strings.TrimSpace(v.comment), Passphrase: v.passphrase}
+		ks.gen = nil
+		return m, tea.Batch(m.setStatusInfo("Generating "+filepath.Base(path)+"…", 0), generateKeyCmd(o))
+
+	case sshKeysInstallForm:
+		k, ok := ks.selected()
+		if !ok || ks.target < 0 || ks.target >= len(ks.targets) {
+			return m, nil
+		}
+		return m, installKeyCmd(k, ks.targets[ks.target].hosts)
+	}
+	return m, nil
+}
+
+// generateKeyCmd creates a key pair in the background; RSA keys take a moment.
+func generateKeyCmd(o sshkeys.GenerateOptions) tea.Cmd {
+	return func() tea.Msg {
+		k, err := sshkeys.Generate(o)
+		return sshKeyGeneratedMsg{key: k, err: err}
+	}
+}
+
+// handleSSHKeyGeneratedMsg reports a new key and reloads the list.
+func (m model) handleSSHKeyGeneratedMsg(msg sshKeyGeneratedMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		return m, m.setStatusError("Generate key: "+msg.err.Error(), statusTTL)
+	}
+	text := fmt.Sprintf("Generated %s (%s).%s", msg.key.Path, msg.key.Fingerprint, SuccessCheck)
+	cmd := m.setStatusSuccess(text, statusTTL)
+	if m.mode != modeSSHKeys || m.ms.sshKeys == nil {
+		return m, cmd
+	}
+	return m, tea.Batch(cmd, loadSSHKeysCmd())
+}
+
+// addKeyToAgent runs ssh-add for the selected key. Its passphrase comes from
+// the key's secret (key:<file>) if there is one, else it's asked for.
+func (m model) addKeyToAgent() (model, tea.Cmd) {
+	k, ok := m.ms.sshKeys.selected()
+	switch {
+	case !ok:
+		return m, nil
+	case k.Path == "":
+		return m, m.setStatusInfo(k.Name()+" is only in the agent.", statusTTL)
+	case k.InAgent:
+		return m, m.setStatusInfo(k.Name()+" is already in the agent.", statusTTL)
+	}
+	cmd, err := sshkeys.AddToAgentCommand(k)
+	if err != nil {
+		return m, m.setStatusError("Add to agent: "+err.Error(), statusTTL)
+	}
+	var stderr bytes.Buffer
+	c := &teeCmd{Cmd: cmd, errBuf: &stderr, askpass: true}
+	return m, tea.Exec(c, func(err error) tea.Msg {
+		if err != nil {
+			if line := str.LastNonEmptyLine(stderr.String()); line != "" {
+				err = fmt.Errorf("%w: %s", err, line)
+			}
+		}
+		return sshKeyAddedMsg{name: k.Name(), err: err}
+	})
+}
+
+// handleSSHKeyAddedMsg reports ssh-add's outcome and reloads the list.
+func (m model) handleSSHKeyAddedMsg(msg sshKeyAddedMsg) (model, tea.Cmd) {
+	var cmd tea.Cmd
+	if msg.err != nil {
+		cmd = m.setStatusError("Add "+msg.name+" to agent: "+msg.err.Error(), statusTTL)
+	} else {
+		cmd = m.setStatusSuccess("Added "+msg.name+" to the agent."+SuccessCheck, statusTTL)
+	}
+	if m.mode != modeSSHKeys || m.ms.sshKeys == nil {
+		return m, cmd
+	}
+	return m, tea.Batch(cmd, loadSSHKeysCmd())
+}
+
+// keyInstall installs a key on hosts one after another, in the terminal so
+// ssh can ask for passwords. It implements tea.ExecCommand.
+type keyInstall struct {
+	key     sshkeys.Key
+	hosts   []*menuItem
+	stdout  io.Writer
+	stderr  io.Writer
+	results []keyInstallResult
+}
+
+// keyInstallResult is the outcome of installing a key on one host.
+type keyInstallResult struct {
+	alias  string
+	output string // last line of output (eg. "key installed")
+	err    error
+}
+
+func (ki *keyInstall) SetStdin(io.Reader)    {}
+func (ki *keyInstall) SetStdout(w io.Writer) { ki.stdout = w }
+func (ki *keyInstall) SetStderr(w io.Writer) { ki.stderr = w }
+
+// Run installs the key on every host, carrying on past failures.
+func (ki *keyInstall) Run() error {
+	for _, h := range ki.hosts {
+		alias := h.spec.Alias
+		fmt.Fprintf(ki.stdout, "Installing %s on %s\r\n", ki.key.Name(), alias)
+		cmd, err := sshkeys.InstallCommand(alias, ki.key)
+		var stdout, stderr bytes.Buffer
+		if err == nil {
+			cmd.Stdout = io.MultiWriter(ki.stdout, &stdout)
+			cmd.Stderr = io.MultiWriter(ki.stderr, &stderr)
+			t := connect.Target{Protocol: h.protocol, Spec: h.spec, Secret: h.app.Secret}
+			err = runWithAskpass(cmd, connect.AskpassTarget(t))
+		}
+		if err != nil {
+			if line := str.LastNonEmptyLine(stderr.String()); line != "" {
+				err = fmt.Errorf("%w: %s", err, line)
+			}
+		}
+		ki.results = append(ki.results, keyInstallResult{alias: alias, output: str.LastNonEmptyLine(stdout.String()), err: err})
+	}
+	return nil
+}
+
+// installKeyCmd installs a key on hosts in the terminal and sends a sshKeyInstalledMsg.
+func installKeyCmd(k sshkeys.Key, hosts []*menuItem) tea.Cmd {
+	ki := &keyInstall{key: k, hosts: hosts}
+	return tea.Exec(ki, func(error) tea.Msg {
+		return sshKeyInstalledMsg{name: k.Name(), results: ki.results}
+	})
+}
+
+// handleSSHKeyInstalledMsg reports which hosts the key was installed on.
+func (m model) handleSSHKeyInstalledMsg(msg sshKeyInstalledMsg) (model, tea.Cmd) {
+	var failed []string
+	done := 0
+	for _, r := range msg.results {
+		if r.err != nil {
+			failed = append(failed, r.alias+": "+r.err.Error())
+			continue
+		}
+		done++
+	}
+	if len(msg.results) == 1 {
+		r := msg.results[0]
+		if r.err != nil {
+			return m, m.setStatusError("Install "+msg.name+" on "+r.alias+": "+r.err.Error(), 0)
+		}
+		return m, m.setStatusSuccess(fmt.Sprintf("%s: %s.%s", r.alias, capitalize(r.output), SuccessCheck), statusTTL)
+	}
+	text := fmt.Sprintf("Installed %s on %d host(s)", msg.name, done)
+	if len(failed) > 0 {
+		text += fmt.Sprintf("; %d failed:\n%s", len(failed), summarizeList(failed, 5))
+		return m, m.setStatusError(text, 0)
+	}
+	return m, m.setStatusSuccess(text+"."+SuccessCheck, statusTTL)
+}
+
+// teeCmd runs a command in the terminal, keeping a copy of its error output.
+// It implements tea.ExecCommand.
+type teeCmd struct {
+	*exec.Cmd
+	errBuf  *bytes.Buffer
+	askpass bool // key passphrase prompts get their key:<file> secret
+}
+
+func (c *teeCmd) SetStdin(r io.Reader)  { c.Stdin = r }
+func (c *teeCmd) SetStdout(w io.Writer) { c.Stdout = w }
+func (c *teeCmd) SetStderr(w io.Writer) { c.Stderr = io.MultiWriter(w, c.errBuf) }
+
+// Run runs the command, with ssh's askpass helper if it asks for one.
+func (c *teeCmd) Run() error {
+	if !c.askpass {
+		return c.Cmd.Run()
+	}
+	return runWithAskpass(c.Cmd, secret.AskpassTarget{})
+}
+
+// runWithAskpass runs an ssh tool from the keys view: password prompts for
+// t get its secret, and key passphrases their key:<file> secret, falling
+// back to the terminal.
+func runWithAskpass(cmd *exec.Cmd, t secret.AskpassTarget) error {
+	stop, err := secret.StartAskpass(cmd, t)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	return cmd.Run()
+}
+
+// keyUsers returns the aliases of the ssh hosts whose IdentityFile names k.
+func (m model) keyUsers(k sshkeys.Key) []string {
+	hosts, _ := getHostItemsWithHints(m.root)
+	var out []string
+	for _, h := range hosts {
+		if h.protocol == config.ProtocolSSH && k.UsedBy(h.options.IdentityFiles) {
+			out = append(out, h.spec.Alias)
+		}
+	}
+	return out
+}
+
+// sshKeysHelpKeys returns the help keys shown in the ssh keys view.
+func (m model) sshKeysHelpKeys() []key.Binding {
+	if ks := m.ms.sshKeys; ks != nil && ks.form != nil {
+		return []key.Binding{m.keys.CloseForm, m.keys.FormPrev, m.keys.FormNext, m.keys.FormSelect}
+	}
+	return []key.Binding{m.keys.CloseDetails, m.lst.KeyMap.CursorUp, m.lst.KeyMap.CursorDown,
+		m.keys.KeyGenerate, m.keys.KeyAgent, m.keys.KeyInstall, m.keys.KeyRefresh}
+}
+
+// resizeSSHKeys sizes the keys view's form to the window.
+func (m *model) resizeSSHKeys() {
+	if m.ms.sshKeys == nil || m.ms.sshKeys.form == nil {
+		return
+	}
+	m.ms.sshKeys.form = m.ms.sshKeys.form.WithWidth(max(0, min(m.width-hostFormPadding, 80)))
+}
+
+// viewSSHKeys renders the key list with the selected key's details, or the
+// open form.
+func (m model) viewSSHKeys() string {
+	ks := m.ms.sshKeys
+	if ks == nil {
+		return ""
+	}
+	lg := lipgloss.NewStyle()
+	dim := lg.Foreground(m.theme.PreflightText)
+	h := m.lst.Help
+	h.Width = m.width
+
+	var body string
+	if ks.form != nil {
+		body = lg.Padding(1, 3).Render(ks.form.View())
+	} else {
+		title := fmt.Sprintf("SSH KEYS • %d", len(ks.keys))
+		header := lg.Padding(1, 0, 1, 1).Render(m.lst.Styles.Title.Render(title))
+
+		footer := transferFooterLines + sshKeysDetailLines
+		if m.status != "" {
+			footer += 1 + lipgloss.Height(m.status)
+		}
+		fit := max(1, m.height-footer-3)
+		first := max(0, ks.cursor-fit+1)
+		width := max(10, m.width-footerPadLeft-2)
+
+		var rows []string
+		for i := first; i < len(ks.keys) && i < first+fit; i++ {
+			prefix := "  "
+			if i == ks.cursor {
+				prefix = lg.Foreground(m.theme.SelectedItemTitle).Render("> ")
+			}
+			rows = append(rows, prefix+m.sshKeyLine(ks.keys[i], width))
+		}
+		switch {
+		case ks.loading && len(ks.keys) == 0:
+			rows = append(rows, dim.Render("  Loading keys…"))
+		case len(ks.keys) == 0:
+			rows = append(rows, dim.Render("  No keys in ~/.ssh or the agent. Press G to generate one."))
+		}
+		if ks.agentErr != "" {
+			rows = append(rows, "", dim.Render("  "+ansi.Truncate("Agent: "+ks.agentErr, width, "…")))
+		}
+		body = header + "\n" + lg.PaddingLeft(footerPadLeft).Render(strings.Join(rows, "\n"))
+		if k, ok := ks.selected(); ok {
+			body += "\n" + m.sshKeyDetails(k)
+		}
+	}
+
+	lines := []string{body}
+	if m.status != "" {
+		lines = append(lines, lg.PaddingLeft(footerPadLeft).PaddingTop(1).Foreground(m.statusColor()).Render(m.status))
+	}
+	lines = append(lines, lg.PaddingLeft(footerPadLeft).PaddingTop(1).Render(h.ShortHelpView(m.sshKeysHelpKeys())))
+	return strings.Join(lines, "\n")
+}
+
+// sshKeyLine renders a key on one line: name, type and size, and where it is.
+func (m model) sshKeyLine(k sshkeys.Key, width int) string {
+	lg := lipgloss.NewStyle()
+	var tags []string
+	if k.InAgent {
+		tags = append(tags, lg.Foreground(m.theme.StatusSuccess).Render("agent"))
+	}
+	if k.Encrypted {
+		tags = append(tags, lg.Foreground(m.theme.PreflightText).Render("passphrase"))
+	}
+	if k.IsDefault() {
+		tags = append(tags, lg.Foreground(m.theme.PreflightText).Render("default"))
+	}
+	right := strings.Join(tags, " ")
+	left := fmt.Sprintf("%-24s %s %d", k.Name(), k.Type, k.Bits)
+	left = ansi.Truncate(left, max(1, width-lipgloss.Width(right)-1), "…")
+	return left + strings.Repeat(" ", max(1, width-lipgloss.Width(left)-lipgloss.Width(right))) + right
+}
+
+// sshKeyDetails renders the selected key's details and the hosts using it.
+func (m model) sshKeyDetails(k sshkeys.Key) string {
+	s := m.newDetailsStyles()
+	path := k.Path
+	if path == "" {
+		path = "(agent only)"
+	}
+	usedBy := "(none)"
+	if users := m.keyUsers(k); len(users) > 0 {
+		usedBy = summarizeList(users, sshKeysUsedByMax)
+	}
+	if k.IsDefault() {
+		usedBy += "; also tried for hosts without an IdentityFile"
+	}
+	rows := [][2]string{
+		{"Path", path},
+		{"Type", fmt.Sprintf("%s %d", k.Type, k.Bits)},
+		{"Fingerprint", k.Fingerprint},
+		{"Comment", k.Comment},
+		{"In Agent", yesNo(k.InAgent)},
+		{"Passphrase", yesNo(k.Encrypted)},
+		{"Used By", usedBy},
+	}
+
+	var b strings.Builder
+	b.WriteString(s.header.PaddingBottom(1).Render("KEY DETAILS"))
+	b.WriteString("\n")
+	for _, r := range rows {
+		label := s.label.Render(fmt.Sprintf("%11s", r[0]))
+		fmt.Fprintf(&b, "%s:  %s\n", label, s.value.Render(r[1]))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
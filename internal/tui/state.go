@@ -1,11 +1,13 @@
 package tui
 
 import (
-	"os/exec"
+	"context"
 	"time"
 
 	"bubbletea-ssh-manager/internal/config"
 	"bubbletea-ssh-manager/internal/connect"
+	"bubbletea-ssh-manager/internal/monitor"
+	"bubbletea-ssh-manager/internal/record"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
@@ -15,21 +17,43 @@ type confirmState struct {
 	form        *huh.Form // confirmation form
 	title       string    // title of confirmation
 	description string    // description of confirmation
-	//returnMode  uiMode  // *** change this so only cancelling on edit goes back to previous mode
-	onConfirm tea.Cmd // command to run on confirm
-	onCancel  tea.Cmd // command to run on cancel
+	body        string    // primary panel content (empty shows the selected host's details)
+	returnMode  uiMode    // mode to return to when closed (the menu by default)
+	onConfirm   tea.Cmd   // command to run on confirm
+	onCancel    tea.Cmd   // command to run on cancel
 }
 
 type preflightState struct {
-	token       int                 // increments on preflight starts; for tick/result matching
-	remaining   int                 // remaining seconds in preflight (for display)
-	endsAt      time.Time           // when the preflight should end
-	protocol    config.Protocol     // protocol being checked
-	hostPort    string              // host:port being checked
-	windowTitle string              // original window title before preflight
-	cmd         *exec.Cmd           // running preflight command
-	tail        *connect.TailBuffer // tail buffer for preflight output
-	display     string              // display target (eg. host:port) for status messages
+	token       int                     // increments on preflight starts; for tick/result matching
+	ctx         context.Context         // canceled when the preflight is canceled or cleared
+	cancel      context.CancelFunc      // cancels ctx (nil if no preflight is running)
+	policy      connect.PreflightPolicy // timeout/retry policy for this host
+	attempt     int                     // current attempt (1-based)
+	retrying    bool                    // waiting before the next attempt
+	remaining   int                     // remaining seconds in the attempt or retry wait (for display)
+	endsAt      time.Time               // when the attempt times out or the retry wait ends
+	protocol    config.Protocol         // protocol being checked
+	hostPort    string                  // host:port being checked
+	windowTitle string                  // original window title before preflight
+	cmd         connect.Command         // connection command to run after preflight
+	tail        *connect.TailBuffer     // tail buffer for preflight output
+	alias       string                  // host alias being connected to
+	knownHost   string                  // host name checked in known_hosts (ssh only)
+	knownPort   string                  // port checked in known_hosts (ssh only)
+	display     string                  // display target (eg. host:port) for status messages
+	via         string                  // first jump hop alias when dialing through ProxyJump
+	capture     record.Capture          // session recorder/log writers
+	argv        []string                // session command for the launcher (nil to run in place)
+	hooks       bool                    // running pre-connect hooks (before any reachability check)
+	post        postHooks               // post-connect hooks for the session
+}
+
+type monitorState struct {
+	enabled bool                      // monitor is running
+	token   int                       // increments on start/stop; for tick/sweep matching
+	cancel  context.CancelFunc        // cancels the sweep in flight (nil if none)
+	cfg     monitor.Config            // probe interval/timeout/concurrency
+	results map[string]monitor.Result // latest results by hostKey
 }
 
 type modeState struct {
@@ -47,4 +71,37 @@ type modeState struct {
 
 	// preflight check state
 	preflight preflightState
+
+	// algorithm probe shown in host details (nil if none)
+	probe *probeState
+
+	// fix offered by the last connection failure (nil if none)
+	pendingFix *pendingFix
+
+	// recordings browser state
+	recordings *recordingsState
+
+	// run command form/results (nil if not open)
+	run *runState
+
+	// bulk action form for the marked hosts (nil if not open)
+	bulk *bulkState
+
+	// file transfer browser (nil if not open)
+	transfer *transferState
+
+	// transfer queue view (nil if not open)
+	transferQueue *transferQueueState
+
+	// ssh keys view (nil if not open)
+	sshKeys *sshKeysState
+
+	// known_hosts view (nil if not open)
+	knownHosts *knownHostsState
+
+	// settings form (nil if not open)
+	settings *settingsState
+
+	// import form (nil if not open)
+	imports *importState
 }
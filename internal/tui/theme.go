@@ -1,6 +1,11 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/settings"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 // Theme groups UI colors used by the TUI so styling is centralized.
 //
@@ -13,8 +18,9 @@ type Theme struct {
 	StatusSuccess lipgloss.Color
 
 	// Protocol colors
-	ProtocolSSH    lipgloss.Color
-	ProtocolTelnet lipgloss.Color
+	ProtocolSSH    lipgloss.Color // ssh and mosh
+	ProtocolTelnet lipgloss.Color // telnet and raw TCP
+	ProtocolOther  lipgloss.Color // serial and custom commands
 
 	// UI element colors
 	SelectedItemBorder lipgloss.Color
@@ -29,6 +35,12 @@ type Theme struct {
 	DetailsLabel       lipgloss.Color
 	OptionsLabel       lipgloss.Color
 
+	// Reachability monitor colors
+	MonitorUp       lipgloss.Color
+	MonitorDegraded lipgloss.Color
+	MonitorDown     lipgloss.Color
+	MonitorUnknown  lipgloss.Color
+
 	// Help/key colors
 	HelpText  lipgloss.Color
 	KeyCursor lipgloss.Color
@@ -41,6 +53,7 @@ type Theme struct {
 	KeyEdit   lipgloss.Color
 	KeyRemove lipgloss.Color
 	KeyEnter  lipgloss.Color
+	KeyRecord lipgloss.Color
 }
 
 // DefaultTheme returns the default Theme with preset color values.
@@ -52,6 +65,7 @@ func DefaultTheme() Theme {
 
 		ProtocolSSH:    lipgloss.Color("#98c379"),
 		ProtocolTelnet: lipgloss.Color("#ea8665"),
+		ProtocolOther:  lipgloss.Color("#56b6c2"),
 
 		SelectedItemBorder: lipgloss.Color("#882d90"),
 		SelectedItemTitle:  lipgloss.Color("#ad58b4"),
@@ -65,6 +79,11 @@ func DefaultTheme() Theme {
 		DetailsLabel:       lipgloss.Color("#ddb034"),
 		OptionsLabel:       lipgloss.Color("#ddb034"),
 
+		MonitorUp:       lipgloss.Color("#98c379"),
+		MonitorDegraded: lipgloss.Color("#e5c07b"),
+		MonitorDown:     lipgloss.Color("#e06c75"),
+		MonitorUnknown:  lipgloss.Color("#5c6370"),
+
 		HelpText:  lipgloss.Color("#949494"),
 		KeyCursor: lipgloss.Color("#98c379"),
 		KeyBack:   lipgloss.Color("#c678dd"),
@@ -76,7 +95,60 @@ func DefaultTheme() Theme {
 		KeyEdit:   lipgloss.Color("#98c379"),
 		KeyRemove: lipgloss.Color("#e06c75"),
 		KeyEnter:  lipgloss.Color("#98c379"),
+		KeyRecord: lipgloss.Color("#61afef"),
+	}
+}
+
+// LightTheme returns a Theme with darker colors, for light terminal backgrounds.
+func LightTheme() Theme {
+	return Theme{
+		StatusDefault: lipgloss.Color("#4e4e4e"),
+		StatusError:   lipgloss.Color("#c0392b"),
+		StatusSuccess: lipgloss.Color("#2e7d32"),
+
+		ProtocolSSH:    lipgloss.Color("#2e7d32"),
+		ProtocolTelnet: lipgloss.Color("#b4510f"),
+		ProtocolOther:  lipgloss.Color("#00838f"),
+
+		SelectedItemBorder: lipgloss.Color("#6a1b9a"),
+		SelectedItemTitle:  lipgloss.Color("#7b1fa2"),
+		SearchLabel:        lipgloss.Color("#3f51b5"),
+		UsernamePrompt:     lipgloss.Color("#8d6e00"),
+		GroupName:          lipgloss.Color("#b35900"),
+		PreflightText:      lipgloss.Color("#616161"),
+		PreflightSpinner:   lipgloss.Color("#c2185b"),
+		DetailsHeader:      lipgloss.Color("#3f51b5"),
+		DetailsBorder:      lipgloss.Color("#1565c0"),
+		DetailsLabel:       lipgloss.Color("#8d6e00"),
+		OptionsLabel:       lipgloss.Color("#8d6e00"),
+
+		MonitorUp:       lipgloss.Color("#2e7d32"),
+		MonitorDegraded: lipgloss.Color("#a67c00"),
+		MonitorDown:     lipgloss.Color("#c0392b"),
+		MonitorUnknown:  lipgloss.Color("#9e9e9e"),
+
+		HelpText:  lipgloss.Color("#616161"),
+		KeyCursor: lipgloss.Color("#2e7d32"),
+		KeyBack:   lipgloss.Color("#8e24aa"),
+		KeyAdd:    lipgloss.Color("#8e24aa"),
+		KeyQuit:   lipgloss.Color("#c0392b"),
+		KeyInfo:   lipgloss.Color("#1565c0"),
+		KeyClear:  lipgloss.Color("#8d6e00"),
+		KeyClose:  lipgloss.Color("#c0392b"),
+		KeyEdit:   lipgloss.Color("#2e7d32"),
+		KeyRemove: lipgloss.Color("#c0392b"),
+		KeyEnter:  lipgloss.Color("#2e7d32"),
+		KeyRecord: lipgloss.Color("#1565c0"),
+	}
+}
+
+// themeNamed returns the theme a ui.theme setting names; unknown names get
+// the default theme.
+func themeNamed(name string) Theme {
+	if name == settings.ThemeLight {
+		return LightTheme()
 	}
+	return DefaultTheme()
 }
 
 func GreenEnter() string {
@@ -95,7 +167,27 @@ func PurpleCaret() string {
 	return lipgloss.NewStyle().Foreground(DefaultTheme().KeyBack).Render("^")
 }
 
+func BlueP() string {
+	return lipgloss.NewStyle().Foreground(DefaultTheme().KeyRecord).Render("P")
+}
+
 const (
+	RecordingDot = "● "
+	MonitorDot   = "●"
+	MarkSymbol   = "✓ "
 	SuccessCheck = " ✔️"
 	ErrorX       = "❌ "
 )
+
+// protocolColor returns the color hosts of protocol p are shown in.
+func (t Theme) protocolColor(p config.Protocol) lipgloss.Color {
+	info, _ := config.LookupProtocol(p)
+	switch info.Style {
+	case config.StyleSSH:
+		return t.ProtocolSSH
+	case config.StyleTelnet:
+		return t.ProtocolTelnet
+	default:
+		return t.ProtocolOther
+	}
+}
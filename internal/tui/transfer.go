@@ -0,0 +1,428 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/config"
+	"bubbletea-ssh-manager/internal/transfer"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	paneLocal  = 0 // local file pane
+	paneRemote = 1 // remote file pane
+
+	transferListTimeout   = 30 * time.Second // remote listing, including login
+	transferHeaderLines   = 4                // title + padding + pane titles
+	transferFooterLines   = 4                // help + padding
+	transferPaneGap       = 2                // space between the panes
+	transferQueuePreview  = 3                // jobs shown under the panes
+	transferSizeColWidth  = 10               // size column in the panes
+	transferParentDirName = ".."
+)
+
+type transferState struct {
+	alias   string            // ssh host alias
+	browser *transfer.Browser // remote listings
+	panes   [2]transferPane   // local and remote panes
+	active  int               // pane with the cursor (paneLocal or paneRemote)
+}
+
+// transferPane is one side of the file browser.
+type transferPane struct {
+	dir     string           // directory shown ("" until the first remote listing)
+	entries []transfer.Entry // entries, with ".." first unless dir is the root
+	cursor  int              // selected entry
+	loading bool             // remote listing in progress
+	seq     int              // increments on remote listings; for result matching
+	err     string           // why the last listing failed
+}
+
+// selected returns the entry under the cursor, if any.
+func (p *transferPane) selected() (transfer.Entry, bool) {
+	if p.cursor < 0 || p.cursor >= len(p.entries) {
+		return transfer.Entry{}, false
+	}
+	return p.entries[p.cursor], true
+}
+
+// setEntries shows entries for dir, selecting the entry named sel if there is one.
+func (p *transferPane) setEntries(dir string, entries []transfer.Entry, root bool, sel string) {
+	p.dir = dir
+	p.entries = p.entries[:0]
+	if !root {
+		p.entries = append(p.entries, transfer.Entry{Name: transferParentDirName, Dir: true})
+	}
+	p.entries = append(p.entries, entries...)
+	p.cursor = 0
+	for i, e := range p.entries {
+		if e.Name == sel {
+			p.cursor = i
+			break
+		}
+	}
+	p.err = ""
+}
+
+// openTransfer opens the file browser for the selected ssh host.
+func (m model) openTransfer() (model, tea.Cmd) {
+	it, _ := m.lst.SelectedItem().(*menuItem)
+	if it == nil || it.kind != itemHost || it.protocol != config.ProtocolSSH {
+		return m, m.setStatusError("Select an ssh host to transfer files with.", statusTTL)
+	}
+
+	ts := &transferState{alias: it.spec.Alias, browser: transfer.NewBrowser(it.spec.Alias)}
+	dir, err := config.GetConfigPath() // the home directory, $HOME-aware like the ssh config
+	if err != nil {
+		dir, _ = os.Getwd()
+	}
+	m.mode = modeTransfer
+	m.ms.transfer = ts
+	m.setStatusInfo("", 0)
+	m.loadLocalPane(dir, "")
+	m.relayout()
+	return m, m.loadRemotePane("", "")
+}
+
+// closeTransfer closes the file browser. Queued transfers keep running.
+func (m model) closeTransfer() (model, tea.Cmd) {
+	if ts := m.ms.transfer; ts != nil {
+		ts.browser.Close()
+	}
+	m.mode = modeMenu
+	m.ms.transfer = nil
+	m.relayout()
+	return m, nil
+}
+
+// loadLocalPane lists a local directory into the local pane.
+func (m *model) loadLocalPane(dir, sel string) {
+	p := &m.ms.transfer.panes[paneLocal]
+	dir = filepath.Clean(dir)
+	entries, err := transfer.ListLocal(dir)
+	if err != nil {
+		p.err = err.Error()
+		if p.dir == "" {
+			p.dir = dir
+		}
+		return
+	}
+	p.setEntries(dir, entries, filepath.Dir(dir) == dir, sel)
+}
+
+// loadRemotePane starts listing a remote directory ("" for the login
+// directory) into the remote pane.
+func (m *model) loadRemotePane(dir, sel string) tea.Cmd {
+	ts := m.ms.transfer
+	p := &ts.panes[paneRemote]
+	p.seq++
+	p.loading = true
+	seq, b := p.seq, ts.browser
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), transferListTimeout)
+		defer cancel()
+		cwd, entries, err := b.List(ctx, dir)
+		return remoteListedMsg{transfer: ts, seq: seq, requested: dir, dir: cwd, entries: entries, sel: sel, err: err}
+	}
+}
+
+// handleRemoteListedMsg shows a remote listing in the remote pane.
+func (m model) handleRemoteListedMsg(msg remoteListedMsg) (model, tea.Cmd) {
+	ts := m.ms.transfer
+	if ts == nil || msg.transfer != ts || msg.seq != ts.panes[paneRemote].seq {
+		return m, nil
+	}
+	p := &ts.panes[paneRemote]
+	p.loading = false
+	if msg.err != nil {
+		p.err = msg.err.Error()
+		return m, nil
+	}
+
+	// the session stays put if the entry couldn't be entered (eg. a link to a file)
+	if msg.requested != "" && msg.dir == p.dir && path.Clean(msg.requested) != p.dir {
+		return m, m.setStatusError(path.Base(msg.requested)+" is not a directory.", statusTTL)
+	}
+	p.setEntries(msg.dir, msg.entries, msg.dir == "/", msg.sel)
+	return m, nil
+}
+
+// openTransferEntry enters the selected directory (or the parent for "..").
+func (m model) openTransferEntry() (model, tea.Cmd) {
+	ts := m.ms.transfer
+	p := &ts.panes[ts.active]
+	e, ok := p.selected()
+	if !ok {
+		return m, nil
+	}
+	if e.Name == transferParentDirName {
+		return m.openTransferParent()
+	}
+	if ts.active == paneLocal {
+		if !e.Dir {
+			return m, m.setStatusInfo("Press C to upload "+e.Name+".", statusTTL)
+		}
+		m.loadLocalPane(filepath.Join(p.dir, e.Name), "")
+		return m, nil
+	}
+	if !e.Dir && !e.Link {
+		return m, m.setStatusInfo("Press C to download "+e.Name+".", statusTTL)
+	}
+	if p.loading {
+		return m, nil
+	}
+	return m, m.loadRemotePane(path.Join(p.dir, e.Name), "")
+}
+
+// openTransferParent moves the active pane to its parent directory.
+func (m model) openTransferParent() (model, tea.Cmd) {
+	ts := m.ms.transfer
+	p := &ts.panes[ts.active]
+	if ts.active == paneLocal {
+		if parent := filepath.Dir(p.dir); parent != p.dir {
+			m.loadLocalPane(parent, filepath.Base(p.dir))
+		}
+		return m, nil
+	}
+	if p.loading || p.dir == "" || p.dir == "/" {
+		return m, nil
+	}
+	return m, m.loadRemotePane(path.Dir(p.dir), path.Base(p.dir))
+}
+
+// refreshTransferPanes lists both panes again, keeping the selection.
+func (m model) refreshTransferPanes() (model, tea.Cmd) {
+	ts := m.ms.transfer
+	local := &ts.panes[paneLocal]
+	sel, _ := local.selected()
+	m.loadLocalPane(local.dir, sel.Name)
+
+	remote := &ts.panes[paneRemote]
+	sel, _ = remote.selected()
+	return m, m.loadRemotePane(remote.dir, sel.Name)
+}
+
+// queueTransfer copies the selected entry to the other pane's directory in the background.
+func (m model) queueTransfer() (model, tea.Cmd) {
+	ts := m.ms.transfer
+	local, remote := &ts.panes[paneLocal], &ts.panes[paneRemote]
+	e, ok := ts.panes[ts.active].selected()
+	if !ok || e.Name == transferParentDirName {
+		return m, m.setStatusInfo("Select a file or directory to copy.", statusTTL)
+	}
+	if remote.dir == "" {
+		return m, m.setStatusInfo("Wait for the remote listing before copying.", statusTTL)
+	}
+
+	var text string
+	if ts.active == paneLocal {
+		m.transfers.Add(ts.alias, transfer.Upload, filepath.Join(local.dir, e.Name), remote.dir)
+		text = fmt.Sprintf("Queued upload of %s to %s:%s.", e.Name, ts.alias, remote.dir)
+	} else {
+		m.transfers.Add(ts.alias, transfer.Download, path.Join(remote.dir, e.Name), local.dir)
+		text = fmt.Sprintf("Queued download of %s to %s.", e.Name, local.dir)
+	}
+	return m, tea.Batch(m.setStatusInfo(text, statusTTL), m.startTransferTick())
+}
+
+// refreshTransferDest lists a pane again if a finished job copied into the
+// directory it shows.
+func (m *model) refreshTransferDest(j transfer.Job) tea.Cmd {
+	ts := m.ms.transfer
+	if ts == nil || ts.alias != j.Alias {
+		return nil
+	}
+	if j.Direction == transfer.Download {
+		p := &ts.panes[paneLocal]
+		if filepath.Clean(j.DestDir) == p.dir {
+			sel, _ := p.selected()
+			m.loadLocalPane(p.dir, sel.Name)
+		}
+		return nil
+	}
+	p := &ts.panes[paneRemote]
+	if path.Clean(j.DestDir) != p.dir || p.loading {
+		return nil
+	}
+	sel, _ := p.selected()
+	return m.loadRemotePane(p.dir, sel.Name)
+}
+
+// handleTransferKeyMsg handles keys in the file browser.
+//
+// Tab switches panes, enter/right opens a directory, left/backspace goes to
+// the parent, C copies the selection to the other pane, ctrl+r refreshes,
+// J opens the transfer queue and esc closes the browser.
+func (m model) handleTransferKeyMsg(msg tea.KeyMsg) (model, tea.Cmd) {
+	ts := m.ms.transfer
+	if ts == nil {
+		return m.closeTransfer()
+	}
+	p := &ts.panes[ts.active]
+
+	switch {
+	case msg.String() == "ctrl+c", key.Matches(msg, m.keys.Quit):
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.CloseForm):
+		return m.closeTransfer()
+
+	case key.Matches(msg, m.keys.TransferPane):
+		ts.active = 1 - ts.active
+		return m, nil
+
+	case key.Matches(msg, m.keys.TransferOpen):
+		return m.openTransferEntry()
+
+	case key.Matches(msg, m.keys.TransferParent):
+		return m.openTransferParent()
+
+	case key.Matches(msg, m.keys.TransferCopy):
+		return m.queueTransfer()
+
+	case key.Matches(msg, m.keys.TransferRefresh):
+		return m.refreshTransferPanes()
+
+	case key.Matches(msg, m.keys.Transfers):
+		return m.openTransferQueue()
+
+	case msg.String() == "up":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case msg.String() == "down":
+		if p.cursor < len(p.entries)-1 {
+			p.cursor++
+		}
+	case msg.String() == "pgup":
+		p.cursor = max(0, p.cursor-m.transferPaneHeight())
+	case msg.String() == "pgdown":
+		p.cursor = max(0, min(len(p.entries)-1, p.cursor+m.transferPaneHeight()))
+	case msg.String() == "home":
+		p.cursor = 0
+	case msg.String() == "end":
+		p.cursor = max(0, len(p.entries)-1)
+	}
+	return m, nil
+}
+
+// transferHelpKeys returns the help keys shown in the file browser.
+func (m model) transferHelpKeys() []key.Binding {
+	return []key.Binding{m.keys.CloseForm, m.keys.TransferPane, m.keys.TransferOpen, m.keys.TransferParent,
+		m.keys.TransferCopy, m.keys.TransferRefresh, m.keys.Transfers}
+}
+
+// transferPaneHeight returns how many entries fit in a pane.
+func (m model) transferPaneHeight() int {
+	footer := transferFooterLines + min(len(m.transfers.Jobs()), transferQueuePreview)
+	if m.status != "" {
+		footer += 1 + lipgloss.Height(m.status)
+	}
+	return max(1, m.height-transferHeaderLines-footer)
+}
+
+// viewTransfer renders the local and remote panes, the latest transfers and help.
+func (m model) viewTransfer() string {
+	ts := m.ms.transfer
+	if ts == nil {
+		return ""
+	}
+	lg := lipgloss.NewStyle()
+	h := m.lst.Help
+	h.Width = m.width
+
+	title := m.lst.Styles.Title.Render(ansi.Truncate("TRANSFER "+ts.alias, max(0, m.width-12), "…"))
+	header := lg.Padding(1, 0, 0, 1).Render(title)
+
+	paneW := max(10, (m.width-footerPadLeft-transferPaneGap)/2)
+	height := m.transferPaneHeight()
+	left := m.viewTransferPane(paneLocal, "Local", paneW, height)
+	right := m.viewTransferPane(paneRemote, ts.alias, paneW, height)
+	body := lg.PaddingLeft(footerPadLeft).PaddingTop(1).Render(
+		lipgloss.JoinHorizontal(lipgloss.Top, left, strings.Repeat(" ", transferPaneGap), right))
+
+	lines := []string{header, body}
+	if jobs := m.transfers.Jobs(); len(jobs) > 0 {
+		jobs = jobs[max(0, len(jobs)-transferQueuePreview):]
+		var b []string
+		for _, j := range jobs {
+			b = append(b, m.transferJobLine(j, m.width-footerPadLeft))
+		}
+		lines = append(lines, lg.PaddingLeft(footerPadLeft).PaddingTop(1).Render(strings.Join(b, "\n")))
+	}
+	if m.status != "" {
+		lines = append(lines, lg.PaddingLeft(footerPadLeft).PaddingTop(1).Foreground(m.statusColor()).Render(m.status))
+	}
+	lines = append(lines, lg.PaddingLeft(footerPadLeft).PaddingTop(1).Render(h.ShortHelpView(m.transferHelpKeys())))
+	return strings.Join(lines, "\n")
+}
+
+// viewTransferPane renders one pane: its directory, then a window of entries
+// around the cursor.
+func (m model) viewTransferPane(i int, name string, width, height int) string {
+	ts := m.ms.transfer
+	p := &ts.panes[i]
+	lg := lipgloss.NewStyle()
+	dim := lg.Foreground(m.theme.PreflightText)
+
+	titleStyle := lg.Foreground(m.theme.PreflightText).Bold(true)
+	if i == ts.active {
+		titleStyle = lg.Foreground(m.theme.SelectedItemTitle).Bold(true)
+	}
+	dir := p.dir
+	if maxW := width - lipgloss.Width(name) - 2; lipgloss.Width(dir) > maxW {
+		dir = ansi.TruncateLeft(dir, lipgloss.Width(dir)-maxW+1, "…")
+	}
+	rows := []string{titleStyle.Render(name+": ") + dim.Render(dir)}
+
+	switch {
+	case p.err != "":
+		rows = append(rows, lg.Foreground(m.theme.StatusError).Width(width).Render(p.err))
+	case p.loading && len(p.entries) == 0:
+		rows = append(rows, dim.Render("Connecting…"))
+	case len(p.entries) == 0:
+		rows = append(rows, dim.Render("(empty)"))
+	}
+
+	first := max(0, p.cursor-height+1)
+	for j := first; j < len(p.entries) && j < first+height; j++ {
+		e := p.entries[j]
+		label := e.Name
+		size := ""
+		style := lg
+		switch {
+		case e.Dir:
+			label += "/"
+			style = lg.Foreground(m.theme.GroupName)
+		case e.Link:
+			label += "@"
+		default:
+			size = formatBytes(e.Size)
+		}
+		nameW := max(1, width-2-transferSizeColWidth)
+		label = ansi.Truncate(label, nameW, "…")
+		line := style.Render(label) + strings.Repeat(" ", max(1, nameW-lipgloss.Width(label))) +
+			dim.Render(fmt.Sprintf("%*s", transferSizeColWidth, size))
+		if j == p.cursor && i == ts.active {
+			line = lg.Foreground(m.theme.SelectedItemTitle).Render("> ") + line
+		} else {
+			line = "  " + line
+		}
+		rows = append(rows, line)
+	}
+	if p.loading && len(p.entries) > 0 {
+		rows[0] += dim.Render(" (loading…)")
+	}
+	return lg.Width(width).Render(strings.Join(rows, "\n"))
+}
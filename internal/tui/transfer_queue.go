@@ -0,0 +1,305 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"bubbletea-ssh-manager/internal/transfer"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	transferTickInterval = 500 * time.Millisecond // progress refresh while transfers run
+	transferBarWidth     = 20                     // progress bar width in the queue view
+	transferJobLines     = 3                      // lines per job in the queue view
+)
+
+type transferQueueState struct {
+	selected int // selected job (index into Queue.Jobs)
+}
+
+// startTransferTick starts the progress tick unless it's already running.
+func (m *model) startTransferTick() tea.Cmd {
+	if m.transferTicking {
+		return nil
+	}
+	m.transferTicking = true
+	return transferTickCmd()
+}
+
+// transferTickCmd schedules the next progress refresh.
+func transferTickCmd() tea.Cmd {
+	return tea.Tick(transferTickInterval, func(time.Time) tea.Msg { return transferTickMsg{} })
+}
+
+// handleTransferTickMsg reports finished transfers and keeps ticking while
+// any are queued or running. Views showing progress redraw on every tick.
+func (m model) handleTransferTickMsg(transferTickMsg) (model, tea.Cmd) {
+	var cmds []tea.Cmd
+	if finished := m.transfers.TakeFinished(); len(finished) > 0 {
+		for _, j := range finished {
+			cmds = append(cmds, m.refreshTransferDest(j))
+		}
+		cmds = append(cmds, m.reportTransfers(finished))
+	}
+	m.syncListTitle()
+
+	if m.transfers.Active() == 0 {
+		m.transferTicking = false
+		return m, tea.Batch(cmds...)
+	}
+	return m, tea.Batch(append(cmds, transferTickCmd())...)
+}
+
+// reportTransfers sets the status for transfers that just finished. Canceled
+// ones aren't reported; the user asked for that.
+func (m *model) reportTransfers(jobs []transfer.Job) tea.Cmd {
+	var done, failed []transfer.Job
+	for _, j := range jobs {
+		switch j.State {
+		case transfer.StateDone:
+			done = append(done, j)
+		case transfer.StateFailed:
+			failed = append(failed, j)
+		}
+	}
+	switch {
+	case len(done)+len(failed) == 0:
+		return nil
+	case len(done) == 1 && len(failed) == 0:
+		return m.setStatusSuccess(transferSummary(done[0])+SuccessCheck, statusTTL)
+	case len(failed) == 1 && len(done) == 0:
+		j := failed[0]
+		return m.setStatusError(fmt.Sprintf("%s of %s failed: %v (J, then U to resume)",
+			capitalize(j.Direction.String()), j.Name(), j.Err), 0)
+	case len(failed) == 0:
+		return m.setStatusSuccess(fmt.Sprintf("%d transfers finished.%s", len(done), SuccessCheck), statusTTL)
+	}
+	return m.setStatusError(fmt.Sprintf("%d transfers finished, %d failed (J to view).", len(done), len(failed)), 0)
+}
+
+// transferSummary describes a finished job (eg. "Uploaded logs to web1:/tmp").
+func transferSummary(j transfer.Job) string {
+	s := fmt.Sprintf("Uploaded %s to %s:%s", j.Name(), j.Alias, j.DestDir)
+	if j.Direction == transfer.Download {
+		s = fmt.Sprintf("Downloaded %s from %s to %s", j.Name(), j.Alias, j.DestDir)
+	}
+	if j.Skipped > 0 {
+		s += fmt.Sprintf(" (%d copied before)", j.Skipped)
+	}
+	return s
+}
+
+// capitalize upper-cases the first letter of s.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// openTransferQueue opens the transfer queue view.
+func (m model) openTransferQueue() (model, tea.Cmd) {
+	if m.transfers.Len() == 0 {
+		return m, m.setStatusInfo("No transfers yet. Press T on an ssh host to copy files.", statusTTL)
+	}
+	m.mode = modeTransferQueue
+	m.ms.transferQueue = &transferQueueState{}
+	m.relayout()
+	return m, nil
+}
+
+// closeTransferQueue returns to the file browser if it's open, otherwise to the menu.
+func (m model) closeTransferQueue() (model, tea.Cmd) {
+	m.ms.transferQueue = nil
+	m.mode = modeMenu
+	if m.ms.transfer != nil {
+		m.mode = modeTransfer
+	}
+	m.relayout()
+	return m, nil
+}
+
+// handleTransferQueueKeyMsg handles keys in the transfer queue view.
+//
+// Up/down pick a job, R cancels it (or removes it once finished), U resumes
+// a failed or canceled job, C clears finished jobs and left/esc goes back.
+func (m model) handleTransferQueueKeyMsg(msg tea.KeyMsg) (model, tea.Cmd) {
+	qs := m.ms.transferQueue
+	if qs == nil {
+		return m.closeTransferQueue()
+	}
+	jobs := m.transfers.Jobs()
+	qs.selected = max(0, min(qs.selected, len(jobs)-1))
+
+	switch {
+	case msg.String() == "ctrl+c", key.Matches(msg, m.keys.Quit):
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.CloseDetails), key.Matches(msg, m.keys.CloseForm):
+		return m.closeTransferQueue()
+
+	case msg.String() == "up":
+		qs.selected = max(0, qs.selected-1)
+
+	case msg.String() == "down":
+		qs.selected = max(0, min(len(jobs)-1, qs.selected+1))
+
+	case key.Matches(msg, m.keys.TransferCancel) && len(jobs) > 0:
+		j := jobs[qs.selected]
+		if j.State.Finished() {
+			m.transfers.Remove(j.ID)
+			return m, nil
+		}
+		m.transfers.Cancel(j.ID)
+		return m, m.setStatusInfo("Canceled "+j.Name()+".", statusTTL)
+
+	case key.Matches(msg, m.keys.TransferRetry) && len(jobs) > 0:
+		j := jobs[qs.selected]
+		if !m.transfers.Retry(j.ID) {
+			return m, m.setStatusInfo("Only failed or canceled transfers can be resumed.", statusTTL)
+		}
+		return m, tea.Batch(m.setStatusInfo("Resuming "+j.Name()+".", statusTTL), m.startTransferTick())
+
+	case key.Matches(msg, m.keys.TransferClear):
+		if n := m.transfers.ClearFinished(); n > 0 {
+			return m, m.setStatusInfo(fmt.Sprintf("Cleared %d finished transfer(s).", n), statusTTL)
+		}
+	}
+	return m, nil
+}
+
+// transferQueueHelpKeys returns the help keys shown in the transfer queue view.
+func (m model) transferQueueHelpKeys() []key.Binding {
+	return []key.Binding{m.keys.CloseDetails, m.lst.KeyMap.CursorUp, m.lst.KeyMap.CursorDown,
+		m.keys.TransferCancel, m.keys.TransferRetry, m.keys.TransferClear}
+}
+
+// viewTransferQueue renders every job with a progress bar.
+func (m model) viewTransferQueue() string {
+	qs := m.ms.transferQueue
+	if qs == nil {
+		return ""
+	}
+	lg := lipgloss.NewStyle()
+	dim := lg.Foreground(m.theme.PreflightText)
+	h := m.lst.Help
+	h.Width = m.width
+
+	jobs := m.transfers.Jobs()
+	title := fmt.Sprintf("TRANSFERS • %d active", m.transfers.Active())
+	header := lg.Padding(1, 0, 1, 1).Render(m.lst.Styles.Title.Render(title))
+
+	footer := transferFooterLines
+	if m.status != "" {
+		footer += 1 + lipgloss.Height(m.status)
+	}
+	fit := max(1, (m.height-footer-3)/transferJobLines)
+	selected := max(0, min(qs.selected, len(jobs)-1))
+	first := max(0, selected-fit+1)
+
+	width := max(10, m.width-footerPadLeft-2)
+	var rows []string
+	for i := first; i < len(jobs) && i < first+fit; i++ {
+		j := jobs[i]
+		prefix := "  "
+		if i == selected {
+			prefix = lg.Foreground(m.theme.SelectedItemTitle).Render("> ")
+		}
+		detail := m.transferJobDetail(j)
+		rows = append(rows,
+			prefix+m.transferJobLine(j, width),
+			"  "+dim.Render(ansi.Truncate(detail, width, "…")),
+			"")
+	}
+	if len(jobs) == 0 {
+		rows = append(rows, dim.Render("  No transfers."))
+	}
+
+	lines := []string{header, lg.PaddingLeft(footerPadLeft).Render(strings.Join(rows, "\n"))}
+	if m.status != "" {
+		lines = append(lines, lg.PaddingLeft(footerPadLeft).PaddingTop(1).Foreground(m.statusColor()).Render(m.status))
+	}
+	lines = append(lines, lg.PaddingLeft(footerPadLeft).PaddingTop(1).Render(h.ShortHelpView(m.transferQueueHelpKeys())))
+	return strings.Join(lines, "\n")
+}
+
+// transferJobLine renders a job on one line: direction, name, destination,
+// a progress bar and its state.
+func (m model) transferJobLine(j transfer.Job, width int) string {
+	lg := lipgloss.NewStyle()
+	icon, color := m.transferStateIcon(j.State)
+	arrow := "⇡"
+	if j.Direction == transfer.Download {
+		arrow = "⇣"
+	}
+	dest := j.Alias + ":" + j.DestDir
+	if j.Direction == transfer.Download {
+		dest = j.DestDir
+	}
+
+	state := j.State.String()
+	if pct := j.Percent(); pct >= 0 && !j.State.Finished() {
+		state = fmt.Sprintf("%3d%%", pct)
+	}
+	right := m.transferBar(j.Percent()) + " " + lg.Foreground(color).Render(icon+" "+state)
+	left := fmt.Sprintf("%s %s → %s", arrow, j.Name(), dest)
+	left = ansi.Truncate(left, max(1, width-lipgloss.Width(right)-1), "…")
+	return left + strings.Repeat(" ", max(1, width-lipgloss.Width(left)-lipgloss.Width(right))) + right
+}
+
+// transferJobDetail describes a job's progress (files, bytes, current file) or how it ended.
+func (m model) transferJobDetail(j transfer.Job) string {
+	var parts []string
+	if j.Files > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d files", j.FilesDone, j.Files),
+			formatBytes(j.Bytes)+" of "+formatBytes(j.TotalBytes))
+	}
+	if j.Skipped > 0 {
+		parts = append(parts, fmt.Sprintf("%d copied before", j.Skipped))
+	}
+	switch {
+	case j.State == transfer.StateRunning && j.Current != "":
+		parts = append(parts, j.Current)
+	case j.State == transfer.StateFailed && j.Err != nil:
+		parts = append(parts, j.Err.Error())
+	case j.State.Finished() && !j.Started.IsZero():
+		parts = append(parts, j.Ended.Sub(j.Started).Round(100*time.Millisecond).String())
+	case j.State == transfer.StateQueued:
+		parts = append(parts, "waiting for a free slot")
+	}
+	return strings.Join(parts, " • ")
+}
+
+// transferBar renders a progress bar for pct (-1 for unknown).
+func (m model) transferBar(pct int) string {
+	lg := lipgloss.NewStyle()
+	filled := 0
+	if pct > 0 {
+		filled = min(transferBarWidth, pct*transferBarWidth/100)
+	}
+	return lg.Foreground(m.theme.StatusSuccess).Render(strings.Repeat("█", filled)) +
+		lg.Foreground(m.theme.PreflightText).Render(strings.Repeat("░", transferBarWidth-filled))
+}
+
+// transferStateIcon returns the icon and color for a job's state.
+func (m model) transferStateIcon(s transfer.State) (string, lipgloss.Color) {
+	switch s {
+	case transfer.StateRunning:
+		return "●", m.theme.PreflightSpinner
+	case transfer.StateDone:
+		return "✔", m.theme.StatusSuccess
+	case transfer.StateFailed:
+		return "✘", m.theme.StatusError
+	case transfer.StateCanceled:
+		return "–", m.theme.PreflightText
+	default:
+		return "·", m.theme.PreflightText
+	}
+}
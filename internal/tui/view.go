@@ -12,17 +12,9 @@ import (
 //
 // It focuses the active menu item if prompting for username.
 func (m model) viewMenu() string {
-	statusColor := m.theme.StatusDefault
-	switch m.statusKind {
-	case statusError:
-		statusColor = m.theme.StatusError
-	case statusSuccess:
-		statusColor = m.theme.StatusSuccess
-	}
-
 	lg := lipgloss.NewStyle()
 	statusPadStyle := lg.PaddingLeft(footerPadLeft).PaddingTop(1)
-	statusTextStyle := lg.Foreground(statusColor)
+	statusTextStyle := lg.Foreground(m.statusColor())
 	searchStyle := lg.Foreground(m.theme.SearchLabel).Bold(true).PaddingLeft(footerPadLeft)
 	promptStyle := lg.Foreground(m.theme.UsernamePrompt).Bold(true).PaddingLeft(footerPadLeft)
 
@@ -52,13 +44,32 @@ func (m model) viewMenu() string {
 // It shows a spinner and countdown timer.
 func (m model) viewPreflight() string {
 	remaining := max(m.ms.preflight.remaining, 0)
+	hostPort := m.ms.preflight.hostPort
+	if via := m.ms.preflight.via; via != "" {
+		hostPort += " (jump host " + via + ")"
+	}
+	pf := m.ms.preflight
+	attempt := ""
+	if pf.policy.Attempts() > 1 && pf.attempt > 1 {
+		attempt = fmt.Sprintf("attempt %d/%d, ", pf.attempt, pf.policy.Attempts())
+	}
+	verb := "Checking"
+	if pf.retrying {
+		verb = "Retrying"
+	}
 	preflightStatusText := fmt.Sprintf(
-		"%s Checking %s %s (%ds)…\nctrl+c to cancel",
+		"%s %s %s %s (%s%ds)…\nctrl+c to cancel",
 		m.spinner.View(),
-		string(m.ms.preflight.protocol),
-		m.ms.preflight.hostPort,
+		verb,
+		string(pf.protocol),
+		hostPort,
+		attempt,
 		remaining,
 	)
+	if pf.hooks {
+		preflightStatusText = fmt.Sprintf("%s Running pre-connect hooks for %s %s…\nctrl+c to cancel",
+			m.spinner.View(), string(pf.protocol), pf.display)
+	}
 
 	lg := lipgloss.NewStyle()
 	preflightPadStyle := lg.PaddingLeft(footerPadLeft + 4).PaddingBottom(3).PaddingTop(1)
@@ -130,7 +141,8 @@ func (m model) viewHostDetails() string {
 
 // viewConfirm renders a confirmation dialog.
 //
-// Today this is used under host details; future confirmations (e.g. form save/cancel)
+// It's shown under host details, or under the confirmation's own body (eg. the
+// hosts a bulk action applies to); future confirmations (e.g. form save/cancel)
 // can reuse the same layout by changing the primary content.
 func (m model) viewConfirm() string {
 	lg := lipgloss.NewStyle()
@@ -151,9 +163,12 @@ func (m model) viewConfirm() string {
 		BorderForeground(m.theme.StatusError).
 		Align(lipgloss.Center)
 
-	// for now confirm is shown beneath host details
+	// confirm is shown beneath host details unless the confirmation brings its own content
 	// when confirmation prompt is added to add/edit form, swap this to the appropriate base
 	primaryContent := m.buildHostDetails()
+	if m.ms.confirm != nil && m.ms.confirm.body != "" {
+		primaryContent = m.ms.confirm.body
+	}
 	return m.viewDetailsConfirm(primaryBox, primaryContent, confirmBox, confirmContent, m.confirmHelpKeys())
 }
 